@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// endpoint describes a single API route exercised by the load test
+type endpoint struct {
+	Name   string
+	Method string
+	Path   string
+	Body   func(description string) []byte
+}
+
+// result records the outcome of one request
+type result struct {
+	Endpoint string
+	Latency  time.Duration
+	Err      bool
+}
+
+// endpoints are the routes the harness knows how to drive
+var endpoints = map[string]endpoint{
+	"generate": {
+		Name:   "generate",
+		Method: http.MethodPost,
+		Path:   "/api/v1/generate-query",
+		Body: func(description string) []byte {
+			body, _ := json.Marshal(map[string]interface{}{"description": description})
+			return body
+		},
+	},
+	"fields": {
+		Name:   "fields",
+		Method: http.MethodGet,
+		Path:   "/api/v1/fields",
+	},
+	"health": {
+		Name:   "health",
+		Method: http.MethodGet,
+		Path:   "/health",
+	},
+}
+
+func main() {
+	var (
+		target      = flag.String("target", "http://localhost:8080", "Base URL of the instance under test")
+		corpusPath  = flag.String("corpus", "cmd/loadtest/corpus.txt", "File with one description per line")
+		rps         = flag.Int("rps", 50, "Requests per second to send")
+		duration    = flag.Duration("duration", 30*time.Second, "How long to run the test")
+		concurrency = flag.Int("concurrency", 32, "Maximum in-flight requests")
+		endpointArg = flag.String("endpoints", "generate", "Comma-separated endpoints to exercise (generate, fields, health)")
+		timeout     = flag.Duration("timeout", 10*time.Second, "Per-request timeout")
+	)
+	flag.Parse()
+
+	if *rps <= 0 || *concurrency <= 0 {
+		log.Fatal("rps and concurrency must be positive")
+	}
+
+	corpus, err := loadCorpus(*corpusPath)
+	if err != nil {
+		log.Fatalf("Failed to load corpus: %v", err)
+	}
+
+	var selected []endpoint
+	for _, name := range strings.Split(*endpointArg, ",") {
+		ep, ok := endpoints[strings.TrimSpace(name)]
+		if !ok {
+			log.Fatalf("Unknown endpoint %q", name)
+		}
+		selected = append(selected, ep)
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	results := run(client, *target, selected, corpus, *rps, *duration, *concurrency)
+	report(os.Stdout, results, *duration)
+}
+
+// loadCorpus reads non-empty, non-comment lines from the corpus file
+func loadCorpus(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var corpus []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		corpus = append(corpus, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(corpus) == 0 {
+		return nil, fmt.Errorf("corpus %s is empty", path)
+	}
+	return corpus, nil
+}
+
+// run fires requests at a fixed rate until the duration elapses
+func run(client *http.Client, target string, selected []endpoint, corpus []string, rps int, duration time.Duration, concurrency int) []result {
+	var (
+		mu      sync.Mutex
+		results []result
+		wg      sync.WaitGroup
+	)
+	slots := make(chan struct{}, concurrency)
+
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+	deadline := time.After(duration)
+
+	for i := 0; ; i++ {
+		select {
+		case <-deadline:
+			wg.Wait()
+			return results
+		case <-ticker.C:
+		}
+
+		ep := selected[i%len(selected)]
+		description := corpus[rand.Intn(len(corpus))]
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			// Saturated: count as an error rather than silently lowering the rate
+			mu.Lock()
+			results = append(results, result{Endpoint: ep.Name, Err: true})
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			r := send(client, target, ep, description)
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}()
+	}
+}
+
+// send performs a single request and measures its latency
+func send(client *http.Client, target string, ep endpoint, description string) result {
+	var body io.Reader
+	if ep.Body != nil {
+		body = bytes.NewReader(ep.Body(description))
+	}
+
+	req, err := http.NewRequest(ep.Method, target+ep.Path, body)
+	if err != nil {
+		return result{Endpoint: ep.Name, Err: true}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return result{Endpoint: ep.Name, Latency: latency, Err: true}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// 4xx responses for unmatched descriptions are expected; only 5xx count as errors
+	return result{Endpoint: ep.Name, Latency: latency, Err: resp.StatusCode >= 500}
+}
+
+// report prints per-endpoint latency percentiles and error rates
+func report(w io.Writer, results []result, duration time.Duration) {
+	byEndpoint := make(map[string][]result)
+	for _, r := range results {
+		byEndpoint[r.Endpoint] = append(byEndpoint[r.Endpoint], r)
+	}
+
+	names := make([]string, 0, len(byEndpoint))
+	for name := range byEndpoint {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "%-10s %8s %8s %8s %10s %10s %10s %10s\n",
+		"endpoint", "requests", "rps", "errors", "p50", "p90", "p99", "max")
+	for _, name := range names {
+		rs := byEndpoint[name]
+
+		var latencies []time.Duration
+		errors := 0
+		for _, r := range rs {
+			if r.Err {
+				errors++
+				continue
+			}
+			latencies = append(latencies, r.Latency)
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		fmt.Fprintf(w, "%-10s %8d %8.1f %7.2f%% %10s %10s %10s %10s\n",
+			name,
+			len(rs),
+			float64(len(rs))/duration.Seconds(),
+			float64(errors)/float64(len(rs))*100,
+			percentile(latencies, 50),
+			percentile(latencies, 90),
+			percentile(latencies, 99),
+			percentile(latencies, 100))
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(float64(len(sorted))*p/100+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank].Round(time.Microsecond)
+}
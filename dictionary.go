@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+)
+
+// runDictionary implements the dictionary subcommand: it loads the configured
+// mappings (or the --csv override) and writes the data dictionary to stdout,
+// returning the process exit code
+func runDictionary(args []string) int {
+	flags := flag.NewFlagSet("dictionary", flag.ContinueOnError)
+	format := flags.String("format", services.FormatMarkdown, "Document format: markdown or html")
+	csvPath := flags.String("csv", "", "Path to field mappings CSV (overrides config)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if *csvPath != "" {
+		cfg.CSVPath = *csvPath
+	}
+
+	fieldService, err := services.NewFieldService(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	document, _, err := fieldService.RenderDictionary(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Print(document)
+	return 0
+}
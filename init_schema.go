@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/services"
+)
+
+// runInitSchema implements the init-schema subcommand: it writes a starter
+// mapping CSV for the given tables to stdout or --output, returning the
+// process exit code
+func runInitSchema(args []string) int {
+	flags := flag.NewFlagSet("init-schema", flag.ContinueOnError)
+	tables := flags.String("tables", "", "Comma-separated table names, e.g. users,orders,products")
+	output := flags.String("output", "", "File to write (default stdout); existing files are not overwritten")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *tables == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s init-schema --tables users,orders [--output field_mappings.csv]\n", os.Args[0])
+		return 2
+	}
+
+	data, err := services.StarterMappings(strings.Split(*tables, ","))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+
+	file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := file.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
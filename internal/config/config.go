@@ -3,14 +3,252 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config holds application configuration
 type Config struct {
 	Port             string
+	// CSVPath lists the mapping files (CSVs or YAML/JSON schema definitions)
+	// and directories of them, comma-separated; all are merged
 	CSVPath          string
+	// CSVDelimiter and CSVEncoding describe mapping CSVs exported by legacy
+	// systems (e.g. ";" or "tab", and "latin1"); empty detects them
+	CSVDelimiter     string
+	CSVEncoding      string
+	// SchemaSource selects where fields come from: csv, postgres, or mysql,
+	// the latter two introspecting DatabaseURL's DatabaseSchema
+	SchemaSource     string
+	DatabaseURL      string
+	DatabaseSchema   string
+	// DatabaseConnections names further databases queries are executed on,
+	// as "system=url,system=url"; a request's system picks its connection,
+	// falling back to DatabaseURL
+	DatabaseConnections string
+	// MaxQueryCost rejects queries whose EXPLAIN cost exceeds it when they
+	// are explained or estimated; 0 allows any cost
+	MaxQueryCost     float64
+	// ExecuteQueries lets /api/v1/execute run generated queries on
+	// DatabaseURL in read-only transactions. Results are cut off after
+	// ResultMaxRows rows or ResultMaxBytes bytes, and statements running
+	// past StatementTimeout are cancelled
+	ExecuteQueries   bool
+	ResultMaxRows    int
+	ResultMaxBytes   int
+	StatementTimeout time.Duration
+	// CursorSecret signs the cursors to later pages of cut off results,
+	// valid for CursorTTL; without it cursors last until a restart and only
+	// work on the instance that issued them
+	CursorSecret     string
+	CursorTTL        time.Duration
+	// DBMaxOpenConns, DBMaxIdleConns, and DBConnMaxLifetime size the
+	// connection pools of DatabaseURL; 0 keeps database/sql's defaults
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	// SchemaDriftInterval compares the catalog with the information_schema
+	// of DatabaseURL and of each system's connection on a timer, logging
+	// columns only one of them has; 0 disables it
+	SchemaDriftInterval time.Duration
 	MatchThreshold   float64
 	MaxMatches       int
+	AliasStyle       string
+	// TableAliases overrides the mappings' table_alias, as
+	// "table=alias,table=alias"
+	TableAliases     string
+	// Dialect renders generated SQL for one database: postgres, mysql,
+	// sqlite, sqlserver, bigquery, or snowflake; empty is portable SQL
+	Dialect          string
+	// KeywordCase (upper or lower), LeadingCommas, and AliasKeyword (columns,
+	// always, or never) restyle generated SQL for a team's style guide
+	KeywordCase      string
+	LeadingCommas    bool
+	AliasKeyword     string
+	// DefaultQueryType is generated when a description has no intent
+	// keywords: SELECT, COUNT, or GROUP
+	DefaultQueryType string
+	// DefaultClearance is the classification clearance of callers that
+	// don't send one: public, internal, or restricted. Callers may lower it
+	// with the X-Clearance header, but raising it needs the admin token
+	// unless TrustClearanceHeader says a gateway sets the header
+	DefaultClearance string
+	TrustClearanceHeader bool
+	// JoinBoundaries assigns tables to systems that must never be joined to
+	// each other, as "system=table,table;system=table"
+	JoinBoundaries   string
+	// JoinWeights overrides the mappings' join_weight per relationship, as
+	// "table:table=weight,table:table=weight"; lower weights are preferred
+	JoinWeights      string
+	// MaxJoinHops, MaxColumns, and MaxTables limit how far joins reach from
+	// the FROM table, how many columns are selected, and how many tables are
+	// joined; 0 means no limit. ComplexityAction is what generation does with
+	// a query over them: reject it (the default), or trim its lowest-scoring fields
+	MaxJoinHops      int
+	MaxColumns       int
+	MaxTables        int
+	ComplexityAction string
+	// WideSelectColumns is how many selected columns without a LIMIT draw a
+	// warning, and LowConfidenceScore the match score below which a field is
+	// flagged as a doubtful match; 0 disables either
+	WideSelectColumns  int
+	LowConfidenceScore float64
+	// DefaultLimit is the LIMIT of queries requesting none, and MaxLimit the
+	// most rows any query may ask for; 0 disables either
+	DefaultLimit int
+	MaxLimit     int
+	// MaxAlternatives is how many alternative queries come with a query whose
+	// confidence is at least AlternativesMinConfidence and under
+	// AlternativesMaxConfidence; 0 offers none
+	MaxAlternatives           int
+	AlternativesMinConfidence float64
+	AlternativesMaxConfidence float64
+
+	// Matcher selects how fields are scored: keyword or embedding. The
+	// local EmbeddingProvider is lexical only; semantic matching needs the
+	// http provider
+	Matcher             string
+	EmbeddingProvider   string
+	EmbeddingURL        string
+	EmbeddingModel      string
+	EmbeddingAPIKey     string
+	EmbeddingDimensions int
+
+	// EmbeddingCache persists field embeddings keyed by schema version so
+	// restarts skip re-embedding; they are kept next to the mapping file
+	// unless EmbeddingCacheDir is set
+	EmbeddingCache    bool
+	EmbeddingCacheDir string
+
+	// Ranker is a weighted ensemble of ranking strategies, such as
+	// "keyword:0.7,tfidf:0.3,feedback"; empty derives it from Matcher
+	Ranker string
+
+	// Hybrid matcher weights for the fuzzy and semantic scores
+	HybridFuzzyWeight    float64
+	HybridSemanticWeight float64
+
+	// ParallelScoringMinFields is the catalog size at which field scoring
+	// is sharded across a worker pool
+	ParallelScoringMinFields int
+
+	// Fuzzy keyword expansion: algorithm is none, fuzzysearch, jaro_winkler,
+	// or trigram; the remaining settings tune its sensitivity
+	FuzzyAlgorithm       string
+	FuzzyMaxEditDistance int
+	FuzzyPrefixLength    int
+	FuzzyTopN            int
+	FuzzyMinSimilarity   float64
+
+	// KeywordScoring is binary (hit or miss) or levenshtein (graded by edit
+	// distance up to LevenshteinMaxDistance)
+	KeywordScoring         string
+	LevenshteinMaxDistance int
+
+	// PhoneticAlgorithm is none, soundex, or metaphone; used only when no
+	// field clears the threshold otherwise
+	PhoneticAlgorithm string
+
+	// FeedbackPath persists accepted/rejected statistics (empty keeps them in
+	// memory); FeedbackMaxBoost caps their effect on match scores
+	FeedbackPath     string
+	FeedbackMaxBoost float64
+
+	// RequestBudget bounds the time spent generating a query, split between
+	// field matching and join planning; a phase that runs out returns the
+	// best result found so far. 0 disables it
+	RequestBudget time.Duration
+
+	// MaxDescriptionLength is the hard cap in characters (larger requests get
+	// 413); MaxKeywords bounds how many keywords are matched before the
+	// description is summarized
+	MaxDescriptionLength int
+	MaxKeywords          int
+
+	// MaxInflight* cap concurrent requests per endpoint class (generation,
+	// catalog lookups, feedback, admin); excess requests get 503. Zero is
+	// unlimited
+	MaxInflightGenerate int
+	MaxInflightFields   int
+	MaxInflightFeedback int
+	MaxInflightAdmin    int
+
+	// DbtSource is the dbt source name that dbt models read tables from when
+	// the mappings record no dbt_ref for them
+	DbtSource string
+
+	// AdminToken protects /admin routes, which are closed without one;
+	// PolicyPath points at the role access policy file (YAML or JSON)
+	AdminToken string
+	PolicyPath string
+
+	// GlossarySource is a business glossary file (YAML or JSON) or an
+	// http(s) URL returning JSON
+	GlossarySource string
+
+	// SchemaHistory is how many schema versions are kept in memory;
+	// SchemaHistoryDir, when set, also keeps every version on disk
+	SchemaHistory    int
+	SchemaHistoryDir string
+
+	// Confidence drift alerting: after a reload, the new schema version's
+	// average confidence and no-match rate over the last DriftWindow
+	// requests are compared with the previous version's once DriftMinSamples
+	// have been seen. Degradation beyond DriftConfidenceDrop points or
+	// DriftNoMatchRise is logged and posted to DriftWebhookURL when set
+	DriftWindow         int
+	DriftMinSamples     int
+	DriftConfidenceDrop float64
+	DriftNoMatchRise    float64
+	DriftWebhookURL     string
+
+	// Generation events for analytics: EventSink selects where a structured
+	// event for every generation is published (http or stdout; empty
+	// disables it). Events are buffered up to EventBuffer, dropping the
+	// excess, and sent in batches of EventBatchSize at least every
+	// EventFlushInterval
+	EventSink          string
+	EventSinkURL       string
+	EventBuffer        int
+	EventBatchSize     int
+	EventFlushInterval time.Duration
+
+	// SchemaRefreshInterval re-reads the mapping source on a timer so
+	// instances sharing a remote file pick up changes; 0 disables it
+	SchemaRefreshInterval time.Duration
+	// SchemaCacheMaxAge is how long clients may reuse schema metadata
+	// responses (fields, DDL, relationships) without revalidating; 0 makes
+	// them revalidate with a conditional GET every time
+	SchemaCacheMaxAge time.Duration
+	// S3Endpoint overrides the AWS endpoint for s3:// mapping paths, e.g. for
+	// MinIO; objects are then addressed path style. Requests are signed when
+	// AWS credentials are set and sent anonymously otherwise
+	S3Endpoint         string
+	S3Region           string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+
+	// Incremental matching sessions expire after MatchSessionTTL idle; at
+	// most MaxMatchSessions are cached
+	MatchSessionTTL  time.Duration
+	MaxMatchSessions int
+
+	// TemplatesPath points at saved description templates (YAML or JSON)
+	TemplatesPath string
+
+	// CohortsPath points at saved cohort filters (YAML or JSON)
+	CohortsPath string
+
+	// QueryTemplatesPath points at curated SQL templates (YAML or JSON)
+	QueryTemplatesPath string
+
+	// Chaos mode injects latency and random failures for resilience
+	// testing; never enable it in production
+	ChaosEnabled     bool
+	ChaosMaxLatency  time.Duration
+	ChaosFailureRate float64
+	ChaosStages      string
+	ChaosSeed        int64
 }
 
 // Load loads configuration from environment variables
@@ -32,11 +270,416 @@ func Load() (*Config, error) {
 		maxMatches = 10
 	}
 	
+	// Parse complexity limits, off by default; 0 means no limit
+	maxJoinHops, err := strconv.Atoi(getEnv("MAX_JOIN_HOPS", "0"))
+	if err != nil {
+		maxJoinHops = 0
+	}
+	maxColumns, err := strconv.Atoi(getEnv("MAX_SELECTED_COLUMNS", "0"))
+	if err != nil {
+		maxColumns = 0
+	}
+	maxTables, err := strconv.Atoi(getEnv("MAX_TABLES", "0"))
+	if err != nil {
+		maxTables = 0
+	}
+	
+	// Parse lint thresholds with defaults of 20 columns and a score of 50
+	wideSelectColumns, err := strconv.Atoi(getEnv("WIDE_SELECT_COLUMNS", "20"))
+	if err != nil {
+		wideSelectColumns = 20
+	}
+	lowConfidenceScore, err := strconv.ParseFloat(getEnv("LOW_CONFIDENCE_SCORE", "50"), 64)
+	if err != nil {
+		lowConfidenceScore = 50
+	}
+	
+	// Parse row limits, off by default; 0 means no limit
+	defaultLimit, err := strconv.Atoi(getEnv("DEFAULT_LIMIT", "0"))
+	if err != nil {
+		defaultLimit = 0
+	}
+	maxLimit, err := strconv.Atoi(getEnv("MAX_LIMIT", "0"))
+	if err != nil {
+		maxLimit = 0
+	}
+	
+	// Parse alternatives with defaults of 3 for confidences from 20 to 70
+	maxAlternatives, err := strconv.Atoi(getEnv("MAX_ALTERNATIVES", "3"))
+	if err != nil {
+		maxAlternatives = 3
+	}
+	alternativesMin, err := strconv.ParseFloat(getEnv("ALTERNATIVES_MIN_CONFIDENCE", "20"), 64)
+	if err != nil {
+		alternativesMin = 20
+	}
+	alternativesMax, err := strconv.ParseFloat(getEnv("ALTERNATIVES_MAX_CONFIDENCE", "70"), 64)
+	if err != nil {
+		alternativesMax = 70
+	}
+	
+	// Parse embedding dimensions with default 256
+	dimensionsStr := getEnv("EMBEDDING_DIMENSIONS", "256")
+	dimensions, err := strconv.Atoi(dimensionsStr)
+	if err != nil {
+		dimensions = 256
+	}
+	
+	// Parse embedding cache toggle with default true
+	embeddingCache, err := strconv.ParseBool(getEnv("EMBEDDING_CACHE", "true"))
+	if err != nil {
+		embeddingCache = true
+	}
+	
+	// Parse hybrid weights with an even default split
+	fuzzyWeight, err := strconv.ParseFloat(getEnv("HYBRID_FUZZY_WEIGHT", "0.5"), 64)
+	if err != nil {
+		fuzzyWeight = 0.5
+	}
+	semanticWeight, err := strconv.ParseFloat(getEnv("HYBRID_SEMANTIC_WEIGHT", "0.5"), 64)
+	if err != nil {
+		semanticWeight = 0.5
+	}
+	
+	// Parse parallel scoring cutoff with default 5000
+	parallelMinFields, err := strconv.Atoi(getEnv("PARALLEL_SCORING_MIN_FIELDS", "5000"))
+	if err != nil {
+		parallelMinFields = 5000
+	}
+	
+	// Parse fuzzy matching sensitivity
+	fuzzyMaxEditDistance, err := strconv.Atoi(getEnv("FUZZY_MAX_EDIT_DISTANCE", "2"))
+	if err != nil {
+		fuzzyMaxEditDistance = 2
+	}
+	fuzzyPrefixLength, err := strconv.Atoi(getEnv("FUZZY_PREFIX_LENGTH", "0"))
+	if err != nil {
+		fuzzyPrefixLength = 0
+	}
+	fuzzyTopN, err := strconv.Atoi(getEnv("FUZZY_TOP_N", "3"))
+	if err != nil {
+		fuzzyTopN = 3
+	}
+	fuzzyMinSimilarity, err := strconv.ParseFloat(getEnv("FUZZY_MIN_SIMILARITY", "0.85"), 64)
+	if err != nil {
+		fuzzyMinSimilarity = 0.85
+	}
+	
+	// Parse Levenshtein cutoff with default 1
+	levenshteinMaxDistance, err := strconv.Atoi(getEnv("LEVENSHTEIN_MAX_DISTANCE", "1"))
+	if err != nil {
+		levenshteinMaxDistance = 1
+	}
+	
+	// Parse feedback boost cap with default 20
+	feedbackMaxBoost, err := strconv.ParseFloat(getEnv("FEEDBACK_MAX_BOOST", "20"), 64)
+	if err != nil {
+		feedbackMaxBoost = 20
+	}
+	
+	// Parse description limits with defaults of 20000 characters and 50 keywords
+	maxDescriptionLength, err := strconv.Atoi(getEnv("MAX_DESCRIPTION_LENGTH", "20000"))
+	if err != nil {
+		maxDescriptionLength = 20000
+	}
+	maxKeywords, err := strconv.Atoi(getEnv("MAX_KEYWORDS", "50"))
+	if err != nil {
+		maxKeywords = 50
+	}
+	
+	// Parse per-endpoint concurrency limits with defaults of 32, 64, 16, and 4
+	maxInflightGenerate, err := strconv.Atoi(getEnv("MAX_INFLIGHT_GENERATE", "32"))
+	if err != nil {
+		maxInflightGenerate = 32
+	}
+	maxInflightFields, err := strconv.Atoi(getEnv("MAX_INFLIGHT_FIELDS", "64"))
+	if err != nil {
+		maxInflightFields = 64
+	}
+	maxInflightFeedback, err := strconv.Atoi(getEnv("MAX_INFLIGHT_FEEDBACK", "16"))
+	if err != nil {
+		maxInflightFeedback = 16
+	}
+	maxInflightAdmin, err := strconv.Atoi(getEnv("MAX_INFLIGHT_ADMIN", "4"))
+	if err != nil {
+		maxInflightAdmin = 4
+	}
+	
+	// Parse schema history size with default 5
+	schemaHistory, err := strconv.Atoi(getEnv("SCHEMA_HISTORY", "5"))
+	if err != nil {
+		schemaHistory = 5
+	}
+	
+	// Parse request time budget with default 5s
+	requestBudget, err := time.ParseDuration(getEnv("REQUEST_BUDGET", "5s"))
+	if err != nil {
+		requestBudget = 5 * time.Second
+	}
+	
+	// Parse confidence drift settings with defaults 200, 50, 10 and 0.1
+	driftWindow, err := strconv.Atoi(getEnv("DRIFT_WINDOW", "200"))
+	if err != nil {
+		driftWindow = 200
+	}
+	driftMinSamples, err := strconv.Atoi(getEnv("DRIFT_MIN_SAMPLES", "50"))
+	if err != nil {
+		driftMinSamples = 50
+	}
+	driftConfidenceDrop, err := strconv.ParseFloat(getEnv("DRIFT_CONFIDENCE_DROP", "10"), 64)
+	if err != nil {
+		driftConfidenceDrop = 10
+	}
+	driftNoMatchRise, err := strconv.ParseFloat(getEnv("DRIFT_NO_MATCH_RISE", "0.1"), 64)
+	if err != nil {
+		driftNoMatchRise = 0.1
+	}
+	
+	// Parse generation event settings with defaults 1000, 100 and 5s
+	eventBuffer, err := strconv.Atoi(getEnv("EVENT_BUFFER", "1000"))
+	if err != nil {
+		eventBuffer = 1000
+	}
+	eventBatchSize, err := strconv.Atoi(getEnv("EVENT_BATCH_SIZE", "100"))
+	if err != nil {
+		eventBatchSize = 100
+	}
+	eventFlushInterval, err := time.ParseDuration(getEnv("EVENT_FLUSH_INTERVAL", "5s"))
+	if err != nil {
+		eventFlushInterval = 5 * time.Second
+	}
+	
+	// Parse schema refresh interval with default 0 (disabled)
+	schemaRefreshInterval, err := time.ParseDuration(getEnv("SCHEMA_REFRESH_INTERVAL", "0s"))
+	if err != nil {
+		schemaRefreshInterval = 0
+	}
+	
+	// Parse schema metadata cache lifetime with default 0 (always revalidate)
+	schemaCacheMaxAge, err := time.ParseDuration(getEnv("SCHEMA_CACHE_MAX_AGE", "0s"))
+	if err != nil {
+		schemaCacheMaxAge = 0
+	}
+	
+	// Parse incremental matching session limits with defaults of 10m and 1000
+	matchSessionTTL, err := time.ParseDuration(getEnv("MATCH_SESSION_TTL", "10m"))
+	if err != nil {
+		matchSessionTTL = 10 * time.Minute
+	}
+	maxMatchSessions, err := strconv.Atoi(getEnv("MAX_MATCH_SESSIONS", "1000"))
+	if err != nil {
+		maxMatchSessions = 1000
+	}
+	
+	// Parse the leading-commas style toggle with default off
+	leadingCommas, err := strconv.ParseBool(getEnv("SQL_LEADING_COMMAS", "false"))
+	if err != nil {
+		leadingCommas = false
+	}
+	
+	// Parse whether a gateway sets the clearance header, with default off
+	trustClearanceHeader, err := strconv.ParseBool(getEnv("TRUST_CLEARANCE_HEADER", "false"))
+	if err != nil {
+		trustClearanceHeader = false
+	}
+	
+	// Parse the query cost limit with default 0 (no limit)
+	maxQueryCost, err := strconv.ParseFloat(getEnv("MAX_QUERY_COST", "0"), 64)
+	if err != nil || maxQueryCost < 0 {
+		maxQueryCost = 0
+	}
+	
+	// Parse query execution settings with defaults off, 1000 rows, 1MB, 30s and 1h
+	executeQueries, err := strconv.ParseBool(getEnv("EXECUTE_QUERIES", "false"))
+	if err != nil {
+		executeQueries = false
+	}
+	resultMaxRows, err := strconv.Atoi(getEnv("RESULT_MAX_ROWS", "1000"))
+	if err != nil || resultMaxRows < 1 {
+		resultMaxRows = 1000
+	}
+	resultMaxBytes, err := strconv.Atoi(getEnv("RESULT_MAX_BYTES", "1048576"))
+	if err != nil || resultMaxBytes < 1 {
+		resultMaxBytes = 1048576
+	}
+	statementTimeout, err := time.ParseDuration(getEnv("STATEMENT_TIMEOUT", "30s"))
+	if err != nil || statementTimeout <= 0 {
+		statementTimeout = 30 * time.Second
+	}
+	cursorTTL, err := time.ParseDuration(getEnv("CURSOR_TTL", "1h"))
+	if err != nil || cursorTTL <= 0 {
+		cursorTTL = time.Hour
+	}
+	
+	// Parse connection pool settings with defaults 10, 5 and 30m
+	dbMaxOpenConns, err := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "10"))
+	if err != nil || dbMaxOpenConns < 0 {
+		dbMaxOpenConns = 10
+	}
+	dbMaxIdleConns, err := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "5"))
+	if err != nil || dbMaxIdleConns < 0 {
+		dbMaxIdleConns = 5
+	}
+	dbConnMaxLifetime, err := time.ParseDuration(getEnv("DB_CONN_MAX_LIFETIME", "30m"))
+	if err != nil || dbConnMaxLifetime < 0 {
+		dbConnMaxLifetime = 30 * time.Minute
+	}
+	schemaDriftInterval, err := time.ParseDuration(getEnv("SCHEMA_DRIFT_INTERVAL", "0s"))
+	if err != nil || schemaDriftInterval < 0 {
+		schemaDriftInterval = 0
+	}
+	
+	// Parse chaos mode settings; any parse failure leaves chaos off or at defaults
+	chaosEnabled, err := strconv.ParseBool(getEnv("CHAOS_ENABLED", "false"))
+	if err != nil {
+		chaosEnabled = false
+	}
+	chaosMaxLatency, err := time.ParseDuration(getEnv("CHAOS_MAX_LATENCY", "200ms"))
+	if err != nil {
+		chaosMaxLatency = 200 * time.Millisecond
+	}
+	chaosFailureRate, err := strconv.ParseFloat(getEnv("CHAOS_FAILURE_RATE", "0.1"), 64)
+	if err != nil {
+		chaosFailureRate = 0.1
+	}
+	chaosSeed, err := strconv.ParseInt(getEnv("CHAOS_SEED", "0"), 10, 64)
+	if err != nil {
+		chaosSeed = 0
+	}
+	
 	return &Config{
 		Port:           port,
 		CSVPath:        csvPath,
+		CSVDelimiter:   getEnv("CSV_DELIMITER", ""),
+		CSVEncoding:    getEnv("CSV_ENCODING", ""),
+		SchemaSource:   getEnv("SCHEMA_SOURCE", "csv"),
+		DatabaseURL:    getEnv("DATABASE_URL", ""),
+		DatabaseSchema: getEnv("DATABASE_SCHEMA", ""),
+		DatabaseConnections: getEnv("DATABASE_CONNECTIONS", ""),
+		MaxQueryCost:   maxQueryCost,
+
+		ExecuteQueries:   executeQueries,
+		ResultMaxRows:    resultMaxRows,
+		ResultMaxBytes:   resultMaxBytes,
+		StatementTimeout: statementTimeout,
+		CursorSecret:     getEnv("CURSOR_SECRET", ""),
+		CursorTTL:        cursorTTL,
+
+		DBMaxOpenConns:    dbMaxOpenConns,
+		DBMaxIdleConns:    dbMaxIdleConns,
+		DBConnMaxLifetime: dbConnMaxLifetime,
+		SchemaDriftInterval: schemaDriftInterval,
+
 		MatchThreshold: threshold,
 		MaxMatches:     maxMatches,
+		AliasStyle:     getEnv("ALIAS_STYLE", "first_letter"),
+		TableAliases:   getEnv("TABLE_ALIASES", ""),
+		Dialect:        getEnv("SQL_DIALECT", ""),
+		KeywordCase:    getEnv("SQL_KEYWORD_CASE", ""),
+		LeadingCommas:  leadingCommas,
+		AliasKeyword:   getEnv("SQL_ALIAS_AS", "columns"),
+
+		DefaultQueryType: getEnv("DEFAULT_QUERY_TYPE", "SELECT"),
+		DefaultClearance: getEnv("DEFAULT_CLEARANCE", "internal"),
+		TrustClearanceHeader: trustClearanceHeader,
+		JoinBoundaries:   getEnv("JOIN_BOUNDARIES", ""),
+		JoinWeights:      getEnv("JOIN_WEIGHTS", ""),
+		MaxJoinHops:      maxJoinHops,
+		MaxColumns:       maxColumns,
+		MaxTables:        maxTables,
+		ComplexityAction: getEnv("COMPLEXITY_ACTION", "reject"),
+		WideSelectColumns:  wideSelectColumns,
+		LowConfidenceScore: lowConfidenceScore,
+		DefaultLimit:       defaultLimit,
+		MaxLimit:           maxLimit,
+		MaxAlternatives:           maxAlternatives,
+		AlternativesMinConfidence: alternativesMin,
+		AlternativesMaxConfidence: alternativesMax,
+
+		Matcher:             getEnv("MATCHER", "keyword"),
+		EmbeddingProvider:   getEnv("EMBEDDING_PROVIDER", "local"),
+		EmbeddingURL:        getEnv("EMBEDDING_URL", ""),
+		EmbeddingModel:      getEnv("EMBEDDING_MODEL", ""),
+		EmbeddingAPIKey:     getEnv("EMBEDDING_API_KEY", ""),
+		EmbeddingDimensions: dimensions,
+		EmbeddingCache:      embeddingCache,
+		EmbeddingCacheDir:   getEnv("EMBEDDING_CACHE_DIR", ""),
+
+		Ranker: getEnv("RANKER", ""),
+
+		HybridFuzzyWeight:    fuzzyWeight,
+		HybridSemanticWeight: semanticWeight,
+
+		ParallelScoringMinFields: parallelMinFields,
+
+		FuzzyAlgorithm:       getEnv("FUZZY_ALGORITHM", "none"),
+		FuzzyMaxEditDistance: fuzzyMaxEditDistance,
+		FuzzyPrefixLength:    fuzzyPrefixLength,
+		FuzzyTopN:            fuzzyTopN,
+		FuzzyMinSimilarity:   fuzzyMinSimilarity,
+
+		KeywordScoring:         getEnv("KEYWORD_SCORING", "binary"),
+		LevenshteinMaxDistance: levenshteinMaxDistance,
+
+		PhoneticAlgorithm: getEnv("PHONETIC_ALGORITHM", "none"),
+
+		FeedbackPath:     getEnv("FEEDBACK_PATH", "feedback_stats.json"),
+		FeedbackMaxBoost: feedbackMaxBoost,
+
+		RequestBudget: requestBudget,
+
+		MaxDescriptionLength: maxDescriptionLength,
+		MaxKeywords:          maxKeywords,
+
+		MaxInflightGenerate: maxInflightGenerate,
+		MaxInflightFields:   maxInflightFields,
+		MaxInflightFeedback: maxInflightFeedback,
+		MaxInflightAdmin:    maxInflightAdmin,
+
+		DbtSource: getEnv("DBT_SOURCE", "warehouse"),
+
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+		PolicyPath: getEnv("POLICY_PATH", ""),
+
+		GlossarySource: getEnv("GLOSSARY_SOURCE", ""),
+
+		SchemaHistory:    schemaHistory,
+		SchemaHistoryDir: getEnv("SCHEMA_HISTORY_DIR", ""),
+
+		DriftWindow:         driftWindow,
+		DriftMinSamples:     driftMinSamples,
+		DriftConfidenceDrop: driftConfidenceDrop,
+		DriftNoMatchRise:    driftNoMatchRise,
+		DriftWebhookURL:     getEnv("DRIFT_WEBHOOK_URL", ""),
+
+		EventSink:          getEnv("EVENT_SINK", ""),
+		EventSinkURL:       getEnv("EVENT_SINK_URL", ""),
+		EventBuffer:        eventBuffer,
+		EventBatchSize:     eventBatchSize,
+		EventFlushInterval: eventFlushInterval,
+
+		SchemaRefreshInterval: schemaRefreshInterval,
+		SchemaCacheMaxAge:     schemaCacheMaxAge,
+		S3Endpoint:            getEnv("S3_ENDPOINT", ""),
+		S3Region:              getEnv("AWS_REGION", "us-east-1"),
+		AWSAccessKeyID:        getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey:    getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSSessionToken:       getEnv("AWS_SESSION_TOKEN", ""),
+
+		MatchSessionTTL:  matchSessionTTL,
+		MaxMatchSessions: maxMatchSessions,
+
+		TemplatesPath: getEnv("TEMPLATES_PATH", ""),
+
+		CohortsPath: getEnv("COHORTS_PATH", ""),
+
+		QueryTemplatesPath: getEnv("QUERY_TEMPLATES_PATH", ""),
+
+		ChaosEnabled:     chaosEnabled,
+		ChaosMaxLatency:  chaosMaxLatency,
+		ChaosFailureRate: chaosFailureRate,
+		ChaosStages:      getEnv("CHAOS_STAGES", ""),
+		ChaosSeed:        chaosSeed,
 	}, nil
 }
 
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+)
+
+// SimulatePolicyHandler reports how a role's access policy would treat a description
+func SimulatePolicyHandler(schema *services.LiveSchema, policies *services.PolicySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		if policies == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no access policies are configured"})
+			return
+		}
+
+		var request models.PolicySimulationRequest
+
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		request.Clearance = level
+
+		response, err := service.SimulatePolicy(policies, request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// ReloadHandler re-reads the mapping source and swaps it in. On failure the
+// previous schema stays live and its version is reported
+func ReloadHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		summary, err := schema.Reload()
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":       "Failed to reload mappings: " + err.Error(),
+				"rolled_back": true,
+				"version":     schema.Fields().Version(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, summary)
+	}
+}
+
+// RollbackSchemaHandler makes a prior schema version live again
+func RollbackSchemaHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		summary, err := schema.Rollback(c.Param("version"))
+		if errors.Is(err, services.ErrUnknownSchemaVersion) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "version": schema.Fields().Version()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to roll back mappings: " + err.Error(), "version": schema.Fields().Version()})
+			return
+		}
+
+		c.JSON(http.StatusOK, summary)
+	}
+}
+
+// maxMappingUpload caps the size of an uploaded mapping file
+const maxMappingUpload = 32 << 20
+
+// readUpload reads the multipart "file" field, capped at maxMappingUpload
+func readUpload(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMappingUpload)
+	upload, err := c.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := upload.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// ValidateMappingsHandler checks an uploaded mapping CSV (multipart field
+// "file") as an upload would load it, without activating it
+func ValidateMappingsHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := readUpload(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, schema.Validate(data))
+	}
+}
+
+// UploadMappingsHandler validates an uploaded mapping CSV (multipart field
+// "file") and swaps it in as the active schema
+func UploadMappingsHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := readUpload(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload: " + err.Error()})
+			return
+		}
+
+		// Refuse files with validation errors before touching the live schema
+		if report := schema.Validate(data); !report.Valid {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      services.ErrInvalidMapping.Error(),
+				"version":    schema.Fields().Version(),
+				"validation": report,
+			})
+			return
+		}
+
+		summary, err := schema.Upload(data)
+		if errors.Is(err, services.ErrUploadUnsupported) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMapping) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "version": schema.Fields().Version()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate mappings: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, summary)
+	}
+}
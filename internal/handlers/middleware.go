@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/services"
+)
+
+// TraceIDHeader carries the generation trace ID on requests and responses
+const TraceIDHeader = "X-Trace-ID"
+
+// ClearanceHeader carries the caller's classification clearance. Callers
+// may lower their clearance below the configured default, but only an
+// authenticated caller or a trusted gateway may raise it
+const ClearanceHeader = "X-Clearance"
+
+// traceIDKey is the gin context key holding the current trace ID
+const traceIDKey = "trace_id"
+
+// validTraceID limits caller-supplied IDs to safe, log-friendly values
+var validTraceID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// TraceMiddleware assigns every request a trace ID, reusing a well-formed
+// one supplied by the caller, and echoes it in the response headers
+func TraceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := c.GetHeader(TraceIDHeader)
+		if !validTraceID.MatchString(traceID) {
+			traceID = services.NewTraceID()
+		}
+
+		c.Set(traceIDKey, traceID)
+		c.Header(TraceIDHeader, traceID)
+		c.Next()
+	}
+}
+
+// traceID returns the trace ID assigned to the current request
+func traceID(c *gin.Context) string {
+	return c.GetString(traceIDKey)
+}
+
+// clearance reads the caller's clearance header; empty leaves the
+// configured default in place
+func clearance(c *gin.Context) (string, error) {
+	header := c.GetHeader(ClearanceHeader)
+	if header == "" {
+		return "", nil
+	}
+	return services.ParseClearance(header)
+}
+
+// ClearanceMiddleware refuses requests whose clearance header raises the
+// caller above the configured default unless they carry the admin token or
+// the header is trusted as set by an authenticating gateway. Malformed
+// headers are left to the handlers to reject
+func ClearanceMiddleware(defaultClearance, adminToken string, trustHeader bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested, err := clearance(c)
+		if err != nil || requested == "" || trustHeader || hasAdminToken(c, adminToken) {
+			c.Next()
+			return
+		}
+
+		baseline, err := services.ParseClearance(defaultClearance)
+		if err == nil && services.ClearanceExceeds(requested, baseline) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":    fmt.Sprintf("clearance %s is above the default %s: raising it needs the admin token", requested, baseline),
+				"trace_id": traceID(c),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasAdminToken reports whether a request carries the configured admin
+// token; with none configured, no request does
+func hasAdminToken(c *gin.Context, token string) bool {
+	if token == "" {
+		return false
+	}
+	expected := "Bearer " + token
+	return subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) == 1
+}
+
+// AdminAuthMiddleware requires the admin bearer token on admin routes.
+// Without a configured token admin routes are closed, answering 503
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "admin routes are disabled: set ADMIN_TOKEN to enable them"})
+			return
+		}
+
+		if !hasAdminToken(c, token) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ChaosMiddleware injects latency and random failures into requests when
+// chaos mode is enabled, for testing client retries and circuit breakers
+func ChaosMiddleware(chaos *services.Chaos) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Chaos-Mode", "enabled")
+		if err := chaos.Inject(services.ChaosStageRequest); err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ConcurrencyLimitMiddleware caps the requests in flight through a route
+// group, rejecting the excess immediately with 503 instead of queueing them.
+// A limit of zero or less disables the cap
+func ConcurrencyLimitMiddleware(name string, limit int) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	slots := make(chan struct{}, limit)
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			c.Next()
+		default:
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":    fmt.Sprintf("too many concurrent %s requests", name),
+				"trace_id": traceID(c),
+			})
+		}
+	}
+}
+
+// SchemaCacheMiddleware lets clients cache schema metadata until the schema
+// changes. Responses carry the live version and the caller's clearance as an
+// ETag and the version's load time as Last-Modified, and matching
+// If-None-Match or If-Modified-Since requests get 304 Not Modified. What a
+// caller may see depends on their clearance, so responses are private to
+// the caller and vary on the headers that set it. maxAge is how long clients
+// may reuse a response without asking; zero makes them revalidate every time
+func SchemaCacheMiddleware(schema *services.LiveSchema, maxAge time.Duration) gin.HandlerFunc {
+	cacheControl := "private, no-cache"
+	if maxAge > 0 {
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+	}
+	return func(c *gin.Context) {
+		// Malformed clearances are left to the handlers to reject
+		level, err := clearance(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		version, loadedAt := schema.Loaded()
+		lastModified := loadedAt.UTC().Truncate(time.Second)
+		etag := `"` + version + `"`
+		if level != "" {
+			etag = `"` + version + "-" + level + `"`
+		}
+		c.Header("Cache-Control", cacheControl)
+		c.Header("Vary", "Authorization, "+ClearanceHeader)
+		c.Header("ETag", etag)
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+
+		// If-None-Match takes precedence over If-Modified-Since
+		if match := c.GetHeader("If-None-Match"); match != "" {
+			if etagMatches(match, etag) {
+				c.AbortWithStatus(http.StatusNotModified)
+				return
+			}
+			c.Next()
+			return
+		}
+		if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastModified.After(since) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+		c.Next()
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, compared
+// weakly as conditional GETs allow
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
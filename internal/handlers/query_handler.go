@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,8 +12,9 @@ import (
 )
 
 // GenerateQueryHandler handles the query generation request
-func GenerateQueryHandler(service *services.QueryService) gin.HandlerFunc {
+func GenerateQueryHandler(schema *services.LiveSchema, estimator *services.Estimator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		service := schema.Queries()
 		var request models.QueryRequest
 		
 		// Validate request
@@ -25,30 +28,730 @@ func GenerateQueryHandler(service *services.QueryService) gin.HandlerFunc {
 			request.System = "default"
 		}
 		
+		// Reject unsupported formats before doing any work
+		if err := services.ValidateFormat(request.Format); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		
+		// Link the generation to this request's trace ID
+		request.TraceID = traceID(c)
+		
+		// Withhold columns above the caller's clearance
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		request.Clearance = level
+		
+		// Dry runs and EXPLAIN go to the system's database, so render the
+		// query in its dialect
+		if request.DryRun || request.Explain {
+			request, err = estimator.Target(request)
+			if errors.Is(err, services.ErrNoDatabase) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dry runs and explain need a database: " + err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+				return
+			}
+		}
+		
 		// Generate query
 		startTime := time.Now()
 		response, err := service.GenerateQuery(request)
+		if errors.Is(err, services.ErrWriteIntent) {
+			c.JSON(http.StatusUnprocessableEntity, service.ReadOnlyRefusal(request, err))
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate query: " + err.Error()})
+			queryError(c, err, "Failed to generate query", request.TraceID)
 			return
 		}
 		
+		// Prepare the query on the database when asked, reporting the tables
+		// and columns it names that the database lacks
+		if request.DryRun {
+			result, err := service.DryRun(estimator, response, request)
+			if errors.Is(err, services.ErrNoDatabase) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dry runs need DATABASE_URL: " + err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			if errors.Is(err, services.ErrDryRunFailed) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "trace_id": request.TraceID, "query": response.Query, "dry_run": result})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dry-run query: " + err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			response.DryRun = &result
+		}
+		
+		// EXPLAIN the query when asked, refusing it above the cost limit
+		if request.Explain {
+			cost, err := service.Explain(estimator, response, request)
+			if errors.Is(err, services.ErrNoDatabase) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Explain needs DATABASE_URL: " + err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			if errors.Is(err, services.ErrCostExceeded) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "trace_id": request.TraceID, "query": response.Query, "cost": cost})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to explain query: " + err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			response.Cost = &cost
+		}
+		
 		// Calculate processing time
 		response.ProcessingTime = time.Since(startTime).Milliseconds()
 		
+		// Render a pasteable report when asked for one
+		if request.Format == services.FormatMarkdown || request.Format == services.FormatHTML {
+			report, contentType, err := services.RenderReport(request.Format, response)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			c.Data(http.StatusOK, contentType, []byte(report))
+			return
+		}
+		
+		// Wrap the query in a Go function to paste into a service
+		if services.IsGoFormat(request.Format) {
+			snippet, contentType, err := service.RenderGoSnippet(request.Format, services.GoSnippetQuery{
+				TraceID: response.TraceID,
+				Dialect: request.Dialect,
+				Query:   response.Query,
+				Plan:    response.Plan,
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			c.Data(http.StatusOK, contentType, []byte(snippet))
+			return
+		}
+		
+		// Write the query as a dbt model to drop into a dbt project
+		if request.Format == services.FormatDbt {
+			model, contentType, filename := services.RenderDbtModel(response, service.DbtTarget())
+			c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+			c.Data(http.StatusOK, contentType, []byte(model))
+			return
+		}
+		
+		// Package the query with its provenance as a downloadable bundle
+		if services.IsBundleFormat(request.Format) {
+			bundle, contentType, filename, err := services.RenderBundle(request.Format, response, request.DbtModel, service.DbtTarget())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+			c.Data(http.StatusOK, contentType, bundle)
+			return
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// BuildQueryHandler assembles SQL from a structured intent, skipping
+// natural-language parsing
+func BuildQueryHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		var request models.BuildQueryRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		
+		request.TraceID = traceID(c)
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		request.Clearance = level
+		
+		response, err := service.BuildQuery(request)
+		if err != nil {
+			queryError(c, err, "Failed to build query", request.TraceID)
+			return
+		}
+		
+		// Wrap the query in a Go function binding its parameters
+		if services.IsGoFormat(request.Output) {
+			snippet, contentType, err := service.RenderGoSnippet(request.Output, services.GoSnippetQuery{
+				TraceID:    response.TraceID,
+				Dialect:    request.Dialect,
+				Query:      response.Query,
+				Plan:       response.Plan,
+				Parameters: response.Parameters,
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+				return
+			}
+			c.Data(http.StatusOK, contentType, []byte(snippet))
+			return
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// RenderQueryHandler renders SQL from a query plan, usually one edited
+// since generate-query or build-query returned it
+func RenderQueryHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		var request models.RenderQueryRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		
+		request.TraceID = traceID(c)
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		request.Clearance = level
+		
+		response, err := service.RenderQuery(request)
+		if err != nil {
+			queryError(c, err, "Failed to render query", request.TraceID)
+			return
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// EstimateHandler generates a query and returns the database's estimate of
+// its size without running it
+func EstimateHandler(schema *services.LiveSchema, estimator *services.Estimator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		var request models.QueryRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		if request.System == "" {
+			request.System = "default"
+		}
+		request.TraceID = traceID(c)
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		request.Clearance = level
+		
+		response, err := service.Estimate(estimator, request)
+		if errors.Is(err, services.ErrNoDatabase) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Estimates need DATABASE_URL: " + err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		if errors.Is(err, services.ErrCostExceeded) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "trace_id": request.TraceID, "estimate": response})
+			return
+		}
+		if errors.Is(err, services.ErrWriteIntent) {
+			c.JSON(http.StatusUnprocessableEntity, service.ReadOnlyRefusal(request, err))
+			return
+		}
+		if err != nil {
+			queryError(c, err, "Failed to estimate query", request.TraceID)
+			return
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// ExecuteHandler generates a query and runs it, returning its result up to
+// the configured row and byte caps
+func ExecuteHandler(schema *services.LiveSchema, executor *services.Executor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		var request models.QueryRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		if request.System == "" {
+			request.System = "default"
+		}
+		request.TraceID = traceID(c)
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		request.Clearance = level
+		format, err := services.ResultFormat(request.ResultFormat, c.GetHeader("Accept"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		
+		// Stream CSV and NDJSON rows as they are read; once rows are written,
+		// the outcome is reported in trailers
+		var response models.ExecuteResponse
+		if stream := services.NewResultWriter(format, c.Writer); stream != nil {
+			response, err = service.ExecuteStream(executor, request, &streamedResult{ResultWriter: stream, c: c, contentType: services.ResultContentType(format)})
+			if c.Writer.Written() {
+				finishStream(c, response.QueryResult, err)
+				return
+			}
+		} else {
+			response, err = service.Execute(executor, request)
+		}
+		if errors.Is(err, services.ErrExecutionDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Running queries needs EXECUTE_QUERIES: " + err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		if errors.Is(err, services.ErrNoDatabase) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Running queries needs DATABASE_URL or DATABASE_CONNECTIONS: " + err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		if errors.Is(err, services.ErrWriteIntent) {
+			c.JSON(http.StatusUnprocessableEntity, service.ReadOnlyRefusal(request, err))
+			return
+		}
+		if err != nil {
+			queryError(c, err, "Failed to run query", request.TraceID)
+			return
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// ResultPageHandler returns the page of an executed query's result a
+// cursor points at, running the query again without generating it
+func ResultPageHandler(executor *services.Executor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.ResultPageRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		format, err := services.ResultFormat(request.ResultFormat, c.GetHeader("Accept"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		
+		var result models.QueryResult
+		if stream := services.NewResultWriter(format, c.Writer); stream != nil {
+			result, err = executor.StreamPage(request.Cursor, &streamedResult{ResultWriter: stream, c: c, contentType: services.ResultContentType(format)})
+			if c.Writer.Written() {
+				finishStream(c, result, err)
+				return
+			}
+		} else {
+			result, err = executor.Page(request.Cursor)
+		}
+		if errors.Is(err, services.ErrExecutionDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Running queries needs EXECUTE_QUERIES: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if errors.Is(err, services.ErrNoDatabase) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Running queries needs DATABASE_URL or DATABASE_CONNECTIONS: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if errors.Is(err, services.ErrStatementTimeout) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run query: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		
+		c.JSON(http.StatusOK, models.ResultPageResponse{TraceID: traceID(c), QueryResult: result})
+	}
+}
+
+// SampleValuesHandler returns a handful of a field's distinct values from
+// the database, up to the limit query parameter, read with the names of the
+// system query parameter's system
+func SampleValuesHandler(schema *services.LiveSchema, executor *services.Executor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		request := models.SampleValuesRequest{
+			Table:  c.Param("table"),
+			Column: c.Param("column"),
+			System: c.Query("system"),
+		}
+		if request.System == "" {
+			request.System = "default"
+		}
+		if limit := c.Query("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number", "trace_id": traceID(c)})
+				return
+			}
+			request.Limit = n
+		}
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		request.Clearance = level
+		
+		response, err := service.SampleValues(executor, request)
+		if errors.Is(err, services.ErrExecutionDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Sampling values needs EXECUTE_QUERIES: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if errors.Is(err, services.ErrNoDatabase) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sampling values needs DATABASE_URL or DATABASE_CONNECTIONS: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if err != nil {
+			queryError(c, err, "Failed to sample values", traceID(c))
+			return
+		}
+		
+		response.TraceID = traceID(c)
 		c.JSON(http.StatusOK, response)
 	}
 }
 
+// statusForError is the HTTP status for an error generating, building,
+// rendering, or running a query. Errors it doesn't recognise are ours
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, services.ErrDescriptionTooLong):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrInsufficientClearance), errors.Is(err, services.ErrAggregateOnly):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrUnknownField):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUnknownSchemaVersion), errors.Is(err, services.ErrInvalidTemplate),
+		errors.Is(err, services.ErrTemplateSlot), errors.Is(err, services.ErrInvalidIntent),
+		errors.Is(err, services.ErrInvalidPlan), errors.Is(err, services.ErrInvalidJoinHint),
+		errors.Is(err, services.ErrUnknownTable), errors.Is(err, services.ErrUnknownDialect),
+		errors.Is(err, services.ErrUnknownAliasStyle), errors.Is(err, services.ErrDialectConflict):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrJoinBoundary), errors.Is(err, services.ErrQueryTooComplex),
+		errors.Is(err, services.ErrWriteIntent), errors.Is(err, services.ErrNotSampleable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrBudgetExceeded), errors.Is(err, services.ErrStatementTimeout):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// queryError responds with err and its status from statusForError,
+// prefixing errors that are ours with the failure
+func queryError(c *gin.Context, err error, failure, traceID string) {
+	status := statusForError(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = failure + ": " + message
+	}
+	c.JSON(status, gin.H{"error": message, "trace_id": traceID})
+}
+
+// streamedResult starts a streamed result's response when its columns are
+// known: status, content type, and the trailers reporting its outcome
+type streamedResult struct {
+	services.ResultWriter
+	c           *gin.Context
+	contentType string
+}
+
+func (r *streamedResult) Begin(columns []string) error {
+	r.c.Header("Content-Type", r.contentType)
+	r.c.Header("Trailer", "X-Row-Count, X-Result-Truncated, X-Next-Cursor, X-Result-Error")
+	r.c.Status(http.StatusOK)
+	return r.ResultWriter.Begin(columns)
+}
+
+// finishStream reports a streamed result's outcome in its trailers: the
+// error that stopped it, or its row count, truncation, and next page
+func finishStream(c *gin.Context, result models.QueryResult, err error) {
+	if err != nil {
+		c.Writer.Header().Set("X-Result-Error", err.Error())
+		return
+	}
+	c.Writer.Header().Set("X-Row-Count", strconv.Itoa(result.RowCount))
+	c.Writer.Header().Set("X-Result-Truncated", strconv.FormatBool(result.Truncated))
+	if result.NextCursor != "" {
+		c.Writer.Header().Set("X-Next-Cursor", result.NextCursor)
+	}
+}
+
+// QueryPairHandler generates a description's query for both source
+// systems, with the mapping between their columns, for reconciliation
+func QueryPairHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		var request models.QueryRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		request.TraceID = traceID(c)
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": request.TraceID})
+			return
+		}
+		request.Clearance = level
+		
+		response, err := service.GenerateQueryPair(request)
+		if errors.Is(err, services.ErrWriteIntent) {
+			c.JSON(http.StatusUnprocessableEntity, service.ReadOnlyRefusal(request, err))
+			return
+		}
+		if err != nil {
+			queryError(c, err, "Failed to generate query pair", request.TraceID)
+			return
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// IncrementalMatchHandler matches a description as it is typed, returning
+// only the matches that changed since the session's previous call
+func IncrementalMatchHandler(schema *services.LiveSchema, sessions *services.MatchSessions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		var request models.IncrementalMatchRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		
+		level, err := clearance(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		request.Clearance = level
+		
+		response, err := service.MatchIncremental(sessions, request)
+		if errors.Is(err, services.ErrDescriptionTooLong) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to match fields: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// ListTemplatesHandler lists the saved description templates
+func ListTemplatesHandler(templates *services.TemplateStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"templates": templates.List()})
+	}
+}
+
+// ListSchemaVersionsHandler lists the schema versions queries can target
+func ListSchemaVersionsHandler(versions *services.SchemaVersions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"current":  versions.Current(),
+			"versions": versions.List(),
+		})
+	}
+}
+
+// SchemaQualityHandler reports the live schema version's rolling match
+// quality against the version it replaced, with recent drift alerts
+func SchemaQualityHandler(drift *services.DriftMonitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current, baseline, alerts := drift.Report()
+		c.JSON(http.StatusOK, gin.H{
+			"current":  current,
+			"baseline": baseline,
+			"alerts":   alerts,
+		})
+	}
+}
+
+// SchemaDriftHandler compares the catalog with the database's
+// information_schema now, under the names of the system query parameter's
+// system, reporting columns only one of them has
+func SchemaDriftHandler(drift *services.SchemaDriftMonitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		system := c.Query("system")
+		if system == "" {
+			system = "default"
+		}
+		
+		report, err := drift.Check(system)
+		if errors.Is(err, services.ErrUnknownSystem) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if errors.Is(err, services.ErrNoDatabase) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Checking schema drift needs DATABASE_URL or DATABASE_CONNECTIONS: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check schema drift: " + err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// MappingReportHandler reports the merged mapping files and any conflicting
+// definitions between them
+func MappingReportHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, schema.Fields().MappingReport())
+	}
+}
+
 // ListFieldsHandler returns all available field mappings
-func ListFieldsHandler(service *services.FieldService) gin.HandlerFunc {
+func ListFieldsHandler(schema *services.LiveSchema) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		service := schema.Fields()
 		system := c.Query("system")
 		if system == "" {
 			system = "default"
 		}
 		
 		fields := service.GetAllFields(system)
+		if owner := c.Query("owner"); owner != "" {
+			fields = service.GetFieldsByOwner(fields, owner)
+		}
+		if tag := c.Query("tag"); tag != "" {
+			fields = service.GetFieldsByTag(fields, tag)
+		}
 		c.JSON(http.StatusOK, gin.H{"fields": fields})
 	}
 }
+
+
+// SchemaDDLHandler renders the loaded catalog as CREATE TABLE statements in
+// the dialect named by the dialect query parameter (postgres by default)
+func SchemaDDLHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ddl, err := schema.Fields().RenderDDL(c.Query("dialect"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/sql; charset=utf-8", []byte(ddl))
+	}
+}
+
+// DataDictionaryHandler renders the loaded catalog as a data dictionary in the
+// format named by the format query parameter (markdown by default, or html)
+func DataDictionaryHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		document, contentType, err := schema.Fields().RenderDictionary(c.Query("format"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, contentType, []byte(document))
+	}
+}
+
+// RelationshipGraphHandler returns the tables and relationships joins are
+// planned over, so clients can show how tables will be joined. The format
+// query parameter renders it as Graphviz DOT or Mermaid instead of JSON
+func RelationshipGraphHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.Query("format")
+		if format == "" || format == services.FormatJSON {
+			c.JSON(http.StatusOK, schema.Fields().RelationshipGraph())
+			return
+		}
+		
+		diagram, contentType, err := schema.Fields().RenderRelationshipGraph(format)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, contentType, []byte(diagram))
+	}
+}
+
+// FeedbackHandler records whether a generated query was accepted
+func FeedbackHandler(schema *services.LiveSchema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := schema.Queries()
+		var request models.FeedbackRequest
+		
+		// Validate request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		if request.TraceID == "" {
+			request.TraceID = traceID(c)
+		}
+		
+		err := service.RecordFeedback(request)
+		if errors.Is(err, services.ErrInvalidFeedback) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "trace_id": traceID(c)})
+			return
+		}
+		
+		c.JSON(http.StatusOK, gin.H{"status": "recorded"})
+	}
+}
+
+// HealthHandler reports the service is up, with the use of the default
+// execution connection pool and of every pool by connection name
+func HealthHandler(executor *services.Executor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := gin.H{"status": "ok"}
+		if pools := executor.PoolStats(); pools != nil {
+			if pool, exists := pools[services.DefaultConnection]; exists {
+				body["database_pool"] = pool
+			}
+			body["database_pools"] = pools
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
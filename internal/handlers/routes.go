@@ -8,28 +8,199 @@ import (
 
 // SetupRoutes configures the API routes
 func SetupRoutes(r *gin.Engine, cfg *config.Config) error {
+	// Reject a misconfigured default query type before loading anything
+	if err := services.ValidateQueryType(cfg.DefaultQueryType); err != nil {
+		return err
+	}
+	if _, err := services.ParseClearance(cfg.DefaultClearance); err != nil {
+		return err
+	}
+	if _, err := services.ParseJoinBoundaries(cfg.JoinBoundaries); err != nil {
+		return err
+	}
+	if _, err := services.ParseSQLStyle(cfg.KeywordCase, cfg.LeadingCommas, cfg.AliasKeyword); err != nil {
+		return err
+	}
+	if err := services.ValidateComplexityAction(cfg.ComplexityAction); err != nil {
+		return err
+	}
+	
 	// Load CSV data
 	fieldService, err := services.NewFieldService(cfg)
 	if err != nil {
 		return err
 	}
 	
-	// Create query service
-	queryService := services.NewQueryService(fieldService)
+	// Keep prior schema versions for time-travel queries
+	versions, err := services.NewSchemaVersions(cfg, fieldService)
+	if err != nil {
+		return err
+	}
 	
-	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Load saved description templates, if configured
+	var templates *services.TemplateStore
+	if cfg.TemplatesPath != "" {
+		templates, err = services.LoadTemplates(cfg.TemplatesPath)
+		if err != nil {
+			return err
+		}
+	}
+	
+	// Serve the schema through a holder so it can be reloaded in place
+	schema := services.NewLiveSchema(cfg, fieldService, versions, templates)
+	
+	// Load access policies, if configured; aggregate-only tables apply to
+	// every generated query
+	var policies *services.PolicySet
+	if cfg.PolicyPath != "" {
+		policies, err = services.LoadPolicies(cfg.PolicyPath)
+		if err != nil {
+			return err
+		}
+		schema.UsePolicies(policies)
+	}
+	
+	// Watch match quality for regressions after reloads
+	drift := services.NewDriftMonitor(cfg, fieldService.Version())
+	schema.UseDriftMonitor(drift)
+	
+	// Publish usage events for analytics, if a sink is configured
+	events, err := services.NewEventStream(cfg)
+	if err != nil {
+		return err
+	}
+	schema.UseEventStream(events)
+	
+	// Keep shared (typically remote) mapping sources current
+	if cfg.SchemaRefreshInterval > 0 {
+		go schema.Watch(cfg.SchemaRefreshInterval, nil)
+	}
+	
+	// Tag every request with a trace ID
+	r.Use(TraceMiddleware())
+	
+	// Only authenticated callers may raise their clearance above the default
+	r.Use(ClearanceMiddleware(cfg.DefaultClearance, cfg.AdminToken, cfg.TrustClearanceHeader))
+	
+	// Inject faults into requests in chaos mode (resilience testing only)
+	chaos, err := services.NewChaos(cfg)
+	if err != nil {
+		return err
+	}
+	if chaos != nil {
+		r.Use(ChaosMiddleware(chaos))
+	}
+	
+	// Estimate generated queries against the database, if one is configured
+	estimator, err := services.NewEstimator(cfg)
+	if err != nil {
+		return err
+	}
+	
+	// Run generated queries on the database, if execution is enabled
+	executor, err := services.NewExecutor(cfg)
+	if err != nil {
+		return err
+	}
+	
+	// Compare the catalog with each system's database columns, on demand
+	// and on a timer when configured
+	schemaDrift := services.NewSchemaDriftMonitor(schema, estimator, executor)
+	if cfg.SchemaDriftInterval > 0 {
+		go schemaDrift.Watch(cfg.SchemaDriftInterval, nil)
+	}
+	
+	// Health check, with the execution connection pool's use
+	r.GET("/health", HealthHandler(executor))
+	
+	// Cap in-flight requests per endpoint class so expensive generation
+	// can't starve catalog lookups, feedback, admin operations, or health
+	// checks
+	generateLimit := ConcurrencyLimitMiddleware("generate", cfg.MaxInflightGenerate)
+	fieldsLimit := ConcurrencyLimitMiddleware("fields", cfg.MaxInflightFields)
+	feedbackLimit := ConcurrencyLimitMiddleware("feedback", cfg.MaxInflightFeedback)
+	
+	// Schema metadata is cacheable until the schema changes
+	schemaCache := SchemaCacheMiddleware(schema, cfg.SchemaCacheMaxAge)
 	
 	// API routes
 	api := r.Group("/api/v1")
 	{
 		// Generate query endpoint
-		api.POST("/generate-query", GenerateQueryHandler(queryService))
+		api.POST("/generate-query", generateLimit, GenerateQueryHandler(schema, estimator))
+		
+		// Structured intent endpoint (no natural-language parsing)
+		api.POST("/build-query", generateLimit, BuildQueryHandler(schema))
+		
+		// SQL from an edited query plan, without matching again
+		api.POST("/render-query", generateLimit, RenderQueryHandler(schema))
+		
+		// The same query for system A and system B, for reconciliation
+		api.POST("/generate-query/pair", generateLimit, QueryPairHandler(schema))
+		
+		// Estimated result size of a generated query, without running it
+		api.POST("/estimate", generateLimit, EstimateHandler(schema, estimator))
+		
+		// A generated query's result, within the configured row and byte caps
+		api.POST("/execute", generateLimit, ExecuteHandler(schema, executor))
+		
+		// Later pages of a cut off result, from the cursor it returned
+		api.POST("/execute/page", generateLimit, ResultPageHandler(executor))
+		
+		// Keystroke-frequency matching for autocomplete clients
+		api.POST("/match/incremental", generateLimit, IncrementalMatchHandler(schema, services.NewMatchSessions(cfg)))
 		
 		// List fields endpoint
-		api.GET("/fields", ListFieldsHandler(fieldService))
+		api.GET("/fields", fieldsLimit, schemaCache, ListFieldsHandler(schema))
+		
+		// Distinct values of a field, to phrase filter values as stored
+		api.GET("/fields/:table/:column/samples", fieldsLimit, SampleValuesHandler(schema, executor))
+		
+		// The catalog as CREATE TABLE statements
+		api.GET("/schema/ddl", fieldsLimit, schemaCache, SchemaDDLHandler(schema))
+		
+		// The catalog as a Markdown or HTML data dictionary for analysts
+		api.GET("/schema/dictionary", fieldsLimit, schemaCache, DataDictionaryHandler(schema))
+		
+		// Tables and the relationships joins are planned over
+		api.GET("/relationships", fieldsLimit, schemaCache, RelationshipGraphHandler(schema))
+		
+		// Mapping files and merge diagnostics
+		api.GET("/mappings", fieldsLimit, schemaCache, MappingReportHandler(schema))
+		
+		// Saved description templates
+		api.GET("/templates", fieldsLimit, ListTemplatesHandler(templates))
+		
+		// Schema versions available to schema_version requests
+		api.GET("/schema-versions", fieldsLimit, ListSchemaVersionsHandler(versions))
+		
+		// Rolling match quality of the live schema version and drift alerts
+		api.GET("/schema-quality", fieldsLimit, SchemaQualityHandler(drift))
+		
+		// Mapped columns missing from the database, and unmapped ones
+		api.GET("/schema/drift", fieldsLimit, SchemaDriftHandler(schemaDrift))
+		
+		// Query acceptance feedback endpoint
+		api.POST("/feedback", feedbackLimit, FeedbackHandler(schema))
+	}
+	
+	// Admin routes
+	admin := r.Group("/admin", AdminAuthMiddleware(cfg.AdminToken), ConcurrencyLimitMiddleware("admin", cfg.MaxInflightAdmin))
+	{
+		// Policy simulation endpoint
+		admin.POST("/policies/simulate", SimulatePolicyHandler(schema, policies))
+		
+		// Re-read the mapping source without a restart
+		admin.POST("/reload", ReloadHandler(schema))
+		
+		// Make a prior schema version live again
+		admin.POST("/schema-versions/:version/rollback", RollbackSchemaHandler(schema))
+		
+		// Replace the mapping CSV with an uploaded one
+		admin.POST("/mappings", UploadMappingsHandler(schema))
+		
+		// Check a mapping CSV without activating it
+		admin.POST("/mappings/validate", ValidateMappingsHandler(schema))
 	}
 	
 	return nil
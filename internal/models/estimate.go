@@ -0,0 +1,52 @@
+package models
+
+import "encoding/json"
+
+// TableEstimate is a table's size from the database's statistics
+type TableEstimate struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+	Bytes int64  `json:"bytes"`
+}
+
+// QueryEstimate is the database's estimate of a query's result size and the
+// bytes it would scan, made without running it. Method is "explain" when the
+// planner estimated the rows, or "table_stats" when only table row counts
+// were available. EstimatedCost is the planner's cost (Postgres's total
+// cost, or the rows MySQL expects to examine) and Plan the plan EXPLAIN
+// returned, both only when Method is "explain"
+type QueryEstimate struct {
+	EstimatedRows  int64           `json:"estimated_rows"`
+	EstimatedBytes int64           `json:"estimated_bytes_scanned"`
+	EstimatedCost  float64         `json:"estimated_cost"`
+	Method         string          `json:"method"`
+	Plan           json.RawMessage `json:"plan,omitempty"`
+	Tables         []TableEstimate `json:"tables,omitempty"`
+	Warnings       []string        `json:"warnings,omitempty"`
+}
+
+// EstimateResponse is a generated query with its estimated size
+type EstimateResponse struct {
+	TraceID string `json:"trace_id"`
+	Query   string `json:"query"`
+	QueryEstimate
+}
+
+// DryRunResult is whether the database prepared a generated query. When it
+// didn't, Error is its reason, and MissingTables and MissingColumns list
+// the tables and columns the query names that the database lacks
+type DryRunResult struct {
+	Valid          bool            `json:"valid"`
+	Error          string          `json:"error,omitempty"`
+	MissingTables  []string        `json:"missing_tables,omitempty"`
+	MissingColumns []MissingColumn `json:"missing_columns,omitempty"`
+	Warnings       []string        `json:"warnings,omitempty"`
+}
+
+// MissingColumn is a mapped field (table.column) whose column the database
+// lacks, with the table and column names the query used for it
+type MissingColumn struct {
+	Field  string `json:"field"`
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
@@ -0,0 +1,73 @@
+package models
+
+// QueryResult is the rows a query returned, up to the configured caps.
+// Truncated is set when it returned more, with TruncatedBy naming the cap
+// (max_rows or max_bytes) that cut it off, and NextCursor fetching the
+// rest a page at a time. Bytes is the size of the rows kept, as JSON
+type QueryResult struct {
+	Columns     []string        `json:"columns"`
+	Rows        [][]interface{} `json:"rows"`
+	RowCount    int             `json:"row_count"`
+	Bytes       int             `json:"bytes"`
+	Truncated   bool            `json:"truncated"`
+	TruncatedBy string          `json:"truncated_by,omitempty"`
+	NextCursor  string          `json:"next_cursor,omitempty"`
+	// Connection names the database connection the query ran on
+	Connection string `json:"connection"`
+	ElapsedMs  int64  `json:"elapsed_ms"`
+}
+
+// ExecuteResponse is a generated query with its result
+type ExecuteResponse struct {
+	TraceID  string   `json:"trace_id"`
+	Query    string   `json:"query"`
+	Warnings []string `json:"warnings,omitempty"`
+	QueryResult
+}
+
+// ResultPageRequest asks for the page of a result a cursor points at
+type ResultPageRequest struct {
+	Cursor       string `json:"cursor" binding:"required"`
+	ResultFormat string `json:"result_format,omitempty"`
+}
+
+// ResultPageResponse is one page of an executed query's result
+type ResultPageResponse struct {
+	TraceID string `json:"trace_id"`
+	QueryResult
+}
+
+// PoolStats is a database connection pool's state: its connections open,
+// in use, and idle, and how often callers waited for one or connections
+// were closed for the idle and lifetime limits. MaxOpen is 0 when unlimited
+type PoolStats struct {
+	MaxOpen           int   `json:"max_open"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"in_use"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"wait_count"`
+	WaitDurationMs    int64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// SampleValuesRequest asks for distinct values of a mapped field, read from
+// the system's database
+type SampleValuesRequest struct {
+	Table     string
+	Column    string
+	System    string
+	Clearance string
+	Limit     int
+}
+
+// SampleValuesResponse is a handful of a field's distinct non-null values
+// and the query that read them
+type SampleValuesResponse struct {
+	TraceID    string        `json:"trace_id"`
+	Table      string        `json:"table"`
+	Column     string        `json:"column"`
+	Query      string        `json:"query"`
+	Connection string        `json:"connection"`
+	Values     []interface{} `json:"values"`
+}
@@ -6,11 +6,51 @@ type Field struct {
 	TableName       string
 	SystemAFieldMap string
 	SystemBFieldMap string
+	// SystemATableMap and SystemBTableMap are the field's table as each
+	// system names it; blank means the system uses TableName
+	SystemATableMap string
+	SystemBTableMap string
 	Description     string
 	FieldType       string
 	JoinKey         string
 	ForeignTable    string
 	ForeignKey      string
+	// JoinGroup names a composite foreign key: a table's rows sharing a join
+	// group and foreign table are joined on all their columns together
+	JoinGroup       string
+	// Cardinality of the foreign key from this table to ForeignTable, such
+	// as many_to_one; blank when unknown
+	Cardinality     string
+	// Nullable marks a foreign key column that may be null
+	Nullable        bool
+	// JoinType is "left" when the relationship is optional and joined with
+	// a LEFT JOIN; blank means an inner join
+	JoinType        string
+	// JoinWeight is the relationship's cost in join planning, which takes
+	// the cheapest path; 0 means the default of 1
+	JoinWeight      float64
+	Owner           string
+	OwnerContact    string
+	RefreshCadence  string
+	FreshnessSLA    string
+	Tags            []string
+	Deprecated      bool
+	GlossaryTerm    string
+	Sensitive       bool
+	// TableAlias is the curated alias for the field's table, if any
+	TableAlias      string
+	// SchemaName and DatabaseName qualify the field's table in generated
+	// SQL, e.g. analytics.orders or mydb.dbo.orders; blank leaves it bare
+	SchemaName      string
+	DatabaseName    string
+	// Classification is public, internal, or restricted; blank means internal
+	Classification  string
+	// Measure is the curated aggregate SQL of a semantic-layer measure;
+	// blank for plain columns
+	Measure         string
+	// DbtRef is how a dbt project reads the field's table, such as
+	// ref('fct_orders') or source('shop', 'products'); blank when unknown
+	DbtRef          string
 }
 
 // FieldMatch represents a matched field with score
@@ -19,6 +59,64 @@ type FieldMatch struct {
 	TableName       string  `json:"table_name"`
 	FieldDescription string  `json:"field_description"`
 	MatchScore      float64 `json:"match_score"`
+	ScoreBreakdown  *ScoreBreakdown `json:"score_breakdown,omitempty"`
+	FeedbackBoost   float64         `json:"feedback_boost,omitempty"`
+	MatchedTerms    []MatchedTerm   `json:"matched_terms"`
+	Deprecated      bool            `json:"deprecated,omitempty"`
+	Sensitive       bool            `json:"sensitive,omitempty"`
+	Classification  string          `json:"classification,omitempty"`
+	Measure         string          `json:"measure,omitempty"`
+	Glossary        *GlossaryCitation `json:"glossary,omitempty"`
+}
+
+// GlossaryCitation cites the governed business definition of a field
+type GlossaryCitation struct {
+	TermID     string `json:"term_id"`
+	Label      string `json:"label"`
+	Definition string `json:"definition"`
+}
+
+// MatchedTerm records a keyword that hit part of a field: its description,
+// column name, or a per-system synonym
+type MatchedTerm struct {
+	Keyword string `json:"keyword"`
+	Source  string `json:"source"`
+	Text    string `json:"text"`
+	// Exact is false when the hit came from a fuzzy, phonetic, or edit-distance alternative
+	Exact bool `json:"exact"`
+}
+
+// ScoreBreakdown shows how an ensemble match score was composed. Fuzzy and
+// Semantic carry the keyword and embedding components when present
+type ScoreBreakdown struct {
+	Fuzzy          float64       `json:"fuzzy"`
+	Semantic       float64       `json:"semantic"`
+	FuzzyWeight    float64       `json:"fuzzy_weight"`
+	SemanticWeight float64       `json:"semantic_weight"`
+	Combined       float64       `json:"combined"`
+	Components     []RankerScore `json:"components,omitempty"`
+}
+
+// RankerScore is one ranker's contribution to an ensemble score
+type RankerScore struct {
+	Ranker string  `json:"ranker"`
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+}
+
+// TableOwner identifies the team responsible for a table
+type TableOwner struct {
+	Table   string `json:"table"`
+	Owner   string `json:"owner"`
+	Contact string `json:"contact,omitempty"`
+}
+
+// FreshnessNote warns that a table used in a query is not real-time
+type FreshnessNote struct {
+	Table   string `json:"table"`
+	Cadence string `json:"cadence"`
+	SLA     string `json:"sla,omitempty"`
+	Note    string `json:"note"`
 }
 
 // Join represents a JOIN relationship between tables
@@ -26,20 +124,205 @@ type Join struct {
 	From      string `json:"from"`
 	To        string `json:"to"`
 	Condition string `json:"condition"`
+	// Columns pairs each From column with the To column it equals; composite
+	// keys have several
+	Columns   []JoinColumn `json:"columns,omitempty"`
+	// Cardinality is how many To rows each From row matches, such as
+	// many_to_one; empty when the mappings don't say
+	Cardinality string `json:"cardinality,omitempty"`
+	// Nullable is set when the foreign key column may be null, so an inner
+	// join leaves those rows out
+	Nullable  bool   `json:"nullable,omitempty"`
+	// Type is "left" for a LEFT JOIN; empty means an inner join
+	Type      string `json:"type,omitempty"`
+	// Weight is the relationship's cost in join planning; lower is preferred
+	Weight    float64 `json:"weight"`
+}
+
+// JoinColumn is one column pair of a join condition
+type JoinColumn struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// JoinHint overrides the join planner for one request: join From to To
+// directly, as an inner (default) or left join
+type JoinHint struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+	Type string `json:"type,omitempty"`
 }
 
 // QueryRequest represents the API request for generating a query
 type QueryRequest struct {
-	Description string `json:"description" binding:"required"`
+	Description string `json:"description" binding:"required_without=Template"`
 	System      string `json:"system,omitempty"`
-	Limit       int    `json:"limit,omitempty"`
+	Limit       int    `json:"limit,omitempty" binding:"omitempty,min=0"`
+	AliasStyle  string `json:"alias_style,omitempty" binding:"omitempty,oneof=first_letter abbreviated numeric full"`
+	// Tables and ExcludeTables scope matching to (or away from) tables
+	Tables        []string `json:"tables,omitempty"`
+	ExcludeTables []string `json:"exclude_tables,omitempty"`
+	// IncludeTags and ExcludeTags restrict matching to fields by tag
+	IncludeTags []string `json:"include_tags,omitempty"`
+	ExcludeTags []string `json:"exclude_tags,omitempty"`
+	// IncludeDeprecated lets matching select deprecated fields
+	IncludeDeprecated bool `json:"include_deprecated,omitempty"`
+	// AllowSensitive lets matching select sensitive (PII) fields
+	AllowSensitive bool `json:"allow_sensitive,omitempty"`
+	// MatchThreshold and MaxMatches override the configured matching limits
+	MatchThreshold *float64 `json:"match_threshold,omitempty" binding:"omitempty,gt=0,lte=100"`
+	MaxMatches     int      `json:"max_matches,omitempty" binding:"omitempty,min=1,max=100"`
+	// SchemaVersion generates against a prior mapping version
+	SchemaVersion string `json:"schema_version,omitempty"`
+	// Joins overrides the planned join path; the first hint's source table
+	// becomes the FROM table
+	Joins []JoinHint `json:"joins,omitempty" binding:"dive"`
+	// Template names a saved description template; Variables fill the
+	// {placeholders} in it, or in Description when no template is named
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	// Format selects the response body: json (default), markdown, html, a
+	// dbt model, a Go snippet (go for database/sql, or sqlx), or a zip/tar
+	// bundle; DbtModel adds a dbt model to bundles.
+	// compact is JSON with the query on one line, and pretty JSON with it
+	// laid out a clause per line
+	Format   string `json:"format,omitempty"`
+	DbtModel bool   `json:"dbt_model,omitempty"`
+	// BudgetMs shortens the configured time budget for this request
+	BudgetMs int `json:"budget_ms,omitempty" binding:"omitempty,min=1"`
+	// Dialect renders the query for a database (postgres, mysql, sqlite,
+	// sqlserver, bigquery, snowflake) instead of the configured default;
+	// Offset skips rows, paging with Limit
+	Dialect string `json:"dialect,omitempty"`
+	Offset  int    `json:"offset,omitempty" binding:"omitempty,min=0"`
+	// MaxJoinHops, MaxColumns, and MaxTables tighten the configured
+	// complexity limits for this request
+	MaxJoinHops int `json:"max_join_hops,omitempty" binding:"omitempty,min=1"`
+	MaxColumns  int `json:"max_columns,omitempty" binding:"omitempty,min=1"`
+	MaxTables   int `json:"max_tables,omitempty" binding:"omitempty,min=1"`
+	// SkipTemplates generates the query even when a curated query template
+	// answers the description
+	SkipTemplates bool `json:"skip_templates,omitempty"`
+	// Explain runs EXPLAIN on the generated query, when a database is
+	// configured, returning its plan and cost. MaxCost rejects the query
+	// when that cost exceeds it, lowering the configured limit
+	Explain bool    `json:"explain,omitempty"`
+	MaxCost float64 `json:"max_cost,omitempty" binding:"omitempty,min=0"`
+	// DryRun prepares the generated query on the configured database,
+	// without running it, to confirm the tables and columns it reads exist
+	DryRun bool `json:"dry_run,omitempty"`
+	// ResultFormat is the format of an executed query's result: json, csv,
+	// or ndjson, the latter two streamed; the Accept header picks it when
+	// unset
+	ResultFormat string `json:"result_format,omitempty"`
+	// Alternatives asks for up to this many alternative queries, instead of
+	// the configured number, when the query's confidence is middling
+	Alternatives int `json:"alternatives,omitempty" binding:"omitempty,min=1,max=10"`
+
+	// TraceID is assigned by the server, never read from the request body
+	TraceID string `json:"-"`
+	// Clearance is the caller's classification clearance, also set by the server
+	Clearance string `json:"-"`
 }
 
 // QueryResponse represents the API response with generated SQL
 type QueryResponse struct {
+	TraceID        string       `json:"trace_id"`
+	SchemaVersion  string       `json:"schema_version"`
+	// ExpandedDescription is the description after template expansion
+	ExpandedDescription string `json:"expanded_description,omitempty"`
 	Query          string       `json:"query"`
+	// Fingerprint hashes the query with its literal values stripped, so
+	// semantically identical queries share one
+	Fingerprint    string       `json:"fingerprint"`
+	// Plan is the query's structure, which Query is rendered from
+	Plan           *QueryPlan   `json:"plan,omitempty"`
+	// QueryType is SELECT, COUNT, or GROUP; QueryTypeSource says whether it
+	// was inferred from the description or is the configured default
+	QueryType       string `json:"query_type"`
+	QueryTypeSource string `json:"query_type_source"`
 	MatchedFields  []FieldMatch `json:"matched_fields"`
 	JoinsUsed      []Join       `json:"joins_used"`
+	// JoinCost is the total weight of the joins used
+	JoinCost       float64      `json:"join_cost"`
 	Confidence     float64      `json:"confidence"`
 	ProcessingTime int64        `json:"processing_time_ms"`
+	Owners         []TableOwner `json:"owners,omitempty"`
+	Freshness      []FreshnessNote `json:"freshness,omitempty"`
+	Warnings       []string     `json:"warnings,omitempty"`
+	// SensitiveColumns lists the sensitive table.column fields the query selects
+	SensitiveColumns []string `json:"sensitive_columns,omitempty"`
+	// WithheldColumns lists the matched table.column fields left out because
+	// they are classified above the caller's clearance
+	WithheldColumns []string `json:"withheld_columns,omitempty"`
+	// Cohorts lists the saved filters the description named and the
+	// predicates they expanded to
+	Cohorts []CohortExpansion `json:"cohorts,omitempty"`
+	// Partial is set when matching or join planning ran out of time budget
+	// and the query is built from the best result found so far
+	Partial bool `json:"partial,omitempty"`
+	// Alternatives are other readings of a description the query matched
+	// with middling confidence, best first
+	Alternatives []AlternativeQuery `json:"alternatives,omitempty"`
+	// QueryTemplate is set when a curated query template answered the
+	// description instead of generation
+	QueryTemplate *QueryTemplateUse `json:"query_template,omitempty"`
+	// Cost is the query's EXPLAIN plan and cost, when explain was asked for
+	Cost *QueryEstimate `json:"cost,omitempty"`
+	// DryRun is whether the database prepared the query, when a dry run
+	// was asked for
+	DryRun *DryRunResult `json:"dry_run,omitempty"`
+}
+
+// QueryTemplateUse records the curated query template a query came from:
+// the phrase the description matched and the value of each slot
+type QueryTemplateUse struct {
+	Name   string            `json:"name"`
+	Phrase string            `json:"phrase"`
+	Slots  map[string]string `json:"slots,omitempty"`
+}
+
+// AlternativeQuery is a query built from a different combination of the
+// fields a description matched, as Fields (table.column) lists them
+type AlternativeQuery struct {
+	Query       string   `json:"query"`
+	Fingerprint string   `json:"fingerprint"`
+	Confidence  float64  `json:"confidence"`
+	Fields      []string `json:"fields"`
+	Tables      []string `json:"tables"`
+}
+
+// ReadOnlyRefusal is the error body for a description asking to change
+// data. SuggestedDescription asks for the rows the change would touch, and
+// SuggestedQuery is the SQL generated for it, when any fields matched
+type ReadOnlyRefusal struct {
+	Error                string `json:"error"`
+	TraceID              string `json:"trace_id"`
+	ReadOnly             bool   `json:"read_only"`
+	Operation            string `json:"operation,omitempty"`
+	SuggestedDescription string `json:"suggested_description,omitempty"`
+	SuggestedQuery       string `json:"suggested_query,omitempty"`
+}
+
+// CohortExpansion records a saved cohort applied to a query
+type CohortExpansion struct {
+	Name      string `json:"name"`
+	Mention   string `json:"mention"`
+	Table     string `json:"table"`
+	Predicate string `json:"predicate"`
+}
+
+
+// FieldRef identifies a single field by table and column
+type FieldRef struct {
+	Table  string `json:"table" binding:"required"`
+	Column string `json:"column" binding:"required"`
+}
+
+// FeedbackRequest reports whether a generated query was accepted
+type FeedbackRequest struct {
+	TraceID     string     `json:"trace_id,omitempty"`
+	Description string     `json:"description" binding:"required"`
+	Fields      []FieldRef `json:"fields" binding:"required,min=1,max=50,dive"`
+	Accepted    *bool      `json:"accepted" binding:"required"`
 }
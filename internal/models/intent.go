@@ -0,0 +1,103 @@
+package models
+
+// IntentField is a column to select, optionally aggregated
+type IntentField struct {
+	Table     string `json:"table" binding:"required"`
+	Column    string `json:"column" binding:"required"`
+	Aggregate string `json:"aggregate,omitempty"`
+}
+
+// IntentFilter is a WHERE condition on a column. Value is a scalar, an array
+// for IN, and omitted for IS NULL / IS NOT NULL
+type IntentFilter struct {
+	Table    string      `json:"table" binding:"required"`
+	Column   string      `json:"column" binding:"required"`
+	Operator string      `json:"operator" binding:"required"`
+	Value    interface{} `json:"value,omitempty"`
+}
+
+// IntentOrder sorts by a column, ascending unless Direction is "desc"
+type IntentOrder struct {
+	Table     string `json:"table" binding:"required"`
+	Column    string `json:"column" binding:"required"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// BuildQueryRequest is an explicit, structured query intent that skips
+// natural-language parsing
+type BuildQueryRequest struct {
+	Fields     []IntentField  `json:"fields" binding:"required,min=1,dive"`
+	Filters    []IntentFilter `json:"filters,omitempty" binding:"dive"`
+	GroupBy    []FieldRef     `json:"group_by,omitempty" binding:"dive"`
+	OrderBy    []IntentOrder  `json:"order_by,omitempty" binding:"dive"`
+	Distinct   bool           `json:"distinct,omitempty"`
+	Limit      int            `json:"limit,omitempty" binding:"omitempty,min=0"`
+	AliasStyle string         `json:"alias_style,omitempty" binding:"omitempty,oneof=first_letter abbreviated numeric full"`
+	// Joins overrides the planned join path, as in QueryRequest
+	Joins []JoinHint `json:"joins,omitempty" binding:"dive"`
+	// Dialect and Offset render the query as in QueryRequest
+	Dialect string `json:"dialect,omitempty"`
+	Offset  int    `json:"offset,omitempty" binding:"omitempty,min=0"`
+	// ParameterStyle replaces filter values with placeholders listed in the
+	// response's parameters: dialect (the dialect's own style), dollar ($1),
+	// question (?), named (:p1), or at (@p1). Empty or inline inlines them
+	ParameterStyle string `json:"parameter_style,omitempty"`
+	// Format lays the query out on one line (compact, the default) or a
+	// clause per line (pretty)
+	Format string `json:"format,omitempty"`
+	// Output is json (the default), or go or sqlx for a Go snippet running
+	// the query with database/sql or sqlx. Snippets bind parameters in the
+	// dialect's style unless ParameterStyle says otherwise
+	Output string `json:"output,omitempty"`
+	// MaxJoinHops, MaxColumns, and MaxTables tighten the configured
+	// complexity limits, as in QueryRequest
+	MaxJoinHops int `json:"max_join_hops,omitempty" binding:"omitempty,min=1"`
+	MaxColumns  int `json:"max_columns,omitempty" binding:"omitempty,min=1"`
+	MaxTables   int `json:"max_tables,omitempty" binding:"omitempty,min=1"`
+
+	// TraceID is assigned by the server, never read from the request body
+	TraceID string `json:"-"`
+	// Clearance is the caller's classification clearance, also set by the server
+	Clearance string `json:"-"`
+}
+
+// BuildQueryResponse is the SQL assembled from a structured intent
+type BuildQueryResponse struct {
+	TraceID string `json:"trace_id"`
+	Query   string `json:"query"`
+	// Fingerprint hashes the query with its literal values stripped, as in
+	// QueryResponse
+	Fingerprint string `json:"fingerprint"`
+	// Plan is the query's structure, which Query is rendered from
+	Plan      *QueryPlan `json:"plan,omitempty"`
+	JoinsUsed []Join     `json:"joins_used"`
+	// JoinCost is the total weight of the joins used
+	JoinCost float64 `json:"join_cost"`
+	// SensitiveColumns lists the sensitive table.column fields the intent references
+	SensitiveColumns []string `json:"sensitive_columns,omitempty"`
+	// WithheldColumns lists selected table.column fields left out because
+	// they are classified above the caller's clearance
+	WithheldColumns []string `json:"withheld_columns,omitempty"`
+	// ParameterStyle and Parameters are set for parameterized queries, with
+	// a parameter per placeholder in query order
+	ParameterStyle string           `json:"parameter_style,omitempty"`
+	Parameters     []QueryParameter `json:"parameters,omitempty"`
+	Warnings       []string         `json:"warnings,omitempty"`
+}
+
+// QueryParameter is the value bound to one placeholder of a parameterized
+// query. Name is set for named styles; Value is normalized for the column
+// type, e.g. "yes" on a boolean column is true
+type QueryParameter struct {
+	Position int         `json:"position"`
+	Name     string      `json:"name,omitempty"`
+	Value    interface{} `json:"value"`
+	Type     string      `json:"type,omitempty"`
+}
+
+// DescriptionTemplate is a saved description with {variable} placeholders
+type DescriptionTemplate struct {
+	Name      string   `json:"name"`
+	Template  string   `json:"template"`
+	Variables []string `json:"variables"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// IncrementalMatchRequest is one keystroke's worth of description from an
+// autocomplete client. SessionID continues an earlier session; a trailing
+// partial word is ignored unless Complete is set
+type IncrementalMatchRequest struct {
+	SessionID   string `json:"session_id,omitempty"`
+	Description string `json:"description"`
+	Complete    bool   `json:"complete,omitempty"`
+	MaxMatches  int    `json:"max_matches,omitempty" binding:"omitempty,min=1,max=100"`
+
+	// Clearance is the caller's classification clearance, set by the server
+	Clearance string `json:"-"`
+}
+
+// IncrementalMatchResponse reports how the matches changed since the
+// session's previous revision. Reset is set when the session was new, had
+// expired, or the schema changed, in which case Added holds every match
+type IncrementalMatchResponse struct {
+	SessionID string       `json:"session_id"`
+	Revision  int          `json:"revision"`
+	Reset     bool         `json:"reset,omitempty"`
+	Changed   bool         `json:"changed"`
+	Keywords  []string     `json:"keywords"`
+	Added     []FieldMatch `json:"added,omitempty"`
+	Updated   []FieldMatch `json:"updated,omitempty"`
+	Removed   []FieldRef   `json:"removed,omitempty"`
+	Matches   int          `json:"matches"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// QueryPairResponse is one description generated for both source systems,
+// side by side, for reconciling them. ColumnMap lists every field the
+// queries read with the name each system gives it
+type QueryPairResponse struct {
+	TraceID   string          `json:"trace_id"`
+	SystemA   QueryResponse   `json:"system_a"`
+	SystemB   QueryResponse   `json:"system_b"`
+	ColumnMap []ColumnMapping `json:"column_map"`
+	Warnings  []string        `json:"warnings,omitempty"`
+}
+
+// ColumnMapping is a mapped field with its table.column in each system
+type ColumnMapping struct {
+	Table       string `json:"table"`
+	Column      string `json:"column"`
+	Description string `json:"description,omitempty"`
+	SystemA     string `json:"system_a"`
+	SystemB     string `json:"system_b"`
+}
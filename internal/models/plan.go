@@ -0,0 +1,102 @@
+package models
+
+// QueryPlan is the structure of a generated query, for tools that rework
+// it without parsing SQL. Expressions are SQL in the query's dialect, with
+// the query's aliases; the SQL itself is rendered from the plan. Selected
+// columns, joins, filters, and orders carry IDs derived from what they
+// are, which stay the same when a plan is edited and rendered again
+type QueryPlan struct {
+	Select   []PlanColumn `json:"select"`
+	Distinct bool         `json:"distinct,omitempty"`
+	From     PlanTable    `json:"from"`
+	Joins    []PlanJoin   `json:"joins,omitempty"`
+	// Filters are AND-ed in the WHERE clause
+	Filters []PlanFilter `json:"filters,omitempty"`
+	GroupBy []string     `json:"group_by,omitempty"`
+	Having  string       `json:"having,omitempty"`
+	OrderBy []PlanOrder  `json:"order_by,omitempty"`
+	// Limit and Offset page the rows; 0 means no limit or offset
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+// PlanColumn is a selected expression. Table and Column name the mapped
+// field it reads, blank for COUNT(*); Aggregate is the function applied
+// to it, or MEASURE for a curated measure selected under Alias
+type PlanColumn struct {
+	ID         string `json:"id"`
+	Expression string `json:"expression"`
+	Table      string `json:"table,omitempty"`
+	Column     string `json:"column,omitempty"`
+	Aggregate  string `json:"aggregate,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+}
+
+// PlanTable is a table as the query names it: the mapped table, the name
+// rendered for it (qualified, and the system's own), and its alias, blank
+// when the query refers to it by that name
+type PlanTable struct {
+	Table string `json:"table"`
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// PlanJoin joins a table on a condition; Type is inner or left
+type PlanJoin struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Table     PlanTable `json:"table"`
+	Condition string    `json:"condition"`
+}
+
+// PlanFilter is a WHERE condition comparing a mapped field with Value, or
+// the predicate of the saved cohort it names
+type PlanFilter struct {
+	ID         string      `json:"id"`
+	Expression string      `json:"expression"`
+	Table      string      `json:"table,omitempty"`
+	Column     string      `json:"column,omitempty"`
+	Operator   string      `json:"operator,omitempty"`
+	Value      interface{} `json:"value,omitempty"`
+	Cohort     string      `json:"cohort,omitempty"`
+}
+
+// PlanOrder sorts by an expression, ASC or DESC
+type PlanOrder struct {
+	ID         string `json:"id"`
+	Expression string `json:"expression"`
+	Direction  string `json:"direction"`
+}
+
+// RenderQueryRequest is a plan to render as SQL, typically one returned by
+// generate-query or build-query and edited since. Dialect, AliasStyle, and
+// System should be those the plan was made with, as its group and order
+// expressions are read in their terms
+type RenderQueryRequest struct {
+	Plan       QueryPlan `json:"plan" binding:"required"`
+	Dialect    string    `json:"dialect,omitempty"`
+	AliasStyle string    `json:"alias_style,omitempty" binding:"omitempty,oneof=first_letter abbreviated numeric full"`
+	System     string    `json:"system,omitempty"`
+	// Format lays the query out on one line (compact, the default) or a
+	// clause per line (pretty)
+	Format string `json:"format,omitempty"`
+
+	// TraceID is assigned by the server, never read from the request body
+	TraceID string `json:"-"`
+	// Clearance is the caller's classification clearance, also set by the server
+	Clearance string `json:"-"`
+}
+
+// RenderQueryResponse is the SQL rendered from a plan, with the plan as
+// rendered: expressions, joins, and aliases are rebuilt from the fields
+// it names, and IDs are kept
+type RenderQueryResponse struct {
+	TraceID     string     `json:"trace_id"`
+	Query       string     `json:"query"`
+	Fingerprint string     `json:"fingerprint"`
+	Plan        *QueryPlan `json:"plan"`
+	JoinsUsed   []Join     `json:"joins_used"`
+	// SensitiveColumns lists the sensitive table.column fields the plan references
+	SensitiveColumns []string `json:"sensitive_columns,omitempty"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// PolicySimulationRequest asks how a role's policy would treat a description
+type PolicySimulationRequest struct {
+	Role        string `json:"role" binding:"required"`
+	Description string `json:"description" binding:"required"`
+	// Tables, tags, and the deprecated and sensitive switches filter
+	// matching as they do for generation
+	Tables            []string `json:"tables,omitempty"`
+	ExcludeTables     []string `json:"exclude_tables,omitempty"`
+	IncludeTags       []string `json:"include_tags,omitempty"`
+	ExcludeTags       []string `json:"exclude_tags,omitempty"`
+	IncludeDeprecated bool     `json:"include_deprecated,omitempty"`
+	AllowSensitive    bool     `json:"allow_sensitive,omitempty"`
+	// Clearance is the caller's classification clearance, set by the server
+	Clearance string `json:"-"`
+}
+
+// PolicyDecision is the verdict for a single matched field
+type PolicyDecision struct {
+	ColumnName string  `json:"column_name"`
+	TableName  string  `json:"table_name"`
+	MatchScore float64 `json:"match_score"`
+	Allowed    bool    `json:"allowed"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// PolicySimulationResponse reports which fields a role could use and whether
+// generation would succeed under its policy
+type PolicySimulationResponse struct {
+	Role         string           `json:"role"`
+	Decisions    []PolicyDecision `json:"decisions"`
+	WouldSucceed bool             `json:"would_succeed"`
+	Query        string           `json:"query,omitempty"`
+	Reason       string           `json:"reason,omitempty"`
+}
@@ -0,0 +1,177 @@
+package models
+
+import "time"
+
+// SchemaVersion describes a mapping version that queries can be generated against
+type SchemaVersion struct {
+	Version  string    `json:"version"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Fields   int       `json:"fields,omitempty"`
+	Current  bool      `json:"current"`
+	InMemory bool      `json:"in_memory"`
+}
+
+// SchemaQuality is the rolling match quality of a schema version: average
+// confidence of matched requests and the share that matched nothing
+type SchemaQuality struct {
+	Version           string  `json:"version"`
+	Requests          int     `json:"requests"`
+	AverageConfidence float64 `json:"average_confidence"`
+	NoMatchRate       float64 `json:"no_match_rate"`
+}
+
+// DriftAlert reports a schema version whose match quality degraded against
+// the version it replaced
+type DriftAlert struct {
+	Version         string        `json:"version"`
+	PreviousVersion string        `json:"previous_version"`
+	Quality         SchemaQuality `json:"quality"`
+	Baseline        SchemaQuality `json:"baseline"`
+	Reasons         []string      `json:"reasons"`
+	RaisedAt        time.Time     `json:"raised_at"`
+}
+
+// GenerationEvent is published for every query generation for usage
+// analytics. The description is only sent as a hash so descriptions can be
+// grouped without shipping what users typed
+type GenerationEvent struct {
+	TraceID         string    `json:"trace_id"`
+	Timestamp       time.Time `json:"timestamp"`
+	SchemaVersion   string    `json:"schema_version"`
+	DescriptionHash string    `json:"description_hash"`
+	// Outcome is generated, template, or no_match
+	Outcome    string   `json:"outcome"`
+	QueryType  string   `json:"query_type,omitempty"`
+	Tables     []string `json:"tables"`
+	Confidence float64  `json:"confidence"`
+	LatencyMs  int64    `json:"latency_ms"`
+	Partial    bool     `json:"partial,omitempty"`
+	// Fingerprint is the generated query's, for counting identical queries
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// MappingFile is one mapping file merged into the schema
+type MappingFile struct {
+	Path   string `json:"path"`
+	Fields int    `json:"fields"`
+}
+
+// MergeDiagnostic reports a table.column defined more than once across the
+// mapping files. Conflicts list the attributes the definitions disagree on; the first
+// file's definition is the one used
+type MergeDiagnostic struct {
+	Table      string   `json:"table"`
+	Column     string   `json:"column"`
+	Files      []string `json:"files"`
+	Conflict   bool     `json:"conflict"`
+	Attributes []string `json:"attributes,omitempty"`
+}
+
+// MappingReport summarizes how the mapping files were merged
+type MappingReport struct {
+	Version     string            `json:"version"`
+	Files       []MappingFile     `json:"files"`
+	Fields      int               `json:"fields"`
+	Diagnostics []MergeDiagnostic `json:"diagnostics"`
+}
+
+// ReloadSummary describes a schema reload. Tables and Relationships measure
+// the join graph
+type ReloadSummary struct {
+	PreviousVersion string `json:"previous_version"`
+	Version         string `json:"version"`
+	Changed         bool   `json:"changed"`
+	Fields          int    `json:"fields"`
+	SkippedRows     int    `json:"skipped_rows"`
+	Tables          int    `json:"tables"`
+	Relationships   int    `json:"relationships"`
+	DurationMs      int64  `json:"duration_ms"`
+}
+
+// ValidationIssue is one problem found in a mapping file. Row is the file
+// line number (the header is row 1), or 0 for file-level issues
+type ValidationIssue struct {
+	Row      int    `json:"row,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Column   string `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the result of validating a mapping file without
+// activating it. A file is valid when it has no error-severity issues
+type ValidationReport struct {
+	Valid    bool              `json:"valid"`
+	Rows     int               `json:"rows"`
+	Fields   int               `json:"fields"`
+	Errors   int               `json:"errors"`
+	Warnings int               `json:"warnings"`
+	Issues   []ValidationIssue `json:"issues"`
+}
+
+// Finish tallies the issues by severity and decides validity
+func (r ValidationReport) Finish() ValidationReport {
+	r.Errors, r.Warnings = 0, 0
+	for _, issue := range r.Issues {
+		if issue.Severity == "error" {
+			r.Errors++
+		} else {
+			r.Warnings++
+		}
+	}
+	r.Valid = r.Errors == 0
+	return r
+}
+
+// RelationshipGraph is the join graph queries are planned over. Edges hold
+// each relationship once, in the direction of its foreign key; queries may
+// walk them either way. Components groups the tables that can be joined to
+// each other, largest first
+type RelationshipGraph struct {
+	Version    string             `json:"version"`
+	Nodes      []RelationshipNode `json:"nodes"`
+	Edges      []Join             `json:"edges"`
+	Components [][]string         `json:"components"`
+}
+
+// RelationshipNode is one table of the relationship graph. Component is the
+// index of its group in Components; Relationships counts its edges
+type RelationshipNode struct {
+	Table         string `json:"table"`
+	Fields        int    `json:"fields"`
+	Relationships int    `json:"relationships"`
+	Component     int    `json:"component"`
+}
+
+// SchemaCheck is the self-check of a loaded schema: the load itself, the
+// relationships between tables, and how the join graph splits into groups
+// of tables that can be joined to each other. Components lists those groups
+type SchemaCheck struct {
+	ValidationReport
+	Version       string     `json:"version,omitempty"`
+	Tables        int        `json:"tables"`
+	Relationships int        `json:"relationships"`
+	Components    [][]string `json:"components"`
+	Connected     bool       `json:"connected"`
+}
+
+// SchemaDriftReport compares the loaded catalog with the database's
+// information_schema, under a system's physical names. MissingTables and
+// MissingColumns are mapped but absent from the database; UnmappedColumns
+// are columns of mapped tables the catalog doesn't map
+type SchemaDriftReport struct {
+	Version         string           `json:"version"`
+	System          string           `json:"system"`
+	CheckedAt       time.Time        `json:"checked_at"`
+	Drifted         bool             `json:"drifted"`
+	MissingTables   []string         `json:"missing_tables,omitempty"`
+	MissingColumns  []MissingColumn  `json:"missing_columns,omitempty"`
+	UnmappedColumns []DatabaseColumn `json:"unmapped_columns,omitempty"`
+}
+
+// DatabaseColumn is a column as information_schema lists it, lowercased
+type DatabaseColumn struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Supported table alias styles
+const (
+	AliasStyleFirstLetter = "first_letter"
+	AliasStyleAbbreviated = "abbreviated"
+	AliasStyleNumeric     = "numeric"
+	AliasStyleFull        = "full"
+)
+
+// ErrUnknownAliasStyle is returned for an unsupported table alias style
+var ErrUnknownAliasStyle = errors.New("unknown alias style")
+
+// validAlias limits curated aliases to plain SQL identifiers
+var validAlias = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// aliasAllocator hands out unique table aliases for a single query
+type aliasAllocator struct {
+	style     string
+	preferred map[string]string
+	locations map[string]tableLocation
+	aliases   map[string]string
+	used      map[string]bool
+	// names renders tables and columns by a system's physical names; nil
+	// keeps the mapped names. Aliases always derive from the mapped names
+	names *systemNames
+}
+
+// newAliasAllocator creates an allocator for the given alias style. Preferred
+// aliases curated in the mappings or configuration win over the style,
+// except numeric and full, which alias every table by its name
+func newAliasAllocator(style string, preferred map[string]string, locations map[string]tableLocation) (*aliasAllocator, error) {
+	if style == "" {
+		style = AliasStyleFirstLetter
+	}
+
+	switch style {
+	case AliasStyleFirstLetter, AliasStyleAbbreviated, AliasStyleNumeric, AliasStyleFull:
+	default:
+		return nil, fmt.Errorf("%w %q: use first_letter, abbreviated, numeric, or full", ErrUnknownAliasStyle, style)
+	}
+
+	return &aliasAllocator{
+		style:     style,
+		preferred: preferred,
+		locations: locations,
+		aliases:   make(map[string]string),
+		used:      make(map[string]bool),
+	}, nil
+}
+
+// aliasFor returns the alias for a table, allocating one on first use.
+// Aliases are stable for the lifetime of the allocator and never collide.
+func (a *aliasAllocator) aliasFor(table string) string {
+	if alias, exists := a.aliases[table]; exists {
+		return alias
+	}
+
+	var base string
+	switch {
+	case a.style == AliasStyleNumeric:
+		base = fmt.Sprintf("t%d", len(a.aliases)+1)
+	case a.style == AliasStyleFull:
+		base = table
+	case a.preferred[table] != "":
+		base = a.preferred[table]
+	case a.style == AliasStyleAbbreviated:
+		base = abbreviateTableName(table)
+	default:
+		base = strings.ToLower(table[0:1])
+	}
+
+	// Append a numeric suffix until the alias is unique
+	alias := base
+	for i := 2; a.used[alias]; i++ {
+		alias = fmt.Sprintf("%s%d", base, i)
+	}
+
+	a.aliases[table] = alias
+	a.used[alias] = true
+	return alias
+}
+
+// abbreviateTableName builds a short alias from a table name, using the
+// initials of multi-word names (order_items -> oi) and the leading
+// consonant skeleton of single words (users -> usr)
+func abbreviateTableName(table string) string {
+	parts := strings.FieldsFunc(strings.ToLower(table), func(r rune) bool {
+		return r == '_' || r == '.' || r == '-'
+	})
+	if len(parts) == 0 {
+		return "t"
+	}
+
+	if len(parts) > 1 {
+		var initials strings.Builder
+		for _, part := range parts {
+			initials.WriteByte(part[0])
+		}
+		return initials.String()
+	}
+
+	word := parts[0]
+	if len(word) <= 3 {
+		return word
+	}
+
+	// Keep the first letter and following consonants, up to three characters
+	abbrev := []byte{word[0]}
+	for i := 1; i < len(word) && len(abbrev) < 3; i++ {
+		if !strings.ContainsRune("aeiou", rune(word[i])) {
+			abbrev = append(abbrev, word[i])
+		}
+	}
+	return string(abbrev)
+}
+
+// assign allocates aliases in the order tables appear in FROM and JOIN
+// clauses, so the columns referencing them can be rendered first
+func (a *aliasAllocator) assign(root string, joins []models.Join) {
+	a.aliasFor(root)
+	for _, join := range joins {
+		a.aliasFor(join.To)
+	}
+}
+
+// tablePlan describes a FROM or JOIN table: its name qualified by schema
+// and database, and its alias, left out when it is the name the table is
+// rendered by
+func (a *aliasAllocator) tablePlan(dialect, table string) models.PlanTable {
+	physical := a.names.table(table)
+	plan := models.PlanTable{Table: table, Name: qualifiedTable(dialect, physical, a.locations[table])}
+	if alias := a.aliasFor(table); alias != physical {
+		plan.Alias = alias
+	}
+	return plan
+}
+
+// column renders a column qualified by its table's alias
+func (a *aliasAllocator) column(dialect, table, column string) string {
+	return columnRef(dialect, a.aliasFor(table), a.names.column(table, column))
+}
+
+// ParseTableAliases parses configured aliases, "table=alias,table=alias",
+// which override the mappings' table_alias
+func ParseTableAliases(spec string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		table, alias, found := strings.Cut(entry, "=")
+		table, alias = strings.TrimSpace(table), strings.TrimSpace(alias)
+		if !found || table == "" || !validAlias.MatchString(alias) {
+			return nil, fmt.Errorf("invalid table alias %q: use table=alias with a plain SQL identifier", entry)
+		}
+		aliases[table] = alias
+	}
+	return aliases, nil
+}
+
+// buildTableAliases collects the curated alias for each table. Configured
+// aliases win, then the first valid alias defined for a table
+func (s *FieldService) buildTableAliases(configured map[string]string) {
+	s.tableAliases = make(map[string]string)
+	for table, alias := range configured {
+		s.tableAliases[table] = alias
+	}
+	for _, field := range s.fields {
+		if field.TableAlias == "" {
+			continue
+		}
+		if !validAlias.MatchString(field.TableAlias) {
+			s.log.Warnf("Ignoring invalid alias %q for table %s", field.TableAlias, field.TableName)
+			continue
+		}
+		if existing, exists := s.tableAliases[field.TableName]; exists {
+			if _, overridden := configured[field.TableName]; !overridden && !strings.EqualFold(existing, field.TableAlias) {
+				s.log.Warnf("Table %s has conflicting aliases %q and %q; using %q", field.TableName, existing, field.TableAlias, existing)
+			}
+			continue
+		}
+		s.tableAliases[field.TableName] = field.TableAlias
+	}
+}
+
+// TableAliases returns the curated alias for each table that has one
+func (s *FieldService) TableAliases() map[string]string {
+	return s.tableAliases
+}
+
+// sqlStringLiteral matches a single-quoted SQL string, which predicate
+// rewriting leaves alone
+var sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// qualifyPredicate rewrites the table-qualified columns of hand-written SQL,
+// such as a cohort predicate or a measure, to use the query's aliases,
+// quoted as the dialect needs
+func (a *aliasAllocator) qualifyPredicate(dialect, predicate string, tables []string) string {
+	rewrite := func(segment string) string {
+		for _, table := range tables {
+			alias := a.aliasFor(table)
+			if alias == table {
+				continue
+			}
+			pattern := regexp.MustCompile(`(^|[^\w."])` + regexp.QuoteMeta(table) + `\.`)
+			segment = pattern.ReplaceAllString(segment, "${1}"+quoteIdent(dialect, alias)+".")
+		}
+		return segment
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, literal := range sqlStringLiteral.FindAllStringIndex(predicate, -1) {
+		b.WriteString(rewrite(predicate[last:literal[0]]))
+		b.WriteString(predicate[literal[0]:literal[1]])
+		last = literal[1]
+	}
+	b.WriteString(rewrite(predicate[last:]))
+	return b.String()
+}
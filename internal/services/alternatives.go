@@ -0,0 +1,122 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// maxAlternatives caps how many alternatives a request may ask for
+const maxAlternatives = 10
+
+// alternativeCount returns how many alternative queries to offer for a
+// query of a confidence: none unless it falls in the configured middling
+// band, otherwise the requested count or the configured one
+func (s *QueryService) alternativeCount(requested int, confidence float64) int {
+	count := s.cfg.MaxAlternatives
+	if requested > 0 {
+		count = min(requested, maxAlternatives)
+	}
+	if count <= 0 || confidence < s.cfg.AlternativesMinConfidence || confidence >= s.cfg.AlternativesMaxConfidence {
+		return 0
+	}
+	return count
+}
+
+// alternativeFieldSets proposes other field combinations for a description:
+// each runner-up swapped in for the weakest match, then each table of the
+// matches left out in turn. Matches are ordered best first
+func alternativeFieldSets(matches, runnersUp []models.FieldMatch) [][]models.FieldMatch {
+	var sets [][]models.FieldMatch
+	if len(matches) > 0 {
+		kept := matches[:len(matches)-1]
+		for _, runnerUp := range runnersUp {
+			set := append(append([]models.FieldMatch{}, kept...), runnerUp)
+			sets = append(sets, set)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		if seen[match.TableName] {
+			continue
+		}
+		seen[match.TableName] = true
+		if set := withoutTable(matches, match.TableName); len(set) > 0 {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// rankAlternatives keeps the best count alternatives by confidence, leaving
+// out ones rendering the same query as the primary or an earlier alternative
+func rankAlternatives(alternatives []models.AlternativeQuery, primary string, count int) []models.AlternativeQuery {
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		return alternatives[i].Confidence > alternatives[j].Confidence
+	})
+	seen := map[string]bool{primary: true}
+	var ranked []models.AlternativeQuery
+	for _, alternative := range alternatives {
+		if len(ranked) == count {
+			break
+		}
+		if seen[alternative.Fingerprint] {
+			continue
+		}
+		seen[alternative.Fingerprint] = true
+		ranked = append(ranked, alternative)
+	}
+	return ranked
+}
+
+// alternativeFields lists the table.column fields of an alternative
+func alternativeFields(matches []models.FieldMatch) []string {
+	fields := make([]string, len(matches))
+	for i, match := range matches {
+		fields[i] = fieldKey(match.TableName, match.ColumnName)
+	}
+	return fields
+}
+
+// runnersUp returns the candidates that aren't among the matches
+func runnersUp(candidates, matches []models.FieldMatch) []models.FieldMatch {
+	matched := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		matched[fieldKey(match.TableName, match.ColumnName)] = true
+	}
+	var rest []models.FieldMatch
+	for _, candidate := range candidates {
+		if !matched[fieldKey(candidate.TableName, candidate.ColumnName)] {
+			rest = append(rest, candidate)
+		}
+	}
+	return rest
+}
+
+// alternativeQuery builds an alternative from a set of fields as the primary
+// query was built, reporting false for sets that can't be joined, go over
+// the complexity limits, or fail the read-only check
+func (s *QueryService) alternativeQuery(request models.QueryRequest, set []models.FieldMatch, queryType string, distinct bool, dialect string, cohorts []models.CohortExpansion, joinHints []models.JoinHint, limits complexityLimits) (models.AlternativeQuery, bool) {
+	aliases, err := s.queryAliases(request.AliasStyle, request.System)
+	if err != nil {
+		return models.AlternativeQuery{}, false
+	}
+	plan, joins, err := s.buildSQLQuery(set, queryType, distinct, request.Limit, request.Offset, dialect, aliases, cohorts, joinHints, nil)
+	if err != nil || limits.exceeded(plan, joins) != "" {
+		return models.AlternativeQuery{}, false
+	}
+	s.enforceRowLimit(&plan)
+	rendered := renderPlan(dialect, plan)
+	query := formatSQL(rendered, dialect, request.Format, s.style)
+	if checkReadOnly(query, dialect) != nil {
+		return models.AlternativeQuery{}, false
+	}
+	return models.AlternativeQuery{
+		Query:       query,
+		Fingerprint: queryFingerprint(rendered, dialect),
+		Confidence:  s.calculateConfidence(set),
+		Fields:      alternativeFields(set),
+		Tables:      tablesUsed(set, joins),
+	}, true
+}
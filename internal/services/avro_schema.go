@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// avroMagic starts every Avro object container file
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+// avroSchema is the part of an Avro schema the importer reads. Type is a
+// primitive name, a nested schema, or a union of either
+type avroSchema struct {
+	Type        json.RawMessage `json:"type"`
+	Name        string          `json:"name"`
+	Doc         string          `json:"doc"`
+	LogicalType string          `json:"logicalType"`
+	Fields      []struct {
+		Name string          `json:"name"`
+		Doc  string          `json:"doc"`
+		Type json.RawMessage `json:"type"`
+	} `json:"fields"`
+}
+
+// avroTypes maps Avro primitive types to column types
+var avroTypes = map[string]string{
+	"string":  "VARCHAR",
+	"int":     "INTEGER",
+	"long":    "BIGINT",
+	"float":   "REAL",
+	"double":  "DOUBLE",
+	"boolean": "BOOLEAN",
+	"bytes":   "BINARY",
+	"fixed":   "BINARY",
+	"enum":    "VARCHAR",
+	"record":  "STRUCT",
+	"array":   "ARRAY",
+	"map":     "MAP",
+}
+
+// avroLogicalTypes maps Avro logical types to column types
+var avroLogicalTypes = map[string]string{
+	"date":                   "DATE",
+	"time-millis":            "TIME",
+	"time-micros":            "TIME",
+	"timestamp-millis":       "TIMESTAMP",
+	"timestamp-micros":       "TIMESTAMP",
+	"local-timestamp-millis": "TIMESTAMP",
+	"local-timestamp-micros": "TIMESTAMP",
+	"decimal":                "DECIMAL",
+	"uuid":                   "VARCHAR",
+}
+
+// readAvroSchema imports a table from an Avro schema (.avsc), named after its
+// record, or from the schema embedded in an Avro data file (.avro), named
+// after the file. Fields keep their doc as a description when they have one
+func readAvroSchema(path string, data []byte) (SchemaDefinition, error) {
+	table := ""
+	if bytes.HasPrefix(data, avroMagic) {
+		embedded, err := avroContainerSchema(data)
+		if err != nil {
+			return SchemaDefinition{}, err
+		}
+		data, table = embedded, lakeTableName(path)
+	}
+
+	var schema avroSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return SchemaDefinition{}, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	if avroTypeName(schema.Type) != "record" {
+		return SchemaDefinition{}, fmt.Errorf("Avro schema must be a record, not %s", avroTypeName(schema.Type))
+	}
+	if table == "" {
+		table = schema.Name[strings.LastIndex(schema.Name, ".")+1:]
+	}
+	if table == "" {
+		table = lakeTableName(path)
+	}
+
+	definition := TableDefinition{Name: table}
+	for _, field := range schema.Fields {
+		definition.Columns = append(definition.Columns, ColumnDefinition{
+			Name:        field.Name,
+			Description: field.Doc,
+			Type:        avroColumnType(field.Type),
+		})
+	}
+	if len(definition.Columns) == 0 {
+		return SchemaDefinition{}, fmt.Errorf("Avro record %s has no fields", table)
+	}
+	return SchemaDefinition{Tables: []TableDefinition{definition}}, nil
+}
+
+// avroColumnType maps an Avro field type to a column type. Nullable unions
+// take the type of their non-null branch
+func avroColumnType(raw json.RawMessage) string {
+	var union []json.RawMessage
+	if json.Unmarshal(raw, &union) == nil {
+		for _, branch := range union {
+			if avroTypeName(branch) != "null" {
+				return avroColumnType(branch)
+			}
+		}
+		return ""
+	}
+
+	var nested avroSchema
+	if json.Unmarshal(raw, &nested) == nil {
+		if columnType, ok := avroLogicalTypes[nested.LogicalType]; ok {
+			return columnType
+		}
+		return avroColumnType(nested.Type)
+	}
+	return avroTypes[avroTypeName(raw)]
+}
+
+// avroTypeName returns a type's primitive or complex type name
+func avroTypeName(raw json.RawMessage) string {
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		return name
+	}
+	var nested struct {
+		Type json.RawMessage `json:"type"`
+	}
+	if json.Unmarshal(raw, &nested) == nil && len(nested.Type) > 0 {
+		return avroTypeName(nested.Type)
+	}
+	return ""
+}
+
+// avroContainerSchema reads the writer schema from an Avro data file's
+// header metadata
+func avroContainerSchema(data []byte) ([]byte, error) {
+	reader := bytes.NewReader(data[len(avroMagic):])
+	for {
+		count, err := binary.ReadVarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Avro header: %w", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("Avro file has no avro.schema metadata")
+		}
+		// Negative block counts are followed by the block's size in bytes
+		if count < 0 {
+			count = -count
+			if _, err := binary.ReadVarint(reader); err != nil {
+				return nil, fmt.Errorf("invalid Avro header: %w", err)
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			key, err := avroBytes(reader)
+			if err != nil {
+				return nil, err
+			}
+			value, err := avroBytes(reader)
+			if err != nil {
+				return nil, err
+			}
+			if string(key) == "avro.schema" {
+				return value, nil
+			}
+		}
+	}
+}
+
+// avroBytes reads a length-prefixed Avro string or bytes value
+func avroBytes(reader *bytes.Reader) ([]byte, error) {
+	length, err := binary.ReadVarint(reader)
+	if err != nil || length < 0 || length > int64(reader.Len()) {
+		return nil, fmt.Errorf("invalid Avro header metadata")
+	}
+	value := make([]byte, length)
+	_, err = reader.Read(value)
+	return value, err
+}
+
+// lakeTableName names a table after its data file: orders.parquet is orders
+func lakeTableName(path string) string {
+	name := filepath.Base(path)
+	if isRemotePath(path) {
+		name = filepath.Base(strings.TrimSuffix(displayLocation(path), "/"))
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(name))
+}
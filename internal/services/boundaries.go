@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrJoinBoundary is returned when a query would join tables of systems
+// configured never to be joined
+var ErrJoinBoundary = errors.New("query spans systems that are never joined")
+
+// JoinBoundaries maps tables to the system they belong to; tables in
+// different systems are never joined
+type JoinBoundaries map[string]string
+
+// ParseJoinBoundaries parses "system=table,table;system=table". An empty
+// spec means no boundaries
+func ParseJoinBoundaries(spec string) (JoinBoundaries, error) {
+	boundaries := make(JoinBoundaries)
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		system, tables, found := strings.Cut(entry, "=")
+		system = strings.TrimSpace(system)
+		if !found || system == "" {
+			return nil, fmt.Errorf("invalid join boundary %q: use system=table,table", entry)
+		}
+		for _, table := range strings.Split(tables, ",") {
+			table = strings.TrimSpace(table)
+			if table == "" {
+				continue
+			}
+			if other, exists := boundaries[table]; exists && other != system {
+				return nil, fmt.Errorf("invalid join boundaries: table %s is in both %s and %s", table, other, system)
+			}
+			boundaries[table] = system
+		}
+	}
+	return boundaries, nil
+}
+
+// check refuses a set of tables that spans more than one system, explaining
+// which tables belong to which system and how to split the request. subject
+// says where the tables came from
+func (b JoinBoundaries) check(subject string, tables []string) error {
+	systems := make(map[string][]string)
+	for _, table := range tables {
+		if system, exists := b[table]; exists && !containsString(systems[system], table) {
+			systems[system] = append(systems[system], table)
+		}
+	}
+	if len(systems) < 2 {
+		return nil
+	}
+
+	names := make([]string, 0, len(systems))
+	for system := range systems {
+		names = append(names, system)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, system := range names {
+		sort.Strings(systems[system])
+		parts[i] = fmt.Sprintf("%s (%s)", system, strings.Join(systems[system], ", "))
+	}
+	return fmt.Errorf("%w: %s %s, which must not be joined; "+
+		"run a separate query for each system's fields instead", ErrJoinBoundary, subject, strings.Join(parts, " and "))
+}
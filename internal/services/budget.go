@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Share of the request budget, from the start of the request, by which field
+// matching and join planning must finish. Time one phase leaves unused
+// carries over to the next; the rest is kept for rendering the query
+const (
+	matchingBudgetShare     = 0.6
+	joinPlanningBudgetShare = 0.9
+)
+
+// budgetCheckInterval is how many fields are scored between deadline checks.
+// The first batch is always scored, so even an exhausted budget yields
+// something to build from
+const budgetCheckInterval = 256
+
+// ErrBudgetExceeded is returned when a request ran out of time before
+// anything usable was found
+var ErrBudgetExceeded = errors.New("request time budget exceeded")
+
+// requestBudget holds the phase deadlines of one request and records what
+// was cut short to meet them. A nil budget never runs out
+type requestBudget struct {
+	matching time.Time
+	joins    time.Time
+	partial  bool
+	notes    []string
+	dropped  map[string]bool
+}
+
+// newRequestBudget starts the budget for a request that began at start. The
+// request may ask for less time than configured, never more
+func (s *QueryService) newRequestBudget(start time.Time, requestMs int) *requestBudget {
+	total := s.cfg.RequestBudget
+	if requested := time.Duration(requestMs) * time.Millisecond; requested > 0 && (total <= 0 || requested < total) {
+		total = requested
+	}
+	if total <= 0 {
+		return nil
+	}
+	return &requestBudget{
+		matching: start.Add(time.Duration(float64(total) * matchingBudgetShare)),
+		joins:    start.Add(time.Duration(float64(total) * joinPlanningBudgetShare)),
+	}
+}
+
+// matchingDeadline is when scoring must stop; zero for no budget
+func (b *requestBudget) matchingDeadline() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	return b.matching
+}
+
+// joinsExpired reports whether join planning has used up its share
+func (b *requestBudget) joinsExpired() bool {
+	return b != nil && time.Now().After(b.joins)
+}
+
+// cut marks the result partial, explaining what was left out
+func (b *requestBudget) cut(note string) {
+	b.partial = true
+	b.notes = append(b.notes, note)
+}
+
+// drop leaves a table out of the query because no join to it was planned in time
+func (b *requestBudget) drop(table string) {
+	if b.dropped == nil {
+		b.dropped = make(map[string]bool)
+	}
+	b.dropped[table] = true
+}
+
+// keep removes matches on tables dropped during join planning
+func (b *requestBudget) keep(matches []models.FieldMatch) []models.FieldMatch {
+	if b == nil || len(b.dropped) == 0 {
+		return matches
+	}
+	kept := make([]models.FieldMatch, 0, len(matches))
+	for _, match := range matches {
+		if !b.dropped[match.TableName] {
+			kept = append(kept, match)
+		}
+	}
+	return kept
+}
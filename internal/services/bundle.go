@@ -0,0 +1,113 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Downloadable bundle formats; tar bundles are gzip-compressed
+const (
+	FormatZip = "zip"
+	FormatTar = "tar"
+)
+
+// bundleFile is one file in a query bundle
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// bundleMetadata is the provenance written next to the bundled SQL
+type bundleMetadata struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	models.QueryResponse
+}
+
+// IsBundleFormat reports whether a response format is a downloadable bundle
+func IsBundleFormat(format string) bool {
+	return format == FormatZip || format == FormatTar
+}
+
+// RenderBundle packages a generated query as a zip or gzipped tar archive
+// holding query.sql, metadata.json, and optionally a dbt model reading its
+// tables as dbt describes. It returns the archive with its content type and
+// file name
+func RenderBundle(format string, response models.QueryResponse, dbtModel bool, dbt DbtTarget) ([]byte, string, string, error) {
+	metadata, err := json.MarshalIndent(bundleMetadata{GeneratedAt: time.Now().UTC(), QueryResponse: response}, "", "  ")
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to encode bundle metadata: %w", err)
+	}
+
+	name := "query"
+	if response.TraceID != "" {
+		name += "-" + response.TraceID
+	}
+	files := []bundleFile{
+		{name: name + "/query.sql", data: []byte(response.Query + ";\n")},
+		{name: name + "/metadata.json", data: append(metadata, '\n')},
+	}
+	if dbtModel {
+		model, _, filename := RenderDbtModel(response, dbt)
+		files = append(files, bundleFile{name: name + "/models/" + filename, data: []byte(model)})
+	}
+
+	switch format {
+	case FormatZip:
+		data, err := writeZip(files)
+		return data, "application/zip", name + ".zip", err
+	case FormatTar:
+		data, err := writeTarGz(files)
+		return data, "application/gzip", name + ".tar.gz", err
+	default:
+		return nil, "", "", fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// writeZip writes the files into a zip archive
+func writeZip(files []bundleFile) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := w.Write(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTarGz writes the files into a gzip-compressed tar archive
+func writeTarGz(files []bundleFile) ([]byte, error) {
+	var buf bytes.Buffer
+	compressed := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(compressed)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(file.data)), ModTime: time.Now()}
+		if err := archive.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := archive.Write(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Join cardinalities, read from the referencing table to the referenced one
+const (
+	CardinalityOneToOne   = "one_to_one"
+	CardinalityManyToOne  = "many_to_one"
+	CardinalityOneToMany  = "one_to_many"
+	CardinalityManyToMany = "many_to_many"
+)
+
+// cardinalities maps the accepted spellings to a cardinality
+var cardinalities = map[string]string{
+	"one_to_one": CardinalityOneToOne, "1:1": CardinalityOneToOne,
+	"many_to_one": CardinalityManyToOne, "n:1": CardinalityManyToOne, "m:1": CardinalityManyToOne,
+	"one_to_many": CardinalityOneToMany, "1:n": CardinalityOneToMany, "1:m": CardinalityOneToMany,
+	"many_to_many": CardinalityManyToMany, "n:m": CardinalityManyToMany, "m:n": CardinalityManyToMany,
+}
+
+// parseCardinality normalizes a mapping cardinality such as "many-to-one"
+// or "n:1"; unknown values are blank
+func parseCardinality(value string) string {
+	key := strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(value)))
+	return cardinalities[key]
+}
+
+// reverseCardinality reads a cardinality from the other table's side
+func reverseCardinality(cardinality string) string {
+	switch cardinality {
+	case CardinalityManyToOne:
+		return CardinalityOneToMany
+	case CardinalityOneToMany:
+		return CardinalityManyToOne
+	}
+	return cardinality
+}
+
+// fansOut reports whether a join can match several To rows per From row,
+// repeating the From row
+func fansOut(join models.Join) bool {
+	return join.Cardinality == CardinalityOneToMany || join.Cardinality == CardinalityManyToMany
+}
+
+// preservesRows reports whether a join is known to match at most one To row
+// per From row
+func preservesRows(join models.Join) bool {
+	return join.Cardinality == CardinalityManyToOne || join.Cardinality == CardinalityOneToOne
+}
+
+// chooseJoinRoot moves to the front the first table from which every other
+// table is reached through joins known not to repeat rows, so the query
+// reads from its most detailed table and looks up the rest. The order is
+// kept when no table qualifies or the mappings don't give cardinalities
+func (s *FieldService) chooseJoinRoot(tableNames []string) []string {
+	for i, root := range tableNames {
+		qualifies := true
+		for _, table := range tableNames {
+			joins, err := s.FindJoinPath(root, table)
+			if err != nil {
+				return tableNames
+			}
+			for _, join := range joins {
+				if !preservesRows(join) {
+					qualifies = false
+					break
+				}
+			}
+			if !qualifies {
+				break
+			}
+		}
+		if qualifies {
+			if i == 0 {
+				return tableNames
+			}
+			reordered := append([]string{root}, tableNames[:i]...)
+			return append(reordered, tableNames[i+1:]...)
+		}
+	}
+	return tableNames
+}
+
+// joinWarnings explains joins that can repeat rows, inflating counts and
+// sums, and inner joins on nullable keys, which leave rows out
+func joinWarnings(joins []models.Join) []string {
+	var warnings []string
+	for _, join := range joins {
+		if fansOut(join) {
+			warnings = append(warnings, fmt.Sprintf(
+				"joining %s to %s is %s: each %s row may appear several times, so counts and sums over %s can be inflated",
+				join.From, join.To, strings.ReplaceAll(join.Cardinality, "_", "-"), join.From, join.From))
+		}
+		if join.Nullable && join.Type != JoinTypeLeft {
+			warnings = append(warnings, fmt.Sprintf(
+				"the key joining %s and %s can be null; the inner join leaves out rows without a match (hint a left join to keep them)",
+				join.From, join.To))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+)
+
+// Stages where chaos mode can inject faults
+const (
+	ChaosStageRequest      = "request"
+	ChaosStageMatching     = "matching"
+	ChaosStageJoinPlanning = "join_planning"
+	ChaosStageBackend      = "backend"
+)
+
+// ErrChaosInjected marks a failure injected by chaos mode
+var ErrChaosInjected = errors.New("chaos: injected failure")
+
+// Chaos injects latency and random failures for resilience testing. It is
+// only ever enabled explicitly; a nil Chaos injects nothing
+type Chaos struct {
+	mu          sync.Mutex
+	rand        *rand.Rand
+	maxLatency  time.Duration
+	failureRate float64
+	stages      map[string]bool
+}
+
+// NewChaos returns the configured fault injector, or nil when chaos mode is off
+func NewChaos(cfg *config.Config) (*Chaos, error) {
+	if !cfg.ChaosEnabled {
+		return nil, nil
+	}
+
+	// A zero maximum injects failures only
+	maxLatency := cfg.ChaosMaxLatency
+	if maxLatency < 0 {
+		maxLatency = 0
+	}
+	failureRate := cfg.ChaosFailureRate
+	if failureRate < 0 || failureRate > 1 {
+		return nil, fmt.Errorf("chaos failure rate must be between 0 and 1, got %g", failureRate)
+	}
+
+	// Every stage is targeted unless a subset is configured
+	stages := map[string]bool{
+		ChaosStageRequest: true, ChaosStageMatching: true,
+		ChaosStageJoinPlanning: true, ChaosStageBackend: true,
+	}
+	if cfg.ChaosStages != "" {
+		selected := make(map[string]bool)
+		for _, stage := range strings.Split(cfg.ChaosStages, ",") {
+			stage = strings.TrimSpace(stage)
+			if !stages[stage] {
+				return nil, fmt.Errorf("unknown chaos stage %q", stage)
+			}
+			selected[stage] = true
+		}
+		stages = selected
+	}
+
+	seed := cfg.ChaosSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Chaos{
+		rand:        rand.New(rand.NewSource(seed)),
+		maxLatency:  maxLatency,
+		failureRate: failureRate,
+		stages:      stages,
+	}, nil
+}
+
+// Inject delays for a random fraction of the maximum latency and fails with
+// the configured probability, if the stage is targeted
+func (c *Chaos) Inject(stage string) error {
+	if c == nil || !c.stages[stage] {
+		return nil
+	}
+
+	c.mu.Lock()
+	var delay time.Duration
+	if c.maxLatency > 0 {
+		delay = time.Duration(c.rand.Int63n(int64(c.maxLatency)))
+	}
+	fail := c.rand.Float64() < c.failureRate
+	c.mu.Unlock()
+
+	time.Sleep(delay)
+	if fail {
+		return fmt.Errorf("%w during %s", ErrChaosInjected, stage)
+	}
+	return nil
+}
+
+// chaosEmbedder injects backend faults into embedding calls
+type chaosEmbedder struct {
+	inner Embedder
+	chaos *Chaos
+}
+
+// Embed implements Embedder
+func (e chaosEmbedder) Embed(texts []string) ([][]float64, error) {
+	if err := e.chaos.Inject(ChaosStageBackend); err != nil {
+		return nil, err
+	}
+	return e.inner.Embed(texts)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Data classification levels, from least to most protected. Callers hold a
+// clearance on the same scale
+const (
+	ClassificationPublic     = "public"
+	ClassificationInternal   = "internal"
+	ClassificationRestricted = "restricted"
+)
+
+// ErrInsufficientClearance is returned when a caller's clearance leaves
+// nothing to query, or an intent filters or sorts on a withheld column
+var ErrInsufficientClearance = errors.New("insufficient clearance")
+
+// classificationRanks orders the classification levels
+var classificationRanks = map[string]int{
+	ClassificationPublic:     0,
+	ClassificationInternal:   1,
+	ClassificationRestricted: 2,
+}
+
+// ParseClearance normalizes a clearance level; empty means internal
+func ParseClearance(level string) (string, error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" {
+		return ClassificationInternal, nil
+	}
+	if _, ok := classificationRanks[level]; !ok {
+		return "", fmt.Errorf("unknown clearance %q: use public, internal, or restricted", level)
+	}
+	return level, nil
+}
+
+// ClearanceExceeds reports whether a normalized clearance is above another
+func ClearanceExceeds(clearance, baseline string) bool {
+	return classificationRanks[clearance] > classificationRanks[baseline]
+}
+
+// classificationLevel normalizes a field classification. Unclassified fields
+// are internal, and unrecognized levels are treated as restricted so a typo
+// never exposes a column
+func classificationLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" {
+		return ClassificationInternal
+	}
+	if _, ok := classificationRanks[level]; !ok {
+		return ClassificationRestricted
+	}
+	return level
+}
+
+// cleared reports whether a clearance permits a classification level
+func cleared(clearance, classification string) bool {
+	if clearance == "" {
+		clearance = ClassificationInternal
+	}
+	return classificationRanks[clearance] >= classificationRanks[classification]
+}
+
+// withheldWarning explains why a column was left out of a query
+func withheldWarning(table, column, classification, clearance string) string {
+	return fmt.Sprintf("column %s.%s is classified %s and was withheld: caller clearance is %s",
+		table, column, classification, clearance)
+}
+
+// withholdMatches drops matches classified above the caller's clearance,
+// returning the kept matches, the withheld table.column names, and a warning
+// for each
+func withholdMatches(matches []models.FieldMatch, clearance string) ([]models.FieldMatch, []string, []string) {
+	var kept []models.FieldMatch
+	var withheld, warnings []string
+	for _, match := range matches {
+		level := classificationLevel(match.Classification)
+		if cleared(clearance, level) {
+			kept = append(kept, match)
+			continue
+		}
+		withheld = append(withheld, fieldKey(match.TableName, match.ColumnName))
+		warnings = append(warnings, withheldWarning(match.TableName, match.ColumnName, level, clearance))
+	}
+	return kept, withheld, warnings
+}
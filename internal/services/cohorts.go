@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Cohort is a governed, reusable filter. Descriptions that mention its name
+// or a synonym get its predicate instead of the matcher guessing at fields
+type Cohort struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Table       string   `json:"table" yaml:"table"`
+	Predicate   string   `json:"predicate" yaml:"predicate"`
+	Synonyms    []string `json:"synonyms,omitempty" yaml:"synonyms,omitempty"`
+}
+
+// CohortSet holds the saved cohorts and the phrases that name them
+type CohortSet struct {
+	Cohorts []Cohort `json:"cohorts" yaml:"cohorts"`
+
+	phrases []cohortPhrase
+}
+
+// cohortPhrase is one name or synonym of a cohort, matched on word boundaries
+type cohortPhrase struct {
+	phrase  string
+	pattern *regexp.Regexp
+	cohort  *Cohort
+}
+
+// LoadCohorts reads saved cohorts from a YAML or JSON file
+func LoadCohorts(path string) (*CohortSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cohorts file: %w", err)
+	}
+
+	var cohorts CohortSet
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cohorts)
+	default:
+		err = json.Unmarshal(data, &cohorts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cohorts file: %w", err)
+	}
+
+	for i := range cohorts.Cohorts {
+		cohort := &cohorts.Cohorts[i]
+		if cohort.Name == "" || cohort.Table == "" || strings.TrimSpace(cohort.Predicate) == "" {
+			return nil, fmt.Errorf("cohort %d needs a name, table, and predicate", i+1)
+		}
+		// Predicates are spliced into WHERE clauses, so keep them to one expression
+		if strings.Contains(cohort.Predicate, ";") {
+			return nil, fmt.Errorf("cohort %q predicate must be a single expression", cohort.Name)
+		}
+		for _, phrase := range append([]string{cohort.Name}, cohort.Synonyms...) {
+			phrase = strings.ToLower(strings.TrimSpace(phrase))
+			if phrase == "" {
+				continue
+			}
+			cohorts.phrases = append(cohorts.phrases, cohortPhrase{
+				phrase:  phrase,
+				pattern: regexp.MustCompile(`\b` + regexp.QuoteMeta(phrase) + `\b`),
+				cohort:  cohort,
+			})
+		}
+	}
+
+	// Longer phrases first, so "inactive customers" wins over "customers"
+	sort.SliceStable(cohorts.phrases, func(i, j int) bool {
+		return len(cohorts.phrases[i].phrase) > len(cohorts.phrases[j].phrase)
+	})
+	return &cohorts, nil
+}
+
+// Expand finds the cohorts a description mentions. It returns the
+// description with those mentions removed, so they don't also drive field
+// matching, and the expansions to apply
+func (c *CohortSet) Expand(description string) (string, []models.CohortExpansion) {
+	if c == nil {
+		return description, nil
+	}
+
+	remaining := strings.ToLower(description)
+	var expansions []models.CohortExpansion
+	applied := make(map[*Cohort]bool)
+	for _, phrase := range c.phrases {
+		if !phrase.pattern.MatchString(remaining) {
+			continue
+		}
+		remaining = phrase.pattern.ReplaceAllString(remaining, " ")
+		if applied[phrase.cohort] {
+			continue
+		}
+		applied[phrase.cohort] = true
+		expansions = append(expansions, models.CohortExpansion{
+			Name:      phrase.cohort.Name,
+			Mention:   phrase.phrase,
+			Table:     phrase.cohort.Table,
+			Predicate: phrase.cohort.Predicate,
+		})
+	}
+	if len(expansions) == 0 {
+		return description, nil
+	}
+	return strings.Join(strings.Fields(remaining), " "), expansions
+}
+
+// Find returns the cohort with a name, or nil when there is none
+func (c *CohortSet) Find(name string) *Cohort {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Cohorts {
+		if strings.EqualFold(c.Cohorts[i].Name, name) {
+			return &c.Cohorts[i]
+		}
+	}
+	return nil
+}
+
+// checkTables verifies every cohort filters a table in the mappings
+func (c *CohortSet) checkTables(hasTable func(string) bool) error {
+	for _, cohort := range c.Cohorts {
+		if !hasTable(cohort.Table) {
+			return fmt.Errorf("cohort %q filters unknown table %s", cohort.Name, cohort.Table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// ErrQueryTooComplex is returned for a query over the complexity limits
+var ErrQueryTooComplex = errors.New("query exceeds complexity limits")
+
+// What generation does with a query over the complexity limits: trim the
+// lowest-scoring fields until it fits, or reject it
+const (
+	ComplexityActionTrim   = "trim"
+	ComplexityActionReject = "reject"
+)
+
+// ValidateComplexityAction checks a configured complexity action; empty
+// means reject
+func ValidateComplexityAction(action string) error {
+	switch action {
+	case "", ComplexityActionTrim, ComplexityActionReject:
+		return nil
+	default:
+		return fmt.Errorf("unknown complexity action %q: use trim or reject", action)
+	}
+}
+
+// complexityLimits caps the join depth, selected columns, and tables of a
+// query; 0 means no limit
+type complexityLimits struct {
+	joinHops int
+	columns  int
+	tables   int
+}
+
+// complexityLimits resolves a request's limits. Requested limits may
+// tighten the configured ones but never loosen them
+func (s *QueryService) complexityLimits(joinHops, columns, tables int) complexityLimits {
+	return complexityLimits{
+		joinHops: tighterLimit(s.cfg.MaxJoinHops, joinHops),
+		columns:  tighterLimit(s.cfg.MaxColumns, columns),
+		tables:   tighterLimit(s.cfg.MaxTables, tables),
+	}
+}
+
+// tighterLimit returns the lower of two limits, where 0 is no limit
+func tighterLimit(configured, requested int) int {
+	if requested > 0 && (configured == 0 || requested < configured) {
+		return requested
+	}
+	return configured
+}
+
+// exceeded describes the first limit a planned query goes over, or is
+// empty when it fits. Only columns reading mapped fields count, not the
+// COUNT(*) of a grouped query
+func (l complexityLimits) exceeded(plan models.QueryPlan, joins []models.Join) string {
+	columns := 0
+	for _, column := range plan.Select {
+		if column.Column != "" {
+			columns++
+		}
+	}
+	if l.columns > 0 && columns > l.columns {
+		return fmt.Sprintf("%d columns are selected, over the limit of %d", columns, l.columns)
+	}
+	if tables := 1 + len(plan.Joins); l.tables > 0 && tables > l.tables {
+		return fmt.Sprintf("%d tables are joined, over the limit of %d", tables, l.tables)
+	}
+	if hops := joinHops(plan.From.Table, joins); l.joinHops > 0 && hops > l.joinHops {
+		return fmt.Sprintf("a table is %d joins from %s, over the limit of %d", hops, plan.From.Table, l.joinHops)
+	}
+	return ""
+}
+
+// joinHops is the longest chain of joins from the root table to another
+func joinHops(root string, joins []models.Join) int {
+	depth := map[string]int{root: 0}
+	deepest := 0
+	for _, join := range joins {
+		from, reached := depth[join.From]
+		if _, joined := depth[join.To]; !reached || joined {
+			continue
+		}
+		depth[join.To] = from + 1
+		if from+1 > deepest {
+			deepest = from + 1
+		}
+	}
+	return deepest
+}
+
+// lowestScoringTable returns the matched table whose best field scored
+// lowest, the first to go when trimming; empty when only one table is left
+func lowestScoringTable(matches []models.FieldMatch) string {
+	best := make(map[string]float64)
+	var tables []string
+	for _, match := range matches {
+		score, seen := best[match.TableName]
+		if !seen {
+			tables = append(tables, match.TableName)
+		}
+		if !seen || match.MatchScore > score {
+			best[match.TableName] = match.MatchScore
+		}
+	}
+	if len(tables) < 2 {
+		return ""
+	}
+
+	// Later tables lose ties
+	lowest := tables[len(tables)-1]
+	for i := len(tables) - 2; i >= 0; i-- {
+		if best[tables[i]] < best[lowest] {
+			lowest = tables[i]
+		}
+	}
+	return lowest
+}
+
+// withoutTable leaves out the matches of a table
+func withoutTable(matches []models.FieldMatch, table string) []models.FieldMatch {
+	kept := make([]models.FieldMatch, 0, len(matches))
+	for _, match := range matches {
+		if match.TableName != table {
+			kept = append(kept, match)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// csvDelimiters are the mapping CSV delimiters that can be configured, by name
+var csvDelimiters = map[string]rune{
+	",": ',', "comma": ',',
+	";": ';', "semicolon": ';',
+	"\t": '\t', `\t`: '\t', "tab": '\t',
+	"|": '|', "pipe": '|',
+}
+
+// detectableDelimiters are tried, in order of preference, when detecting
+var detectableDelimiters = []rune{',', ';', '\t', '|'}
+
+// utf8BOM starts UTF-8 files saved by spreadsheet applications
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// csvComment starts comment lines in mapping files, which are skipped
+const csvComment = '#'
+
+// CSVFormat returns the delimiter and encoding the loader reads the mapping
+// file at path with: the configured ones, with .tsv files always tab-separated
+func CSVFormat(cfg *config.Config, path string) (delimiter, encodingName string) {
+	delimiter = cfg.CSVDelimiter
+	if mappingExt(path) == ".tsv" {
+		delimiter = "tab"
+	}
+	return delimiter, cfg.CSVEncoding
+}
+
+// readMappingRecords parses a mapping CSV in the given delimiter and
+// encoding, detecting either when empty
+func readMappingRecords(data []byte, delimiter, encodingName string) ([][]string, error) {
+	records, _, err := readMappingLines(data, delimiter, encodingName)
+	return records, err
+}
+
+// readMappingLines is readMappingRecords also returning the file line each
+// record starts on, which comment lines make differ from its position
+func readMappingLines(data []byte, delimiter, encodingName string) ([][]string, []int, error) {
+	text, err := decodeCSVText(data, encodingName)
+	if err != nil {
+		return nil, nil, err
+	}
+	comma, err := csvDelimiter(text, delimiter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(text))
+	reader.Comma = comma
+	reader.Comment = csvComment
+	reader.FieldsPerRecord = -1 // Short rows are reported by the caller
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, lines, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+}
+
+// csvDelimiter resolves a configured delimiter, or detects it from the header
+func csvDelimiter(text []byte, name string) (rune, error) {
+	if name == "" {
+		return detectDelimiter(text), nil
+	}
+	if comma, exists := csvDelimiters[strings.ToLower(name)]; exists {
+		return comma, nil
+	}
+	if trimmed := strings.TrimSpace(name); trimmed != "" {
+		if comma, exists := csvDelimiters[strings.ToLower(trimmed)]; exists {
+			return comma, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported CSV delimiter %q: use comma, semicolon, tab, or pipe", name)
+}
+
+// detectDelimiter picks the candidate delimiter appearing most often outside
+// quotes in the header row, preferring commas. Comment lines before the
+// header are skipped
+func detectDelimiter(text []byte) rune {
+	for bytes.HasPrefix(text, []byte{csvComment}) {
+		end := bytes.IndexByte(text, '\n')
+		if end < 0 {
+			return ','
+		}
+		text = text[end+1:]
+	}
+
+	counts := make(map[rune]int)
+	quoted := false
+	for _, r := range string(text) {
+		if r == '"' {
+			quoted = !quoted
+			continue
+		}
+		if !quoted && (r == '\n' || r == '\r') {
+			break
+		}
+		if !quoted {
+			counts[r]++
+		}
+	}
+
+	best := ','
+	for _, candidate := range detectableDelimiters {
+		if counts[candidate] > counts[best] {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// decodeCSVText converts a mapping file to UTF-8 without a byte order mark.
+// Without a configured encoding, UTF-16 is recognized by its byte order mark
+// and other text that isn't valid UTF-8 is read as Windows-1252, the usual
+// encoding of legacy spreadsheet exports
+func decodeCSVText(data []byte, encodingName string) ([]byte, error) {
+	var decoder encoding.Encoding
+	switch name := strings.ToLower(strings.TrimSpace(encodingName)); name {
+	case "":
+		switch {
+		case bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+			decoder = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+		case utf8.Valid(data):
+			return bytes.TrimPrefix(data, utf8BOM), nil
+		default:
+			decoder = charmap.Windows1252
+		}
+	case "utf-8", "utf8":
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("mapping file is not valid UTF-8; set CSV_ENCODING to its encoding")
+		}
+		return bytes.TrimPrefix(data, utf8BOM), nil
+	case "latin-1", "latin1", "iso-8859-1", "iso8859-1":
+		// Strict Latin-1, so bytes 0x80-0x9F stay control characters
+		decoder = charmap.ISO8859_1
+	default:
+		var err error
+		decoder, err = htmlindex.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported CSV encoding %q", encodingName)
+		}
+	}
+
+	text, err := decoder.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mapping file as %s: %w", encodingName, err)
+	}
+	return bytes.TrimPrefix(text, utf8BOM), nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// defaultCursorTTL is how long a result cursor stays valid when not
+// configured
+const defaultCursorTTL = time.Hour
+
+// ErrInvalidCursor is returned for a result cursor that is malformed,
+// tampered with, or expired
+var ErrInvalidCursor = errors.New("invalid result cursor")
+
+// resultCursor is where the next page of a result starts: the connection
+// and query it came from, the plan the query was rendered from if it had
+// one, and the rows already returned. Cursors are signed, so only queries
+// this service generated are run again
+type resultCursor struct {
+	Connection string            `json:"c"`
+	Query      string            `json:"q"`
+	Plan       *models.QueryPlan `json:"p,omitempty"`
+	Offset     int               `json:"o"`
+	Expires    int64             `json:"x"`
+}
+
+// cursorKey returns the key cursors are signed with: the configured secret,
+// or a random key, making cursors valid only until a restart
+func cursorKey(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate cursor key: %v", err))
+	}
+	return key
+}
+
+// encodeCursor signs a cursor to the next page of a query's result
+func (e *Executor) encodeCursor(connection, query string, plan *models.QueryPlan, offset int) string {
+	payload, _ := json.Marshal(resultCursor{Connection: connection, Query: query, Plan: plan, Offset: offset, Expires: time.Now().Add(e.cursorTTL).Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(e.signCursor(encoded))
+}
+
+// decodeCursor verifies a cursor's signature and expiry
+func (e *Executor) decodeCursor(token string) (resultCursor, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if !found || err != nil || !hmac.Equal(sum, e.signCursor(encoded)) {
+		return resultCursor{}, fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return resultCursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var cursor resultCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.Query == "" || cursor.Offset < 1 {
+		return resultCursor{}, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	if time.Now().Unix() > cursor.Expires {
+		return resultCursor{}, fmt.Errorf("%w: expired", ErrInvalidCursor)
+	}
+	return cursor, nil
+}
+
+// signCursor is the HMAC-SHA256 of an encoded cursor
+func (e *Executor) signCursor(encoded string) []byte {
+	mac := hmac.New(sha256.New, e.cursorKey)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// pageQuery renders a query that skips the rows of earlier pages. A query
+// planned in its connection's dialect is rendered again with the pages
+// added to its offset and taken from its limit, keeping its ORDER BY
+// outermost; other SQL is wrapped in a subquery
+func pageQuery(driver, query string, plan *models.QueryPlan, offset int) string {
+	if plan != nil && driver != "" {
+		paged := *plan
+		paged.Offset += offset
+		if paged.Limit > 0 {
+			paged.Limit -= offset
+		}
+		return renderPlan(driver, paged)
+	}
+	_, clause := rowLimit(driver, 0, offset, false)
+	return "SELECT * FROM (" + strings.TrimRight(strings.TrimSpace(query), ";") + ") page " + clause
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// dbtManifest is the part of a dbt manifest.json the importer reads
+type dbtManifest struct {
+	Metadata struct {
+		SchemaVersion string `json:"dbt_schema_version"`
+	} `json:"metadata"`
+	Nodes   map[string]dbtNode `json:"nodes"`
+	Sources map[string]dbtNode `json:"sources"`
+}
+
+// dbtNode is a model, source, or test in a manifest
+type dbtNode struct {
+	ResourceType string               `json:"resource_type"`
+	Name         string               `json:"name"`
+	SourceName   string               `json:"source_name"`
+	Alias        string               `json:"alias"`
+	Identifier   string               `json:"identifier"`
+	Description  string               `json:"description"`
+	Tags         []string             `json:"tags"`
+	Meta         map[string]any       `json:"meta"`
+	Columns      map[string]dbtColumn `json:"columns"`
+	Config       struct {
+		Meta map[string]any `json:"meta"`
+	} `json:"config"`
+
+	// Relationship tests name the child model and column they check
+	AttachedNode string `json:"attached_node"`
+	ColumnName   string `json:"column_name"`
+	TestMetadata *struct {
+		Name   string         `json:"name"`
+		Kwargs map[string]any `json:"kwargs"`
+	} `json:"test_metadata"`
+}
+
+// dbtColumn is a documented column of a model or source
+type dbtColumn struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	DataType    string         `json:"data_type"`
+	Tags        []string       `json:"tags"`
+	Meta        map[string]any `json:"meta"`
+}
+
+// dbtCatalog is the part of a dbt catalog.json the importer reads: the
+// warehouse column types of each node
+type dbtCatalog struct {
+	Nodes   map[string]dbtCatalogNode `json:"nodes"`
+	Sources map[string]dbtCatalogNode `json:"sources"`
+}
+
+// dbtCatalogNode holds a node's columns as the warehouse reports them
+type dbtCatalogNode struct {
+	Columns map[string]struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"columns"`
+}
+
+// dbtRef matches ref('model') and source('source', 'table') calls in test
+// arguments, capturing the model or table name
+var dbtRef = regexp.MustCompile(`(?:ref\(\s*['"]([^'"]+)['"]\s*\)|source\(\s*['"][^'"]+['"]\s*,\s*['"]([^'"]+)['"]\s*\))`)
+
+// isDbtManifest reports whether a JSON mapping file is a dbt manifest
+func isDbtManifest(data []byte) bool {
+	var header struct {
+		Metadata struct {
+			SchemaVersion string `json:"dbt_schema_version"`
+		} `json:"metadata"`
+	}
+	return json.Unmarshal(data, &header) == nil && strings.Contains(header.Metadata.SchemaVersion, "/manifest/")
+}
+
+// readDbtManifest parses a dbt manifest, with column types from the
+// catalog.json beside it when the manifest has none
+func readDbtManifest(path string, data []byte) (SchemaDefinition, error) {
+	catalog, err := readDbtCatalog(path)
+	if err != nil {
+		return SchemaDefinition{}, err
+	}
+	return parseDbtManifest(data, catalog)
+}
+
+// parseDbtManifest converts a dbt manifest into a schema definition: models
+// and sources become tables, their documented columns become fields, and
+// relationships tests become references. Column types come from the
+// manifest or, failing that, from catalog
+func parseDbtManifest(data []byte, catalog *dbtCatalog) (SchemaDefinition, error) {
+	var manifest dbtManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return SchemaDefinition{}, err
+	}
+
+	// Models and sources, keyed by unique ID, in a stable order
+	nodes := make(map[string]dbtNode)
+	for id, node := range manifest.Nodes {
+		if node.ResourceType == "model" || node.ResourceType == "seed" || node.ResourceType == "snapshot" {
+			nodes[id] = node
+		}
+	}
+	for id, node := range manifest.Sources {
+		nodes[id] = node
+	}
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Tests reference models by name; map them to the table each is built as
+	tableNames := make(map[string]string)
+	for _, id := range ids {
+		tableNames[nodes[id].Name] = dbtTableName(nodes[id])
+	}
+	references := dbtRelationships(manifest.Nodes, nodes, tableNames)
+
+	var definition SchemaDefinition
+	for _, id := range ids {
+		node := nodes[id]
+		table := TableDefinition{
+			Name:  dbtTableName(node),
+			Owner: dbtMetaString(node.Meta, node.Config.Meta, "owner"),
+			Tags:  node.Tags,
+		}
+		table.DbtRef = dbtNodeRef(node)
+		table.OwnerContact = dbtMetaString(node.Meta, node.Config.Meta, "owner_contact")
+
+		columnNames := make([]string, 0, len(node.Columns))
+		for name := range node.Columns {
+			columnNames = append(columnNames, name)
+		}
+		sort.Strings(columnNames)
+		for _, key := range columnNames {
+			column := node.Columns[key]
+			name := column.Name
+			if name == "" {
+				name = key
+			}
+			dataType := column.DataType
+			if dataType == "" {
+				dataType = catalog.columnType(id, name)
+			}
+			table.Columns = append(table.Columns, ColumnDefinition{
+				Name:           name,
+				Description:    column.Description,
+				Type:           strings.ToUpper(dataType),
+				References:     references[fieldKey(table.Name, strings.ToLower(name))],
+				Tags:           column.Tags,
+				Sensitive:      parseFlag(dbtMetaString(column.Meta, nil, "sensitive")),
+				Classification: dbtMetaString(column.Meta, nil, "classification"),
+			})
+		}
+		if len(table.Columns) > 0 {
+			definition.Tables = append(definition.Tables, table)
+		}
+	}
+	if len(definition.Tables) == 0 {
+		return SchemaDefinition{}, fmt.Errorf("dbt manifest documents no model columns")
+	}
+	return definition, nil
+}
+
+// dbtRelationships turns relationships tests into table.column references,
+// keyed by the child table.column. Tests on undocumented models are skipped
+func dbtRelationships(manifestNodes map[string]dbtNode, documented map[string]dbtNode, tableNames map[string]string) map[string]string {
+	references := make(map[string]string)
+	for _, node := range manifestNodes {
+		if node.ResourceType != "test" || node.TestMetadata == nil || node.TestMetadata.Name != "relationships" {
+			continue
+		}
+		kwargs := node.TestMetadata.Kwargs
+		column := node.ColumnName
+		if column == "" {
+			column, _ = kwargs["column_name"].(string)
+		}
+		field, _ := kwargs["field"].(string)
+		to, _ := kwargs["to"].(string)
+		parent := dbtRefName(to)
+
+		// Newer manifests attach the test to its model; older ones only
+		// carry the model in the test arguments
+		var child string
+		if attached, ok := documented[node.AttachedNode]; ok {
+			child = dbtTableName(attached)
+		} else if model, ok := kwargs["model"].(string); ok {
+			child = tableNames[dbtRefName(model)]
+		}
+
+		parentTable := tableNames[parent]
+		if child == "" || parentTable == "" || column == "" || field == "" {
+			continue
+		}
+		references[fieldKey(child, strings.ToLower(column))] = parentTable + "." + field
+	}
+	return references
+}
+
+// dbtRefName extracts the model or table named by a ref() or source() call
+func dbtRefName(expression string) string {
+	match := dbtRef.FindStringSubmatch(expression)
+	if match == nil {
+		return ""
+	}
+	if match[1] != "" {
+		return match[1]
+	}
+	return match[2]
+}
+
+// dbtTableName is the relation a node is built as: its alias or identifier
+// when set, otherwise its name
+func dbtTableName(node dbtNode) string {
+	switch {
+	case node.Alias != "":
+		return node.Alias
+	case node.Identifier != "":
+		return node.Identifier
+	default:
+		return node.Name
+	}
+}
+
+// dbtNodeRef is the call dbt models read a node through: source() for
+// sources, ref() for models, seeds, and snapshots
+func dbtNodeRef(node dbtNode) string {
+	if node.ResourceType != "source" {
+		return fmt.Sprintf("ref('%s')", node.Name)
+	}
+	if node.SourceName == "" {
+		return ""
+	}
+	return fmt.Sprintf("source('%s', '%s')", node.SourceName, node.Name)
+}
+
+// dbtMetaString reads a string from node meta, falling back to config meta
+func dbtMetaString(meta, configMeta map[string]any, key string) string {
+	for _, source := range []map[string]any{meta, configMeta} {
+		switch value := source[key].(type) {
+		case string:
+			return value
+		case bool:
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+// columnType looks a column's warehouse type up in the catalog, if loaded
+func (c *dbtCatalog) columnType(id, column string) string {
+	if c == nil {
+		return ""
+	}
+	node, exists := c.Nodes[id]
+	if !exists {
+		node = c.Sources[id]
+	}
+	for key, entry := range node.Columns {
+		if strings.EqualFold(key, column) || strings.EqualFold(entry.Name, column) {
+			return entry.Type
+		}
+	}
+	return ""
+}
+
+// readDbtCatalog loads the catalog.json dbt writes next to a local
+// manifest, if there is one
+func readDbtCatalog(manifestPath string) (*dbtCatalog, error) {
+	if isRemotePath(manifestPath) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(manifestPath), "catalog.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dbt catalog: %w", err)
+	}
+	var catalog dbtCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse dbt catalog: %w", err)
+	}
+	return &catalog, nil
+}
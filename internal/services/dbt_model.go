@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// FormatDbt renders the generated query as a dbt model file
+const FormatDbt = "dbt"
+
+// defaultDbtSource is the dbt source name models read from when not configured
+const defaultDbtSource = "warehouse"
+
+// dbtModelFile is the file name dbt models are written as
+const dbtModelFile = "generated_query.sql"
+
+// DbtTarget describes how a dbt model reads its tables. Refs holds the
+// ref() or source() call of each table the catalog knows from a dbt
+// project; other tables are read from Source
+type DbtTarget struct {
+	Source string
+	Refs   map[string]string
+}
+
+// tableReference matches a table named in a FROM or JOIN clause, perhaps
+// qualified by a schema or database, capturing the bare table name
+var tableReference = regexp.MustCompile(`\b(FROM|JOIN) ((?:[A-Za-z_][A-Za-z0-9_]*\.)*([A-Za-z_][A-Za-z0-9_]*))\b`)
+
+// RenderDbtModel turns a generated query into a dbt model file with a
+// config block, reading each table through ref() or source() so dbt
+// tracks lineage. It returns the model with its content type and file name
+func RenderDbtModel(response models.QueryResponse, dbt DbtTarget) (string, string, string) {
+	source := dbt.Source
+	if source == "" {
+		source = defaultDbtSource
+	}
+
+	var b strings.Builder
+	b.WriteString("-- Generated by go_query_api")
+	if response.SchemaVersion != "" {
+		b.WriteString(" from schema version " + response.SchemaVersion)
+	}
+	if response.TraceID != "" {
+		b.WriteString(" (trace " + response.TraceID + ")")
+	}
+	b.WriteString("\n{{ config(materialized='view') }}\n\n")
+	b.WriteString(tableReference.ReplaceAllStringFunc(response.Query, func(clause string) string {
+		parts := tableReference.FindStringSubmatch(clause)
+		ref, known := dbt.Refs[parts[3]]
+		if !known {
+			ref = fmt.Sprintf("source('%s', '%s')", source, parts[3])
+		}
+		return parts[1] + " {{ " + ref + " }}"
+	}))
+	b.WriteString("\n")
+	return b.String(), "text/plain; charset=utf-8", dbtModelFile
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ddlTypes maps mapping field types to column types per dialect. Types not
+// listed are kept as written when they look like plain SQL, or become text
+var ddlTypes = map[string]map[string]string{
+	DialectPostgres: {
+		"VARCHAR": "VARCHAR", "STRING": "TEXT", "TEXT": "TEXT", "CHAR": "CHAR",
+		"INTEGER": "INTEGER", "INT": "INTEGER", "SMALLINT": "SMALLINT", "BIGINT": "BIGINT",
+		"DECIMAL": "DECIMAL", "NUMERIC": "NUMERIC", "REAL": "REAL", "FLOAT": "DOUBLE PRECISION", "DOUBLE": "DOUBLE PRECISION",
+		"BOOLEAN": "BOOLEAN", "BOOL": "BOOLEAN",
+		"DATE": "DATE", "TIME": "TIME", "TIMESTAMP": "TIMESTAMP", "DATETIME": "TIMESTAMP", "TIMESTAMPTZ": "TIMESTAMPTZ",
+		"UUID": "UUID", "BINARY": "BYTEA", "BYTES": "BYTEA", "JSON": "JSONB", "JSONB": "JSONB",
+		"STRUCT": "JSONB", "ARRAY": "JSONB", "MAP": "JSONB",
+	},
+	DialectMySQL: {
+		"VARCHAR": "VARCHAR(255)", "STRING": "TEXT", "TEXT": "TEXT", "CHAR": "CHAR",
+		"INTEGER": "INT", "INT": "INT", "SMALLINT": "SMALLINT", "BIGINT": "BIGINT",
+		"DECIMAL": "DECIMAL", "NUMERIC": "DECIMAL", "REAL": "FLOAT", "FLOAT": "DOUBLE", "DOUBLE": "DOUBLE",
+		"BOOLEAN": "BOOLEAN", "BOOL": "BOOLEAN",
+		"DATE": "DATE", "TIME": "TIME", "TIMESTAMP": "DATETIME", "DATETIME": "DATETIME", "TIMESTAMPTZ": "DATETIME",
+		"UUID": "CHAR(36)", "BINARY": "BLOB", "BYTES": "BLOB", "JSON": "JSON", "JSONB": "JSON",
+		"STRUCT": "JSON", "ARRAY": "JSON", "MAP": "JSON",
+	},
+	DialectSQLite: {
+		"VARCHAR": "TEXT", "STRING": "TEXT", "TEXT": "TEXT", "CHAR": "TEXT",
+		"INTEGER": "INTEGER", "INT": "INTEGER", "SMALLINT": "INTEGER", "BIGINT": "INTEGER",
+		"DECIMAL": "NUMERIC", "NUMERIC": "NUMERIC", "REAL": "REAL", "FLOAT": "REAL", "DOUBLE": "REAL",
+		"BOOLEAN": "INTEGER", "BOOL": "INTEGER",
+		"DATE": "TEXT", "TIME": "TEXT", "TIMESTAMP": "TEXT", "DATETIME": "TEXT", "TIMESTAMPTZ": "TEXT",
+		"UUID": "TEXT", "BINARY": "BLOB", "BYTES": "BLOB", "JSON": "TEXT", "JSONB": "TEXT",
+		"STRUCT": "TEXT", "ARRAY": "TEXT", "MAP": "TEXT",
+	},
+}
+
+// sqlType matches a plain SQL type with an optional size, such as
+// VARCHAR(64) or DECIMAL(10, 2)
+var sqlType = regexp.MustCompile(`^([A-Za-z][A-Za-z ]*?)\s*(\(\s*\d+\s*(?:,\s*\d+\s*)?\))?$`)
+
+// sizedTypes take a length or precision, such as VARCHAR(64)
+var sizedTypes = map[string]bool{"VARCHAR": true, "CHAR": true, "DECIMAL": true, "NUMERIC": true}
+
+// ddlColumn is a column of a table being rendered
+type ddlColumn struct {
+	name        string
+	columnType  string
+	description string
+}
+
+// ddlTable is a table being rendered, with its keys. Keys are column lists,
+// several columns for a composite key
+type ddlTable struct {
+	name       string
+	columns    []ddlColumn
+	primaryKey []string
+	unique     [][]string
+	foreign    []*foreignKey
+}
+
+// RenderDDL renders the catalog as CREATE TABLE statements for a dialect.
+// Columns referenced by foreign keys become primary keys (or unique, when a
+// table is referenced by several), and are added to their table when the
+// mappings don't list them. Composite keys are declared as table constraints.
+// Measures aren't physical columns and are skipped
+func (s *FieldService) RenderDDL(dialect string) (string, error) {
+	dialect, err := ResolveDialect(dialect)
+	if err != nil {
+		return "", err
+	}
+	if _, supported := ddlTypes[dialect]; !supported {
+		return "", fmt.Errorf("%w %q for DDL: use postgres, mysql, or sqlite", ErrUnknownDialect, dialect)
+	}
+
+	tables := make(map[string]*ddlTable)
+	seen := make(map[string]bool)
+	types := make(map[string]string)
+	table := func(name string) *ddlTable {
+		if tables[name] == nil {
+			tables[name] = &ddlTable{name: name}
+		}
+		return tables[name]
+	}
+	for _, field := range s.fields {
+		if field.Measure != "" || seen[fieldKey(field.TableName, field.ColumnName)] {
+			continue
+		}
+		seen[fieldKey(field.TableName, field.ColumnName)] = true
+		types[fieldKey(field.TableName, field.ColumnName)] = field.FieldType
+		table(field.TableName).columns = append(table(field.TableName).columns, ddlColumn{
+			name:        field.ColumnName,
+			columnType:  ddlColumnType(dialect, field.FieldType),
+			description: field.Description,
+		})
+	}
+	for _, key := range foreignKeys(s.fields) {
+		if seen[fieldKey(key.table, key.columns[0])] {
+			table(key.table).foreign = append(table(key.table).foreign, key)
+		}
+	}
+
+	// Referenced columns need a key; the parent may not list them at all
+	referenced := make(map[string]map[string][]string)
+	for _, t := range tables {
+		for _, key := range t.foreign {
+			for i, column := range key.foreignColumns {
+				if !seen[fieldKey(key.foreignTable, column)] {
+					seen[fieldKey(key.foreignTable, column)] = true
+					parent := table(key.foreignTable)
+					parent.columns = append(parent.columns, ddlColumn{
+						name:       column,
+						columnType: ddlColumnType(dialect, types[fieldKey(key.table, key.columns[i])]),
+					})
+				}
+			}
+			if referenced[key.foreignTable] == nil {
+				referenced[key.foreignTable] = make(map[string][]string)
+			}
+			referenced[key.foreignTable][strings.Join(key.foreignColumns, ", ")] = key.foreignColumns
+		}
+	}
+	for name, keys := range referenced {
+		names := make([]string, 0, len(keys))
+		for columns := range keys {
+			names = append(names, columns)
+		}
+		sort.Strings(names)
+		tables[name].primaryKey = keys[names[0]]
+		for _, columns := range names[1:] {
+			tables[name].unique = append(tables[name].unique, keys[columns])
+		}
+	}
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema version %s, %s dialect\n", s.Version(), dialect)
+	for _, name := range names {
+		b.WriteString("\n")
+		writeCreateTable(&b, dialect, tables[name])
+	}
+
+	// Foreign keys go last so tables can reference each other in any order;
+	// SQLite can't add them later and declares them inline instead
+	if dialect != DialectSQLite {
+		for _, name := range names {
+			for _, key := range tables[name].foreign {
+				fmt.Fprintf(&b, "\nALTER TABLE %s ADD CONSTRAINT fk_%s_%s FOREIGN KEY (%s) REFERENCES %s (%s);\n",
+					key.table, key.table, strings.Join(key.columns, "_"), strings.Join(key.columns, ", "),
+					key.foreignTable, strings.Join(key.foreignColumns, ", "))
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// writeCreateTable writes one CREATE TABLE statement, with descriptions as
+// comments in the dialect's style
+func writeCreateTable(b *strings.Builder, dialect string, t *ddlTable) {
+	lines := make([]string, 0, len(t.columns)+2)
+	for _, column := range t.columns {
+		line := "  " + column.name + " " + column.columnType
+		if len(t.primaryKey) == 1 && t.primaryKey[0] == column.name {
+			line += " PRIMARY KEY"
+		} else if isSingleColumnKey(t.unique, column.name) {
+			line += " UNIQUE"
+		}
+		if dialect == DialectMySQL && column.description != "" {
+			line += " COMMENT " + quoteString(dialect, column.description)
+		}
+		lines = append(lines, line)
+	}
+	if len(t.primaryKey) > 1 {
+		lines = append(lines, "  PRIMARY KEY ("+strings.Join(t.primaryKey, ", ")+")")
+	}
+	for _, columns := range t.unique {
+		if len(columns) > 1 {
+			lines = append(lines, "  UNIQUE ("+strings.Join(columns, ", ")+")")
+		}
+	}
+	if dialect == DialectSQLite {
+		for _, key := range t.foreign {
+			lines = append(lines, fmt.Sprintf("  FOREIGN KEY (%s) REFERENCES %s (%s)",
+				strings.Join(key.columns, ", "), key.foreignTable, strings.Join(key.foreignColumns, ", ")))
+		}
+	}
+
+	fmt.Fprintf(b, "CREATE TABLE %s (\n", t.name)
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			line += ","
+		}
+		// SQLite keeps descriptions as trailing comments
+		if dialect == DialectSQLite && i < len(t.columns) && t.columns[i].description != "" {
+			line += " -- " + strings.Join(strings.Fields(t.columns[i].description), " ")
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(");\n")
+
+	if dialect == DialectPostgres {
+		for _, column := range t.columns {
+			if column.description != "" {
+				fmt.Fprintf(b, "COMMENT ON COLUMN %s.%s IS %s;\n", t.name, column.name, quoteString(dialect, column.description))
+			}
+		}
+	}
+}
+
+// isSingleColumnKey reports whether column alone is one of the keys
+func isSingleColumnKey(keys [][]string, column string) bool {
+	for _, columns := range keys {
+		if len(columns) == 1 && columns[0] == column {
+			return true
+		}
+	}
+	return false
+}
+
+// ddlColumnType maps a mapping field type to a dialect's column type, keeping
+// any size given with it
+func ddlColumnType(dialect, fieldType string) string {
+	match := sqlType.FindStringSubmatch(strings.TrimSpace(fieldType))
+	if match == nil {
+		return ddlTypes[dialect]["TEXT"]
+	}
+	base, size := strings.ToUpper(match[1]), strings.ReplaceAll(match[2], " ", "")
+	mapped, known := ddlTypes[dialect][base]
+	if !known {
+		if dialect == DialectSQLite {
+			return "TEXT"
+		}
+		return base + size
+	}
+	// A size carries over to the types that take one
+	if size != "" && dialect != DialectSQLite && sizedTypes[base] {
+		return strings.SplitN(mapped, "(", 2)[0] + size
+	}
+	return mapped
+}
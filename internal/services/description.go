@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Defaults for description length handling when not configured
+const (
+	defaultMaxDescriptionLength = 20000
+	defaultMaxKeywords          = 50
+)
+
+// ErrDescriptionTooLong is returned when a description exceeds the hard cap
+var ErrDescriptionTooLong = errors.New("description exceeds maximum length")
+
+// checkDescriptionLength enforces the hard cap on description size
+func (s *QueryService) checkDescriptionLength(description string) error {
+	limit := s.cfg.MaxDescriptionLength
+	if limit <= 0 {
+		limit = defaultMaxDescriptionLength
+	}
+	if length := utf8.RuneCountInString(description); length > limit {
+		return fmt.Errorf("%w: %d characters (limit %d)", ErrDescriptionTooLong, length, limit)
+	}
+	return nil
+}
+
+// maxKeywords returns the configured keyword budget
+func (s *QueryService) maxKeywords() int {
+	if s.cfg.MaxKeywords <= 0 {
+		return defaultMaxKeywords
+	}
+	return s.cfg.MaxKeywords
+}
+
+// summarizeKeywords reduces a long keyword list to at most max distinct
+// keywords, keeping the most frequent ones in their original order. When any
+// keyword is a known schema term, unknown words are dropped first since they
+// can only dilute match scores.
+func summarizeKeywords(keywords []string, max int, known func(string) bool) []string {
+	hasKnown := false
+	for _, keyword := range keywords {
+		if known(keyword) {
+			hasKnown = true
+			break
+		}
+	}
+
+	counts := make(map[string]int)
+	unique := make([]string, 0)
+	for _, keyword := range keywords {
+		if hasKnown && !known(keyword) {
+			continue
+		}
+		if counts[keyword] == 0 {
+			unique = append(unique, keyword)
+		}
+		counts[keyword]++
+	}
+	if len(unique) <= max {
+		return unique
+	}
+
+	// Rank by frequency, earlier keywords winning ties
+	ranked := make([]string, len(unique))
+	copy(ranked, unique)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return counts[ranked[i]] > counts[ranked[j]]
+	})
+	kept := make(map[string]bool, max)
+	for _, keyword := range ranked[:max] {
+		kept[keyword] = true
+	}
+
+	summary := make([]string, 0, max)
+	for _, keyword := range unique {
+		if kept[keyword] {
+			summary = append(summary, keyword)
+		}
+	}
+	return summary
+}
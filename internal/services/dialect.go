@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Supported SQL dialects
+const (
+	DialectPostgres  = "postgres"
+	DialectMySQL     = "mysql"
+	DialectSQLite    = "sqlite"
+	DialectSQLServer = "sqlserver"
+	DialectBigQuery  = "bigquery"
+	DialectSnowflake = "snowflake"
+)
+
+// ErrUnknownDialect is returned for an unsupported SQL dialect
+var ErrUnknownDialect = errors.New("unknown SQL dialect")
+
+// ResolveDialect normalizes a requested dialect; empty means postgres
+func ResolveDialect(dialect string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(dialect)) {
+	case "", DialectPostgres, "postgresql":
+		return DialectPostgres, nil
+	case DialectMySQL:
+		return DialectMySQL, nil
+	case DialectSQLite, "sqlite3":
+		return DialectSQLite, nil
+	case DialectSQLServer, "mssql", "tsql":
+		return DialectSQLServer, nil
+	case DialectBigQuery:
+		return DialectBigQuery, nil
+	case DialectSnowflake:
+		return DialectSnowflake, nil
+	default:
+		return "", fmt.Errorf("%w %q: use postgres, mysql, sqlite, sqlserver, bigquery, or snowflake", ErrUnknownDialect, dialect)
+	}
+}
+
+// queryDialect picks the dialect generated SQL is rendered in: the
+// request's, else the configured default. With neither, queries stay in
+// portable SQL (anyDialect), which postgres and most others accept
+func (s *QueryService) queryDialect(requested string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		requested = s.cfg.Dialect
+	}
+	if strings.TrimSpace(requested) == "" {
+		return anyDialect, nil
+	}
+	return ResolveDialect(requested)
+}
+
+// quoteString renders a SQL string literal for a dialect. MySQL and
+// Snowflake also treat backslashes as escapes; BigQuery only escapes quotes
+// with a backslash
+func quoteString(dialect, value string) string {
+	switch dialect {
+	case DialectMySQL, DialectSnowflake:
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	case DialectBigQuery:
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// unboundedLimits are the LIMIT a dialect needs before an OFFSET when no
+// row limit was asked for; dialects not listed accept a bare OFFSET
+var unboundedLimits = map[string]string{
+	DialectMySQL:     "LIMIT 18446744073709551615",
+	DialectSQLite:    "LIMIT -1",
+	DialectBigQuery:  "LIMIT 9223372036854775807",
+	DialectSnowflake: "LIMIT NULL",
+}
+
+// rowLimit renders a query's row limit and offset for a dialect, as a
+// prefix for the select list and a clause to append. SQL Server limits with
+// TOP, and pages with OFFSET ... FETCH, which needs an ORDER BY; ordered
+// says whether the query already has one
+func rowLimit(dialect string, limit, offset int, ordered bool) (string, string) {
+	if limit <= 0 && offset <= 0 {
+		return "", ""
+	}
+	if dialect == DialectSQLServer {
+		if offset <= 0 {
+			return fmt.Sprintf("TOP %d ", limit), ""
+		}
+		clause := fmt.Sprintf("OFFSET %d ROWS", offset)
+		if limit > 0 {
+			clause += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", limit)
+		}
+		if !ordered {
+			clause = "ORDER BY (SELECT NULL) " + clause
+		}
+		return "", clause
+	}
+
+	var clauses []string
+	if limit > 0 {
+		clauses = append(clauses, fmt.Sprintf("LIMIT %d", limit))
+	} else if unbounded, exists := unboundedLimits[dialect]; exists {
+		clauses = append(clauses, unbounded)
+	}
+	if offset > 0 {
+		clauses = append(clauses, fmt.Sprintf("OFFSET %d", offset))
+	}
+	return "", strings.Join(clauses, " ")
+}
+
+// withTop puts rowLimit's select list prefix after any DISTINCT
+func withTop(selectClause, top string) string {
+	if top == "" {
+		return selectClause
+	}
+	if rest, distinct := strings.CutPrefix(selectClause, "DISTINCT "); distinct {
+		return "DISTINCT " + top + rest
+	}
+	return top + selectClause
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// dictionaryTable is one table of the data dictionary, with the ownership
+// and refresh details of its first field that has them
+type dictionaryTable struct {
+	Name    string
+	Details string
+	Fields  []dictionaryField
+}
+
+// dictionaryField is one column of the data dictionary
+type dictionaryField struct {
+	models.Field
+	Notes string
+}
+
+// dictionaryData is the HTML dictionary's template input
+type dictionaryData struct {
+	Version string
+	Tables  []dictionaryTable
+	Diagram string
+}
+
+// RenderDictionary renders the catalog as a data dictionary for analysts:
+// every table with its columns, descriptions, and system mappings, followed
+// by the joins between them as a Mermaid entity-relationship diagram. format
+// is markdown (the default) or html; the content type is returned with it
+func (s *FieldService) RenderDictionary(format string) (string, string, error) {
+	data := dictionaryData{Version: s.Version(), Tables: s.dictionaryTables(), Diagram: s.relationshipDiagram()}
+	switch format {
+	case "", FormatMarkdown:
+		return renderMarkdownDictionary(data), "text/markdown; charset=utf-8", nil
+	case FormatHTML:
+		var buf bytes.Buffer
+		if err := htmlDictionary.Execute(&buf, data); err != nil {
+			return "", "", fmt.Errorf("failed to render data dictionary: %w", err)
+		}
+		return buf.String(), "text/html; charset=utf-8", nil
+	default:
+		return "", "", fmt.Errorf("%w %q: use markdown or html", ErrUnknownFormat, format)
+	}
+}
+
+// dictionaryTables groups the catalog by table, in name order
+func (s *FieldService) dictionaryTables() []dictionaryTable {
+	byName := make(map[string][]models.Field)
+	for _, field := range s.fields {
+		byName[field.TableName] = append(byName[field.TableName], field)
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tables := make([]dictionaryTable, 0, len(names))
+	for _, name := range names {
+		table := dictionaryTable{Name: name, Details: tableDetails(byName[name])}
+		for _, field := range byName[name] {
+			table.Fields = append(table.Fields, dictionaryField{Field: field, Notes: fieldNotes(field)})
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// relationshipDiagram draws the catalog's foreign keys as a Mermaid
+// entity-relationship diagram, many referencing rows to one referenced row;
+// empty when there are none
+func (s *FieldService) relationshipDiagram() string {
+	var lines []string
+	for _, key := range foreignKeys(s.fields) {
+		pairs := make([]string, len(key.columns))
+		for i := range key.columns {
+			pairs[i] = key.columns[i] + " = " + key.foreignColumns[i]
+		}
+		lines = append(lines, fmt.Sprintf("    %s }o--|| %s : \"%s\"",
+			mermaidName(key.table), mermaidName(key.foreignTable), strings.Join(pairs, ", ")))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	sort.Strings(lines)
+	return "erDiagram\n" + strings.Join(lines, "\n")
+}
+
+// mermaidName makes a table name a valid Mermaid entity name
+func mermaidName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// tableDetails summarizes a table's owner and refresh schedule, taken from
+// the first of its fields that lists them
+func tableDetails(fields []models.Field) string {
+	var details []string
+	for _, field := range fields {
+		if field.Owner != "" {
+			owner := "Owner: " + field.Owner
+			if field.OwnerContact != "" {
+				owner += " (" + field.OwnerContact + ")"
+			}
+			details = append(details, owner)
+			break
+		}
+	}
+	for _, field := range fields {
+		if field.RefreshCadence != "" {
+			refresh := "Refreshed " + field.RefreshCadence
+			if field.FreshnessSLA != "" {
+				refresh += ", SLA " + field.FreshnessSLA
+			}
+			details = append(details, refresh)
+			break
+		}
+	}
+	return strings.Join(details, ". ")
+}
+
+// fieldNotes lists what an analyst should know about a field beyond its
+// description
+func fieldNotes(field models.Field) string {
+	var notes []string
+	if field.Deprecated {
+		notes = append(notes, "deprecated")
+	}
+	if field.Sensitive {
+		notes = append(notes, "sensitive")
+	}
+	if field.Classification != "" {
+		notes = append(notes, field.Classification)
+	}
+	if field.Measure != "" {
+		notes = append(notes, "measure: "+field.Measure)
+	}
+	if field.ForeignTable != "" && field.ForeignKey != "" {
+		reference := "references " + field.ForeignTable + "." + field.ForeignKey
+		if field.Cardinality != "" {
+			reference += " (" + strings.ReplaceAll(field.Cardinality, "_", "-") + ")"
+		}
+		if field.Nullable {
+			reference += ", nullable"
+		}
+		if field.JoinType == JoinTypeLeft {
+			reference += ", left join"
+		}
+		notes = append(notes, reference)
+	}
+	if len(field.Tags) > 0 {
+		notes = append(notes, "tags: "+strings.Join(field.Tags, ", "))
+	}
+	return strings.Join(notes, "; ")
+}
+
+// renderMarkdownDictionary renders the data dictionary as Markdown
+func renderMarkdownDictionary(data dictionaryData) string {
+	var b strings.Builder
+	b.WriteString("# Data dictionary\n\n")
+	fmt.Fprintf(&b, "Schema version `%s`, %d tables.\n\n", data.Version, len(data.Tables))
+	for _, table := range data.Tables {
+		fmt.Fprintf(&b, "- [%s](#%s)\n", table.Name, strings.ToLower(table.Name))
+	}
+
+	for _, table := range data.Tables {
+		fmt.Fprintf(&b, "\n## %s\n\n", table.Name)
+		if table.Details != "" {
+			b.WriteString(table.Details + "\n\n")
+		}
+		b.WriteString("| Column | Type | Description | System A | System B | Notes |\n")
+		b.WriteString("|---|---|---|---|---|---|\n")
+		for _, field := range table.Fields {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+				markdownCell(field.ColumnName), markdownCell(field.FieldType), markdownCell(field.Description),
+				markdownCell(field.SystemAFieldMap), markdownCell(field.SystemBFieldMap), markdownCell(field.Notes))
+		}
+	}
+
+	if data.Diagram != "" {
+		b.WriteString("\n## Joins\n\n```mermaid\n" + data.Diagram + "\n```\n")
+	}
+	return b.String()
+}
+
+// htmlDictionary renders the data dictionary as a standalone page; Mermaid
+// draws the join diagram when the page can load it, and html/template
+// escapes every value
+var htmlDictionary = template.Must(template.New("dictionary").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Data dictionary</title>
+<style>table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:4px 8px;text-align:left;vertical-align:top}</style>
+</head>
+<body>
+<h1>Data dictionary</h1>
+<p>Schema version <code>{{.Version}}</code>, {{len .Tables}} tables.</p>
+<ul>
+{{- range .Tables}}
+<li><a href="#{{.Name}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+{{- range .Tables}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+{{- if .Details}}
+<p>{{.Details}}</p>
+{{- end}}
+<table>
+<thead><tr><th>Column</th><th>Type</th><th>Description</th><th>System A</th><th>System B</th><th>Notes</th></tr></thead>
+<tbody>
+{{- range .Fields}}
+<tr><td>{{.ColumnName}}</td><td>{{.FieldType}}</td><td>{{.Description}}</td><td>{{.SystemAFieldMap}}</td><td>{{.SystemBFieldMap}}</td><td>{{.Notes}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- if .Diagram}}
+<h2 id="joins">Joins</h2>
+<pre class="mermaid">
+{{.Diagram}}
+</pre>
+<script type="module">
+import mermaid from "https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs";
+mermaid.initialize({startOnLoad: true});
+</script>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Drift alerting defaults, used when not configured
+const (
+	defaultDriftWindow         = 200
+	defaultDriftMinSamples     = 50
+	defaultDriftConfidenceDrop = 10.0
+	defaultDriftNoMatchRise    = 0.1
+)
+
+// maxDriftAlerts is how many recent alerts are kept for reporting
+const maxDriftAlerts = 20
+
+// driftWebhookTimeout bounds posting an alert to the webhook
+const driftWebhookTimeout = 10 * time.Second
+
+// driftOutcome is one generation: its confidence, or a miss
+type driftOutcome struct {
+	confidence float64
+	matched    bool
+}
+
+// driftWindow holds the outcomes of a schema version's latest requests in
+// a ring, with running totals
+type driftWindow struct {
+	version  string
+	outcomes []driftOutcome
+	next     int
+	count    int
+	sum      float64
+	matched  int
+}
+
+// add records an outcome, replacing the oldest once the window is full
+func (w *driftWindow) add(outcome driftOutcome) {
+	if w.count == len(w.outcomes) {
+		oldest := w.outcomes[w.next]
+		if oldest.matched {
+			w.sum -= oldest.confidence
+			w.matched--
+		}
+	} else {
+		w.count++
+	}
+	w.outcomes[w.next] = outcome
+	w.next = (w.next + 1) % len(w.outcomes)
+	if outcome.matched {
+		w.sum += outcome.confidence
+		w.matched++
+	}
+}
+
+// quality summarizes the window
+func (w *driftWindow) quality() models.SchemaQuality {
+	quality := models.SchemaQuality{Version: w.version, Requests: w.count}
+	if w.matched > 0 {
+		quality.AverageConfidence = w.sum / float64(w.matched)
+	}
+	if w.count > 0 {
+		quality.NoMatchRate = float64(w.count-w.matched) / float64(w.count)
+	}
+	return quality
+}
+
+// DriftMonitor tracks rolling match quality of the live schema version and
+// alerts when a reload makes it noticeably worse than the version it
+// replaced, which usually means a bad mapping change
+type DriftMonitor struct {
+	mu       sync.Mutex
+	log      *logrus.Logger
+	client   *http.Client
+	webhook  string
+	size     int
+	min      int
+	drop     float64
+	rise     float64
+	current  *driftWindow
+	baseline *models.SchemaQuality
+	alerted  bool
+	alerts   []models.DriftAlert
+}
+
+// NewDriftMonitor creates a monitor for the given schema version
+func NewDriftMonitor(cfg *config.Config, version string) *DriftMonitor {
+	log := logrus.New()
+	log.SetFormatter(&logrus.JSONFormatter{})
+
+	m := &DriftMonitor{
+		log:     log,
+		client:  &http.Client{Timeout: driftWebhookTimeout},
+		webhook: cfg.DriftWebhookURL,
+		size:    cfg.DriftWindow,
+		min:     cfg.DriftMinSamples,
+		drop:    cfg.DriftConfidenceDrop,
+		rise:    cfg.DriftNoMatchRise,
+	}
+	if m.size <= 0 {
+		m.size = defaultDriftWindow
+	}
+	if m.min <= 0 {
+		m.min = defaultDriftMinSamples
+	}
+	if m.min > m.size {
+		m.min = m.size
+	}
+	if m.drop <= 0 {
+		m.drop = defaultDriftConfidenceDrop
+	}
+	if m.rise <= 0 {
+		m.rise = defaultDriftNoMatchRise
+	}
+	m.current = m.newWindow(version)
+	return m
+}
+
+// newWindow starts an empty window for a version
+func (m *DriftMonitor) newWindow(version string) *driftWindow {
+	return &driftWindow{version: version, outcomes: make([]driftOutcome, m.size)}
+}
+
+// Switch starts tracking a newly activated schema version. The outgoing
+// version becomes the baseline when it saw enough requests to judge by;
+// otherwise the existing baseline is kept
+func (m *DriftMonitor) Switch(version string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if version == m.current.version {
+		return
+	}
+	if m.current.count >= m.min {
+		quality := m.current.quality()
+		m.baseline = &quality
+	}
+	m.current, m.alerted = m.newWindow(version), false
+}
+
+// Observe records a generation against version. Requests for any version
+// other than the live one, such as time-travel queries, are ignored
+func (m *DriftMonitor) Observe(version string, confidence float64, matched bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	if version != m.current.version {
+		m.mu.Unlock()
+		return
+	}
+	m.current.add(driftOutcome{confidence: confidence, matched: matched})
+	alert, raised := m.check()
+	m.mu.Unlock()
+
+	if raised {
+		m.raise(alert)
+	}
+}
+
+// check compares the live version with its baseline once it has enough
+// requests, at most once per version. Callers hold the lock
+func (m *DriftMonitor) check() (models.DriftAlert, bool) {
+	if m.alerted || m.baseline == nil || m.current.count < m.min {
+		return models.DriftAlert{}, false
+	}
+
+	quality := m.current.quality()
+	var reasons []string
+	// Confidence is only averaged over requests that matched something
+	if drop := m.baseline.AverageConfidence - quality.AverageConfidence; m.current.matched > 0 && drop >= m.drop {
+		reasons = append(reasons, fmt.Sprintf("average confidence fell %.1f points, from %.1f to %.1f",
+			drop, m.baseline.AverageConfidence, quality.AverageConfidence))
+	}
+	if rise := quality.NoMatchRate - m.baseline.NoMatchRate; rise >= m.rise {
+		reasons = append(reasons, fmt.Sprintf("no-match rate rose from %.1f%% to %.1f%%",
+			m.baseline.NoMatchRate*100, quality.NoMatchRate*100))
+	}
+	if len(reasons) == 0 {
+		return models.DriftAlert{}, false
+	}
+
+	m.alerted = true
+	alert := models.DriftAlert{
+		Version:         quality.Version,
+		PreviousVersion: m.baseline.Version,
+		Quality:         quality,
+		Baseline:        *m.baseline,
+		Reasons:         reasons,
+		RaisedAt:        time.Now(),
+	}
+	m.alerts = append(m.alerts, alert)
+	if len(m.alerts) > maxDriftAlerts {
+		m.alerts = m.alerts[len(m.alerts)-maxDriftAlerts:]
+	}
+	return alert, true
+}
+
+// raise logs an alert and posts it to the webhook in the background
+func (m *DriftMonitor) raise(alert models.DriftAlert) {
+	m.log.WithFields(logrus.Fields{
+		"version":          alert.Version,
+		"previous_version": alert.PreviousVersion,
+		"reasons":          alert.Reasons,
+	}).Warn("Match quality degraded after schema change")
+
+	if m.webhook == "" {
+		return
+	}
+	go func() {
+		body, err := json.Marshal(alert)
+		if err != nil {
+			return
+		}
+		resp, err := m.client.Post(m.webhook, "application/json", bytes.NewReader(body))
+		if err != nil {
+			m.log.WithError(err).Warn("Failed to post drift alert")
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			m.log.Warnf("Drift alert webhook returned %s", resp.Status)
+		}
+	}()
+}
+
+// Report returns the live version's quality, its baseline (nil until a
+// reload after enough requests), and recent alerts, newest last
+func (m *DriftMonitor) Report() (models.SchemaQuality, *models.SchemaQuality, []models.DriftAlert) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var baseline *models.SchemaQuality
+	if m.baseline != nil {
+		copied := *m.baseline
+		baseline = &copied
+	}
+	return m.current.quality(), baseline, append([]models.DriftAlert{}, m.alerts...)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// ErrDryRunFailed is returned when the database refuses to prepare a
+// generated query
+var ErrDryRunFailed = errors.New("the database rejected the query")
+
+// DryRun prepares a generated query on its system's database without
+// executing it, confirming the tables and columns it reads exist. When the
+// database rejects it, the mapped fields whose tables or columns the
+// database lacks are looked up in information_schema and listed with the
+// names the query used for them, and ErrDryRunFailed is returned
+func (s *QueryService) DryRun(estimator *Estimator, generated models.QueryResponse, request models.QueryRequest) (models.DryRunResult, error) {
+	conn, _, schema, err := estimator.connection(request.System)
+	if err != nil {
+		return models.DryRunResult{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), estimateTimeout)
+	defer cancel()
+
+	statement, err := conn.db.PrepareContext(ctx, generated.Query)
+	if err == nil {
+		statement.Close()
+		return models.DryRunResult{Valid: true}, nil
+	}
+	result := models.DryRunResult{Error: err.Error()}
+
+	names := s.fieldService.systemNames(request.System)
+	referenced := dryRunFields(generated)
+	tables := make([]string, 0, len(referenced))
+	for _, ref := range referenced {
+		tables = append(tables, names.table(ref.Table))
+	}
+	existing, lookupErr := databaseColumns(ctx, conn.db, conn.driver, schema, tables)
+	if lookupErr != nil {
+		result.Warnings = append(result.Warnings, "missing tables and columns weren't looked up: "+lookupErr.Error())
+		return result, fmt.Errorf("%w: %v", ErrDryRunFailed, err)
+	}
+
+	missingTables := make(map[string]bool)
+	for _, ref := range referenced {
+		table, column := names.table(ref.Table), names.column(ref.Table, ref.Column)
+		columns, exists := existing[physicalName(table)]
+		switch {
+		case !exists:
+			missingTables[table] = true
+		case !columns[strings.ToLower(column)]:
+			result.MissingColumns = append(result.MissingColumns, models.MissingColumn{
+				Field:  fieldKey(ref.Table, ref.Column),
+				Table:  table,
+				Column: column,
+			})
+		}
+	}
+	for table := range missingTables {
+		result.MissingTables = append(result.MissingTables, table)
+	}
+	sort.Strings(result.MissingTables)
+	return result, fmt.Errorf("%w: %v", ErrDryRunFailed, err)
+}
+
+// dryRunFields lists the mapped fields a generated query reads: those its
+// plan selects and filters on, and its join keys
+func dryRunFields(generated models.QueryResponse) []models.FieldRef {
+	var refs []models.FieldRef
+	seen := make(map[string]bool)
+	add := func(table, column string) {
+		if key := fieldKey(table, column); !seen[key] {
+			seen[key] = true
+			refs = append(refs, models.FieldRef{Table: table, Column: column})
+		}
+	}
+	for _, ref := range planFields(generated.Plan) {
+		add(ref.Table, ref.Column)
+	}
+	for _, join := range generated.JoinsUsed {
+		for _, column := range join.Columns {
+			add(join.From, column.From)
+			add(join.To, column.To)
+		}
+	}
+	return refs
+}
+
+// databaseColumns reads the columns of a database schema from
+// information_schema as lowercased column names by lowercased table name,
+// limited to the given tables unless there are none. An empty schema is
+// Postgres's current schema or MySQL's connection database
+func databaseColumns(ctx context.Context, db *sql.DB, driver, schema string, tables []string) (map[string]map[string]bool, error) {
+	query := `
+		SELECT c.TABLE_NAME, c.COLUMN_NAME
+		FROM information_schema.COLUMNS c
+		WHERE c.TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE())`
+	if driver == SchemaSourcePostgres {
+		query = `
+		SELECT c.table_name, c.column_name
+		FROM information_schema.columns c
+		WHERE c.table_schema = COALESCE(NULLIF($1, ''), current_schema())`
+	}
+	rows, err := db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		wanted[physicalName(table)] = true
+	}
+	columns := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan columns: %w", err)
+		}
+		table = strings.ToLower(table)
+		if len(wanted) > 0 && !wanted[table] {
+			continue
+		}
+		if columns[table] == nil {
+			columns[table] = make(map[string]bool)
+		}
+		columns[table][strings.ToLower(column)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	return columns, nil
+}
+
+// physicalName is the lowercased, unqualified, unquoted name of a table
+// as information_schema lists it
+func physicalName(table string) string {
+	if dot := strings.LastIndex(table, "."); dot >= 0 {
+		table = table[dot+1:]
+	}
+	return strings.ToLower(unquoteIdent(table))
+}
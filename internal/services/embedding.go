@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+)
+
+// Supported field matchers
+const (
+	MatcherKeyword   = "keyword"
+	MatcherEmbedding = "embedding"
+	MatcherHybrid    = "hybrid"
+)
+
+// Supported embedding providers. The local provider is lexical only: it
+// matches shared words and spellings, not meaning, so phrases like "how much
+// money did we make" only reach revenue fields through the http provider
+const (
+	EmbeddingProviderLocal = "local"
+	EmbeddingProviderHTTP  = "http"
+)
+
+// defaultEmbeddingDimensions is the vector size used by the local embedder
+const defaultEmbeddingDimensions = 256
+
+// embeddingBatchSize is the most texts sent to the http provider in one
+// request; OpenAI accepts up to 2048 inputs per request
+const embeddingBatchSize = 2048
+
+// Embedder converts texts into vectors for semantic comparison
+type Embedder interface {
+	Embed(texts []string) ([][]float64, error)
+}
+
+// NewEmbedder creates the embedder selected in the configuration
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+	switch cfg.EmbeddingProvider {
+	case "", EmbeddingProviderLocal:
+		dimensions := cfg.EmbeddingDimensions
+		if dimensions <= 0 {
+			dimensions = defaultEmbeddingDimensions
+		}
+		return &hashingEmbedder{dimensions: dimensions}, nil
+	case EmbeddingProviderHTTP:
+		if cfg.EmbeddingURL == "" {
+			return nil, fmt.Errorf("EMBEDDING_URL is required for the http embedding provider")
+		}
+		return &httpEmbedder{
+			url:    cfg.EmbeddingURL,
+			model:  cfg.EmbeddingModel,
+			apiKey: cfg.EmbeddingAPIKey,
+			client: &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.EmbeddingProvider)
+	}
+}
+
+// hashingEmbedder is a dependency-free embedder that hashes words and
+// character trigrams into a fixed-size vector. It is lexical only: it
+// captures spelling and morphology overlap but not synonyms, which need the
+// http provider's model
+type hashingEmbedder struct {
+	dimensions int
+}
+
+// Embed vectorizes each text independently
+func (e *hashingEmbedder) Embed(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embedOne(text)
+	}
+	return vectors, nil
+}
+
+// embedOne builds a normalized feature-hashed vector for a single text
+func (e *hashingEmbedder) embedOne(text string) []float64 {
+	vector := make([]float64, e.dimensions)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		e.add(vector, "w:"+word, 1.0)
+
+		padded := "^" + word + "$"
+		for i := 0; i+3 <= len(padded); i++ {
+			e.add(vector, "g:"+padded[i:i+3], 0.5)
+		}
+	}
+	normalize(vector)
+	return vector
+}
+
+// add hashes a feature into the vector using a signed bucket
+func (e *hashingEmbedder) add(vector []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	index := int(sum % uint64(e.dimensions))
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	vector[index] += weight
+}
+
+// httpEmbedder calls an OpenAI-compatible embeddings endpoint
+type httpEmbedder struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed sends the texts in batches of at most embeddingBatchSize, since
+// providers cap the inputs of one request, and returns their vectors in
+// the order of texts
+func (e *httpEmbedder) Embed(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedBatch embeds texts in a single request
+func (e *httpEmbedder) embedBatch(texts []string) ([][]float64, error) {
+	body, err := json.Marshal(embeddingRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request returned status %d", resp.StatusCode)
+	}
+
+	var decoded embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(decoded.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response has %d vectors, expected %d", len(decoded.Data), len(texts))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, item := range decoded.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response has invalid index %d", item.Index)
+		}
+		normalize(item.Embedding)
+		vectors[item.Index] = item.Embedding
+	}
+
+	return vectors, nil
+}
+
+// normalize scales a vector to unit length in place
+func normalize(vector []float64) {
+	var sum float64
+	for _, v := range vector {
+		sum += v * v
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+// cosineSimilarity compares two unit-length vectors
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// embeddingCache is the on-disk form of precomputed field embeddings. It is
+// only reused when both the schema version and the embedder match
+type embeddingCache struct {
+	SchemaVersion string      `json:"schema_version"`
+	Embedder      string      `json:"embedder"`
+	Vectors       [][]float64 `json:"vectors"`
+}
+
+// embeddingCachePath is where field embeddings are persisted: next to the
+// mapping file, or in EmbeddingCacheDir when set, which database and remote
+// sources require. Empty disables the cache
+func (s *FieldService) embeddingCachePath() string {
+	if !s.cfg.EmbeddingCache {
+		return ""
+	}
+	switch s.cfg.SchemaSource {
+	case "", SchemaSourceCSV:
+		// Merged mappings are cached next to the first file or directory
+		mappingPath := strings.TrimSpace(strings.Split(s.cfg.CSVPath, ",")[0])
+		dir := s.cfg.EmbeddingCacheDir
+		if isRemotePath(mappingPath) {
+			// Remote files have no local directory to sit next to
+			if dir == "" {
+				return ""
+			}
+			return filepath.Join(dir, path.Base(displayLocation(mappingPath))+".embeddings.json")
+		}
+		mappingPath = filepath.Clean(mappingPath)
+		if dir == "" {
+			dir = filepath.Dir(mappingPath)
+		}
+		return filepath.Join(dir, filepath.Base(mappingPath)+".embeddings.json")
+	default:
+		// Database schemas have no mapping file to sit next to
+		if s.cfg.EmbeddingCacheDir == "" {
+			return ""
+		}
+		return filepath.Join(s.cfg.EmbeddingCacheDir, s.cfg.SchemaSource+".embeddings.json")
+	}
+}
+
+// embedderIdentity names the embedder configuration; vectors from a different
+// provider, model, or size are never reused
+func (s *FieldService) embedderIdentity() string {
+	switch s.cfg.EmbeddingProvider {
+	case EmbeddingProviderHTTP:
+		return EmbeddingProviderHTTP + ":" + s.cfg.EmbeddingURL + ":" + s.cfg.EmbeddingModel
+	default:
+		dimensions := s.cfg.EmbeddingDimensions
+		if dimensions <= 0 {
+			dimensions = defaultEmbeddingDimensions
+		}
+		return EmbeddingProviderLocal + ":" + strconv.Itoa(dimensions)
+	}
+}
+
+// readEmbeddingCache returns cached vectors for the loaded schema, or nil
+// when there are none or they are stale
+func (s *FieldService) readEmbeddingCache(path string) ([][]float64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding cache: %w", err)
+	}
+
+	var cache embeddingCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding cache: %w", err)
+	}
+	if cache.SchemaVersion != s.version || cache.Embedder != s.embedderIdentity() || len(cache.Vectors) != len(s.fields) {
+		return nil, nil
+	}
+	return cache.Vectors, nil
+}
+
+// writeEmbeddingCache atomically persists vectors for the loaded schema
+func (s *FieldService) writeEmbeddingCache(path string, vectors [][]float64) error {
+	data, err := json.Marshal(embeddingCache{
+		SchemaVersion: s.version,
+		Embedder:      s.embedderIdentity(),
+		Vectors:       vectors,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode embedding cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".embeddings-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
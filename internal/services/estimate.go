@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Estimate methods: the planner's row estimate, or table statistics alone
+const (
+	EstimateMethodExplain    = "explain"
+	EstimateMethodTableStats = "table_stats"
+)
+
+// estimateTimeout bounds EXPLAIN and the statistics lookups
+const estimateTimeout = 10 * time.Second
+
+// ErrNoDatabase is returned when an operation needs DATABASE_URL and none is
+// configured
+var ErrNoDatabase = errors.New("no database configured")
+
+// ErrCostExceeded is returned when EXPLAIN costs a query above the limit
+var ErrCostExceeded = errors.New("query cost exceeds the limit")
+
+// Estimator estimates generated queries without running them, rejecting
+// those costing more than maxCost. Like execution, each system's queries
+// are estimated on its own connection, when it has one, and otherwise on
+// the default one. Schema is the database schema dry runs look up missing
+// columns in on the default connection
+type Estimator struct {
+	connections map[string]*connection
+	schema      string
+	maxCost     float64
+}
+
+// NewEstimator opens (lazily) the configured databases: DATABASE_URL as the
+// default connection and each of DATABASE_CONNECTIONS under its system's
+// name. A system with neither can't be estimated, returning ErrNoDatabase
+func NewEstimator(cfg *config.Config) (*Estimator, error) {
+	named, err := ParseDatabaseConnections(cfg.DatabaseConnections)
+	if err != nil {
+		return nil, err
+	}
+	estimator := &Estimator{connections: make(map[string]*connection), schema: cfg.DatabaseSchema, maxCost: cfg.MaxQueryCost}
+	if err := openConnections(cfg, named, estimator.UseConnection); err != nil {
+		return nil, err
+	}
+	return estimator, nil
+}
+
+// NewDatabaseEstimator estimates against an already open database, the
+// default connection unless db is nil, using the given driver's EXPLAIN
+// (postgres or mysql)
+func NewDatabaseEstimator(db *sql.DB, driver string) *Estimator {
+	estimator := &Estimator{connections: make(map[string]*connection)}
+	if db != nil {
+		estimator.UseConnection(DefaultConnection, db, driver)
+	}
+	return estimator
+}
+
+// UseConnection estimates the queries of a system, or the default
+// connection's, on an open database
+func (e *Estimator) UseConnection(name string, db *sql.DB, driver string) {
+	e.connections[strings.ToLower(name)] = &connection{db: db, driver: driver}
+}
+
+// connection picks the connection a system's queries are estimated on, its
+// name, and the schema missing columns are looked up in
+func (e *Estimator) connection(system string) (*connection, string, string, error) {
+	if e == nil {
+		return nil, "", "", ErrNoDatabase
+	}
+	conn, name, exists := pickConnection(e.connections, system)
+	if !exists {
+		return nil, "", "", fmt.Errorf("%w for system %q", ErrNoDatabase, system)
+	}
+	if name != DefaultConnection {
+		return conn, name, "", nil
+	}
+	return conn, name, e.schema, nil
+}
+
+// ownConnection is a system's own connection, without falling back to the
+// default one, or nil when it has none
+func (e *Estimator) ownConnection(system string) *connection {
+	if e == nil {
+		return nil
+	}
+	return e.connections[strings.ToLower(system)]
+}
+
+// Target resolves the connection a request's query is estimated, explained,
+// or dry-run on, rendering the query in its driver's dialect as execution
+// does. It returns ErrNoDatabase when the system has no connection, and
+// ErrDialectConflict when the request names another dialect
+func (e *Estimator) Target(request models.QueryRequest) (models.QueryRequest, error) {
+	conn, name, _, err := e.connection(request.System)
+	if err != nil {
+		return request, err
+	}
+	return connectionDialect(conn, name, request)
+}
+
+// openDatabase opens (lazily) a database with the configured connection
+// pool limits; limits left at zero keep database/sql's defaults
+func openDatabase(cfg *config.Config, driver, url string) (*sql.DB, error) {
+	db, err := sql.Open(driver, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if cfg.DBMaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	}
+	if cfg.DBMaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	}
+	if cfg.DBConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	}
+	return db, nil
+}
+
+// databaseDriver picks the driver for DATABASE_URL: the schema source when
+// it is a database, otherwise the URL's
+func databaseDriver(cfg *config.Config) (string, error) {
+	switch cfg.SchemaSource {
+	case SchemaSourcePostgres, SchemaSourceMySQL:
+		return cfg.SchemaSource, nil
+	}
+	return urlDriver(cfg.DatabaseURL)
+}
+
+// mysqlDSN matches a MySQL data source name,
+// [user[:password]@][protocol[(address)]]/dbname
+var mysqlDSN = regexp.MustCompile(`^(?:.*@)?(?:[a-z0-9]+(?:\([^)]*\))?)?/`)
+
+// urlDriver picks the driver for a database URL: postgres for postgres://
+// URLs and mysql for MySQL DSNs. Anything else has no driver here
+func urlDriver(url string) (string, error) {
+	lower := strings.ToLower(url)
+	switch {
+	case strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://"):
+		return SchemaSourcePostgres, nil
+	case !strings.Contains(lower, "://") && mysqlDSN.MatchString(lower):
+		return SchemaSourceMySQL, nil
+	}
+	scheme, _, _ := strings.Cut(lower, ":")
+	return "", fmt.Errorf("unsupported database URL scheme %q: use a postgres:// URL or a MySQL DSN", scheme)
+}
+
+// Estimate generates the query for a request and asks the database how large
+// its result would be. Estimates are capped at the request's limit, and a
+// COUNT returns one row. A query costing more than the limit is returned
+// with ErrCostExceeded
+func (s *QueryService) Estimate(estimator *Estimator, request models.QueryRequest) (models.EstimateResponse, error) {
+	request, err := estimator.Target(request)
+	if err != nil {
+		return models.EstimateResponse{}, err
+	}
+	generated, err := s.GenerateQuery(request)
+	if err != nil {
+		return models.EstimateResponse{}, err
+	}
+
+	estimate, err := s.Explain(estimator, generated, request)
+	if err != nil && !errors.Is(err, ErrCostExceeded) {
+		return models.EstimateResponse{}, err
+	}
+	estimate.Warnings = append(generated.Warnings, estimate.Warnings...)
+	return models.EstimateResponse{TraceID: generated.TraceID, Query: generated.Query, QueryEstimate: estimate}, err
+}
+
+// Explain runs EXPLAIN on a generated query on its system's database,
+// returning its plan, cost, and estimated size, with the tables it reads
+// sized under the system's names. A query costing more than the lower of
+// the configured limit and the request's MaxCost is returned with
+// ErrCostExceeded; one EXPLAIN failed on can't be costed, and is let
+// through with a warning
+func (s *QueryService) Explain(estimator *Estimator, generated models.QueryResponse, request models.QueryRequest) (models.QueryEstimate, error) {
+	names := s.fieldService.systemNames(request.System)
+	var tables []string
+	for _, table := range tablesUsed(generated.MatchedFields, generated.JoinsUsed) {
+		tables = append(tables, physicalName(names.table(table)))
+	}
+	return estimator.explain(generated, request, tables)
+}
+
+// explain runs EXPLAIN for Explain, sizing the given database tables
+func (e *Estimator) explain(generated models.QueryResponse, request models.QueryRequest, tables []string) (models.QueryEstimate, error) {
+	conn, _, _, err := e.connection(request.System)
+	if err != nil {
+		return models.QueryEstimate{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), estimateTimeout)
+	defer cancel()
+	var estimate models.QueryEstimate
+	if conn.driver == SchemaSourcePostgres {
+		estimate, err = EstimatePostgres(ctx, conn.db, generated.Query, tables)
+	} else {
+		estimate, err = EstimateMySQL(ctx, conn.db, generated.Query, tables)
+	}
+	if err != nil {
+		return models.QueryEstimate{}, fmt.Errorf("failed to estimate query: %w", err)
+	}
+
+	if generated.QueryType == QueryTypeCount && estimate.Method == EstimateMethodTableStats {
+		estimate.EstimatedRows = 1
+	}
+	if request.Limit > 0 && estimate.EstimatedRows > int64(request.Limit) {
+		estimate.EstimatedRows = int64(request.Limit)
+	}
+
+	limit := e.maxCost
+	if request.MaxCost > 0 && (limit == 0 || request.MaxCost < limit) {
+		limit = request.MaxCost
+	}
+	switch {
+	case limit == 0:
+	case estimate.Method != EstimateMethodExplain:
+		estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("the cost limit of %g wasn't checked without a plan", limit))
+	case estimate.EstimatedCost > limit:
+		return estimate, fmt.Errorf("%w: %g > %g", ErrCostExceeded, estimate.EstimatedCost, limit)
+	}
+	return estimate, nil
+}
+
+// EstimatePostgres estimates a query with EXPLAIN (FORMAT JSON), costing it
+// at its plan's total cost, and sizes the tables it reads from pg_class.
+// Without a plan, the largest table's row count stands in for the result
+func EstimatePostgres(ctx context.Context, db *sql.DB, query string, tables []string) (models.QueryEstimate, error) {
+	placeholders := make([]string, len(tables))
+	for i := range tables {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+	stats, err := tableStats(ctx, db, `
+		SELECT c.relname, GREATEST(c.reltuples, 0)::bigint, pg_total_relation_size(c.oid)
+		FROM pg_class c
+		WHERE c.relkind IN ('r', 'p', 'm') AND pg_table_is_visible(c.oid)
+		  AND c.relname IN (`+strings.Join(placeholders, ", ")+`)`, tables)
+	if err != nil {
+		return models.QueryEstimate{}, err
+	}
+
+	var plan string
+	if err := db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&plan); err != nil {
+		return fallbackEstimate(stats, err), nil
+	}
+	var plans []struct {
+		Plan json.RawMessage `json:"Plan"`
+	}
+	var top struct {
+		Rows float64 `json:"Plan Rows"`
+		Cost float64 `json:"Total Cost"`
+	}
+	if err := json.Unmarshal([]byte(plan), &plans); err != nil || len(plans) == 0 || json.Unmarshal(plans[0].Plan, &top) != nil {
+		return fallbackEstimate(stats, fmt.Errorf("unreadable plan")), nil
+	}
+	estimate := explainedEstimate(stats, int64(top.Rows))
+	estimate.EstimatedCost = top.Cost
+	estimate.Plan = plans[0].Plan
+	return estimate, nil
+}
+
+// EstimateMySQL estimates a query with EXPLAIN, multiplying each step's
+// examined rows by its filtered percentage, costs it at the rows all its
+// steps examine, and sizes the tables it reads from information_schema.
+// Without a plan, the largest table's row count stands in for the result
+func EstimateMySQL(ctx context.Context, db *sql.DB, query string, tables []string) (models.QueryEstimate, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tables)), ", ")
+	stats, err := tableStats(ctx, db, `
+		SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME IN (`+placeholders+`)`, tables)
+	if err != nil {
+		return models.QueryEstimate{}, err
+	}
+
+	rows, err := db.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return fallbackEstimate(stats, err), nil
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return fallbackEstimate(stats, err), nil
+	}
+	rowsColumn, filteredColumn := -1, -1
+	for i, column := range columns {
+		switch strings.ToLower(column) {
+		case "rows":
+			rowsColumn = i
+		case "filtered":
+			filteredColumn = i
+		}
+	}
+	if rowsColumn < 0 {
+		return fallbackEstimate(stats, fmt.Errorf("EXPLAIN has no rows column")), nil
+	}
+
+	estimated, examinedTotal := 1.0, 0.0
+	var steps []map[string]interface{}
+	values := make([]sql.NullString, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return fallbackEstimate(stats, err), nil
+		}
+		examined, _ := strconv.ParseFloat(values[rowsColumn].String, 64)
+		filtered := 100.0
+		if filteredColumn >= 0 && values[filteredColumn].Valid {
+			filtered, _ = strconv.ParseFloat(values[filteredColumn].String, 64)
+		}
+		if values[rowsColumn].Valid {
+			estimated *= examined * filtered / 100
+			examinedTotal += examined
+		}
+		step := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			step[column] = nil
+			if values[i].Valid {
+				step[column] = values[i].String
+			}
+		}
+		steps = append(steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return fallbackEstimate(stats, err), nil
+	}
+	estimate := explainedEstimate(stats, int64(estimated))
+	estimate.EstimatedCost = examinedTotal
+	estimate.Plan, _ = json.Marshal(steps)
+	return estimate, nil
+}
+
+// tableStats runs a (table, rows, bytes) statistics query over the tables
+func tableStats(ctx context.Context, db *sql.DB, query string, tables []string) ([]models.TableEstimate, error) {
+	if len(tables) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(tables))
+	for i, table := range tables {
+		args[i] = table
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.TableEstimate
+	for rows.Next() {
+		var stat models.TableEstimate
+		if err := rows.Scan(&stat.Table, &stat.Rows, &stat.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan table statistics: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	return stats, nil
+}
+
+// explainedEstimate combines the planner's row estimate with the bytes of
+// every table read
+func explainedEstimate(stats []models.TableEstimate, rows int64) models.QueryEstimate {
+	estimate := models.QueryEstimate{EstimatedRows: rows, Method: EstimateMethodExplain, Tables: stats}
+	for _, stat := range stats {
+		estimate.EstimatedBytes += stat.Bytes
+	}
+	return estimate
+}
+
+// fallbackEstimate estimates from table statistics alone when EXPLAIN fails
+func fallbackEstimate(stats []models.TableEstimate, cause error) models.QueryEstimate {
+	estimate := explainedEstimate(stats, 0)
+	estimate.Method = EstimateMethodTableStats
+	for _, stat := range stats {
+		if stat.Rows > estimate.EstimatedRows {
+			estimate.EstimatedRows = stat.Rows
+		}
+	}
+	estimate.Warnings = []string{fmt.Sprintf("EXPLAIN failed (%v); rows are estimated from the largest table", cause)}
+	return estimate
+}
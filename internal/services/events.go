@@ -0,0 +1,290 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Generation event outcomes
+const (
+	EventOutcomeGenerated = "generated"
+	EventOutcomeTemplate  = "template"
+	EventOutcomeNoMatch   = "no_match"
+)
+
+// Event stream defaults, used when not configured
+const (
+	defaultEventBuffer        = 1000
+	defaultEventBatchSize     = 100
+	defaultEventFlushInterval = 5 * time.Second
+)
+
+// eventPublishTimeout bounds publishing one batch
+const eventPublishTimeout = 10 * time.Second
+
+// EventSink publishes batches of generation events to an analytics
+// pipeline
+type EventSink interface {
+	Publish(ctx context.Context, events []models.GenerationEvent) error
+}
+
+// EventSinkFactory creates a sink from the configuration
+type EventSinkFactory func(cfg *config.Config) (EventSink, error)
+
+// eventSinks are the sinks EVENT_SINK can name. Supporting another
+// transport, such as a Kafka or NATS producer, is a new entry here
+var eventSinks = map[string]EventSinkFactory{
+	"http":   newHTTPEventSink,
+	"stdout": newWriterEventSink,
+}
+
+// RegisterEventSink makes a sink available to EVENT_SINK under name
+func RegisterEventSink(name string, factory EventSinkFactory) {
+	eventSinks[strings.ToLower(name)] = factory
+}
+
+// HTTPEventSink POSTs each batch as newline-delimited JSON
+type HTTPEventSink struct {
+	url    string
+	client *http.Client
+}
+
+// newHTTPEventSink creates a sink posting to EventSinkURL
+func newHTTPEventSink(cfg *config.Config) (EventSink, error) {
+	if cfg.EventSinkURL == "" {
+		return nil, fmt.Errorf("EVENT_SINK_URL is required for the http event sink")
+	}
+	return &HTTPEventSink{url: cfg.EventSinkURL, client: &http.Client{Timeout: eventPublishTimeout}}, nil
+}
+
+// Publish posts the batch, failing on a non-2xx response
+func (s *HTTPEventSink) Publish(ctx context.Context, events []models.GenerationEvent) error {
+	body, err := encodeEvents(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("event sink returned %s", resp.Status)
+	}
+	return nil
+}
+
+// WriterEventSink writes one JSON line per event, for log shippers to
+// forward
+type WriterEventSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newWriterEventSink creates a sink writing to stdout
+func newWriterEventSink(cfg *config.Config) (EventSink, error) {
+	return NewWriterEventSink(os.Stdout), nil
+}
+
+// NewWriterEventSink creates a sink writing JSON lines to w
+func NewWriterEventSink(w io.Writer) *WriterEventSink {
+	return &WriterEventSink{w: w}
+}
+
+// Publish writes the batch
+func (s *WriterEventSink) Publish(ctx context.Context, events []models.GenerationEvent) error {
+	body, err := encodeEvents(events)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(body)
+	return err
+}
+
+// encodeEvents renders events as newline-delimited JSON
+func encodeEvents(events []models.GenerationEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// EventStream buffers generation events and publishes them to a sink in
+// the background, in batches. Emitting never blocks a request: when the
+// buffer is full the event is dropped and counted
+type EventStream struct {
+	sink     EventSink
+	log      *logrus.Logger
+	events   chan models.GenerationEvent
+	batch    int
+	interval time.Duration
+	done     chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+	failed  int
+}
+
+// NewEventStream creates the stream configured by EventSink, or nil when
+// events are disabled
+func NewEventStream(cfg *config.Config) (*EventStream, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.EventSink))
+	if name == "" {
+		return nil, nil
+	}
+	factory, exists := eventSinks[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown event sink %q", cfg.EventSink)
+	}
+	sink, err := factory(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewEventStreamWithSink(cfg, sink), nil
+}
+
+// NewEventStreamWithSink starts a stream publishing to sink
+func NewEventStreamWithSink(cfg *config.Config, sink EventSink) *EventStream {
+	log := logrus.New()
+	log.SetFormatter(&logrus.JSONFormatter{})
+
+	buffer, batch, interval := cfg.EventBuffer, cfg.EventBatchSize, cfg.EventFlushInterval
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+	if batch <= 0 {
+		batch = defaultEventBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultEventFlushInterval
+	}
+
+	s := &EventStream{
+		sink:     sink,
+		log:      log,
+		events:   make(chan models.GenerationEvent, buffer),
+		batch:    batch,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Emit queues an event, dropping it when the buffer is full. A nil or
+// closed stream ignores events
+func (s *EventStream) Emit(event models.GenerationEvent) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		s.dropped++
+	}
+}
+
+// Stats returns how many events were dropped for a full buffer and how
+// many were lost to failed publishes
+func (s *EventStream) Stats() (dropped, failed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped, s.failed
+}
+
+// Close publishes the queued events and stops the stream
+func (s *EventStream) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+// run collects events into batches, publishing each when full or when the
+// flush interval passes
+func (s *EventStream) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	pending := make([]models.GenerationEvent, 0, s.batch)
+	for {
+		select {
+		case event, open := <-s.events:
+			if !open {
+				s.publish(pending)
+				return
+			}
+			pending = append(pending, event)
+			if len(pending) >= s.batch {
+				s.publish(pending)
+				pending = make([]models.GenerationEvent, 0, s.batch)
+			}
+		case <-ticker.C:
+			if len(pending) > 0 {
+				s.publish(pending)
+				pending = make([]models.GenerationEvent, 0, s.batch)
+			}
+		}
+	}
+}
+
+// publish sends one batch; failed batches are logged and dropped
+func (s *EventStream) publish(events []models.GenerationEvent) {
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+	defer cancel()
+	if err := s.sink.Publish(ctx, events); err != nil {
+		s.mu.Lock()
+		s.failed += len(events)
+		s.mu.Unlock()
+		s.log.WithError(err).WithField("events", len(events)).Warn("Failed to publish generation events")
+	}
+}
+
+// descriptionHash hashes a description for grouping in analytics; case and
+// spacing differences hash the same
+func descriptionHash(description string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(description)), " ")
+	digest := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,500 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Result caps, used when not configured
+const (
+	defaultResultMaxRows    = 1000
+	defaultResultMaxBytes   = 1 << 20
+	defaultStatementTimeout = 30 * time.Second
+)
+
+// Caps that cut off a result
+const (
+	TruncatedByRows  = "max_rows"
+	TruncatedByBytes = "max_bytes"
+)
+
+// ErrExecutionDisabled is returned when running queries isn't enabled
+var ErrExecutionDisabled = errors.New("query execution is disabled")
+
+// ErrStatementTimeout is returned when a query runs past the statement
+// timeout and is cancelled
+var ErrStatementTimeout = errors.New("statement timed out")
+
+// ErrDialectConflict is returned when a query to be run asks for a dialect
+// other than its connection's database
+var ErrDialectConflict = errors.New("dialect doesn't match the connection")
+
+// ResultLimits caps the rows and bytes of a result kept, and how long its
+// statement may run
+type ResultLimits struct {
+	MaxRows  int
+	MaxBytes int
+	Timeout  time.Duration
+}
+
+// DefaultConnection names the connection of DATABASE_URL, which runs the
+// queries of systems without a connection of their own
+const DefaultConnection = "default"
+
+// ErrInvalidConnections is returned for a malformed list of named database
+// connections
+var ErrInvalidConnections = errors.New("invalid database connections")
+
+// Executor runs generated queries in read-only transactions, keeping their
+// results within limits. Each system's queries run on its own connection,
+// when it has one, and otherwise on the default one. Results cut off by a
+// cap get a cursor to their next page, signed with cursorKey
+type Executor struct {
+	connections map[string]*connection
+	enabled     bool
+	limits      ResultLimits
+	cursorKey   []byte
+	cursorTTL   time.Duration
+}
+
+// connection is an open database and its driver
+type connection struct {
+	db     *sql.DB
+	driver string
+}
+
+// ParseDatabaseConnections reads named database connections given as
+// "system=url,system=url"
+func ParseDatabaseConnections(spec string) (map[string]string, error) {
+	connections := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, url, found := strings.Cut(entry, "=")
+		name, url = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(url)
+		if !found || name == "" || url == "" {
+			return nil, fmt.Errorf("%w: %q is not system=url", ErrInvalidConnections, entry)
+		}
+		if _, exists := connections[name]; exists {
+			return nil, fmt.Errorf("%w: %q is named twice", ErrInvalidConnections, name)
+		}
+		connections[name] = url
+	}
+	return connections, nil
+}
+
+// NewExecutor opens (lazily) the configured databases when execution is
+// enabled: DATABASE_URL as the default connection and each of
+// DATABASE_CONNECTIONS under its system's name. Otherwise every run
+// returns ErrExecutionDisabled, or ErrNoDatabase without a connection
+func NewExecutor(cfg *config.Config) (*Executor, error) {
+	named, err := ParseDatabaseConnections(cfg.DatabaseConnections)
+	if err != nil {
+		return nil, err
+	}
+	limits := ResultLimits{MaxRows: cfg.ResultMaxRows, MaxBytes: cfg.ResultMaxBytes, Timeout: cfg.StatementTimeout}
+	executor := NewDatabaseExecutor(nil, "", limits)
+	executor.enabled = cfg.ExecuteQueries
+	executor.cursorKey = cursorKey(cfg.CursorSecret)
+	if cfg.CursorTTL > 0 {
+		executor.cursorTTL = cfg.CursorTTL
+	}
+	if !cfg.ExecuteQueries {
+		return executor, nil
+	}
+
+	if err := openConnections(cfg, named, executor.UseConnection); err != nil {
+		return nil, err
+	}
+	return executor, nil
+}
+
+// openConnections opens (lazily) DATABASE_URL as the default connection,
+// when set, and each named connection, handing each to use
+func openConnections(cfg *config.Config, named map[string]string, use func(name string, db *sql.DB, driver string)) error {
+	if cfg.DatabaseURL != "" {
+		driver, err := databaseDriver(cfg)
+		if err != nil {
+			return err
+		}
+		db, err := openDatabase(cfg, driver, cfg.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		use(DefaultConnection, db, driver)
+	}
+	for name, url := range named {
+		driver, err := urlDriver(url)
+		if err != nil {
+			return fmt.Errorf("connection %q: %w", name, err)
+		}
+		db, err := openDatabase(cfg, driver, url)
+		if err != nil {
+			return fmt.Errorf("connection %q: %w", name, err)
+		}
+		use(name, db, driver)
+	}
+	return nil
+}
+
+// NewDatabaseExecutor runs queries on an already open database, the
+// default connection unless db is nil. Limits left at zero take their
+// defaults, and cursors are signed with a random key
+func NewDatabaseExecutor(db *sql.DB, driver string, limits ResultLimits) *Executor {
+	if limits.MaxRows <= 0 {
+		limits.MaxRows = defaultResultMaxRows
+	}
+	if limits.MaxBytes <= 0 {
+		limits.MaxBytes = defaultResultMaxBytes
+	}
+	if limits.Timeout <= 0 {
+		limits.Timeout = defaultStatementTimeout
+	}
+	executor := &Executor{
+		connections: make(map[string]*connection),
+		enabled:     true,
+		limits:      limits,
+		cursorKey:   cursorKey(""),
+		cursorTTL:   defaultCursorTTL,
+	}
+	if db != nil {
+		executor.UseConnection(DefaultConnection, db, driver)
+	}
+	return executor
+}
+
+// UseConnection runs the queries of a system, or the default connection's,
+// on an open database
+func (e *Executor) UseConnection(name string, db *sql.DB, driver string) {
+	e.connections[strings.ToLower(name)] = &connection{db: db, driver: driver}
+}
+
+// PoolStats reports each connection's pool by name, or nil without any
+func (e *Executor) PoolStats() map[string]models.PoolStats {
+	if e == nil || len(e.connections) == 0 {
+		return nil
+	}
+	pools := make(map[string]models.PoolStats, len(e.connections))
+	for name, conn := range e.connections {
+		stats := conn.db.Stats()
+		pools[name] = models.PoolStats{
+			MaxOpen:           stats.MaxOpenConnections,
+			Open:              stats.OpenConnections,
+			InUse:             stats.InUse,
+			Idle:              stats.Idle,
+			WaitCount:         stats.WaitCount,
+			WaitDurationMs:    stats.WaitDuration.Milliseconds(),
+			MaxIdleClosed:     stats.MaxIdleClosed,
+			MaxLifetimeClosed: stats.MaxLifetimeClosed,
+		}
+	}
+	return pools
+}
+
+// connection picks the connection a system's queries run on, and its name:
+// the system's own, else the default one. It reports why queries can't be
+// run, if they can't
+func (e *Executor) connection(system string) (*connection, string, error) {
+	if e == nil || !e.enabled {
+		return nil, "", ErrExecutionDisabled
+	}
+	conn, name, exists := pickConnection(e.connections, system)
+	if !exists {
+		return nil, "", fmt.Errorf("%w for system %q", ErrNoDatabase, system)
+	}
+	return conn, name, nil
+}
+
+// pickConnection finds a system's connection, and its name: the system's
+// own, else the default one
+func pickConnection(connections map[string]*connection, system string) (*connection, string, bool) {
+	name := strings.ToLower(system)
+	if conn, exists := connections[name]; exists {
+		return conn, name, true
+	}
+	conn, exists := connections[DefaultConnection]
+	return conn, DefaultConnection, exists
+}
+
+// ownConnection is a system's own connection, without falling back to the
+// default one, or nil when it has none or execution is disabled
+func (e *Executor) ownConnection(system string) *connection {
+	if e == nil || !e.enabled {
+		return nil
+	}
+	return e.connections[strings.ToLower(system)]
+}
+
+// Execute generates the query for a request and runs it, returning as much
+// of its result as the limits allow
+func (s *QueryService) Execute(executor *Executor, request models.QueryRequest) (models.ExecuteResponse, error) {
+	request, err := executionRequest(executor, request)
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+	generated, err := s.GenerateQuery(request)
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+
+	result, err := executor.Run(request.System, generated.Query, generated.Plan)
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+	return models.ExecuteResponse{
+		TraceID:     generated.TraceID,
+		Query:       generated.Query,
+		Warnings:    pagingWarnings(generated, result),
+		QueryResult: result,
+	}, nil
+}
+
+// ExecuteStream generates and runs a query like Execute, writing its rows
+// to out as they are read instead of collecting them. Nothing is written
+// when generation or the query fails, so the caller can still answer with
+// an error; the response has the result's counts and truncation
+func (s *QueryService) ExecuteStream(executor *Executor, request models.QueryRequest, out ResultWriter) (models.ExecuteResponse, error) {
+	request, err := executionRequest(executor, request)
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+	generated, err := s.GenerateQuery(request)
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+
+	result, err := executor.Stream(request.System, generated.Query, generated.Plan, out.Begin, out.WriteRow)
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+	if err := out.End(); err != nil {
+		return models.ExecuteResponse{}, fmt.Errorf("failed to write result: %w", err)
+	}
+	return models.ExecuteResponse{
+		TraceID:     generated.TraceID,
+		Query:       generated.Query,
+		Warnings:    pagingWarnings(generated, result),
+		QueryResult: result,
+	}, nil
+}
+
+// executionRequest resolves the connection a request's query runs on and
+// renders the query in its driver's dialect
+func executionRequest(executor *Executor, request models.QueryRequest) (models.QueryRequest, error) {
+	conn, name, err := executor.connection(request.System)
+	if err != nil {
+		return request, err
+	}
+	return connectionDialect(conn, name, request)
+}
+
+// connectionDialect renders a request's query in its connection's dialect.
+// A request naming another dialect is refused with ErrDialectConflict, as
+// its SQL wouldn't run there
+func connectionDialect(conn *connection, name string, request models.QueryRequest) (models.QueryRequest, error) {
+	if conn.driver == "" {
+		return request, nil
+	}
+	if strings.TrimSpace(request.Dialect) != "" {
+		dialect, err := ResolveDialect(request.Dialect)
+		if err != nil {
+			return request, err
+		}
+		if dialect != conn.driver {
+			return request, fmt.Errorf("%w: %s queries run on connection %q, which is %s", ErrDialectConflict, dialect, name, conn.driver)
+		}
+	}
+	request.Dialect = conn.driver
+	return request, nil
+}
+
+// pagingWarnings adds a warning to a generated query's when its result has
+// pages but no ORDER BY, so rows may move between them
+func pagingWarnings(generated models.QueryResponse, result models.QueryResult) []string {
+	if result.NextCursor == "" || (generated.Plan != nil && len(generated.Plan.OrderBy) > 0) {
+		return generated.Warnings
+	}
+	return append(generated.Warnings, "the query has no ORDER BY, so later pages may repeat or skip rows")
+}
+
+// Run runs a query on a system's connection in a read-only transaction,
+// reading rows until the result runs out or reaches a cap, and rolls the
+// transaction back. A statement running past the timeout is cancelled with
+// ErrStatementTimeout. Later pages are rendered from plan, the query's
+// plan, when it isn't nil
+func (e *Executor) Run(system, query string, plan *models.QueryPlan) (models.QueryResult, error) {
+	return e.collect(system, query, plan, 0)
+}
+
+// Stream runs a query like Run, but hands begin the result's columns once
+// the query ran and emit each row kept as it is read, instead of
+// collecting them
+func (e *Executor) Stream(system, query string, plan *models.QueryPlan, begin func(columns []string) error, emit func(row []interface{}) error) (models.QueryResult, error) {
+	return e.stream(system, query, plan, 0, begin, emit)
+}
+
+// Page runs the query a cursor came from again, on the same connection,
+// returning the page of its result the cursor points at, without
+// generating it again
+func (e *Executor) Page(cursor string) (models.QueryResult, error) {
+	page, err := e.pageCursor(cursor)
+	if err != nil {
+		return models.QueryResult{}, err
+	}
+	return e.collect(page.Connection, page.Query, page.Plan, page.Offset)
+}
+
+// StreamPage writes the page of a result a cursor points at to out
+func (e *Executor) StreamPage(cursor string, out ResultWriter) (models.QueryResult, error) {
+	page, err := e.pageCursor(cursor)
+	if err != nil {
+		return models.QueryResult{}, err
+	}
+	result, err := e.stream(page.Connection, page.Query, page.Plan, page.Offset, out.Begin, out.WriteRow)
+	if err != nil {
+		return models.QueryResult{}, err
+	}
+	if err := out.End(); err != nil {
+		return models.QueryResult{}, fmt.Errorf("failed to write result: %w", err)
+	}
+	return result, nil
+}
+
+// pageCursor reads a cursor whose connection is still configured
+func (e *Executor) pageCursor(cursor string) (resultCursor, error) {
+	if e == nil || !e.enabled {
+		return resultCursor{}, ErrExecutionDisabled
+	}
+	page, err := e.decodeCursor(cursor)
+	if err != nil {
+		return resultCursor{}, err
+	}
+	if _, exists := e.connections[page.Connection]; !exists {
+		return resultCursor{}, fmt.Errorf("%w: connection %q is no longer configured", ErrInvalidCursor, page.Connection)
+	}
+	return page, nil
+}
+
+// collect runs a query from an offset, collecting its rows
+func (e *Executor) collect(system, query string, plan *models.QueryPlan, offset int) (models.QueryResult, error) {
+	rows := make([][]interface{}, 0)
+	result, err := e.stream(system, query, plan, offset, nil, func(row []interface{}) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return models.QueryResult{}, err
+	}
+	result.Rows = rows
+	return result, nil
+}
+
+// stream runs a query from an offset, skipping the rows of earlier pages
+// in the database, and signs a cursor to the page after this one when a cap
+// cut it off. A page whose first row alone is over the byte cap gets none,
+// as it would point back at itself
+func (e *Executor) stream(system, query string, plan *models.QueryPlan, offset int, begin func(columns []string) error, emit func(row []interface{}) error) (models.QueryResult, error) {
+	conn, name, err := e.connection(system)
+	if err != nil {
+		return models.QueryResult{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.limits.Timeout)
+	defer cancel()
+	started := time.Now()
+
+	run := query
+	if offset > 0 {
+		run = pageQuery(conn.driver, query, plan, offset)
+	}
+	result, err := runQuery(ctx, conn.db, run, e.limits, begin, emit)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return models.QueryResult{}, fmt.Errorf("%w after %s", ErrStatementTimeout, e.limits.Timeout)
+	}
+	if err != nil {
+		return models.QueryResult{}, err
+	}
+	result.Connection = name
+	result.ElapsedMs = time.Since(started).Milliseconds()
+	if result.Truncated && result.RowCount > 0 {
+		result.NextCursor = e.encodeCursor(name, query, plan, offset+result.RowCount)
+	}
+	return result, nil
+}
+
+// runQuery reads a query's rows within the caps
+func runQuery(ctx context.Context, db *sql.DB, query string, limits ResultLimits, begin func(columns []string) error, emit func(row []interface{}) error) (models.QueryResult, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return models.QueryResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return models.QueryResult{}, fmt.Errorf("failed to run query: %w", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return models.QueryResult{}, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	if begin != nil {
+		if err := begin(columns); err != nil {
+			return models.QueryResult{}, fmt.Errorf("failed to write result: %w", err)
+		}
+	}
+
+	result := models.QueryResult{Columns: columns}
+	for rows.Next() {
+		if result.RowCount == limits.MaxRows {
+			result.Truncated, result.TruncatedBy = true, TruncatedByRows
+			break
+		}
+		row, err := scanRow(rows, len(columns))
+		if err != nil {
+			return models.QueryResult{}, err
+		}
+		encoded, _ := json.Marshal(row)
+		if result.Bytes+len(encoded) > limits.MaxBytes {
+			result.Truncated, result.TruncatedBy = true, TruncatedByBytes
+			break
+		}
+		if err := emit(row); err != nil {
+			return models.QueryResult{}, fmt.Errorf("failed to write result: %w", err)
+		}
+		result.RowCount++
+		result.Bytes += len(encoded)
+	}
+	if err := rows.Err(); err != nil {
+		return models.QueryResult{}, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return result, nil
+}
+
+// scanRow scans the current row, with byte slices as strings
+func scanRow(rows *sql.Rows, width int) ([]interface{}, error) {
+	values := make([]interface{}, width)
+	targets := make([]interface{}, width)
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+	for i, value := range values {
+		if raw, ok := value.([]byte); ok {
+			values[i] = string(raw)
+		}
+	}
+	return values, nil
+}
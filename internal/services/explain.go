@@ -0,0 +1,123 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Parts of a field a keyword can hit
+const (
+	MatchSourceDescription = "description"
+	MatchSourceColumnName  = "column_name"
+	MatchSourceSynonym     = "synonym"
+	MatchSourceGlossary    = "glossary"
+)
+
+// explainMatch lists which keywords hit which part of a field. Only
+// description and glossary hits contribute to the score; column name and
+// synonym hits are reported so that low-confidence matches are still
+// understandable
+func (s *FieldService) explainMatch(field models.Field, index int, terms []keywordTerm) []models.MatchedTerm {
+	words := strings.Fields(strings.ToLower(field.Description))
+	graded := s.cfg.KeywordScoring == KeywordScoringLevenshtein
+
+	var matched []models.MatchedTerm
+	for _, term := range terms {
+		keyword := strings.ToLower(term.keyword)
+
+		// Description: exact substring, then fuzzy alternatives, then edit distance
+		if word, exact, ok := findTermWord(words, keyword, term.alternatives); ok {
+			matched = append(matched, models.MatchedTerm{
+				Keyword: term.keyword,
+				Source:  MatchSourceDescription,
+				Text:    word,
+				Exact:   exact,
+			})
+		} else if graded {
+			if word, ok := closestWord(keyword, words, s.levenshteinMaxDistance()); ok {
+				matched = append(matched, models.MatchedTerm{
+					Keyword: term.keyword,
+					Source:  MatchSourceDescription,
+					Text:    word,
+				})
+			}
+		}
+
+		// Linked glossary term's preferred label and synonyms
+		if index < len(s.fieldTerms) && s.fieldTerms[index] != nil {
+			glossaryTerm := s.fieldTerms[index]
+			for _, label := range append([]string{glossaryTerm.Label}, glossaryTerm.Synonyms...) {
+				labelWords := strings.Fields(strings.ToLower(label))
+				if _, exact, ok := findTermWord(labelWords, keyword, term.alternatives); ok {
+					matched = append(matched, models.MatchedTerm{
+						Keyword: term.keyword,
+						Source:  MatchSourceGlossary,
+						Text:    label,
+						Exact:   exact,
+					})
+					break
+				}
+			}
+		}
+
+		// Column name, with underscores separating words
+		columnWords := strings.Split(strings.ToLower(field.ColumnName), "_")
+		if _, exact, ok := findTermWord(columnWords, keyword, term.alternatives); ok {
+			matched = append(matched, models.MatchedTerm{
+				Keyword: term.keyword,
+				Source:  MatchSourceColumnName,
+				Text:    field.ColumnName,
+				Exact:   exact,
+			})
+		}
+
+		// Per-system field names act as synonyms
+		for _, synonym := range []string{field.SystemAFieldMap, field.SystemBFieldMap} {
+			if synonym == "" {
+				continue
+			}
+			synonymWords := strings.Split(strings.ToLower(synonym), "_")
+			if _, exact, ok := findTermWord(synonymWords, keyword, term.alternatives); ok {
+				matched = append(matched, models.MatchedTerm{
+					Keyword: term.keyword,
+					Source:  MatchSourceSynonym,
+					Text:    synonym,
+					Exact:   exact,
+				})
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// findTermWord returns the first word containing the keyword, or failing that
+// one of its alternatives; exact reports whether the keyword itself hit
+func findTermWord(words []string, keyword string, alternatives []string) (word string, exact bool, ok bool) {
+	for _, w := range words {
+		if strings.Contains(w, keyword) {
+			return w, true, true
+		}
+	}
+	for _, alternative := range alternatives {
+		for _, w := range words {
+			if strings.Contains(w, alternative) {
+				return w, false, true
+			}
+		}
+	}
+	return "", false, false
+}
+
+// closestWord returns the word nearest the keyword within maxDistance edits
+func closestWord(keyword string, words []string, maxDistance int) (string, bool) {
+	best, bestDistance := "", maxDistance+1
+	for _, word := range words {
+		if distance := fuzzy.LevenshteinDistance(keyword, word); distance < bestDistance {
+			best, bestDistance = word, distance
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultFeedbackMaxBoost caps how many score points feedback can add or remove
+const defaultFeedbackMaxBoost = 20.0
+
+// feedbackCounts tallies how often a field was accepted or rejected for a keyword
+type feedbackCounts struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
+// Feedback caps: keywords counted per report, and distinct keywords kept;
+// reports past the latter still count for keywords already known
+const (
+	maxFeedbackReportKeywords = 20
+	maxFeedbackKeywords       = 10000
+)
+
+// ErrInvalidFeedback is returned for feedback that can't be recorded: no
+// keywords, or fields the catalog doesn't map
+var ErrInvalidFeedback = errors.New("invalid feedback")
+
+// FeedbackStore accumulates keyword → field acceptance statistics and
+// optionally persists them to a JSON file so they survive restarts. Writes
+// happen outside the statistics lock and are shared: reports arriving while
+// a write is in progress are all saved by the next one
+type FeedbackStore struct {
+	mu       sync.RWMutex
+	path     string
+	maxBoost float64
+	stats    map[string]map[string]*feedbackCounts
+
+	// writeMu serializes writes; generation counts the reports applied and
+	// persisted the generation last written
+	writeMu    sync.Mutex
+	generation uint64
+	persisted  uint64
+}
+
+// NewFeedbackStore loads existing statistics from path, if set and present
+func NewFeedbackStore(path string, maxBoost float64) (*FeedbackStore, error) {
+	if maxBoost <= 0 {
+		maxBoost = defaultFeedbackMaxBoost
+	}
+	store := &FeedbackStore{
+		path:     path,
+		maxBoost: maxBoost,
+		stats:    make(map[string]map[string]*feedbackCounts),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.stats); err != nil {
+		return nil, fmt.Errorf("failed to parse feedback stats: %w", err)
+	}
+	return store, nil
+}
+
+// Record updates statistics for every keyword/field pair and persists them.
+// When the write fails the report is taken back out of the statistics
+func (f *FeedbackStore) Record(keywords []string, fieldKeys []string, accepted bool) error {
+	f.mu.Lock()
+	keywords = f.admit(keywords)
+	f.apply(keywords, fieldKeys, accepted, 1)
+	f.generation++
+	generation := f.generation
+	f.mu.Unlock()
+
+	return f.flush(generation, func() {
+		f.mu.Lock()
+		f.apply(keywords, fieldKeys, accepted, -1)
+		f.mu.Unlock()
+	})
+}
+
+// admit lowercases and dedupes a report's keywords, keeping at most
+// maxFeedbackReportKeywords and leaving out new ones once the store holds
+// maxFeedbackKeywords; callers hold the lock
+func (f *FeedbackStore) admit(keywords []string) []string {
+	admitted := make([]string, 0, len(keywords))
+	seen := make(map[string]bool)
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(keyword)
+		if seen[keyword] || len(admitted) == maxFeedbackReportKeywords {
+			continue
+		}
+		if _, known := f.stats[keyword]; !known && len(f.stats) >= maxFeedbackKeywords {
+			continue
+		}
+		seen[keyword] = true
+		admitted = append(admitted, keyword)
+	}
+	return admitted
+}
+
+// apply adds (delta 1) or takes back (delta -1) a report, dropping counts
+// that fall to zero; callers hold the lock
+func (f *FeedbackStore) apply(keywords []string, fieldKeys []string, accepted bool, delta int) {
+	for _, keyword := range keywords {
+		if f.stats[keyword] == nil {
+			f.stats[keyword] = make(map[string]*feedbackCounts)
+		}
+		for _, key := range fieldKeys {
+			counts := f.stats[keyword][key]
+			if counts == nil {
+				counts = &feedbackCounts{}
+				f.stats[keyword][key] = counts
+			}
+			if accepted {
+				counts.Accepted += delta
+			} else {
+				counts.Rejected += delta
+			}
+			if counts.Accepted <= 0 && counts.Rejected <= 0 {
+				delete(f.stats[keyword], key)
+			}
+		}
+		if len(f.stats[keyword]) == 0 {
+			delete(f.stats, keyword)
+		}
+	}
+}
+
+// flush persists the statistics unless a write since the report's
+// generation already did. A failed write is rolled back before the next
+// write starts, so no later write saves the failed report
+func (f *FeedbackStore) flush(generation uint64, rollback func()) error {
+	if f.path == "" {
+		return nil
+	}
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if f.persisted >= generation {
+		return nil
+	}
+
+	f.mu.RLock()
+	data, err := json.Marshal(f.stats)
+	current := f.generation
+	f.mu.RUnlock()
+	if err == nil {
+		err = f.persist(data)
+	}
+	if err != nil {
+		rollback()
+		return err
+	}
+	f.persisted = current
+	return nil
+}
+
+// persist atomically writes encoded statistics to disk; callers hold writeMu
+func (f *FeedbackStore) persist(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".feedback-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write feedback stats: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write feedback stats: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write feedback stats: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write feedback stats: %w", err)
+	}
+	return nil
+}
+
+// Boost returns the score adjustment for a field given the request keywords,
+// in the range [-maxBoost, maxBoost]. Fields with no history get zero.
+func (f *FeedbackStore) Boost(keywords []string, fieldKey string) float64 {
+	if f == nil {
+		return 0
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var total float64
+	for _, keyword := range keywords {
+		counts := f.stats[strings.ToLower(keyword)][fieldKey]
+		if counts == nil {
+			continue
+		}
+		// Smoothed net acceptance rate so a single vote has limited effect
+		net := float64(counts.Accepted - counts.Rejected)
+		total += net / float64(counts.Accepted+counts.Rejected+1)
+	}
+	if len(keywords) == 0 {
+		return 0
+	}
+	return total / float64(len(keywords)) * f.maxBoost
+}
+
+// fieldKey identifies a field in feedback statistics
+func fieldKey(table, column string) string {
+	return table + "." + column
+}
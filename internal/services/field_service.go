@@ -1,21 +1,83 @@
 package services
 
 import (
-	"encoding/csv"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"math"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/mgarce/go_query_api/internal/config"
 	"github.com/mgarce/go_query_api/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
+// defaultParallelScoringMinFields is the catalog size at which matching
+// switches to the worker pool when not configured
+const defaultParallelScoringMinFields = 5000
+
 // FieldService handles field mappings and relationships
 type FieldService struct {
 	fields            []models.Field
 	relationshipGraph map[string]map[string]models.Join
+	cfg               *config.Config
 	log               *logrus.Logger
+
+	// Semantic matching state, populated when the embedding matcher is enabled
+	embedder        Embedder
+	fieldEmbeddings [][]float64
+	
+	// Fuzzy matching state; fuzzy is nil when fuzzy matching is disabled
+	fuzzy      *fuzzyMatcher
+	vocabulary []string
+	
+	// Phonetic fallback; nil when phonetic matching is disabled
+	phonetic *phoneticMatcher
+	
+	// Accepted/rejected feedback used to boost previously chosen fields
+	feedback *FeedbackStore
+	
+	// Weighted ranking strategies used to score fields
+	ranker *rankingPipeline
+	
+	// Business glossary terms linked to each field (by index), and the text
+	// keywords are matched against once glossary vocabulary is added
+	glossary   *Glossary
+	fieldTerms []*GlossaryTerm
+	matchTexts []string
+	
+	// Schema version (content digest) and the raw mapping file it came from
+	version string
+	source  []byte
+	
+	// Saved cohort filters; nil when none are configured
+	cohorts *CohortSet
+	
+	// Curated query templates; nil when none are configured
+	queryTemplates *QueryTemplateSet
+	
+	// Curated table aliases from the configuration and mappings, by table name
+	tableAliases map[string]string
+	
+	// Schema and database qualifying each table, by table name
+	tableLocations map[string]tableLocation
+	
+	// Mapping files merged into the field list, and any definitions they
+	// disagreed on
+	mappingFiles     []models.MappingFile
+	mergeDiagnostics []models.MergeDiagnostic
+	skippedRows      int
+	
+	// Fault injection for resilience testing; nil unless chaos mode is on
+	chaos *Chaos
+	
+	// Configured relationship weights, overriding the mappings' join_weight
+	joinWeights JoinWeights
 }
 
 // NewFieldService creates a new field service
@@ -26,102 +88,343 @@ func NewFieldService(cfg *config.Config) (*FieldService, error) {
 	service := &FieldService{
 		fields:            make([]models.Field, 0),
 		relationshipGraph: make(map[string]map[string]models.Join),
+		cfg:               cfg,
 		log:               log,
 	}
 	
-	if err := service.loadCSV(cfg.CSVPath); err != nil {
-		return nil, fmt.Errorf("failed to load CSV: %w", err)
+	if err := service.loadFields(); err != nil {
+		return nil, err
 	}
 	
+	// Configured join weights override the mappings'
+	joinWeights, err := ParseJoinWeights(cfg.JoinWeights)
+	if err != nil {
+		return nil, err
+	}
+	service.joinWeights = joinWeights
 	service.buildRelationshipGraph()
 	
+	// Configured table aliases override the mappings'
+	tableAliases, err := ParseTableAliases(cfg.TableAliases)
+	if err != nil {
+		return nil, err
+	}
+	service.buildTableAliases(tableAliases)
+	service.buildTableLocations()
+	
+	if cfg.GlossarySource != "" {
+		glossary, err := LoadGlossary(cfg.GlossarySource)
+		if err != nil {
+			return nil, err
+		}
+		service.linkGlossary(glossary)
+	}
+	
+	if cfg.CohortsPath != "" {
+		cohorts, err := LoadCohorts(cfg.CohortsPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := cohorts.checkTables(service.HasTable); err != nil {
+			return nil, err
+		}
+		service.cohorts = cohorts
+	}
+	
+	if cfg.QueryTemplatesPath != "" {
+		queryTemplates, err := LoadQueryTemplates(cfg.QueryTemplatesPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := queryTemplates.checkTables(service.HasTable); err != nil {
+			return nil, err
+		}
+		service.queryTemplates = queryTemplates
+	}
+	
+	fuzzyMatcher, err := newFuzzyMatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	service.fuzzy = fuzzyMatcher
+	service.vocabulary = buildVocabulary(service.fields)
+	
+	phoneticMatcher, err := newPhoneticMatcher(cfg, service.vocabulary)
+	if err != nil {
+		return nil, err
+	}
+	service.phonetic = phoneticMatcher
+	
+	feedback, err := NewFeedbackStore(cfg.FeedbackPath, cfg.FeedbackMaxBoost)
+	if err != nil {
+		return nil, err
+	}
+	service.feedback = feedback
+	
+	chaos, err := NewChaos(cfg)
+	if err != nil {
+		return nil, err
+	}
+	service.chaos = chaos
+	
+	ranker, err := newRankingPipeline(service, rankerSpec(service))
+	if err != nil {
+		return nil, err
+	}
+	service.ranker = ranker
+	
+	if ranker.usesRanker(RankerEmbedding) {
+		if err := service.loadEmbeddings(); err != nil {
+			return nil, fmt.Errorf("failed to embed field descriptions: %w", err)
+		}
+	}
+	
 	return service, nil
 }
 
-// loadCSV loads field mappings from a CSV file
-func (s *FieldService) loadCSV(path string) error {
-	file, err := os.Open(path)
+// requiredColumns are the mapping CSV columns every file must provide, in
+// their legacy positional order
+var requiredColumns = []string{
+	"column_name", "table_name", "system_a_fieldmap", "system_b_fieldmap",
+	"field_description", "field_type", "join_key", "foreign_table", "foreign_key",
+}
+
+// csvColumns maps header names to their position in each row
+type csvColumns map[string]int
+
+// newCSVColumns indexes the header row. Required columns missing from the
+// header fall back to their legacy position so headerless files still load.
+func newCSVColumns(header []string) csvColumns {
+	columns := make(csvColumns)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for i, name := range requiredColumns {
+		if _, exists := columns[name]; !exists {
+			columns[name] = i
+		}
+	}
+	return columns
+}
+
+// get returns the trimmed value of a named column, or "" if absent
+func (c csvColumns) get(row []string, name string) string {
+	i, exists := c[name]
+	if !exists || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// readCSV reads field mappings from a CSV file, returning them with the raw file
+func (s *FieldService) readCSV(path string) ([]models.Field, []byte, error) {
+	data, err := s.readSource(path)
 	if err != nil {
-		return fmt.Errorf("failed to open CSV file: %w", err)
+		return nil, nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
-	defer file.Close()
 	
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	delimiter, encodingName := CSVFormat(s.cfg, path)
+	records, err := readMappingRecords(data, delimiter, encodingName) // Short rows are reported and skipped below
 	if err != nil {
-		return fmt.Errorf("failed to read CSV: %w", err)
+		return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
 	}
 	
+	fields := make([]models.Field, 0)
+	
 	// Skip header row
 	if len(records) > 0 {
+		columns := newCSVColumns(records[0])
 		for i := 1; i < len(records); i++ {
 			row := records[i]
-			if len(row) < 9 {
+			if len(row) < len(requiredColumns) {
+				s.skippedRows++
 				s.log.Warnf("Skipping invalid CSV row: %v", row)
 				continue
 			}
 			
 			field := models.Field{
-				ColumnName:      row[0],
-				TableName:       row[1],
-				SystemAFieldMap: row[2],
-				SystemBFieldMap: row[3],
-				Description:     row[4],
-				FieldType:       row[5],
-				JoinKey:         row[6],
-				ForeignTable:    row[7],
-				ForeignKey:      row[8],
+				ColumnName:      columns.get(row, "column_name"),
+				TableName:       columns.get(row, "table_name"),
+				SystemAFieldMap: columns.get(row, "system_a_fieldmap"),
+				SystemBFieldMap: columns.get(row, "system_b_fieldmap"),
+				SystemATableMap: columns.get(row, "system_a_tablemap"),
+				SystemBTableMap: columns.get(row, "system_b_tablemap"),
+				Description:     columns.get(row, "field_description"),
+				FieldType:       columns.get(row, "field_type"),
+				JoinKey:         columns.get(row, "join_key"),
+				ForeignTable:    columns.get(row, "foreign_table"),
+				ForeignKey:      columns.get(row, "foreign_key"),
+				JoinGroup:       columns.get(row, "join_group"),
+				Cardinality:     parseCardinality(columns.get(row, "cardinality")),
+				Nullable:        parseFlag(columns.get(row, "nullable")),
+				JoinType:        parseJoinType(columns.get(row, "join_type")),
+				JoinWeight:      parseJoinWeight(columns.get(row, "join_weight")),
+				Owner:           columns.get(row, "owner"),
+				OwnerContact:    columns.get(row, "owner_contact"),
+				RefreshCadence:  strings.ToLower(columns.get(row, "refresh_cadence")),
+				FreshnessSLA:    columns.get(row, "freshness_sla"),
+				Tags:            parseTags(columns.get(row, "tags")),
+				Deprecated:      parseFlag(columns.get(row, "deprecated")),
+				GlossaryTerm:    columns.get(row, "glossary_term"),
+				Sensitive:       parseFlag(columns.get(row, "sensitive")),
+				TableAlias:      columns.get(row, "table_alias"),
+				SchemaName:      columns.get(row, "schema_name"),
+				DatabaseName:    columns.get(row, "database_name"),
+				Classification:  strings.ToLower(columns.get(row, "classification")),
+				Measure:         columns.get(row, "measure"),
+				DbtRef:          columns.get(row, "dbt_ref"),
 			}
 			
-			s.fields = append(s.fields, field)
+			fields = append(fields, field)
 		}
 	}
 	
-	s.log.Infof("Loaded %d fields from %s", len(s.fields), path)
-	return nil
+	return fields, data, nil
+}
+
+// setSource records the mapping file behind the loaded fields; the schema
+// version is a digest of its contents
+func (s *FieldService) setSource(data []byte) {
+	s.source = data
+	digest := sha256.Sum256(data)
+	s.version = hex.EncodeToString(digest[:6])
 }
 
 // buildRelationshipGraph builds a graph of table relationships for JOIN path finding
 func (s *FieldService) buildRelationshipGraph() {
-	for _, field := range s.fields {
-		// Skip fields without join relationships
-		if field.ForeignTable == "" || field.ForeignKey == "" {
-			continue
-		}
-		
+	for _, key := range foreignKeys(s.fields) {
 		// Create the source table node if it doesn't exist
-		if _, exists := s.relationshipGraph[field.TableName]; !exists {
-			s.relationshipGraph[field.TableName] = make(map[string]models.Join)
+		if _, exists := s.relationshipGraph[key.table]; !exists {
+			s.relationshipGraph[key.table] = make(map[string]models.Join)
 		}
 		
 		// Create the target table node if it doesn't exist
-		if _, exists := s.relationshipGraph[field.ForeignTable]; !exists {
-			s.relationshipGraph[field.ForeignTable] = make(map[string]models.Join)
+		if _, exists := s.relationshipGraph[key.foreignTable]; !exists {
+			s.relationshipGraph[key.foreignTable] = make(map[string]models.Join)
 		}
 		
 		// Add the relationship (bidirectional)
-		joinCondition := fmt.Sprintf("%s.%s = %s.%s", 
-			field.TableName, field.ColumnName,
-			field.ForeignTable, field.ForeignKey)
+		joinCondition := key.condition()
 		
 		// From source to target
-		s.relationshipGraph[field.TableName][field.ForeignTable] = models.Join{
-			From:      field.TableName,
-			To:        field.ForeignTable,
-			Condition: joinCondition,
+		s.relationshipGraph[key.table][key.foreignTable] = models.Join{
+			From:        key.table,
+			To:          key.foreignTable,
+			Condition:   joinCondition,
+			Columns:     key.joinColumns(false),
+			Cardinality: key.cardinality,
+			Nullable:    key.nullable,
+			Type:        key.joinType,
+			Weight:      s.joinWeights.weight(key),
 		}
 		
 		// From target to source (for bidirectional traversal)
-		s.relationshipGraph[field.ForeignTable][field.TableName] = models.Join{
-			From:      field.ForeignTable,
-			To:        field.TableName,
-			Condition: joinCondition,
+		s.relationshipGraph[key.foreignTable][key.table] = models.Join{
+			From:        key.foreignTable,
+			To:          key.table,
+			Condition:   joinCondition,
+			Columns:     key.joinColumns(true),
+			Cardinality: reverseCardinality(key.cardinality),
+			Nullable:    key.nullable,
+			Type:        key.joinType,
+			Weight:      s.joinWeights.weight(key),
 		}
 	}
 	
 	s.log.Infof("Built relationship graph with %d tables", len(s.relationshipGraph))
 }
 
+// relationshipCount counts the distinct joins in the relationship graph
+func (s *FieldService) relationshipCount() int {
+	count := 0
+	for _, edges := range s.relationshipGraph {
+		count += len(edges)
+	}
+	// Every join is stored in both directions
+	return count / 2
+}
+
+// buildVocabulary collects the distinct lowercase words used in field
+// descriptions and column names, the targets for fuzzy keyword expansion
+func buildVocabulary(fields []models.Field) []string {
+	seen := make(map[string]bool)
+	vocabulary := make([]string, 0)
+	for _, field := range fields {
+		text := strings.ToLower(field.Description + " " + strings.ReplaceAll(field.ColumnName, "_", " "))
+		for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if !seen[word] {
+				seen[word] = true
+				vocabulary = append(vocabulary, word)
+			}
+		}
+	}
+	sort.Strings(vocabulary)
+	return vocabulary
+}
+
+// IsKnownTerm reports whether a word appears in the schema vocabulary
+func (s *FieldService) IsKnownTerm(word string) bool {
+	i := sort.SearchStrings(s.vocabulary, word)
+	return i < len(s.vocabulary) && s.vocabulary[i] == word
+}
+
+// loadEmbeddings vectorizes every field so requests can be compared by cosine similarity
+func (s *FieldService) loadEmbeddings() error {
+	embedder, err := NewEmbedder(s.cfg)
+	if err != nil {
+		return err
+	}
+	
+	s.embedder = embedder
+	if s.chaos != nil {
+		s.embedder = chaosEmbedder{inner: embedder, chaos: s.chaos}
+	}
+	
+	// Reuse embeddings persisted for this schema version, if any; a broken
+	// cache only costs a re-embed
+	cachePath := s.embeddingCachePath()
+	if cachePath != "" {
+		vectors, err := s.readEmbeddingCache(cachePath)
+		if err != nil {
+			s.log.Warnf("Ignoring embedding cache: %v", err)
+		}
+		if vectors != nil {
+			s.fieldEmbeddings = vectors
+			s.log.Infof("Loaded %d field embeddings from %s", len(vectors), cachePath)
+			return nil
+		}
+	}
+	
+	texts := make([]string, len(s.fields))
+	for i, field := range s.fields {
+		texts[i] = fieldEmbeddingText(field)
+	}
+	
+	vectors, err := embedder.Embed(texts)
+	if err != nil {
+		return err
+	}
+	
+	s.fieldEmbeddings = vectors
+	s.log.Infof("Embedded %d field descriptions", len(vectors))
+	
+	if cachePath != "" {
+		if err := s.writeEmbeddingCache(cachePath, vectors); err != nil {
+			s.log.Warnf("Failed to persist field embeddings: %v", err)
+		}
+	}
+	return nil
+}
+
+// fieldEmbeddingText is the text embedded for a field: its description plus
+// its humanized table and column names
+func fieldEmbeddingText(field models.Field) string {
+	name := strings.ReplaceAll(field.TableName+" "+field.ColumnName, "_", " ")
+	return field.Description + " " + name
+}
+
 // GetAllFields returns all field mappings, optionally filtered by system
 func (s *FieldService) GetAllFields(system string) []models.Field {
 	if system == "" || system == "default" {
@@ -141,13 +444,270 @@ func (s *FieldService) GetAllFields(system string) []models.Field {
 	return filtered
 }
 
-// FindFieldMatches finds fields matching the given keywords with fuzzy matching
+// Version identifies the loaded mapping file by a digest of its contents
+func (s *FieldService) Version() string {
+	return s.version
+}
+
+// HasTable reports whether any field belongs to the table, ignoring case
+func (s *FieldService) HasTable(table string) bool {
+	for _, field := range s.fields {
+		if strings.EqualFold(field.TableName, strings.TrimSpace(table)) {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupField returns the mapping for a table and column
+func (s *FieldService) LookupField(table, column string) (models.Field, bool) {
+	for _, field := range s.fields {
+		if field.TableName == table && field.ColumnName == column {
+			return field, true
+		}
+	}
+	return models.Field{}, false
+}
+
+// RecordFeedback stores whether the fields chosen for a set of keywords were
+// accepted, so future rankings can favour (or demote) them. Every field
+// must be in the catalog, so feedback can't grow the statistics at will
+func (s *FieldService) RecordFeedback(keywords []string, fields []models.FieldRef, accepted bool) error {
+	keys := make([]string, 0, len(fields))
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if _, exists := s.LookupField(field.Table, field.Column); !exists {
+			return fmt.Errorf("%w: unknown field %s.%s", ErrInvalidFeedback, field.Table, field.Column)
+		}
+		if key := fieldKey(field.Table, field.Column); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return s.feedback.Record(keywords, keys, accepted)
+}
+
+// inheritFeedback carries over a previous schema's feedback store so a
+// reload keeps what was learned, including statistics held only in memory
+func (s *FieldService) inheritFeedback(previous *FieldService) {
+	if previous == nil || previous.feedback == nil {
+		return
+	}
+	s.feedback = previous.feedback
+	s.ranker.feedback = previous.feedback
+}
+
+// GetFieldsByOwner filters fields to those owned by the given team (case-insensitive)
+func (s *FieldService) GetFieldsByOwner(fields []models.Field, owner string) []models.Field {
+	filtered := make([]models.Field, 0)
+	for _, field := range fields {
+		if strings.EqualFold(field.Owner, owner) {
+			filtered = append(filtered, field)
+		}
+	}
+	return filtered
+}
+
+// GetFieldsByTag filters fields to those carrying a tag
+func (s *FieldService) GetFieldsByTag(fields []models.Field, tag string) []models.Field {
+	filtered := make([]models.Field, 0)
+	for _, field := range fields {
+		if hasAnyTag(field, []string{tag}) {
+			filtered = append(filtered, field)
+		}
+	}
+	return filtered
+}
+
+// GetTableOwners returns ownership details for the given tables, skipping
+// tables with no recorded owner
+func (s *FieldService) GetTableOwners(tables []string) []models.TableOwner {
+	owners := make([]models.TableOwner, 0)
+	for _, table := range tables {
+		for _, field := range s.fields {
+			if field.TableName == table && field.Owner != "" {
+				owners = append(owners, models.TableOwner{
+					Table:   table,
+					Owner:   field.Owner,
+					Contact: field.OwnerContact,
+				})
+				break
+			}
+		}
+	}
+	return owners
+}
+
+// DbtRefs returns the ref() or source() call dbt models read each table
+// through, for the tables the mappings record one for
+func (s *FieldService) DbtRefs() map[string]string {
+	refs := make(map[string]string)
+	for _, field := range s.fields {
+		if field.DbtRef != "" {
+			refs[field.TableName] = field.DbtRef
+		}
+	}
+	return refs
+}
+
+// GetFreshnessNotes returns a note for every table that is not loaded in
+// real time, so callers don't mistake batch data for live data
+func (s *FieldService) GetFreshnessNotes(tables []string) []models.FreshnessNote {
+	notes := make([]models.FreshnessNote, 0)
+	for _, table := range tables {
+		for _, field := range s.fields {
+			if field.TableName != table || field.RefreshCadence == "" {
+				continue
+			}
+			if field.RefreshCadence == "realtime" || field.RefreshCadence == "streaming" {
+				break
+			}
+			
+			note := fmt.Sprintf("%s is refreshed %s", table, field.RefreshCadence)
+			if field.FreshnessSLA != "" {
+				note += fmt.Sprintf("; data may be up to %s old", field.FreshnessSLA)
+			}
+			notes = append(notes, models.FreshnessNote{
+				Table:   table,
+				Cadence: field.RefreshCadence,
+				SLA:     field.FreshnessSLA,
+				Note:    note,
+			})
+			break
+		}
+	}
+	return notes
+}
+
+// FindFieldMatches finds fields matching the given keywords with fuzzy
+// matching, best first. A maxMatches of zero or less matches nothing
 func (s *FieldService) FindFieldMatches(keywords []string, threshold float64, maxMatches int) []models.FieldMatch {
-	matches := make([]models.FieldMatch, 0)
+	return s.FindFilteredFieldMatches(keywords, threshold, maxMatches, FieldFilter{}, logrus.NewEntry(s.log))
+}
+
+// FindFilteredFieldMatches finds matching fields among those the filter
+// admits, logging to the request's log entry
+func (s *FieldService) FindFilteredFieldMatches(keywords []string, threshold float64, maxMatches int, filter FieldFilter, log *logrus.Entry) []models.FieldMatch {
+	if maxMatches <= 0 {
+		return []models.FieldMatch{}
+	}
+	matches, _ := s.findFieldMatches(keywords, threshold, maxMatches, filter, time.Time{}, log)
+	return matches
+}
+
+// findFieldMatches is FindFilteredFieldMatches with a deadline (zero for
+// none). Scoring stops once the deadline passes, returning the best matches
+// so far and whether any fields went unscored
+func (s *FieldService) findFieldMatches(keywords []string, threshold float64, maxMatches int, filter FieldFilter, deadline time.Time, log *logrus.Entry) ([]models.FieldMatch, bool) {
+	// Embed the request once when semantic matching is enabled
+	var requestEmbedding []float64
+	if s.embedder != nil && len(keywords) > 0 {
+		vectors, err := s.embedder.Embed([]string{strings.Join(keywords, " ")})
+		if err != nil {
+			log.WithError(err).Warn("Embedding request failed, falling back to keyword matching")
+		} else {
+			requestEmbedding = vectors[0]
+		}
+	}
 	
-	for _, field := range s.fields {
-		// Calculate match score against field description
-		score := s.calculateMatchScore(field.Description, keywords)
+	// Pair each keyword with its fuzzy alternatives (none when disabled)
+	request := &RankRequest{
+		Keywords:  keywords,
+		Embedding: requestEmbedding,
+		terms:     s.fuzzy.expand(keywords, s.vocabulary),
+		deadline:  deadline,
+	}
+	matches, truncated := s.rankFields(request, threshold, maxMatches, filter, log)
+	
+	// Fall back to sound-alike words only when nothing else cleared the
+	// threshold and there is time left to try
+	if len(matches) == 0 && s.phonetic != nil && !truncated {
+		log.Debug("No fields cleared the threshold, retrying with sound-alike words")
+		request.terms = s.phonetic.expand(keywords)
+		matches, truncated = s.rankFields(request, threshold, maxMatches, filter, log)
+	}
+	
+	return matches, truncated
+}
+
+// rankFields scores every field against the request and returns the best
+// maxMatches above threshold, and whether the request deadline cut scoring short
+func (s *FieldService) rankFields(request *RankRequest, threshold float64, maxMatches int, filter FieldFilter, log *logrus.Entry) ([]models.FieldMatch, bool) {
+	// Small catalogs are cheaper to score on the calling goroutine
+	workers := runtime.GOMAXPROCS(0)
+	if workers < 2 || len(s.fields) < s.parallelScoringMinFields() {
+		top := newTopMatches(maxMatches)
+		truncated := s.scoreFields(0, len(s.fields), request, threshold, filter, top)
+		return top.results(), truncated
+	}
+	
+	// Shard the catalog across workers, each keeping its own top-K
+	shardSize := (len(s.fields) + workers - 1) / workers
+	log.Debugf("Scoring %d fields across %d workers", len(s.fields), workers)
+	partials := make([]*topMatches, workers)
+	truncated := make([]bool, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * shardSize
+		end := start + shardSize
+		if end > len(s.fields) {
+			end = len(s.fields)
+		}
+		partials[w] = newTopMatches(maxMatches)
+		if start >= end {
+			continue
+		}
+		
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			truncated[w] = s.scoreFields(start, end, request, threshold, filter, partials[w])
+		}(w, start, end)
+	}
+	wg.Wait()
+	
+	// Merge the partial results; catalog order keeps tie-breaking identical
+	// to the single-threaded path
+	top := newTopMatches(maxMatches)
+	for _, partial := range partials {
+		for _, candidate := range partial.heap {
+			top.offer(candidate)
+		}
+	}
+	
+	// Best matches first, limited to maxMatches
+	cut := false
+	for _, shard := range truncated {
+		cut = cut || shard
+	}
+	return top.results(), cut
+}
+
+// parallelScoringMinFields returns the catalog size at which scoring is sharded
+func (s *FieldService) parallelScoringMinFields() int {
+	if s.cfg.ParallelScoringMinFields <= 0 {
+		return defaultParallelScoringMinFields
+	}
+	return s.cfg.ParallelScoringMinFields
+}
+
+// scoreFields scores the fields in fields[start:end] that the filter admits and
+// offers those above threshold to top. It reports whether it stopped early
+// because the request deadline passed
+func (s *FieldService) scoreFields(start, end int, request *RankRequest, threshold float64, filter FieldFilter, top *topMatches) bool {
+	for i := start; i < end; i++ {
+		// Check the deadline between batches, always scoring the first
+		if i > start && (i-start)%budgetCheckInterval == 0 && !request.deadline.IsZero() && time.Now().After(request.deadline) {
+			return true
+		}
+		
+		field := s.fields[i]
+		if !filter.allows(field) {
+			continue
+		}
+		
+		// Score with the configured ranking pipeline
+		score, rank, boost, breakdown := s.ranker.rank(request, field, i)
 		
 		// Skip fields below threshold
 		if score < threshold {
@@ -159,44 +719,76 @@ func (s *FieldService) FindFieldMatches(keywords []string, threshold float64, ma
 			TableName:       field.TableName,
 			FieldDescription: field.Description,
 			MatchScore:      score,
+			ScoreBreakdown:   breakdown,
+			FeedbackBoost:    boost,
+			MatchedTerms:     s.explainMatch(field, i, request.terms),
+			Deprecated:       field.Deprecated,
+			Sensitive:        field.Sensitive,
+			Classification:   classificationLevel(field.Classification),
+			Measure:          field.Measure,
+			Glossary:         s.glossaryCitation(i),
 		}
 		
-		matches = append(matches, match)
+		top.offer(rankedMatch{match: match, rank: rank, order: i})
 	}
-	
-	// Sort matches by score (descending)
-	sortMatchesByScore(matches)
-	
-	// Limit number of matches
-	if len(matches) > maxMatches {
-		matches = matches[:maxMatches]
-	}
-	
-	return matches
+	return false
+}
+
+// semanticScore converts cosine similarity into a 0-100 score
+func semanticScore(request, field []float64) float64 {
+	return math.Max(cosineSimilarity(request, field), 0) * 100
 }
 
 // calculateMatchScore calculates how well the keywords match the description
 // Returns a score from 0-100, with 100 being a perfect match
-func (s *FieldService) calculateMatchScore(description string, keywords []string) float64 {
-	if len(keywords) == 0 {
+func (s *FieldService) calculateMatchScore(description string, terms []keywordTerm) float64 {
+	if len(terms) == 0 {
 		return 0
 	}
 	
 	description = strings.ToLower(description)
 	
-	// Count how many keywords are in the description
-	matchedCount := 0
-	for _, keyword := range keywords {
-		if strings.Contains(description, strings.ToLower(keyword)) {
-			matchedCount++
+	// Graded scoring gives partial credit by edit distance to description words
+	var words []string
+	graded := s.cfg.KeywordScoring == KeywordScoringLevenshtein
+	if graded {
+		words = strings.Fields(description)
+	}
+	
+	// Sum the credit for each keyword (or its fuzzy alternatives) in the description
+	var matched float64
+	for _, term := range terms {
+		if strings.Contains(description, strings.ToLower(term.keyword)) {
+			matched++
+			continue
+		}
+		credit := 0.0
+		for _, alternative := range term.alternatives {
+			if strings.Contains(description, alternative) {
+				credit = 1
+				break
+			}
+		}
+		if credit == 0 && graded {
+			credit = levenshteinCredit(strings.ToLower(term.keyword), words, s.levenshteinMaxDistance())
 		}
+		matched += credit
 	}
 	
 	// Calculate percentage of matched keywords
-	return float64(matchedCount) / float64(len(keywords)) * 100
+	return matched / float64(len(terms)) * 100
 }
 
-// FindJoinPath finds the shortest join path between tables
+// levenshteinMaxDistance returns the configured edit distance cutoff
+func (s *FieldService) levenshteinMaxDistance() int {
+	if s.cfg.LevenshteinMaxDistance <= 0 {
+		return defaultLevenshteinMaxDistance
+	}
+	return s.cfg.LevenshteinMaxDistance
+}
+
+// FindJoinPath finds the cheapest join path between tables by relationship
+// weight, the shortest when no weights are set
 func (s *FieldService) FindJoinPath(fromTable string, toTable string) ([]models.Join, error) {
 	// If tables are the same, no join needed
 	if fromTable == toTable {
@@ -211,8 +803,8 @@ func (s *FieldService) FindJoinPath(fromTable string, toTable string) ([]models.
 		return nil, fmt.Errorf("table %s not found in relationship graph", toTable)
 	}
 	
-	// Breadth-First Search to find shortest path
-	path, err := s.bfsShortestPath(fromTable, toTable)
+	// Take the cheapest path, preferring curated low-weight relationships
+	path, err := s.cheapestPath(fromTable, toTable)
 	if err != nil {
 		return nil, err
 	}
@@ -226,53 +818,3 @@ func (s *FieldService) FindJoinPath(fromTable string, toTable string) ([]models.
 	return joins, nil
 }
 
-// bfsShortestPath performs a BFS to find the shortest path between tables
-func (s *FieldService) bfsShortestPath(start, end string) ([]string, error) {
-	// Queue for BFS
-	queue := []string{start}
-	
-	// Track visited nodes to prevent cycles
-	visited := map[string]bool{start: true}
-	
-	// Track parents to reconstruct path
-	parents := make(map[string]string)
-	
-	for len(queue) > 0 {
-		// Dequeue current node
-		current := queue[0]
-		queue = queue[1:]
-		
-		// Check if we reached the destination
-		if current == end {
-			// Reconstruct path
-			path := []string{end}
-			for node := end; node != start; node = parents[node] {
-				path = append([]string{parents[node]}, path...)
-			}
-			return path, nil
-		}
-		
-		// Visit neighbors
-		for neighbor := range s.relationshipGraph[current] {
-			if !visited[neighbor] {
-				visited[neighbor] = true
-				parents[neighbor] = current
-				queue = append(queue, neighbor)
-			}
-		}
-	}
-	
-	return nil, fmt.Errorf("no join path found between %s and %s", start, end)
-}
-
-// sortMatchesByScore sorts field matches by score (descending)
-func sortMatchesByScore(matches []models.FieldMatch) {
-	// Simple bubble sort for now
-	for i := 0; i < len(matches); i++ {
-		for j := i + 1; j < len(matches); j++ {
-			if matches[i].MatchScore < matches[j].MatchScore {
-				matches[i], matches[j] = matches[j], matches[i]
-			}
-		}
-	}
-}
\ No newline at end of file
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// ErrUnknownTable is returned for a table hint naming no table in the mappings
+var ErrUnknownTable = errors.New("unknown table")
+
+// FieldFilter restricts which fields take part in matching. The zero value
+// admits every field that is neither deprecated nor sensitive
+type FieldFilter struct {
+	// IncludeTags, when set, admits only fields carrying at least one of these tags
+	IncludeTags []string
+	// ExcludeTags rejects fields carrying any of these tags
+	ExcludeTags []string
+	// IncludeDeprecated admits fields flagged as deprecated
+	IncludeDeprecated bool
+	// AllowSensitive admits fields flagged as sensitive (PII)
+	AllowSensitive bool
+	// Tables, when set, admits only fields from these tables
+	Tables []string
+	// ExcludeTables rejects fields from these tables
+	ExcludeTables []string
+}
+
+// allows reports whether a field passes the filter
+func (f FieldFilter) allows(field models.Field) bool {
+	if field.Deprecated && !f.IncludeDeprecated {
+		return false
+	}
+	if field.Sensitive && !f.AllowSensitive {
+		return false
+	}
+	if len(f.Tables) > 0 && !containsFold(f.Tables, field.TableName) {
+		return false
+	}
+	if containsFold(f.ExcludeTables, field.TableName) {
+		return false
+	}
+	if len(f.IncludeTags) > 0 && !hasAnyTag(field, f.IncludeTags) {
+		return false
+	}
+	return !hasAnyTag(field, f.ExcludeTags)
+}
+
+// hasAnyTag reports whether a field carries any of the tags, ignoring case
+func hasAnyTag(field models.Field, tags []string) bool {
+	for _, tag := range tags {
+		for _, fieldTag := range field.Tags {
+			if strings.EqualFold(fieldTag, strings.TrimSpace(tag)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values holds s, ignoring case and padding
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTags splits a comma-separated tag list, dropping blanks
+func parseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseFlag reads a boolean CSV cell such as "true", "yes", or "1"
+func parseFlag(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "y", "1":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkTables verifies every table the filter names is in the mappings, as
+// a hint naming no known table is almost always a typo
+func (f FieldFilter) checkTables(fields *FieldService) error {
+	for _, table := range append(append([]string{}, f.Tables...), f.ExcludeTables...) {
+		if !fields.HasTable(table) {
+			return fmt.Errorf("%w %q in table hints", ErrUnknownTable, table)
+		}
+	}
+	return nil
+}
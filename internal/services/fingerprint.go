@@ -0,0 +1,55 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// queryFingerprint hashes a rendered query normalized so that queries
+// differing only in literal values, placeholders, keyword case, or layout
+// hash the same: literals and placeholders become ?, IN lists collapse to
+// a single ?, and keywords are uppercased
+func queryFingerprint(query, dialect string) string {
+	tokens := tokenizeSQL(query, dialect)
+	normalized := make([]sqlToken, 0, len(tokens))
+	for i, token := range tokens {
+		switch {
+		case isLiteralToken(token.text) || strings.EqualFold(token.text, "TRUE") || strings.EqualFold(token.text, "FALSE"):
+			token.text = "?"
+		case isKeyword(tokens, i):
+			token.text = strings.ToUpper(token.text)
+		}
+		normalized = append(normalized, token)
+
+		// IN (?, ?, ?) collapses to IN (?) once its closing parenthesis is seen
+		if n := len(normalized); token.text == ")" && n >= 4 {
+			open := n - 2
+			for open > 0 && (normalized[open].text == "?" || normalized[open].text == ",") {
+				open--
+			}
+			if open > 0 && normalized[open].text == "(" && strings.EqualFold(normalized[open-1].text, "IN") && open+2 < n {
+				normalized = append(normalized[:open+2], token)
+			}
+		}
+	}
+	digest := sha256.Sum256([]byte(compactSQL(normalized)))
+	return hex.EncodeToString(digest[:])
+}
+
+// isLiteralToken reports whether a token is a string or numeric literal or
+// a bind placeholder
+func isLiteralToken(text string) bool {
+	if strings.HasPrefix(text, "'") || text == "?" {
+		return true
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil && strings.ContainsRune("0123456789.-", rune(text[0])) {
+		return true
+	}
+	if len(text) > 1 && strings.ContainsRune("$:@", rune(text[0])) {
+		_, err := strconv.Atoi(strings.TrimPrefix(text[1:], "p"))
+		return err == nil
+	}
+	return false
+}
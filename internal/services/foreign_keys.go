@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// foreignKey is a relationship from a table to a foreign table over one or
+// more column pairs
+type foreignKey struct {
+	table          string
+	foreignTable   string
+	columns        []string
+	foreignColumns []string
+	cardinality    string
+	nullable       bool
+	joinType       string
+	weight         float64
+}
+
+// foreignKeys groups the catalog's foreign key columns into relationships, in
+// catalog order. A table's rows that share a join_group and foreign table form
+// one composite key; rows without a join group are keys of their own
+func foreignKeys(fields []models.Field) []*foreignKey {
+	var keys []*foreignKey
+	groups := make(map[string]*foreignKey)
+	for _, field := range fields {
+		if field.ForeignTable == "" || field.ForeignKey == "" {
+			continue
+		}
+		group := fieldKey(field.TableName, field.ForeignTable) + "#" + field.JoinGroup
+		if key, exists := groups[group]; exists && field.JoinGroup != "" {
+			if !containsString(key.columns, field.ColumnName) {
+				key.columns = append(key.columns, field.ColumnName)
+				key.foreignColumns = append(key.foreignColumns, field.ForeignKey)
+			}
+			if key.cardinality == "" {
+				key.cardinality = field.Cardinality
+			}
+			key.nullable = key.nullable || field.Nullable
+			if key.joinType == "" {
+				key.joinType = field.JoinType
+			}
+			if key.weight == 0 {
+				key.weight = field.JoinWeight
+			}
+			continue
+		}
+		key := &foreignKey{
+			table:          field.TableName,
+			foreignTable:   field.ForeignTable,
+			columns:        []string{field.ColumnName},
+			foreignColumns: []string{field.ForeignKey},
+			cardinality:    field.Cardinality,
+			nullable:       field.Nullable,
+			joinType:       field.JoinType,
+			weight:         field.JoinWeight,
+		}
+		groups[group] = key
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// condition renders the key as a join condition, one equality per column
+// pair
+func (k *foreignKey) condition() string {
+	parts := make([]string, len(k.columns))
+	for i := range k.columns {
+		parts[i] = k.table + "." + k.columns[i] + " = " + k.foreignTable + "." + k.foreignColumns[i]
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// joinColumns pairs the key's columns from the table to the foreign table,
+// or the other way round when reversed
+func (k *foreignKey) joinColumns(reversed bool) []models.JoinColumn {
+	columns := make([]models.JoinColumn, len(k.columns))
+	for i := range k.columns {
+		columns[i] = models.JoinColumn{From: k.columns[i], To: k.foreignColumns[i]}
+		if reversed {
+			columns[i] = models.JoinColumn{From: k.foreignColumns[i], To: k.columns[i]}
+		}
+	}
+	return columns
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/mgarce/go_query_api/internal/config"
+)
+
+// Supported fuzzy matching algorithms
+const (
+	FuzzyAlgorithmNone        = "none"
+	FuzzyAlgorithmFuzzysearch = "fuzzysearch"
+	FuzzyAlgorithmJaroWinkler = "jaro_winkler"
+	FuzzyAlgorithmTrigram     = "trigram"
+)
+
+// Supported keyword scoring modes
+const (
+	KeywordScoringBinary      = "binary"
+	KeywordScoringLevenshtein = "levenshtein"
+)
+
+// defaultLevenshteinMaxDistance is the edit distance cutoff when not configured
+const defaultLevenshteinMaxDistance = 1
+
+// keywordTerm is a request keyword plus the vocabulary words it fuzzily matches
+type keywordTerm struct {
+	keyword      string
+	alternatives []string
+}
+
+// fuzzyMatcher expands request keywords to similar words from the schema
+// vocabulary, so near-misses like "custmer" still count as a hit
+type fuzzyMatcher struct {
+	algorithm       string
+	maxEditDistance int
+	prefixLength    int
+	topN            int
+	minSimilarity   float64
+}
+
+// newFuzzyMatcher creates the matcher selected in the configuration, or nil
+// when fuzzy matching is disabled
+func newFuzzyMatcher(cfg *config.Config) (*fuzzyMatcher, error) {
+	switch cfg.FuzzyAlgorithm {
+	case "", FuzzyAlgorithmNone:
+		return nil, nil
+	case FuzzyAlgorithmFuzzysearch, FuzzyAlgorithmJaroWinkler, FuzzyAlgorithmTrigram:
+	default:
+		return nil, fmt.Errorf("unknown fuzzy algorithm %q", cfg.FuzzyAlgorithm)
+	}
+
+	topN := cfg.FuzzyTopN
+	if topN <= 0 {
+		topN = 3
+	}
+	minSimilarity := cfg.FuzzyMinSimilarity
+	if minSimilarity <= 0 {
+		minSimilarity = 0.85
+	}
+
+	return &fuzzyMatcher{
+		algorithm:       cfg.FuzzyAlgorithm,
+		maxEditDistance: cfg.FuzzyMaxEditDistance,
+		prefixLength:    cfg.FuzzyPrefixLength,
+		topN:            topN,
+		minSimilarity:   minSimilarity,
+	}, nil
+}
+
+// expand pairs each keyword with its top-N most similar vocabulary words
+func (m *fuzzyMatcher) expand(keywords []string, vocabulary []string) []keywordTerm {
+	terms := make([]keywordTerm, len(keywords))
+	for i, keyword := range keywords {
+		terms[i] = keywordTerm{keyword: keyword}
+		if m == nil {
+			continue
+		}
+
+		type candidate struct {
+			word       string
+			similarity float64
+		}
+		var candidates []candidate
+		for _, word := range vocabulary {
+			if word == keyword {
+				continue
+			}
+			if similarity, ok := m.similarity(keyword, word); ok {
+				candidates = append(candidates, candidate{word, similarity})
+			}
+		}
+
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return candidates[a].similarity > candidates[b].similarity
+		})
+		for j := 0; j < len(candidates) && j < m.topN; j++ {
+			terms[i].alternatives = append(terms[i].alternatives, candidates[j].word)
+		}
+	}
+	return terms
+}
+
+// similarity scores two words in [0, 1] and reports whether they are close
+// enough to count as a match under the configured sensitivity
+func (m *fuzzyMatcher) similarity(keyword, word string) (float64, bool) {
+	if m.prefixLength > 0 {
+		if len(keyword) < m.prefixLength || len(word) < m.prefixLength ||
+			keyword[:m.prefixLength] != word[:m.prefixLength] {
+			return 0, false
+		}
+	}
+
+	distance := fuzzy.LevenshteinDistance(keyword, word)
+	if m.maxEditDistance > 0 && distance > m.maxEditDistance {
+		return 0, false
+	}
+
+	switch m.algorithm {
+	case FuzzyAlgorithmJaroWinkler:
+		similarity := jaroWinkler(keyword, word)
+		return similarity, similarity >= m.minSimilarity
+	case FuzzyAlgorithmTrigram:
+		similarity := trigramSimilarity(keyword, word)
+		return similarity, similarity >= m.minSimilarity
+	default:
+		// fuzzysearch matches when either word's characters appear in order
+		// in the other; rank by edit distance
+		if !fuzzy.MatchFold(keyword, word) && !fuzzy.MatchFold(word, keyword) {
+			return 0, false
+		}
+		longest := len(keyword)
+		if len(word) > longest {
+			longest = len(word)
+		}
+		return 1 - float64(distance)/float64(longest), true
+	}
+}
+
+// jaroWinkler computes the Jaro-Winkler similarity of two strings
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	window := len(a)
+	if len(b) > window {
+		window = len(b)
+	}
+	window = window/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	aMatched := make([]bool, len(a))
+	bMatched := make([]bool, len(b))
+	matches := 0
+	for i := range a {
+		lo, hi := i-window, i+window+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(b) {
+			hi = len(b)
+		}
+		for j := lo; j < hi; j++ {
+			if !bMatched[j] && a[i] == b[j] {
+				aMatched[i], bMatched[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	// Count transpositions between the matched characters
+	transpositions := 0
+	j := 0
+	for i := range a {
+		if !aMatched[i] {
+			continue
+		}
+		for !bMatched[j] {
+			j++
+		}
+		if a[i] != b[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(a)) + m/float64(len(b)) + (m-float64(transpositions)/2)/m) / 3
+
+	// Boost for a common prefix of up to four characters
+	prefix := 0
+	for prefix < 4 && prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// trigramSimilarity computes the Jaccard similarity of padded character trigrams
+func trigramSimilarity(a, b string) float64 {
+	gramsA, gramsB := trigrams(a), trigrams(b)
+	if len(gramsA) == 0 || len(gramsB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for gram := range gramsA {
+		if gramsB[gram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(gramsA)+len(gramsB)-shared)
+}
+
+// trigrams returns the set of trigrams in a word padded with boundary markers
+func trigrams(word string) map[string]bool {
+	padded := "  " + strings.ToLower(word) + " "
+	grams := make(map[string]bool)
+	for i := 0; i+3 <= len(padded); i++ {
+		grams[padded[i:i+3]] = true
+	}
+	return grams
+}
+
+// levenshteinCredit returns partial credit in [0, 1) for the description
+// word closest to keyword, or 0 when every word is beyond maxDistance edits
+func levenshteinCredit(keyword string, words []string, maxDistance int) float64 {
+	best := 0.0
+	for _, word := range words {
+		distance := fuzzy.LevenshteinDistance(keyword, word)
+		if distance > maxDistance {
+			continue
+		}
+		longest := len(keyword)
+		if len(word) > longest {
+			longest = len(word)
+		}
+		if credit := 1 - float64(distance)/float64(longest); credit > best {
+			best = credit
+		}
+	}
+	return best
+}
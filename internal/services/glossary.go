@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// GlossaryTerm is a governed business term with its official definition
+type GlossaryTerm struct {
+	ID         string   `json:"id" yaml:"id"`
+	Label      string   `json:"label" yaml:"label"`
+	Definition string   `json:"definition" yaml:"definition"`
+	Synonyms   []string `json:"synonyms" yaml:"synonyms"`
+	// Fields links the term to table.column fields, alongside the mapping
+	// CSV's glossary_term column
+	Fields []string `json:"fields" yaml:"fields"`
+}
+
+// Glossary indexes business terms by ID
+type Glossary struct {
+	Terms []GlossaryTerm `json:"terms" yaml:"terms"`
+
+	byID map[string]*GlossaryTerm
+}
+
+// LoadGlossary reads a glossary from a file (YAML or JSON by extension) or,
+// for http(s) sources, from a JSON API
+func LoadGlossary(source string) (*Glossary, error) {
+	var glossary Glossary
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch glossary: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("glossary API returned %s", resp.Status)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read glossary: %w", err)
+		}
+		if err := json.Unmarshal(data, &glossary); err != nil {
+			return nil, fmt.Errorf("failed to parse glossary: %w", err)
+		}
+	} else {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read glossary file: %w", err)
+		}
+		switch strings.ToLower(filepath.Ext(source)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &glossary)
+		default:
+			err = json.Unmarshal(data, &glossary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse glossary file: %w", err)
+		}
+	}
+
+	glossary.byID = make(map[string]*GlossaryTerm, len(glossary.Terms))
+	for i := range glossary.Terms {
+		term := &glossary.Terms[i]
+		if term.ID == "" {
+			return nil, fmt.Errorf("glossary term %q has no id", term.Label)
+		}
+		if _, exists := glossary.byID[term.ID]; exists {
+			return nil, fmt.Errorf("duplicate glossary term id %q", term.ID)
+		}
+		glossary.byID[term.ID] = term
+	}
+	return &glossary, nil
+}
+
+// Term returns the term with the given ID
+func (g *Glossary) Term(id string) (*GlossaryTerm, bool) {
+	if g == nil {
+		return nil, false
+	}
+	term, exists := g.byID[id]
+	return term, exists
+}
+
+// vocabulary is the text a term contributes to matching: its preferred
+// label and synonyms
+func (t *GlossaryTerm) vocabulary() string {
+	return strings.TrimSpace(t.Label + " " + strings.Join(t.Synonyms, " "))
+}
+
+// linkGlossary resolves each field's glossary term, from the mapping CSV or
+// the glossary's own field lists, and extends the text matched against
+func (s *FieldService) linkGlossary(glossary *Glossary) {
+	s.glossary = glossary
+	s.fieldTerms = make([]*GlossaryTerm, len(s.fields))
+	s.matchTexts = make([]string, len(s.fields))
+
+	linked := make(map[string]*GlossaryTerm)
+	for i := range glossary.Terms {
+		for _, key := range glossary.Terms[i].Fields {
+			linked[key] = &glossary.Terms[i]
+		}
+	}
+
+	var count int
+	for i := range s.fields {
+		field := &s.fields[i]
+		term := linked[fieldKey(field.TableName, field.ColumnName)]
+		if field.GlossaryTerm != "" {
+			if csvTerm, exists := glossary.Term(field.GlossaryTerm); exists {
+				term = csvTerm
+			} else {
+				s.log.Warnf("Field %s.%s references unknown glossary term %q", field.TableName, field.ColumnName, field.GlossaryTerm)
+			}
+		}
+
+		s.matchTexts[i] = field.Description
+		if term == nil {
+			continue
+		}
+		field.GlossaryTerm = term.ID
+		s.fieldTerms[i] = term
+		s.matchTexts[i] = field.Description + " " + term.vocabulary()
+		count++
+	}
+	s.log.Infof("Linked %d fields to %d glossary terms", count, len(glossary.Terms))
+}
+
+// matchText is the text keywords are matched against: the description plus
+// any linked glossary vocabulary
+func (s *FieldService) matchText(field models.Field, index int) string {
+	if index < len(s.matchTexts) {
+		return s.matchTexts[index]
+	}
+	return field.Description
+}
+
+// glossaryCitation returns the official definition for the field at index
+func (s *FieldService) glossaryCitation(index int) *models.GlossaryCitation {
+	if index >= len(s.fieldTerms) || s.fieldTerms[index] == nil {
+		return nil
+	}
+	term := s.fieldTerms[index]
+	return &models.GlossaryCitation{
+		TermID:     term.ID,
+		Label:      term.Label,
+		Definition: term.Definition,
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Go snippet formats: a function running the query with database/sql or
+// with sqlx, scanning its rows into a struct of the selected columns
+const (
+	FormatGo   = "go"
+	FormatSqlx = "sqlx"
+)
+
+// IsGoFormat reports whether a response format is a Go snippet
+func IsGoFormat(format string) bool {
+	return format == FormatGo || format == FormatSqlx
+}
+
+// goScanTypes are the Go types columns of each normalized field type scan
+// into. Any column may be null, so these are the sql.Null types; types
+// without an entry scan into sql.NullString
+var goScanTypes = map[string]string{
+	"DATE":        "sql.NullTime",
+	"TIMESTAMP":   "sql.NullTime",
+	"DATETIME":    "sql.NullTime",
+	"TIMESTAMPTZ": "sql.NullTime",
+	"BOOLEAN":     "sql.NullBool",
+	"BOOL":        "sql.NullBool",
+	"INTEGER":     "sql.NullInt64",
+	"INT":         "sql.NullInt64",
+	"SMALLINT":    "sql.NullInt64",
+	"BIGINT":      "sql.NullInt64",
+	"DECIMAL":     "sql.NullFloat64",
+	"NUMERIC":     "sql.NullFloat64",
+	"REAL":        "sql.NullFloat64",
+	"FLOAT":       "sql.NullFloat64",
+	"DOUBLE":      "sql.NullFloat64",
+}
+
+// goInitialisms are the name parts Go spells in capitals
+var goInitialisms = map[string]bool{"ID": true, "URL": true, "UUID": true, "IP": true, "API": true, "SQL": true, "HTTP": true}
+
+// GoSnippetQuery is the query a Go snippet runs: its plan, the parameters
+// bound to its placeholders, and the SQL to run as is when it has no plan
+type GoSnippetQuery struct {
+	TraceID    string
+	Dialect    string
+	Query      string
+	Plan       *models.QueryPlan
+	Parameters []models.QueryParameter
+}
+
+// goField is one field of the row struct of a snippet
+type goField struct {
+	name   string
+	goType string
+	column string
+}
+
+// RenderGoSnippet renders a ready-to-paste Go file running a query with
+// database/sql (go) or sqlx: the query, laid out a clause per line, a Row
+// struct typed from the selected fields' types, and a function binding the
+// parameters and scanning the rows. Unaliased aggregates are aliased so
+// sqlx can map them. It returns the snippet with its content type
+func (s *QueryService) RenderGoSnippet(snippetFormat string, query GoSnippetQuery) (string, string, error) {
+	if !IsGoFormat(snippetFormat) {
+		return "", "", fmt.Errorf("%w %q", ErrUnknownFormat, snippetFormat)
+	}
+	dialect, err := s.queryDialect(query.Dialect)
+	if err != nil {
+		return "", "", err
+	}
+
+	sqlText := query.Query
+	var fields []goField
+	if query.Plan != nil {
+		plan := *query.Plan
+		plan.Select = append([]models.PlanColumn{}, plan.Select...)
+		fields = s.goFields(&plan)
+		sqlText = formatSQL(renderPlan(dialect, plan), dialect, FormatPretty, s.style)
+	}
+
+	var args []string
+	usesSQL := snippetFormat == FormatGo
+	for _, parameter := range query.Parameters {
+		value := goValue(parameter.Value)
+		if parameter.Name != "" {
+			value = fmt.Sprintf("sql.Named(%q, %s)", parameter.Name, value)
+			usesSQL = true
+		}
+		args = append(args, value)
+	}
+	for _, field := range fields {
+		usesSQL = usesSQL || strings.HasPrefix(field.goType, "sql.")
+	}
+
+	var b strings.Builder
+	b.WriteString("package queries\n\nimport (\n\t\"context\"\n")
+	if usesSQL {
+		b.WriteString("\t\"database/sql\"\n")
+	}
+	if snippetFormat == FormatSqlx {
+		b.WriteString("\n\t\"github.com/jmoiron/sqlx\"\n")
+	}
+	b.WriteString(")\n\n")
+
+	if len(fields) > 0 {
+		b.WriteString("// Row is one row of the query's result\ntype Row struct {\n")
+		for _, field := range fields {
+			b.WriteString("\t" + field.name + " " + field.goType)
+			if snippetFormat == FormatSqlx {
+				b.WriteString(" `db:\"" + field.column + "\"`")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("// query was generated by go_query_api")
+	if query.TraceID != "" {
+		b.WriteString(" (trace " + query.TraceID + ")")
+	}
+	b.WriteString("\nconst query = " + goString(sqlText) + "\n\n")
+
+	call := "query"
+	if len(args) > 0 {
+		call += ", " + strings.Join(args, ", ")
+	}
+	switch {
+	case len(fields) == 0 && snippetFormat == FormatGo:
+		b.WriteString("// FetchRows runs the query, leaving its rows to the caller to scan\n")
+		b.WriteString("func FetchRows(ctx context.Context, db *sql.DB) (*sql.Rows, error) {\n")
+		b.WriteString("\treturn db.QueryContext(ctx, " + call + ")\n}\n")
+	case len(fields) == 0:
+		b.WriteString("// FetchRows runs the query, leaving its rows to the caller to scan\n")
+		b.WriteString("func FetchRows(ctx context.Context, db *sqlx.DB) (*sqlx.Rows, error) {\n")
+		b.WriteString("\treturn db.QueryxContext(ctx, " + call + ")\n}\n")
+	case snippetFormat == FormatGo:
+		targets := make([]string, len(fields))
+		for i, field := range fields {
+			targets[i] = "&row." + field.name
+		}
+		b.WriteString("// FetchRows runs the query and scans its rows\n")
+		b.WriteString("func FetchRows(ctx context.Context, db *sql.DB) ([]Row, error) {\n")
+		b.WriteString("\trows, err := db.QueryContext(ctx, " + call + ")\n")
+		b.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n\tdefer rows.Close()\n\n")
+		b.WriteString("\tvar result []Row\n\tfor rows.Next() {\n\t\tvar row Row\n")
+		b.WriteString("\t\tif err := rows.Scan(" + strings.Join(targets, ", ") + "); err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+		b.WriteString("\t\tresult = append(result, row)\n\t}\n\treturn result, rows.Err()\n}\n")
+	default:
+		b.WriteString("// FetchRows runs the query and scans its rows\n")
+		b.WriteString("func FetchRows(ctx context.Context, db *sqlx.DB) ([]Row, error) {\n")
+		b.WriteString("\tvar rows []Row\n\terr := db.SelectContext(ctx, &rows, " + call + ")\n\treturn rows, err\n}\n")
+	}
+
+	formatted, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render Go snippet: %w", err)
+	}
+	return string(formatted), "text/plain; charset=utf-8", nil
+}
+
+// goFields types the selected columns of a plan as row struct fields,
+// aliasing aggregates that have no alias after their function and column
+func (s *QueryService) goFields(plan *models.QueryPlan) []goField {
+	fields := make([]goField, 0, len(plan.Select))
+	names := make(map[string]bool)
+	for i, column := range plan.Select {
+		field, _ := s.fieldService.LookupField(column.Table, column.Column)
+		goType, known := goScanTypes[literalType(field.FieldType)]
+		if !known {
+			goType = "sql.NullString"
+		}
+
+		name := column.Column
+		switch column.Aggregate {
+		case "":
+		case "COUNT":
+			goType = "int64"
+		case "MIN", "MAX":
+		default:
+			goType = "sql.NullFloat64"
+		}
+		if column.Aggregate != "" && column.Alias == "" {
+			plan.Select[i].Alias = strings.Trim(strings.ToLower(column.Aggregate)+"_"+column.Column, "_")
+		}
+		if plan.Select[i].Alias != "" {
+			name = plan.Select[i].Alias
+		}
+
+		goName := goIdentifier(name)
+		if names[goName] {
+			goName = goIdentifier(column.Table + "_" + name)
+		}
+		for n := 2; names[goName]; n++ {
+			goName = goIdentifier(name) + strconv.Itoa(n)
+		}
+		names[goName] = true
+		fields = append(fields, goField{name: goName, goType: goType, column: name})
+	}
+	return fields
+}
+
+// goIdentifier turns a column name into an exported Go name: user_id is
+// UserID
+func goIdentifier(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, part := range parts {
+		if upper := strings.ToUpper(part); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + strings.ToLower(part[1:]))
+	}
+	identifier := b.String()
+	if identifier == "" || unicode.IsDigit(rune(identifier[0])) {
+		identifier = "Column" + identifier
+	}
+	return identifier
+}
+
+// goValue renders a parameter value as a Go literal
+func goValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// goString renders SQL as a Go string literal: a raw string unless the SQL
+// quotes identifiers with backticks
+func goString(sql string) string {
+	if strings.Contains(sql, "`") {
+		return strconv.Quote(sql)
+	}
+	return "`" + sql + "`"
+}
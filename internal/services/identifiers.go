@@ -0,0 +1,157 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// plainIdentifier matches identifiers every dialect reads as written:
+// lowercase, since Postgres folds unquoted names to lowercase
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// reservedWords are keywords reserved in at least one supported dialect,
+// which must be quoted to be used as names
+var reservedWords = map[string]bool{
+	"all": true, "alter": true, "analyze": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "between": true, "both": true, "by": true, "case": true, "cast": true, "check": true,
+	"collate": true, "column": true, "constraint": true, "create": true, "cross": true, "current_date": true,
+	"current_time": true, "current_timestamp": true, "current_user": true, "database": true, "default": true,
+	"delete": true, "desc": true, "distinct": true, "drop": true, "else": true, "end": true, "except": true,
+	"exists": true, "false": true, "fetch": true, "for": true, "foreign": true, "from": true, "full": true,
+	"grant": true, "group": true, "groups": true, "having": true, "if": true, "in": true, "index": true,
+	"inner": true, "insert": true, "intersect": true, "interval": true, "into": true, "is": true, "join": true,
+	"key": true, "keys": true, "leading": true, "left": true, "like": true, "limit": true, "natural": true,
+	"not": true, "null": true, "offset": true, "on": true, "only": true, "or": true, "order": true,
+	"outer": true, "over": true, "partition": true, "percent": true, "primary": true, "range": true,
+	"rank": true, "read": true, "references": true, "right": true, "rows": true, "select": true,
+	"session_user": true, "set": true, "some": true, "table": true, "then": true, "to": true, "top": true,
+	"trailing": true, "true": true, "union": true, "unique": true, "update": true, "user": true,
+	"using": true, "values": true, "when": true, "where": true, "window": true, "with": true,
+	// Statements other than SELECT, so checkReadOnly can tell them apart
+	// from names
+	"attach": true, "call": true, "commit": true, "detach": true, "exec": true, "execute": true,
+	"lock": true, "merge": true, "pragma": true, "rename": true, "rollback": true, "truncate": true,
+	"vacuum": true,
+}
+
+// identifierQuotes are the opening and closing identifier quotes of the
+// dialects that don't use the standard double quote
+var identifierQuotes = map[string][2]string{
+	DialectMySQL:     {"`", "`"},
+	DialectBigQuery:  {"`", "`"},
+	DialectSQLServer: {"[", "]"},
+}
+
+// quoteIdent quotes a single identifier for a dialect when it is reserved
+// or not plain lowercase, escaping the closing quote inside it
+func quoteIdent(dialect, name string) string {
+	if plainIdentifier.MatchString(name) && !reservedWords[name] {
+		return name
+	}
+	quotes, exists := identifierQuotes[dialect]
+	if !exists {
+		quotes = [2]string{`"`, `"`}
+	}
+	escaped := quotes[1] + quotes[1]
+	if dialect == DialectBigQuery {
+		name = strings.ReplaceAll(name, `\`, `\\`)
+		escaped = `\` + quotes[1]
+	}
+	return quotes[0] + strings.ReplaceAll(name, quotes[1], escaped) + quotes[1]
+}
+
+// quoteName quotes each part of a possibly schema-qualified table name
+func quoteName(dialect, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdent(dialect, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// columnRef renders a column reference qualified by a table name or alias
+func columnRef(dialect, qualifier, column string) string {
+	return quoteName(dialect, qualifier) + "." + quoteIdent(dialect, column)
+}
+
+// joinCondition renders a join's condition over the tables' aliases with
+// quoted identifiers, keeping the column order of the planned condition
+func joinCondition(dialect string, join models.Join, aliases *aliasAllocator) string {
+	if len(join.Columns) == 0 {
+		return join.Condition
+	}
+	reversed := !strings.HasPrefix(join.Condition, join.From+"."+join.Columns[0].From+" = ")
+	parts := make([]string, len(join.Columns))
+	for i, column := range join.Columns {
+		from, to := aliases.column(dialect, join.From, column.From), aliases.column(dialect, join.To, column.To)
+		if reversed {
+			from, to = to, from
+		}
+		parts[i] = from + " = " + to
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// tableLocation is the database and schema a table lives in, from the
+// mappings' database_name and schema_name
+type tableLocation struct {
+	database string
+	schema   string
+}
+
+// qualifiedTable renders a table name qualified as the dialect addresses
+// tables. Postgres and SQLite can't reach other databases, so only the
+// schema is used; MySQL's databases are its schemas. SQL Server and
+// Snowflake fall back to the default schema with database..table
+func qualifiedTable(dialect, table string, location tableLocation) string {
+	var parts []string
+	switch dialect {
+	case DialectPostgres, DialectSQLite:
+		parts = []string{location.schema}
+	case DialectMySQL:
+		if location.database != "" {
+			parts = []string{location.database}
+		} else {
+			parts = []string{location.schema}
+		}
+	case DialectSQLServer, DialectSnowflake:
+		if location.database != "" {
+			schema := ""
+			if location.schema != "" {
+				schema = quoteName(dialect, location.schema)
+			}
+			return quoteName(dialect, location.database) + "." + schema + "." + quoteName(dialect, table)
+		}
+		parts = []string{location.schema}
+	default:
+		parts = []string{location.database, location.schema}
+	}
+
+	var qualified []string
+	for _, part := range append(parts, table) {
+		if part != "" {
+			qualified = append(qualified, quoteName(dialect, part))
+		}
+	}
+	return strings.Join(qualified, ".")
+}
+
+// buildTableLocations collects where each table lives; the first field of
+// a table naming a schema or database wins
+func (s *FieldService) buildTableLocations() {
+	s.tableLocations = make(map[string]tableLocation)
+	for _, field := range s.fields {
+		location := s.tableLocations[field.TableName]
+		if location.schema == "" {
+			location.schema = field.SchemaName
+		}
+		if location.database == "" {
+			location.database = field.DatabaseName
+		}
+		if location != (tableLocation{}) {
+			s.tableLocations[field.TableName] = location
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// ErrInvalidIntent is returned when a structured intent cannot be built
+var ErrInvalidIntent = errors.New("invalid query intent")
+
+// Aggregates allowed on intent fields
+var intentAggregates = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+}
+
+// Comparison operators allowed in intent filters
+var intentOperators = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+	"LIKE": true, "IN": true, "IS NULL": true, "IS NOT NULL": true,
+}
+
+// BuildQuery assembles SQL from a structured intent, running only join
+// planning and SQL assembly
+func (s *QueryService) BuildQuery(request models.BuildQueryRequest) (models.BuildQueryResponse, error) {
+	if request.TraceID == "" {
+		request.TraceID = NewTraceID()
+	}
+	log := s.log.WithField("trace_id", request.TraceID)
+	dialect, err := s.queryDialect(request.Dialect)
+	if err != nil {
+		return models.BuildQueryResponse{}, err
+	}
+	switch request.Output {
+	case "", FormatJSON, FormatGo, FormatSqlx:
+	default:
+		return models.BuildQueryResponse{}, fmt.Errorf("%w: unknown output %q: use json, go, or sqlx", ErrInvalidIntent, request.Output)
+	}
+	if IsGoFormat(request.Output) && request.ParameterStyle == "" {
+		request.ParameterStyle = ParameterStyleDialect
+	}
+	binder, err := newParameterBinder(request.ParameterStyle, dialect)
+	if err != nil {
+		return models.BuildQueryResponse{}, err
+	}
+	switch request.Format {
+	case "", FormatCompact, FormatPretty:
+	default:
+		return models.BuildQueryResponse{}, fmt.Errorf("%w: unknown format %q: use compact or pretty", ErrInvalidIntent, request.Format)
+	}
+
+	// Tables of the referenced columns that exist and are cleared are
+	// collected in first-seen order and joined first, so aliases follow the
+	// FROM order when the columns are rendered
+	clearance := s.clearance(request.Clearance)
+	var tableNames []string
+	seen := make(map[string]bool)
+	for _, ref := range intentReferences(request) {
+		field, exists := s.fieldService.LookupField(ref.Table, ref.Column)
+		if exists && cleared(clearance, classificationLevel(field.Classification)) && !seen[ref.Table] {
+			seen[ref.Table] = true
+			tableNames = append(tableNames, ref.Table)
+		}
+	}
+	aliasStyle := request.AliasStyle
+	if aliasStyle == "" {
+		aliasStyle = s.cfg.AliasStyle
+	}
+	aliases, err := newAliasAllocator(aliasStyle, s.fieldService.TableAliases(), s.fieldService.tableLocations)
+	if err != nil {
+		return models.BuildQueryResponse{}, fmt.Errorf("%w: %v", ErrInvalidIntent, err)
+	}
+	var joins []models.Join
+	if len(tableNames) > 0 {
+		tableNames, joins, err = s.planJoins(tableNames, request.Joins, nil)
+		if errors.Is(err, ErrInvalidJoinHint) || errors.Is(err, ErrJoinBoundary) {
+			return models.BuildQueryResponse{}, err
+		}
+		if err != nil {
+			return models.BuildQueryResponse{}, fmt.Errorf("%w: %v", ErrInvalidIntent, err)
+		}
+		aliases.assign(tableNames[0], joins)
+	}
+
+	// Every referenced column must exist
+	var sensitive []string
+	flagged := make(map[string]bool)
+	reference := func(table, column string) (string, error) {
+		field, exists := s.fieldService.LookupField(table, column)
+		if !exists {
+			return "", fmt.Errorf("%w: unknown field %s.%s", ErrInvalidIntent, table, column)
+		}
+		if level := classificationLevel(field.Classification); !cleared(clearance, level) {
+			return "", fmt.Errorf("%w: %s.%s is classified %s", ErrInsufficientClearance, table, column, level)
+		}
+		// Explicitly named sensitive fields are allowed but flagged
+		if key := fieldKey(table, column); field.Sensitive && !flagged[key] {
+			flagged[key] = true
+			sensitive = append(sensitive, key)
+		}
+		return aliases.column(dialect, table, column), nil
+	}
+
+	// Measures are already aggregates: they are selected under their name
+	// and can't be aggregated again, filtered, or grouped by
+	measure := func(table, column string) string {
+		field, _ := s.fieldService.LookupField(table, column)
+		return field.Measure
+	}
+	notMeasure := func(table, column, use string) error {
+		if measure(table, column) != "" {
+			return fmt.Errorf("%w: %s.%s is a measure and can't be %s", ErrInvalidIntent, table, column, use)
+		}
+		return nil
+	}
+
+	// SELECT list; columns above the caller's clearance are withheld with a
+	// warning, while filters, grouping, and ordering on them are refused
+	plan := models.QueryPlan{Distinct: request.Distinct, Limit: request.Limit, Offset: request.Offset}
+	var plainColumns []string
+	var withheld, warnings []string
+	for _, field := range request.Fields {
+		column, err := reference(field.Table, field.Column)
+		if errors.Is(err, ErrInsufficientClearance) {
+			stored, _ := s.fieldService.LookupField(field.Table, field.Column)
+			withheld = append(withheld, fieldKey(field.Table, field.Column))
+			warnings = append(warnings, withheldWarning(field.Table, field.Column, classificationLevel(stored.Classification), clearance))
+			continue
+		}
+		if err != nil {
+			return models.BuildQueryResponse{}, err
+		}
+		aggregate := strings.ToUpper(strings.TrimSpace(field.Aggregate))
+		selected := models.PlanColumn{Expression: column, Table: field.Table, Column: field.Column, Aggregate: aggregate}
+		switch expression := measure(field.Table, field.Column); {
+		case expression != "" && aggregate != "":
+			return models.BuildQueryResponse{}, notMeasure(field.Table, field.Column, "aggregated again")
+		case expression != "":
+			selected.Expression = aliases.qualifyPredicate(dialect, expression, tableNames)
+			selected.Aggregate = planMeasure
+			selected.Alias = field.Column
+		case aggregate == "":
+			plainColumns = append(plainColumns, column)
+		case intentAggregates[aggregate]:
+			selected.Expression = fmt.Sprintf("%s(%s)", aggregate, column)
+		default:
+			return models.BuildQueryResponse{}, fmt.Errorf("%w: unsupported aggregate %q", ErrInvalidIntent, field.Aggregate)
+		}
+		plan.Select = append(plan.Select, selected)
+	}
+
+	if len(plan.Select) == 0 {
+		return models.BuildQueryResponse{}, fmt.Errorf("%w: every selected field is classified above %s", ErrInsufficientClearance, clearance)
+	}
+
+	// WHERE conditions
+	for _, filter := range request.Filters {
+		column, err := reference(filter.Table, filter.Column)
+		if err != nil {
+			return models.BuildQueryResponse{}, err
+		}
+		if err := notMeasure(filter.Table, filter.Column, "filtered"); err != nil {
+			return models.BuildQueryResponse{}, err
+		}
+		field, _ := s.fieldService.LookupField(filter.Table, filter.Column)
+		condition, filterWarnings, err := renderFilter(column, field.FieldType, dialect, filter, binder)
+		if err != nil {
+			return models.BuildQueryResponse{}, err
+		}
+		warnings = append(warnings, filterWarnings...)
+		plan.Filters = append(plan.Filters, models.PlanFilter{
+			Expression: condition,
+			Table:      filter.Table,
+			Column:     filter.Column,
+			Operator:   strings.ToUpper(strings.Join(strings.Fields(filter.Operator), " ")),
+			Value:      filter.Value,
+		})
+	}
+
+	// GROUP BY must cover every column selected without an aggregate
+	grouped := make(map[string]bool)
+	for _, field := range request.GroupBy {
+		column, err := reference(field.Table, field.Column)
+		if err != nil {
+			return models.BuildQueryResponse{}, err
+		}
+		if err := notMeasure(field.Table, field.Column, "grouped by"); err != nil {
+			return models.BuildQueryResponse{}, err
+		}
+		plan.GroupBy = append(plan.GroupBy, column)
+		grouped[column] = true
+	}
+	if len(plan.GroupBy) > 0 || len(plainColumns) < len(plan.Select) {
+		for _, column := range plainColumns {
+			if !grouped[column] {
+				return models.BuildQueryResponse{}, fmt.Errorf("%w: %s must be aggregated or listed in group_by", ErrInvalidIntent, column)
+			}
+		}
+	}
+
+	// ORDER BY
+	for _, order := range request.OrderBy {
+		column, err := reference(order.Table, order.Column)
+		if err != nil {
+			return models.BuildQueryResponse{}, err
+		}
+		if measure(order.Table, order.Column) != "" {
+			column = quoteIdent(dialect, order.Column)
+		}
+		switch strings.ToUpper(order.Direction) {
+		case "", "ASC":
+			plan.OrderBy = append(plan.OrderBy, models.PlanOrder{Expression: column, Direction: "ASC"})
+		case "DESC":
+			plan.OrderBy = append(plan.OrderBy, models.PlanOrder{Expression: column, Direction: "DESC"})
+		default:
+			return models.BuildQueryResponse{}, fmt.Errorf("%w: unsupported order direction %q", ErrInvalidIntent, order.Direction)
+		}
+	}
+
+	if request.Limit < 0 {
+		return models.BuildQueryResponse{}, fmt.Errorf("%w: limit must not be negative", ErrInvalidIntent)
+	}
+
+	warnings = append(warnings, joinWarnings(joins)...)
+
+	// Aggregate-only tables need an aggregate or grouped intent, and groups
+	// below the minimum size are left out
+	if restricted := s.policies.aggregateOnly(joinedTables(tableNames, joins)); len(restricted) > 0 {
+		if len(plan.GroupBy) == 0 && len(plainColumns) == len(plan.Select) {
+			return models.BuildQueryResponse{}, fmt.Errorf("%w: %s may only be queried with aggregates or group_by",
+				ErrAggregateOnly, strings.Join(restricted, ", "))
+		}
+		plan.Having = fmt.Sprintf("COUNT(*) >= %d", s.policies.minGroupSize())
+		warnings = append(warnings, minGroupSizeWarning(restricted, s.policies.minGroupSize()))
+	}
+
+	// Assemble the complete query
+	plan.From = aliases.tablePlan(dialect, tableNames[0])
+	plan.Joins = joinPlans(dialect, tableNames[0], joins, aliases)
+	limits := s.complexityLimits(request.MaxJoinHops, request.MaxColumns, request.MaxTables)
+	if exceeded := limits.exceeded(plan, joins); exceeded != "" {
+		return models.BuildQueryResponse{}, fmt.Errorf("%w: %s", ErrQueryTooComplex, exceeded)
+	}
+	if note := s.enforceRowLimit(&plan); note != "" {
+		warnings = append(warnings, note)
+	}
+	warnings = append(warnings, s.lintQuery(plan, joins, nil)...)
+	assignPlanIDs(&plan)
+	rendered := renderPlan(dialect, plan)
+	query := formatSQL(rendered, dialect, request.Format, s.style)
+	if err := checkReadOnly(query, dialect); err != nil {
+		log.WithError(err).Error("Built SQL failed the read-only check")
+		return models.BuildQueryResponse{}, err
+	}
+
+	log.WithField("tables", tableNames).Info("Built query from intent")
+
+	return models.BuildQueryResponse{
+		TraceID:          request.TraceID,
+		Query:            query,
+		Fingerprint:      queryFingerprint(rendered, dialect),
+		Plan:             &plan,
+		JoinsUsed:        joins,
+		JoinCost:         joinCost(joins),
+		SensitiveColumns: sensitive,
+		WithheldColumns:  withheld,
+		ParameterStyle:   binder.styleName(),
+		Parameters:       binder.values(),
+		Warnings:         warnings,
+	}, nil
+}
+
+// intentReferences lists the columns an intent references, in the order
+// they appear in it
+func intentReferences(request models.BuildQueryRequest) []models.FieldRef {
+	var refs []models.FieldRef
+	for _, field := range request.Fields {
+		refs = append(refs, models.FieldRef{Table: field.Table, Column: field.Column})
+	}
+	for _, filter := range request.Filters {
+		refs = append(refs, models.FieldRef{Table: filter.Table, Column: filter.Column})
+	}
+	refs = append(refs, request.GroupBy...)
+	for _, order := range request.OrderBy {
+		refs = append(refs, models.FieldRef{Table: order.Table, Column: order.Column})
+	}
+	return refs
+}
+
+// renderFilter renders a single WHERE condition, formatting values for the
+// column's field type, or binding them as parameters when a binder is given.
+// Comparisons the column's type makes doubtful are rendered with a warning
+func renderFilter(column, fieldType, dialect string, filter models.IntentFilter, binder *parameterBinder) (string, []string, error) {
+	operator := strings.ToUpper(strings.Join(strings.Fields(filter.Operator), " "))
+	if !intentOperators[operator] {
+		return "", nil, fmt.Errorf("%w: unsupported operator %q", ErrInvalidIntent, filter.Operator)
+	}
+
+	var warnings []string
+	mismatch := func(value interface{}) {
+		if kind := literalMismatch(fieldType, value); kind != "" {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s is %s text but is compared with %s, %v; the value is compared as text",
+				column, literalType(fieldType), kind, value))
+		}
+	}
+
+	switch operator {
+	case "IS NULL", "IS NOT NULL":
+		return fmt.Sprintf("%s %s", column, operator), nil, nil
+	case "IN":
+		values, ok := filter.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return "", nil, fmt.Errorf("%w: IN on %s needs a non-empty array", ErrInvalidIntent, column)
+		}
+		literals := make([]string, len(values))
+		for i, value := range values {
+			literal, err := binder.bind(fieldType, dialect, value)
+			if err != nil {
+				return "", nil, err
+			}
+			literals[i] = literal
+			mismatch(value)
+		}
+		return fmt.Sprintf("%s IN (%s)", column, strings.Join(literals, ", ")), warnings, nil
+	case "LIKE":
+		// Patterns are text whatever the column's type
+		pattern, ok := filter.Value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: LIKE on %s needs a string pattern", ErrInvalidIntent, column)
+		}
+		literal, err := binder.bind("TEXT", dialect, pattern)
+		if err != nil {
+			return "", nil, err
+		}
+		if kind := literalType(fieldType); kind != "" && !textTypes[kind] {
+			warnings = append(warnings, fmt.Sprintf(
+				"LIKE matches the %s column %s as text: the result depends on how the database formats it", kind, column))
+		}
+		return fmt.Sprintf("%s LIKE %s", column, literal), warnings, nil
+	default:
+		literal, err := binder.bind(fieldType, dialect, filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		mismatch(filter.Value)
+		if kind := literalType(fieldType); (kind == "BOOLEAN" || kind == "BOOL") && strings.ContainsAny(operator, "<>") && operator != "<>" {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s is a boolean: %s orders it with false before true, which rarely reads as intended", column, operator))
+		}
+		return fmt.Sprintf("%s %s %s", column, operator, literal), warnings, nil
+	}
+}
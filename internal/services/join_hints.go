@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Join types a join hint may ask for; inner is the default
+const (
+	JoinTypeInner = "inner"
+	JoinTypeLeft  = "left"
+)
+
+// ErrInvalidJoinHint is returned for join hints the relationship graph can't
+// honor
+var ErrInvalidJoinHint = errors.New("invalid join hint")
+
+// hintedJoins resolves join hints into relationship-graph joins, ordered so
+// each join's source table is already part of the query. The first hint's
+// source table is the root. Inner joins may be hinted in either direction;
+// a left join must start from a table already joined
+func (s *FieldService) hintedJoins(hints []models.JoinHint) ([]models.Join, error) {
+	types := make([]string, len(hints))
+	for i, hint := range hints {
+		joinType := strings.ToLower(strings.TrimSpace(hint.Type))
+		if joinType != "" && joinType != JoinTypeInner && joinType != JoinTypeLeft {
+			return nil, fmt.Errorf("%w: unsupported join type %q: use inner or left", ErrInvalidJoinHint, hint.Type)
+		}
+		if _, exists := s.relationshipGraph[hint.From][hint.To]; !exists {
+			return nil, fmt.Errorf("%w: %s and %s are not directly related", ErrInvalidJoinHint, hint.From, hint.To)
+		}
+		types[i] = joinType
+	}
+
+	joined := map[string]bool{hints[0].From: true}
+	placed := make([]bool, len(hints))
+	var joins []models.Join
+	for len(joins) < len(hints) {
+		progress := false
+		for i, hint := range hints {
+			if placed[i] {
+				continue
+			}
+			from, to := hint.From, hint.To
+			switch {
+			case joined[from] && joined[to]:
+				return nil, fmt.Errorf("%w: %s is joined more than once", ErrInvalidJoinHint, to)
+			case !joined[from] && joined[to] && types[i] != JoinTypeLeft:
+				from, to = to, from
+			case !joined[from]:
+				continue
+			}
+			join := s.relationshipGraph[from][to]
+			// A hinted type overrides the relationship's configured one
+			if types[i] != "" {
+				join.Type = joinTypeKeyword(types[i])
+			}
+			joins = append(joins, join)
+			joined[to], placed[i], progress = true, true, true
+		}
+		if !progress {
+			for i, hint := range hints {
+				if !placed[i] {
+					return nil, fmt.Errorf("%w: %s to %s isn't connected to %s", ErrInvalidJoinHint, hint.From, hint.To, hints[0].From)
+				}
+			}
+		}
+	}
+	return joins, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// optionalJoinPhrase finds descriptions asking to keep rows that have no
+// related rows: "including users without orders", "even customers with no
+// payments", "products with or without reviews". The first table is kept
+// whole and the second is joined to it with a LEFT JOIN
+var optionalJoinPhrase = regexp.MustCompile(`\b(?:(?:including|include|includes|even|also)\s+(?:all\s+|the\s+)?(\w+)\s+(?:without|with\s+no|that\s+have\s+no|who\s+have\s+no|lacking)|(\w+)\s+with\s+or\s+without)\s+(?:any\s+)?(\w+)`)
+
+// parseJoinType normalizes a mapping join type, "left" for optional
+// relationships; inner, blank, and unknown values are blank
+func parseJoinType(value string) string {
+	switch strings.Join(strings.Fields(strings.ToLower(value)), " ") {
+	case "left", "left outer", "left join", "left outer join", "optional":
+		return JoinTypeLeft
+	}
+	return ""
+}
+
+// joinTypeKeyword is the Join.Type for a join hint's type; inner joins
+// leave it empty
+func joinTypeKeyword(joinType string) string {
+	if joinType == JoinTypeLeft {
+		return JoinTypeLeft
+	}
+	return ""
+}
+
+// optionalJoins turns phrases like "including users without orders" into
+// left join hints, from the table whose rows are kept to the table they may
+// lack. It returns the description with each such phrase reduced to its two
+// table words, since the connecting words would only dilute field matching.
+// Phrases naming tables that aren't directly related are left alone
+func (s *FieldService) optionalJoins(description string) (string, []models.JoinHint) {
+	var hints []models.JoinHint
+	lowered := strings.ToLower(description)
+	rewritten := optionalJoinPhrase.ReplaceAllStringFunc(lowered, func(phrase string) string {
+		match := optionalJoinPhrase.FindStringSubmatch(phrase)
+		kept := match[1]
+		if kept == "" {
+			kept = match[2]
+		}
+		from, fromFound := s.relatedTableNamed(kept)
+		to, toFound := s.relatedTableNamed(match[3])
+		if !fromFound || !toFound || from == to {
+			return phrase
+		}
+		if _, related := s.relationshipGraph[from][to]; !related {
+			return phrase
+		}
+		hints = append(hints, models.JoinHint{From: from, To: to, Type: JoinTypeLeft})
+		return kept + " " + match[3]
+	})
+	if len(hints) == 0 {
+		return description, nil
+	}
+	return rewritten, hints
+}
+
+// relatedTableNamed finds the table in the relationship graph a word names,
+// allowing for singular and plural forms
+func (s *FieldService) relatedTableNamed(word string) (string, bool) {
+	for _, candidate := range []string{word, word + "s", word + "es", strings.TrimSuffix(word, "s")} {
+		for table := range s.relationshipGraph {
+			if strings.EqualFold(table, candidate) {
+				return table, true
+			}
+		}
+	}
+	return "", false
+}
+
+// propagateLeftJoins makes every join reached through a left join a left
+// join too; an inner join off a left-joined table would drop the very rows
+// the left join kept
+func propagateLeftJoins(joins []models.Join) []models.Join {
+	optional := make(map[string]bool)
+	for i, join := range joins {
+		if optional[join.From] {
+			joins[i].Type = JoinTypeLeft
+		}
+		if joins[i].Type == JoinTypeLeft {
+			optional[join.To] = true
+		}
+	}
+	return joins
+}
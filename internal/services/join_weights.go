@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// defaultJoinWeight is the cost of a relationship with no configured weight
+const defaultJoinWeight = 1.0
+
+// JoinWeights overrides relationship weights, keyed by the pair of tables
+// in either order. Join planning takes the cheapest path, so lower weights
+// mark preferred relationships
+type JoinWeights map[string]float64
+
+// ParseJoinWeights parses "table:table=weight,table:table=weight". An
+// empty spec overrides nothing
+func ParseJoinWeights(spec string) (JoinWeights, error) {
+	weights := make(JoinWeights)
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		pair, value, found := strings.Cut(entry, "=")
+		from, to, paired := strings.Cut(pair, ":")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !found || !paired || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid join weight %q: use table:table=weight", entry)
+		}
+		weight := parseJoinWeight(value)
+		if weight == 0 {
+			return nil, fmt.Errorf("invalid join weight %q: the weight must be a positive number", entry)
+		}
+		weights[tablePair(from, to)] = weight
+	}
+	return weights, nil
+}
+
+// parseJoinWeight reads a mapping join weight; blank and invalid values
+// are 0, meaning the default
+func parseJoinWeight(value string) float64 {
+	weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || weight <= 0 {
+		return 0
+	}
+	return weight
+}
+
+// formatJoinWeight writes a join weight CSV cell, leaving the default blank
+func formatJoinWeight(weight float64) string {
+	if weight == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(weight, 'f', -1, 64)
+}
+
+// tablePair keys a relationship by its tables regardless of direction
+func tablePair(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + ":" + b
+}
+
+// weight resolves a foreign key's weight: a configured override, else the
+// mapping's join_weight, else the default
+func (w JoinWeights) weight(key *foreignKey) float64 {
+	if weight, exists := w[tablePair(key.table, key.foreignTable)]; exists {
+		return weight
+	}
+	if key.weight > 0 {
+		return key.weight
+	}
+	return defaultJoinWeight
+}
+
+// joinCost sums the weights of a query's joins
+func joinCost(joins []models.Join) float64 {
+	cost := 0.0
+	for _, join := range joins {
+		cost += join.Weight
+	}
+	return cost
+}
+
+// cheapestPath finds the lowest-weight path between two tables with
+// Dijkstra's algorithm. Equal costs go to the path with fewer joins, then
+// to tables earlier in name order, so the choice is stable
+func (s *FieldService) cheapestPath(start, end string) ([]string, error) {
+	cost := map[string]float64{start: 0}
+	hops := map[string]int{start: 0}
+	parents := make(map[string]string)
+	done := make(map[string]bool)
+
+	for {
+		// Settle the cheapest table not yet settled
+		current, found := "", false
+		for table := range cost {
+			if done[table] {
+				continue
+			}
+			if !found || cheaper(cost[table], hops[table], table, cost[current], hops[current], current) {
+				current, found = table, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no join path found between %s and %s", start, end)
+		}
+		if current == end {
+			break
+		}
+		done[current] = true
+
+		neighbors := make([]string, 0, len(s.relationshipGraph[current]))
+		for neighbor := range s.relationshipGraph[current] {
+			neighbors = append(neighbors, neighbor)
+		}
+		sort.Strings(neighbors)
+		for _, neighbor := range neighbors {
+			if done[neighbor] {
+				continue
+			}
+			candidate := cost[current] + s.relationshipGraph[current][neighbor].Weight
+			known, seen := cost[neighbor]
+			if !seen || candidate < known || (candidate == known && hops[current]+1 < hops[neighbor]) {
+				cost[neighbor], hops[neighbor], parents[neighbor] = candidate, hops[current]+1, current
+			}
+		}
+	}
+
+	path := []string{end}
+	for node := end; node != start; node = parents[node] {
+		path = append([]string{parents[node]}, path...)
+	}
+	return path, nil
+}
+
+// cheaper orders tables for settling: by cost, then hops, then name
+func cheaper(costA float64, hopsA int, nameA string, costB float64, hopsB int, nameB string) bool {
+	if costA != costB {
+		return costA < costB
+	}
+	if hopsA != hopsB {
+		return hopsA < hopsB
+	}
+	return nameA < nameB
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// lintQuery flags risky constructs of a planned query: joins that can pair
+// every row with every row, aggregates over joins that repeat rows, wide
+// selects without a LIMIT, and fields matched below the low-confidence
+// score. Structured intents pass no matches, having no scores
+func (s *QueryService) lintQuery(plan models.QueryPlan, joins []models.Join, matches []models.FieldMatch) []string {
+	var warnings []string
+	for _, join := range joins {
+		if len(join.Columns) == 0 && !strings.Contains(join.Condition, "=") {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s and %s are joined without shared keys: every row may pair with every row (a cartesian product)",
+				join.From, join.To))
+		} else if join.Cardinality == CardinalityManyToMany {
+			warnings = append(warnings, fmt.Sprintf(
+				"the key joining %s and %s is unique on neither side: rows sharing a key pair with each other (a partial cartesian product)",
+				join.From, join.To))
+		}
+	}
+
+	if aggregate := planAggregate(plan); aggregate != "" {
+		for _, join := range joins {
+			if fansOut(join) {
+				warnings = append(warnings, fmt.Sprintf(
+					"%s is computed over the %s join of %s to %s, which repeats %s rows: it may count or sum them more than once",
+					aggregate, strings.ReplaceAll(join.Cardinality, "_", "-"), join.From, join.To, join.From))
+			}
+		}
+	} else if wide := s.cfg.WideSelectColumns; wide > 0 && plan.Limit == 0 && len(plan.Select) >= wide {
+		warnings = append(warnings, fmt.Sprintf(
+			"%d columns are selected without a LIMIT: add one to keep the result manageable", len(plan.Select)))
+	}
+
+	if low := s.cfg.LowConfidenceScore; low > 0 {
+		for _, match := range matches {
+			if match.MatchScore < low {
+				warnings = append(warnings, fmt.Sprintf(
+					"%s.%s matched with a low score of %.1f, under %.1f: check it is the field you meant",
+					match.TableName, match.ColumnName, match.MatchScore, low))
+			}
+		}
+	}
+	return warnings
+}
+
+// planAggregate returns the first aggregate a plan selects, or empty when
+// it selects plain rows
+func planAggregate(plan models.QueryPlan) string {
+	for _, column := range plan.Select {
+		if column.Aggregate != "" {
+			return column.Expression
+		}
+	}
+	return ""
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LiteralFormatter renders a JSON filter value as a SQL literal of one column
+// type in one dialect
+type LiteralFormatter func(dialect string, value interface{}) (string, error)
+
+// anyDialect registers a formatter for every dialect without its own
+const anyDialect = ""
+
+// literalFormatters renders literals by normalized field type, then dialect.
+// Supporting a new column type is a new entry here; types without one are
+// rendered by formatLiteral from the value alone
+var literalFormatters = map[string]map[string]LiteralFormatter{
+	"DATE":        {anyDialect: formatDate, DialectSQLite: formatSQLiteDate, DialectSQLServer: formatSQLServerDate},
+	"TIMESTAMP":   {anyDialect: formatTimestamp, DialectSQLite: formatSQLiteDate, DialectSQLServer: formatSQLServerTimestamp},
+	"DATETIME":    {anyDialect: formatTimestamp, DialectSQLite: formatSQLiteDate, DialectSQLServer: formatSQLServerTimestamp, DialectBigQuery: formatBigQueryDatetime},
+	"TIMESTAMPTZ": {anyDialect: formatTimestamp, DialectSQLite: formatSQLiteDate, DialectSQLServer: formatSQLServerTimestamp},
+	"BOOLEAN":     {anyDialect: formatBoolean, DialectSQLite: formatBitBoolean, DialectSQLServer: formatBitBoolean},
+	"BOOL":        {anyDialect: formatBoolean, DialectSQLite: formatBitBoolean, DialectSQLServer: formatBitBoolean},
+	"INTEGER":     {anyDialect: formatInteger},
+	"INT":         {anyDialect: formatInteger},
+	"SMALLINT":    {anyDialect: formatInteger},
+	"BIGINT":      {anyDialect: formatInteger},
+	"DECIMAL":     {anyDialect: formatNumber},
+	"NUMERIC":     {anyDialect: formatNumber},
+	"REAL":        {anyDialect: formatNumber},
+	"FLOAT":       {anyDialect: formatNumber},
+	"DOUBLE":      {anyDialect: formatNumber},
+	"UUID":        {anyDialect: formatUUID, DialectPostgres: formatPostgresUUID},
+	"INET":        {anyDialect: formatInet, DialectPostgres: formatPostgresInet},
+	"VARCHAR":     {anyDialect: formatText},
+	"NVARCHAR":    {anyDialect: formatText},
+	"CHAR":        {anyDialect: formatText},
+	"NCHAR":       {anyDialect: formatText},
+	"TEXT":        {anyDialect: formatText},
+	"STRING":      {anyDialect: formatText},
+}
+
+// textTypes are the field types holding text, which every value is compared
+// to as a string
+var textTypes = map[string]bool{"VARCHAR": true, "NVARCHAR": true, "CHAR": true, "NCHAR": true, "TEXT": true, "STRING": true}
+
+// dateLayouts are the date spellings accepted besides ISO, rewritten to
+// YYYY-MM-DD. Day-first and month-first numeric dates are ambiguous, so
+// only spelled-out months and year-first dates are read
+var dateLayouts = []string{"2006-01-02", "2006/01/02", "January 2, 2006", "Jan 2, 2006", "2 January 2006", "2 Jan 2006"}
+
+// timestampOffset matches the UTC offset normalizeTimestamp keeps
+var timestampOffset = regexp.MustCompile(`[+-]\d{2}:\d{2}$`)
+
+// uuidPattern matches a canonical UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// formatTypedLiteral renders a value for a column of fieldType, using the
+// registered formatter for the type and dialect, or the type's default
+func formatTypedLiteral(fieldType, dialect string, value interface{}) (string, error) {
+	if value == nil {
+		return "", fmt.Errorf("%w: filter value is required", ErrInvalidIntent)
+	}
+	if formatters, exists := literalFormatters[literalType(fieldType)]; exists {
+		if format, exists := formatters[dialect]; exists {
+			return format(dialect, value)
+		}
+		if format, exists := formatters[anyDialect]; exists {
+			return format(dialect, value)
+		}
+	}
+	return formatLiteral(dialect, value)
+}
+
+// literalType normalizes a field type for lookup: VARCHAR(64) is VARCHAR
+func literalType(fieldType string) string {
+	if i := strings.Index(fieldType, "("); i >= 0 {
+		fieldType = fieldType[:i]
+	}
+	return strings.ToUpper(strings.TrimSpace(fieldType))
+}
+
+// formatLiteral renders a JSON value as a SQL literal from its JSON type,
+// escaping strings
+func formatLiteral(dialect string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("%w: filter value is required", ErrInvalidIntent)
+	case string:
+		return quoteString(dialect, v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	default:
+		return "", fmt.Errorf("%w: unsupported filter value %v", ErrInvalidIntent, value)
+	}
+}
+
+// literalString reads a value that must be given as a string
+func literalString(value interface{}, kind string) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %v is not a %s", ErrInvalidIntent, value, kind)
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// normalizeDate rewrites a date in one of the dateLayouts as YYYY-MM-DD
+func normalizeDate(s string) (string, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q is not a YYYY-MM-DD date", ErrInvalidIntent, s)
+}
+
+// formatDate renders a date as a DATE literal
+func formatDate(dialect string, value interface{}) (string, error) {
+	s, err := literalString(value, "date")
+	if err != nil {
+		return "", err
+	}
+	date, err := normalizeDate(s)
+	if err != nil {
+		return "", err
+	}
+	return "DATE " + quoteString(dialect, date), nil
+}
+
+// formatTimestamp renders a date, "YYYY-MM-DD HH:MM:SS", or RFC 3339 time as
+// a TIMESTAMP literal. Offsets are kept only when one was given
+func formatTimestamp(dialect string, value interface{}) (string, error) {
+	s, err := literalString(value, "timestamp")
+	if err != nil {
+		return "", err
+	}
+	normalized, err := normalizeTimestamp(s)
+	if err != nil {
+		return "", err
+	}
+	return "TIMESTAMP " + quoteString(dialect, normalized), nil
+}
+
+// formatSQLiteDate renders dates and timestamps as the ISO text SQLite
+// stores them as
+func formatSQLiteDate(dialect string, value interface{}) (string, error) {
+	s, err := literalString(value, "date or timestamp")
+	if err != nil {
+		return "", err
+	}
+	if date, err := normalizeDate(s); err == nil {
+		return quoteString(dialect, date), nil
+	}
+	normalized, err := normalizeTimestamp(s)
+	if err != nil {
+		return "", err
+	}
+	return quoteString(dialect, normalized), nil
+}
+
+// formatSQLServerDate casts a date to DATE, as SQL Server has no DATE
+// literal
+func formatSQLServerDate(dialect string, value interface{}) (string, error) {
+	s, err := literalString(value, "date")
+	if err != nil {
+		return "", err
+	}
+	date, err := normalizeDate(s)
+	if err != nil {
+		return "", err
+	}
+	return "CAST(" + quoteString(dialect, date) + " AS DATE)", nil
+}
+
+// formatSQLServerTimestamp casts a timestamp to DATETIME2, or to
+// DATETIMEOFFSET when it carries an offset
+func formatSQLServerTimestamp(dialect string, value interface{}) (string, error) {
+	s, err := literalString(value, "timestamp")
+	if err != nil {
+		return "", err
+	}
+	normalized, err := normalizeTimestamp(s)
+	if err != nil {
+		return "", err
+	}
+	target := "DATETIME2"
+	if timestampOffset.MatchString(normalized) {
+		target = "DATETIMEOFFSET"
+	}
+	return "CAST(" + quoteString(dialect, normalized) + " AS " + target + ")", nil
+}
+
+// formatBigQueryDatetime renders a DATETIME literal, BigQuery's type for
+// civil times; a time with an offset is a TIMESTAMP instead
+func formatBigQueryDatetime(dialect string, value interface{}) (string, error) {
+	literal, err := formatTimestamp(dialect, value)
+	if err != nil || timestampOffset.MatchString(strings.TrimSuffix(literal, "'")) {
+		return literal, err
+	}
+	return "DATETIME " + strings.TrimPrefix(literal, "TIMESTAMP "), nil
+}
+
+// normalizeTimestamp rewrites a supported time layout as "YYYY-MM-DD HH:MM:SS"
+func normalizeTimestamp(s string) (string, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.Format("2006-01-02 15:04:05.999999999-07:00"), nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02 15:04:05.999999999"), nil
+		}
+	}
+	if date, err := normalizeDate(s); err == nil {
+		return date + " 00:00:00", nil
+	}
+	return "", fmt.Errorf("%w: %q is not a timestamp", ErrInvalidIntent, s)
+}
+
+// parseBoolean accepts JSON booleans and the usual spellings of them
+func parseBoolean(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "t", "yes", "y", "1":
+			return true, nil
+		case "false", "f", "no", "n", "0":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%w: %v is not a boolean", ErrInvalidIntent, value)
+}
+
+// formatBoolean renders TRUE or FALSE
+func formatBoolean(dialect string, value interface{}) (string, error) {
+	b, err := parseBoolean(value)
+	if err != nil {
+		return "", err
+	}
+	if b {
+		return "TRUE", nil
+	}
+	return "FALSE", nil
+}
+
+// formatBitBoolean renders 1 or 0, as SQLite and SQL Server's BIT store
+// booleans
+func formatBitBoolean(dialect string, value interface{}) (string, error) {
+	b, err := parseBoolean(value)
+	if err != nil {
+		return "", err
+	}
+	if b {
+		return "1", nil
+	}
+	return "0", nil
+}
+
+// formatNumber renders a number, or a numeric string, bare
+func formatNumber(dialect string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if _, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXpP_") {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %v is not a number", ErrInvalidIntent, value)
+}
+
+// formatInteger renders a whole number bare
+func formatInteger(dialect string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return strconv.FormatInt(int64(v), 10), nil
+		}
+	case int:
+		return strconv.Itoa(v), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %v is not an integer", ErrInvalidIntent, value)
+}
+
+// formatUUID renders a validated UUID as a string
+func formatUUID(dialect string, value interface{}) (string, error) {
+	s, err := literalString(value, "UUID")
+	if err != nil {
+		return "", err
+	}
+	if !uuidPattern.MatchString(s) {
+		return "", fmt.Errorf("%w: %q is not a UUID", ErrInvalidIntent, s)
+	}
+	return quoteString(dialect, strings.ToLower(s)), nil
+}
+
+// formatPostgresUUID casts a validated UUID to the uuid type
+func formatPostgresUUID(dialect string, value interface{}) (string, error) {
+	literal, err := formatUUID(dialect, value)
+	if err != nil {
+		return "", err
+	}
+	return literal + "::uuid", nil
+}
+
+// formatInet renders a validated IP address or CIDR block as a string
+func formatInet(dialect string, value interface{}) (string, error) {
+	s, err := literalString(value, "network address")
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(s) == nil {
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return "", fmt.Errorf("%w: %q is not an IP address or CIDR block", ErrInvalidIntent, s)
+		}
+	}
+	return quoteString(dialect, s), nil
+}
+
+// formatPostgresInet casts a validated address to the inet type
+func formatPostgresInet(dialect string, value interface{}) (string, error) {
+	literal, err := formatInet(dialect, value)
+	if err != nil {
+		return "", err
+	}
+	return literal + "::inet", nil
+}
+
+// formatText renders a value as a string literal; numbers and booleans are
+// compared as their text
+func formatText(dialect string, value interface{}) (string, error) {
+	s, err := textValue(value)
+	if err != nil {
+		return "", err
+	}
+	return quoteString(dialect, s), nil
+}
+
+// textValue spells a JSON value as text
+func textValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("%w: unsupported filter value %v", ErrInvalidIntent, value)
+}
+
+// literalMismatch describes a value of the wrong kind for a column that
+// formatTypedLiteral renders anyway, or is empty when the kinds agree.
+// Numbers and booleans given as text are what extraction produces, so only
+// text columns compared with numbers or booleans are flagged
+func literalMismatch(fieldType string, value interface{}) string {
+	if !textTypes[literalType(fieldType)] {
+		return ""
+	}
+	switch value.(type) {
+	case float64, int:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return ""
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// LiveSchema holds the field and query services serving requests, so the
+// mappings can be reloaded without restarting. Handlers fetch the services
+// per request; a request in flight keeps the schema it started with
+type LiveSchema struct {
+	mu       sync.RWMutex
+	fields   *FieldService
+	queries  *QueryService
+	loadedAt time.Time
+
+	// reloadMu serializes reloads without blocking readers while loading
+	reloadMu  sync.Mutex
+	cfg       *config.Config
+	versions  *SchemaVersions
+	templates *TemplateStore
+	policies  *PolicySet
+	drift     *DriftMonitor
+	events    *EventStream
+}
+
+// NewLiveSchema serves the given field service, wiring every query service
+// it creates to the schema history and saved templates
+func NewLiveSchema(cfg *config.Config, fields *FieldService, versions *SchemaVersions, templates *TemplateStore) *LiveSchema {
+	l := &LiveSchema{cfg: cfg, versions: versions, templates: templates, loadedAt: time.Now()}
+	l.fields, l.queries = fields, l.newQueryService(fields)
+	return l
+}
+
+// UsePolicies enforces an access policy set on every query service, now and
+// after reloads
+func (l *LiveSchema) UsePolicies(policies *PolicySet) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policies = policies
+	l.queries.UsePolicies(policies)
+}
+
+// UseDriftMonitor tracks match quality of every query service, now and after
+// reloads, telling the monitor whenever a different version goes live
+func (l *LiveSchema) UseDriftMonitor(drift *DriftMonitor) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.drift = drift
+	l.queries.UseDriftMonitor(drift)
+}
+
+// UseEventStream publishes generation events from every query service, now
+// and after reloads
+func (l *LiveSchema) UseEventStream(events *EventStream) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = events
+	l.queries.UseEventStream(events)
+}
+
+// Fields returns the field service currently serving requests
+func (l *LiveSchema) Fields() *FieldService {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.fields
+}
+
+// Loaded returns the live schema version and when it went live. Reloads
+// that don't change the version keep the earlier time
+func (l *LiveSchema) Loaded() (string, time.Time) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.fields.Version(), l.loadedAt
+}
+
+// Queries returns the query service currently serving requests
+func (l *LiveSchema) Queries() *QueryService {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.queries
+}
+
+// Reload re-reads the configured mapping source. The previous schema keeps
+// serving until the new one has loaded, and stays live if loading fails
+func (l *LiveSchema) Reload() (models.ReloadSummary, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	start := time.Now()
+	next, err := NewFieldService(l.cfg)
+	if err != nil {
+		return models.ReloadSummary{}, err
+	}
+	return l.activate(next, start)
+}
+
+// Refresh reloads the schema when its source may have changed, reporting
+// whether it reloaded. Sources made up entirely of remote files are first
+// checked with conditional requests and left alone while every ETag matches
+func (l *LiveSchema) Refresh() (models.ReloadSummary, bool, error) {
+	if l.cfg.SchemaSource == "" || l.cfg.SchemaSource == SchemaSourceCSV {
+		paths, err := mappingPaths(l.cfg.CSVPath)
+		if err != nil {
+			return models.ReloadSummary{}, false, err
+		}
+		remote, changed := true, false
+		for _, path := range paths {
+			if !isRemotePath(path) {
+				remote = false
+				break
+			}
+			_, fetched, err := fetchRemote(l.cfg, path)
+			if err != nil {
+				return models.ReloadSummary{}, false, err
+			}
+			changed = changed || fetched
+		}
+		if remote && !changed {
+			return models.ReloadSummary{}, false, nil
+		}
+	}
+
+	summary, err := l.Reload()
+	if err != nil {
+		return models.ReloadSummary{}, false, err
+	}
+	return summary, true, nil
+}
+
+// Watch refreshes the schema every interval until stop is closed. Failed
+// refreshes are logged and the current schema keeps serving
+func (l *LiveSchema) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			summary, reloaded, err := l.Refresh()
+			log := l.Fields().log
+			switch {
+			case err != nil:
+				log.WithError(err).Warn("Schema refresh failed; keeping the current schema")
+			case reloaded && summary.Changed:
+				log.Infof("Refreshed schema from %s to %s", summary.PreviousVersion, summary.Version)
+			}
+		}
+	}
+}
+
+// Rollback makes a previously loaded schema version live again. The mapping
+// source itself is unchanged, so a later reload picks up whatever it holds
+func (l *LiveSchema) Rollback(version string) (models.ReloadSummary, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	if l.versions == nil {
+		return models.ReloadSummary{}, fmt.Errorf("%w: %s", ErrUnknownSchemaVersion, version)
+	}
+	start := time.Now()
+	previous, err := l.versions.Get(version)
+	if err != nil {
+		return models.ReloadSummary{}, err
+	}
+	return l.activate(previous, start)
+}
+
+// activate validates a freshly loaded field service and swaps it in.
+// Callers hold reloadMu
+func (l *LiveSchema) activate(next *FieldService, start time.Time) (models.ReloadSummary, error) {
+	// An empty catalog is almost always a truncated or misconfigured source
+	if len(next.fields) == 0 {
+		return models.ReloadSummary{}, fmt.Errorf("mapping source has no fields")
+	}
+
+	previous := l.Fields()
+	next.inheritFeedback(previous)
+	if l.versions != nil {
+		if err := l.versions.Record(next); err != nil {
+			return models.ReloadSummary{}, err
+		}
+	}
+
+	l.mu.Lock()
+	if next.Version() != previous.Version() {
+		l.loadedAt = time.Now()
+	}
+	l.fields, l.queries = next, l.newQueryService(next)
+	l.mu.Unlock()
+	l.drift.Switch(next.Version())
+
+	return models.ReloadSummary{
+		PreviousVersion: previous.Version(),
+		Version:         next.Version(),
+		Changed:         previous.Version() != next.Version(),
+		Fields:          len(next.fields),
+		SkippedRows:     next.skippedRows,
+		Tables:          len(next.relationshipGraph),
+		Relationships:   next.relationshipCount(),
+		DurationMs:      time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// newQueryService creates a query service over fields with the shared
+// history and templates
+func (l *LiveSchema) newQueryService(fields *FieldService) *QueryService {
+	queries := NewQueryService(fields)
+	if l.versions != nil {
+		queries.UseSchemaVersions(l.versions)
+	}
+	if l.templates != nil {
+		queries.UseTemplates(l.templates)
+	}
+	queries.UsePolicies(l.policies)
+	queries.UseDriftMonitor(l.drift)
+	queries.UseEventStream(l.events)
+	return queries
+}
+
+// ErrInvalidMapping is returned for an uploaded mapping file that fails to load
+var ErrInvalidMapping = errors.New("invalid mapping file")
+
+// ErrUploadUnsupported is returned when the mapping source can't be replaced
+// by an upload
+var ErrUploadUnsupported = errors.New("mapping uploads require a single CSV mapping file")
+
+// Validate checks a mapping CSV the way Upload would load it, in the
+// configured delimiter and encoding
+func (l *LiveSchema) Validate(data []byte) models.ValidationReport {
+	delimiter, encodingName := CSVFormat(l.cfg, strings.TrimSpace(l.cfg.CSVPath))
+	return ValidateMappings(data, delimiter, encodingName)
+}
+
+// Upload replaces the configured mapping CSV with an uploaded one and swaps
+// it in. The upload must load cleanly, with no skipped rows; otherwise the
+// file on disk and the live schema are left untouched
+func (l *LiveSchema) Upload(data []byte) (models.ReloadSummary, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	start := time.Now()
+	path := strings.TrimSpace(l.cfg.CSVPath)
+	if (l.cfg.SchemaSource != "" && l.cfg.SchemaSource != SchemaSourceCSV) || strings.Contains(path, ",") || isSchemaFile(path) || isRemotePath(path) {
+		return models.ReloadSummary{}, ErrUploadUnsupported
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return models.ReloadSummary{}, ErrUploadUnsupported
+	}
+	previous, err := os.ReadFile(path)
+	existed := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return models.ReloadSummary{}, fmt.Errorf("failed to read current mappings: %w", err)
+	}
+	// Put the previous file back, or remove the upload when there was none,
+	// so reloads and restarts keep serving what was live
+	restore := func() error {
+		if !existed {
+			return os.Remove(path)
+		}
+		return writeFileAtomic(path, previous)
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return models.ReloadSummary{}, err
+	}
+
+	next, err := NewFieldService(l.cfg)
+	if err == nil && next.skippedRows > 0 {
+		err = fmt.Errorf("%d rows have too few columns", next.skippedRows)
+	}
+	if err == nil && len(next.fields) == 0 {
+		err = fmt.Errorf("mapping file has no fields")
+	}
+	if err != nil {
+		if restoreErr := restore(); restoreErr != nil {
+			return models.ReloadSummary{}, fmt.Errorf("%w: %v (and restoring the previous file failed: %v)", ErrInvalidMapping, err, restoreErr)
+		}
+		return models.ReloadSummary{}, fmt.Errorf("%w: %v", ErrInvalidMapping, err)
+	}
+
+	summary, err := l.activate(next, start)
+	if err != nil {
+		if restoreErr := restore(); restoreErr != nil {
+			return models.ReloadSummary{}, fmt.Errorf("%w (and restoring the previous file failed: %v)", err, restoreErr)
+		}
+		return models.ReloadSummary{}, err
+	}
+	return summary, nil
+}
+
+// writeFileAtomic replaces a file via a temporary file in the same directory
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".mappings-*.csv")
+	if err != nil {
+		return fmt.Errorf("failed to write mappings: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write mappings: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write mappings: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write mappings: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,606 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxLookMLDepth bounds include nesting and ${} substitution, so cycles fail
+// instead of recursing forever
+const maxLookMLDepth = 10
+
+// lookmlNode is one parameter of a LookML file: a value, a list, or a named
+// block such as view: orders { ... }
+type lookmlNode struct {
+	Key      string
+	Name     string
+	Value    string
+	List     []string
+	Children []*lookmlNode
+}
+
+// child returns the first parameter named key, or nil
+func (n *lookmlNode) child(key string) *lookmlNode {
+	for _, child := range n.Children {
+		if child.Key == key {
+			return child
+		}
+	}
+	return nil
+}
+
+// value returns the value of the parameter named key, or ""
+func (n *lookmlNode) value(key string) string {
+	if child := n.child(key); child != nil {
+		return child.Value
+	}
+	return ""
+}
+
+// childList returns the list value of the parameter named key
+func (n *lookmlNode) childList(key string) []string {
+	if child := n.child(key); child != nil {
+		return child.List
+	}
+	return nil
+}
+
+// lookmlReference matches a ${field}, ${view.field}, or ${TABLE} substitution
+var lookmlReference = regexp.MustCompile(`\$\{\s*([A-Za-z0-9_.]+)\s*\}`)
+
+// lookmlColumn matches a dimension that reads one column: ${TABLE}.column
+var lookmlColumn = regexp.MustCompile("^\\$\\{TABLE\\}\\.[\"`]?([A-Za-z0-9_]+)[\"`]?$")
+
+// lookmlJoinOn matches an equality join: ${view.field} = ${view.field}
+var lookmlJoinOn = regexp.MustCompile(`^\$\{(\w+)\.(\w+)\}\s*=\s*\$\{(\w+)\.(\w+)\}$`)
+
+// lookmlMeasureFuncs are the measure types imported, by their SQL aggregate
+var lookmlMeasureFuncs = map[string]string{
+	"sum":     "SUM",
+	"average": "AVG",
+	"min":     "MIN",
+	"max":     "MAX",
+}
+
+// lookmlTypes maps dimension types to column types; others are VARCHAR
+var lookmlTypes = map[string]string{
+	"number":    "NUMERIC",
+	"yesno":     "BOOLEAN",
+	"date":      "DATE",
+	"date_time": "TIMESTAMP",
+	"time":      "TIMESTAMP",
+}
+
+// readLookML parses a LookML model or view file. A local file's include:
+// globs are followed, so pointing at a model file loads its views
+func readLookML(path string, data []byte) (SchemaDefinition, error) {
+	nodes, err := parseLookMLFile(path, data, 0)
+	if err != nil {
+		return SchemaDefinition{}, err
+	}
+	return lookmlSchema(nodes)
+}
+
+// parseLookMLFile parses a LookML file along with the files it includes
+func parseLookMLFile(path string, data []byte, depth int) ([]*lookmlNode, error) {
+	if depth > maxLookMLDepth {
+		return nil, fmt.Errorf("LookML includes nested deeper than %d files", maxLookMLDepth)
+	}
+	nodes, err := parseLookML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if isRemotePath(path) {
+		return nodes, nil
+	}
+
+	// Includes are relative to the project root, taken to be this file's
+	// directory
+	var included []*lookmlNode
+	for _, node := range nodes {
+		if node.Key != "include" {
+			continue
+		}
+		pattern := filepath.Join(filepath.Dir(path), strings.TrimPrefix(node.Value, "/"))
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LookML include %q: %w", node.Value, err)
+		}
+		for _, match := range matches {
+			if match == path {
+				continue
+			}
+			data, err := os.ReadFile(match)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read LookML include: %w", err)
+			}
+			children, err := parseLookMLFile(match, data, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			included = append(included, children...)
+		}
+	}
+	return append(nodes, included...), nil
+}
+
+// lookmlView is a parsed view and the fields it defines
+type lookmlView struct {
+	node     *lookmlNode
+	table    string
+	fields   map[string]*lookmlNode
+	columns  map[string]string
+	measures map[string]string
+}
+
+// lookmlSchema converts parsed LookML into a schema definition: views become
+// tables, dimensions that read a column become columns, and measures become
+// fields carrying their aggregate SQL. Equality joins in explores become
+// references
+func lookmlSchema(nodes []*lookmlNode) (SchemaDefinition, error) {
+	views := make(map[string]*lookmlView)
+	var order []string
+	for _, node := range nodes {
+		// Refinements (view: +orders) only adjust an existing view
+		if node.Key != "view" || node.Name == "" || strings.HasPrefix(node.Name, "+") || views[node.Name] != nil {
+			continue
+		}
+		views[node.Name] = newLookMLView(node)
+		order = append(order, node.Name)
+	}
+
+	for _, name := range order {
+		views[name].resolveFields(views)
+	}
+	references := lookmlJoins(nodes, views)
+
+	var definition SchemaDefinition
+	for _, name := range order {
+		view := views[name]
+		table := TableDefinition{Name: view.table, Tags: view.node.childList("tags")}
+		for _, field := range view.node.Children {
+			column, isColumn := view.columns[field.Name]
+			measure, isMeasure := view.measures[field.Name]
+			switch {
+			case isColumn && field.Key != "measure":
+				dataType := lookmlTypes[field.value("type")]
+				if field.Key == "dimension_group" {
+					dataType = "TIMESTAMP"
+				} else if dataType == "" {
+					dataType = "VARCHAR"
+				}
+				table.Columns = append(table.Columns, ColumnDefinition{
+					Name:        column,
+					Description: lookmlDescription(field),
+					Type:        dataType,
+					References:  references[fieldKey(view.table, column)],
+					Tags:        field.childList("tags"),
+				})
+			case isMeasure && field.Key == "measure":
+				dataType := "NUMERIC"
+				if strings.HasPrefix(field.value("type"), "count") {
+					dataType = "INTEGER"
+				}
+				table.Columns = append(table.Columns, ColumnDefinition{
+					Name:        field.Name,
+					Description: lookmlDescription(field),
+					Type:        dataType,
+					Tags:        field.childList("tags"),
+					Measure:     measure,
+				})
+			}
+		}
+		if len(table.Columns) > 0 {
+			definition.Tables = append(definition.Tables, table)
+		}
+	}
+	if len(definition.Tables) == 0 {
+		return SchemaDefinition{}, fmt.Errorf("LookML defines no views with dimensions or measures")
+	}
+	return definition, nil
+}
+
+// newLookMLView indexes a view's fields. The table is the last part of
+// sql_table_name, or the view name
+func newLookMLView(node *lookmlNode) *lookmlView {
+	view := &lookmlView{
+		node:     node,
+		table:    node.Name,
+		fields:   make(map[string]*lookmlNode),
+		columns:  make(map[string]string),
+		measures: make(map[string]string),
+	}
+	if name := strings.Trim(node.value("sql_table_name"), "`\" "); name != "" {
+		parts := strings.Split(name, ".")
+		view.table = strings.Trim(parts[len(parts)-1], "`\"")
+	}
+	for _, field := range node.Children {
+		switch field.Key {
+		case "dimension", "dimension_group", "measure":
+			if field.Name != "" {
+				view.fields[field.Name] = field
+			}
+		}
+	}
+	return view
+}
+
+// resolveFields works out the column each dimension reads and the SQL of
+// each measure. Derived dimensions, duration groups, and filtered or
+// unsupported measure types are left out rather than approximated
+func (v *lookmlView) resolveFields(views map[string]*lookmlView) {
+	for name, field := range v.fields {
+		switch field.Key {
+		case "dimension", "dimension_group":
+			if field.Key == "dimension_group" && field.value("type") == "duration" {
+				continue
+			}
+			if column, ok := v.columnOf(field); ok {
+				v.columns[name] = column
+			}
+		case "measure":
+			if measure, ok := v.measureSQL(field, views, 0); ok {
+				v.measures[name] = measure
+			}
+		}
+	}
+}
+
+// columnOf returns the column a dimension reads, if it reads exactly one
+func (v *lookmlView) columnOf(field *lookmlNode) (string, bool) {
+	sql := strings.TrimSpace(field.value("sql"))
+	if sql == "" {
+		return field.Name, true
+	}
+	if match := lookmlColumn.FindStringSubmatch(sql); match != nil {
+		return match[1], true
+	}
+	return "", false
+}
+
+// measureSQL renders a measure as an aggregate expression over its view's
+// table, inlining the fields it references
+func (v *lookmlView) measureSQL(field *lookmlNode, views map[string]*lookmlView, depth int) (string, bool) {
+	if field.child("filters") != nil {
+		return "", false
+	}
+	sql := strings.TrimSpace(field.value("sql"))
+	measureType := field.value("type")
+	if sql == "" && measureType != "count" {
+		return "", false
+	}
+	var expression string
+	if sql != "" {
+		var ok bool
+		if expression, ok = v.substitute(sql, views, depth); !ok {
+			return "", false
+		}
+	}
+
+	switch measureType {
+	case "count":
+		if expression == "" {
+			return "COUNT(*)", true
+		}
+		return "COUNT(" + expression + ")", true
+	case "count_distinct":
+		return "COUNT(DISTINCT " + expression + ")", true
+	case "number":
+		return expression, true
+	}
+	if function, ok := lookmlMeasureFuncs[measureType]; ok {
+		return function + "(" + expression + ")", true
+	}
+	return "", false
+}
+
+// substitute replaces ${TABLE}, ${field}, and ${view.field} references with
+// table.column SQL or, for measures, their aggregate expressions
+func (v *lookmlView) substitute(sql string, views map[string]*lookmlView, depth int) (string, bool) {
+	if depth > maxLookMLDepth {
+		return "", false
+	}
+	ok := true
+	rendered := lookmlReference.ReplaceAllStringFunc(sql, func(reference string) string {
+		name := lookmlReference.FindStringSubmatch(reference)[1]
+		if name == "TABLE" {
+			return v.table
+		}
+		view := v
+		if viewName, fieldName, qualified := strings.Cut(name, "."); qualified {
+			view, name = views[viewName], fieldName
+		}
+		if view == nil {
+			ok = false
+			return reference
+		}
+		field := view.field(name)
+		if field == nil {
+			ok = false
+			return reference
+		}
+		if field.Key == "measure" {
+			measure, resolved := view.measureSQL(field, views, depth+1)
+			ok = ok && resolved
+			return measure
+		}
+		if column, resolved := view.columnOf(field); resolved {
+			return view.table + "." + column
+		}
+		rendered, resolved := view.substitute(field.value("sql"), views, depth+1)
+		ok = ok && resolved
+		return rendered
+	})
+	return rendered, ok
+}
+
+// field looks a field up by name. Timeframes of a dimension group
+// (created_date) resolve to the group (created)
+func (v *lookmlView) field(name string) *lookmlNode {
+	if field, exists := v.fields[name]; exists {
+		return field
+	}
+	if i := strings.LastIndex(name, "_"); i > 0 {
+		if field, exists := v.fields[name[:i]]; exists && field.Key == "dimension_group" {
+			return field
+		}
+	}
+	return nil
+}
+
+// lookmlJoins turns equality joins in explores into table.column references,
+// keyed by the table.column holding the foreign key. In a one_to_many join
+// the joined view holds it; otherwise the view it joins to does
+func lookmlJoins(nodes []*lookmlNode, views map[string]*lookmlView) map[string]string {
+	references := make(map[string]string)
+	for _, explore := range nodes {
+		if explore.Key != "explore" {
+			continue
+		}
+		// Explores and joins may name a view under another name with from:
+		aliases := map[string]string{explore.Name: explore.Name}
+		if from := explore.value("from"); from != "" {
+			aliases[explore.Name] = from
+		} else if viewName := explore.value("view_name"); viewName != "" {
+			aliases[explore.Name] = viewName
+		}
+		for _, join := range explore.Children {
+			if join.Key != "join" {
+				continue
+			}
+			aliases[join.Name] = join.Name
+			if from := join.value("from"); from != "" {
+				aliases[join.Name] = from
+			}
+		}
+
+		column := func(alias, name string) (*lookmlView, string, bool) {
+			view := views[aliases[alias]]
+			if view == nil {
+				return nil, "", false
+			}
+			column, ok := view.columns[name]
+			return view, column, ok
+		}
+
+		for _, join := range explore.Children {
+			if join.Key != "join" {
+				continue
+			}
+			match := lookmlJoinOn.FindStringSubmatch(strings.TrimSpace(join.value("sql_on")))
+			if match == nil {
+				continue
+			}
+			left, right := [2]string{match[1], match[2]}, [2]string{match[3], match[4]}
+			if right[0] == join.Name {
+				left, right = right, left
+			}
+			if left[0] != join.Name {
+				continue
+			}
+			// left is now the joined view
+			child, parent := right, left
+			if join.value("relationship") == "one_to_many" {
+				child, parent = left, right
+			}
+			childView, childColumn, ok := column(child[0], child[1])
+			parentView, parentColumn, parentOK := column(parent[0], parent[1])
+			if !ok || !parentOK {
+				continue
+			}
+			references[fieldKey(childView.table, childColumn)] = parentView.table + "." + parentColumn
+		}
+	}
+	return references
+}
+
+// lookmlDescription is a field's description, falling back to its label and
+// then its name
+func lookmlDescription(field *lookmlNode) string {
+	if description := field.value("description"); description != "" {
+		return description
+	}
+	if label := field.value("label"); label != "" {
+		return label
+	}
+	return strings.ReplaceAll(field.Name, "_", " ")
+}
+
+// lookmlParser reads LookML's key: value syntax
+type lookmlParser struct {
+	src string
+	pos int
+}
+
+// parseLookML parses LookML source into its top-level parameters
+func parseLookML(src string) ([]*lookmlNode, error) {
+	parser := &lookmlParser{src: src}
+	return parser.parseBlock(false)
+}
+
+// parseBlock parses parameters until the end of input or, inside a block,
+// the closing brace
+func (p *lookmlParser) parseBlock(inBlock bool) ([]*lookmlNode, error) {
+	var nodes []*lookmlNode
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			if inBlock {
+				return nil, fmt.Errorf("unclosed block at end of file")
+			}
+			return nodes, nil
+		}
+		if p.src[p.pos] == '}' {
+			if !inBlock {
+				return nil, p.errorf("unexpected }")
+			}
+			p.pos++
+			return nodes, nil
+		}
+		node, err := p.parseParameter()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+// parseParameter parses one key: value, key: [list], or key: name { block }
+func (p *lookmlParser) parseParameter() (*lookmlNode, error) {
+	start := p.pos
+	for p.pos < len(p.src) && isLookMLWordByte(p.src[p.pos]) {
+		p.pos++
+	}
+	node := &lookmlNode{Key: p.src[start:p.pos]}
+	p.skipSpace()
+	if node.Key == "" || p.pos >= len(p.src) || p.src[p.pos] != ':' {
+		return nil, p.errorf("expected parameter name and colon")
+	}
+	p.pos++
+	p.skipSpace()
+
+	// SQL and HTML run to a ;; terminator
+	if node.Key == "sql" || node.Key == "html" || node.Key == "expression" ||
+		strings.HasPrefix(node.Key, "sql_") || strings.HasSuffix(node.Key, "_sql") {
+		end := strings.Index(p.src[p.pos:], ";;")
+		if end < 0 {
+			return nil, p.errorf("%s is missing its ;; terminator", node.Key)
+		}
+		node.Value = strings.TrimSpace(p.src[p.pos : p.pos+end])
+		p.pos += end + 2
+		return node, nil
+	}
+
+	switch {
+	case p.pos >= len(p.src):
+		return nil, p.errorf("%s has no value", node.Key)
+	case p.src[p.pos] == '"':
+		value, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		node.Value = value
+		return node, nil
+	case p.src[p.pos] == '[':
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		node.List = list
+		return node, nil
+	case p.src[p.pos] != '{':
+		start := p.pos
+		for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n{}", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		node.Value = p.src[start:p.pos]
+		p.skipSpace()
+	}
+
+	// A value followed by a brace names a block
+	if p.pos < len(p.src) && p.src[p.pos] == '{' {
+		p.pos++
+		children, err := p.parseBlock(true)
+		if err != nil {
+			return nil, err
+		}
+		node.Name, node.Value, node.Children = node.Value, "", children
+	}
+	return node, nil
+}
+
+// parseString parses a double-quoted string
+func (p *lookmlParser) parseString() (string, error) {
+	var value strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; c {
+		case '\\':
+			if p.pos+1 < len(p.src) {
+				p.pos++
+				value.WriteByte(p.src[p.pos])
+			}
+		case '"':
+			p.pos++
+			return value.String(), nil
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseList parses a bracketed, comma-separated list, unquoting its items
+func (p *lookmlParser) parseList() ([]string, error) {
+	var items []string
+	var item strings.Builder
+	quoted := false
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ',' || c == ']'):
+			if text := strings.TrimSpace(item.String()); text != "" {
+				items = append(items, text)
+			}
+			item.Reset()
+			if c == ']' {
+				p.pos++
+				return items, nil
+			}
+		default:
+			item.WriteByte(c)
+		}
+	}
+	return nil, p.errorf("unterminated list")
+}
+
+// skipSpace skips whitespace and # comments
+func (p *lookmlParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// errorf reports a parse error at the current line
+func (p *lookmlParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// isLookMLWordByte reports whether c may appear in a parameter name
+func isLookMLWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// mappingExtensions are the file types loaded from a mapping directory
+var mappingExtensions = map[string]bool{".csv": true, ".tsv": true, ".yaml": true, ".yml": true, ".json": true}
+
+// mappingPaths expands a mapping path spec: a comma-separated list of files,
+// remote URLs, and directories, each directory contributing its mapping files
+// in name order
+func mappingPaths(spec string) ([]string, error) {
+	paths := make([]string, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		info, err := os.Stat(entry)
+		if isRemotePath(entry) || err != nil || !info.IsDir() {
+			// Missing files are reported when they are read
+			paths = append(paths, entry)
+			continue
+		}
+		entries, err := os.ReadDir(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mapping directory: %w", err)
+		}
+		found := false
+		for _, file := range entries {
+			name := file.Name()
+			// Persisted embeddings share the directory but are not mappings
+			if file.IsDir() || !mappingExtensions[strings.ToLower(filepath.Ext(name))] || strings.HasSuffix(name, ".embeddings.json") {
+				continue
+			}
+			paths = append(paths, filepath.Join(entry, name))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("mapping directory %s has no mapping files", entry)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no mapping files configured")
+	}
+	return paths, nil
+}
+
+// loadMappings loads and merges every mapping file in the spec. A single
+// file keeps its raw contents as the schema source; merged files are
+// versioned by their combined mapping CSV
+func (s *FieldService) loadMappings(spec string) error {
+	paths, err := mappingPaths(spec)
+	if err != nil {
+		return err
+	}
+
+	merged := make([]models.Field, 0)
+	origins := make([]string, 0)
+	definedIn := make(map[string]int)
+	diagnostics := make(map[string]*models.MergeDiagnostic)
+	var order []string
+	var source []byte
+	for _, path := range paths {
+		fields, data, err := s.readMappingFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		source = data
+		s.mappingFiles = append(s.mappingFiles, models.MappingFile{Path: path, Fields: len(fields)})
+
+		for _, field := range fields {
+			key := fieldKey(field.TableName, field.ColumnName)
+			i, exists := definedIn[key]
+			if !exists {
+				definedIn[key] = len(merged)
+				merged = append(merged, field)
+				origins = append(origins, path)
+				continue
+			}
+
+			diagnostic, seen := diagnostics[key]
+			if !seen {
+				diagnostic = &models.MergeDiagnostic{
+					Table:  field.TableName,
+					Column: field.ColumnName,
+					Files:  []string{origins[i]},
+				}
+				diagnostics[key] = diagnostic
+				order = append(order, key)
+			}
+			diagnostic.Files = append(diagnostic.Files, path)
+			for _, attribute := range fieldDifferences(merged[i], field) {
+				if !containsString(diagnostic.Attributes, attribute) {
+					diagnostic.Attributes = append(diagnostic.Attributes, attribute)
+				}
+			}
+			diagnostic.Conflict = len(diagnostic.Attributes) > 0
+		}
+	}
+
+	s.fields = merged
+	if len(paths) == 1 {
+		s.setSource(source)
+	} else {
+		s.setSource(encodeFieldsCSV(merged))
+	}
+
+	s.mergeDiagnostics = make([]models.MergeDiagnostic, 0, len(order))
+	for _, key := range order {
+		diagnostic := diagnostics[key]
+		sort.Strings(diagnostic.Attributes)
+		s.mergeDiagnostics = append(s.mergeDiagnostics, *diagnostic)
+		if diagnostic.Conflict {
+			s.log.Warnf("Conflicting definitions of %s.%s in %s (%s); using the first",
+				diagnostic.Table, diagnostic.Column, strings.Join(diagnostic.Files, ", "), strings.Join(diagnostic.Attributes, ", "))
+		}
+	}
+
+	s.log.Infof("Loaded %d fields from %s", len(merged), strings.Join(paths, ", "))
+	return nil
+}
+
+// fieldDifferences lists the mapping attributes two definitions of the same
+// field disagree on
+func fieldDifferences(a, b models.Field) []string {
+	var differences []string
+	compare := func(name, x, y string) {
+		if !strings.EqualFold(strings.TrimSpace(x), strings.TrimSpace(y)) {
+			differences = append(differences, name)
+		}
+	}
+	compare("field_description", a.Description, b.Description)
+	compare("field_type", a.FieldType, b.FieldType)
+	compare("system_a_fieldmap", a.SystemAFieldMap, b.SystemAFieldMap)
+	compare("system_b_fieldmap", a.SystemBFieldMap, b.SystemBFieldMap)
+	compare("system_a_tablemap", a.SystemATableMap, b.SystemATableMap)
+	compare("system_b_tablemap", a.SystemBTableMap, b.SystemBTableMap)
+	compare("foreign_table", a.ForeignTable, b.ForeignTable)
+	compare("foreign_key", a.ForeignKey, b.ForeignKey)
+	compare("owner", a.Owner, b.Owner)
+	compare("table_alias", a.TableAlias, b.TableAlias)
+	compare("schema_name", a.SchemaName, b.SchemaName)
+	compare("database_name", a.DatabaseName, b.DatabaseName)
+	compare("dbt_ref", a.DbtRef, b.DbtRef)
+	compare("deprecated", formatFlag(a.Deprecated), formatFlag(b.Deprecated))
+	compare("sensitive", formatFlag(a.Sensitive), formatFlag(b.Sensitive))
+	compare("classification", a.Classification, b.Classification)
+	compare("measure", a.Measure, b.Measure)
+	return differences
+}
+
+// containsString reports whether a list holds a value exactly
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MappingReport describes the mapping files behind the current schema and
+// any definitions they disagreed on
+func (s *FieldService) MappingReport() models.MappingReport {
+	return models.MappingReport{
+		Version:     s.version,
+		Files:       s.mappingFiles,
+		Fields:      len(s.fields),
+		Diagnostics: s.mergeDiagnostics,
+	}
+}
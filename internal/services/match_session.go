@@ -0,0 +1,198 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Defaults for incremental matching sessions when not configured
+const (
+	defaultMatchSessionTTL  = 10 * time.Minute
+	defaultMaxMatchSessions = 1000
+)
+
+// matchMemoLimit bounds the keyword sets remembered per session, so
+// backspacing to an earlier description doesn't re-run matching
+const matchMemoLimit = 32
+
+// matchSession is one autocomplete client's matching state
+type matchSession struct {
+	mu       sync.Mutex
+	version  string
+	key      string
+	matches  []models.FieldMatch
+	memo     map[string][]models.FieldMatch
+	revision int
+	lastUsed time.Time
+}
+
+// MatchSessions caches incremental matching state per client session,
+// expiring sessions idle for longer than the TTL
+type MatchSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*matchSession
+	ttl      time.Duration
+	max      int
+}
+
+// NewMatchSessions creates an empty session cache with the configured limits
+func NewMatchSessions(cfg *config.Config) *MatchSessions {
+	ttl := cfg.MatchSessionTTL
+	if ttl <= 0 {
+		ttl = defaultMatchSessionTTL
+	}
+	max := cfg.MaxMatchSessions
+	if max <= 0 {
+		max = defaultMaxMatchSessions
+	}
+	return &MatchSessions{sessions: make(map[string]*matchSession), ttl: ttl, max: max}
+}
+
+// Len returns the number of cached sessions
+func (m *MatchSessions) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
+
+// session returns the live session for an ID, or a new session under a fresh
+// ID when the ID is empty, unknown, or expired
+func (m *MatchSessions) session(id string) (string, *matchSession, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if session, exists := m.sessions[id]; exists && now.Sub(session.lastUsed) <= m.ttl {
+		session.lastUsed = now
+		return id, session, false
+	}
+	delete(m.sessions, id)
+	m.evict(now)
+
+	id = NewTraceID()
+	session := &matchSession{memo: make(map[string][]models.FieldMatch), lastUsed: now}
+	m.sessions[id] = session
+	return id, session, true
+}
+
+// evict drops expired sessions and, at capacity, the least recently used.
+// Callers hold mu
+func (m *MatchSessions) evict(now time.Time) {
+	var oldestID string
+	var oldest time.Time
+	for id, session := range m.sessions {
+		if now.Sub(session.lastUsed) > m.ttl {
+			delete(m.sessions, id)
+			continue
+		}
+		if oldestID == "" || session.lastUsed.Before(oldest) {
+			oldestID, oldest = id, session.lastUsed
+		}
+	}
+	if len(m.sessions) >= m.max {
+		delete(m.sessions, oldestID)
+	}
+}
+
+// MatchIncremental matches a growing description within a session and
+// returns only what changed since the session's last call. Matching only
+// re-runs when the set of completed keywords changes
+func (s *QueryService) MatchIncremental(sessions *MatchSessions, request models.IncrementalMatchRequest) (models.IncrementalMatchResponse, error) {
+	if err := s.checkDescriptionLength(request.Description); err != nil {
+		return models.IncrementalMatchResponse{}, err
+	}
+
+	id, session, created := sessions.session(request.SessionID)
+	log := s.log.WithField("session_id", id)
+
+	// The word still being typed would only churn the matches
+	description := request.Description
+	if !request.Complete {
+		description = trimPartialWord(description)
+	}
+	keywords := s.extractKeywords(description, log)
+	clearance := s.clearance(request.Clearance)
+	threshold, maxMatches := s.matchLimits(nil, request.MaxMatches)
+	key := strings.Join(keywords, " ") + "|" + strconv.Itoa(maxMatches) + "|" + clearance
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// A schema reload invalidates everything the session has seen
+	reset := created || session.version != s.fieldService.Version()
+	if reset {
+		session.version, session.key, session.matches = s.fieldService.Version(), "", nil
+		session.memo = make(map[string][]models.FieldMatch)
+	}
+
+	response := models.IncrementalMatchResponse{SessionID: id, Reset: reset, Keywords: keywords}
+	if !reset && key == session.key {
+		response.Revision, response.Matches = session.revision, len(session.matches)
+		return response, nil
+	}
+
+	matches, remembered := session.memo[key]
+	if !remembered {
+		if len(keywords) > 0 {
+			matches = s.fieldService.FindFilteredFieldMatches(keywords, threshold, maxMatches, FieldFilter{}, log)
+			matches, _, _ = withholdMatches(matches, clearance)
+		}
+		if len(session.memo) >= matchMemoLimit {
+			session.memo = make(map[string][]models.FieldMatch)
+		}
+		session.memo[key] = matches
+	}
+
+	response.Added, response.Updated, response.Removed = matchDelta(session.matches, matches)
+	response.Changed = reset || len(response.Added)+len(response.Updated)+len(response.Removed) > 0
+	session.key, session.matches = key, matches
+	session.revision++
+	response.Revision, response.Matches = session.revision, len(matches)
+	return response, nil
+}
+
+// matchDelta compares two match lists by field, returning the matches that
+// are new, the ones whose score changed, and the fields no longer matched
+func matchDelta(previous, current []models.FieldMatch) ([]models.FieldMatch, []models.FieldMatch, []models.FieldRef) {
+	before := make(map[string]models.FieldMatch, len(previous))
+	for _, match := range previous {
+		before[fieldKey(match.TableName, match.ColumnName)] = match
+	}
+
+	var added, updated []models.FieldMatch
+	seen := make(map[string]bool, len(current))
+	for _, match := range current {
+		key := fieldKey(match.TableName, match.ColumnName)
+		seen[key] = true
+		old, existed := before[key]
+		switch {
+		case !existed:
+			added = append(added, match)
+		case old.MatchScore != match.MatchScore:
+			updated = append(updated, match)
+		}
+	}
+
+	var removed []models.FieldRef
+	for _, match := range previous {
+		if !seen[fieldKey(match.TableName, match.ColumnName)] {
+			removed = append(removed, models.FieldRef{Table: match.TableName, Column: match.ColumnName})
+		}
+	}
+	return added, updated, removed
+}
+
+// trimPartialWord drops a trailing word that may still be being typed,
+// keeping everything up to the last separator
+func trimPartialWord(description string) string {
+	trimmed := strings.TrimRightFunc(description, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+	})
+	return trimmed
+}
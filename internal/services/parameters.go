@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Parameter styles of parameterized queries
+const (
+	ParameterStyleInline   = "inline"
+	ParameterStyleDialect  = "dialect"
+	ParameterStyleDollar   = "dollar"
+	ParameterStyleQuestion = "question"
+	ParameterStyleNamed    = "named"
+	ParameterStyleAt       = "at"
+)
+
+// dialectParameterStyles are the placeholders each dialect's drivers take;
+// unlisted dialects use Postgres's
+var dialectParameterStyles = map[string]string{
+	DialectMySQL:     ParameterStyleQuestion,
+	DialectSQLite:    ParameterStyleQuestion,
+	DialectSnowflake: ParameterStyleQuestion,
+	DialectSQLServer: ParameterStyleAt,
+	DialectBigQuery:  ParameterStyleAt,
+}
+
+// parameterBinder collects the values of a parameterized query as its
+// placeholders are rendered. A nil binder inlines literals instead
+type parameterBinder struct {
+	style      string
+	parameters []models.QueryParameter
+}
+
+// newParameterBinder resolves a requested parameter style for a dialect,
+// returning nil for inline literals
+func newParameterBinder(style, dialect string) (*parameterBinder, error) {
+	switch strings.ToLower(strings.TrimSpace(style)) {
+	case "", ParameterStyleInline:
+		return nil, nil
+	case ParameterStyleDialect:
+		resolved, exists := dialectParameterStyles[dialect]
+		if !exists {
+			resolved = ParameterStyleDollar
+		}
+		return &parameterBinder{style: resolved}, nil
+	case ParameterStyleDollar, ParameterStyleQuestion, ParameterStyleNamed, ParameterStyleAt:
+		return &parameterBinder{style: strings.ToLower(strings.TrimSpace(style))}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown parameter style %q: use inline, dialect, dollar, question, named, or at", ErrInvalidIntent, style)
+	}
+}
+
+// bind renders a filter value for a column of fieldType: a placeholder
+// bound to the normalized value, or the inline literal without a binder
+func (b *parameterBinder) bind(fieldType, dialect string, value interface{}) (string, error) {
+	literal, err := formatTypedLiteral(fieldType, dialect, value)
+	if err != nil || b == nil {
+		return literal, err
+	}
+
+	position := len(b.parameters) + 1
+	parameter := models.QueryParameter{Position: position, Value: parameterValue(fieldType, value), Type: fieldType}
+	placeholder := "?"
+	switch b.style {
+	case ParameterStyleDollar:
+		placeholder = "$" + strconv.Itoa(position)
+	case ParameterStyleNamed:
+		parameter.Name = "p" + strconv.Itoa(position)
+		placeholder = ":" + parameter.Name
+	case ParameterStyleAt:
+		parameter.Name = "p" + strconv.Itoa(position)
+		placeholder = "@" + parameter.Name
+	}
+	b.parameters = append(b.parameters, parameter)
+	return placeholder, nil
+}
+
+// parameterValue normalizes a value formatTypedLiteral accepted to what a
+// driver binds for the column type: booleans and integers are parsed, times
+// are ISO text, and everything else is sent as given
+func parameterValue(fieldType string, value interface{}) interface{} {
+	switch literalType(fieldType) {
+	case "BOOLEAN", "BOOL":
+		b, _ := parseBoolean(value)
+		return b
+	case "INTEGER", "INT", "SMALLINT", "BIGINT":
+		switch v := value.(type) {
+		case float64:
+			return int64(v)
+		case string:
+			n, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			return n
+		}
+	case "DATE":
+		date, _ := normalizeDate(strings.TrimSpace(value.(string)))
+		return date
+	case "TIMESTAMP", "DATETIME", "TIMESTAMPTZ":
+		normalized, _ := normalizeTimestamp(strings.TrimSpace(value.(string)))
+		return normalized
+	case "UUID":
+		return strings.ToLower(strings.TrimSpace(value.(string)))
+	case "VARCHAR", "NVARCHAR", "CHAR", "NCHAR", "TEXT", "STRING":
+		text, _ := textValue(value)
+		return text
+	case "DECIMAL", "NUMERIC", "REAL", "FLOAT", "DOUBLE", "INET":
+		if s, ok := value.(string); ok {
+			return strings.TrimSpace(s)
+		}
+	}
+	return value
+}
+
+// values returns the bound values, or nil for inline literals
+func (b *parameterBinder) values() []models.QueryParameter {
+	if b == nil {
+		return nil
+	}
+	return b.parameters
+}
+
+// styleName is the resolved style reported with the query
+func (b *parameterBinder) styleName() string {
+	if b == nil {
+		return ""
+	}
+	return b.style
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// parquetMagic starts and ends every Parquet file
+var parquetMagic = []byte("PAR1")
+
+// maxParquetFooterBytes caps the footer read from a Parquet file
+const maxParquetFooterBytes = 16 << 20
+
+// Parquet physical types, converted types, and logical types, numbered as in
+// the format's Thrift definition
+const (
+	parquetBoolean           = 0
+	parquetInt32             = 1
+	parquetInt64             = 2
+	parquetInt96             = 3
+	parquetFloat             = 4
+	parquetDouble            = 5
+	parquetByteArray         = 6
+	parquetFixedLenByteArray = 7
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedMap             = 1
+	parquetConvertedMapKeyValue     = 2
+	parquetConvertedList            = 3
+	parquetConvertedEnum            = 4
+	parquetConvertedDecimal         = 5
+	parquetConvertedDate            = 6
+	parquetConvertedTimeMillis      = 7
+	parquetConvertedTimeMicros      = 8
+	parquetConvertedTimestampMillis = 9
+	parquetConvertedTimestampMicros = 10
+	parquetConvertedJSON            = 19
+
+	parquetLogicalString    = 1
+	parquetLogicalMap       = 2
+	parquetLogicalList      = 3
+	parquetLogicalEnum      = 4
+	parquetLogicalDecimal   = 5
+	parquetLogicalDate      = 6
+	parquetLogicalTime      = 7
+	parquetLogicalTimestamp = 8
+	parquetLogicalJSON      = 12
+	parquetLogicalUUID      = 14
+)
+
+// parquetPhysicalTypes maps physical types to column types
+var parquetPhysicalTypes = map[int32]string{
+	parquetBoolean:           "BOOLEAN",
+	parquetInt32:             "INTEGER",
+	parquetInt64:             "BIGINT",
+	parquetInt96:             "TIMESTAMP",
+	parquetFloat:             "REAL",
+	parquetDouble:            "DOUBLE",
+	parquetByteArray:         "BINARY",
+	parquetFixedLenByteArray: "BINARY",
+}
+
+// parquetConvertedTypes maps converted (legacy logical) types to column types
+var parquetConvertedTypes = map[int32]string{
+	parquetConvertedUTF8:            "VARCHAR",
+	parquetConvertedMap:             "MAP",
+	parquetConvertedMapKeyValue:     "MAP",
+	parquetConvertedList:            "ARRAY",
+	parquetConvertedEnum:            "VARCHAR",
+	parquetConvertedDecimal:         "DECIMAL",
+	parquetConvertedDate:            "DATE",
+	parquetConvertedTimeMillis:      "TIME",
+	parquetConvertedTimeMicros:      "TIME",
+	parquetConvertedTimestampMillis: "TIMESTAMP",
+	parquetConvertedTimestampMicros: "TIMESTAMP",
+	parquetConvertedJSON:            "VARCHAR",
+}
+
+// parquetLogicalTypes maps logical type annotations to column types
+var parquetLogicalTypes = map[int16]string{
+	parquetLogicalString:    "VARCHAR",
+	parquetLogicalMap:       "MAP",
+	parquetLogicalList:      "ARRAY",
+	parquetLogicalEnum:      "VARCHAR",
+	parquetLogicalDecimal:   "DECIMAL",
+	parquetLogicalDate:      "DATE",
+	parquetLogicalTime:      "TIME",
+	parquetLogicalTimestamp: "TIMESTAMP",
+	parquetLogicalJSON:      "VARCHAR",
+	parquetLogicalUUID:      "VARCHAR",
+}
+
+// parquetElement is the part of a footer SchemaElement the importer reads
+type parquetElement struct {
+	name          string
+	physicalType  int32
+	hasPhysical   bool
+	convertedType int32
+	hasConverted  bool
+	logicalType   int16
+	numChildren   int32
+}
+
+// readParquetTail reads just the footer of a local Parquet file, which is
+// all the schema import needs
+func readParquetTail(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < 12 {
+		return nil, fmt.Errorf("%s is too small to be a Parquet file", path)
+	}
+	trailer := make([]byte, 8)
+	if _, err := file.ReadAt(trailer, info.Size()-8); err != nil {
+		return nil, err
+	}
+	length := int64(binary.LittleEndian.Uint32(trailer))
+	if length > maxParquetFooterBytes || length+8 > info.Size() {
+		return nil, fmt.Errorf("%s has an invalid Parquet footer", path)
+	}
+	tail := make([]byte, length+8)
+	if _, err := file.ReadAt(tail, info.Size()-length-8); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return tail, nil
+}
+
+// readParquetSchema imports a table from a Parquet footer, named after the
+// file. Columns are the schema's top-level fields; nested groups become
+// STRUCT, ARRAY, or MAP columns. Parquet carries no descriptions
+func readParquetSchema(path string, data []byte) (SchemaDefinition, error) {
+	if len(data) < 8 || !bytes.Equal(data[len(data)-4:], parquetMagic) {
+		return SchemaDefinition{}, fmt.Errorf("not a Parquet file: missing PAR1 trailer")
+	}
+	length := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if length > len(data)-8 {
+		return SchemaDefinition{}, fmt.Errorf("invalid Parquet footer length %d", length)
+	}
+	elements, err := parseParquetFooter(data[len(data)-8-length : len(data)-8])
+	if err != nil {
+		return SchemaDefinition{}, fmt.Errorf("invalid Parquet footer: %w", err)
+	}
+	if len(elements) < 2 {
+		return SchemaDefinition{}, fmt.Errorf("Parquet schema has no columns")
+	}
+
+	table := TableDefinition{Name: lakeTableName(path)}
+	// The first element is the root; each top-level column's descendants
+	// follow it depth first and are skipped
+	for i := 1; i < len(elements); {
+		element := elements[i]
+		table.Columns = append(table.Columns, ColumnDefinition{
+			Name: element.name,
+			Type: parquetColumnType(element),
+		})
+		i += parquetSubtreeSize(elements, i)
+	}
+	return SchemaDefinition{Tables: []TableDefinition{table}}, nil
+}
+
+// parquetSubtreeSize counts an element and all of its descendants
+func parquetSubtreeSize(elements []parquetElement, i int) int {
+	size := 1
+	for child := int32(0); child < elements[i].numChildren && i+size < len(elements); child++ {
+		size += parquetSubtreeSize(elements, i+size)
+	}
+	return size
+}
+
+// parquetColumnType maps an element's annotations to a column type,
+// preferring the logical type, then the converted type
+func parquetColumnType(element parquetElement) string {
+	if columnType, ok := parquetLogicalTypes[element.logicalType]; ok {
+		return columnType
+	}
+	if element.hasConverted {
+		if columnType, ok := parquetConvertedTypes[element.convertedType]; ok {
+			return columnType
+		}
+	}
+	if !element.hasPhysical {
+		return "STRUCT"
+	}
+	return parquetPhysicalTypes[element.physicalType]
+}
+
+// parseParquetFooter decodes the schema list from a Thrift compact-encoded
+// FileMetaData, skipping everything else
+func parseParquetFooter(footer []byte) ([]parquetElement, error) {
+	reader := &thriftReader{data: footer}
+	var elements []parquetElement
+	err := reader.readStruct(func(id int16, fieldType byte) error {
+		if id != 2 || fieldType != thriftList {
+			return reader.skip(fieldType)
+		}
+		size, elementType, err := reader.readListHeader()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if elementType != thriftStruct {
+				if err := reader.skip(elementType); err != nil {
+					return err
+				}
+				continue
+			}
+			element, err := reader.readSchemaElement()
+			if err != nil {
+				return err
+			}
+			elements = append(elements, element)
+		}
+		return nil
+	})
+	return elements, err
+}
+
+// readSchemaElement decodes one SchemaElement struct
+func (r *thriftReader) readSchemaElement() (parquetElement, error) {
+	var element parquetElement
+	err := r.readStruct(func(id int16, fieldType byte) error {
+		var err error
+		switch {
+		case id == 1 && fieldType == thriftI32:
+			element.physicalType, err = r.readI32()
+			element.hasPhysical = true
+		case id == 4 && fieldType == thriftBinary:
+			var name []byte
+			name, err = r.readBinary()
+			element.name = string(name)
+		case id == 5 && fieldType == thriftI32:
+			element.numChildren, err = r.readI32()
+		case id == 6 && fieldType == thriftI32:
+			element.convertedType, err = r.readI32()
+			element.hasConverted = true
+		case id == 10 && fieldType == thriftStruct:
+			// LogicalType is a union: the set field names the type
+			err = r.readStruct(func(id int16, fieldType byte) error {
+				element.logicalType = id
+				return r.skip(fieldType)
+			})
+		default:
+			err = r.skip(fieldType)
+		}
+		return err
+	})
+	return element, err
+}
+
+// Thrift compact protocol field types
+const (
+	thriftStop      = 0
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftByte      = 3
+	thriftI16       = 4
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftDouble    = 7
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftSet       = 10
+	thriftMap       = 11
+	thriftStruct    = 12
+)
+
+// maxThriftDepth bounds struct nesting in an untrusted footer
+const maxThriftDepth = 32
+
+// thriftReader decodes the Thrift compact protocol
+type thriftReader struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// readStruct calls field for each field of a struct until its stop byte
+func (r *thriftReader) readStruct(field func(id int16, fieldType byte) error) error {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > maxThriftDepth {
+		return fmt.Errorf("structs nested too deeply")
+	}
+
+	var lastID int16
+	for {
+		header, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if header == thriftStop {
+			return nil
+		}
+		fieldType := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			lastID += delta
+		} else {
+			id, err := r.readVarint()
+			if err != nil {
+				return err
+			}
+			lastID = int16(zigzag(id))
+		}
+		if err := field(lastID, fieldType); err != nil {
+			return err
+		}
+	}
+}
+
+// skip reads past a value of the given type
+func (r *thriftReader) skip(fieldType byte) error {
+	switch fieldType {
+	case thriftBoolTrue, thriftBoolFalse:
+		return nil
+	case thriftByte:
+		_, err := r.readByte()
+		return err
+	case thriftI16, thriftI32, thriftI64:
+		_, err := r.readVarint()
+		return err
+	case thriftDouble:
+		return r.advance(8)
+	case thriftBinary:
+		_, err := r.readBinary()
+		return err
+	case thriftList, thriftSet:
+		size, elementType, err := r.readListHeader()
+		if err != nil {
+			return err
+		}
+		elementType = collectionType(elementType)
+		for i := 0; i < size; i++ {
+			if err := r.skip(elementType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case thriftMap:
+		size, err := r.readVarint()
+		if err != nil || size == 0 {
+			return err
+		}
+		if size > uint64(len(r.data)-r.pos) {
+			return fmt.Errorf("map of %d entries overruns the footer", size)
+		}
+		types, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		keyType, valueType := collectionType(types>>4), collectionType(types&0x0f)
+		for i := uint64(0); i < size; i++ {
+			if err := r.skip(keyType); err != nil {
+				return err
+			}
+			if err := r.skip(valueType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case thriftStruct:
+		return r.readStruct(func(_ int16, fieldType byte) error {
+			return r.skip(fieldType)
+		})
+	}
+	return fmt.Errorf("unknown field type %d", fieldType)
+}
+
+// collectionType is the type a collection's elements are read as.
+// Booleans in collections take a byte each
+func collectionType(elementType byte) byte {
+	if elementType == thriftBoolTrue || elementType == thriftBoolFalse {
+		return thriftByte
+	}
+	return elementType
+}
+
+// readListHeader reads a list's size and element type
+func (r *thriftReader) readListHeader() (int, byte, error) {
+	header, err := r.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := uint64(header >> 4)
+	if size == 15 {
+		if size, err = r.readVarint(); err != nil {
+			return 0, 0, err
+		}
+	}
+	if size > uint64(len(r.data)-r.pos) {
+		return 0, 0, fmt.Errorf("list of %d elements overruns the footer", size)
+	}
+	return int(size), header & 0x0f, nil
+}
+
+// readI32 reads a zigzag-encoded 32-bit integer
+func (r *thriftReader) readI32() (int32, error) {
+	value, err := r.readVarint()
+	if err != nil {
+		return 0, err
+	}
+	decoded := zigzag(value)
+	if decoded < math.MinInt32 || decoded > math.MaxInt32 {
+		return 0, fmt.Errorf("integer %d out of range", decoded)
+	}
+	return int32(decoded), nil
+}
+
+// readBinary reads a length-prefixed byte string
+func (r *thriftReader) readBinary() ([]byte, error) {
+	length, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)-r.pos) {
+		return nil, fmt.Errorf("string of %d bytes overruns the footer", length)
+	}
+	value := r.data[r.pos : r.pos+int(length)]
+	r.pos += int(length)
+	return value, nil
+}
+
+// readVarint reads an unsigned LEB128 varint
+func (r *thriftReader) readVarint() (uint64, error) {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("truncated varint at byte %d", r.pos)
+	}
+	r.pos += n
+	return value, nil
+}
+
+// readByte reads a single byte
+func (r *thriftReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("unexpected end of footer")
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+// advance skips n bytes
+func (r *thriftReader) advance(n int) error {
+	if n > len(r.data)-r.pos {
+		return fmt.Errorf("unexpected end of footer")
+	}
+	r.pos += n
+	return nil
+}
+
+// zigzag decodes a zigzag-encoded integer
+func zigzag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
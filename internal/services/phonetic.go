@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/config"
+)
+
+// Supported phonetic algorithms
+const (
+	PhoneticAlgorithmNone      = "none"
+	PhoneticAlgorithmSoundex   = "soundex"
+	PhoneticAlgorithmMetaphone = "metaphone"
+)
+
+// phoneticMatcher expands keywords to schema words that sound alike, as a
+// fallback for phonetic spellings such as "kwery" or "recieve"
+type phoneticMatcher struct {
+	encode func(string) string
+	index  map[string][]string
+}
+
+// newPhoneticMatcher indexes the vocabulary by phonetic code, or returns nil
+// when phonetic matching is disabled
+func newPhoneticMatcher(cfg *config.Config, vocabulary []string) (*phoneticMatcher, error) {
+	var encode func(string) string
+	switch cfg.PhoneticAlgorithm {
+	case "", PhoneticAlgorithmNone:
+		return nil, nil
+	case PhoneticAlgorithmSoundex:
+		encode = soundex
+	case PhoneticAlgorithmMetaphone:
+		encode = metaphone
+	default:
+		return nil, fmt.Errorf("unknown phonetic algorithm %q", cfg.PhoneticAlgorithm)
+	}
+
+	index := make(map[string][]string)
+	for _, word := range vocabulary {
+		if code := encode(word); code != "" {
+			index[code] = append(index[code], word)
+		}
+	}
+	return &phoneticMatcher{encode: encode, index: index}, nil
+}
+
+// expand pairs each keyword with the vocabulary words sharing its phonetic code
+func (m *phoneticMatcher) expand(keywords []string) []keywordTerm {
+	terms := make([]keywordTerm, len(keywords))
+	for i, keyword := range keywords {
+		terms[i] = keywordTerm{keyword: keyword}
+		if code := m.encode(keyword); code != "" {
+			terms[i].alternatives = m.index[code]
+		}
+	}
+	return terms
+}
+
+// soundexCodes maps letters to their Soundex digit; vowels and H, W, Y are 0
+var soundexCodes = map[byte]byte{
+	'B': '1', 'F': '1', 'P': '1', 'V': '1',
+	'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+	'D': '3', 'T': '3',
+	'L': '4',
+	'M': '5', 'N': '5',
+	'R': '6',
+}
+
+// soundex computes the American Soundex code of a word (e.g. R163)
+func soundex(word string) string {
+	letters := lettersOnly(word)
+	if letters == "" {
+		return ""
+	}
+
+	code := []byte{letters[0]}
+	last := soundexCodes[letters[0]]
+	for i := 1; i < len(letters) && len(code) < 4; i++ {
+		c := letters[i]
+		digit, ok := soundexCodes[c]
+		if !ok {
+			// Vowels separate repeated codes; H and W do not
+			if c != 'H' && c != 'W' {
+				last = 0
+			}
+			continue
+		}
+		if digit != last {
+			code = append(code, digit)
+		}
+		last = digit
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+// metaphone computes a simplified original Metaphone key of a word
+func metaphone(word string) string {
+	w := lettersOnly(word)
+	if w == "" {
+		return ""
+	}
+
+	// Initial-letter exceptions
+	switch {
+	case strings.HasPrefix(w, "AE"), strings.HasPrefix(w, "GN"), strings.HasPrefix(w, "KN"),
+		strings.HasPrefix(w, "PN"), strings.HasPrefix(w, "WR"):
+		w = w[1:]
+	case strings.HasPrefix(w, "X"):
+		w = "S" + w[1:]
+	case strings.HasPrefix(w, "WH"):
+		w = "W" + w[2:]
+	}
+	// "QU" is pronounced "KW"
+	w = strings.ReplaceAll(w, "QU", "KW")
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(w) {
+			return 0
+		}
+		return w[i]
+	}
+	isVowel := func(c byte) bool { return c != 0 && strings.IndexByte("AEIOU", c) >= 0 }
+	frontVowel := func(c byte) bool { return c == 'E' || c == 'I' || c == 'Y' }
+
+	var key strings.Builder
+	for i := 0; i < len(w); i++ {
+		c := w[i]
+		// Skip doubled letters except C
+		if c != 'C' && i > 0 && at(i-1) == c {
+			continue
+		}
+
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				key.WriteByte(c)
+			}
+		case 'B':
+			if !(i == len(w)-1 && at(i-1) == 'M') {
+				key.WriteByte('B')
+			}
+		case 'C':
+			switch {
+			case at(i+1) == 'I' && at(i+2) == 'A', at(i+1) == 'H':
+				key.WriteByte('X')
+			case frontVowel(at(i + 1)):
+				if at(i-1) != 'S' {
+					key.WriteByte('S')
+				}
+			default:
+				key.WriteByte('K')
+			}
+		case 'D':
+			if at(i+1) == 'G' && frontVowel(at(i+2)) {
+				key.WriteByte('J')
+			} else {
+				key.WriteByte('T')
+			}
+		case 'G':
+			switch {
+			case at(i+1) == 'H' && i+2 < len(w) && !isVowel(at(i+2)):
+			case at(i+1) == 'N':
+			case frontVowel(at(i + 1)):
+				key.WriteByte('J')
+			default:
+				key.WriteByte('K')
+			}
+		case 'H':
+			prev := at(i - 1)
+			if strings.IndexByte("CSPTG", prev) < 0 && isVowel(at(i+1)) && !isVowel(prev) {
+				key.WriteByte('H')
+			}
+		case 'K':
+			if at(i-1) != 'C' {
+				key.WriteByte('K')
+			}
+		case 'P':
+			if at(i+1) == 'H' {
+				key.WriteByte('F')
+			} else {
+				key.WriteByte('P')
+			}
+		case 'Q':
+			key.WriteByte('K')
+		case 'S':
+			if at(i+1) == 'H' || (at(i+1) == 'I' && (at(i+2) == 'O' || at(i+2) == 'A')) {
+				key.WriteByte('X')
+			} else {
+				key.WriteByte('S')
+			}
+		case 'T':
+			switch {
+			case at(i+1) == 'I' && (at(i+2) == 'O' || at(i+2) == 'A'):
+				key.WriteByte('X')
+			case at(i+1) == 'H':
+				key.WriteByte('0')
+			case at(i+1) == 'C' && at(i+2) == 'H':
+			default:
+				key.WriteByte('T')
+			}
+		case 'V':
+			key.WriteByte('F')
+		case 'W', 'Y':
+			if isVowel(at(i + 1)) {
+				key.WriteByte(c)
+			}
+		case 'X':
+			key.WriteString("KS")
+		case 'Z':
+			key.WriteByte('S')
+		default:
+			key.WriteByte(c)
+		}
+	}
+	return key.String()
+}
+
+// lettersOnly upper-cases a word and strips everything but A-Z
+func lettersOnly(word string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(word) {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Aggregate of a plan column selecting a curated measure
+const planMeasure = "MEASURE"
+
+// renderPlan renders a query plan as SQL, limited and offset the way the
+// dialect pages
+func renderPlan(dialect string, plan models.QueryPlan) string {
+	columns := make([]string, len(plan.Select))
+	for i, column := range plan.Select {
+		columns[i] = column.Expression
+		if column.Alias != "" {
+			columns[i] += " AS " + quoteIdent(dialect, column.Alias)
+		}
+	}
+	selectClause := strings.Join(columns, ", ")
+	if plan.Distinct {
+		selectClause = "DISTINCT " + selectClause
+	}
+	top, limitClause := rowLimit(dialect, plan.Limit, plan.Offset, len(plan.OrderBy) > 0)
+
+	query := "SELECT " + withTop(selectClause, top) + " FROM " + renderTable(dialect, plan.From)
+	for _, join := range plan.Joins {
+		keyword := "JOIN"
+		if join.Type == JoinTypeLeft {
+			keyword = "LEFT JOIN"
+		}
+		query += " " + keyword + " " + renderTable(dialect, join.Table) + " ON " + join.Condition
+	}
+	if len(plan.Filters) > 0 {
+		conditions := make([]string, len(plan.Filters))
+		for i, filter := range plan.Filters {
+			conditions[i] = filter.Expression
+		}
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if len(plan.GroupBy) > 0 {
+		query += " GROUP BY " + strings.Join(plan.GroupBy, ", ")
+	}
+	if plan.Having != "" {
+		query += " HAVING " + plan.Having
+	}
+	if len(plan.OrderBy) > 0 {
+		orders := make([]string, len(plan.OrderBy))
+		for i, order := range plan.OrderBy {
+			orders[i] = order.Expression + " " + order.Direction
+		}
+		query += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	if limitClause != "" {
+		query += " " + limitClause
+	}
+	return query
+}
+
+// renderTable renders a FROM or JOIN table with its alias
+func renderTable(dialect string, table models.PlanTable) string {
+	if table.Alias == "" {
+		return table.Name
+	}
+	return table.Name + " " + quoteIdent(dialect, table.Alias)
+}
+
+// assignPlanIDs gives the columns, joins, filters, and orders of a plan
+// that have no ID one derived from what they are, so the same query always
+// gets the same IDs. IDs already set, such as those of an edited plan, are
+// kept; repeats get a numbered suffix
+func assignPlanIDs(plan *models.QueryPlan) {
+	used := make(map[string]bool)
+	for _, id := range planIDs(plan) {
+		if id != "" {
+			used[id] = true
+		}
+	}
+	assign := func(id *string, kind string, parts ...string) {
+		if *id != "" {
+			return
+		}
+		digest := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+		base := kind + "_" + hex.EncodeToString(digest[:4])
+		*id = base
+		for n := 2; used[*id]; n++ {
+			*id = base + "_" + strconv.Itoa(n)
+		}
+		used[*id] = true
+	}
+
+	for i := range plan.Select {
+		column := &plan.Select[i]
+		if column.Table == "" {
+			assign(&column.ID, "col", column.Expression)
+		} else {
+			assign(&column.ID, "col", column.Table, column.Column, column.Aggregate, column.Alias)
+		}
+	}
+	for i := range plan.Joins {
+		assign(&plan.Joins[i].ID, "join", plan.Joins[i].Table.Table)
+	}
+	for i := range plan.Filters {
+		filter := &plan.Filters[i]
+		if filter.Cohort != "" {
+			assign(&filter.ID, "filter", "cohort", filter.Cohort)
+		} else {
+			assign(&filter.ID, "filter", filter.Table, filter.Column, filter.Operator)
+		}
+	}
+	for i := range plan.OrderBy {
+		assign(&plan.OrderBy[i].ID, "order", plan.OrderBy[i].Expression)
+	}
+}
+
+// planIDs lists the IDs a plan already carries
+func planIDs(plan *models.QueryPlan) []string {
+	var ids []string
+	for _, column := range plan.Select {
+		ids = append(ids, column.ID)
+	}
+	for _, join := range plan.Joins {
+		ids = append(ids, join.ID)
+	}
+	for _, filter := range plan.Filters {
+		ids = append(ids, filter.ID)
+	}
+	for _, order := range plan.OrderBy {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RolePolicy restricts which tables and fields a role may query
+type RolePolicy struct {
+	// AllowTables lists permitted tables; empty or "*" permits every table
+	AllowTables []string `json:"allow_tables" yaml:"allow_tables"`
+	DenyTables  []string `json:"deny_tables" yaml:"deny_tables"`
+	// DenyFields lists blocked fields as table.column
+	DenyFields []string `json:"deny_fields" yaml:"deny_fields"`
+}
+
+// AggregatePolicy limits tables to aggregate queries: they may only be
+// counted or grouped, and every group must hold at least MinGroupSize rows
+type AggregatePolicy struct {
+	Tables       []string `json:"tables" yaml:"tables"`
+	MinGroupSize int      `json:"min_group_size" yaml:"min_group_size"`
+}
+
+// defaultMinGroupSize is the smallest group reported when an aggregate-only
+// policy sets no minimum
+const defaultMinGroupSize = 10
+
+// ErrAggregateOnly is returned when a query would read rows from a table
+// that may only be aggregated
+var ErrAggregateOnly = errors.New("aggregate-only table")
+
+// PolicySet holds the access policy for every role, and the aggregate-only
+// tables that apply to every query
+type PolicySet struct {
+	Roles         map[string]RolePolicy `json:"roles" yaml:"roles"`
+	AggregateOnly *AggregatePolicy      `json:"aggregate_only,omitempty" yaml:"aggregate_only,omitempty"`
+}
+
+// LoadPolicies reads a policy file, parsed as YAML or JSON by extension
+func LoadPolicies(path string) (*PolicySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policies PolicySet
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &policies)
+	default:
+		err = json.Unmarshal(data, &policies)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+	if len(policies.Roles) == 0 && policies.AggregateOnly == nil {
+		return nil, fmt.Errorf("policy file %s defines no roles", path)
+	}
+	if aggregate := policies.AggregateOnly; aggregate != nil {
+		if len(aggregate.Tables) == 0 {
+			return nil, fmt.Errorf("policy file %s: aggregate_only lists no tables", path)
+		}
+		if aggregate.MinGroupSize < 0 {
+			return nil, fmt.Errorf("policy file %s: aggregate_only min_group_size must not be negative", path)
+		}
+	}
+	return &policies, nil
+}
+
+// Role returns the policy for a role
+func (p *PolicySet) Role(role string) (RolePolicy, bool) {
+	if p == nil {
+		return RolePolicy{}, false
+	}
+	policy, exists := p.Roles[role]
+	return policy, exists
+}
+
+// AllowsTable reports whether the role may read from a table at all
+func (r RolePolicy) AllowsTable(table string) (bool, string) {
+	for _, denied := range r.DenyTables {
+		if denied == table {
+			return false, fmt.Sprintf("table %s is denied", table)
+		}
+	}
+	if len(r.AllowTables) == 0 {
+		return true, ""
+	}
+	for _, allowed := range r.AllowTables {
+		if allowed == "*" || allowed == table {
+			return true, ""
+		}
+	}
+	return false, fmt.Sprintf("table %s is not in the allowed tables", table)
+}
+
+// AllowsField reports whether the role may select a field, with the reason
+// when it may not
+func (r RolePolicy) AllowsField(table, column string) (bool, string) {
+	if allowed, reason := r.AllowsTable(table); !allowed {
+		return false, reason
+	}
+	key := fieldKey(table, column)
+	for _, denied := range r.DenyFields {
+		if denied == key {
+			return false, fmt.Sprintf("field %s is denied", key)
+		}
+	}
+	return true, ""
+}
+
+// aggregateOnly lists the tables among tables that may only be aggregated
+func (p *PolicySet) aggregateOnly(tables []string) []string {
+	if p == nil || p.AggregateOnly == nil {
+		return nil
+	}
+	var restricted []string
+	for _, table := range tables {
+		if containsString(p.AggregateOnly.Tables, table) && !containsString(restricted, table) {
+			restricted = append(restricted, table)
+		}
+	}
+	return restricted
+}
+
+// minGroupSize is the smallest group an aggregate-only query may report
+func (p *PolicySet) minGroupSize() int {
+	if p == nil || p.AggregateOnly == nil || p.AggregateOnly.MinGroupSize == 0 {
+		return defaultMinGroupSize
+	}
+	return p.AggregateOnly.MinGroupSize
+}
+
+// minGroupSizeWarning tells the caller that small groups are suppressed
+func minGroupSizeWarning(tables []string, size int) string {
+	return fmt.Sprintf("%s may only be aggregated: groups with fewer than %d rows are left out",
+		strings.Join(tables, ", "), size)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Source systems with their own physical names in the mappings
+const (
+	SystemA = "system_a"
+	SystemB = "system_b"
+)
+
+// GenerateQueryPair generates a description's query for system A and for
+// system B, with a table mapping each field the queries read to its name
+// in both. Curated query templates are skipped, being written for one
+// schema. Fields a system has no name for keep their mapped name, with a
+// warning, so reconciliation doesn't compare a column with itself unawares
+func (s *QueryService) GenerateQueryPair(request models.QueryRequest) (models.QueryPairResponse, error) {
+	if request.TraceID == "" {
+		request.TraceID = NewTraceID()
+	}
+	request.SkipTemplates = true
+
+	request.System = SystemA
+	systemA, err := s.GenerateQuery(request)
+	if err != nil {
+		return models.QueryPairResponse{}, err
+	}
+	request.System = SystemB
+	systemB, err := s.GenerateQuery(request)
+	if err != nil {
+		return models.QueryPairResponse{}, err
+	}
+
+	namesA, namesB := s.fieldService.systemNames(SystemA), s.fieldService.systemNames(SystemB)
+	columnMap := make([]models.ColumnMapping, 0)
+	var warnings []string
+	seen := make(map[string]bool)
+	for _, ref := range planFields(systemA.Plan) {
+		key := fieldKey(ref.Table, ref.Column)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		field, _ := s.fieldService.LookupField(ref.Table, ref.Column)
+		mapping := models.ColumnMapping{
+			Table:       ref.Table,
+			Column:      ref.Column,
+			Description: field.Description,
+			SystemA:     namesA.table(ref.Table) + "." + namesA.column(ref.Table, ref.Column),
+			SystemB:     namesB.table(ref.Table) + "." + namesB.column(ref.Table, ref.Column),
+		}
+		if field.SystemAFieldMap == "" {
+			warnings = append(warnings, fmt.Sprintf("%s has no %s name; the query uses its mapped name", key, SystemA))
+		}
+		if field.SystemBFieldMap == "" {
+			warnings = append(warnings, fmt.Sprintf("%s has no %s name; the query uses its mapped name", key, SystemB))
+		}
+		columnMap = append(columnMap, mapping)
+	}
+
+	return models.QueryPairResponse{
+		TraceID:   request.TraceID,
+		SystemA:   systemA,
+		SystemB:   systemB,
+		ColumnMap: columnMap,
+		Warnings:  warnings,
+	}, nil
+}
+
+// planFields lists the mapped fields a plan selects and filters on, in
+// plan order
+func planFields(plan *models.QueryPlan) []models.FieldRef {
+	if plan == nil {
+		return nil
+	}
+	var refs []models.FieldRef
+	for _, column := range plan.Select {
+		if column.Table != "" {
+			refs = append(refs, models.FieldRef{Table: column.Table, Column: column.Column})
+		}
+	}
+	for _, filter := range plan.Filters {
+		if filter.Column != "" {
+			refs = append(refs, models.FieldRef{Table: filter.Table, Column: filter.Column})
+		}
+	}
+	return refs
+}
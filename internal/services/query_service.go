@@ -2,79 +2,662 @@ package services
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/mgarce/go_query_api/internal/config"
 	"github.com/mgarce/go_query_api/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
+// Query types generated from a description
+const (
+	QueryTypeSelect = "SELECT"
+	QueryTypeCount  = "COUNT"
+	QueryTypeGroup  = "GROUP"
+)
+
+// Whether a response's query type came from intent keywords or the default
+const (
+	QueryTypeInferred  = "inferred"
+	QueryTypeDefaulted = "default"
+)
+
+// Matching limits used when the configuration leaves them unset
+const (
+	defaultMatchThreshold = 30.0
+	defaultMaxMatches     = 10
+)
+
 // QueryService handles SQL query generation
 type QueryService struct {
 	fieldService *FieldService
+	cfg          *config.Config
 	log          *logrus.Logger
+
+	// Prior schema versions for time-travel queries; nil disables them
+	versions *SchemaVersions
+
+	// Saved description templates
+	templates *TemplateStore
+
+	// Access policies; aggregate-only tables apply to every query
+	policies *PolicySet
+
+	// Match quality tracking for drift alerts; nil disables it
+	drift *DriftMonitor
+
+	// Usage events for analytics; nil disables them
+	events *EventStream
+
+	// Systems whose tables are never joined to each other
+	boundaries JoinBoundaries
+
+	// Keyword case, comma placement, and AS usage of generated SQL
+	style SQLStyle
 }
 
 // NewQueryService creates a new query service
 func NewQueryService(fieldService *FieldService) *QueryService {
 	log := logrus.New()
 	log.SetFormatter(&logrus.JSONFormatter{})
-	
+
+	// Boundaries are validated when routes are set up
+	boundaries, _ := ParseJoinBoundaries(fieldService.cfg.JoinBoundaries)
+	style, _ := ParseSQLStyle(fieldService.cfg.KeywordCase, fieldService.cfg.LeadingCommas, fieldService.cfg.AliasKeyword)
+
 	return &QueryService{
 		fieldService: fieldService,
+		cfg:          fieldService.cfg,
 		log:          log,
+		boundaries:   boundaries,
+		style:        style,
 	}
 }
 
+// UseSchemaVersions enables generating against prior schema versions
+func (s *QueryService) UseSchemaVersions(versions *SchemaVersions) {
+	s.versions = versions
+}
+
+// UseTemplates enables saved description templates
+func (s *QueryService) UseTemplates(templates *TemplateStore) {
+	s.templates = templates
+}
+
+// UsePolicies enforces the aggregate-only tables of an access policy set
+func (s *QueryService) UsePolicies(policies *PolicySet) {
+	s.policies = policies
+}
+
+// UseDriftMonitor reports the outcome of every generation to a drift monitor
+func (s *QueryService) UseDriftMonitor(drift *DriftMonitor) {
+	s.drift = drift
+}
+
+// UseEventStream publishes an analytics event for every generation
+func (s *QueryService) UseEventStream(events *EventStream) {
+	s.events = events
+}
+
+// withSchema returns a copy of the service generating against another
+// version of the schema, with the same templates, policies, monitoring,
+// boundaries, and style
+func (s *QueryService) withSchema(fieldService *FieldService) *QueryService {
+	clone := *s
+	clone.fieldService = fieldService
+	clone.cfg = fieldService.cfg
+	return &clone
+}
+
+// DbtTarget returns how dbt models read the catalog's tables: through the
+// refs the mappings record, or else from the configured dbt source
+func (s *QueryService) DbtTarget() DbtTarget {
+	return DbtTarget{Source: s.cfg.DbtSource, Refs: s.fieldService.DbtRefs()}
+}
+
 // GenerateQuery generates an SQL query based on the natural language description
 func (s *QueryService) GenerateQuery(request models.QueryRequest) (models.QueryResponse, error) {
+	// Expand templates and variables before anything parses the description
+	var expanded string
+	if request.Template != "" || len(request.Variables) > 0 {
+		description, err := s.templates.Expand(request.Template, request.Description, request.Variables)
+		if err != nil {
+			return models.QueryResponse{}, err
+		}
+		request.Description, expanded = description, description
+		request.Template, request.Variables = "", nil
+	}
+
+	// Generate against an older mapping when a prior schema version is requested
+	if request.SchemaVersion != "" && request.SchemaVersion != s.fieldService.Version() {
+		if s.versions == nil {
+			return models.QueryResponse{}, fmt.Errorf("%w: %s", ErrUnknownSchemaVersion, request.SchemaVersion)
+		}
+		historical, err := s.versions.Get(request.SchemaVersion)
+		if err != nil {
+			return models.QueryResponse{}, err
+		}
+		response, err := s.withSchema(historical).GenerateQuery(request)
+		response.ExpandedDescription = expanded
+		return response, err
+	}
+
 	startTime := time.Now()
-	
+	budget := s.newRequestBudget(startTime, request.BudgetMs)
+
+	// Stamp every log line for this generation with its trace ID
+	if request.TraceID == "" {
+		request.TraceID = NewTraceID()
+	}
+	log := s.log.WithField("trace_id", request.TraceID)
+
+	// Reject pathologically long descriptions outright
+	if err := s.checkDescriptionLength(request.Description); err != nil {
+		log.WithError(err).Warn("Rejected description")
+		return models.QueryResponse{}, err
+	}
+
+	// Requests to change data would otherwise come back as a confusing SELECT
+	if err := checkWriteIntent(request.Description); err != nil {
+		log.WithError(err).Warn("Refused write request")
+		return models.QueryResponse{}, err
+	}
+
+	// Curated query templates answer the questions they were written for
+	// outright, when the caller may read what they read; everything else
+	// falls through to generation
+	if match := s.fieldService.queryTemplates.Match(request.Description); match != nil && !request.SkipTemplates {
+		response, refusal, err := s.templateResponse(request, match, expanded, startTime)
+		if err != nil || refusal == "" {
+			log.WithField("template", match.Template.Name).Info("Answered with a query template")
+			return response, err
+		}
+		log.WithField("template", match.Template.Name).Infof("Query template skipped: %s", refusal)
+	}
+
+	// Swap saved cohort names for their governed predicates, so they don't
+	// also steer field matching
+	description, cohorts := s.fieldService.cohorts.Expand(request.Description)
+
+	// Phrases like "including users without orders" ask for a LEFT JOIN
+	description, optionalJoins := s.fieldService.optionalJoins(description)
+
 	// Parse description for keywords
-	keywords := s.extractKeywords(request.Description)
-	
+	keywords := s.extractKeywords(description, log)
+
+	// Keep matching time bounded for long descriptions by summarizing keywords
+	var warnings []string
+	keywords, summarized := s.summarizeKeywords(keywords, log)
+	if summarized != "" {
+		warnings = append(warnings, summarized)
+	}
+
 	// Identify query type and intent
-	queryType, distinct := s.identifyQueryType(request.Description)
-	
-	// Find matching fields
-	matchedFields := s.fieldService.FindFieldMatches(keywords, 30.0, 10)
-	
+	queryType, distinct, inferred := s.identifyQueryType(request.Description)
+	queryTypeSource := QueryTypeDefaulted
+	if inferred {
+		queryTypeSource = QueryTypeInferred
+	}
+
+	// Find matching fields, restricted to the requested tables and tags and
+	// skipping deprecated and sensitive fields unless asked for
+	filter := requestFilter(request)
+	if err := filter.checkTables(s.fieldService); err != nil {
+		log.WithError(err).Warn("Unknown table hint")
+		return models.QueryResponse{}, err
+	}
+	threshold, maxMatches := s.matchLimits(request.MatchThreshold, request.MaxMatches)
+	if err := s.fieldService.chaos.Inject(ChaosStageMatching); err != nil {
+		log.WithError(err).Warn("Matching failed")
+		return models.QueryResponse{}, err
+	}
+	matchedFields, truncated := s.fieldService.findFieldMatches(keywords, threshold, maxMatches, filter, budget.matchingDeadline(), log)
+	if truncated {
+		budget.cut("field matching ran out of time; matches are the best found before the budget ran out")
+		log.Warn("Field matching stopped at the time budget")
+	}
+
 	if len(matchedFields) == 0 {
+		log.Warn("No matching fields found")
+		s.drift.Observe(s.fieldService.Version(), 0, false)
+		s.events.Emit(models.GenerationEvent{
+			TraceID:         request.TraceID,
+			Timestamp:       startTime,
+			SchemaVersion:   s.fieldService.Version(),
+			DescriptionHash: descriptionHash(request.Description),
+			Outcome:         EventOutcomeNoMatch,
+			Tables:          []string{},
+			LatencyMs:       time.Since(startTime).Milliseconds(),
+		})
+		if truncated {
+			return models.QueryResponse{}, fmt.Errorf("%w: no field matched before matching ran out of time", ErrBudgetExceeded)
+		}
 		return models.QueryResponse{}, fmt.Errorf("no matching fields found for description")
 	}
-	
-	// Generate SQL query
-	query, joins, err := s.buildSQLQuery(matchedFields, queryType, distinct, request.Limit)
+
+	// Leave out columns classified above the caller's clearance, explaining
+	// each, and only fail when nothing is left to query
+	clearance := s.clearance(request.Clearance)
+	matchedFields, withheld, withheldWarnings := withholdMatches(matchedFields, clearance)
+	warnings = append(warnings, withheldWarnings...)
+	if len(matchedFields) == 0 {
+		log.WithField("withheld", withheld).Warn("Every matching field was withheld")
+		return models.QueryResponse{}, fmt.Errorf("%w: every matching field is classified above %s", ErrInsufficientClearance, clearance)
+	}
+	for _, match := range matchedFields {
+		if match.Deprecated {
+			warnings = append(warnings, fmt.Sprintf(
+				"field %s.%s is deprecated", match.TableName, match.ColumnName))
+		}
+	}
+
+	aliases, err := s.queryAliases(request.AliasStyle, request.System)
+	if err != nil {
+		return models.QueryResponse{}, err
+	}
+
+	dialect, err := s.queryDialect(request.Dialect)
+	if err != nil {
+		return models.QueryResponse{}, err
+	}
+
+	// Join hints in the request win over ones read from the description
+	joinHints := request.Joins
+	if len(joinHints) == 0 {
+		joinHints = optionalJoins
+	}
+
+	// Noisy matches are trimmed to the column limit, best scores first, when
+	// trimming is configured
+	limits := s.complexityLimits(request.MaxJoinHops, request.MaxColumns, request.MaxTables)
+	trim := s.cfg.ComplexityAction == ComplexityActionTrim
+	if trim && limits.columns > 0 && len(matchedFields) > limits.columns {
+		warnings = append(warnings, fmt.Sprintf(
+			"kept the %d best-scoring of %d matched fields, the column limit", limits.columns, len(matchedFields)))
+		matchedFields = matchedFields[:limits.columns]
+	}
+
+	// Generate SQL query, leaving out the lowest-scoring table until the
+	// query is within the complexity limits
+	plan, joins, err := s.buildSQLQuery(matchedFields, queryType, distinct, request.Limit, request.Offset, dialect, aliases, cohorts, joinHints, budget)
+	for err == nil {
+		exceeded := limits.exceeded(plan, joins)
+		if exceeded == "" {
+			break
+		}
+		table := lowestScoringTable(matchedFields)
+		if !trim || table == "" {
+			log.WithField("exceeded", exceeded).Warn("Query is too complex")
+			return models.QueryResponse{}, fmt.Errorf("%w: %s", ErrQueryTooComplex, exceeded)
+		}
+		warnings = append(warnings, fmt.Sprintf("left out the fields of table %s: %s", table, exceeded))
+		matchedFields = withoutTable(matchedFields, table)
+		if aliases, err = s.queryAliases(request.AliasStyle, request.System); err != nil {
+			return models.QueryResponse{}, err
+		}
+		plan, joins, err = s.buildSQLQuery(matchedFields, queryType, distinct, request.Limit, request.Offset, dialect, aliases, cohorts, joinHints, budget)
+	}
 	if err != nil {
+		log.WithError(err).Warn("Failed to build SQL query")
 		return models.QueryResponse{}, fmt.Errorf("failed to build SQL query: %w", err)
 	}
-	
+	if note := s.enforceRowLimit(&plan); note != "" {
+		warnings = append(warnings, note)
+	}
+	assignPlanIDs(&plan)
+	rendered := renderPlan(dialect, plan)
+	query := formatSQL(rendered, dialect, request.Format, s.style)
+
+	// Measures and cohort predicates are SQL from the mappings, so nothing
+	// leaves without passing the read-only check
+	if err := checkReadOnly(query, dialect); err != nil {
+		log.WithError(err).Error("Generated SQL failed the read-only check")
+		return models.QueryResponse{}, err
+	}
+
+	// Joins that repeat or drop rows change what counts and sums mean
+	warnings = append(warnings, joinWarnings(joins)...)
+	warnings = append(warnings, s.lintQuery(plan, joins, matchedFields)...)
+
+	// Fields on tables join planning had no time to reach are left out
+	matchedFields = budget.keep(matchedFields)
+	partial := budget != nil && budget.partial
+	if partial {
+		warnings = append(warnings, budget.notes...)
+	}
+	var sensitive []string
+	for _, match := range matchedFields {
+		if match.Sensitive {
+			sensitive = append(sensitive, match.TableName+"."+match.ColumnName)
+		}
+	}
+
 	// Calculate confidence score
 	confidence := s.calculateConfidence(matchedFields)
-	
+	s.drift.Observe(s.fieldService.Version(), confidence, true)
+
+	// Surface ownership and freshness of every table the query touches
+	tables := tablesUsed(matchedFields, joins)
+	if restricted := s.policies.aggregateOnly(tables); len(restricted) > 0 {
+		warnings = append(warnings, minGroupSizeWarning(restricted, s.policies.minGroupSize()))
+	}
+
+	// Middling confidence comes with other readings of the description,
+	// from runner-up fields and fewer tables
+	fingerprint := queryFingerprint(rendered, dialect)
+	var alternatives []models.AlternativeQuery
+	if count := s.alternativeCount(request.Alternatives, confidence); count > 0 && !partial {
+		candidates, _ := s.fieldService.findFieldMatches(keywords, threshold, maxMatches+count, filter, budget.matchingDeadline(), log)
+		candidates, _, _ = withholdMatches(candidates, clearance)
+		for _, set := range alternativeFieldSets(matchedFields, runnersUp(candidates, matchedFields)) {
+			if alternative, ok := s.alternativeQuery(request, set, queryType, distinct, dialect, cohorts, joinHints, limits); ok {
+				alternatives = append(alternatives, alternative)
+			}
+		}
+		alternatives = rankAlternatives(alternatives, fingerprint, count)
+	}
+
 	response := models.QueryResponse{
-		Query:          query,
-		MatchedFields:  matchedFields,
-		JoinsUsed:      joins,
-		Confidence:     confidence,
-		ProcessingTime: time.Since(startTime).Milliseconds(),
+		TraceID:             request.TraceID,
+		SchemaVersion:       s.fieldService.Version(),
+		ExpandedDescription: expanded,
+		Query:               query,
+		Fingerprint:         fingerprint,
+		Plan:                &plan,
+		QueryType:           queryType,
+		QueryTypeSource:     queryTypeSource,
+		MatchedFields:       matchedFields,
+		JoinsUsed:           joins,
+		JoinCost:            joinCost(joins),
+		Confidence:          confidence,
+		ProcessingTime:      time.Since(startTime).Milliseconds(),
+		Owners:              s.fieldService.GetTableOwners(tables),
+		Freshness:           s.fieldService.GetFreshnessNotes(tables),
+		Warnings:            warnings,
+		SensitiveColumns:    sensitive,
+		WithheldColumns:     withheld,
+		Cohorts:             cohorts,
+		Partial:             partial,
+		Alternatives:        alternatives,
 	}
-	
+
+	log.WithFields(logrus.Fields{
+		"query_type": queryType,
+		"confidence": confidence,
+		"tables":     tables,
+	}).Info("Generated query")
+	s.events.Emit(models.GenerationEvent{
+		TraceID:         request.TraceID,
+		Timestamp:       startTime,
+		SchemaVersion:   response.SchemaVersion,
+		DescriptionHash: descriptionHash(request.Description),
+		Outcome:         EventOutcomeGenerated,
+		QueryType:       queryType,
+		Tables:          tables,
+		Confidence:      confidence,
+		LatencyMs:       response.ProcessingTime,
+		Partial:         partial,
+		Fingerprint:     response.Fingerprint,
+	})
+
 	return response, nil
 }
 
+// matchLimits returns the match threshold and maximum number of matches,
+// preferring per-request overrides over the configured values
+func (s *QueryService) matchLimits(threshold *float64, maxMatches int) (float64, int) {
+	resolvedThreshold := s.cfg.MatchThreshold
+	if resolvedThreshold <= 0 {
+		resolvedThreshold = defaultMatchThreshold
+	}
+	if threshold != nil {
+		resolvedThreshold = *threshold
+	}
+
+	resolvedMax := s.cfg.MaxMatches
+	if resolvedMax <= 0 {
+		resolvedMax = defaultMaxMatches
+	}
+	if maxMatches > 0 {
+		resolvedMax = maxMatches
+	}
+	return resolvedThreshold, resolvedMax
+}
+
+// joinedTables lists the given tables plus every table a join passes through
+func joinedTables(tables []string, joins []models.Join) []string {
+	joined := append([]string{}, tables...)
+	for _, join := range joins {
+		joined = append(joined, join.From, join.To)
+	}
+	return joined
+}
+
+// tablesUsed lists every table referenced by the query, including
+// intermediate tables only reached through joins
+func tablesUsed(matches []models.FieldMatch, joins []models.Join) []string {
+	seen := make(map[string]bool)
+	tables := make([]string, 0)
+	add := func(table string) {
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+
+	for _, match := range matches {
+		add(match.TableName)
+	}
+	for _, join := range joins {
+		add(join.From)
+		add(join.To)
+	}
+	return tables
+}
+
+// SimulatePolicy reports how a role's access policy would affect generation
+// for a description, without enforcing anything. Fields are matched and
+// screened as generation screens them, with the request's filters and the
+// caller's clearance, and the query is planned with the same cohorts, join
+// hints, and aggregate-only tables, so the simulation and generation agree
+func (s *QueryService) SimulatePolicy(policies *PolicySet, request models.PolicySimulationRequest) (models.PolicySimulationResponse, error) {
+	policy, exists := policies.Role(request.Role)
+	if !exists {
+		return models.PolicySimulationResponse{}, fmt.Errorf("unknown role %q", request.Role)
+	}
+
+	log := s.log.WithField("role", request.Role)
+	response := models.PolicySimulationResponse{Role: request.Role, Decisions: make([]models.PolicyDecision, 0)}
+	if err := checkWriteIntent(request.Description); err != nil {
+		response.Reason = err.Error()
+		return response, nil
+	}
+	generation := models.QueryRequest{
+		Description:       request.Description,
+		Tables:            request.Tables,
+		ExcludeTables:     request.ExcludeTables,
+		IncludeTags:       request.IncludeTags,
+		ExcludeTags:       request.ExcludeTags,
+		IncludeDeprecated: request.IncludeDeprecated,
+		AllowSensitive:    request.AllowSensitive,
+		Clearance:         request.Clearance,
+	}
+	filter := requestFilter(generation)
+	if err := filter.checkTables(s.fieldService); err != nil {
+		return models.PolicySimulationResponse{}, err
+	}
+
+	// A template the role may read answers outright, as it would generation
+	if match := s.fieldService.queryTemplates.Match(request.Description); match != nil {
+		simulated, answered, err := s.simulateTemplate(policy, generation, match, response)
+		if err != nil || answered {
+			return simulated, err
+		}
+	}
+
+	description, cohorts := s.fieldService.cohorts.Expand(request.Description)
+	description, joinHints := s.fieldService.optionalJoins(description)
+	keywords, _ := s.summarizeKeywords(s.extractKeywords(description, log), log)
+	queryType, distinct, _ := s.identifyQueryType(request.Description)
+	threshold, maxMatches := s.matchLimits(nil, 0)
+	matchedFields := s.fieldService.FindFilteredFieldMatches(keywords, threshold, maxMatches, filter, log)
+
+	// Judge every matched field against the caller's clearance, then the policy
+	clearance := s.clearance(request.Clearance)
+	var allowedFields []models.FieldMatch
+	for _, match := range matchedFields {
+		allowed, reason := policy.AllowsField(match.TableName, match.ColumnName)
+		if level := classificationLevel(match.Classification); !cleared(clearance, level) {
+			allowed, reason = false, withheldWarning(match.TableName, match.ColumnName, level, clearance)
+		}
+		response.Decisions = append(response.Decisions, models.PolicyDecision{
+			ColumnName: match.ColumnName,
+			TableName:  match.TableName,
+			MatchScore: match.MatchScore,
+			Allowed:    allowed,
+			Reason:     reason,
+		})
+		if allowed {
+			allowedFields = append(allowedFields, match)
+		}
+	}
+
+	switch {
+	case len(matchedFields) == 0:
+		response.Reason = "no matching fields found for description"
+		return response, nil
+	case len(allowedFields) == 0:
+		response.Reason = "every matched field is blocked by policy or clearance"
+		return response, nil
+	}
+
+	// Build with the permitted fields only; join paths must avoid blocked
+	// tables too, and aggregate-only tables are enforced while planning
+	aliases, err := s.queryAliases("", "")
+	if err != nil {
+		return models.PolicySimulationResponse{}, err
+	}
+	dialect, err := s.queryDialect("")
+	if err != nil {
+		return models.PolicySimulationResponse{}, err
+	}
+	plan, joins, err := s.buildSQLQuery(allowedFields, queryType, distinct, 0, 0, dialect, aliases, cohorts, joinHints, nil)
+	if err != nil {
+		response.Reason = err.Error()
+		return response, nil
+	}
+	for _, table := range tablesUsed(allowedFields, joins) {
+		if allowed, reason := policy.AllowsTable(table); !allowed {
+			response.Reason = "join path requires a blocked table: " + reason
+			return response, nil
+		}
+	}
+
+	s.enforceRowLimit(&plan)
+	query := renderPlan(dialect, plan)
+	if err := checkReadOnly(query, dialect); err != nil {
+		response.Reason = err.Error()
+		return response, nil
+	}
+	response.WouldSucceed = true
+	response.Query = query
+	return response, nil
+}
+
+// simulateTemplate judges the fields of the template a description matched
+// against a role's policy. It reports false when the template wouldn't
+// answer the request, which then falls through to generation
+func (s *QueryService) simulateTemplate(policy RolePolicy, request models.QueryRequest, match *TemplateMatch, response models.PolicySimulationResponse) (models.PolicySimulationResponse, bool, error) {
+	if s.templateRefusal(match.Template, requestFilter(request), s.clearance(request.Clearance)) != "" {
+		return response, false, nil
+	}
+	for _, ref := range match.Template.refs {
+		allowed, reason := policy.AllowsField(ref.Table, ref.Column)
+		if !allowed {
+			return response, false, nil
+		}
+		response.Decisions = append(response.Decisions, models.PolicyDecision{
+			ColumnName: ref.Column,
+			TableName:  ref.Table,
+			MatchScore: 100,
+			Allowed:    allowed,
+			Reason:     reason,
+		})
+	}
+
+	dialect, err := s.queryDialect("")
+	if err != nil {
+		return response, false, err
+	}
+	query, _, _, refusal, err := s.renderTemplate(request, match, dialect)
+	if err != nil {
+		response.Reason = err.Error()
+		return response, true, nil
+	}
+	if refusal != "" {
+		return response, false, nil
+	}
+	response.WouldSucceed = true
+	response.Query = query
+	return response, true, nil
+}
+
+// requestFilter is the field filter of a generation request: its tables
+// and tags, skipping deprecated and sensitive fields unless asked for
+func requestFilter(request models.QueryRequest) FieldFilter {
+	return FieldFilter{
+		Tables:            request.Tables,
+		ExcludeTables:     request.ExcludeTables,
+		IncludeTags:       request.IncludeTags,
+		ExcludeTags:       request.ExcludeTags,
+		IncludeDeprecated: request.IncludeDeprecated,
+		AllowSensitive:    request.AllowSensitive,
+	}
+}
+
+// summarizeKeywords keeps matching time bounded for long descriptions by
+// cutting their keywords to the configured limit, explaining the cut
+func (s *QueryService) summarizeKeywords(keywords []string, log *logrus.Entry) ([]string, string) {
+	if len(keywords) <= s.maxKeywords() {
+		return keywords, ""
+	}
+	original := len(keywords)
+	keywords = summarizeKeywords(keywords, s.maxKeywords(), s.fieldService.IsKnownTerm)
+	log.Warnf("Summarized %d keywords to %d", original, len(keywords))
+	return keywords, fmt.Sprintf("description was summarized: matched on %d of %d keywords", len(keywords), original)
+}
+
+// RecordFeedback records whether the fields in a generated query were accepted
+// for the description that produced them
+func (s *QueryService) RecordFeedback(request models.FeedbackRequest) error {
+	log := s.log.WithField("trace_id", request.TraceID)
+	keywords := s.extractKeywords(request.Description, log)
+	if len(keywords) == 0 {
+		return fmt.Errorf("%w: description has no keywords", ErrInvalidFeedback)
+	}
+
+	if err := s.fieldService.RecordFeedback(keywords, request.Fields, *request.Accepted); err != nil {
+		return fmt.Errorf("failed to record feedback: %w", err)
+	}
+
+	log.WithField("accepted", *request.Accepted).Info("Recorded feedback")
+	return nil
+}
+
 // extractKeywords extracts relevant keywords from the description
-func (s *QueryService) extractKeywords(description string) []string {
+func (s *QueryService) extractKeywords(description string, log *logrus.Entry) []string {
 	// Remove special characters and convert to lowercase
 	sanitized := strings.ToLower(description)
 	re := regexp.MustCompile(`[^\w\s]`)
 	sanitized = re.ReplaceAllString(sanitized, " ")
-	
+
 	// Split into words
 	words := strings.Fields(sanitized)
-	
+
 	// Filter out common stopwords
 	stopwords := map[string]bool{
 		"a": true, "an": true, "the": true, "and": true, "or": true,
@@ -93,171 +676,354 @@ func (s *QueryService) extractKeywords(description string) []string {
 		"they": true, "them": true, "their": true, "theirs": true, "themselves": true,
 		"what": true, "which": true, "who": true, "whom": true, "whose": true,
 	}
-	
+
 	var keywords []string
 	for _, word := range words {
 		if !stopwords[word] && len(word) > 1 {
 			keywords = append(keywords, word)
 		}
 	}
-	
-	s.log.Infof("Extracted keywords: %v", keywords)
+
+	log.Infof("Extracted keywords: %v", keywords)
 	return keywords
 }
 
 // identifyQueryType identifies the type of query to generate
-func (s *QueryService) identifyQueryType(description string) (string, bool) {
+func (s *QueryService) identifyQueryType(description string) (string, bool, bool) {
 	desc := strings.ToLower(description)
-	
+
 	// Check for COUNT operations
-	if strings.Contains(desc, "count") || 
-	   strings.Contains(desc, "how many") || 
-	   strings.Contains(desc, "number of") {
-		return "COUNT", false
+	if strings.Contains(desc, "count") ||
+		strings.Contains(desc, "how many") ||
+		strings.Contains(desc, "number of") {
+		return QueryTypeCount, false, true
 	}
-	
+
 	// Check for GROUP BY operations
-	if strings.Contains(desc, "group") || 
-	   strings.Contains(desc, "grouped") || 
-	   strings.Contains(desc, "per") {
-		return "GROUP", false
+	if strings.Contains(desc, "group") ||
+		strings.Contains(desc, "grouped") ||
+		strings.Contains(desc, "per") {
+		return QueryTypeGroup, false, true
 	}
-	
+
 	// Check for DISTINCT
-	distinct := strings.Contains(desc, "distinct") || 
-	           strings.Contains(desc, "unique") ||
-	           strings.Contains(desc, "different")
-	
-	// Default to SELECT
-	return "SELECT", distinct
+	distinct := strings.Contains(desc, "distinct") ||
+		strings.Contains(desc, "unique") ||
+		strings.Contains(desc, "different")
+	if distinct {
+		return QueryTypeSelect, true, true
+	}
+
+	// Fall back to the configured default
+	return s.defaultQueryType(), false, false
 }
 
-// buildSQLQuery builds an SQL query based on matched fields
-func (s *QueryService) buildSQLQuery(matches []models.FieldMatch, queryType string, distinct bool, limit int) (string, []models.Join, error) {
+// defaultQueryType is the query type used when no intent keywords are found
+func (s *QueryService) defaultQueryType() string {
+	if s.cfg.DefaultQueryType == "" {
+		return QueryTypeSelect
+	}
+	return strings.ToUpper(s.cfg.DefaultQueryType)
+}
+
+// clearance resolves a caller's clearance, falling back to the configured
+// default. Callers are validated upstream; anything unparseable is treated
+// as the lowest clearance
+func (s *QueryService) clearance(requested string) string {
+	if requested == "" {
+		requested = s.cfg.DefaultClearance
+	}
+	level, err := ParseClearance(requested)
+	if err != nil {
+		return ClassificationPublic
+	}
+	return level
+}
+
+// ValidateQueryType checks a configured default query type; empty means SELECT
+func ValidateQueryType(queryType string) error {
+	switch strings.ToUpper(queryType) {
+	case "", QueryTypeSelect, QueryTypeCount, QueryTypeGroup:
+		return nil
+	default:
+		return fmt.Errorf("unknown default query type %q: use SELECT, COUNT, or GROUP", queryType)
+	}
+}
+
+// queryAliases creates the alias allocator of a query, in the requested
+// alias style or the configured default, naming tables and columns as the
+// requested system does
+func (s *QueryService) queryAliases(style, system string) (*aliasAllocator, error) {
+	if style == "" {
+		style = s.cfg.AliasStyle
+	}
+	aliases, err := newAliasAllocator(style, s.fieldService.TableAliases(), s.fieldService.tableLocations)
+	if err != nil {
+		return nil, err
+	}
+	aliases.names = s.fieldService.systemNames(system)
+	return aliases, nil
+}
+
+// buildSQLQuery plans an SQL query based on matched fields, limited and
+// offset by the given rows
+func (s *QueryService) buildSQLQuery(matches []models.FieldMatch, queryType string, distinct bool, limit, offset int, dialect string, aliases *aliasAllocator, cohorts []models.CohortExpansion, hints []models.JoinHint, budget *requestBudget) (models.QueryPlan, []models.Join, error) {
 	if len(matches) == 0 {
-		return "", nil, fmt.Errorf("no field matches provided")
+		return models.QueryPlan{}, nil, fmt.Errorf("no field matches provided")
 	}
-	
-	// Collect required tables
+
+	// Collect required tables in match order so aliases are stable
 	tables := make(map[string]bool)
+	tableNames := make([]string, 0)
 	for _, match := range matches {
-		tables[match.TableName] = true
+		if !tables[match.TableName] {
+			tables[match.TableName] = true
+			tableNames = append(tableNames, match.TableName)
+		}
 	}
-	tableNames := make([]string, 0, len(tables))
-	for table := range tables {
-		tableNames = append(tableNames, table)
+
+	// Cohort predicates may filter tables no matched field comes from
+	for _, cohort := range cohorts {
+		if !tables[cohort.Table] {
+			tables[cohort.Table] = true
+			tableNames = append(tableNames, cohort.Table)
+		}
 	}
-	
+
 	// Find join paths between tables
+	tableNames, allJoins, err := s.planJoins(tableNames, hints, budget)
+	if err != nil {
+		return models.QueryPlan{}, nil, err
+	}
+
+	aliases.assign(tableNames[0], allJoins)
+
+	// A cohort filter can't be dropped without changing what the query means
+	for _, cohort := range cohorts {
+		if budget != nil && budget.dropped[cohort.Table] {
+			return models.QueryPlan{}, nil, fmt.Errorf("%w: no join to cohort table %s was planned in time", ErrBudgetExceeded, cohort.Table)
+		}
+	}
+	matches = budget.keep(matches)
+	if len(matches) == 0 {
+		return models.QueryPlan{}, nil, fmt.Errorf("%w: no join to any matched table was planned in time", ErrBudgetExceeded)
+	}
+
+	// Measures carry their own aggregate SQL; everything else is a column
+	var dimensions, measures []models.FieldMatch
+	for _, match := range matches {
+		if match.Measure != "" {
+			measures = append(measures, match)
+		} else {
+			dimensions = append(dimensions, match)
+		}
+	}
+
+	// Aggregate-only tables, even ones only joined through, may never be read
+	// row by row, and every group they feed must meet the minimum size
+	havingClause := ""
+	if restricted := s.policies.aggregateOnly(joinedTables(tableNames, allJoins)); len(restricted) > 0 {
+		if queryType != QueryTypeCount && queryType != QueryTypeGroup && len(measures) == 0 {
+			return models.QueryPlan{}, nil, fmt.Errorf("%w: %s may only appear in count or group-by queries",
+				ErrAggregateOnly, strings.Join(restricted, ", "))
+		}
+		havingClause = fmt.Sprintf("COUNT(*) >= %d", s.policies.minGroupSize())
+	}
+
+	// Build the SELECT list
+	plan := models.QueryPlan{Limit: limit, Offset: offset}
+	dimension := func(match models.FieldMatch) models.PlanColumn {
+		return models.PlanColumn{
+			Expression: aliases.column(dialect, match.TableName, match.ColumnName),
+			Table:      match.TableName,
+			Column:     match.ColumnName,
+		}
+	}
+
+	switch {
+	case len(dimensions) == 0:
+		// Measures alone aggregate over every row
+		plan.Select = measureColumns(dialect, measures, aliases, tableNames)
+
+	case queryType == "COUNT":
+		// For COUNT queries, select the count of the first field
+		count := dimension(dimensions[0])
+		count.Expression = fmt.Sprintf("COUNT(%s)", count.Expression)
+		count.Aggregate = "COUNT"
+		plan.Select = []models.PlanColumn{count}
+
+	case queryType == "GROUP":
+		// For GROUP BY queries, select the group field with its measures,
+		// or its count when no measure was asked for
+		group := dimension(dimensions[0])
+		plan.GroupBy = []string{group.Expression}
+		plan.Select = []models.PlanColumn{group}
+		if len(measures) > 0 {
+			plan.Select = append(plan.Select, measureColumns(dialect, measures, aliases, tableNames)...)
+		} else {
+			plan.Select = append(plan.Select, models.PlanColumn{Expression: "COUNT(*)", Aggregate: "COUNT"})
+		}
+
+	default: // SELECT
+		// For regular SELECT queries, select all matched fields, grouping
+		// the columns when measures are selected alongside them
+		for _, match := range dimensions {
+			plan.Select = append(plan.Select, dimension(match))
+		}
+		plan.Distinct = distinct
+		if len(measures) > 0 {
+			for _, column := range plan.Select {
+				plan.GroupBy = append(plan.GroupBy, column.Expression)
+			}
+			plan.Select = append(plan.Select, measureColumns(dialect, measures, aliases, tableNames)...)
+		}
+	}
+
+	// FROM table with its alias, and the joins
+	plan.From = aliases.tablePlan(dialect, tableNames[0])
+	plan.Joins = joinPlans(dialect, tableNames[0], allJoins, aliases)
+
+	// WHERE conditions from the saved cohorts the description named
+	for _, cohort := range cohorts {
+		plan.Filters = append(plan.Filters, models.PlanFilter{
+			Expression: "(" + aliases.qualifyPredicate(dialect, cohort.Predicate, tableNames) + ")",
+			Table:      cohort.Table,
+			Cohort:     cohort.Name,
+		})
+	}
+	plan.Having = havingClause
+
+	return plan, allJoins, nil
+}
+
+// measureColumns selects each measure's aggregate SQL under its name, with
+// the table names it qualifies columns by rewritten to aliases
+func measureColumns(dialect string, measures []models.FieldMatch, aliases *aliasAllocator, tables []string) []models.PlanColumn {
+	columns := make([]models.PlanColumn, 0, len(measures))
+	for _, measure := range measures {
+		columns = append(columns, models.PlanColumn{
+			Expression: aliases.qualifyPredicate(dialect, measure.Measure, tables),
+			Table:      measure.TableName,
+			Column:     measure.ColumnName,
+			Aggregate:  planMeasure,
+			Alias:      measure.ColumnName,
+		})
+	}
+	return columns
+}
+
+// planJoins finds the join path from the first table to each of the others.
+// Join hints come first and make their first source table the root; the
+// planner only fills in tables they don't reach. Without hints, a table
+// reaching the others through many-to-one joins becomes the root. The tables
+// are returned root first
+func (s *QueryService) planJoins(tableNames []string, hints []models.JoinHint, budget *requestBudget) ([]string, []models.Join, error) {
+	if err := s.fieldService.chaos.Inject(ChaosStageJoinPlanning); err != nil {
+		return nil, nil, err
+	}
+
+	// Refuse tables of systems that are never joined before looking for a path
+	if err := s.boundaries.check("the matched fields belong to", tableNames); err != nil {
+		return nil, nil, err
+	}
+
 	var allJoins []models.Join
+	reached := make(map[string]bool)
+	if len(hints) > 0 {
+		hinted, err := s.fieldService.hintedJoins(hints)
+		if err != nil {
+			return nil, nil, err
+		}
+		root := hints[0].From
+		reordered := []string{root}
+		for _, table := range tableNames {
+			if table != root {
+				reordered = append(reordered, table)
+			}
+		}
+		tableNames, allJoins = reordered, hinted
+		for _, join := range hinted {
+			reached[join.From], reached[join.To] = true, true
+		}
+	} else if len(tableNames) > 1 {
+		tableNames = s.fieldService.chooseJoinRoot(tableNames)
+	}
+
 	if len(tableNames) > 1 {
-		// Start with the first table and find paths to all others
+		// Start with the first table and find paths to all others. Once the
+		// budget runs out, tables no planned path passes through are dropped
+		planned := map[string]bool{tableNames[0]: true}
+		var dropped []string
 		for i := 1; i < len(tableNames); i++ {
+			if reached[tableNames[i]] {
+				continue
+			}
+			if budget.joinsExpired() {
+				if !planned[tableNames[i]] {
+					dropped = append(dropped, tableNames[i])
+				}
+				continue
+			}
 			joins, err := s.fieldService.FindJoinPath(tableNames[0], tableNames[i])
 			if err != nil {
-				return "", nil, fmt.Errorf("failed to find join path: %w", err)
+				return nil, nil, fmt.Errorf("failed to find join path: %w", err)
 			}
 			allJoins = append(allJoins, joins...)
+			for _, join := range joins {
+				planned[join.From], planned[join.To] = true, true
+			}
 		}
-		
+
 		// Deduplicate joins
 		allJoins = deduplicateJoins(allJoins)
+
+		// A path may not pass through another system's tables either
+		if err := s.boundaries.check("the only join path passes through", tablesUsed(nil, allJoins)); err != nil {
+			return nil, nil, err
+		}
+
+		if len(dropped) > 0 {
+			for _, table := range dropped {
+				budget.drop(table)
+			}
+			kept := make([]string, 0, len(tableNames))
+			for _, table := range tableNames {
+				if !budget.dropped[table] {
+					kept = append(kept, table)
+				}
+			}
+			tableNames = kept
+			budget.cut(fmt.Sprintf("join planning ran out of time; left out %s", strings.Join(dropped, ", ")))
+		}
 	}
-	
-	// Build SELECT clause
-	var selectClause string
-	
-	switch queryType {
-	case "COUNT":
-		// For COUNT queries, select the count of the first field
-		selectClause = fmt.Sprintf("COUNT(%s.%s)", 
-			matches[0].TableName, 
-			matches[0].ColumnName)
-			
-	case "GROUP":
-		// For GROUP BY queries, select the count and group by field
-		selectClause = fmt.Sprintf("%s.%s, COUNT(*)", 
-			matches[0].TableName, 
-			matches[0].ColumnName)
-			
-	default: // SELECT
-		// For regular SELECT queries, select all matched fields
-		var fields []string
-		for _, match := range matches {
-			fields = append(fields, fmt.Sprintf("%s.%s", 
-				match.TableName, 
-				match.ColumnName))
-		}
-		
-		if distinct {
-			selectClause = "DISTINCT " + strings.Join(fields, ", ")
-		} else {
-			selectClause = strings.Join(fields, ", ")
-		}
-	}
-	
-	// Build FROM clause with table alias
-	fromClause := fmt.Sprintf("%s %s", tableNames[0], tableNames[0][0:1])
-	
-	// Build JOIN clauses
-	var joinClauses []string
-	tablesInJoin := map[string]bool{tableNames[0]: true}
-	
-	for _, join := range allJoins {
+	return tableNames, propagateLeftJoins(allJoins), nil
+}
+
+// joinPlans plans a JOIN for each table reached from root, with columns
+// qualified by alias and identifiers quoted for the dialect
+func joinPlans(dialect, root string, joins []models.Join, aliases *aliasAllocator) []models.PlanJoin {
+	var plans []models.PlanJoin
+	tablesInJoin := map[string]bool{root: true}
+
+	for _, join := range joins {
 		if tablesInJoin[join.To] {
 			continue // Skip tables already joined
 		}
-		
-		// Add table alias to the join condition
-		condition := join.Condition
-		
-		// Add the JOIN clause
-		joinClauses = append(joinClauses, 
-			fmt.Sprintf("JOIN %s %s ON %s", 
-				join.To, 
-				join.To[0:1], 
-				condition))
-		
+
+		// Add the JOIN
+		joinType := JoinTypeInner
+		if join.Type == JoinTypeLeft {
+			joinType = JoinTypeLeft
+		}
+		plans = append(plans, models.PlanJoin{
+			Type:      joinType,
+			Table:     aliases.tablePlan(dialect, join.To),
+			Condition: joinCondition(dialect, join, aliases),
+		})
+
 		tablesInJoin[join.To] = true
 	}
-	
-	// Build WHERE clause (empty for now, would be based on additional criteria)
-	whereClause := ""
-	
-	// Build GROUP BY clause
-	groupByClause := ""
-	if queryType == "GROUP" {
-		groupByClause = fmt.Sprintf("GROUP BY %s.%s", 
-			matches[0].TableName, 
-			matches[0].ColumnName)
-	}
-	
-	// Build LIMIT clause
-	limitClause := ""
-	if limit > 0 {
-		limitClause = fmt.Sprintf("LIMIT %d", limit)
-	}
-	
-	// Assemble the complete query
-	query := fmt.Sprintf("SELECT %s FROM %s", selectClause, fromClause)
-	
-	if len(joinClauses) > 0 {
-		query += " " + strings.Join(joinClauses, " ")
-	}
-	
-	if whereClause != "" {
-		query += " WHERE " + whereClause
-	}
-	
-	if groupByClause != "" {
-		query += " " + groupByClause
-	}
-	
-	if limitClause != "" {
-		query += " " + limitClause
-	}
-	
-	return query, allJoins, nil
+	return plans
 }
 
 // deduplicateJoins removes duplicate join conditions
@@ -265,18 +1031,18 @@ func deduplicateJoins(joins []models.Join) []models.Join {
 	if len(joins) <= 1 {
 		return joins
 	}
-	
-	uniqueJoins := make(map[string]models.Join)
+
+	// Keep the first occurrence of each condition, preserving path order
+	seen := make(map[string]bool)
+	result := make([]models.Join, 0, len(joins))
 	for _, join := range joins {
-		key := join.Condition
-		uniqueJoins[key] = join
-	}
-	
-	result := make([]models.Join, 0, len(uniqueJoins))
-	for _, join := range uniqueJoins {
+		if seen[join.Condition] {
+			continue
+		}
+		seen[join.Condition] = true
 		result = append(result, join)
 	}
-	
+
 	return result
 }
 
@@ -285,19 +1051,19 @@ func (s *QueryService) calculateConfidence(matches []models.FieldMatch) float64
 	if len(matches) == 0 {
 		return 0
 	}
-	
+
 	// Average the match scores of all fields
 	var total float64
 	for _, match := range matches {
 		total += match.MatchScore
 	}
-	
+
 	confidence := total / float64(len(matches))
-	
+
 	// Adjust confidence based on number of matched fields
 	// More matches = higher confidence, up to a point
 	fieldCountFactor := math.Min(float64(len(matches))/3.0, 1.0)
-	
+
 	return confidence * fieldCountFactor
 }
 
@@ -305,24 +1071,24 @@ func (s *QueryService) calculateConfidence(matches []models.FieldMatch) float64
 func (s *QueryService) EnhanceDescriptionWithFuzzy(keywords []string, fields []models.Field) []string {
 	var enhancedKeywords []string
 	enhancedKeywords = append(enhancedKeywords, keywords...)
-	
+
 	// Extract all words from field descriptions
 	var fieldWords []string
 	for _, field := range fields {
 		words := strings.Fields(strings.ToLower(field.Description))
 		fieldWords = append(fieldWords, words...)
 	}
-	
+
 	// Remove duplicates
 	uniqueFieldWords := make(map[string]bool)
 	for _, word := range fieldWords {
 		uniqueFieldWords[word] = true
 	}
-	
+
 	// For each keyword, find fuzzy matches
 	for _, keyword := range keywords {
 		matches := fuzzy.Find(keyword, stringMapToSlice(uniqueFieldWords))
-		
+
 		// Add top fuzzy matches to enhanced keywords
 		for i, match := range matches {
 			if i >= 3 { // Limit to top 3 fuzzy matches
@@ -331,7 +1097,7 @@ func (s *QueryService) EnhanceDescriptionWithFuzzy(keywords []string, fields []m
 			enhancedKeywords = append(enhancedKeywords, match)
 		}
 	}
-	
+
 	return enhancedKeywords
 }
 
@@ -343,15 +1109,3 @@ func stringMapToSlice(m map[string]bool) []string {
 	}
 	return result
 }
-
-// Import math for the confidence calculation
-var math = struct {
-	Min func(a, b float64) float64
-}{
-	Min: func(a, b float64) float64 {
-		if a < b {
-			return a
-		}
-		return b
-	},
-}
\ No newline at end of file
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrTemplateSlot is returned when a query template matched a description
+// but a slot of it can't be filled
+var ErrTemplateSlot = errors.New("invalid query template slot")
+
+// QueryTypeTemplate is the query type and query type source of responses
+// answered by a query template
+const QueryTypeTemplate = "template"
+
+// QueryTemplate is curated SQL for a high-value question. A description
+// matching one of its phrases is answered with its SQL, skipping
+// generation. Phrases and SQL share {slot} placeholders: the phrase
+// captures a slot's value from the description, and the SQL gets it as a
+// literal of the slot's type
+type QueryTemplate struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Phrases are intent phrases such as "monthly active users in {month}"
+	Phrases []string `json:"phrases" yaml:"phrases"`
+	SQL     string   `json:"sql" yaml:"sql"`
+	// Dialects replaces SQL for the dialects that need their own
+	Dialects map[string]string `json:"dialects,omitempty" yaml:"dialects,omitempty"`
+	Slots    []TemplateSlot    `json:"slots,omitempty" yaml:"slots,omitempty"`
+	// Fields are the table.column names the SQL reads, which access
+	// control judges the template by
+	Fields []string `json:"fields" yaml:"fields"`
+
+	refs []models.FieldRef
+}
+
+// TemplateSlot is a value a query template takes. Type is a field type
+// such as DATE or INTEGER, rendered as filter values are; Default fills the
+// slot when the matched phrase doesn't capture it
+type TemplateSlot struct {
+	Name    string `json:"name" yaml:"name"`
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// QueryTemplateSet holds the query templates and the phrases that name them
+type QueryTemplateSet struct {
+	Templates []QueryTemplate `json:"templates" yaml:"templates"`
+
+	phrases []templatePhrase
+}
+
+// templatePhrase is one phrase of a template, matched on word boundaries,
+// with a capture group per slot it names
+type templatePhrase struct {
+	phrase   string
+	pattern  *regexp.Regexp
+	template *QueryTemplate
+}
+
+// TemplateMatch is a template a description matched, with its slot values
+type TemplateMatch struct {
+	Template *QueryTemplate
+	Phrase   string
+	Values   map[string]string
+}
+
+// LoadQueryTemplates reads query templates from a YAML or JSON file
+func LoadQueryTemplates(path string) (*QueryTemplateSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query templates file: %w", err)
+	}
+
+	var set QueryTemplateSet
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &set)
+	default:
+		err = json.Unmarshal(data, &set)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query templates file: %w", err)
+	}
+
+	for i := range set.Templates {
+		template := &set.Templates[i]
+		if err := template.prepare(); err != nil {
+			return nil, fmt.Errorf("query template %d: %w", i+1, err)
+		}
+		for _, phrase := range template.Phrases {
+			phrase = strings.ToLower(strings.Join(strings.Fields(phrase), " "))
+			if phrase == "" {
+				continue
+			}
+			set.phrases = append(set.phrases, templatePhrase{
+				phrase:   phrase,
+				pattern:  phrasePattern(phrase),
+				template: template,
+			})
+		}
+	}
+
+	// Longer phrases first, so "weekly active users" wins over "active users"
+	sort.SliceStable(set.phrases, func(i, j int) bool {
+		return len(set.phrases[i].phrase) > len(set.phrases[j].phrase)
+	})
+	return &set, nil
+}
+
+// prepare validates a template and resolves its dialect and field names.
+// It needs a name, phrases, fields, and SQL; every slot its phrases and SQL
+// name must be declared; and its SQL must pass the read-only check whatever
+// the slots hold
+func (t *QueryTemplate) prepare() error {
+	if t.Name == "" || len(t.Phrases) == 0 || len(t.Fields) == 0 || strings.TrimSpace(t.SQL) == "" {
+		return errors.New("a query template needs a name, phrases, fields, and sql")
+	}
+
+	t.refs = make([]models.FieldRef, 0, len(t.Fields))
+	for _, name := range t.Fields {
+		table, column, found := strings.Cut(strings.TrimSpace(name), ".")
+		if !found || table == "" || column == "" {
+			return fmt.Errorf("template %q: field %q is not table.column", t.Name, name)
+		}
+		t.refs = append(t.refs, models.FieldRef{Table: table, Column: column})
+	}
+
+	dialects := make(map[string]string, len(t.Dialects))
+	for name, sql := range t.Dialects {
+		dialect, err := ResolveDialect(name)
+		if err != nil {
+			return fmt.Errorf("template %q: %w", t.Name, err)
+		}
+		dialects[dialect] = sql
+	}
+	t.Dialects = dialects
+
+	declared := make(map[string]bool, len(t.Slots))
+	for _, slot := range t.Slots {
+		declared[slot.Name] = true
+	}
+	texts := append([]string{t.SQL}, t.Phrases...)
+	for _, sql := range t.Dialects {
+		texts = append(texts, sql)
+	}
+	for _, text := range texts {
+		for _, slot := range templateVariables(text) {
+			if !declared[slot] {
+				return fmt.Errorf("template %q uses undeclared slot {%s}", t.Name, slot)
+			}
+		}
+	}
+
+	checks := map[string]string{anyDialect: t.SQL}
+	for dialect, sql := range t.Dialects {
+		checks[dialect] = sql
+	}
+	for dialect, sql := range checks {
+		if err := checkReadOnly(templateVariable.ReplaceAllString(sql, "NULL"), dialect); err != nil {
+			return fmt.Errorf("template %q: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkTables verifies every template reads tables in the mappings
+func (s *QueryTemplateSet) checkTables(hasTable func(string) bool) error {
+	for _, template := range s.Templates {
+		for _, ref := range template.refs {
+			if !hasTable(ref.Table) {
+				return fmt.Errorf("query template %q reads unknown table %s", template.Name, ref.Table)
+			}
+		}
+	}
+	return nil
+}
+
+// phrasePattern compiles a phrase to a pattern capturing its slots. A slot
+// ending the phrase takes the rest of the description; others as little
+// as reaches the words after them
+func phrasePattern(phrase string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`\b`)
+	last := 0
+	locations := templateVariable.FindAllStringSubmatchIndex(phrase, -1)
+	for i, location := range locations {
+		b.WriteString(regexp.QuoteMeta(phrase[last:location[0]]))
+		capture := `(.+?)`
+		if i == len(locations)-1 && location[1] == len(phrase) {
+			capture = `(.+)`
+		}
+		b.WriteString(`(?P<` + phrase[location[2]:location[3]] + `>` + capture[1:])
+		last = location[1]
+	}
+	b.WriteString(regexp.QuoteMeta(phrase[last:]))
+	if last < len(phrase) {
+		b.WriteString(`\b`)
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// Match finds the template a description asks for, reading the slot values
+// its phrase captures. It returns nil when no template matches
+func (s *QueryTemplateSet) Match(description string) *TemplateMatch {
+	if s == nil {
+		return nil
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(description), " "))
+	normalized = strings.TrimRight(normalized, ".!?")
+	for _, phrase := range s.phrases {
+		captured := phrase.pattern.FindStringSubmatch(normalized)
+		if captured == nil {
+			continue
+		}
+		values := make(map[string]string)
+		for i, name := range phrase.pattern.SubexpNames() {
+			if name != "" && captured[i] != "" {
+				values[name] = strings.TrimSpace(captured[i])
+			}
+		}
+		return &TemplateMatch{Template: phrase.template, Phrase: phrase.phrase, Values: values}
+	}
+	return nil
+}
+
+// Render fills a matched template's slots for a dialect. Values are
+// rendered as filter values of the slot's type, so they are escaped, and
+// slots the phrase didn't capture take their defaults, which are recorded
+// in Values
+func (m *TemplateMatch) Render(dialect string) (string, error) {
+	sql := m.Template.SQL
+	if override, exists := m.Template.Dialects[dialect]; exists {
+		sql = override
+	}
+
+	literals := make(map[string]string, len(m.Template.Slots))
+	for _, slot := range m.Template.Slots {
+		value, captured := m.Values[strings.ToLower(slot.Name)]
+		if !captured {
+			value = slot.Default
+		}
+		if value == "" {
+			return "", fmt.Errorf("%w: template %q needs a value for {%s}", ErrTemplateSlot, m.Template.Name, slot.Name)
+		}
+		literal, err := formatTypedLiteral(slot.Type, dialect, value)
+		if err != nil {
+			return "", fmt.Errorf("%w: {%s} of template %q: %v", ErrTemplateSlot, slot.Name, m.Template.Name, err)
+		}
+		literals[slot.Name] = literal
+		m.Values[strings.ToLower(slot.Name)] = value
+	}
+	return templateVariable.ReplaceAllStringFunc(sql, func(placeholder string) string {
+		return literals[placeholder[1:len(placeholder)-1]]
+	}), nil
+}
+
+// templateRefusal says why a matched template can't answer a request, or
+// returns "" when it can. Every field it reads must pass the request's
+// filter and the caller's clearance, and none may be on an aggregate-only
+// table, as curated SQL isn't held to the minimum group size. Columns the
+// mappings don't list are judged by their table alone
+func (s *QueryService) templateRefusal(template *QueryTemplate, filter FieldFilter, clearance string) string {
+	tables := make([]string, 0, len(template.refs))
+	for _, ref := range template.refs {
+		field, mapped := s.fieldService.LookupField(ref.Table, ref.Column)
+		if !mapped {
+			field = models.Field{TableName: ref.Table, ColumnName: ref.Column}
+		}
+		if !filter.allows(field) {
+			return fmt.Sprintf("%s.%s is left out by the request's filters", ref.Table, ref.Column)
+		}
+		if level := classificationLevel(field.Classification); !cleared(clearance, level) {
+			return withheldWarning(ref.Table, ref.Column, level, clearance)
+		}
+		tables = append(tables, ref.Table)
+	}
+	if restricted := s.policies.aggregateOnly(tables); len(restricted) > 0 {
+		return minGroupSizeWarning(restricted, s.policies.minGroupSize())
+	}
+	return ""
+}
+
+// renderTemplate renders a matched template in a dialect and the request's
+// format, under the row limit the request asked for or the configured
+// default and maximum. It returns why the template can't answer instead
+// when its own row limit can't be lowered to that
+func (s *QueryService) renderTemplate(request models.QueryRequest, match *TemplateMatch, dialect string) (query, rendered string, warnings []string, refusal string, err error) {
+	rendered, err = match.Render(dialect)
+	if err != nil {
+		return "", "", nil, "", err
+	}
+
+	limits := models.QueryPlan{Limit: request.Limit}
+	if note := s.enforceRowLimit(&limits); note != "" {
+		warnings = append(warnings, note)
+	}
+	if limits.Limit > 0 {
+		limited, ok := limitTemplate(rendered, dialect, limits.Limit)
+		if !ok {
+			return "", "", nil, fmt.Sprintf("its own row limit can't be lowered to %d", limits.Limit), nil
+		}
+		rendered = limited
+	}
+
+	query = formatSQL(rendered, dialect, request.Format, s.style)
+	if err := checkReadOnly(query, dialect); err != nil {
+		return "", "", nil, "", err
+	}
+	return query, rendered, warnings, "", nil
+}
+
+// limitTemplate caps the rows of a rendered template. A LIMIT, TOP, or
+// FETCH of its own at the top level is lowered to limit when it is higher;
+// without one, the dialect's row limit is appended after any ORDER BY. It
+// reports false for a limit of its own that isn't a number
+func limitTemplate(query, dialect string, limit int) (string, bool) {
+	tokens := tokenizeSQL(query, dialect)
+	ordered, limited := false, false
+	for i, token := range tokens {
+		if token.depth != 0 {
+			continue
+		}
+		keyword := strings.ToUpper(token.text)
+		switch {
+		case keyword == "ORDER":
+			ordered = true
+			continue
+		case keyword == "LIMIT" || keyword == "TOP":
+		case (keyword == "NEXT" || keyword == "FIRST") && i > 0 && strings.EqualFold(tokens[i-1].text, "FETCH"):
+		default:
+			continue
+		}
+		if i+1 == len(tokens) {
+			return "", false
+		}
+		own, err := strconv.Atoi(tokens[i+1].text)
+		// MySQL's LIMIT offset, count puts the count second
+		if err != nil || own < 0 || (i+2 < len(tokens) && tokens[i+2].text == ",") {
+			return "", false
+		}
+		if own > limit {
+			tokens[i+1].text = strconv.Itoa(limit)
+		}
+		limited = true
+	}
+	if limited {
+		return compactSQL(tokens), true
+	}
+
+	if dialect == DialectSQLServer {
+		clause := fmt.Sprintf("OFFSET 0 ROWS FETCH NEXT %d ROWS ONLY", limit)
+		if !ordered {
+			clause = "ORDER BY (SELECT NULL) " + clause
+		}
+		return query + " " + clause, true
+	}
+	_, clause := rowLimit(dialect, limit, 0, ordered)
+	return query + " " + clause, true
+}
+
+// templateResponse answers a description with the query template it
+// matched, rendered for the request's dialect and format. It returns why
+// the template can't answer the request instead, when it can't
+func (s *QueryService) templateResponse(request models.QueryRequest, match *TemplateMatch, expanded string, startTime time.Time) (models.QueryResponse, string, error) {
+	if refusal := s.templateRefusal(match.Template, requestFilter(request), s.clearance(request.Clearance)); refusal != "" {
+		return models.QueryResponse{}, refusal, nil
+	}
+	dialect, err := s.queryDialect(request.Dialect)
+	if err != nil {
+		return models.QueryResponse{}, "", err
+	}
+	query, rendered, warnings, refusal, err := s.renderTemplate(request, match, dialect)
+	if err != nil || refusal != "" {
+		return models.QueryResponse{}, refusal, err
+	}
+
+	response := models.QueryResponse{
+		TraceID:             request.TraceID,
+		SchemaVersion:       s.fieldService.Version(),
+		ExpandedDescription: expanded,
+		Query:               query,
+		Fingerprint:         queryFingerprint(rendered, dialect),
+		QueryType:           QueryTypeTemplate,
+		QueryTypeSource:     QueryTypeTemplate,
+		MatchedFields:       []models.FieldMatch{},
+		JoinsUsed:           []models.Join{},
+		Warnings:            warnings,
+		// Curated SQL answers exactly the question it was written for
+		Confidence:     100,
+		ProcessingTime: time.Since(startTime).Milliseconds(),
+		QueryTemplate: &models.QueryTemplateUse{
+			Name:   match.Template.Name,
+			Phrase: match.Phrase,
+			Slots:  match.Values,
+		},
+	}
+	s.events.Emit(models.GenerationEvent{
+		TraceID:         request.TraceID,
+		Timestamp:       startTime,
+		SchemaVersion:   response.SchemaVersion,
+		DescriptionHash: descriptionHash(request.Description),
+		Outcome:         EventOutcomeTemplate,
+		QueryType:       QueryTypeTemplate,
+		Tables:          []string{},
+		Confidence:      response.Confidence,
+		LatencyMs:       response.ProcessingTime,
+		Fingerprint:     response.Fingerprint,
+	})
+	return response, "", nil
+}
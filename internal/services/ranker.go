@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Built-in ranking strategies. RankerFeedback is not a scorer of its own: its
+// weight scales the feedback boost added to the blended score
+const (
+	RankerKeyword   = "keyword"
+	RankerTFIDF     = "tfidf"
+	RankerFuzzy     = "fuzzy"
+	RankerEmbedding = "embedding"
+	RankerFeedback  = "feedback"
+)
+
+// RankRequest carries the per-request inputs that rankers score against
+type RankRequest struct {
+	Keywords []string
+	// Embedding is nil unless semantic matching is enabled and succeeded
+	Embedding []float64
+
+	terms []keywordTerm
+	// deadline stops scoring early when set
+	deadline time.Time
+}
+
+// Ranker scores how well the field at index matches a request, from 0 to 100
+type Ranker interface {
+	Score(request *RankRequest, field models.Field, index int) float64
+}
+
+// weightedRanker is one member of a ranking ensemble
+type weightedRanker struct {
+	name   string
+	ranker Ranker
+	weight float64
+}
+
+// rankingPipeline blends its members by weight and then applies the
+// feedback boost
+type rankingPipeline struct {
+	members []weightedRanker
+	// withoutEmbedding replaces members when a request could not be embedded
+	withoutEmbedding []weightedRanker
+	feedback         *FeedbackStore
+	feedbackWeight   float64
+}
+
+// rankerSpec returns the configured ensemble, e.g. "keyword:0.7,tfidf:0.3,feedback".
+// Without one, the spec is derived from the matcher setting
+func rankerSpec(s *FieldService) string {
+	if s.cfg.Ranker != "" {
+		return s.cfg.Ranker
+	}
+	switch s.cfg.Matcher {
+	case MatcherEmbedding:
+		return "embedding,feedback"
+	case MatcherHybrid:
+		fuzzyWeight, semanticWeight := s.cfg.HybridFuzzyWeight, s.cfg.HybridSemanticWeight
+		if fuzzyWeight < 0 {
+			fuzzyWeight = 0
+		}
+		if semanticWeight < 0 {
+			semanticWeight = 0
+		}
+		if fuzzyWeight+semanticWeight == 0 {
+			fuzzyWeight, semanticWeight = 0.5, 0.5
+		}
+		return fmt.Sprintf("keyword:%g,embedding:%g,feedback", fuzzyWeight, semanticWeight)
+	default:
+		return "keyword,feedback"
+	}
+}
+
+// newRankingPipeline parses a ranker spec into a weighted pipeline. Weights
+// default to 1 and are normalized across the scoring members
+func newRankingPipeline(s *FieldService, spec string) (*rankingPipeline, error) {
+	pipeline := &rankingPipeline{feedback: s.feedback}
+
+	var total float64
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, weight := entry, 1.0
+		if i := strings.Index(entry, ":"); i >= 0 {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(entry[i+1:]), 64)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("invalid weight in ranker %q", entry)
+			}
+			name, weight = strings.TrimSpace(entry[:i]), parsed
+		}
+		name = strings.ToLower(name)
+
+		if name == RankerFeedback {
+			pipeline.feedbackWeight = weight
+			continue
+		}
+		ranker, err := newRanker(s, name)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.members = append(pipeline.members, weightedRanker{name: name, ranker: ranker, weight: weight})
+		total += weight
+	}
+
+	if len(pipeline.members) == 0 {
+		return nil, fmt.Errorf("ranker %q has no scoring strategies", spec)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("ranker %q has zero total weight", spec)
+	}
+	for i := range pipeline.members {
+		pipeline.members[i].weight /= total
+	}
+
+	// Semantic members drop out when a request cannot be embedded, falling
+	// back to keyword scoring if nothing else is left. Members left with
+	// no weight between them share it equally
+	var remaining float64
+	for _, member := range pipeline.members {
+		if member.name != RankerEmbedding {
+			pipeline.withoutEmbedding = append(pipeline.withoutEmbedding, member)
+			remaining += member.weight
+		}
+	}
+	for i := range pipeline.withoutEmbedding {
+		if remaining == 0 {
+			pipeline.withoutEmbedding[i].weight = 1 / float64(len(pipeline.withoutEmbedding))
+			continue
+		}
+		pipeline.withoutEmbedding[i].weight /= remaining
+	}
+	if len(pipeline.withoutEmbedding) == 0 {
+		pipeline.withoutEmbedding = []weightedRanker{{name: RankerKeyword, ranker: keywordRanker{s}, weight: 1}}
+	}
+	return pipeline, nil
+}
+
+// newRanker creates a built-in ranker by name
+func newRanker(s *FieldService, name string) (Ranker, error) {
+	switch name {
+	case RankerKeyword:
+		return keywordRanker{s}, nil
+	case RankerTFIDF:
+		return newTFIDFRanker(s.fields), nil
+	case RankerFuzzy:
+		return fuzzyRanker{}, nil
+	case RankerEmbedding:
+		return embeddingRanker{s}, nil
+	default:
+		return nil, fmt.Errorf("unknown ranker %q", name)
+	}
+}
+
+// usesRanker reports whether the pipeline includes the named ranker
+func (p *rankingPipeline) usesRanker(name string) bool {
+	for _, member := range p.members {
+		if member.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rank scores a field. score is clamped to 0..100 for display while rank
+// keeps the unclamped value so boosts still order fields that tie at 100.
+// The breakdown is set only for ensembles of more than one ranker
+func (p *rankingPipeline) rank(request *RankRequest, field models.Field, index int) (score, rank, boost float64, breakdown *models.ScoreBreakdown) {
+	members := p.members
+	if request.Embedding == nil {
+		members = p.withoutEmbedding
+	}
+
+	if len(members) == 1 {
+		score = members[0].ranker.Score(request, field, index)
+	} else {
+		breakdown = &models.ScoreBreakdown{}
+		for _, member := range members {
+			memberScore := member.ranker.Score(request, field, index)
+			score += memberScore * member.weight
+			breakdown.Components = append(breakdown.Components, models.RankerScore{
+				Ranker: member.name,
+				Score:  memberScore,
+				Weight: member.weight,
+			})
+			switch member.name {
+			case RankerKeyword:
+				breakdown.Fuzzy, breakdown.FuzzyWeight = memberScore, member.weight
+			case RankerEmbedding:
+				breakdown.Semantic, breakdown.SemanticWeight = memberScore, member.weight
+			}
+		}
+		breakdown.Combined = score
+	}
+
+	// Nudge fields users previously accepted (or rejected) for these keywords;
+	// feedback alone never makes an unrelated field match
+	rank = score
+	if score > 0 && p.feedbackWeight > 0 {
+		boost = p.feedback.Boost(request.Keywords, fieldKey(field.TableName, field.ColumnName)) * p.feedbackWeight
+		rank = score + boost
+		score = math.Max(0, math.Min(100, rank))
+	}
+	return score, rank, boost, breakdown
+}
+
+// keywordRanker scores the share of keywords found in the description and
+// any linked glossary vocabulary
+type keywordRanker struct {
+	s *FieldService
+}
+
+// Score implements Ranker
+func (r keywordRanker) Score(request *RankRequest, field models.Field, index int) float64 {
+	return r.s.calculateMatchScore(r.s.matchText(field, index), request.terms)
+}
+
+// embeddingRanker scores cosine similarity between request and description
+type embeddingRanker struct {
+	s *FieldService
+}
+
+// Score implements Ranker
+func (r embeddingRanker) Score(request *RankRequest, field models.Field, index int) float64 {
+	if request.Embedding == nil || index >= len(r.s.fieldEmbeddings) {
+		return 0
+	}
+	return semanticScore(request.Embedding, r.s.fieldEmbeddings[index])
+}
+
+// tfidfRanker weights keyword hits by how rare each keyword is across field
+// descriptions, so "email" counts for more than "user". Descriptions are
+// short, so term frequency is treated as presence
+type tfidfRanker struct {
+	documentFrequency map[string]int
+	documents         int
+}
+
+// newTFIDFRanker counts the descriptions each word appears in
+func newTFIDFRanker(fields []models.Field) tfidfRanker {
+	r := tfidfRanker{documentFrequency: make(map[string]int), documents: len(fields)}
+	for _, field := range fields {
+		seen := make(map[string]bool)
+		for _, word := range strings.Fields(strings.ToLower(field.Description)) {
+			if !seen[word] {
+				seen[word] = true
+				r.documentFrequency[word]++
+			}
+		}
+	}
+	return r
+}
+
+// idf is the smoothed inverse document frequency of a word; unseen words
+// are treated as the rarest
+func (r tfidfRanker) idf(word string) float64 {
+	return math.Log(float64(r.documents+1)/float64(r.documentFrequency[word]+1)) + 1
+}
+
+// Score implements Ranker
+func (r tfidfRanker) Score(request *RankRequest, field models.Field, index int) float64 {
+	description := strings.ToLower(field.Description)
+
+	var matched, total float64
+	for _, term := range request.terms {
+		weight := r.idf(strings.ToLower(term.keyword))
+		total += weight
+		if strings.Contains(description, strings.ToLower(term.keyword)) {
+			matched += weight
+			continue
+		}
+		for _, alternative := range term.alternatives {
+			if strings.Contains(description, alternative) {
+				matched += weight
+				break
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return matched / total * 100
+}
+
+// fuzzyRanker gives each keyword the Jaro-Winkler similarity of its closest
+// description word, so near-misses earn partial credit without expansion
+type fuzzyRanker struct{}
+
+// Score implements Ranker
+func (fuzzyRanker) Score(request *RankRequest, field models.Field, index int) float64 {
+	if len(request.terms) == 0 {
+		return 0
+	}
+	words := strings.Fields(strings.ToLower(field.Description))
+
+	var total float64
+	for _, term := range request.terms {
+		keyword := strings.ToLower(term.keyword)
+		best := 0.0
+		for _, word := range words {
+			if strings.Contains(word, keyword) {
+				best = 1
+				break
+			}
+			best = math.Max(best, jaroWinkler(keyword, word))
+		}
+		total += best
+	}
+	return total / float64(len(request.terms)) * 100
+}
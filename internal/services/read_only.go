@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsafeSQL is returned when generated SQL fails the read-only check,
+// which only happens when mappings smuggle SQL into measures or cohorts
+var ErrUnsafeSQL = errors.New("generated SQL failed the read-only check")
+
+// writeKeywords change data, schema, or permissions, or run other code.
+// All are reserved words, so names using them are quoted and a bare one is
+// a keyword, which may not appear in a generated query
+var writeKeywords = map[string]bool{
+	"insert": true, "update": true, "delete": true, "merge": true, "truncate": true, "drop": true,
+	"alter": true, "create": true, "rename": true, "grant": true, "revoke": true, "into": true, "set": true,
+	"call": true, "exec": true, "execute": true, "attach": true, "detach": true, "pragma": true,
+	"vacuum": true, "lock": true, "commit": true, "rollback": true,
+}
+
+// checkReadOnly is the last pass over generated SQL, guaranteeing it is a
+// single SELECT, perhaps after WITH, that is safe to run against a
+// production replica: no statement separators, no comments that could hide
+// the rest of a line, and no keyword that writes. Quoted strings and
+// identifiers may hold anything
+func checkReadOnly(query, dialect string) error {
+	tokens := tokenizeSQL(query, dialect)
+	if len(tokens) == 0 || !strings.EqualFold(tokens[0].text, "SELECT") && !strings.EqualFold(tokens[0].text, "WITH") {
+		return fmt.Errorf("%w: the query is not a SELECT", ErrUnsafeSQL)
+	}
+	for _, token := range tokens {
+		if quoted(token.text) {
+			continue
+		}
+		switch {
+		case strings.Contains(token.text, ";"):
+			return fmt.Errorf("%w: %q separates statements", ErrUnsafeSQL, token.text)
+		case strings.Contains(token.text, "--") || strings.Contains(token.text, "/*") ||
+			strings.Contains(token.text, "*/") || strings.Contains(token.text, "#"):
+			return fmt.Errorf("%w: %q starts or ends a comment", ErrUnsafeSQL, token.text)
+		case writeKeywords[strings.ToLower(token.text)]:
+			return fmt.Errorf("%w: %s is not allowed in a read-only query", ErrUnsafeSQL, strings.ToUpper(token.text))
+		}
+	}
+	return nil
+}
+
+// quoted reports whether a token is a quoted string or identifier, or a
+// Postgres escape string
+func quoted(text string) bool {
+	return strings.ContainsAny(text[:1], "'\"`[") || len(text) > 1 && strings.ContainsAny(text[:1], "Ee") && text[1] == '\''
+}
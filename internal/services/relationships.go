@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Relationship graph export formats
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+// RelationshipGraph describes the join graph: every table, including ones
+// only named as a join target or joined to nothing, and each relationship
+// once, in foreign key direction and catalog order
+func (s *FieldService) RelationshipGraph() models.RelationshipGraph {
+	fields := make(map[string]int)
+	tables := make(map[string]bool)
+	for _, field := range s.fields {
+		fields[field.TableName]++
+		tables[field.TableName] = true
+	}
+	for table := range s.relationshipGraph {
+		tables[table] = true
+	}
+
+	graph := models.RelationshipGraph{
+		Version:    s.version,
+		Nodes:      make([]models.RelationshipNode, 0, len(tables)),
+		Edges:      make([]models.Join, 0),
+		Components: s.graphComponents(tables),
+	}
+	seen := make(map[string]bool)
+	for _, key := range foreignKeys(s.fields) {
+		pair := tablePair(key.table, key.foreignTable)
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		graph.Edges = append(graph.Edges, s.relationshipGraph[key.table][key.foreignTable])
+	}
+
+	component := make(map[string]int)
+	for i, tables := range graph.Components {
+		for _, table := range tables {
+			component[table] = i
+		}
+	}
+	for table := range tables {
+		graph.Nodes = append(graph.Nodes, models.RelationshipNode{
+			Table:         table,
+			Fields:        fields[table],
+			Relationships: len(s.relationshipGraph[table]),
+			Component:     component[table],
+		})
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Table < graph.Nodes[j].Table
+	})
+	return graph
+}
+
+// RenderRelationshipGraph renders the join graph as Graphviz DOT or as a
+// Mermaid entity-relationship diagram, returning it with its content type.
+// Tables nothing joins to are drawn on their own so curators can spot them
+func (s *FieldService) RenderRelationshipGraph(format string) (string, string, error) {
+	graph := s.RelationshipGraph()
+	var isolated []string
+	for _, node := range graph.Nodes {
+		if node.Relationships == 0 {
+			isolated = append(isolated, node.Table)
+		}
+	}
+
+	switch format {
+	case FormatDOT:
+		var b strings.Builder
+		b.WriteString("digraph relationships {\n    rankdir=LR;\n    node [shape=box];\n")
+		for _, edge := range graph.Edges {
+			style := ""
+			if edge.Type == JoinTypeLeft {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(&b, "    %s -> %s [label=%s%s];\n", dotID(edge.From), dotID(edge.To), dotID(edgeLabel(edge)), style)
+		}
+		for _, table := range isolated {
+			fmt.Fprintf(&b, "    %s [color=red, tooltip=\"joined to no other table\"];\n", dotID(table))
+		}
+		b.WriteString("}\n")
+		return b.String(), "text/vnd.graphviz; charset=utf-8", nil
+	case FormatMermaid:
+		diagram := s.relationshipDiagram()
+		if diagram == "" {
+			diagram = "erDiagram"
+		}
+		for _, table := range isolated {
+			diagram += "\n    " + mermaidName(table)
+		}
+		return diagram + "\n", "text/plain; charset=utf-8", nil
+	default:
+		return "", "", fmt.Errorf("%w %q: use json, dot, or mermaid", ErrUnknownFormat, format)
+	}
+}
+
+// edgeLabel names the column pairs of a relationship, as the dictionary's
+// diagram does
+func edgeLabel(edge models.Join) string {
+	pairs := make([]string, len(edge.Columns))
+	for i, column := range edge.Columns {
+		pairs[i] = column.From + " = " + column.To
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// dotID quotes a name as a DOT identifier
+func dotID(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
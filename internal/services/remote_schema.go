@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+)
+
+// remoteFetchTimeout bounds downloading a remote mapping file
+const remoteFetchTimeout = 30 * time.Second
+
+// maxRemoteMappingBytes caps the size of a downloaded mapping file
+const maxRemoteMappingBytes = 64 << 20
+
+// defaultS3Region is used to sign S3 requests when no region is configured
+const defaultS3Region = "us-east-1"
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// remoteObject is the last fetched copy of a remote mapping file
+type remoteObject struct {
+	etag string
+	data []byte
+}
+
+// remoteObjects caches remote mapping files by location, so refreshes make
+// conditional requests and reuse the cached body on 304 Not Modified
+var remoteObjects = struct {
+	sync.Mutex
+	byLocation map[string]remoteObject
+}{byLocation: make(map[string]remoteObject)}
+
+// isRemotePath reports whether a mapping path is an http(s):// or s3:// URL
+func isRemotePath(path string) bool {
+	lower := strings.ToLower(strings.TrimSpace(path))
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "s3://")
+}
+
+// mappingExt returns a mapping path's lowercased extension, ignoring the
+// query string of remote URLs such as presigned links
+func mappingExt(path string) string {
+	if isRemotePath(path) {
+		if parsed, err := url.Parse(path); err == nil {
+			path = parsed.Path
+		}
+	}
+	return strings.ToLower(filepath.Ext(path))
+}
+
+// displayLocation strips credentials and query strings from a remote
+// location so it is safe to log
+func displayLocation(location string) string {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "remote mapping file"
+	}
+	parsed.User, parsed.RawQuery = nil, ""
+	return parsed.String()
+}
+
+// readSource reads a mapping file from disk or a remote location
+func (s *FieldService) readSource(path string) ([]byte, error) {
+	if !isRemotePath(path) {
+		return os.ReadFile(path)
+	}
+	data, _, err := fetchRemote(s.cfg, path)
+	return data, err
+}
+
+// fetchRemote downloads a remote mapping file, sending the cached ETag so an
+// unchanged file isn't transferred again. changed is false when the cached
+// copy is still current
+func fetchRemote(cfg *config.Config, location string) ([]byte, bool, error) {
+	remoteObjects.Lock()
+	cached, hasCached := remoteObjects.byLocation[location]
+	remoteObjects.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+	defer cancel()
+	req, err := remoteRequest(ctx, cfg, location)
+	if err != nil {
+		return nil, false, err
+	}
+	if hasCached && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Transport errors repeat the full URL, which may carry a presigned token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, false, fmt.Errorf("failed to fetch %s: %w", displayLocation(location), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && hasCached {
+		return cached.data, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("failed to fetch %s: %s", displayLocation(location), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteMappingBytes+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch %s: %w", displayLocation(location), err)
+	}
+	if len(data) > maxRemoteMappingBytes {
+		return nil, false, fmt.Errorf("%s is larger than %d bytes", displayLocation(location), maxRemoteMappingBytes)
+	}
+
+	remoteObjects.Lock()
+	remoteObjects.byLocation[location] = remoteObject{etag: resp.Header.Get("ETag"), data: data}
+	remoteObjects.Unlock()
+	return data, !hasCached || !bytes.Equal(cached.data, data), nil
+}
+
+// remoteRequest builds the GET for a remote location. s3:// locations are
+// addressed virtual-host style on AWS, or path style under S3Endpoint, and
+// signed when AWS credentials are configured
+func remoteRequest(ctx context.Context, cfg *config.Config, location string) (*http.Request, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping URL: %w", err)
+	}
+	if !strings.EqualFold(parsed.Scheme, "s3") {
+		return http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	}
+
+	bucket, key := parsed.Host, strings.TrimPrefix(parsed.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 location %q: use s3://bucket/key", location)
+	}
+	region := s3Region(cfg)
+	var objectURL string
+	if cfg.S3Endpoint != "" {
+		objectURL = strings.TrimRight(cfg.S3Endpoint, "/") + "/" + bucket + "/" + escapeS3Key(key)
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, escapeS3Key(key))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	signS3Request(cfg, req, region, time.Now())
+	return req, nil
+}
+
+// s3Region returns the configured S3 region or the default
+func s3Region(cfg *config.Config) string {
+	if cfg.S3Region == "" {
+		return defaultS3Region
+	}
+	return cfg.S3Region
+}
+
+// escapeS3Key escapes each segment of an object key
+func escapeS3Key(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// signS3Request adds an AWS Signature Version 4 to an S3 GET. Without
+// credentials the request is sent anonymously, for public buckets
+func signS3Request(cfg *config.Config, req *http.Request, region string, now time.Time) {
+	if cfg.AWSAccessKeyID == "" || cfg.AWSSecretAccessKey == "" {
+		return
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if cfg.AWSSessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.AWSSessionToken)
+	}
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": emptyPayloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if cfg.AWSSessionToken != "" {
+		headers["x-amz-security-token"] = cfg.AWSSessionToken
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet, req.URL.EscapedPath(), req.URL.Query().Encode(),
+		canonicalHeaders.String(), signedHeaders, emptyPayloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestDigest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestDigest[:])
+
+	key := []byte("AWS4" + cfg.AWSSecretAccessKey)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		cfg.AWSAccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 computes an HMAC-SHA256 of data
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// ErrInvalidPlan is returned when a plan sent to be rendered can't be
+var ErrInvalidPlan = errors.New("invalid query plan")
+
+// RenderQuery renders SQL from a query plan, usually one a client edited
+// after generation, without matching a description again. The plan's SQL
+// isn't trusted: selected columns and filters are rebuilt from the fields,
+// operators, values, and cohorts they name, group and order expressions
+// must be column references of the plan's tables or selected aliases, and
+// joins are planned again for the tables still referenced. Clearance,
+// aggregate-only policies, complexity and row limits, and the read-only
+// check apply as they do to generated queries
+func (s *QueryService) RenderQuery(request models.RenderQueryRequest) (models.RenderQueryResponse, error) {
+	if request.TraceID == "" {
+		request.TraceID = NewTraceID()
+	}
+	log := s.log.WithField("trace_id", request.TraceID)
+	dialect, err := s.queryDialect(request.Dialect)
+	if err != nil {
+		return models.RenderQueryResponse{}, err
+	}
+	switch request.Format {
+	case "", FormatCompact, FormatPretty:
+	default:
+		return models.RenderQueryResponse{}, fmt.Errorf("%w: unknown format %q: use compact or pretty", ErrInvalidPlan, request.Format)
+	}
+	input := request.Plan
+	if len(input.Select) == 0 {
+		return models.RenderQueryResponse{}, fmt.Errorf("%w: nothing is selected", ErrInvalidPlan)
+	}
+	if input.Limit < 0 || input.Offset < 0 {
+		return models.RenderQueryResponse{}, fmt.Errorf("%w: limit and offset must not be negative", ErrInvalidPlan)
+	}
+
+	// Group and order expressions are read in terms of the plan's own tables
+	references, err := s.planReferences(dialect, request.System, input)
+	if err != nil {
+		return models.RenderQueryResponse{}, err
+	}
+	groups := make([]models.FieldRef, len(input.GroupBy))
+	for i, expression := range input.GroupBy {
+		ref, exists := references[expression]
+		if !exists {
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: group by %s is not a column of the plan's tables", ErrInvalidPlan, expression)
+		}
+		groups[i] = ref
+	}
+
+	// Every referenced field must exist and be cleared; sensitive ones are flagged
+	clearance := s.clearance(request.Clearance)
+	var sensitive []string
+	flagged := make(map[string]bool)
+	lookup := func(table, column string) (models.Field, error) {
+		field, exists := s.fieldService.LookupField(table, column)
+		if !exists {
+			return models.Field{}, fmt.Errorf("%w: unknown field %s.%s", ErrInvalidPlan, table, column)
+		}
+		if level := classificationLevel(field.Classification); !cleared(clearance, level) {
+			return models.Field{}, fmt.Errorf("%w: %s.%s is classified %s", ErrInsufficientClearance, table, column, level)
+		}
+		if key := fieldKey(table, column); field.Sensitive && !flagged[key] {
+			flagged[key] = true
+			sensitive = append(sensitive, key)
+		}
+		return field, nil
+	}
+
+	// Tables in the order the plan references them
+	var tableNames []string
+	seen := make(map[string]bool)
+	reference := func(table string) {
+		if !seen[table] {
+			seen[table] = true
+			tableNames = append(tableNames, table)
+		}
+	}
+	cohorts := make([]*Cohort, len(input.Filters))
+	for i, filter := range input.Filters {
+		if filter.Cohort != "" {
+			cohorts[i] = s.fieldService.cohorts.Find(filter.Cohort)
+			if cohorts[i] == nil {
+				return models.RenderQueryResponse{}, fmt.Errorf("%w: unknown cohort %q", ErrInvalidPlan, filter.Cohort)
+			}
+		}
+	}
+	for _, column := range input.Select {
+		if column.Table != "" {
+			reference(column.Table)
+		}
+	}
+	for i, filter := range input.Filters {
+		if cohorts[i] != nil {
+			reference(cohorts[i].Table)
+		} else if filter.Table != "" {
+			reference(filter.Table)
+		}
+	}
+	for _, group := range groups {
+		reference(group.Table)
+	}
+	orders := make([]models.FieldRef, len(input.OrderBy))
+	for i, order := range input.OrderBy {
+		if ref, exists := references[order.Expression]; exists {
+			orders[i] = ref
+			reference(ref.Table)
+		}
+	}
+	if len(tableNames) == 0 {
+		return models.RenderQueryResponse{}, fmt.Errorf("%w: no mapped field is selected", ErrInvalidPlan)
+	}
+
+	aliases, err := s.queryAliases(request.AliasStyle, request.System)
+	if err != nil {
+		return models.RenderQueryResponse{}, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	tableNames, joins, err := s.planJoins(tableNames, nil, nil)
+	if errors.Is(err, ErrJoinBoundary) {
+		return models.RenderQueryResponse{}, err
+	}
+	if err != nil {
+		return models.RenderQueryResponse{}, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	aliases.assign(tableNames[0], joins)
+
+	// SELECT list, rebuilt from the fields and aggregates named
+	plan := models.QueryPlan{Distinct: input.Distinct, Limit: input.Limit, Offset: input.Offset}
+	var plainColumns []string
+	for _, column := range input.Select {
+		aggregate := strings.ToUpper(strings.TrimSpace(column.Aggregate))
+		selected := models.PlanColumn{ID: column.ID, Table: column.Table, Column: column.Column, Aggregate: aggregate}
+		if column.Table == "" {
+			if aggregate != "COUNT" {
+				return models.RenderQueryResponse{}, fmt.Errorf("%w: column %s names no field", ErrInvalidPlan, column.ID)
+			}
+			selected.Expression = "COUNT(*)"
+			plan.Select = append(plan.Select, selected)
+			continue
+		}
+		field, err := lookup(column.Table, column.Column)
+		if err != nil {
+			return models.RenderQueryResponse{}, err
+		}
+		expression := aliases.column(dialect, column.Table, column.Column)
+		switch {
+		case field.Measure != "" && aggregate != "" && aggregate != planMeasure:
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: %s.%s is a measure and can't be aggregated again", ErrInvalidPlan, column.Table, column.Column)
+		case field.Measure != "":
+			selected.Expression = aliases.qualifyPredicate(dialect, field.Measure, tableNames)
+			selected.Aggregate = planMeasure
+			selected.Alias = column.Column
+		case aggregate == planMeasure:
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: %s.%s is not a measure", ErrInvalidPlan, column.Table, column.Column)
+		case aggregate == "":
+			selected.Expression = expression
+			plainColumns = append(plainColumns, expression)
+		case intentAggregates[aggregate]:
+			selected.Expression = fmt.Sprintf("%s(%s)", aggregate, expression)
+		default:
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: unsupported aggregate %q", ErrInvalidPlan, column.Aggregate)
+		}
+		if column.Alias != "" && selected.Aggregate != planMeasure {
+			if !validAlias.MatchString(column.Alias) {
+				return models.RenderQueryResponse{}, fmt.Errorf("%w: alias %q is not a plain identifier", ErrInvalidPlan, column.Alias)
+			}
+			selected.Alias = column.Alias
+		}
+		plan.Select = append(plan.Select, selected)
+	}
+
+	// WHERE conditions, rebuilt from their cohorts or operators and values
+	var warnings []string
+	for i, filter := range input.Filters {
+		if cohort := cohorts[i]; cohort != nil {
+			plan.Filters = append(plan.Filters, models.PlanFilter{
+				ID:         filter.ID,
+				Expression: "(" + aliases.qualifyPredicate(dialect, cohort.Predicate, tableNames) + ")",
+				Table:      cohort.Table,
+				Cohort:     cohort.Name,
+			})
+			continue
+		}
+		if filter.Table == "" {
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: filter %s names no field or cohort", ErrInvalidPlan, filter.ID)
+		}
+		field, err := lookup(filter.Table, filter.Column)
+		if err != nil {
+			return models.RenderQueryResponse{}, err
+		}
+		if field.Measure != "" {
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: %s.%s is a measure and can't be filtered", ErrInvalidPlan, filter.Table, filter.Column)
+		}
+		intent := models.IntentFilter{Table: filter.Table, Column: filter.Column, Operator: filter.Operator, Value: filter.Value}
+		condition, filterWarnings, err := renderFilter(aliases.column(dialect, filter.Table, filter.Column), field.FieldType, dialect, intent, nil)
+		if errors.Is(err, ErrInvalidIntent) {
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+		}
+		if err != nil {
+			return models.RenderQueryResponse{}, err
+		}
+		warnings = append(warnings, filterWarnings...)
+		plan.Filters = append(plan.Filters, models.PlanFilter{
+			ID:         filter.ID,
+			Expression: condition,
+			Table:      filter.Table,
+			Column:     filter.Column,
+			Operator:   strings.ToUpper(strings.Join(strings.Fields(filter.Operator), " ")),
+			Value:      filter.Value,
+		})
+	}
+
+	// GROUP BY must cover every column selected without an aggregate
+	grouped := make(map[string]bool)
+	for _, group := range groups {
+		if _, err := lookup(group.Table, group.Column); err != nil {
+			return models.RenderQueryResponse{}, err
+		}
+		column := aliases.column(dialect, group.Table, group.Column)
+		plan.GroupBy = append(plan.GroupBy, column)
+		grouped[column] = true
+	}
+	if len(plan.GroupBy) > 0 || len(plainColumns) < len(plan.Select) {
+		for _, column := range plainColumns {
+			if !grouped[column] {
+				return models.RenderQueryResponse{}, fmt.Errorf("%w: %s must be aggregated or grouped by", ErrInvalidPlan, column)
+			}
+		}
+	}
+
+	// ORDER BY a column or a selected alias
+	aliased := make(map[string]bool)
+	for _, column := range plan.Select {
+		if column.Alias != "" {
+			aliased[column.Alias] = true
+		}
+	}
+	for i, order := range input.OrderBy {
+		alias := unquoteIdent(order.Expression)
+		if orders[i].Table == "" && !aliased[alias] {
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: order by %s is not a column of the plan's tables or a selected alias", ErrInvalidPlan, order.Expression)
+		}
+		expression := quoteIdent(dialect, alias)
+		if orders[i].Table != "" {
+			if _, err := lookup(orders[i].Table, orders[i].Column); err != nil {
+				return models.RenderQueryResponse{}, err
+			}
+			expression = aliases.column(dialect, orders[i].Table, orders[i].Column)
+		}
+		direction := strings.ToUpper(strings.TrimSpace(order.Direction))
+		switch direction {
+		case "":
+			direction = "ASC"
+		case "ASC", "DESC":
+		default:
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: unsupported order direction %q", ErrInvalidPlan, order.Direction)
+		}
+		plan.OrderBy = append(plan.OrderBy, models.PlanOrder{ID: order.ID, Expression: expression, Direction: direction})
+	}
+
+	warnings = append(warnings, joinWarnings(joins)...)
+
+	// Aggregate-only tables need an aggregate or grouped plan, and groups
+	// below the minimum size are left out whatever the plan's HAVING said
+	if restricted := s.policies.aggregateOnly(joinedTables(tableNames, joins)); len(restricted) > 0 {
+		if len(plan.GroupBy) == 0 && len(plainColumns) == len(plan.Select) {
+			return models.RenderQueryResponse{}, fmt.Errorf("%w: %s may only be queried with aggregates or group by",
+				ErrAggregateOnly, strings.Join(restricted, ", "))
+		}
+		plan.Having = fmt.Sprintf("COUNT(*) >= %d", s.policies.minGroupSize())
+		warnings = append(warnings, minGroupSizeWarning(restricted, s.policies.minGroupSize()))
+	}
+
+	// Assemble the complete query
+	plan.From = aliases.tablePlan(dialect, tableNames[0])
+	plan.Joins = joinPlans(dialect, tableNames[0], joins, aliases)
+	for i := range plan.Joins {
+		for _, join := range input.Joins {
+			if join.Table.Table == plan.Joins[i].Table.Table {
+				plan.Joins[i].ID = join.ID
+			}
+		}
+	}
+	if exceeded := s.complexityLimits(0, 0, 0).exceeded(plan, joins); exceeded != "" {
+		return models.RenderQueryResponse{}, fmt.Errorf("%w: %s", ErrQueryTooComplex, exceeded)
+	}
+	if note := s.enforceRowLimit(&plan); note != "" {
+		warnings = append(warnings, note)
+	}
+	warnings = append(warnings, s.lintQuery(plan, joins, nil)...)
+	assignPlanIDs(&plan)
+	rendered := renderPlan(dialect, plan)
+	query := formatSQL(rendered, dialect, request.Format, s.style)
+	if err := checkReadOnly(query, dialect); err != nil {
+		log.WithError(err).Error("Rendered SQL failed the read-only check")
+		return models.RenderQueryResponse{}, err
+	}
+
+	log.WithField("tables", tableNames).Info("Rendered query from plan")
+
+	return models.RenderQueryResponse{
+		TraceID:          request.TraceID,
+		Query:            query,
+		Fingerprint:      queryFingerprint(rendered, dialect),
+		Plan:             &plan,
+		JoinsUsed:        joins,
+		SensitiveColumns: sensitive,
+		Warnings:         warnings,
+	}, nil
+}
+
+// planReferences maps every column reference the tables of a plan can be
+// rendered with, as the plan names them, to its field
+func (s *QueryService) planReferences(dialect, system string, plan models.QueryPlan) (map[string]models.FieldRef, error) {
+	names := s.fieldService.systemNames(system)
+	references := make(map[string]models.FieldRef)
+	for _, table := range append([]models.PlanTable{plan.From}, planJoinTables(plan.Joins)...) {
+		if table.Table == "" {
+			continue
+		}
+		if !s.fieldService.HasTable(table.Table) {
+			return nil, fmt.Errorf("%w: unknown table %s", ErrInvalidPlan, table.Table)
+		}
+		qualifier := table.Alias
+		if qualifier == "" {
+			qualifier = names.table(table.Table)
+		}
+		for _, field := range s.fieldService.fields {
+			if field.TableName == table.Table {
+				ref := columnRef(dialect, qualifier, names.column(table.Table, field.ColumnName))
+				references[ref] = models.FieldRef{Table: table.Table, Column: field.ColumnName}
+			}
+		}
+	}
+	return references, nil
+}
+
+// planJoinTables lists the tables a plan joins
+func planJoinTables(joins []models.PlanJoin) []models.PlanTable {
+	tables := make([]models.PlanTable, len(joins))
+	for i, join := range joins {
+		tables[i] = join.Table
+	}
+	return tables
+}
+
+// unquoteIdent strips the quotes of a quoted identifier, leaving plain
+// ones as they are
+func unquoteIdent(name string) string {
+	if len(name) >= 2 && strings.ContainsAny(name[:1], "\"`[") {
+		return name[1 : len(name)-1]
+	}
+	return name
+}
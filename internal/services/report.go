@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Supported response formats
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// ErrUnknownFormat is returned for an unsupported response format
+var ErrUnknownFormat = errors.New("unknown response format")
+
+// ValidateFormat checks a requested response format; empty means JSON
+func ValidateFormat(format string) error {
+	switch format {
+	case "", FormatJSON, FormatCompact, FormatPretty, FormatMarkdown, FormatHTML, FormatZip, FormatTar, FormatDbt, FormatGo, FormatSqlx:
+		return nil
+	default:
+		return fmt.Errorf("%w %q: use json, compact, pretty, markdown, html, zip, tar, dbt, go, or sqlx", ErrUnknownFormat, format)
+	}
+}
+
+// RenderReport renders a generated query as a Markdown or HTML fragment for
+// pasting into tickets and runbooks, returning the content type with it
+func RenderReport(format string, response models.QueryResponse) (string, string, error) {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdownReport(response), "text/markdown; charset=utf-8", nil
+	case FormatHTML:
+		var buf bytes.Buffer
+		if err := htmlReport.Execute(&buf, reportData{response, joinDiagram(response)}); err != nil {
+			return "", "", fmt.Errorf("failed to render report: %w", err)
+		}
+		return buf.String(), "text/html; charset=utf-8", nil
+	default:
+		return "", "", fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// joinDiagram draws the join path as indented text, one table per line
+func joinDiagram(response models.QueryResponse) string {
+	if len(response.JoinsUsed) == 0 {
+		return ""
+	}
+
+	var lines []string
+	depth := map[string]int{response.JoinsUsed[0].From: 0}
+	lines = append(lines, response.JoinsUsed[0].From)
+	for _, join := range response.JoinsUsed {
+		if _, joined := depth[join.To]; joined {
+			continue
+		}
+		depth[join.To] = depth[join.From] + 1
+		lines = append(lines, fmt.Sprintf("%s└─ %s ON %s",
+			strings.Repeat("   ", depth[join.From]), join.To, join.Condition))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderMarkdownReport renders the report as Markdown
+func renderMarkdownReport(response models.QueryResponse) string {
+	var b strings.Builder
+	b.WriteString("### Generated query\n\n")
+	b.WriteString("```sql\n" + response.Query + "\n```\n\n")
+	fmt.Fprintf(&b, "**Confidence:** %.1f%%", response.Confidence)
+	if response.TraceID != "" {
+		fmt.Fprintf(&b, " · **Trace ID:** `%s`", response.TraceID)
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString("#### Matched fields\n\n")
+	b.WriteString("| Table | Column | Score | Description |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, match := range response.MatchedFields {
+		fmt.Fprintf(&b, "| %s | %s | %.1f | %s |\n",
+			markdownCell(match.TableName), markdownCell(match.ColumnName),
+			match.MatchScore, markdownCell(match.FieldDescription))
+	}
+
+	if diagram := joinDiagram(response); diagram != "" {
+		b.WriteString("\n#### Joins\n\n```text\n" + diagram + "\n```\n")
+	}
+
+	if len(response.Warnings) > 0 {
+		b.WriteString("\n#### Warnings\n\n")
+		for _, warning := range response.Warnings {
+			b.WriteString("- " + warning + "\n")
+		}
+	}
+	return b.String()
+}
+
+// markdownCell escapes text for use inside a Markdown table cell
+func markdownCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", `\|`), "\n", " ")
+}
+
+// reportData is the HTML report's template input
+type reportData struct {
+	models.QueryResponse
+	Diagram string
+}
+
+// htmlReport renders the report as an HTML fragment; html/template escapes
+// every value
+var htmlReport = template.Must(template.New("report").Parse(`<section class="query-report">
+<h3>Generated query</h3>
+<pre><code class="language-sql">{{.Query}}</code></pre>
+<p><strong>Confidence:</strong> {{printf "%.1f" .Confidence}}%{{if .TraceID}} · <strong>Trace ID:</strong> <code>{{.TraceID}}</code>{{end}}</p>
+<h4>Matched fields</h4>
+<table>
+<thead><tr><th>Table</th><th>Column</th><th>Score</th><th>Description</th></tr></thead>
+<tbody>
+{{- range .MatchedFields}}
+<tr><td>{{.TableName}}</td><td>{{.ColumnName}}</td><td>{{printf "%.1f" .MatchScore}}</td><td>{{.FieldDescription}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- if .Diagram}}
+<h4>Joins</h4>
+<pre>{{.Diagram}}</pre>
+{{- end}}
+{{- if .Warnings}}
+<h4>Warnings</h4>
+<ul>
+{{- range .Warnings}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</section>
+`))
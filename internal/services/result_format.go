@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Result formats of executed queries: a JSON response with the rows as
+// arrays, or the rows streamed as CSV or as a JSON object per line
+const (
+	ResultFormatJSON   = "json"
+	ResultFormatCSV    = "csv"
+	ResultFormatNDJSON = "ndjson"
+)
+
+// resultMediaTypes are the Accept header media types of the streamed
+// result formats
+var resultMediaTypes = map[string]string{
+	"text/csv":             ResultFormatCSV,
+	"application/csv":      ResultFormatCSV,
+	"application/x-ndjson": ResultFormatNDJSON,
+	"application/ndjson":   ResultFormatNDJSON,
+	"application/jsonl":    ResultFormatNDJSON,
+}
+
+// ResultFormat picks the format of an executed query's result: the
+// request's result_format when set, otherwise the first CSV or NDJSON
+// media type the Accept header lists, otherwise JSON
+func ResultFormat(requested, accept string) (string, error) {
+	switch strings.ToLower(requested) {
+	case "":
+	case ResultFormatJSON, ResultFormatCSV, ResultFormatNDJSON:
+		return strings.ToLower(requested), nil
+	default:
+		return "", fmt.Errorf("%w %q: use json, csv, or ndjson", ErrUnknownFormat, requested)
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if format, exists := resultMediaTypes[mediaType]; err == nil && exists {
+			return format, nil
+		}
+	}
+	return ResultFormatJSON, nil
+}
+
+// ResultContentType is the content type of a streamed result format
+func ResultContentType(format string) string {
+	if format == ResultFormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// ResultWriter writes a result as its rows are read: Begin with its
+// columns, a WriteRow per row, then End
+type ResultWriter interface {
+	Begin(columns []string) error
+	WriteRow(row []interface{}) error
+	End() error
+}
+
+// NewResultWriter writes results to w in a streamed format, csv or ndjson.
+// It returns nil for json, whose rows are collected in the response
+func NewResultWriter(format string, w io.Writer) ResultWriter {
+	switch format {
+	case ResultFormatCSV:
+		return &csvResultWriter{w: csv.NewWriter(w)}
+	case ResultFormatNDJSON:
+		return &ndjsonResultWriter{w: bufio.NewWriter(w)}
+	}
+	return nil
+}
+
+// csvResultWriter writes a header row of the column names, then the rows
+type csvResultWriter struct {
+	w *csv.Writer
+}
+
+func (r *csvResultWriter) Begin(columns []string) error {
+	return r.w.Write(columns)
+}
+
+func (r *csvResultWriter) WriteRow(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = resultCell(value)
+	}
+	return r.w.Write(record)
+}
+
+func (r *csvResultWriter) End() error {
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// resultCell renders a value as a CSV cell: NULL is empty and times are
+// RFC 3339
+func resultCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ndjsonResultWriter writes each row as a JSON object, its keys the
+// columns in result order
+type ndjsonResultWriter struct {
+	w       *bufio.Writer
+	columns [][]byte
+}
+
+func (r *ndjsonResultWriter) Begin(columns []string) error {
+	r.columns = make([][]byte, len(columns))
+	for i, column := range columns {
+		r.columns[i], _ = json.Marshal(column)
+	}
+	return nil
+}
+
+func (r *ndjsonResultWriter) WriteRow(row []interface{}) error {
+	r.w.WriteByte('{')
+	for i, value := range row {
+		if i > 0 {
+			r.w.WriteByte(',')
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode row: %w", err)
+		}
+		r.w.Write(r.columns[i])
+		r.w.WriteByte(':')
+		r.w.Write(encoded)
+	}
+	_, err := r.w.WriteString("}\n")
+	return err
+}
+
+func (r *ndjsonResultWriter) End() error {
+	return r.w.Flush()
+}
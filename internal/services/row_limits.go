@@ -0,0 +1,32 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// enforceRowLimit applies the configured default LIMIT to a plan asking for
+// none and caps its limit at the configured maximum, returning a note when
+// it changed the limit. Plans aggregating every row into one are left alone
+func (s *QueryService) enforceRowLimit(plan *models.QueryPlan) string {
+	if len(plan.GroupBy) == 0 && planAggregate(*plan) != "" {
+		return ""
+	}
+	switch maximum := s.cfg.MaxLimit; {
+	case plan.Limit == 0 && s.cfg.DefaultLimit > 0:
+		plan.Limit = s.cfg.DefaultLimit
+		if maximum > 0 && plan.Limit > maximum {
+			plan.Limit = maximum
+		}
+		return fmt.Sprintf("no limit was requested, so the default LIMIT %d was applied", plan.Limit)
+	case plan.Limit == 0 && maximum > 0:
+		plan.Limit = maximum
+		return fmt.Sprintf("no limit was requested, so the maximum LIMIT %d was applied", plan.Limit)
+	case maximum > 0 && plan.Limit > maximum:
+		requested := plan.Limit
+		plan.Limit = maximum
+		return fmt.Sprintf("the requested limit of %d was lowered to the maximum of %d", requested, maximum)
+	}
+	return ""
+}
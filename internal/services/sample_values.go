@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Sample sizes, when not requested and at most
+const (
+	defaultSampleValues = 10
+	maxSampleValues     = 100
+)
+
+// ErrUnknownField is returned for a table and column that aren't mapped
+var ErrUnknownField = errors.New("unknown field")
+
+// ErrNotSampleable is returned for fields whose values aren't sampled:
+// measures, which aren't columns, and sensitive fields
+var ErrNotSampleable = errors.New("field values can't be sampled")
+
+// SampleValues reads a handful of a field's distinct non-null values from
+// the database its system's queries run on, so callers can phrase filter
+// values as they are stored. The field must be cleared for the caller's
+// clearance, and the read runs within the executor's caps like any other
+// executed query. Fields of aggregate-only tables aren't sampled
+func (s *QueryService) SampleValues(executor *Executor, request models.SampleValuesRequest) (models.SampleValuesResponse, error) {
+	conn, _, err := executor.connection(request.System)
+	if err != nil {
+		return models.SampleValuesResponse{}, err
+	}
+	field, exists := s.fieldService.LookupField(request.Table, request.Column)
+	if !exists {
+		return models.SampleValuesResponse{}, fmt.Errorf("%w %s.%s", ErrUnknownField, request.Table, request.Column)
+	}
+	if level := classificationLevel(field.Classification); !cleared(s.clearance(request.Clearance), level) {
+		return models.SampleValuesResponse{}, fmt.Errorf("%w: %s.%s is classified %s", ErrInsufficientClearance, request.Table, request.Column, level)
+	}
+	// Distinct values are row-level reads, which aggregate-only tables refuse
+	if restricted := s.policies.aggregateOnly([]string{request.Table}); len(restricted) > 0 {
+		return models.SampleValuesResponse{}, fmt.Errorf("%w: %s may only be queried with aggregates, so its values can't be sampled", ErrAggregateOnly, request.Table)
+	}
+	switch {
+	case field.Measure != "":
+		return models.SampleValuesResponse{}, fmt.Errorf("%w: %s.%s is a measure", ErrNotSampleable, request.Table, request.Column)
+	case field.Sensitive:
+		return models.SampleValuesResponse{}, fmt.Errorf("%w: %s.%s is sensitive", ErrNotSampleable, request.Table, request.Column)
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultSampleValues
+	}
+	if limit > maxSampleValues {
+		limit = maxSampleValues
+	}
+	query := s.sampleQuery(conn.driver, request.System, request.Table, request.Column, limit)
+
+	result, err := executor.Run(request.System, query, nil)
+	if err != nil {
+		return models.SampleValuesResponse{}, err
+	}
+	values := make([]interface{}, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		values = append(values, row[0])
+	}
+	return models.SampleValuesResponse{
+		Table:      request.Table,
+		Column:     request.Column,
+		Query:      query,
+		Connection: result.Connection,
+		Values:     values,
+	}, nil
+}
+
+// sampleQuery renders the query reading distinct values of a field with
+// the system's physical names, quoted for the connection's driver
+func (s *QueryService) sampleQuery(dialect, system, table, column string, limit int) string {
+	names := s.fieldService.systemNames(system)
+	name := qualifiedTable(dialect, names.table(table), s.fieldService.tableLocations[table])
+	ref := quoteIdent(dialect, names.column(table, column))
+	top, clause := rowLimit(dialect, limit, 0, true)
+	return strings.TrimSpace(fmt.Sprintf("SELECT DISTINCT %s%s FROM %s WHERE %s IS NOT NULL ORDER BY %s %s", top, ref, name, ref, ref, clause))
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnknownSystem is returned for a system other than default, system_a,
+// or system_b
+var ErrUnknownSystem = errors.New("unknown system")
+
+// SchemaDriftMonitor compares the live catalog with the database's
+// information_schema, on demand and on a timer, so mappings that went
+// stale when the database changed are caught before queries fail
+type SchemaDriftMonitor struct {
+	schema    *LiveSchema
+	estimator *Estimator
+	executor  *Executor
+}
+
+// NewSchemaDriftMonitor compares the schema with each system's own
+// connection, when it has one, and otherwise with the estimator's default
+// database (DATABASE_URL); with neither a check returns ErrNoDatabase
+func NewSchemaDriftMonitor(schema *LiveSchema, estimator *Estimator, executor *Executor) *SchemaDriftMonitor {
+	return &SchemaDriftMonitor{schema: schema, estimator: estimator, executor: executor}
+}
+
+// Check reads the columns of the mapped tables from information_schema
+// and reports, under a system's physical names, the mapped tables and
+// columns the database lacks and the database columns the catalog doesn't
+// map. Measures aren't columns and aren't compared
+func (m *SchemaDriftMonitor) Check(system string) (models.SchemaDriftReport, error) {
+	system = strings.ToLower(strings.TrimSpace(system))
+	switch system {
+	case "default", SystemA, SystemB:
+	default:
+		return models.SchemaDriftReport{}, fmt.Errorf("%w %q: use default, system_a, or system_b", ErrUnknownSystem, system)
+	}
+	db, driver, schemaName := m.database(system)
+	if db == nil {
+		return models.SchemaDriftReport{}, fmt.Errorf("%w for system %q", ErrNoDatabase, system)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), estimateTimeout)
+	defer cancel()
+
+	fields := m.schema.Fields()
+	names := fields.systemNames(system)
+	var tables []string
+	for _, field := range fields.fields {
+		tables = append(tables, names.table(field.TableName))
+	}
+	existing, err := databaseColumns(ctx, db, driver, schemaName, tables)
+	if err != nil {
+		return models.SchemaDriftReport{}, err
+	}
+	return fields.schemaDrift(system, existing), nil
+}
+
+// database picks the database a system is compared with: its own
+// execution or estimation connection, in the connection's default schema,
+// else DATABASE_URL in the configured schema
+func (m *SchemaDriftMonitor) database(system string) (*sql.DB, string, string) {
+	if conn := m.executor.ownConnection(system); conn != nil {
+		return conn.db, conn.driver, ""
+	}
+	conn, _, schema, err := m.estimator.connection(system)
+	if err != nil {
+		return nil, "", ""
+	}
+	return conn.db, conn.driver, schema
+}
+
+// watched lists the checks a timer runs: the default names against the
+// default database, and each system with a connection of its own
+func (m *SchemaDriftMonitor) watched() []string {
+	var systems []string
+	if db, _, _ := m.database("default"); db != nil {
+		systems = append(systems, "default")
+	}
+	for _, system := range []string{SystemA, SystemB} {
+		if m.executor.ownConnection(system) != nil || m.estimator.ownConnection(system) != nil {
+			systems = append(systems, system)
+		}
+	}
+	return systems
+}
+
+// Watch checks the default database and each system's own connection
+// every interval until stop is closed, logging drift when it is found.
+// Failed checks are logged too
+func (m *SchemaDriftMonitor) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, system := range m.watched() {
+				report, err := m.Check(system)
+				log := m.schema.Fields().log.WithField("system", system)
+				switch {
+				case err != nil:
+					log.WithError(err).Warn("Schema drift check failed")
+				case report.Drifted:
+					log.WithFields(logrus.Fields{
+						"version":          report.Version,
+						"missing_tables":   report.MissingTables,
+						"missing_columns":  len(report.MissingColumns),
+						"unmapped_columns": len(report.UnmappedColumns),
+					}).Warn("Catalog has drifted from the database schema")
+				}
+			}
+		}
+	}
+}
+
+// schemaDrift compares the catalog, under a system's names, with the
+// columns information_schema lists by table
+func (s *FieldService) schemaDrift(system string, existing map[string]map[string]bool) models.SchemaDriftReport {
+	report := models.SchemaDriftReport{Version: s.Version(), System: system, CheckedAt: time.Now().UTC()}
+	names := s.systemNames(system)
+	mapped := make(map[string]map[string]bool)
+	missingTables := make(map[string]bool)
+	for _, field := range s.fields {
+		if field.Measure != "" {
+			continue
+		}
+		table, column := names.table(field.TableName), names.column(field.TableName, field.ColumnName)
+		physical := physicalName(table)
+		if mapped[physical] == nil {
+			mapped[physical] = make(map[string]bool)
+		}
+		mapped[physical][strings.ToLower(column)] = true
+
+		columns, exists := existing[physical]
+		switch {
+		case !exists:
+			missingTables[table] = true
+		case !columns[strings.ToLower(column)]:
+			report.MissingColumns = append(report.MissingColumns, models.MissingColumn{
+				Field:  fieldKey(field.TableName, field.ColumnName),
+				Table:  table,
+				Column: column,
+			})
+		}
+	}
+	for table := range missingTables {
+		report.MissingTables = append(report.MissingTables, table)
+	}
+	for table, columns := range existing {
+		for column := range columns {
+			if mapped[table] != nil && !mapped[table][column] {
+				report.UnmappedColumns = append(report.UnmappedColumns, models.DatabaseColumn{Table: table, Column: column})
+			}
+		}
+	}
+
+	sort.Strings(report.MissingTables)
+	sort.Slice(report.MissingColumns, func(i, j int) bool {
+		return report.MissingColumns[i].Field < report.MissingColumns[j].Field
+	})
+	sort.Slice(report.UnmappedColumns, func(i, j int) bool {
+		a, b := report.UnmappedColumns[i], report.UnmappedColumns[j]
+		return a.Table < b.Table || (a.Table == b.Table && a.Column < b.Column)
+	})
+	report.Drifted = len(report.MissingTables) > 0 || len(report.MissingColumns) > 0 || len(report.UnmappedColumns) > 0
+	return report
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaDefinition is the YAML/JSON alternative to the mapping CSV, nesting
+// columns under their tables
+type SchemaDefinition struct {
+	Tables []TableDefinition `json:"tables" yaml:"tables"`
+}
+
+// TableDefinition describes a table and the metadata shared by its columns.
+// Schema and Database qualify the table in generated SQL; Synonyms holds
+// the per-system table names, as columns' Synonyms do; DbtRef is the ref()
+// or source() dbt models read it through
+type TableDefinition struct {
+	Name           string             `json:"name" yaml:"name"`
+	Alias          string             `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Schema         string             `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Database       string             `json:"database,omitempty" yaml:"database,omitempty"`
+	Synonyms       map[string]string  `json:"synonyms,omitempty" yaml:"synonyms,omitempty"`
+	Owner          string             `json:"owner,omitempty" yaml:"owner,omitempty"`
+	OwnerContact   string             `json:"owner_contact,omitempty" yaml:"owner_contact,omitempty"`
+	RefreshCadence string             `json:"refresh_cadence,omitempty" yaml:"refresh_cadence,omitempty"`
+	FreshnessSLA   string             `json:"freshness_sla,omitempty" yaml:"freshness_sla,omitempty"`
+	Tags           []string           `json:"tags,omitempty" yaml:"tags,omitempty"`
+	DbtRef         string             `json:"dbt_ref,omitempty" yaml:"dbt_ref,omitempty"`
+	Columns        []ColumnDefinition `json:"columns" yaml:"columns"`
+}
+
+// ColumnDefinition describes one column. Synonyms holds the per-system
+// names (system_a, system_b); References is the joined table.column
+type ColumnDefinition struct {
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description" yaml:"description"`
+	Type        string            `json:"type,omitempty" yaml:"type,omitempty"`
+	Synonyms    map[string]string `json:"synonyms,omitempty" yaml:"synonyms,omitempty"`
+	References  string            `json:"references,omitempty" yaml:"references,omitempty"`
+	// JoinGroup joins this reference together with the table's others in
+	// the same group, as one composite key
+	JoinGroup string `json:"join_group,omitempty" yaml:"join_group,omitempty"`
+	// Cardinality and Nullable describe the reference, as the mapping CSV's
+	// cardinality and nullable columns do
+	Cardinality string `json:"cardinality,omitempty" yaml:"cardinality,omitempty"`
+	Nullable    bool   `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	// JoinType is left for an optional reference, joined with a LEFT JOIN
+	JoinType string `json:"join_type,omitempty" yaml:"join_type,omitempty"`
+	// JoinWeight is the reference's cost in join planning; lower is preferred
+	JoinWeight float64  `json:"join_weight,omitempty" yaml:"join_weight,omitempty"`
+	Tags       []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Deprecated bool     `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Sensitive  bool     `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+	// Classification is public, internal, or restricted
+	Classification string `json:"classification,omitempty" yaml:"classification,omitempty"`
+	GlossaryTerm   string `json:"glossary_term,omitempty" yaml:"glossary_term,omitempty"`
+	// Measure is the aggregate SQL of a metric, used in place of the column
+	Measure string `json:"measure,omitempty" yaml:"measure,omitempty"`
+}
+
+// isSchemaFile reports whether a mapping path is a YAML, JSON, LookML, Avro,
+// or Parquet schema file rather than a CSV
+func isSchemaFile(path string) bool {
+	switch mappingExt(path) {
+	case ".yaml", ".yml", ".json", ".lkml", ".avsc", ".avro", ".parquet":
+		return true
+	}
+	return false
+}
+
+// readMappingFile reads fields from a mapping CSV or a schema file, chosen by
+// extension, along with the mapping CSV they are versioned as
+func (s *FieldService) readMappingFile(path string) ([]models.Field, []byte, error) {
+	if isSchemaFile(path) {
+		return s.readSchemaFile(path)
+	}
+	return s.readCSV(path)
+}
+
+// readSchemaFile reads fields from a YAML or JSON schema definition, a dbt
+// manifest, LookML, or a data-lake file's Avro or Parquet schema
+func (s *FieldService) readSchemaFile(path string) ([]models.Field, []byte, error) {
+	var data []byte
+	var err error
+	if mappingExt(path) == ".parquet" && !isRemotePath(path) {
+		// The schema is in the footer; the row data can be skipped
+		data, err = readParquetTail(path)
+	} else {
+		data, err = s.readSource(path)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open schema file: %w", err)
+	}
+
+	var definition SchemaDefinition
+	switch mappingExt(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &definition)
+	case ".lkml":
+		definition, err = readLookML(path, data)
+	case ".avsc", ".avro":
+		definition, err = readAvroSchema(path, data)
+	case ".parquet":
+		definition, err = readParquetSchema(path, data)
+	default:
+		// dbt manifests are imported; other JSON files are schema definitions
+		if isDbtManifest(data) {
+			definition, err = readDbtManifest(path, data)
+		} else {
+			err = json.Unmarshal(data, &definition)
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse schema file: %w", err)
+	}
+
+	fields, err := definition.Fields()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Keep the schema as a mapping CSV so versions are saved in one format
+	return fields, encodeFieldsCSV(fields), nil
+}
+
+// Fields flattens the definition into fields. Column tags add to their
+// table's tags
+func (d SchemaDefinition) Fields() ([]models.Field, error) {
+	fields := make([]models.Field, 0)
+	for _, table := range d.Tables {
+		if table.Name == "" {
+			return nil, fmt.Errorf("schema table without a name")
+		}
+		for _, column := range table.Columns {
+			if column.Name == "" {
+				return nil, fmt.Errorf("schema table %s has a column without a name", table.Name)
+			}
+			field := models.Field{
+				ColumnName:      column.Name,
+				TableName:       table.Name,
+				SystemAFieldMap: column.Synonyms["system_a"],
+				SystemBFieldMap: column.Synonyms["system_b"],
+				SystemATableMap: table.Synonyms["system_a"],
+				SystemBTableMap: table.Synonyms["system_b"],
+				Description:     column.Description,
+				FieldType:       column.Type,
+				Owner:           table.Owner,
+				OwnerContact:    table.OwnerContact,
+				RefreshCadence:  strings.ToLower(table.RefreshCadence),
+				FreshnessSLA:    table.FreshnessSLA,
+				Tags:            parseTags(strings.Join(append(append([]string{}, table.Tags...), column.Tags...), ",")),
+				Deprecated:      column.Deprecated,
+				GlossaryTerm:    column.GlossaryTerm,
+				Sensitive:       column.Sensitive,
+				TableAlias:      table.Alias,
+				SchemaName:      table.Schema,
+				DatabaseName:    table.Database,
+				Classification:  strings.ToLower(column.Classification),
+				Measure:         column.Measure,
+				DbtRef:          table.DbtRef,
+			}
+			if column.References != "" {
+				foreignTable, foreignKey, found := strings.Cut(column.References, ".")
+				if !found || foreignTable == "" || foreignKey == "" {
+					return nil, fmt.Errorf("column %s.%s references %q; use table.column", table.Name, column.Name, column.References)
+				}
+				field.JoinKey = column.Name
+				field.ForeignTable = foreignTable
+				field.ForeignKey = foreignKey
+				field.JoinGroup = column.JoinGroup
+				field.Cardinality = parseCardinality(column.Cardinality)
+				field.Nullable = column.Nullable
+				field.JoinType = parseJoinType(column.JoinType)
+				if column.JoinWeight > 0 {
+					field.JoinWeight = column.JoinWeight
+				}
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
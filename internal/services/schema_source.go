@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql" // MySQL/MariaDB driver for schema introspection
+	_ "github.com/lib/pq"              // Postgres driver for schema introspection
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Supported field sources
+const (
+	SchemaSourceCSV      = "csv"
+	SchemaSourcePostgres = "postgres"
+	SchemaSourceMySQL    = "mysql"
+)
+
+// introspectionTimeout bounds connecting to and reading a live schema
+const introspectionTimeout = 30 * time.Second
+
+// mappingColumns is the full mapping CSV header, used when a schema read from
+// a database is kept as a mapping file
+var mappingColumns = append(append([]string{}, requiredColumns...),
+	"owner", "owner_contact", "refresh_cadence", "freshness_sla",
+	"tags", "deprecated", "glossary_term", "sensitive", "table_alias",
+	"classification", "measure", "join_group", "cardinality", "nullable",
+	"join_type", "join_weight", "schema_name", "database_name",
+	"system_a_tablemap", "system_b_tablemap", "dbt_ref",
+)
+
+// loadFields loads the field list from the configured source
+func (s *FieldService) loadFields() error {
+	switch s.cfg.SchemaSource {
+	case "", SchemaSourceCSV:
+		if err := s.loadMappings(s.cfg.CSVPath); err != nil {
+			return fmt.Errorf("failed to load mappings: %w", err)
+		}
+	case SchemaSourcePostgres:
+		if err := s.loadDatabase("postgres", IntrospectPostgres); err != nil {
+			return fmt.Errorf("failed to introspect Postgres schema: %w", err)
+		}
+	case SchemaSourceMySQL:
+		if err := s.loadDatabase("mysql", IntrospectMySQL); err != nil {
+			return fmt.Errorf("failed to introspect MySQL schema: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown schema source %q", s.cfg.SchemaSource)
+	}
+	return nil
+}
+
+// loadDatabase reads fields from a live database with the given introspector
+func (s *FieldService) loadDatabase(driver string, introspect func(context.Context, *sql.DB, string) ([]models.Field, error)) error {
+	if s.cfg.DatabaseURL == "" {
+		return fmt.Errorf("DATABASE_URL is required for schema source %s", s.cfg.SchemaSource)
+	}
+	db, err := sql.Open(driver, s.cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), introspectionTimeout)
+	defer cancel()
+	fields, err := introspect(ctx, db, s.cfg.DatabaseSchema)
+	if err != nil {
+		return err
+	}
+
+	// Keep the introspected schema as a mapping file so versions can be saved
+	s.fields = fields
+	s.setSource(encodeFieldsCSV(fields))
+	s.log.Infof("Loaded %d fields from %s schema", len(fields), s.cfg.SchemaSource)
+	return nil
+}
+
+// IntrospectPostgres builds fields from a Postgres schema's columns, column
+// comments, and foreign key constraints. An empty schema means "public"
+func IntrospectPostgres(ctx context.Context, db *sql.DB, schema string) ([]models.Field, error) {
+	if schema == "" {
+		schema = "public"
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.table_name, c.column_name, c.data_type,
+		       COALESCE(col_description(format('%I.%I', c.table_schema, c.table_name)::regclass, c.ordinal_position), '')
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+		  ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = $1 AND t.table_type = 'BASE TABLE'
+		ORDER BY c.table_name, c.ordinal_position`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	fields, err := scanColumns(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT kcu.table_name, kcu.column_name, ccu.table_name, ccu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+		  ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+		JOIN information_schema.constraint_column_usage ccu
+		  ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = $1`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+	if err := applyForeignKeys(rows, fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// IntrospectMySQL builds fields from a MySQL or MariaDB database's columns,
+// column comments, and foreign key constraints. An empty schema means the
+// database named in the connection string
+func IntrospectMySQL(ctx context.Context, db *sql.DB, schema string) ([]models.Field, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE, c.COLUMN_COMMENT
+		FROM information_schema.COLUMNS c
+		JOIN information_schema.TABLES t
+		  ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+		WHERE c.TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND t.TABLE_TYPE = 'BASE TABLE'
+		ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	fields, err := scanColumns(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND REFERENCED_TABLE_NAME IS NOT NULL`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+	if err := applyForeignKeys(rows, fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// scanColumns reads (table, column, type, comment) rows into fields. Columns
+// without a comment are described by their humanized names so keyword
+// matching still has something to work with
+func scanColumns(rows *sql.Rows) ([]models.Field, error) {
+	defer rows.Close()
+
+	fields := make([]models.Field, 0)
+	for rows.Next() {
+		var field models.Field
+		if err := rows.Scan(&field.TableName, &field.ColumnName, &field.FieldType, &field.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		field.FieldType = strings.ToUpper(field.FieldType)
+		if strings.TrimSpace(field.Description) == "" {
+			field.Description = humanize(field.ColumnName) + " of " + humanize(field.TableName)
+		}
+		fields = append(fields, field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	return fields, nil
+}
+
+// applyForeignKeys reads (table, column, foreign table, foreign column) rows
+// and records them as join metadata on the matching fields
+func applyForeignKeys(rows *sql.Rows, fields []models.Field) error {
+	defer rows.Close()
+
+	index := make(map[string]int, len(fields))
+	for i, field := range fields {
+		index[fieldKey(field.TableName, field.ColumnName)] = i
+	}
+	for rows.Next() {
+		var table, column, foreignTable, foreignColumn string
+		if err := rows.Scan(&table, &column, &foreignTable, &foreignColumn); err != nil {
+			return fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		if i, exists := index[fieldKey(table, column)]; exists {
+			fields[i].JoinKey = column
+			fields[i].ForeignTable = foreignTable
+			fields[i].ForeignKey = foreignColumn
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+	return nil
+}
+
+// humanize turns an identifier like order_items into "order items"
+func humanize(identifier string) string {
+	return strings.ReplaceAll(identifier, "_", " ")
+}
+
+// encodeFieldsCSV renders fields as a mapping CSV with every known column
+func encodeFieldsCSV(fields []models.Field) []byte {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write(mappingColumns)
+	for _, field := range fields {
+		_ = writer.Write([]string{
+			field.ColumnName, field.TableName, field.SystemAFieldMap, field.SystemBFieldMap,
+			field.Description, field.FieldType, field.JoinKey, field.ForeignTable, field.ForeignKey,
+			field.Owner, field.OwnerContact, field.RefreshCadence, field.FreshnessSLA,
+			strings.Join(field.Tags, ","), formatFlag(field.Deprecated), field.GlossaryTerm, formatFlag(field.Sensitive),
+			field.TableAlias, field.Classification, field.Measure, field.JoinGroup,
+			field.Cardinality, formatFlag(field.Nullable), field.JoinType, formatJoinWeight(field.JoinWeight),
+			field.SchemaName, field.DatabaseName, field.SystemATableMap, field.SystemBTableMap, field.DbtRef,
+		})
+	}
+	writer.Flush()
+	return buf.Bytes()
+}
+
+// formatFlag writes a boolean CSV cell, leaving false blank
+func formatFlag(value bool) string {
+	if value {
+		return "true"
+	}
+	return ""
+}
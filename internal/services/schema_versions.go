@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// defaultSchemaHistory is how many schema versions are kept in memory when
+// not configured
+const defaultSchemaHistory = 5
+
+// ErrUnknownSchemaVersion is returned for a schema version that was never
+// loaded or has been evicted
+var ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+// schemaVersion is a loaded mapping version
+type schemaVersion struct {
+	service  *FieldService
+	loadedAt time.Time
+}
+
+// SchemaVersions keeps prior schema versions so queries can be generated
+// against the mapping that was live at the time. Versions are held in memory
+// and, when a history directory is configured, on disk so they survive
+// restarts
+type SchemaVersions struct {
+	mu       sync.Mutex
+	cfg      *config.Config
+	dir      string
+	limit    int
+	current  string
+	order    []string
+	versions map[string]*schemaVersion
+}
+
+// NewSchemaVersions starts a history with the current field service
+func NewSchemaVersions(cfg *config.Config, current *FieldService) (*SchemaVersions, error) {
+	limit := cfg.SchemaHistory
+	if limit <= 0 {
+		limit = defaultSchemaHistory
+	}
+	v := &SchemaVersions{
+		cfg:      cfg,
+		dir:      cfg.SchemaHistoryDir,
+		limit:    limit,
+		versions: make(map[string]*schemaVersion),
+	}
+	if v.dir != "" {
+		if err := os.MkdirAll(v.dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create schema history directory: %w", err)
+		}
+	}
+	if err := v.Record(current); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Record adds a newly loaded field service as the current version
+func (v *SchemaVersions) Record(service *FieldService) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.dir != "" {
+		path := v.path(service.Version())
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if err := os.WriteFile(path, service.source, 0o644); err != nil {
+				return fmt.Errorf("failed to save schema version: %w", err)
+			}
+		}
+	}
+
+	v.current = service.Version()
+	v.add(service)
+	return nil
+}
+
+// Current returns the current schema version
+func (v *SchemaVersions) Current() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.current
+}
+
+// Get returns the field service for a version, reloading it from the
+// history directory if it is no longer in memory
+func (v *SchemaVersions) Get(version string) (*FieldService, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if loaded, exists := v.versions[version]; exists {
+		return loaded.service, nil
+	}
+
+	// Versions are hex digests; anything else cannot name a history file
+	if v.dir == "" || version == "" || strings.Trim(version, "0123456789abcdef") != "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSchemaVersion, version)
+	}
+	path := v.path(version)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSchemaVersion, version)
+	}
+
+	cfg := *v.cfg
+	cfg.SchemaSource = SchemaSourceCSV
+	cfg.CSVPath = path
+	service, err := NewFieldService(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema version %s: %w", version, err)
+	}
+	v.add(service)
+	return service, nil
+}
+
+// List describes the versions available, newest first
+func (v *SchemaVersions) List() []models.SchemaVersion {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	seen := make(map[string]bool)
+	list := make([]models.SchemaVersion, 0, len(v.order))
+	for i := len(v.order) - 1; i >= 0; i-- {
+		id := v.order[i]
+		seen[id] = true
+		list = append(list, models.SchemaVersion{
+			Version:  id,
+			LoadedAt: v.versions[id].loadedAt,
+			Fields:   len(v.versions[id].service.fields),
+			Current:  id == v.current,
+			InMemory: true,
+		})
+	}
+
+	// Versions only on disk, by file modification time
+	if v.dir != "" {
+		paths, _ := filepath.Glob(filepath.Join(v.dir, "*.csv"))
+		var onDisk []models.SchemaVersion
+		for _, path := range paths {
+			id := strings.TrimSuffix(filepath.Base(path), ".csv")
+			if seen[id] {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			onDisk = append(onDisk, models.SchemaVersion{Version: id, LoadedAt: info.ModTime()})
+		}
+		sort.Slice(onDisk, func(i, j int) bool { return onDisk[i].LoadedAt.After(onDisk[j].LoadedAt) })
+		list = append(list, onDisk...)
+	}
+	return list
+}
+
+// add caches a version in memory, evicting the oldest non-current versions
+// beyond the limit. Callers hold the lock
+func (v *SchemaVersions) add(service *FieldService) {
+	id := service.Version()
+	if _, exists := v.versions[id]; exists {
+		// Move to the newest position
+		for i, existing := range v.order {
+			if existing == id {
+				v.order = append(v.order[:i], v.order[i+1:]...)
+				break
+			}
+		}
+	}
+	v.versions[id] = &schemaVersion{service: service, loadedAt: time.Now()}
+	v.order = append(v.order, id)
+
+	for len(v.order) > v.limit {
+		evict := 0
+		if v.order[0] == v.current {
+			evict = 1
+		}
+		delete(v.versions, v.order[evict])
+		v.order = append(v.order[:evict], v.order[evict+1:]...)
+	}
+}
+
+// path is where a version's mapping file is kept on disk
+func (v *SchemaVersions) path(version string) string {
+	return filepath.Join(v.dir, version+".csv")
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Schema self-check issue codes, alongside the mapping validation codes
+const (
+	IssueLoadFailed         = "load_failed"
+	IssueSkippedRows        = "skipped_rows"
+	IssueConflict           = "conflicting_definition"
+	IssueDisconnectedTables = "disconnected_tables"
+)
+
+// CheckSchema loads the configured schema and self-checks it, reporting a
+// load failure as an issue rather than an error
+func CheckSchema(cfg *config.Config) models.SchemaCheck {
+	fieldService, err := NewFieldService(cfg)
+	if err != nil {
+		check := models.SchemaCheck{Components: make([][]string, 0)}
+		check.Issues = []models.ValidationIssue{{Severity: SeverityError, Code: IssueLoadFailed, Message: err.Error()}}
+		check.ValidationReport = check.ValidationReport.Finish()
+		return check
+	}
+	return fieldService.SelfCheck()
+}
+
+// SelfCheck reports problems with the loaded schema: skipped rows, conflicting
+// definitions across mapping files, joins to tables with no fields, and tables
+// that no join path connects
+func (s *FieldService) SelfCheck() models.SchemaCheck {
+	check := models.SchemaCheck{
+		Version:       s.version,
+		Relationships: s.relationshipCount(),
+	}
+	check.Rows, check.Fields = len(s.fields)+s.skippedRows, len(s.fields)
+	check.Issues = make([]models.ValidationIssue, 0)
+	add := func(issue models.ValidationIssue) {
+		check.Issues = append(check.Issues, issue)
+	}
+
+	if len(s.fields) == 0 {
+		add(models.ValidationIssue{Severity: SeverityError, Code: IssueNoFields, Message: "schema defines no fields"})
+	}
+	if s.skippedRows > 0 {
+		add(models.ValidationIssue{Severity: SeverityError, Code: IssueSkippedRows,
+			Message: fmt.Sprintf("%d mapping rows were skipped for having too few columns", s.skippedRows)})
+	}
+	for _, diagnostic := range s.mergeDiagnostics {
+		if diagnostic.Conflict {
+			add(models.ValidationIssue{Table: diagnostic.Table, Column: diagnostic.Column, Severity: SeverityWarning, Code: IssueConflict,
+				Message: fmt.Sprintf("defined differently in %s (%s); the first definition is used",
+					strings.Join(diagnostic.Files, ", "), strings.Join(diagnostic.Attributes, ", "))})
+		}
+	}
+
+	// Every table with fields, plus any only named as a join target
+	tables := make(map[string]bool)
+	for _, field := range s.fields {
+		tables[field.TableName] = true
+	}
+	for _, field := range s.fields {
+		if field.ForeignTable != "" && !tables[field.ForeignTable] {
+			add(models.ValidationIssue{Table: field.TableName, Column: field.ColumnName, Severity: SeverityError, Code: IssueDanglingTable,
+				Message: fmt.Sprintf("foreign_table %s has no fields", field.ForeignTable)})
+		}
+	}
+	for table := range s.relationshipGraph {
+		tables[table] = true
+	}
+	check.Tables = len(tables)
+
+	check.Components = s.graphComponents(tables)
+	check.Connected = len(check.Components) <= 1
+	if !check.Connected {
+		groups := make([]string, 0, len(check.Components))
+		for _, component := range check.Components {
+			groups = append(groups, "["+strings.Join(component, ", ")+"]")
+		}
+		add(models.ValidationIssue{Severity: SeverityWarning, Code: IssueDisconnectedTables,
+			Message: fmt.Sprintf("join graph has %d unconnected groups, so queries cannot span them: %s",
+				len(check.Components), strings.Join(groups, " "))})
+	}
+
+	check.ValidationReport = check.ValidationReport.Finish()
+	return check
+}
+
+// graphComponents splits tables into groups reachable from each other
+// through the join graph, largest first
+func (s *FieldService) graphComponents(tables map[string]bool) [][]string {
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	visited := make(map[string]bool)
+	components := make([][]string, 0)
+	for _, start := range names {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		component := []string{}
+		queue := []string{start}
+		for len(queue) > 0 {
+			table := queue[0]
+			queue = queue[1:]
+			component = append(component, table)
+			for neighbour := range s.relationshipGraph[table] {
+				if !visited[neighbour] {
+					visited[neighbour] = true
+					queue = append(queue, neighbour)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		return len(components[i]) > len(components[j])
+	})
+	return components
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layouts of the generated SQL, chosen with the request's format
+const (
+	FormatCompact = "compact"
+	FormatPretty  = "pretty"
+)
+
+// prettyIndent indents a clause's items under its keyword
+const prettyIndent = "  "
+
+// Keyword cases and AS-keyword usages of an SQLStyle
+const (
+	KeywordCaseUpper = "upper"
+	KeywordCaseLower = "lower"
+
+	AliasKeywordColumns = "columns"
+	AliasKeywordAlways  = "always"
+	AliasKeywordNever   = "never"
+)
+
+// SQLStyle is a team's style guide for generated SQL. KeywordCase is upper
+// or lower, or empty to keep keywords as rendered; LeadingCommas starts
+// the items of pretty lists with their comma; AliasKeyword says where AS
+// introduces an alias: columns (the default), always, tables too, or never
+type SQLStyle struct {
+	KeywordCase   string
+	LeadingCommas bool
+	AliasKeyword  string
+}
+
+// ParseSQLStyle validates the configured SQL style
+func ParseSQLStyle(keywordCase string, leadingCommas bool, aliasKeyword string) (SQLStyle, error) {
+	style := SQLStyle{
+		KeywordCase:   strings.ToLower(strings.TrimSpace(keywordCase)),
+		LeadingCommas: leadingCommas,
+		AliasKeyword:  strings.ToLower(strings.TrimSpace(aliasKeyword)),
+	}
+	switch style.KeywordCase {
+	case "", KeywordCaseUpper, KeywordCaseLower:
+	default:
+		return SQLStyle{}, fmt.Errorf("invalid SQL keyword case %q: use upper or lower", keywordCase)
+	}
+	switch style.AliasKeyword {
+	case "", AliasKeywordColumns, AliasKeywordAlways, AliasKeywordNever:
+	default:
+		return SQLStyle{}, fmt.Errorf("invalid SQL alias keyword usage %q: use columns, always, or never", aliasKeyword)
+	}
+	return style, nil
+}
+
+// restyles reports whether the style changes queries as rendered
+func (s SQLStyle) restyles() bool {
+	return s.KeywordCase != "" || s.AliasKeyword == AliasKeywordAlways || s.AliasKeyword == AliasKeywordNever
+}
+
+// sqlToken is a word, quoted string or identifier, parenthesis, or comma
+// of a rendered query. depth is the parenthesis nesting it sits at
+type sqlToken struct {
+	text  string
+	space bool
+	depth int
+}
+
+// tokenizeSQL splits a rendered query into tokens, keeping quoted strings
+// and identifiers whole by the dialect's rules for where they end
+func tokenizeSQL(query, dialect string) []sqlToken {
+	var tokens []sqlToken
+	depth := 0
+	space := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+		switch {
+		case sqlSpace(c):
+			space = true
+			i++
+			continue
+		case c == '(' || c == ',':
+			i++
+		case c == ')':
+			depth--
+			i++
+		case c == '\'' || c == '"' || c == '`' || (c == '[' && dialect == DialectSQLServer):
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			i = quotedEnd(query, i+1, closing, backslashEscapes(dialect, c))
+		case (c == 'E' || c == 'e') && dialect == DialectPostgres && i+1 < len(query) && query[i+1] == '\'':
+			// Postgres escape strings, E'...', honour backslashes
+			i = quotedEnd(query, i+2, '\'', true)
+		default:
+			for i < len(query) && !sqlSpace(query[i]) && !strings.ContainsRune("(),'\"`", rune(query[i])) &&
+				!(query[i] == '[' && dialect == DialectSQLServer) {
+				i++
+			}
+		}
+		tokens = append(tokens, sqlToken{text: query[start:i], space: space, depth: depth})
+		if c == '(' {
+			depth++
+		}
+		space = false
+	}
+	return tokens
+}
+
+// sqlSpace reports whether c is whitespace to a SQL engine, which ends a
+// word however unusual the character
+func sqlSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\f', '\v':
+		return true
+	}
+	return false
+}
+
+// backslashEscapes reports whether backslashes escape characters between
+// a dialect's quotes: in MySQL strings, either quoted, in BigQuery strings
+// and identifiers, and in Snowflake strings
+func backslashEscapes(dialect string, quote byte) bool {
+	switch dialect {
+	case DialectMySQL:
+		return quote == '\'' || quote == '"'
+	case DialectBigQuery:
+		return true
+	case DialectSnowflake:
+		return quote == '\''
+	}
+	return false
+}
+
+// quotedEnd finds the end of a quoted string or identifier whose contents
+// start at i: just past its closing quote, or the end of the query. A
+// doubled closing quote is an escaped one, as is one after a backslash
+// when backslashes escape
+func quotedEnd(query string, i int, closing byte, escapes bool) int {
+	for ; i < len(query); i++ {
+		if query[i] == '\\' && escapes {
+			i++
+			continue
+		}
+		if query[i] == closing {
+			if i+1 < len(query) && query[i+1] == closing {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// clauseKeywords start a line in pretty SQL; multi-word keywords come
+// first so LEFT JOIN isn't read as LEFT
+var clauseKeywords = [][]string{
+	{"LEFT", "JOIN"}, {"GROUP", "BY"}, {"ORDER", "BY"},
+	{"SELECT"}, {"FROM"}, {"JOIN"}, {"WHERE"}, {"HAVING"}, {"LIMIT"}, {"OFFSET"}, {"FETCH"},
+}
+
+// listClauses put each of their comma-separated items on its own line
+var listClauses = map[string]bool{"SELECT": true, "GROUP BY": true, "ORDER BY": true}
+
+// conditionClauses put each AND-ed condition on its own line
+var conditionClauses = map[string]bool{"WHERE": true, "HAVING": true, "JOIN": true, "LEFT JOIN": true}
+
+// clauseAt returns the clause keyword starting at a top-level token and
+// how many tokens it spans. Matching ignores case
+func clauseAt(tokens []sqlToken, i int) (string, int) {
+	for _, keyword := range clauseKeywords {
+		if i+len(keyword) > len(tokens) {
+			continue
+		}
+		matched := true
+		for j, word := range keyword {
+			if tokens[i+j].depth != 0 || !strings.EqualFold(tokens[i+j].text, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return strings.Join(keyword, " "), len(keyword)
+		}
+	}
+	return "", 0
+}
+
+// tableClauses name a table, optionally followed by its alias
+var tableClauses = map[string]bool{"FROM": true, "JOIN": true, "LEFT JOIN": true}
+
+// typedLiterals are the type keywords that prefix a string literal
+var typedLiterals = map[string]bool{"date": true, "time": true, "timestamp": true, "datetime": true, "interval": true}
+
+// isKeyword reports whether a token is an SQL keyword. Reserved words are
+// always quoted when used as names, so a bare one is a keyword
+func isKeyword(tokens []sqlToken, i int) bool {
+	word := strings.ToLower(tokens[i].text)
+	switch {
+	case reservedWords[word]:
+		return true
+	case word == "next":
+		return i > 0 && strings.EqualFold(tokens[i-1].text, "FETCH")
+	case typedLiterals[word]:
+		return i+1 < len(tokens) && strings.HasPrefix(tokens[i+1].text, "'")
+	}
+	return false
+}
+
+// applyStyle recases keywords and adds or drops the AS before aliases
+func applyStyle(tokens []sqlToken, style SQLStyle) []sqlToken {
+	styled := make([]sqlToken, 0, len(tokens))
+	clause := ""
+	tableAliased := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.depth == 0 {
+			if keyword, n := clauseAt(tokens, i); n > 0 {
+				clause = keyword
+				tableAliased = false
+			} else if strings.EqualFold(token.text, "AS") && style.AliasKeyword == AliasKeywordNever && clause == "SELECT" {
+				continue
+			} else if style.AliasKeyword == AliasKeywordAlways && tableClauses[clause] && !tableAliased && token.space {
+				// The table name follows the keyword, and the next spaced
+				// word is its alias unless it's the join's ON
+				if _, n := clauseAt(tokens, i-1); n == 0 {
+					tableAliased = true
+					if !strings.EqualFold(token.text, "ON") {
+						styled = append(styled, sqlToken{text: style.keyword("AS"), space: true})
+					}
+				}
+			}
+		}
+		if isKeyword(tokens, i) {
+			token.text = style.keyword(token.text)
+		}
+		styled = append(styled, token)
+	}
+	return styled
+}
+
+// keyword cases a keyword as the style asks
+func (s SQLStyle) keyword(word string) string {
+	switch s.KeywordCase {
+	case KeywordCaseUpper:
+		return strings.ToUpper(word)
+	case KeywordCaseLower:
+		return strings.ToLower(word)
+	}
+	return word
+}
+
+// prettySQL lays a query's tokens out a clause per line. SELECT, GROUP BY,
+// and ORDER BY list their items indented on lines of their own, and the
+// AND-ed conditions of WHERE, HAVING, and joins continue on indented lines.
+// Anything in parentheses stays as rendered
+func prettySQL(tokens []sqlToken, leadingCommas bool) string {
+	var b strings.Builder
+	clause := ""
+	separator := ""
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.depth == 0 {
+			if keyword, n := clauseAt(tokens, i); n > 0 {
+				if i > 0 {
+					b.WriteString("\n")
+				}
+				for j := 0; j < n; j++ {
+					if j > 0 {
+						b.WriteString(" ")
+					}
+					b.WriteString(tokens[i+j].text)
+				}
+				i += n - 1
+				clause = keyword
+				separator = ""
+				if listClauses[clause] {
+					// DISTINCT and TOP n stay on the SELECT line
+					for i+1 < len(tokens) && (strings.EqualFold(tokens[i+1].text, "DISTINCT") || strings.EqualFold(tokens[i+1].text, "TOP")) {
+						b.WriteString(" " + tokens[i+1].text)
+						if strings.EqualFold(tokens[i+1].text, "TOP") && i+2 < len(tokens) {
+							b.WriteString(" " + tokens[i+2].text)
+							i++
+						}
+						i++
+					}
+					separator = "\n" + prettyIndent
+				}
+				continue
+			}
+			if token.text == "," && listClauses[clause] {
+				if leadingCommas {
+					separator = "\n" + prettyIndent + ", "
+				} else {
+					b.WriteString(",")
+					separator = "\n" + prettyIndent
+				}
+				continue
+			}
+			if strings.EqualFold(token.text, "AND") && conditionClauses[clause] {
+				separator = "\n" + prettyIndent
+			}
+		}
+		switch {
+		case separator != "":
+			b.WriteString(separator)
+			separator = ""
+		case token.space:
+			b.WriteString(" ")
+		}
+		b.WriteString(token.text)
+	}
+	return b.String()
+}
+
+// compactSQL renders tokens on one line as they were spaced
+func compactSQL(tokens []sqlToken) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		if token.space {
+			b.WriteString(" ")
+		}
+		b.WriteString(token.text)
+	}
+	return b.String()
+}
+
+// formatSQL lays out a rendered query in a requested format, styled as
+// configured; compact keeps the single line it was rendered as
+func formatSQL(query, dialect, format string, style SQLStyle) string {
+	if format != FormatPretty && !style.restyles() {
+		return query
+	}
+	tokens := applyStyle(tokenizeSQL(query, dialect), style)
+	if format == FormatPretty {
+		return prettySQL(tokens, style.LeadingCommas)
+	}
+	return compactSQL(tokens)
+}
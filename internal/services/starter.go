@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// mappingColumnDocs explains each mapping CSV column, in the comments of
+// starter mapping files
+var mappingColumnDocs = map[string]string{
+	"column_name":       "the column's name in the warehouse (required)",
+	"table_name":        "the table the column belongs to (required)",
+	"system_a_fieldmap": "what system A calls the field, matched like the description",
+	"system_b_fieldmap": "what system B calls the field",
+	"field_description": "plain-language meaning; descriptions are matched against requests, so use the words people ask with",
+	"field_type":        "SQL type, e.g. INTEGER, VARCHAR(64), DATE; filter values are checked against it",
+	"join_key":          "on a foreign key row, the column joined on (usually column_name)",
+	"foreign_table":     "on a foreign key row, the table it references",
+	"foreign_key":       "on a foreign key row, the referenced column in foreign_table",
+	"owner":             "team that owns the table, shown with results",
+	"owner_contact":     "how to reach the owner, e.g. an email or channel",
+	"refresh_cadence":   "how often the table is loaded: realtime, hourly, daily, weekly",
+	"freshness_sla":     "how stale the table may get, e.g. 1h or 24h",
+	"tags":              "comma-separated labels requests can include or exclude (quote the cell)",
+	"deprecated":        "true to stop matching the field unless asked for",
+	"glossary_term":     "business glossary term the field implements",
+	"sensitive":         "true for fields only returned when a request allows sensitive data",
+	"table_alias":       "alias used for the table in generated SQL",
+	"classification":    "public, internal (the default), or restricted; compared with the caller's clearance",
+	"measure":           "aggregate SQL for a semantic-layer measure, e.g. SUM(total_amount)",
+	"join_group":        "name shared by the rows of a composite foreign key",
+	"cardinality":       "on a foreign key row: many_to_one, one_to_one, one_to_many, or many_to_many",
+	"nullable":          "true when the foreign key may be null, so inner joins drop those rows",
+	"join_type":         "left for optional relationships joined with a LEFT JOIN; blank for inner",
+	"join_weight":       "cost of the relationship in join planning (default 1); lower weights win when tables connect several ways",
+	"schema_name":       "schema the table lives in, e.g. analytics; generated SQL qualifies the table with it",
+	"database_name":     "database (or BigQuery project, Snowflake database) holding the schema, for dialects that can query across databases",
+	"system_a_tablemap": "what system A calls the table; queries for system_a use it with system_a_fieldmap",
+	"system_b_tablemap": "what system B calls the table",
+	"dbt_ref":           "how dbt models read the table, e.g. ref('fct_orders') or source('shop', 'products'); dbt output uses it",
+}
+
+// StarterMappings writes a starter mapping CSV for the named tables: every
+// column of the format, explained in comment lines, and sample rows for
+// each table to rename and extend. Each table after the first gets an
+// example foreign key to the first
+func StarterMappings(tables []string) ([]byte, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, table := range tables {
+		table = strings.TrimSpace(table)
+		if table == "" || seen[table] {
+			continue
+		}
+		if !validAlias.MatchString(table) {
+			return nil, fmt.Errorf("table name %q must be a plain SQL identifier", table)
+		}
+		seen[table] = true
+		names = append(names, table)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no tables given")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Starter field mappings. Lines starting with # are comments and are\n")
+	buf.WriteString("# ignored; delete the sample rows you don't need. Only the first nine\n")
+	buf.WriteString("# columns are required, the rest may be left blank or removed.\n#\n")
+	for _, column := range mappingColumns {
+		fmt.Fprintf(&buf, "# %s: %s\n", column, mappingColumnDocs[column])
+	}
+
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write(mappingColumns)
+	for i, table := range names {
+		writer.Flush()
+		fmt.Fprintf(&buf, "# %s\n", table)
+		for _, row := range starterRows(table, names[0], i > 0) {
+			record := make([]string, len(mappingColumns))
+			for j, column := range mappingColumns {
+				record[j] = row[column]
+			}
+			_ = writer.Write(record)
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// starterRows are a table's sample mapping rows: an identifier, a name, a
+// creation time, and optionally a foreign key to the parent table
+func starterRows(table, parent string, reference bool) []map[string]string {
+	entity := singular(table)
+	rows := []map[string]string{
+		{"column_name": entity + "_id", "field_description": "Unique identifier for " + entity, "field_type": "INTEGER", "tags": "core"},
+		{"column_name": "name", "field_description": strings.ToUpper(entity[:1]) + entity[1:] + " display name", "field_type": "VARCHAR(255)"},
+		{"column_name": "created_at", "field_description": "When the " + entity + " was created", "field_type": "TIMESTAMP"},
+	}
+	if reference {
+		key := singular(parent) + "_id"
+		rows = append(rows, map[string]string{
+			"column_name": key, "field_description": strings.ToUpper(singular(parent)[:1]) + singular(parent)[1:] + " this " + entity + " belongs to",
+			"field_type": "INTEGER", "join_key": key, "foreign_table": parent, "foreign_key": key, "cardinality": CardinalityManyToOne,
+		})
+	}
+	for _, row := range rows {
+		row["table_name"] = table
+		row["owner"] = "data-platform"
+		row["refresh_cadence"] = "daily"
+		row["classification"] = ClassificationInternal
+	}
+	return rows
+}
+
+// singular guesses the singular of a plural table name
+func singular(table string) string {
+	switch {
+	case strings.HasSuffix(table, "ies") && len(table) > 3:
+		return strings.TrimSuffix(table, "ies") + "y"
+	case strings.HasSuffix(table, "sses"), strings.HasSuffix(table, "xes"):
+		return strings.TrimSuffix(table, "es")
+	case strings.HasSuffix(table, "s") && !strings.HasSuffix(table, "ss") && len(table) > 1:
+		return strings.TrimSuffix(table, "s")
+	}
+	return table
+}
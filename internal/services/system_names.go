@@ -0,0 +1,49 @@
+package services
+
+// systemNames maps logical tables and columns to the physical names one
+// source system uses for them, from the mappings' system_*_fieldmap and
+// system_*_tablemap columns. A nil systemNames keeps the logical names
+type systemNames struct {
+	tables  map[string]string
+	columns map[string]string
+}
+
+// systemNames collects the physical names of system_a or system_b; other
+// systems, including default, render the mapped names as they are
+func (s *FieldService) systemNames(system string) *systemNames {
+	if system != "system_a" && system != "system_b" {
+		return nil
+	}
+
+	names := &systemNames{tables: make(map[string]string), columns: make(map[string]string)}
+	for _, field := range s.fields {
+		table, column := field.SystemATableMap, field.SystemAFieldMap
+		if system == "system_b" {
+			table, column = field.SystemBTableMap, field.SystemBFieldMap
+		}
+		// The first field of a table naming it for the system wins
+		if _, exists := names.tables[field.TableName]; table != "" && !exists {
+			names.tables[field.TableName] = table
+		}
+		if column != "" {
+			names.columns[fieldKey(field.TableName, field.ColumnName)] = column
+		}
+	}
+	return names
+}
+
+// table returns a table's physical name, falling back to its logical name
+func (n *systemNames) table(table string) string {
+	if n == nil || n.tables[table] == "" {
+		return table
+	}
+	return n.tables[table]
+}
+
+// column returns a column's physical name, falling back to its logical name
+func (n *systemNames) column(table, column string) string {
+	if n == nil || n.columns[fieldKey(table, column)] == "" {
+		return column
+	}
+	return n.columns[fieldKey(table, column)]
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidTemplate is returned when a description template cannot be expanded
+var ErrInvalidTemplate = errors.New("invalid description template")
+
+// templateVariable matches {name} placeholders in a description template
+var templateVariable = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// TemplateStore holds saved description templates by name
+type TemplateStore struct {
+	templates map[string]models.DescriptionTemplate
+}
+
+// LoadTemplates reads saved templates from a YAML or JSON file mapping each
+// template name to its text
+func LoadTemplates(path string) (*TemplateStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates file: %w", err)
+	}
+
+	var raw struct {
+		Templates map[string]string `json:"templates" yaml:"templates"`
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates file: %w", err)
+	}
+
+	store := &TemplateStore{templates: make(map[string]models.DescriptionTemplate, len(raw.Templates))}
+	for name, text := range raw.Templates {
+		store.templates[name] = models.DescriptionTemplate{
+			Name:      name,
+			Template:  text,
+			Variables: templateVariables(text),
+		}
+	}
+	return store, nil
+}
+
+// List returns the saved templates sorted by name
+func (t *TemplateStore) List() []models.DescriptionTemplate {
+	if t == nil {
+		return []models.DescriptionTemplate{}
+	}
+	list := make([]models.DescriptionTemplate, 0, len(t.templates))
+	for _, template := range t.templates {
+		list = append(list, template)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Expand fills a saved template, or when name is empty the given
+// description, with the variable values
+func (t *TemplateStore) Expand(name, description string, values map[string]string) (string, error) {
+	text := description
+	if name != "" {
+		var template models.DescriptionTemplate
+		var exists bool
+		if t != nil {
+			template, exists = t.templates[name]
+		}
+		if !exists {
+			return "", fmt.Errorf("%w: unknown template %q", ErrInvalidTemplate, name)
+		}
+		text = template.Template
+	}
+
+	var missing []string
+	expanded := templateVariable.ReplaceAllStringFunc(text, func(placeholder string) string {
+		variable := placeholder[1 : len(placeholder)-1]
+		value, exists := values[variable]
+		if !exists {
+			missing = append(missing, variable)
+			return placeholder
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: missing values for %s", ErrInvalidTemplate, strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// templateVariables lists a template's distinct variables in order of appearance
+func templateVariables(text string) []string {
+	seen := make(map[string]bool)
+	variables := make([]string, 0)
+	for _, match := range templateVariable.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			variables = append(variables, match[1])
+		}
+	}
+	return variables
+}
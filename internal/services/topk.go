@@ -0,0 +1,84 @@
+package services
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// rankedMatch pairs a match with its ranking key and its position in the
+// catalog so ties are broken deterministically in favour of earlier fields.
+// rank usually equals MatchScore but may exceed the 0-100 range (e.g. with
+// feedback boosts) to order matches that share a capped score.
+type rankedMatch struct {
+	match models.FieldMatch
+	rank  float64
+	order int
+}
+
+// worse reports whether a ranks below b
+func (a rankedMatch) worse(b rankedMatch) bool {
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+	return a.order > b.order
+}
+
+// matchHeap is a min-heap keeping the worst retained match at the root
+type matchHeap []rankedMatch
+
+func (h matchHeap) Len() int            { return len(h) }
+func (h matchHeap) Less(i, j int) bool  { return h[i].worse(h[j]) }
+func (h matchHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x interface{}) { *h = append(*h, x.(rankedMatch)) }
+func (h *matchHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// topMatches keeps the best matches seen so far, bounded by limit
+type topMatches struct {
+	limit int
+	heap  matchHeap
+}
+
+// newTopMatches creates a collector retaining at most limit matches. A
+// limit of zero or less retains none, as FindFieldMatches matches nothing
+func newTopMatches(limit int) *topMatches {
+	if limit < 0 {
+		limit = 0
+	}
+	return &topMatches{limit: limit, heap: make(matchHeap, 0, limit)}
+}
+
+// offer considers a match, evicting the current worst if the collector is full
+func (t *topMatches) offer(candidate rankedMatch) {
+	if t.limit == 0 {
+		return
+	}
+	if len(t.heap) < t.limit {
+		heap.Push(&t.heap, candidate)
+		return
+	}
+	if t.heap[0].worse(candidate) {
+		t.heap[0] = candidate
+		heap.Fix(&t.heap, 0)
+	}
+}
+
+// results returns the retained matches sorted by score, best first
+func (t *topMatches) results() []models.FieldMatch {
+	ranked := make([]rankedMatch, len(t.heap))
+	copy(ranked, t.heap)
+	sort.Slice(ranked, func(i, j int) bool { return ranked[j].worse(ranked[i]) })
+
+	matches := make([]models.FieldMatch, len(ranked))
+	for i, r := range ranked {
+		matches[i] = r.match
+	}
+	return matches
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// Validation issue severities; any error makes a mapping file invalid
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Validation issue codes
+const (
+	IssueMalformedCSV       = "malformed_csv"
+	IssueMissingHeader      = "missing_header"
+	IssueColumnCount        = "wrong_column_count"
+	IssueMissingName        = "missing_name"
+	IssueDuplicateField     = "duplicate_field"
+	IssueDanglingTable      = "dangling_foreign_table"
+	IssueDanglingKey        = "dangling_foreign_key"
+	IssueMissingDescription = "missing_description"
+	IssueNoFields           = "no_fields"
+	IssueClassification     = "unknown_classification"
+	IssueCardinality        = "unknown_cardinality"
+	IssueJoinType           = "unknown_join_type"
+	IssueJoinWeight         = "invalid_join_weight"
+)
+
+// ValidateMappings checks a mapping CSV without loading it: rows with the
+// wrong number of columns, duplicate table.column pairs, foreign_table
+// references to undefined tables, missing descriptions, and unknown
+// classifications, cardinalities, join types, or join weights. It parses the
+// file in the given delimiter and encoding, detecting either when empty, so
+// pass the loader's (see CSVFormat). Row numbers are file line numbers,
+// counting comment lines
+func ValidateMappings(data []byte, delimiter, encodingName string) models.ValidationReport {
+	report := models.ValidationReport{Issues: make([]models.ValidationIssue, 0)}
+	add := func(issue models.ValidationIssue) {
+		report.Issues = append(report.Issues, issue)
+	}
+
+	records, lines, err := readMappingLines(data, delimiter, encodingName)
+	if err != nil {
+		add(models.ValidationIssue{Severity: SeverityError, Code: IssueMalformedCSV, Message: err.Error()})
+		return report.Finish()
+	}
+	if len(records) == 0 {
+		add(models.ValidationIssue{Severity: SeverityError, Code: IssueMissingHeader, Message: "file is empty"})
+		return report.Finish()
+	}
+
+	header := records[0]
+	columns := newCSVColumns(header)
+	present := make(map[string]bool, len(header))
+	for _, name := range header {
+		present[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	var missing []string
+	for _, name := range requiredColumns {
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		add(models.ValidationIssue{Row: lines[0], Severity: SeverityWarning, Code: IssueMissingHeader,
+			Message: fmt.Sprintf("header lacks %s; those columns are read by position", strings.Join(missing, ", "))})
+	}
+
+	// First pass: per-row checks and the set of defined fields
+	type reference struct {
+		row                                     int
+		table, column, foreignTable, foreignKey string
+	}
+	firstRow := make(map[string]int)
+	tables := make(map[string]bool)
+	var references []reference
+	for i, row := range records[1:] {
+		line := lines[i+1]
+		report.Rows++
+		if len(row) < len(requiredColumns) {
+			add(models.ValidationIssue{Row: line, Severity: SeverityError, Code: IssueColumnCount,
+				Message: fmt.Sprintf("row has %d columns, at least %d are required", len(row), len(requiredColumns))})
+			continue
+		}
+		if len(row) != len(header) {
+			add(models.ValidationIssue{Row: line, Severity: SeverityWarning, Code: IssueColumnCount,
+				Message: fmt.Sprintf("row has %d columns, header has %d", len(row), len(header))})
+		}
+
+		table, column := columns.get(row, "table_name"), columns.get(row, "column_name")
+		if table == "" || column == "" {
+			add(models.ValidationIssue{Row: line, Table: table, Column: column, Severity: SeverityError, Code: IssueMissingName,
+				Message: "table_name and column_name are required"})
+			continue
+		}
+
+		key := fieldKey(table, column)
+		if first, exists := firstRow[key]; exists {
+			add(models.ValidationIssue{Row: line, Table: table, Column: column, Severity: SeverityError, Code: IssueDuplicateField,
+				Message: fmt.Sprintf("%s.%s is already defined on row %d", table, column, first)})
+			continue
+		}
+		firstRow[key] = line
+		tables[table] = true
+		report.Fields++
+
+		if columns.get(row, "field_description") == "" {
+			add(models.ValidationIssue{Row: line, Table: table, Column: column, Severity: SeverityWarning, Code: IssueMissingDescription,
+				Message: "field has no description, so it can only match by column name"})
+		}
+		if level := columns.get(row, "classification"); level != "" && classificationLevel(level) != strings.ToLower(level) {
+			add(models.ValidationIssue{Row: line, Table: table, Column: column, Severity: SeverityWarning, Code: IssueClassification,
+				Message: fmt.Sprintf("classification %q is not public, internal, or restricted; the field is treated as restricted", level)})
+		}
+		if cardinality := columns.get(row, "cardinality"); cardinality != "" && parseCardinality(cardinality) == "" {
+			add(models.ValidationIssue{Row: line, Table: table, Column: column, Severity: SeverityWarning, Code: IssueCardinality,
+				Message: fmt.Sprintf("cardinality %q is not one_to_one, many_to_one, one_to_many, or many_to_many; it is ignored", cardinality)})
+		}
+		if joinType := columns.get(row, "join_type"); joinType != "" && parseJoinType(joinType) == "" && !strings.EqualFold(strings.TrimSpace(joinType), JoinTypeInner) {
+			add(models.ValidationIssue{Row: line, Table: table, Column: column, Severity: SeverityWarning, Code: IssueJoinType,
+				Message: fmt.Sprintf("join_type %q is not inner or left; the relationship is joined inner", joinType)})
+		}
+		if weight := columns.get(row, "join_weight"); weight != "" && parseJoinWeight(weight) == 0 {
+			add(models.ValidationIssue{Row: line, Table: table, Column: column, Severity: SeverityWarning, Code: IssueJoinWeight,
+				Message: fmt.Sprintf("join_weight %q is not a positive number; the relationship weighs 1", weight)})
+		}
+		if foreignTable := columns.get(row, "foreign_table"); foreignTable != "" {
+			references = append(references, reference{line, table, column, foreignTable, columns.get(row, "foreign_key")})
+		}
+	}
+
+	if report.Fields == 0 {
+		add(models.ValidationIssue{Severity: SeverityError, Code: IssueNoFields, Message: "file defines no fields"})
+	}
+
+	// Second pass: references can point at rows defined later in the file
+	for _, ref := range references {
+		if !tables[ref.foreignTable] {
+			add(models.ValidationIssue{Row: ref.row, Table: ref.table, Column: ref.column, Severity: SeverityError, Code: IssueDanglingTable,
+				Message: fmt.Sprintf("foreign_table %s is not defined in the mappings", ref.foreignTable)})
+			continue
+		}
+		if ref.foreignKey == "" {
+			add(models.ValidationIssue{Row: ref.row, Table: ref.table, Column: ref.column, Severity: SeverityError, Code: IssueDanglingKey,
+				Message: fmt.Sprintf("foreign_table %s is set without a foreign_key", ref.foreignTable)})
+			continue
+		}
+		if _, exists := firstRow[fieldKey(ref.foreignTable, ref.foreignKey)]; !exists {
+			add(models.ValidationIssue{Row: ref.row, Table: ref.table, Column: ref.column, Severity: SeverityWarning, Code: IssueDanglingKey,
+				Message: fmt.Sprintf("foreign_key %s.%s is not defined in the mappings", ref.foreignTable, ref.foreignKey)})
+		}
+	}
+
+	return report.Finish()
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// ErrWriteIntent is returned for descriptions asking to change data; the
+// service only generates read-only queries
+var ErrWriteIntent = errors.New("generated queries are read-only")
+
+// writeIntentPattern matches descriptions that open with a data-changing
+// verb and its object, after any politeness: "delete old orders", "please
+// update user emails". The verb must stand alone, so "drop-off rate by
+// month" reads as a question
+var writeIntentPattern = regexp.MustCompile(`^(?:(?:please|can you|could you|would you|i want to|i need to|let's|lets)\s+)*` +
+	`(delete|remove|drop|truncate|purge|erase|update|modify|edit|insert|upsert|merge|alter|rename|grant|revoke|create|add)(?:\s+(.*))?$`)
+
+// createdObjectPattern is what create and add must be making to change
+// data, so "add up order totals" still reads as a question
+var createdObjectPattern = regexp.MustCompile(`^(?:(?:a|an|new)\s+)*(?:tables?|columns?|index(?:es)?|views?|rows?|records?|entr(?:y|ies))\b\s*(.*)$`)
+
+// compoundNouns follow one of the verbs to name a thing rather than act on
+// it: "edit history for user 5", "update count by day"
+var compoundNouns = map[string]bool{
+	"history": true, "histories": true, "log": true, "logs": true,
+	"count": true, "counts": true, "rate": true, "rates": true,
+	"date": true, "dates": true, "time": true, "times": true,
+	"timestamp": true, "timestamps": true, "status": true, "statuses": true,
+	"event": true, "events": true, "reason": true, "reasons": true,
+	"frequency": true, "activity": true,
+}
+
+// updateValuePattern is the part of an update naming the new value: "to
+// lowercase", "= 0", "set to null"
+var updateValuePattern = regexp.MustCompile(`\s+(?:to|=|as|with|set)\s.*$|\s*=.*$`)
+
+// insertValuesPattern is the part of an insert listing the new values
+var insertValuesPattern = regexp.MustCompile(`\s*\bvalues\b.*$`)
+
+// leadingPreposition introduces the rows a created object is for: "of
+// products", "into orders"
+var leadingPreposition = regexp.MustCompile(`^(?:of|for|to|into|in|on)\s+`)
+
+// writeIntent finds a data-changing request in a description, returning the
+// operation asked for and the rows it would touch as a noun phrase
+func writeIntent(description string) (string, string, bool) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(description)), " ")
+	match := writeIntentPattern.FindStringSubmatch(strings.TrimRight(normalized, ".!?"))
+	if match == nil {
+		return "", "", false
+	}
+	operation, object := match[1], match[2]
+	if first, _, _ := strings.Cut(object, " "); compoundNouns[first] {
+		return "", "", false
+	}
+
+	switch operation {
+	case "create", "add":
+		created := createdObjectPattern.FindStringSubmatch(object)
+		if created == nil {
+			return "", "", false
+		}
+		object = leadingPreposition.ReplaceAllString(created[1], "")
+	case "insert", "upsert":
+		object = leadingPreposition.ReplaceAllString(insertValuesPattern.ReplaceAllString(object, ""), "")
+	case "update", "modify", "edit", "merge":
+		object = updateValuePattern.ReplaceAllString(object, "")
+	}
+	return operation, inspectedRows(object), true
+}
+
+// inspectedRows rewrites the object of a write as the rows to show: "a new
+// order for user 5" becomes "orders for user 5"
+func inspectedRows(object string) string {
+	words := strings.Fields(object)
+	singular := false
+	for len(words) > 0 && (words[0] == "a" || words[0] == "an" || words[0] == "new") {
+		singular = singular || words[0] != "new"
+		words = words[1:]
+	}
+	if singular && len(words) > 0 {
+		words[0] = plural(words[0])
+	}
+	return strings.Join(words, " ")
+}
+
+// plural makes a plain English plural of a singular noun
+func plural(noun string) string {
+	switch {
+	case strings.HasSuffix(noun, "y") && len(noun) > 1 && !strings.ContainsRune("aeiou", rune(noun[len(noun)-2])):
+		return noun[:len(noun)-1] + "ies"
+	case strings.HasSuffix(noun, "s"), strings.HasSuffix(noun, "x"), strings.HasSuffix(noun, "ch"), strings.HasSuffix(noun, "sh"):
+		return noun + "es"
+	}
+	return noun + "s"
+}
+
+// checkWriteIntent refuses descriptions asking to change data, pointing at
+// the read-only query that shows the rows instead
+func checkWriteIntent(description string) error {
+	operation, target, found := writeIntent(description)
+	if !found {
+		return nil
+	}
+	if target == "" {
+		return fmt.Errorf("%w: %q would change data, which this service never does", ErrWriteIntent, operation)
+	}
+	return fmt.Errorf("%w: %q would change data, which this service never does; describe the rows to inspect instead, e.g. %q",
+		ErrWriteIntent, operation, "show "+target)
+}
+
+// ReadOnlyRefusal explains why a description was refused as a write, with
+// the inspection query to run instead when one can be generated
+func (s *QueryService) ReadOnlyRefusal(request models.QueryRequest, err error) models.ReadOnlyRefusal {
+	refusal := models.ReadOnlyRefusal{Error: err.Error(), TraceID: request.TraceID, ReadOnly: true}
+	operation, target, found := writeIntent(request.Description)
+	if !found {
+		return refusal
+	}
+	refusal.Operation = operation
+	if target == "" {
+		return refusal
+	}
+	refusal.SuggestedDescription = "show " + target
+
+	// The suggestion is best effort; a target matching no fields still gets
+	// the rewritten description
+	suggestion := request
+	suggestion.Description = refusal.SuggestedDescription
+	suggestion.Template, suggestion.Variables = "", nil
+	if response, err := s.GenerateQuery(suggestion); err == nil {
+		refusal.SuggestedQuery = response.Query
+	}
+	return refusal
+}
@@ -0,0 +1,54 @@
+package testutil
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// intentTemplates wrap field phrases in the kinds of questions analysts ask
+var intentTemplates = []string{
+	"get %s",
+	"show %s",
+	"count %s",
+	"how many %s",
+	"unique %s",
+	"%s per %s",
+	"list %s and %s",
+}
+
+// CorpusEntry is a generated description and the fields it was built from
+type CorpusEntry struct {
+	Description string
+	// Expected holds "table.column" for each field the description targets
+	Expected []string
+}
+
+// GenerateCorpus builds n descriptions by sampling field descriptions from
+// the schema, so each entry has a known expected answer
+func GenerateCorpus(schema *Schema, n int, seed int64) []CorpusEntry {
+	rng := rand.New(rand.NewSource(seed))
+	corpus := make([]CorpusEntry, 0, n)
+	if len(schema.Fields) == 0 {
+		return corpus
+	}
+
+	for i := 0; i < n; i++ {
+		template := intentTemplates[rng.Intn(len(intentTemplates))]
+		slots := strings.Count(template, "%s")
+
+		args := make([]interface{}, slots)
+		expected := make([]string, slots)
+		for j := 0; j < slots; j++ {
+			field := schema.Fields[rng.Intn(len(schema.Fields))]
+			args[j] = strings.ToLower(field.Description)
+			expected[j] = field.TableName + "." + field.ColumnName
+		}
+
+		corpus = append(corpus, CorpusEntry{
+			Description: fmt.Sprintf(template, args...),
+			Expected:    expected,
+		})
+	}
+	return corpus
+}
@@ -0,0 +1,158 @@
+// Package testutil generates synthetic schemas and description corpora for
+// benchmarks, property tests, and evaluation runs, so tests don't depend on
+// the single checked-in mapping file.
+package testutil
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/mgarce/go_query_api/internal/models"
+)
+
+// mappingHeader is the column order written by WriteCSV
+var mappingHeader = []string{
+	"column_name", "table_name", "system_a_fieldmap", "system_b_fieldmap",
+	"field_description", "field_type", "join_key", "foreign_table", "foreign_key",
+}
+
+// Word lists used to build readable table names and field descriptions
+var (
+	entities   = []string{"customer", "order", "invoice", "product", "shipment", "payment", "account", "vendor", "ticket", "campaign", "region", "warehouse"}
+	attributes = []string{"name", "status", "amount", "date", "email", "code", "count", "score", "type", "rating", "balance", "address"}
+	qualifiers = []string{"primary", "billing", "current", "original", "total", "average", "latest", "external"}
+	fieldTypes = []string{"VARCHAR", "INTEGER", "DECIMAL", "TIMESTAMP", "BOOLEAN"}
+)
+
+// SchemaOptions controls the shape of a generated schema
+type SchemaOptions struct {
+	Tables         int
+	FieldsPerTable int
+	// Connectivity is the probability that each table after the first links
+	// to an earlier table; 1.0 yields a fully connected join graph
+	Connectivity float64
+	// ExtraJoins adds additional random foreign keys, creating cycles and
+	// alternative join paths
+	ExtraJoins int
+	Seed       int64
+}
+
+// Schema is a generated set of tables and field mappings
+type Schema struct {
+	Tables []string
+	Fields []models.Field
+}
+
+// GenerateSchema builds a deterministic synthetic schema for the given options
+func GenerateSchema(opts SchemaOptions) *Schema {
+	if opts.Tables <= 0 {
+		opts.Tables = 1
+	}
+	if opts.FieldsPerTable <= 0 {
+		opts.FieldsPerTable = 1
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	schema := &Schema{}
+	for t := 0; t < opts.Tables; t++ {
+		table := fmt.Sprintf("%s_%d", entities[t%len(entities)], t)
+		schema.Tables = append(schema.Tables, table)
+
+		// Every table gets an id column that other tables can reference
+		schema.Fields = append(schema.Fields, models.Field{
+			ColumnName:  "id",
+			TableName:   table,
+			Description: fmt.Sprintf("Unique identifier for %s", entities[t%len(entities)]),
+			FieldType:   "INTEGER",
+		})
+
+		for f := 1; f < opts.FieldsPerTable; f++ {
+			attribute := attributes[rng.Intn(len(attributes))]
+			qualifier := qualifiers[rng.Intn(len(qualifiers))]
+			schema.Fields = append(schema.Fields, models.Field{
+				ColumnName:      fmt.Sprintf("%s_%s_%d", qualifier, attribute, f),
+				TableName:       table,
+				SystemAFieldMap: fmt.Sprintf("a_%s_%d", attribute, f),
+				SystemBFieldMap: fmt.Sprintf("b_%s_%d", attribute, f),
+				Description:     fmt.Sprintf("%s %s %s", capitalize(qualifier), entities[t%len(entities)], attribute),
+				FieldType:       fieldTypes[rng.Intn(len(fieldTypes))],
+			})
+		}
+
+		if t > 0 && rng.Float64() < opts.Connectivity {
+			schema.addForeignKey(table, schema.Tables[rng.Intn(t)])
+		}
+	}
+
+	for i := 0; i < opts.ExtraJoins && opts.Tables > 1; i++ {
+		from := rng.Intn(opts.Tables)
+		to := rng.Intn(opts.Tables)
+		if from != to {
+			schema.addForeignKey(schema.Tables[from], schema.Tables[to])
+		}
+	}
+
+	return schema
+}
+
+// addForeignKey adds a column on table referencing target.id
+func (s *Schema) addForeignKey(table, target string) {
+	column := target + "_id"
+	for _, field := range s.Fields {
+		if field.TableName == table && field.ColumnName == column {
+			return
+		}
+	}
+	s.Fields = append(s.Fields, models.Field{
+		ColumnName:   column,
+		TableName:    table,
+		Description:  fmt.Sprintf("Reference to %s", target),
+		FieldType:    "INTEGER",
+		JoinKey:      column,
+		ForeignTable: target,
+		ForeignKey:   "id",
+	})
+}
+
+// WriteCSV writes the schema in the mapping CSV format
+func (s *Schema) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(mappingHeader); err != nil {
+		return err
+	}
+	for _, field := range s.Fields {
+		row := []string{
+			field.ColumnName, field.TableName, field.SystemAFieldMap, field.SystemBFieldMap,
+			field.Description, field.FieldType, field.JoinKey, field.ForeignTable, field.ForeignKey,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSVFile writes the schema to a mapping CSV at path
+func (s *Schema) WriteCSVFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteCSV(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// capitalize upper-cases the first letter of a word
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+	return string(word[0]-'a'+'A') + word[1:]
+}
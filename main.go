@@ -12,6 +12,17 @@ import (
 )
 
 func main() {
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "dictionary" {
+		os.Exit(runDictionary(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "init-schema" {
+		os.Exit(runInitSchema(os.Args[2:]))
+	}
+
 	// Define command-line flags
 	var (
 		port      = flag.String("port", "", "Server port (overrides config)")
@@ -19,6 +30,8 @@ func main() {
 		debugMode = flag.Bool("debug", false, "Enable debug mode")
 		showHelp  = flag.Bool("help", false, "Show help message")
 		showVersion = flag.Bool("version", false, "Show version information")
+		chaosMode = flag.Bool("chaos", false, "Inject latency and random failures (resilience testing only)")
+		validateOnly = flag.Bool("validate-only", false, "Load and self-check the mappings, print the report, and exit")
 	)
 
 	// Parse flags
@@ -49,6 +62,15 @@ func main() {
 	if *csvPath != "" {
 		cfg.CSVPath = *csvPath
 	}
+	if *chaosMode {
+		cfg.ChaosEnabled = true
+		log.Printf("WARNING: chaos mode enabled, requests will see injected latency and failures")
+	}
+
+	// Check the schema without starting the server, for deployment gates
+	if *validateOnly {
+		os.Exit(runSelfCheck(cfg))
+	}
 
 	// Set Gin mode
 	if *debugMode {
@@ -76,7 +98,11 @@ func main() {
 func printHelp() {
 	fmt.Println("Go Query API - Natural Language to SQL Converter")
 	fmt.Println("\nUsage:")
-	fmt.Printf("  %s [options]\n\n", os.Args[0])
+	fmt.Printf("  %s [options]\n", os.Args[0])
+	fmt.Printf("  %s --validate-only [--csv <mappings>]\n", os.Args[0])
+	fmt.Printf("  %s validate <mappings.csv> [more.csv ...]\n", os.Args[0])
+	fmt.Printf("  %s dictionary [--format markdown|html] [--csv <mappings>] > dictionary.md\n", os.Args[0])
+	fmt.Printf("  %s init-schema --tables users,orders,products [--output field_mappings.csv]\n\n", os.Args[0])
 	fmt.Println("Options:")
 	flag.PrintDefaults()
 	fmt.Println("\nExample:")
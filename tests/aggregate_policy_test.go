@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePolicies writes a policy file into a fresh directory
+func writePolicies(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestAggregateOnlyPolicy(t *testing.T) {
+	policies, err := services.LoadPolicies(writePolicies(t, "aggregate_only:\n  tables: [orders]\n  min_group_size: 5\n"))
+	require.NoError(t, err)
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+	queryService.UsePolicies(policies)
+
+	testCases := []struct {
+		name          string
+		description   string
+		expectedErr   error
+		expectedQuery string
+		having        bool
+	}{
+		{
+			name:          "Count gets a minimum group size",
+			description:   "count of orders by order total",
+			expectedQuery: "SELECT COUNT(o.total_amount) FROM orders o HAVING COUNT(*) >= 5",
+			having:        true,
+		},
+		{
+			name:          "Grouping gets a minimum group size",
+			description:   "order total per order",
+			expectedQuery: "GROUP BY o.total_amount HAVING COUNT(*) >= 5",
+			having:        true,
+		},
+		{
+			name:        "Row-level reads are refused",
+			description: "order total value",
+			expectedErr: services.ErrAggregateOnly,
+		},
+		{
+			name:        "Joining through an aggregate-only table is refused",
+			description: "user email address and product display name",
+			expectedErr: services.ErrAggregateOnly,
+		},
+		{
+			name:          "Other tables are unaffected",
+			description:   "email address",
+			expectedQuery: "u.email",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{Description: tc.description})
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, response.Query, tc.expectedQuery)
+			if tc.having {
+				assert.Contains(t, response.Warnings, "orders may only be aggregated: groups with fewer than 5 rows are left out")
+			} else {
+				assert.NotContains(t, response.Query, "HAVING")
+			}
+		})
+	}
+
+	t.Run("Build requires an aggregate intent", func(t *testing.T) {
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{
+				{Table: "orders", Column: "user_id"},
+				{Table: "orders", Column: "total_amount", Aggregate: "sum"},
+			},
+			GroupBy: []models.FieldRef{{Table: "orders", Column: "user_id"}},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, response.Query, "GROUP BY o.user_id HAVING COUNT(*) >= 5")
+
+		_, err = queryService.BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{{Table: "orders", Column: "total_amount"}},
+		})
+		assert.ErrorIs(t, err, services.ErrAggregateOnly)
+	})
+}
+
+func TestAggregateOnlyPolicyErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "No tables", content: "aggregate_only:\n  min_group_size: 5\n"},
+		{name: "Negative group size", content: "aggregate_only:\n  tables: [orders]\n  min_group_size: -1\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := services.LoadPolicies(writePolicies(t, tc.content))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlternativeQueries(t *testing.T) {
+	band := config.Config{MaxAlternatives: 3, AlternativesMinConfidence: 20, AlternativesMaxConfidence: 70}
+
+	testCases := []struct {
+		name                 string
+		cfg                  config.Config
+		request              models.QueryRequest
+		expectedAlternatives []string
+	}{
+		{
+			name:    "Not configured",
+			request: models.QueryRequest{Description: "user email"},
+		},
+		{
+			name:    "Tables left out in turn",
+			cfg:     band,
+			request: models.QueryRequest{Description: "user email"},
+			expectedAlternatives: []string{
+				"SELECT u.email, u.user_id FROM users u",
+				"SELECT o.user_id FROM orders o",
+			},
+		},
+		{
+			name:                 "Requested count",
+			cfg:                  band,
+			request:              models.QueryRequest{Description: "user email", Alternatives: 1},
+			expectedAlternatives: []string{"SELECT u.email, u.user_id FROM users u"},
+		},
+		{
+			name:    "Runner-up swapped in",
+			cfg:     config.Config{MaxMatches: 2, MaxAlternatives: 3, AlternativesMinConfidence: 20, AlternativesMaxConfidence: 70},
+			request: models.QueryRequest{Description: "user email"},
+			expectedAlternatives: []string{
+				"SELECT u.email, o.user_id FROM users u JOIN orders o ON o.user_id = u.user_id",
+			},
+		},
+		{
+			name:    "Confident queries come alone",
+			cfg:     config.Config{MaxAlternatives: 3, AlternativesMinConfidence: 20, AlternativesMaxConfidence: 60},
+			request: models.QueryRequest{Description: "user email"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.CSVPath = "../field_mappings.csv"
+			fieldService, err := services.NewFieldService(&cfg)
+			require.NoError(t, err)
+
+			response, err := services.NewQueryService(fieldService).GenerateQuery(tc.request)
+			require.NoError(t, err)
+			var queries []string
+			for i, alternative := range response.Alternatives {
+				queries = append(queries, alternative.Query)
+				assert.NotEqual(t, response.Fingerprint, alternative.Fingerprint)
+				if i > 0 {
+					assert.LessOrEqual(t, alternative.Confidence, response.Alternatives[i-1].Confidence)
+				}
+			}
+			assert.Equal(t, tc.expectedAlternatives, queries)
+		})
+	}
+}
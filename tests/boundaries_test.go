@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinBoundaries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name           string
+		boundaries     string
+		description    string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "No boundaries",
+			description:    "user email and order total amount",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Fields in two systems",
+			boundaries:     "identity=users;commerce=orders,order_items,products",
+			description:    "user email and order total amount",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "query spans systems that are never joined: the matched fields belong to commerce (orders) and identity (users), which must not be joined; run a separate query for each system's fields instead",
+		},
+		{
+			name:           "Fields in one system",
+			boundaries:     "identity=users;commerce=orders,order_items,products",
+			description:    "order total amount and product name",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Join path through another system",
+			boundaries:     "identity=users;fulfilment=order_items",
+			description:    "user email and product name",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "the only join path passes through fulfilment (order_items) and identity (users)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv", JoinBoundaries: tc.boundaries}))
+
+			body, _ := json.Marshal(map[string]string{"description": tc.description})
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+			if tc.expectedError != "" {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Contains(t, response["error"], tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestParseJoinBoundaries(t *testing.T) {
+	testCases := []struct {
+		name        string
+		spec        string
+		expected    services.JoinBoundaries
+		expectError bool
+	}{
+		{name: "Empty", spec: "", expected: services.JoinBoundaries{}},
+		{
+			name:     "Two systems",
+			spec:     " billing = invoices, payments ; crm=contacts;",
+			expected: services.JoinBoundaries{"invoices": "billing", "payments": "billing", "contacts": "crm"},
+		},
+		{name: "Missing system", spec: "=invoices", expectError: true},
+		{name: "Missing equals", spec: "invoices,payments", expectError: true},
+		{name: "Table in two systems", spec: "billing=invoices;crm=invoices", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			boundaries, err := services.ParseJoinBoundaries(tc.spec)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, boundaries)
+		})
+	}
+}
+
+func TestJoinBoundariesBuildQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv", JoinBoundaries: "identity=users;commerce=orders"}))
+
+	body := `{"fields": [{"table": "users", "column": "email"}, {"table": "orders", "column": "total_amount"}]}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/build-query", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "commerce (orders) and identity (users)")
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/mgarce/go_query_api/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowStageConfig delays one stage well past a 10ms budget; seed 1 sleeps
+// about 48ms and never fails
+func slowStageConfig(csvPath, stage string) *config.Config {
+	return &config.Config{
+		CSVPath:          csvPath,
+		ChaosEnabled:     true,
+		ChaosMaxLatency:  50 * time.Millisecond,
+		ChaosFailureRate: 0,
+		ChaosStages:      stage,
+		ChaosSeed:        1,
+	}
+}
+
+func TestRequestBudgetMatching(t *testing.T) {
+	// Enough fields that scoring is checked against the deadline
+	schema := testutil.GenerateSchema(testutil.SchemaOptions{Tables: 100, FieldsPerTable: 20, Connectivity: 1.0, Seed: 1})
+	csvPath := filepath.Join(t.TempDir(), "fields.csv")
+	require.NoError(t, schema.WriteCSVFile(csvPath))
+
+	testCases := []struct {
+		name          string
+		cfg           *config.Config
+		budgetMs      int
+		expectPartial bool
+	}{
+		{name: "No budget", cfg: slowStageConfig(csvPath, services.ChaosStageMatching)},
+		{name: "Budget met", cfg: &config.Config{CSVPath: csvPath}, budgetMs: 60000},
+		{name: "Matching out of time", cfg: slowStageConfig(csvPath, services.ChaosStageMatching), budgetMs: 10, expectPartial: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(tc.cfg)
+			require.NoError(t, err)
+			response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{
+				Description: "unique identifier for customer",
+				BudgetMs:    tc.budgetMs,
+				MaxMatches:  1,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectPartial, response.Partial)
+			assert.NotEmpty(t, response.Query)
+
+			// The first table's id is in the batch scored before any deadline check
+			require.Len(t, response.MatchedFields, 1)
+			assert.Equal(t, "customer_0", response.MatchedFields[0].TableName)
+			if tc.expectPartial {
+				assert.Contains(t, strings.Join(response.Warnings, "\n"), "field matching ran out of time")
+			}
+		})
+	}
+}
+
+func TestRequestBudgetJoinPlanning(t *testing.T) {
+	testCases := []struct {
+		name           string
+		cfg            *config.Config
+		budgetMs       int
+		expectPartial  bool
+		expectedTables []string
+	}{
+		{name: "Joins planned", cfg: &config.Config{CSVPath: "../field_mappings.csv"}, budgetMs: 60000, expectedTables: []string{"users", "orders"}},
+		{name: "Join planning out of time", cfg: slowStageConfig("../field_mappings.csv", services.ChaosStageJoinPlanning), budgetMs: 10, expectPartial: true, expectedTables: []string{"users"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(tc.cfg)
+			require.NoError(t, err)
+			response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{
+				Description: "user email address and order total amount",
+				BudgetMs:    tc.budgetMs,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectPartial, response.Partial)
+
+			var tables []string
+			for _, match := range response.MatchedFields {
+				if !contains(tables, match.TableName) {
+					tables = append(tables, match.TableName)
+				}
+			}
+			assert.Equal(t, tc.expectedTables, tables)
+			if tc.expectPartial {
+				assert.Empty(t, response.JoinsUsed)
+				assert.NotContains(t, response.Query, "JOIN")
+				assert.Contains(t, strings.Join(response.Warnings, "\n"), "left out orders")
+			}
+		})
+	}
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
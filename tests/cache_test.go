@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaConditionalGet(t *testing.T) {
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, csvData, 0o644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: csvPath, SchemaCacheMaxAge: time.Minute, AdminToken: testAdminToken}))
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/fields", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, max-age=60", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Authorization, X-Clearance", w.Header().Get("Vary"))
+	etag := w.Header().Get("ETag")
+	lastModified := w.Header().Get("Last-Modified")
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, lastModified)
+
+	testCases := []struct {
+		name         string
+		path         string
+		headers      map[string]string
+		expectedCode int
+	}{
+		{name: "Unchanged since last load", path: "/api/v1/fields", headers: map[string]string{"If-Modified-Since": lastModified}, expectedCode: http.StatusNotModified},
+		{name: "Loaded after the client's copy", path: "/api/v1/fields", headers: map[string]string{"If-Modified-Since": "Mon, 01 Jan 2001 00:00:00 GMT"}, expectedCode: http.StatusOK},
+		{name: "Matching ETag", path: "/api/v1/schema/ddl", headers: map[string]string{"If-None-Match": etag}, expectedCode: http.StatusNotModified},
+		{name: "Weak ETag in a list", path: "/api/v1/schema/dictionary", headers: map[string]string{"If-None-Match": `"stale", W/` + etag}, expectedCode: http.StatusNotModified},
+		{name: "Stale ETag wins over a current date", path: "/api/v1/fields", headers: map[string]string{"If-None-Match": `"stale"`, "If-Modified-Since": lastModified}, expectedCode: http.StatusOK},
+		{name: "Queries are not cached", path: "/api/v1/schema-versions", headers: map[string]string{"If-None-Match": etag}, expectedCode: http.StatusOK},
+		{name: "Another clearance's ETag", path: "/api/v1/fields", headers: map[string]string{"If-None-Match": etag, "X-Clearance": "public"}, expectedCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := get(tc.path, tc.headers)
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+
+	// Each clearance has its own ETag
+	public := get("/api/v1/fields", map[string]string{"X-Clearance": "public"})
+	require.Equal(t, http.StatusOK, public.Code)
+	assert.NotEqual(t, etag, public.Header().Get("ETag"))
+	w = get("/api/v1/fields", map[string]string{"X-Clearance": "public", "If-None-Match": public.Header().Get("ETag")})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// A reload that changes the schema invalidates cached copies
+	updated := append(append([]byte{}, csvData...), []byte("status,orders,state,order_state,Order fulfillment status,VARCHAR,,,\n")...)
+	require.NoError(t, os.WriteFile(csvPath, updated, 0o644))
+	req, _ := http.NewRequest(http.MethodPost, "/admin/reload", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	w = get("/api/v1/fields", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), "Order fulfillment status")
+}
+
+func TestSchemaCacheRevalidatesByDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/fields", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinCardinality(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,cardinality,nullable
+customer_id,customers,,,Customer identifier,INTEGER,,,,,
+name,customers,,,Customer name,VARCHAR,,,,,
+order_id,orders,,,Order identifier,INTEGER,,,,,
+customer_id,orders,,,Customer who ordered,INTEGER,customer_id,customers,customer_id,many-to-one,true
+line_id,order_lines,,,Order line identifier,INTEGER,,,,,
+order_id,order_lines,,,Order the line belongs to,INTEGER,order_id,orders,order_id,n:1,
+quantity,order_lines,,,Units ordered,INTEGER,,,,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	joins, err := fieldService.FindJoinPath("orders", "customers")
+	require.NoError(t, err)
+	require.Len(t, joins, 1)
+	assert.Equal(t, services.CardinalityManyToOne, joins[0].Cardinality)
+	assert.True(t, joins[0].Nullable)
+
+	joins, err = fieldService.FindJoinPath("customers", "orders")
+	require.NoError(t, err)
+	require.Len(t, joins, 1)
+	assert.Equal(t, services.CardinalityOneToMany, joins[0].Cardinality)
+
+	testCases := []struct {
+		name             string
+		request          models.BuildQueryRequest
+		expectedFrom     string
+		expectedWarnings []string
+	}{
+		{
+			name: "Most detailed table becomes the root",
+			request: models.BuildQueryRequest{Fields: []models.IntentField{
+				{Table: "customers", Column: "name"}, {Table: "order_lines", Column: "quantity"},
+			}},
+			expectedFrom: "FROM order_lines o JOIN orders",
+			expectedWarnings: []string{
+				"the key joining orders and customers can be null; the inner join leaves out rows without a match (hint a left join to keep them)",
+			},
+		},
+		{
+			name: "Hinted root fans out",
+			request: models.BuildQueryRequest{
+				Fields: []models.IntentField{{Table: "customers", Column: "name"}, {Table: "orders", Column: "order_id"}},
+				Joins:  []models.JoinHint{{From: "customers", To: "orders", Type: "left"}},
+			},
+			expectedFrom: "FROM customers c LEFT JOIN orders",
+			expectedWarnings: []string{
+				"joining customers to orders is one-to-many: each customers row may appear several times, so counts and sums over customers can be inflated",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(tc.request)
+			require.NoError(t, err)
+			assert.Contains(t, response.Query, tc.expectedFrom)
+			assert.Equal(t, tc.expectedWarnings, response.Warnings)
+		})
+	}
+}
+
+func TestValidateMappingsCardinality(t *testing.T) {
+	report := services.ValidateMappings([]byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,cardinality
+customer_id,customers,,,Customer identifier,INTEGER,,,,
+customer_id,orders,,,Customer who ordered,INTEGER,customer_id,customers,customer_id,lots
+`), "", "")
+
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, services.IssueCardinality, report.Issues[0].Code)
+	assert.Equal(t, 3, report.Issues[0].Row)
+}
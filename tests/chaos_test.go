@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chaosConfig(stages string, failureRate float64) *config.Config {
+	return &config.Config{
+		CSVPath:          "../field_mappings.csv",
+		ChaosEnabled:     true,
+		ChaosMaxLatency:  time.Millisecond,
+		ChaosFailureRate: failureRate,
+		ChaosStages:      stages,
+		ChaosSeed:        1,
+	}
+}
+
+func TestChaosInjection(t *testing.T) {
+	testCases := []struct {
+		name        string
+		stages      string
+		failureRate float64
+		description string
+		expectError bool
+	}{
+		{name: "Matching failure", stages: services.ChaosStageMatching, failureRate: 1, description: "user email", expectError: true},
+		{name: "Join planning failure", stages: services.ChaosStageJoinPlanning, failureRate: 1, description: "user who placed order value", expectError: true},
+		{name: "Latency only", stages: services.ChaosStageMatching, failureRate: 0, description: "user email"},
+		{name: "Untargeted stage", stages: services.ChaosStageBackend, failureRate: 1, description: "user email"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(chaosConfig(tc.stages, tc.failureRate))
+			require.NoError(t, err)
+
+			_, err = services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{Description: tc.description})
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrChaosInjected)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// Disabled chaos injects nothing
+	chaos, err := services.NewChaos(&config.Config{})
+	require.NoError(t, err)
+	assert.Nil(t, chaos)
+	assert.NoError(t, chaos.Inject(services.ChaosStageMatching))
+}
+
+func TestChaosMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, chaosConfig(services.ChaosStageRequest, 1)))
+
+	req, _ := http.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "enabled", w.Header().Get("X-Chaos-Mode"))
+}
+
+func TestChaosWithoutLatency(t *testing.T) {
+	cfg := chaosConfig(services.ChaosStageMatching, 0)
+	cfg.ChaosMaxLatency = 0
+	chaos, err := services.NewChaos(cfg)
+	require.NoError(t, err)
+
+	// A zero maximum is no latency, not the 200ms default
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, chaos.Inject(services.ChaosStageMatching))
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestChaosConfigErrors(t *testing.T) {
+	for _, cfg := range []*config.Config{
+		chaosConfig("network", 0.5),
+		chaosConfig("", 1.5),
+	} {
+		_, err := services.NewChaos(cfg)
+		assert.Error(t, err)
+	}
+}
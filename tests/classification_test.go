@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassificationClearance(t *testing.T) {
+	// schema.example.yaml classifies users.email as restricted
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../schema.example.yaml"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name            string
+		description     string
+		clearance       string
+		expectedErr     error
+		expectedQuery   string
+		expectedMissing string
+		withheld        []string
+	}{
+		{
+			name:            "Restricted column withheld from internal caller",
+			description:     "user email address and order total",
+			clearance:       "",
+			expectedQuery:   "total_amount",
+			expectedMissing: "email",
+			withheld:        []string{"users.email"},
+		},
+		{
+			name:          "Restricted caller sees restricted columns",
+			description:   "user email address and order total",
+			clearance:     services.ClassificationRestricted,
+			expectedQuery: "u.email",
+		},
+		{
+			name:        "Nothing left to query",
+			description: "user email address",
+			clearance:   services.ClassificationPublic,
+			expectedErr: services.ErrInsufficientClearance,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{
+				Description: tc.description,
+				Clearance:   tc.clearance,
+			})
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, response.Query, tc.expectedQuery)
+			if tc.expectedMissing != "" {
+				assert.NotContains(t, response.Query, tc.expectedMissing)
+			}
+			assert.Equal(t, tc.withheld, response.WithheldColumns)
+			if len(tc.withheld) > 0 {
+				assert.Contains(t, response.Warnings, "column users.email is classified restricted and was withheld: caller clearance is internal")
+			}
+		})
+	}
+
+	t.Run("Build withholds selected columns", func(t *testing.T) {
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{
+				{Table: "users", Column: "user_id"},
+				{Table: "users", Column: "email"},
+			},
+		})
+		require.NoError(t, err)
+		assert.NotContains(t, response.Query, "email")
+		assert.Equal(t, []string{"users.email"}, response.WithheldColumns)
+		assert.Len(t, response.Warnings, 1)
+	})
+
+	t.Run("Build refuses filters on withheld columns", func(t *testing.T) {
+		_, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:  []models.IntentField{{Table: "users", Column: "user_id"}},
+			Filters: []models.IntentFilter{{Table: "users", Column: "email", Operator: "=", Value: "a@example.com"}},
+		})
+		assert.ErrorIs(t, err, services.ErrInsufficientClearance)
+	})
+}
+
+func TestClearanceHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../schema.example.yaml", DefaultClearance: "internal", AdminToken: "s3cret"}))
+
+	testCases := []struct {
+		name           string
+		clearance      string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "Default clearance", expectedStatus: http.StatusOK},
+		{name: "Authenticated caller raises their clearance", clearance: "Restricted", authorization: "Bearer s3cret", expectedStatus: http.StatusOK},
+		{name: "Unauthenticated caller can't raise their clearance", clearance: "restricted", expectedStatus: http.StatusForbidden},
+		{name: "Wrong token can't raise clearance", clearance: "restricted", authorization: "Bearer guess", expectedStatus: http.StatusForbidden},
+		{name: "Default clearance sent explicitly", clearance: "internal", expectedStatus: http.StatusOK},
+		{name: "Nothing left to query", clearance: "public", expectedStatus: http.StatusForbidden},
+		{name: "Unknown clearance", clearance: "secret", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.QueryRequest{Description: "user email address"})
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.clearance != "" {
+				req.Header.Set(handlers.ClearanceHeader, tc.clearance)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("Trusted gateway raises clearance", func(t *testing.T) {
+		trusted := gin.New()
+		require.NoError(t, handlers.SetupRoutes(trusted, &config.Config{CSVPath: "../schema.example.yaml", TrustClearanceHeader: true}))
+		body, _ := json.Marshal(models.QueryRequest{Description: "user email address"})
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(handlers.ClearanceHeader, "restricted")
+		w := httptest.NewRecorder()
+		trusted.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("Invalid default clearance is rejected at startup", func(t *testing.T) {
+		err := handlers.SetupRoutes(gin.New(), &config.Config{CSVPath: "../schema.example.yaml", DefaultClearance: "top"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedCohorts(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{
+		CSVPath:     "../field_mappings.csv",
+		CohortsPath: "../cohorts.example.yaml",
+	})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name            string
+		description     string
+		expectedCohorts []string
+		expectedQuery   []string
+	}{
+		{
+			name:            "Cohort by name",
+			description:     "user email address for active customers",
+			expectedCohorts: []string{"active customers"},
+			expectedQuery:   []string{"u.email", "WHERE (u.status = 'active' AND u.deleted_at IS NULL)"},
+		},
+		{
+			name:            "Cohort by synonym",
+			description:     "user email address of Active Users",
+			expectedCohorts: []string{"active customers"},
+			expectedQuery:   []string{"WHERE (u.status = 'active'"},
+		},
+		{
+			name:            "Cohort on another table is joined in",
+			description:     "user email address with large orders",
+			expectedCohorts: []string{"large orders"},
+			expectedQuery:   []string{"JOIN orders", "WHERE (o.total_amount >= 50000)"},
+		},
+		{
+			name:            "Several cohorts",
+			description:     "user email address for active customers with large orders",
+			expectedCohorts: []string{"active customers", "large orders"},
+			expectedQuery:   []string{"(u.status = 'active' AND u.deleted_at IS NULL) AND (o.total_amount >= 50000)"},
+		},
+		{
+			name:          "No cohort mentioned",
+			description:   "user email address",
+			expectedQuery: []string{"u.email"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{Description: tc.description})
+			require.NoError(t, err)
+
+			var names []string
+			for _, cohort := range response.Cohorts {
+				names = append(names, cohort.Name)
+			}
+			assert.ElementsMatch(t, tc.expectedCohorts, names)
+			for _, fragment := range tc.expectedQuery {
+				assert.Contains(t, response.Query, fragment)
+			}
+			if len(tc.expectedCohorts) == 0 {
+				assert.NotContains(t, response.Query, "WHERE")
+			}
+
+			// Cohort names are not matched as fields
+			for _, match := range response.MatchedFields {
+				assert.NotEqual(t, "total_amount", match.ColumnName)
+			}
+		})
+	}
+}
+
+func TestCohortConfigErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "Missing predicate", content: "cohorts:\n  - name: active\n    table: users\n"},
+		{name: "Multiple statements", content: "cohorts:\n  - name: active\n    table: users\n    predicate: \"1=1; DROP TABLE users\"\n"},
+		{name: "Unknown table", content: "cohorts:\n  - name: active\n    table: accounts\n    predicate: \"accounts.active\"\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cohorts.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o644))
+			_, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv", CohortsPath: path})
+			assert.Error(t, err)
+		})
+	}
+}
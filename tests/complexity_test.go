@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplexityLimits(t *testing.T) {
+	testCases := []struct {
+		name             string
+		cfg              config.Config
+		request          models.QueryRequest
+		expectedQuery    string
+		expectedWarnings []string
+		expectError      bool
+	}{
+		{
+			name:          "No limits",
+			expectedQuery: "SELECT u.user_id, u.email, o.order_id, o.user_id, p.product_name, oi.order_item_id FROM users u JOIN orders o ON o.user_id = u.user_id JOIN order_items oi ON oi.order_id = o.order_id JOIN products p ON oi.product_id = p.product_id",
+		},
+		{
+			name:          "Trimmed to the table limit",
+			cfg:           config.Config{MaxTables: 2, ComplexityAction: "trim"},
+			expectedQuery: "SELECT u.user_id, u.email, o.order_id, o.user_id FROM users u JOIN orders o ON o.user_id = u.user_id",
+			expectedWarnings: []string{
+				"left out the fields of table order_items: 4 tables are joined, over the limit of 2",
+				"left out the fields of table products: 4 tables are joined, over the limit of 2",
+			},
+		},
+		{
+			name:             "Trimmed to the column limit",
+			cfg:              config.Config{MaxColumns: 2, ComplexityAction: "trim"},
+			expectedQuery:    "SELECT u.user_id, u.email FROM users u",
+			expectedWarnings: []string{"kept the 2 best-scoring of 6 matched fields, the column limit"},
+		},
+		{
+			name:          "Trimmed to the join depth",
+			cfg:           config.Config{ComplexityAction: "trim"},
+			request:       models.QueryRequest{MaxJoinHops: 2},
+			expectedQuery: "SELECT u.user_id, u.email, o.order_id, o.user_id FROM users u JOIN orders o ON o.user_id = u.user_id",
+			expectedWarnings: []string{
+				"left out the fields of table order_items: a table is 3 joins from users, over the limit of 2",
+				"left out the fields of table products: a table is 3 joins from users, over the limit of 2",
+			},
+		},
+		{
+			name:          "Requests can't loosen the configured limits",
+			cfg:           config.Config{MaxTables: 2, ComplexityAction: "trim"},
+			request:       models.QueryRequest{MaxTables: 10},
+			expectedQuery: "SELECT u.user_id, u.email, o.order_id, o.user_id FROM users u JOIN orders o ON o.user_id = u.user_id",
+			expectedWarnings: []string{
+				"left out the fields of table order_items: 4 tables are joined, over the limit of 2",
+				"left out the fields of table products: 4 tables are joined, over the limit of 2",
+			},
+		},
+		{
+			name:        "Rejected",
+			cfg:         config.Config{MaxTables: 2, ComplexityAction: "reject"},
+			expectError: true,
+		},
+		{
+			name:        "Rejected unless trimming is configured",
+			cfg:         config.Config{MaxColumns: 2},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.CSVPath = "../field_mappings.csv"
+			fieldService, err := services.NewFieldService(&cfg)
+			require.NoError(t, err)
+
+			request := tc.request
+			request.Description = "order id and user email and product name"
+			response, err := services.NewQueryService(fieldService).GenerateQuery(request)
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrQueryTooComplex)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+			for _, warning := range tc.expectedWarnings {
+				assert.Contains(t, response.Warnings, warning)
+			}
+		})
+	}
+
+	t.Run("Structured intents are refused", func(t *testing.T) {
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv", MaxJoinHops: 2})
+		require.NoError(t, err)
+		_, err = services.NewQueryService(fieldService).BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{{Table: "users", Column: "email"}, {Table: "products", Column: "product_name"}},
+		})
+		assert.ErrorIs(t, err, services.ErrQueryTooComplex)
+		assert.ErrorContains(t, err, "a table is 3 joins from users, over the limit of 2")
+	})
+
+	t.Run("Invalid action", func(t *testing.T) {
+		assert.Error(t, services.ValidateComplexityAction("truncate"))
+	})
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompositeJoinKeys(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "tenants.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,join_group
+tenant_id,orders,,,Tenant owning the order,INTEGER,,,,
+order_id,orders,,,Order number within the tenant,INTEGER,,,,
+placed_at,orders,,,When the order was placed,TIMESTAMP,,,,
+tenant_id,shipments,,,Tenant owning the shipment,INTEGER,tenant_id,orders,tenant_id,order
+order_id,shipments,,,Order being shipped,INTEGER,order_id,orders,order_id,order
+carrier,shipments,,,Shipping carrier name,VARCHAR,,,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+
+	t.Run("Join path", func(t *testing.T) {
+		joins, err := fieldService.FindJoinPath("shipments", "orders")
+		require.NoError(t, err)
+		require.Len(t, joins, 1)
+		assert.Equal(t, "shipments.tenant_id = orders.tenant_id AND shipments.order_id = orders.order_id", joins[0].Condition)
+		assert.Equal(t, []models.JoinColumn{{From: "tenant_id", To: "tenant_id"}, {From: "order_id", To: "order_id"}}, joins[0].Columns)
+
+		reversed, err := fieldService.FindJoinPath("orders", "shipments")
+		require.NoError(t, err)
+		require.Len(t, reversed, 1)
+		assert.Equal(t, joins[0].Condition, reversed[0].Condition)
+		assert.Equal(t, []models.JoinColumn{{From: "tenant_id", To: "tenant_id"}, {From: "order_id", To: "order_id"}}, reversed[0].Columns)
+	})
+
+	t.Run("Generated query", func(t *testing.T) {
+		response, err := services.NewQueryService(fieldService).BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{{Table: "orders", Column: "placed_at"}, {Table: "shipments", Column: "carrier"}},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, response.Query, "JOIN shipments s ON s.tenant_id = o.tenant_id AND s.order_id = o.order_id")
+	})
+
+	t.Run("DDL", func(t *testing.T) {
+		ddl, err := fieldService.RenderDDL("postgres")
+		require.NoError(t, err)
+		assert.Contains(t, ddl, "  placed_at TIMESTAMP,\n  PRIMARY KEY (tenant_id, order_id)\n);")
+		assert.Contains(t, ddl, "ALTER TABLE shipments ADD CONSTRAINT fk_shipments_tenant_id_order_id FOREIGN KEY (tenant_id, order_id) REFERENCES orders (tenant_id, order_id);")
+	})
+}
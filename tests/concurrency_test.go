@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name     string
+		limit    int
+		inFlight int
+		expected int
+	}{
+		{name: "Under the limit", limit: 2, inFlight: 1, expected: http.StatusOK},
+		{name: "At the limit", limit: 2, inFlight: 2, expected: http.StatusServiceUnavailable},
+		{name: "Unlimited", limit: 0, inFlight: 5, expected: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			r := gin.New()
+			limited := handlers.ConcurrencyLimitMiddleware("generate", tc.limit)
+			r.GET("/slow", limited, func(c *gin.Context) {
+				started <- struct{}{}
+				<-release
+				c.Status(http.StatusOK)
+			})
+			r.GET("/fast", limited, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			r.GET("/health", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			// Park requests inside the limited handler
+			var wg sync.WaitGroup
+			for i := 0; i < tc.inFlight; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+					r.ServeHTTP(httptest.NewRecorder(), req)
+				}()
+				<-started
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, "/fast", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expected, w.Code)
+			if tc.expected == http.StatusServiceUnavailable {
+				assert.Equal(t, "1", w.Header().Get("Retry-After"))
+				assert.Contains(t, w.Body.String(), "too many concurrent generate requests")
+			}
+
+			// Unlimited routes are unaffected
+			req, _ = http.NewRequest(http.MethodGet, "/health", nil)
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			close(release)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingCSVFormats(t *testing.T) {
+	testCases := []struct {
+		name        string
+		file        string
+		contents    string
+		delimiter   string
+		encoding    string
+		description string
+		expectError bool
+	}{
+		{
+			name:        "Semicolons detected",
+			file:        "mappings.csv",
+			contents:    "column_name;table_name;system_a_fieldmap;system_b_fieldmap;field_description;field_type;join_key;foreign_table;foreign_key\ncafe_name;shops;;;\"Shop name; as signed\";VARCHAR;;;\n",
+			description: "Shop name; as signed",
+		},
+		{
+			name:        "Tab-separated file",
+			file:        "mappings.tsv",
+			contents:    "column_name\ttable_name\tsystem_a_fieldmap\tsystem_b_fieldmap\tfield_description\tfield_type\tjoin_key\tforeign_table\tforeign_key\ncafe_name\tshops\t\t\tShop name, as signed\tVARCHAR\t\t\t\n",
+			description: "Shop name, as signed",
+		},
+		{
+			name:        "Latin-1 detected",
+			file:        "mappings.csv",
+			contents:    "column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key\ncafe_name,shops,,,Caf\xe9 name,VARCHAR,,,\n",
+			description: "Café name",
+		},
+		{
+			name:        "UTF-8 byte order mark",
+			file:        "mappings.csv",
+			contents:    "\xef\xbb\xbfcolumn_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key\ncafe_name,shops,,,Café name,VARCHAR,,,\n",
+			description: "Café name",
+		},
+		{
+			name:        "Configured delimiter and encoding",
+			file:        "mappings.csv",
+			contents:    "column_name|table_name|system_a_fieldmap|system_b_fieldmap|field_description|field_type|join_key|foreign_table|foreign_key\ncafe_name|shops|||Caf\xe9, name|VARCHAR|||\n",
+			delimiter:   "pipe",
+			encoding:    "latin1",
+			description: "Café, name",
+		},
+		{
+			name:        "Configured UTF-8 rejects Latin-1",
+			file:        "mappings.csv",
+			contents:    "column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key\ncafe_name,shops,,,Caf\xe9 name,VARCHAR,,,\n",
+			encoding:    "utf-8",
+			expectError: true,
+		},
+		{
+			name:        "Unknown delimiter",
+			file:        "mappings.csv",
+			contents:    "column_name,table_name\n",
+			delimiter:   "colon",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			csvPath := filepath.Join(t.TempDir(), tc.file)
+			require.NoError(t, os.WriteFile(csvPath, []byte(tc.contents), 0o644))
+
+			fieldService, err := services.NewFieldService(&config.Config{
+				CSVPath:      csvPath,
+				CSVDelimiter: tc.delimiter,
+				CSVEncoding:  tc.encoding,
+			})
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			fields := fieldService.GetAllFields("")
+			require.Len(t, fields, 1)
+			assert.Equal(t, "shops", fields[0].TableName)
+			assert.Equal(t, "cafe_name", fields[0].ColumnName)
+			assert.Equal(t, tc.description, fields[0].Description)
+		})
+	}
+}
+
+func TestValidateMappingsDetectsFormat(t *testing.T) {
+	data := []byte("column_name;table_name;system_a_fieldmap;system_b_fieldmap;field_description;field_type;join_key;foreign_table;foreign_key\ncafe_name;shops;;;Caf\xe9 name;VARCHAR;;;\n")
+
+	report := services.ValidateMappings(data, "", "")
+
+	assert.True(t, report.Valid)
+	assert.Empty(t, report.Issues)
+}
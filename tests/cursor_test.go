@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultPages(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	executor := services.NewDatabaseExecutor(db, services.SchemaSourcePostgres, services.ResultLimits{MaxRows: 2})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT p.product_name FROM products p$").WillReturnRows(sqlmock.NewRows([]string{"product_name"}).
+		AddRow("Lamp").AddRow("Desk").AddRow("Chair"))
+	mock.ExpectRollback()
+	first, err := queryService.Execute(executor, models.QueryRequest{Description: "product display name", MaxMatches: 1})
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{{"Lamp"}, {"Desk"}}, first.Rows)
+	require.NotEmpty(t, first.NextCursor)
+	assert.Contains(t, first.Warnings, "the query has no ORDER BY, so later pages may repeat or skip rows")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(first.Query + " OFFSET 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"product_name"}).AddRow("Chair"))
+	mock.ExpectRollback()
+	second, err := executor.Page(first.NextCursor)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, [][]interface{}{{"Chair"}}, second.Rows)
+	assert.False(t, second.Truncated)
+	assert.Empty(t, second.NextCursor)
+
+	testCases := []struct {
+		name     string
+		cursor   string
+		executor *services.Executor
+	}{
+		{name: "Tampered cursor", cursor: "x" + first.NextCursor, executor: executor},
+		{name: "Unsigned cursor", cursor: "eyJxIjoiREVMRVRFIEZST00gdXNlcnMiLCJvIjoxfQ", executor: executor},
+		{name: "Cursor from another instance", cursor: first.NextCursor, executor: services.NewDatabaseExecutor(db, services.SchemaSourcePostgres, services.ResultLimits{})},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.executor.Page(tc.cursor)
+			assert.ErrorIs(t, err, services.ErrInvalidCursor)
+		})
+	}
+}
+
+func TestResultPagesOfJoins(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	executor := services.NewDatabaseExecutor(db, services.SchemaSourceMySQL, services.ResultLimits{MaxRows: 2})
+
+	// users and orders both have a user_id, which MySQL refuses to select
+	// twice from a derived table, so later pages render the plan again
+	columns := []string{"user_id", "order_id", "user_id", "order_item_id", "email"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("JOIN orders o").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, 10, 1, 100, "a@example.com").
+		AddRow(1, 11, 1, 101, "a@example.com").
+		AddRow(2, 12, 2, 102, "b@example.com"))
+	mock.ExpectRollback()
+	first, err := queryService.Execute(executor, models.QueryRequest{Description: "user id and order user id"})
+	require.NoError(t, err)
+	require.Contains(t, first.Query, "o.user_id")
+	require.NotEmpty(t, first.NextCursor)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(first.Query + " LIMIT 18446744073709551615 OFFSET 2")).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, 12, 2, 102, "b@example.com"))
+	mock.ExpectRollback()
+	second, err := executor.Page(first.NextCursor)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Len(t, second.Rows, 1)
+	assert.Empty(t, second.NextCursor)
+}
+
+func TestResultPageEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name           string
+		cfg            *config.Config
+		body           string
+		expectedStatus int
+	}{
+		{name: "Missing cursor", cfg: &config.Config{CSVPath: "../field_mappings.csv"}, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "Execution disabled", cfg: &config.Config{CSVPath: "../field_mappings.csv"}, body: `{"cursor": "abc.def"}`, expectedStatus: http.StatusForbidden},
+		{name: "Unknown result format", cfg: &config.Config{CSVPath: "../field_mappings.csv"}, body: `{"cursor": "abc.def", "result_format": "xml"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			require.NoError(t, handlers.SetupRoutes(r, tc.cfg))
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/execute/page", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+}
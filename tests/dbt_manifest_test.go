@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dbtManifest documents two models, a source, and a relationships test
+// in both the attached_node (newer) and kwargs-only (older) forms
+const dbtManifest = `{
+  "metadata": {"dbt_schema_version": "https://schemas.getdbt.com/dbt/manifest/v11.json"},
+  "nodes": {
+    "model.shop.users": {
+      "resource_type": "model", "name": "users", "tags": ["identity"],
+      "config": {"meta": {"owner": "identity"}},
+      "columns": {
+        "user_id": {"name": "user_id", "description": "Unique identifier for user", "data_type": "integer"},
+        "email": {"name": "email", "description": "User email address", "meta": {"sensitive": true, "classification": "restricted"}}
+      }
+    },
+    "model.shop.fct_orders": {
+      "resource_type": "model", "name": "fct_orders", "alias": "orders", "meta": {"owner": "payments"},
+      "columns": {
+        "order_id": {"name": "order_id", "description": "Unique order identifier"},
+        "user_id": {"name": "user_id", "description": "User who placed order"},
+        "product_id": {"name": "product_id", "description": "Product ordered"}
+      }
+    },
+    "model.shop.undocumented": {"resource_type": "model", "name": "undocumented", "columns": {}},
+    "test.shop.relationships_orders_user_id": {
+      "resource_type": "test", "attached_node": "model.shop.fct_orders", "column_name": "user_id",
+      "test_metadata": {"name": "relationships", "kwargs": {"column_name": "user_id", "to": "ref('users')", "field": "user_id"}}
+    },
+    "test.shop.relationships_orders_product_id": {
+      "resource_type": "test",
+      "test_metadata": {"name": "relationships", "kwargs": {
+        "column_name": "product_id", "to": "source('shop', 'products')", "field": "id",
+        "model": "{{ get_where_subquery(ref('fct_orders')) }}"}}
+    },
+    "test.shop.not_null_users_user_id": {
+      "resource_type": "test", "attached_node": "model.shop.users", "column_name": "user_id",
+      "test_metadata": {"name": "not_null", "kwargs": {"column_name": "user_id"}}
+    }
+  },
+  "sources": {
+    "source.shop.shop.products": {
+      "resource_type": "source", "name": "products", "source_name": "shop", "identifier": "products",
+      "columns": {"id": {"name": "id", "description": "Product identifier"}}
+    }
+  }
+}`
+
+// dbtCatalog supplies the warehouse types the manifest leaves out
+const dbtCatalog = `{
+  "nodes": {
+    "model.shop.fct_orders": {"columns": {"ORDER_ID": {"name": "ORDER_ID", "type": "bigint"}}}
+  },
+  "sources": {}
+}`
+
+func TestDbtManifestImport(t *testing.T) {
+	dir := writeMappings(t, map[string]string{
+		"manifest.json": dbtManifest,
+		"catalog.json":  dbtCatalog,
+	})
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: filepath.Join(dir, "manifest.json")})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		table        string
+		column       string
+		fieldType    string
+		owner        string
+		foreignTable string
+		foreignKey   string
+		sensitive    bool
+		dbtRef       string
+	}{
+		{name: "Model column with manifest type", table: "users", column: "user_id", fieldType: "INTEGER", owner: "identity", dbtRef: "ref('users')"},
+		{name: "Column meta flags", table: "users", column: "email", owner: "identity", sensitive: true, dbtRef: "ref('users')"},
+		{name: "Alias names the table and catalog fills the type", table: "orders", column: "order_id", fieldType: "BIGINT", owner: "payments", dbtRef: "ref('fct_orders')"},
+		{name: "Attached relationships test", table: "orders", column: "user_id", owner: "payments", foreignTable: "users", foreignKey: "user_id", dbtRef: "ref('fct_orders')"},
+		{name: "Relationships test to a source", table: "orders", column: "product_id", owner: "payments", foreignTable: "products", foreignKey: "id", dbtRef: "ref('fct_orders')"},
+		{name: "Source column", table: "products", column: "id", dbtRef: "source('shop', 'products')"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			field, found := fieldService.LookupField(tc.table, tc.column)
+			require.True(t, found)
+			assert.Equal(t, tc.fieldType, field.FieldType)
+			assert.Equal(t, tc.owner, field.Owner)
+			assert.Equal(t, tc.foreignTable, field.ForeignTable)
+			assert.Equal(t, tc.foreignKey, field.ForeignKey)
+			assert.Equal(t, tc.sensitive, field.Sensitive)
+			assert.Equal(t, tc.dbtRef, field.DbtRef)
+		})
+	}
+
+	t.Run("Relationships become joins", func(t *testing.T) {
+		joins, err := fieldService.FindJoinPath("users", "products")
+		require.NoError(t, err)
+		assert.Len(t, joins, 2)
+		assert.False(t, fieldService.HasTable("undocumented"))
+	})
+
+	t.Run("Manifest without documented columns", func(t *testing.T) {
+		empty := writeMappings(t, map[string]string{
+			"manifest.json": `{"metadata": {"dbt_schema_version": "https://schemas.getdbt.com/dbt/manifest/v11.json"}, "nodes": {}}`,
+		})
+		_, err := services.NewFieldService(&config.Config{CSVPath: filepath.Join(empty, "manifest.json")})
+		assert.Error(t, err)
+	})
+}
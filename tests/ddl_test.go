@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDDL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	testCases := []struct {
+		name           string
+		dialect        string
+		expectedStatus int
+		contains       []string
+		excludes       []string
+	}{
+		{
+			name:           "Postgres by default",
+			expectedStatus: http.StatusOK,
+			contains: []string{
+				"CREATE TABLE users (\n  user_id INTEGER PRIMARY KEY,\n  email VARCHAR,",
+				"COMMENT ON COLUMN users.email IS 'User email address';",
+				"ALTER TABLE orders ADD CONSTRAINT fk_orders_user_id FOREIGN KEY (user_id) REFERENCES users (user_id);",
+				// products.product_id is only known from the foreign key
+				"CREATE TABLE products (\n  product_name VARCHAR,\n  product_id INTEGER PRIMARY KEY\n);",
+			},
+		},
+		{
+			name:           "MySQL",
+			dialect:        "mysql",
+			expectedStatus: http.StatusOK,
+			contains: []string{
+				"  email VARCHAR(255) COMMENT 'User email address',",
+				"  order_id INT PRIMARY KEY COMMENT 'Unique order identifier',",
+				"ALTER TABLE order_items ADD CONSTRAINT fk_order_items_product_id FOREIGN KEY (product_id) REFERENCES products (product_id);",
+			},
+			excludes: []string{"COMMENT ON"},
+		},
+		{
+			name:           "SQLite declares foreign keys inline",
+			dialect:        "sqlite",
+			expectedStatus: http.StatusOK,
+			contains: []string{
+				"  email TEXT, -- User email address",
+				"  FOREIGN KEY (user_id) REFERENCES users (user_id)\n);",
+			},
+			excludes: []string{"ALTER TABLE", "COMMENT"},
+		},
+		{name: "Unknown dialect", dialect: "oracle", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/schema/ddl?dialect="+tc.dialect, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+			for _, expected := range tc.contains {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			for _, unexpected := range tc.excludes {
+				assert.NotContains(t, w.Body.String(), unexpected)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialectRendering(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "events.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key
+event_date,events,,,Day the event happened,DATE,,,
+created_at,events,,,When the event was recorded,DATETIME,,,
+is_test,events,,,Whether the event is synthetic,BOOLEAN,,,
+label,events,,,Event label,VARCHAR(32),,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	filters := []models.IntentFilter{
+		{Table: "events", Column: "event_date", Operator: ">=", Value: "2024-03-01"},
+		{Table: "events", Column: "created_at", Operator: "<", Value: "2024-03-01 12:30:00"},
+		{Table: "events", Column: "is_test", Operator: "=", Value: false},
+		{Table: "events", Column: "label", Operator: "=", Value: `it's \n`},
+	}
+
+	testCases := []struct {
+		name          string
+		dialect       string
+		offset        int
+		expectedQuery string
+	}{
+		{
+			name:          "Postgres",
+			dialect:       "postgresql",
+			offset:        20,
+			expectedQuery: `SELECT e.label FROM events e WHERE e.event_date >= DATE '2024-03-01' AND e.created_at < TIMESTAMP '2024-03-01 12:30:00' AND e.is_test = FALSE AND e.label = 'it''s \n' LIMIT 10 OFFSET 20`,
+		},
+		{
+			name:          "MySQL",
+			dialect:       "mysql",
+			expectedQuery: `SELECT e.label FROM events e WHERE e.event_date >= DATE '2024-03-01' AND e.created_at < TIMESTAMP '2024-03-01 12:30:00' AND e.is_test = FALSE AND e.label = 'it''s \\n' LIMIT 10`,
+		},
+		{
+			name:          "SQLite",
+			dialect:       "sqlite",
+			offset:        20,
+			expectedQuery: `SELECT e.label FROM events e WHERE e.event_date >= '2024-03-01' AND e.created_at < '2024-03-01 12:30:00' AND e.is_test = 0 AND e.label = 'it''s \n' LIMIT 10 OFFSET 20`,
+		},
+		{
+			name:          "SQL Server",
+			dialect:       "mssql",
+			expectedQuery: `SELECT TOP 10 e.label FROM events e WHERE e.event_date >= CAST('2024-03-01' AS DATE) AND e.created_at < CAST('2024-03-01 12:30:00' AS DATETIME2) AND e.is_test = 0 AND e.label = 'it''s \n'`,
+		},
+		{
+			name:          "SQL Server page",
+			dialect:       "sqlserver",
+			offset:        20,
+			expectedQuery: `SELECT e.label FROM events e WHERE e.event_date >= CAST('2024-03-01' AS DATE) AND e.created_at < CAST('2024-03-01 12:30:00' AS DATETIME2) AND e.is_test = 0 AND e.label = 'it''s \n' ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`,
+		},
+		{
+			name:          "BigQuery",
+			dialect:       "bigquery",
+			offset:        20,
+			expectedQuery: `SELECT e.label FROM events e WHERE e.event_date >= DATE '2024-03-01' AND e.created_at < DATETIME '2024-03-01 12:30:00' AND e.is_test = FALSE AND e.label = 'it\'s \\n' LIMIT 10 OFFSET 20`,
+		},
+		{
+			name:          "Snowflake",
+			dialect:       "snowflake",
+			expectedQuery: `SELECT e.label FROM events e WHERE e.event_date >= DATE '2024-03-01' AND e.created_at < TIMESTAMP '2024-03-01 12:30:00' AND e.is_test = FALSE AND e.label = 'it''s \\n' LIMIT 10`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "events", Column: "label"}},
+				Filters: filters,
+				Limit:   10,
+				Offset:  tc.offset,
+				Dialect: tc.dialect,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+}
+
+func TestGeneratedQueryDialect(t *testing.T) {
+	testCases := []struct {
+		name           string
+		configured     string
+		dialect        string
+		limit          int
+		offset         int
+		expectedPrefix string
+		expectedSuffix string
+		expectError    bool
+	}{
+		{name: "Portable by default", limit: 3, offset: 5, expectedPrefix: "SELECT u.email", expectedSuffix: " LIMIT 3 OFFSET 5"},
+		{name: "Configured default", configured: "sqlserver", limit: 3, expectedPrefix: "SELECT TOP 3 u.email"},
+		{name: "Request overrides the default", configured: "sqlserver", dialect: "mysql", limit: 3, expectedPrefix: "SELECT u.email", expectedSuffix: " LIMIT 3"},
+		{name: "Offset without a limit", dialect: "mysql", offset: 5, expectedSuffix: " LIMIT 18446744073709551615 OFFSET 5"},
+		{name: "Unknown dialect", dialect: "oracle", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv", Dialect: tc.configured})
+			require.NoError(t, err)
+			response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{
+				Description: "user email",
+				Limit:       tc.limit,
+				Offset:      tc.offset,
+				Dialect:     tc.dialect,
+			})
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrUnknownDialect)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, response.Query, tc.expectedPrefix)
+			assert.True(t, strings.HasSuffix(response.Query, tc.expectedSuffix), response.Query)
+		})
+	}
+}
+
+func TestDDLDialects(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+
+	_, err = fieldService.RenderDDL("bigquery")
+	assert.ErrorIs(t, err, services.ErrUnknownDialect)
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataDictionary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	testCases := []struct {
+		name                string
+		format              string
+		expectedStatus      int
+		expectedContentType string
+		contains            []string
+	}{
+		{
+			name:                "Markdown by default",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/markdown; charset=utf-8",
+			contains: []string{
+				"- [orders](#orders)",
+				"Owner: payments (payments-oncall@example.com). Refreshed hourly, SLA 1h",
+				"| order_id | INTEGER | Unique order identifier | order_num | transaction_id | tags: core, finance |",
+				"| tax_id | VARCHAR | User social security number | ssn | tax_number | sensitive; tags: identity, pii |",
+				"```mermaid\nerDiagram\n    order_items }o--|| orders : \"order_id = order_id\"",
+			},
+		},
+		{
+			name:                "HTML",
+			format:              "html",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			contains: []string{
+				"<h2 id=\"users\">users</h2>",
+				"<tr><td>email</td><td>VARCHAR</td><td>User email address</td><td>email_addr</td><td>user_email</td>",
+				"<pre class=\"mermaid\">\nerDiagram\n    order_items }o--|| orders : &#34;order_id = order_id&#34;",
+			},
+		},
+		{name: "Unknown format", format: "pdf", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/schema/dictionary?format="+tc.format, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+			if tc.expectedContentType != "" {
+				assert.Equal(t, tc.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			for _, expected := range tc.contains {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// driftOutcome is a batch of identical generations fed to a drift monitor
+type driftOutcome struct {
+	count      int
+	confidence float64
+	matched    bool
+}
+
+func TestDriftMonitor(t *testing.T) {
+	cfg := &config.Config{DriftWindow: 20, DriftMinSamples: 10}
+
+	// The previous version averaged 60 confidence with one miss in ten
+	baseline := []driftOutcome{{count: 9, confidence: 60, matched: true}, {count: 1}}
+
+	testCases := []struct {
+		name           string
+		after          []driftOutcome
+		expectAlert    bool
+		expectedReason string
+	}{
+		{name: "Unchanged quality", after: baseline},
+		{name: "Too few samples", after: []driftOutcome{{count: 9}}},
+		{name: "Confidence dropped", after: []driftOutcome{{count: 9, confidence: 40, matched: true}, {count: 1}}, expectAlert: true, expectedReason: "average confidence fell 20.0 points"},
+		{name: "More misses", after: []driftOutcome{{count: 7, confidence: 60, matched: true}, {count: 3}}, expectAlert: true, expectedReason: "no-match rate rose from 10.0% to 30.0%"},
+		{name: "Small dip", after: []driftOutcome{{count: 9, confidence: 55, matched: true}, {count: 1}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			monitor := services.NewDriftMonitor(cfg, "v1")
+			feed := func(version string, outcomes []driftOutcome) {
+				for _, outcome := range outcomes {
+					for i := 0; i < outcome.count; i++ {
+						monitor.Observe(version, outcome.confidence, outcome.matched)
+					}
+				}
+			}
+			feed("v1", baseline)
+			monitor.Switch("v2")
+			feed("v2", tc.after)
+
+			// Stragglers on the old version don't count against the new one
+			feed("v1", []driftOutcome{{count: 5}})
+
+			current, previous, alerts := monitor.Report()
+			assert.Equal(t, "v2", current.Version)
+			require.NotNil(t, previous)
+			assert.Equal(t, "v1", previous.Version)
+			assert.InDelta(t, 60, previous.AverageConfidence, 0.001)
+			if !tc.expectAlert {
+				assert.Empty(t, alerts)
+				return
+			}
+			require.Len(t, alerts, 1)
+			assert.Equal(t, "v2", alerts[0].Version)
+			assert.Equal(t, "v1", alerts[0].PreviousVersion)
+			require.Len(t, alerts[0].Reasons, 1)
+			assert.Contains(t, alerts[0].Reasons[0], tc.expectedReason)
+
+			// A version alerts once, however long it stays degraded
+			feed("v2", tc.after)
+			_, _, alerts = monitor.Report()
+			assert.Len(t, alerts, 1)
+		})
+	}
+}
+
+func TestDriftMonitorWebhook(t *testing.T) {
+	received := make(chan models.DriftAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert models.DriftAlert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		received <- alert
+	}))
+	defer server.Close()
+
+	monitor := services.NewDriftMonitor(&config.Config{DriftMinSamples: 5, DriftWebhookURL: server.URL}, "v1")
+	for i := 0; i < 5; i++ {
+		monitor.Observe("v1", 80, true)
+	}
+	monitor.Switch("v2")
+	for i := 0; i < 5; i++ {
+		monitor.Observe("v2", 0, false)
+	}
+
+	select {
+	case alert := <-received:
+		assert.Equal(t, "v2", alert.Version)
+		assert.Equal(t, 1.0, alert.Quality.NoMatchRate)
+		assert.Len(t, alert.Reasons, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("drift alert was not posted")
+	}
+}
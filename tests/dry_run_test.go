@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "systems.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,system_a_tablemap,system_b_tablemap
+user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,,tbl_user,
+email,users,email_addr,,User email address,VARCHAR,,,,tbl_user,
+order_id,orders,order_num,transaction_id,Unique order identifier,INTEGER,,,,tbl_order,Orders
+user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,user_id,tbl_order,Orders
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	request := models.QueryRequest{Description: "order id and user email", System: "system_a", Dialect: "postgres"}
+	generated, err := queryService.GenerateQuery(request)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		expect         func(mock sqlmock.Sqlmock)
+		expectedError  error
+		expectedResult models.DryRunResult
+	}{
+		{
+			name: "Prepared",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare("SELECT u.uid, u.email_addr").WillBeClosed()
+			},
+			expectedResult: models.DryRunResult{Valid: true},
+		},
+		{
+			name: "Drifted column and table",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare("SELECT").WillReturnError(errors.New(`column u.email_addr does not exist`))
+				mock.ExpectQuery("FROM information_schema.columns").WithArgs("").
+					WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+						AddRow("tbl_user", "uid").AddRow("tbl_user", "email").AddRow("tbl_payment", "amount"))
+			},
+			expectedError: services.ErrDryRunFailed,
+			expectedResult: models.DryRunResult{
+				Error:          "column u.email_addr does not exist",
+				MissingTables:  []string{"tbl_order"},
+				MissingColumns: []models.MissingColumn{{Field: "users.email", Table: "tbl_user", Column: "email_addr"}},
+			},
+		},
+		{
+			name: "Columns unavailable",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare("SELECT").WillReturnError(errors.New(`relation "tbl_order" does not exist`))
+				mock.ExpectQuery("FROM information_schema.columns").WillReturnError(errors.New("permission denied"))
+			},
+			expectedError: services.ErrDryRunFailed,
+			expectedResult: models.DryRunResult{
+				Error:    `relation "tbl_order" does not exist`,
+				Warnings: []string{"missing tables and columns weren't looked up: failed to read columns: permission denied"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			tc.expect(mock)
+
+			result, err := queryService.DryRun(services.NewDatabaseEstimator(db, services.SchemaSourcePostgres), generated, request)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.expectedResult, result)
+		})
+	}
+
+	t.Run("No database configured", func(t *testing.T) {
+		_, err := queryService.DryRun(services.NewDatabaseEstimator(nil, services.SchemaSourcePostgres), generated, request)
+		assert.ErrorIs(t, err, services.ErrNoDatabase)
+	})
+}
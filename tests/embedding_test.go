@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/mgarce/go_query_api/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingMatcher(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+		Matcher: services.MatcherEmbedding,
+	}
+
+	service, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	// Plural forms still land on the singular field through shared trigrams
+	matches := service.FindFieldMatches([]string{"user", "emails"}, 30.0, 3)
+	assert.NotEmpty(t, matches)
+	assert.Equal(t, "email", matches[0].ColumnName)
+	assert.Equal(t, "users", matches[0].TableName)
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	// Fake provider that maps "money" and "revenue" texts onto the same axis
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		data := make([]item, len(req.Input))
+		for i, text := range req.Input {
+			vector := []float64{0, 1}
+			if text == "money make" || text == "Total order value in cents orders total amount" {
+				vector = []float64{1, 0}
+			}
+			data[i] = item{Index: i, Embedding: vector}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		CSVPath:           "../field_mappings.csv",
+		Matcher:           services.MatcherEmbedding,
+		EmbeddingProvider: services.EmbeddingProviderHTTP,
+		EmbeddingURL:      server.URL,
+		EmbeddingAPIKey:   "secret",
+	}
+
+	service, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	matches := service.FindFieldMatches([]string{"money", "make"}, 90.0, 10)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "total_amount", matches[0].ColumnName)
+}
+
+func TestHTTPEmbedderBatches(t *testing.T) {
+	// More fields than a provider accepts in one request
+	schema := testutil.GenerateSchema(testutil.SchemaOptions{Tables: 30, FieldsPerTable: 100, Seed: 1})
+	csvPath := filepath.Join(t.TempDir(), "mappings.csv")
+	require.NoError(t, schema.WriteCSVFile(csvPath))
+
+	// The last table's id field lands in the second batch
+	last := schema.Tables[len(schema.Tables)-1]
+	var target string
+	for _, field := range schema.Fields {
+		if field.TableName == last && field.ColumnName == "id" {
+			target = field.Description + " " + strings.ReplaceAll(last, "_", " ") + " id"
+		}
+	}
+
+	// Fake provider that rejects oversized requests, answers out of order,
+	// and puts the target field and the "target" keyword on their own axis
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		batches = append(batches, len(req.Input))
+		if len(req.Input) > 2048 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		data := make([]item, 0, len(req.Input))
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			vector := []float64{0, 1}
+			if req.Input[i] == target || req.Input[i] == "target" {
+				vector = []float64{1, 0}
+			}
+			data = append(data, item{Index: i, Embedding: vector})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	service, err := services.NewFieldService(&config.Config{
+		CSVPath:           csvPath,
+		Matcher:           services.MatcherEmbedding,
+		EmbeddingProvider: services.EmbeddingProviderHTTP,
+		EmbeddingURL:      server.URL,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2048, len(schema.Fields) - 2048}, batches)
+
+	// Vectors line up with their fields across batches
+	matches := service.FindFieldMatches([]string{"target"}, 90.0, 10)
+	require.Len(t, matches, 1)
+	assert.Equal(t, last, matches[0].TableName)
+	assert.Equal(t, "id", matches[0].ColumnName)
+}
+
+func TestEmbeddingCache(t *testing.T) {
+	// Fake provider that counts how many texts it was asked to embed
+	embedded := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		embedded += len(req.Input)
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		data := make([]item, len(req.Input))
+		for i := range req.Input {
+			data[i] = item{Index: i, Embedding: []float64{1, float64(i)}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	csvPath := filepath.Join(dir, "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, csvData, 0o644))
+
+	cfg := &config.Config{
+		CSVPath:           csvPath,
+		Matcher:           services.MatcherEmbedding,
+		EmbeddingProvider: services.EmbeddingProviderHTTP,
+		EmbeddingURL:      server.URL,
+		EmbeddingCache:    true,
+	}
+
+	// The first load embeds every field and persists the vectors
+	_, err = services.NewFieldService(cfg)
+	require.NoError(t, err)
+	assert.Greater(t, embedded, 0)
+	assert.FileExists(t, csvPath+".embeddings.json")
+
+	// A restart against the same schema reuses them
+	embedded = 0
+	_, err = services.NewFieldService(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 0, embedded)
+
+	// A different embedding model invalidates the cache
+	cfg.EmbeddingModel = "other-model"
+	_, err = services.NewFieldService(cfg)
+	require.NoError(t, err)
+	assert.Greater(t, embedded, 0)
+
+	// So does a schema change
+	embedded = 0
+	require.NoError(t, os.WriteFile(csvPath, append(csvData, []byte("status,orders,state,order_state,Order status,VARCHAR,,,,,,,,,,\n")...), 0o644))
+	_, err = services.NewFieldService(cfg)
+	require.NoError(t, err)
+	assert.Greater(t, embedded, 0)
+
+	// The cache can be kept elsewhere
+	cfg.EmbeddingCacheDir = t.TempDir()
+	_, err = services.NewFieldService(cfg)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(cfg.EmbeddingCacheDir, "field_mappings.csv.embeddings.json"))
+}
+
+func TestEmbedderConfigErrors(t *testing.T) {
+	_, err := services.NewEmbedder(&config.Config{EmbeddingProvider: services.EmbeddingProviderHTTP})
+	assert.Error(t, err)
+
+	_, err = services.NewEmbedder(&config.Config{EmbeddingProvider: "bogus"})
+	assert.Error(t, err)
+}
+
+func TestHybridMatcher(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath:              "../field_mappings.csv",
+		Matcher:              services.MatcherHybrid,
+		HybridFuzzyWeight:    3,
+		HybridSemanticWeight: 1,
+	}
+
+	service, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	matches := service.FindFieldMatches([]string{"user", "email"}, 30.0, 10)
+	assert.NotEmpty(t, matches)
+
+	for _, match := range matches {
+		breakdown := match.ScoreBreakdown
+		if assert.NotNil(t, breakdown) {
+			// Weights are normalized and the combined score is their blend
+			assert.InDelta(t, 0.75, breakdown.FuzzyWeight, 0.0001)
+			assert.InDelta(t, 0.25, breakdown.SemanticWeight, 0.0001)
+			assert.InDelta(t, 0.75*breakdown.Fuzzy+0.25*breakdown.Semantic, match.MatchScore, 0.0001)
+		}
+	}
+	assert.Equal(t, "email", matches[0].ColumnName)
+}
+
+func TestSemanticMatchingNeedsHTTPProvider(t *testing.T) {
+	description := "how much money did we make"
+
+	// The local embedder is lexical: no field shares a word with the request
+	local, err := services.NewFieldService(&config.Config{
+		CSVPath: "../field_mappings.csv",
+		Matcher: services.MatcherEmbedding,
+	})
+	require.NoError(t, err)
+	_, err = services.NewQueryService(local).GenerateQuery(models.QueryRequest{Description: description})
+	assert.Error(t, err)
+
+	// A model that knows money and order value mean the same thing finds
+	// the revenue field. The fake provider stands in for one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		data := make([]item, len(req.Input))
+		for i, text := range req.Input {
+			vector := []float64{0, 1}
+			if strings.Contains(text, "money") || strings.Contains(text, "order value") {
+				vector = []float64{1, 0}
+			}
+			data[i] = item{Index: i, Embedding: vector}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	semantic, err := services.NewFieldService(&config.Config{
+		CSVPath:           "../field_mappings.csv",
+		Matcher:           services.MatcherEmbedding,
+		EmbeddingProvider: services.EmbeddingProviderHTTP,
+		EmbeddingURL:      server.URL,
+		MatchThreshold:    90,
+	})
+	require.NoError(t, err)
+	response, err := services.NewQueryService(semantic).GenerateQuery(models.QueryRequest{Description: description})
+	require.NoError(t, err)
+	require.NotEmpty(t, response.MatchedFields)
+	assert.Equal(t, "orders", response.MatchedFields[0].TableName)
+	assert.Equal(t, "total_amount", response.MatchedFields[0].ColumnName)
+}
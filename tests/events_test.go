@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink keeps published events in memory
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]models.GenerationEvent
+}
+
+func (s *recordingSink) Publish(ctx context.Context, events []models.GenerationEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func TestGenerationEvents(t *testing.T) {
+	cfg := &config.Config{CSVPath: "../field_mappings.csv", EventBatchSize: 2, EventFlushInterval: time.Hour}
+	fieldService, err := services.NewFieldService(cfg)
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+	sink := &recordingSink{}
+	events := services.NewEventStreamWithSink(cfg, sink)
+	queryService.UseEventStream(events)
+
+	_, err = queryService.GenerateQuery(models.QueryRequest{Description: "Show user email addresses", TraceID: "trace-1"})
+	require.NoError(t, err)
+	_, err = queryService.GenerateQuery(models.QueryRequest{Description: "  SHOW user email   addresses", TraceID: "trace-2"})
+	require.NoError(t, err)
+	_, err = queryService.GenerateQuery(models.QueryRequest{Description: "xyz12345 nonexistent fields", TraceID: "trace-3"})
+	require.Error(t, err)
+	events.Close()
+
+	// Two full batches: the last is flushed on close
+	require.Len(t, sink.batches, 2)
+	var published []models.GenerationEvent
+	for _, batch := range sink.batches {
+		published = append(published, batch...)
+	}
+	require.Len(t, published, 3)
+
+	generated := published[0]
+	assert.Equal(t, "trace-1", generated.TraceID)
+	assert.Equal(t, services.EventOutcomeGenerated, generated.Outcome)
+	assert.Equal(t, fieldService.Version(), generated.SchemaVersion)
+	assert.Contains(t, generated.Tables, "users")
+	assert.Greater(t, generated.Confidence, 0.0)
+	assert.Len(t, generated.DescriptionHash, 64)
+	assert.NotContains(t, generated.DescriptionHash, "email")
+
+	// Case and spacing don't change the hash
+	assert.Equal(t, generated.DescriptionHash, published[1].DescriptionHash)
+
+	missed := published[2]
+	assert.Equal(t, services.EventOutcomeNoMatch, missed.Outcome)
+	assert.Empty(t, missed.Tables)
+	assert.NotEqual(t, generated.DescriptionHash, missed.DescriptionHash)
+
+	// Events after closing are ignored
+	events.Emit(models.GenerationEvent{TraceID: "late"})
+	dropped, failed := events.Stats()
+	assert.Zero(t, dropped)
+	assert.Zero(t, failed)
+}
+
+func TestHTTPEventSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []models.GenerationEvent
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		contentType = r.Header.Get("Content-Type")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var event models.GenerationEvent
+			if json.Unmarshal(scanner.Bytes(), &event) == nil {
+				received = append(received, event)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		cfg         config.Config
+		expectError bool
+		disabled    bool
+	}{
+		{name: "Disabled", cfg: config.Config{}, disabled: true},
+		{name: "HTTP", cfg: config.Config{EventSink: "http", EventSinkURL: server.URL}},
+		{name: "HTTP without a URL", cfg: config.Config{EventSink: "http"}, expectError: true},
+		{name: "Unknown sink", cfg: config.Config{EventSink: "carrier-pigeon"}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := services.NewEventStream(&tc.cfg)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.disabled {
+				assert.Nil(t, stream)
+				stream.Emit(models.GenerationEvent{TraceID: "ignored"})
+				return
+			}
+
+			stream.Emit(models.GenerationEvent{TraceID: "a", Outcome: services.EventOutcomeGenerated, Tables: []string{"users"}})
+			stream.Emit(models.GenerationEvent{TraceID: "b", Outcome: services.EventOutcomeNoMatch, Tables: []string{}})
+			stream.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, "application/x-ndjson", contentType)
+			require.Len(t, received, 2)
+			assert.Equal(t, "a", received[0].TraceID)
+			assert.Equal(t, []string{"users"}, received[0].Tables)
+			assert.Equal(t, services.EventOutcomeNoMatch, received[1].Outcome)
+		})
+	}
+}
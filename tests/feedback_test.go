@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedbackBoostsAcceptedFields(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath:      "../field_mappings.csv",
+		FeedbackPath: filepath.Join(t.TempDir(), "feedback.json"),
+	}
+
+	service, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	// Several fields mention "user"; without feedback users.user_id ranks first
+	before := service.FindFieldMatches([]string{"user"}, 30.0, 10)
+	assert.Equal(t, "users", before[0].TableName)
+
+	accepted := []models.FieldRef{{Table: "orders", Column: "user_id"}}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, service.RecordFeedback([]string{"user"}, accepted, true))
+	}
+
+	after := service.FindFieldMatches([]string{"user"}, 30.0, 10)
+	assert.Equal(t, "orders", after[0].TableName)
+	assert.Equal(t, "user_id", after[0].ColumnName)
+	assert.Greater(t, after[0].FeedbackBoost, 0.0)
+
+	// Statistics survive a restart
+	reloaded, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, after, reloaded.FindFieldMatches([]string{"user"}, 30.0, 10))
+
+	// Unrelated keywords are unaffected
+	assert.Empty(t, reloaded.FindFieldMatches([]string{"xyz"}, 30.0, 10))
+}
+
+func TestFeedbackValidation(t *testing.T) {
+	t.Run("Unknown fields are refused", func(t *testing.T) {
+		service, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+		require.NoError(t, err)
+		err = service.RecordFeedback([]string{"user"}, []models.FieldRef{{Table: "users", Column: "made_up"}}, true)
+		assert.ErrorIs(t, err, services.ErrInvalidFeedback)
+	})
+
+	t.Run("Failed writes are rolled back", func(t *testing.T) {
+		service, err := services.NewFieldService(&config.Config{
+			CSVPath:      "../field_mappings.csv",
+			FeedbackPath: filepath.Join(t.TempDir(), "missing", "feedback.json"),
+		})
+		require.NoError(t, err)
+		err = service.RecordFeedback([]string{"user"}, []models.FieldRef{{Table: "orders", Column: "user_id"}}, true)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, services.ErrInvalidFeedback)
+		for _, match := range service.FindFieldMatches([]string{"user"}, 30.0, 10) {
+			assert.Zero(t, match.FeedbackBoost, match.TableName+"."+match.ColumnName)
+		}
+	})
+
+	t.Run("Keywords per report are capped", func(t *testing.T) {
+		service, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+		require.NoError(t, err)
+		keywords := make([]string, 30)
+		for i := range keywords {
+			keywords[i] = fmt.Sprintf("word%d", i)
+		}
+		keywords[0] = "user"
+		keywords[29] = "email"
+		require.NoError(t, service.RecordFeedback(keywords, []models.FieldRef{{Table: "users", Column: "email"}}, true))
+
+		boosts := make(map[string]float64)
+		for _, keyword := range []string{"user", "email"} {
+			for _, match := range service.FindFieldMatches([]string{keyword}, 30.0, 10) {
+				if match.TableName == "users" && match.ColumnName == "email" {
+					boosts[keyword] = match.FeedbackBoost
+				}
+			}
+		}
+		require.Contains(t, boosts, "email")
+		assert.Greater(t, boosts["user"], 0.0)
+		assert.Zero(t, boosts["email"])
+	})
+}
+
+func TestFeedbackHandler(t *testing.T) {
+	r, err := setupTestRouter()
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+	}{
+		{"Accepted", `{"description":"user emails","fields":[{"table":"users","column":"email"}],"accepted":true}`, http.StatusOK},
+		{"Rejected", `{"description":"user emails","fields":[{"table":"users","column":"email"}],"accepted":false}`, http.StatusOK},
+		{"Missing verdict", `{"description":"user emails","fields":[{"table":"users","column":"email"}]}`, http.StatusBadRequest},
+		{"Missing fields", `{"description":"user emails","accepted":true}`, http.StatusBadRequest},
+		{"Only stopwords", `{"description":"the a an","fields":[{"table":"users","column":"email"}],"accepted":true}`, http.StatusBadRequest},
+		{"Unknown field", `{"description":"user emails","fields":[{"table":"users","column":"password"}],"accepted":true}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/api/v1/feedback", bytes.NewBufferString(tc.payload))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		})
+	}
+}
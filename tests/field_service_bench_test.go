@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/mgarce/go_query_api/internal/testutil"
+)
+
+// writeSyntheticCSV writes a generated mapping file with roughly n fields
+func writeSyntheticCSV(tb testing.TB, n int) string {
+	tb.Helper()
+
+	schema := testutil.GenerateSchema(testutil.SchemaOptions{
+		Tables:         n / 200,
+		FieldsPerTable: 200,
+		Connectivity:   1.0,
+		Seed:           1,
+	})
+	path := filepath.Join(tb.TempDir(), "fields.csv")
+	if err := schema.WriteCSVFile(path); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func benchmarkFindFieldMatchesWithCutoff(b *testing.B, n, cutoff int) {
+	cfg := &config.Config{CSVPath: writeSyntheticCSV(b, n), ParallelScoringMinFields: cutoff}
+	service, err := services.NewFieldService(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// "customer" appears in many descriptions so ranking has to consider them all
+	keywords := []string{"customer", "billing", "amount"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matches := service.FindFieldMatches(keywords, 30.0, 10)
+		if len(matches) != 10 {
+			b.Fatalf("expected 10 matches, got %d", len(matches))
+		}
+	}
+}
+
+func benchmarkFindFieldMatches(b *testing.B, n int) { benchmarkFindFieldMatchesWithCutoff(b, n, 0) }
+
+func BenchmarkFindFieldMatchesSequential100k(b *testing.B) {
+	benchmarkFindFieldMatchesWithCutoff(b, 100000, 1<<30)
+}
+
+func BenchmarkFindFieldMatches1k(b *testing.B)   { benchmarkFindFieldMatches(b, 1000) }
+func BenchmarkFindFieldMatches10k(b *testing.B)  { benchmarkFindFieldMatches(b, 10000) }
+func BenchmarkFindFieldMatches100k(b *testing.B) { benchmarkFindFieldMatches(b, 100000) }
+
+// rankCandidates returns every match above the threshold in a fixed
+// shuffled order, the unsorted input the old ranking started from
+func rankCandidates(b *testing.B) []models.FieldMatch {
+	service, err := services.NewFieldService(&config.Config{CSVPath: writeSyntheticCSV(b, 100000)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	// A limit of every field keeps every match
+	candidates := service.FindFieldMatches([]string{"customer", "billing", "amount"}, 30.0, len(service.GetAllFields("default")))
+	if len(candidates) < 10 {
+		b.Fatalf("expected at least 10 matches, got %d", len(candidates))
+	}
+	random := rand.New(rand.NewSource(1))
+	random.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates
+}
+
+// bubbleSortMatches is a copy of the sortMatchesByScore bubble sort the
+// bounded heap replaced
+func bubbleSortMatches(matches []models.FieldMatch) {
+	for i := 0; i < len(matches); i++ {
+		for j := i + 1; j < len(matches); j++ {
+			if matches[i].MatchScore < matches[j].MatchScore {
+				matches[i], matches[j] = matches[j], matches[i]
+			}
+		}
+	}
+}
+
+// benchmarkRankFields times sorting the 100k field candidates and taking the
+// top 10. These baselines leave out scoring, which
+// BenchmarkFindFieldMatches100k includes
+func benchmarkRankFields(b *testing.B, sortMatches func([]models.FieldMatch)) {
+	candidates := rankCandidates(b)
+	matches := make([]models.FieldMatch, len(candidates))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(matches, candidates)
+		sortMatches(matches)
+		if top := matches[:10]; top[0].MatchScore < top[9].MatchScore {
+			b.Fatal("matches not sorted by score")
+		}
+	}
+}
+
+// BenchmarkRankFieldsBubbleSort is the ranking the bounded heap replaced
+func BenchmarkRankFieldsBubbleSort(b *testing.B) { benchmarkRankFields(b, bubbleSortMatches) }
+
+// BenchmarkRankFieldsFullSort keeps every candidate but sorts them with
+// sort.Slice instead
+func BenchmarkRankFieldsFullSort(b *testing.B) {
+	benchmarkRankFields(b, func(matches []models.FieldMatch) {
+		sort.Slice(matches, func(i, j int) bool { return matches[i].MatchScore > matches[j].MatchScore })
+	})
+}
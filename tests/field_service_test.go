@@ -5,8 +5,10 @@ import (
 	"testing"
 
 	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
 	"github.com/mgarce/go_query_api/internal/services"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFieldService(t *testing.T) {
@@ -40,6 +42,11 @@ func TestFieldService(t *testing.T) {
 	}
 	assert.True(t, emailFound, "Email field should be matched")
 	
+	// A limit of zero matches nothing
+	matches = service.FindFieldMatches([]string{"email", "user"}, 30.0, 0)
+	assert.NotNil(t, matches)
+	assert.Empty(t, matches)
+	
 	// Test FindJoinPath
 	joins, err := service.FindJoinPath("users", "orders")
 	assert.NoError(t, err)
@@ -85,4 +92,104 @@ func TestFieldServiceWithEmptyFile(t *testing.T) {
 	joins, err := service.FindJoinPath("users", "orders") 
 	assert.Error(t, err) // Should error as the tables don't exist
 	assert.Empty(t, joins)
-}
\ No newline at end of file
+}
+
+func TestFindFieldMatchesOrdering(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+	}
+
+	service, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	// Results are sorted best-first and truncated to maxMatches
+	all := service.FindFieldMatches([]string{"order", "user"}, 1.0, 100)
+	assert.NotEmpty(t, all)
+	for i := 1; i < len(all); i++ {
+		assert.GreaterOrEqual(t, all[i-1].MatchScore, all[i].MatchScore)
+	}
+
+	top := service.FindFieldMatches([]string{"order", "user"}, 1.0, 2)
+	assert.Len(t, top, 2)
+	assert.Equal(t, all[:2], top)
+}
+
+func TestParallelScoringMatchesSequential(t *testing.T) {
+	path := writeSyntheticCSV(t, 20000)
+
+	sequential, err := services.NewFieldService(&config.Config{CSVPath: path, ParallelScoringMinFields: 1 << 30})
+	assert.NoError(t, err)
+	parallel, err := services.NewFieldService(&config.Config{CSVPath: path, ParallelScoringMinFields: 1})
+	assert.NoError(t, err)
+
+	for _, maxMatches := range []int{1, 10, 250} {
+		keywords := []string{"customer", "billing", "amount"}
+		assert.Equal(t,
+			sequential.FindFieldMatches(keywords, 30.0, maxMatches),
+			parallel.FindFieldMatches(keywords, 30.0, maxMatches))
+	}
+}
+
+// writeMappingCSV writes a mapping file with the standard header and the given rows
+func writeMappingCSV(t *testing.T, rows ...string) string {
+	t.Helper()
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "fields_*.csv")
+	assert.NoError(t, err)
+	defer tmpFile.Close()
+
+	_, err = tmpFile.WriteString("column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key\n")
+	assert.NoError(t, err)
+	for _, row := range rows {
+		_, err = tmpFile.WriteString(row + "\n")
+		assert.NoError(t, err)
+	}
+	return tmpFile.Name()
+}
+
+func TestMatchedTerms(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scoring  string
+		keywords []string
+		expected []models.MatchedTerm
+	}{
+		{
+			name:     "Description, column name, and synonym hits",
+			keywords: []string{"user", "email"},
+			expected: []models.MatchedTerm{
+				{Keyword: "user", Source: services.MatchSourceDescription, Text: "user", Exact: true},
+				{Keyword: "user", Source: services.MatchSourceSynonym, Text: "user_email", Exact: true},
+				{Keyword: "email", Source: services.MatchSourceDescription, Text: "email", Exact: true},
+				{Keyword: "email", Source: services.MatchSourceColumnName, Text: "email", Exact: true},
+				{Keyword: "email", Source: services.MatchSourceSynonym, Text: "email_addr", Exact: true},
+			},
+		},
+		{
+			name:     "Edit-distance hit is not exact",
+			scoring:  services.KeywordScoringLevenshtein,
+			keywords: []string{"user", "emaill"},
+			expected: []models.MatchedTerm{
+				{Keyword: "user", Source: services.MatchSourceDescription, Text: "user", Exact: true},
+				{Keyword: "user", Source: services.MatchSourceSynonym, Text: "user_email", Exact: true},
+				{Keyword: "emaill", Source: services.MatchSourceDescription, Text: "email", Exact: false},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, err := services.NewFieldService(&config.Config{
+				CSVPath:        "../field_mappings.csv",
+				KeywordScoring: tc.scoring,
+			})
+			require.NoError(t, err)
+
+			matches := service.FindFieldMatches(tc.keywords, 30.0, 10)
+			require.NotEmpty(t, matches)
+			assert.Equal(t, "users", matches[0].TableName)
+			assert.Equal(t, "email", matches[0].ColumnName)
+			assert.Equal(t, tc.expected, matches[0].MatchedTerms)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFingerprint(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	base := models.BuildQueryRequest{
+		Fields: []models.IntentField{{Table: "users", Column: "email"}},
+		Filters: []models.IntentFilter{
+			{Table: "users", Column: "username", Operator: "=", Value: "ada"},
+			{Table: "users", Column: "user_id", Operator: "in", Value: []interface{}{1.0, 2.0}},
+		},
+		Limit: 10,
+	}
+	fingerprint := func(t *testing.T, request models.BuildQueryRequest) string {
+		response, err := queryService.BuildQuery(request)
+		require.NoError(t, err)
+		require.Len(t, response.Fingerprint, 64)
+		return response.Fingerprint
+	}
+	expected := fingerprint(t, base)
+
+	testCases := []struct {
+		name   string
+		modify func(request *models.BuildQueryRequest)
+		same   bool
+	}{
+		{
+			name: "Other literal values",
+			modify: func(request *models.BuildQueryRequest) {
+				request.Filters = []models.IntentFilter{
+					{Table: "users", Column: "username", Operator: "=", Value: "it's grace"},
+					{Table: "users", Column: "user_id", Operator: "in", Value: []interface{}{3.0, 4.0, 5.0}},
+				}
+				request.Limit = 50
+			},
+			same: true,
+		},
+		{
+			name:   "Placeholders",
+			modify: func(request *models.BuildQueryRequest) { request.ParameterStyle = "dollar" },
+			same:   true,
+		},
+		{
+			name:   "Pretty layout",
+			modify: func(request *models.BuildQueryRequest) { request.Format = "pretty" },
+			same:   true,
+		},
+		{
+			name: "Different operator",
+			modify: func(request *models.BuildQueryRequest) {
+				request.Filters = []models.IntentFilter{
+					{Table: "users", Column: "username", Operator: "!=", Value: "ada"},
+					{Table: "users", Column: "user_id", Operator: "in", Value: []interface{}{1.0, 2.0}},
+				}
+			},
+		},
+		{
+			name: "Different columns",
+			modify: func(request *models.BuildQueryRequest) {
+				request.Fields = []models.IntentField{{Table: "users", Column: "username"}}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := base
+			tc.modify(&request)
+			if tc.same {
+				assert.Equal(t, expected, fingerprint(t, request))
+			} else {
+				assert.NotEqual(t, expected, fingerprint(t, request))
+			}
+		})
+	}
+
+	t.Run("Generated queries", func(t *testing.T) {
+		first, err := queryService.GenerateQuery(models.QueryRequest{Description: "user email", Limit: 5})
+		require.NoError(t, err)
+		second, err := queryService.GenerateQuery(models.QueryRequest{Description: "user email", Limit: 20, Format: "pretty"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, first.Fingerprint)
+		assert.Equal(t, first.Fingerprint, second.Fingerprint)
+	})
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyAlgorithms(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cfg           config.Config
+		keywords      []string
+		expectedEmail bool
+	}{
+		{"Disabled", config.Config{FuzzyAlgorithm: "none"}, []string{"emial"}, false},
+		{"Fuzzysearch", config.Config{FuzzyAlgorithm: "fuzzysearch", FuzzyMaxEditDistance: 2}, []string{"emal"}, true},
+		{"Jaro-Winkler", config.Config{FuzzyAlgorithm: "jaro_winkler", FuzzyMinSimilarity: 0.9}, []string{"emial"}, true},
+		{"Trigram", config.Config{FuzzyAlgorithm: "trigram", FuzzyMinSimilarity: 0.6}, []string{"emails"}, true},
+		{"Edit distance cutoff", config.Config{FuzzyAlgorithm: "jaro_winkler", FuzzyMaxEditDistance: 1}, []string{"emial"}, false},
+		{"Prefix length", config.Config{FuzzyAlgorithm: "jaro_winkler", FuzzyPrefixLength: 3}, []string{"emial"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.CSVPath = "../field_mappings.csv"
+
+			service, err := services.NewFieldService(&cfg)
+			assert.NoError(t, err)
+
+			matches := service.FindFieldMatches(tc.keywords, 30.0, 10)
+			emailFound := false
+			for _, match := range matches {
+				if match.ColumnName == "email" {
+					emailFound = true
+				}
+			}
+			assert.Equal(t, tc.expectedEmail, emailFound)
+		})
+	}
+}
+
+func TestUnknownFuzzyAlgorithm(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath:        "../field_mappings.csv",
+		FuzzyAlgorithm: "soundex",
+	}
+
+	_, err := services.NewFieldService(cfg)
+	assert.Error(t, err)
+}
+
+func TestLevenshteinScoring(t *testing.T) {
+	path := writeMappingCSV(t,
+		"customer_name,customers,,,Customer full name,VARCHAR,,,",
+		"cluster_id,clusters,,,Cluster identifier,VARCHAR,,,",
+	)
+
+	testCases := []struct {
+		name        string
+		maxDistance int
+		expected    map[string]bool
+	}{
+		{"Typo within cutoff", 1, map[string]bool{"customer_name": true, "cluster_id": false}},
+		{"Cutoff of zero uses default", 0, map[string]bool{"customer_name": true, "cluster_id": false}},
+		{"Loose cutoff admits more words", 2, map[string]bool{"customer_name": true, "cluster_id": true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				CSVPath:                path,
+				KeywordScoring:         services.KeywordScoringLevenshtein,
+				LevenshteinMaxDistance: tc.maxDistance,
+			}
+			service, err := services.NewFieldService(cfg)
+			assert.NoError(t, err)
+
+			found := make(map[string]float64)
+			for _, match := range service.FindFieldMatches([]string{"custmer"}, 50.0, 10) {
+				found[match.ColumnName] = match.MatchScore
+			}
+			for column, expected := range tc.expected {
+				_, ok := found[column]
+				assert.Equal(t, expected, ok, column)
+			}
+			// Graded: one edit short of a perfect score
+			assert.InDelta(t, 87.5, found["customer_name"], 0.01)
+		})
+	}
+
+	// Binary scoring gives no credit for the typo
+	service, err := services.NewFieldService(&config.Config{CSVPath: path})
+	assert.NoError(t, err)
+	assert.Empty(t, service.FindFieldMatches([]string{"custmer"}, 50.0, 10))
+}
+
+func TestPhoneticFallback(t *testing.T) {
+	path := writeMappingCSV(t,
+		"query_text,saved_queries,,,Saved query text,VARCHAR,,,",
+		"received_at,shipments,,,Time the shipment was received,TIMESTAMP,,,",
+	)
+
+	testCases := []struct {
+		algorithm string
+		keyword   string
+		expected  string
+	}{
+		{"soundex", "recieved", "received_at"},
+		{"metaphone", "recieved", "received_at"},
+		{"metaphone", "kwery", "query_text"},
+		{"none", "kwery", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.algorithm+" "+tc.keyword, func(t *testing.T) {
+			cfg := &config.Config{
+				CSVPath:           path,
+				PhoneticAlgorithm: tc.algorithm,
+			}
+			service, err := services.NewFieldService(cfg)
+			assert.NoError(t, err)
+
+			matches := service.FindFieldMatches([]string{tc.keyword}, 30.0, 10)
+			if tc.expected == "" {
+				assert.Empty(t, matches)
+				return
+			}
+			if assert.Len(t, matches, 1) {
+				assert.Equal(t, tc.expected, matches[0].ColumnName)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const glossaryJSON = `{"terms": [
+	{"id": "GL-001", "label": "Customer", "definition": "Someone who placed an order.",
+	 "synonyms": ["buyer"], "fields": ["orders.user_id"]},
+	{"id": "GL-002", "label": "Order Value", "definition": "Total charged in cents.",
+	 "synonyms": ["revenue"]}
+]}`
+
+func TestGlossaryMatching(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "glossary.json")
+	require.NoError(t, os.WriteFile(filePath, []byte(glossaryJSON), 0o644))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(glossaryJSON))
+	}))
+	defer server.Close()
+
+	// GL-002 is linked through the mapping CSV rather than the glossary
+	csvPath := filepath.Join(dir, "fields.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(
+		"column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,glossary_term\n"+
+			"user_id,orders,customer_id,user_ref,User who placed order,INTEGER,,,,\n"+
+			"total_amount,orders,order_total,amount,Total order value in cents,INTEGER,,,,GL-002\n"+
+			"order_id,orders,order_num,transaction_id,Unique order identifier,INTEGER,,,,GL-404\n"), 0o644))
+
+	for _, source := range []string{filePath, server.URL} {
+		t.Run(source, func(t *testing.T) {
+			service, err := services.NewFieldService(&config.Config{
+				CSVPath:        csvPath,
+				GlossarySource: source,
+			})
+			require.NoError(t, err)
+
+			testCases := []struct {
+				keyword string
+				column  string
+				termID  string
+			}{
+				{keyword: "buyer", column: "user_id", termID: "GL-001"},
+				{keyword: "revenue", column: "total_amount", termID: "GL-002"},
+			}
+			for _, tc := range testCases {
+				matches := service.FindFieldMatches([]string{tc.keyword}, 30.0, 10)
+				require.Len(t, matches, 1, tc.keyword)
+				assert.Equal(t, tc.column, matches[0].ColumnName)
+				if assert.NotNil(t, matches[0].Glossary) {
+					assert.Equal(t, tc.termID, matches[0].Glossary.TermID)
+					assert.NotEmpty(t, matches[0].Glossary.Definition)
+				}
+				require.NotEmpty(t, matches[0].MatchedTerms)
+				assert.Equal(t, services.MatchSourceGlossary, matches[0].MatchedTerms[0].Source)
+			}
+
+			// Fields without a term carry no citation
+			matches := service.FindFieldMatches([]string{"identifier"}, 30.0, 10)
+			require.Len(t, matches, 1)
+			assert.Nil(t, matches[0].Glossary)
+		})
+	}
+}
+
+func TestGlossaryErrors(t *testing.T) {
+	dir := t.TempDir()
+	duplicate := filepath.Join(dir, "duplicate.yaml")
+	require.NoError(t, os.WriteFile(duplicate, []byte("terms:\n  - id: A\n  - id: A\n"), 0o644))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	for _, source := range []string{duplicate, filepath.Join(dir, "missing.yaml"), server.URL} {
+		_, err := services.LoadGlossary(source)
+		assert.Error(t, err, source)
+	}
+}
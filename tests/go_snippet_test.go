@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoSnippet(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name        string
+		request     models.BuildQueryRequest
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "database/sql with dialect parameters",
+			request: models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "users", Column: "user_id"}, {Table: "users", Column: "email"}},
+				Filters: []models.IntentFilter{{Table: "users", Column: "email", Operator: "=", Value: "a@example.com"}},
+				Output:  services.FormatGo,
+			},
+			contains: []string{
+				"\t\"database/sql\"\n",
+				"\tUserID sql.NullInt64\n\tEmail  sql.NullString\n",
+				"WHERE u.email = $1",
+				"db.QueryContext(ctx, query, \"a@example.com\")",
+				"rows.Scan(&row.UserID, &row.Email)",
+			},
+			notContains: []string{"sqlx", "`db:"},
+		},
+		{
+			name: "sqlx aliases aggregates and tags fields",
+			request: models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "users", Column: "email"}, {Table: "orders", Column: "total_amount", Aggregate: "sum"}},
+				GroupBy: []models.FieldRef{{Table: "users", Column: "email"}},
+				Output:  services.FormatSqlx,
+			},
+			contains: []string{
+				"\"github.com/jmoiron/sqlx\"",
+				"Email          sql.NullString  `db:\"email\"`",
+				"SumTotalAmount sql.NullFloat64 `db:\"sum_total_amount\"`",
+				"SUM(o.total_amount) AS sum_total_amount",
+				"db.SelectContext(ctx, &rows, query)",
+			},
+		},
+		{
+			name: "Named parameters",
+			request: models.BuildQueryRequest{
+				Fields:         []models.IntentField{{Table: "users", Column: "email", Aggregate: "count"}},
+				Filters:        []models.IntentFilter{{Table: "users", Column: "user_id", Operator: ">", Value: 10}},
+				ParameterStyle: services.ParameterStyleNamed,
+				Output:         services.FormatSqlx,
+			},
+			contains: []string{
+				"CountEmail int64 `db:\"count_email\"`",
+				"WHERE u.user_id > :p1",
+				"db.SelectContext(ctx, &rows, query, sql.Named(\"p1\", 10))",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(tc.request)
+			require.NoError(t, err)
+			snippet, contentType, err := queryService.RenderGoSnippet(tc.request.Output, services.GoSnippetQuery{
+				TraceID:    response.TraceID,
+				Query:      response.Query,
+				Plan:       response.Plan,
+				Parameters: response.Parameters,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "text/plain; charset=utf-8", contentType)
+			assert.Contains(t, snippet, "package queries")
+			for _, expected := range tc.contains {
+				assert.Contains(t, snippet, expected)
+			}
+			for _, unexpected := range tc.notContains {
+				assert.NotContains(t, snippet, unexpected)
+			}
+		})
+	}
+
+	t.Run("Queries without a plan are returned unscanned", func(t *testing.T) {
+		snippet, _, err := queryService.RenderGoSnippet(services.FormatGo, services.GoSnippetQuery{Query: "SELECT 1", Dialect: "mysql"})
+		require.NoError(t, err)
+		assert.Contains(t, snippet, "const query = `SELECT 1`")
+		assert.Contains(t, snippet, "(*sql.Rows, error)")
+	})
+
+	t.Run("Backtick-quoted SQL becomes an interpreted string", func(t *testing.T) {
+		snippet, _, err := queryService.RenderGoSnippet(services.FormatGo, services.GoSnippetQuery{Query: "SELECT `order` FROM t"})
+		require.NoError(t, err)
+		assert.Contains(t, snippet, "const query = \"SELECT `order` FROM t\"")
+	})
+
+	t.Run("Unknown output", func(t *testing.T) {
+		_, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{{Table: "users", Column: "email"}},
+			Output: "rust",
+		})
+		assert.ErrorIs(t, err, services.ErrInvalidIntent)
+	})
+}
@@ -3,15 +3,16 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mgarce/go_query_api/internal/config"
 	"github.com/mgarce/go_query_api/internal/handlers"
 	"github.com/mgarce/go_query_api/internal/models"
-	"github.com/mgarce/go_query_api/internal/services"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -84,6 +85,39 @@ func TestGenerateQueryHandler(t *testing.T) {
 				assert.Contains(t, query, "LIMIT 10")
 			},
 		},
+		{
+			name: "Invalid request - max matches out of range",
+			requestPayload: models.QueryRequest{
+				Description: "Get user emails",
+				MaxMatches:  500,
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, response map[string]interface{}) {
+				assert.Contains(t, response, "error")
+			},
+		},
+		{
+			name: "Invalid request - unknown table hint",
+			requestPayload: models.QueryRequest{
+				Description: "Get user emails",
+				Tables:      []string{"customers"},
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, response map[string]interface{}) {
+				assert.Contains(t, response, "error")
+			},
+		},
+		{
+			name: "Invalid request - unknown alias style",
+			requestPayload: models.QueryRequest{
+				Description: "Get user emails",
+				AliasStyle:  "bogus",
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, response map[string]interface{}) {
+				assert.Contains(t, response, "error")
+			},
+		},
 	}
 	
 	// Run test cases
@@ -168,4 +202,131 @@ func TestHealthCheck(t *testing.T) {
 	
 	// Check health status
 	assert.Equal(t, "ok", response["status"])
-}
\ No newline at end of file
+}
+
+func TestOwnershipMetadata(t *testing.T) {
+	r, err := setupTestRouter()
+	assert.NoError(t, err)
+
+	// Filter fields by owning team
+	req, err := http.NewRequest("GET", "/api/v1/fields?owner=payments", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var fieldsResponse struct {
+		Fields []models.Field `json:"fields"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &fieldsResponse))
+	assert.NotEmpty(t, fieldsResponse.Fields)
+	for _, field := range fieldsResponse.Fields {
+		assert.Equal(t, "payments", field.Owner)
+		assert.Contains(t, []string{"orders", "order_items"}, field.TableName)
+	}
+
+	// Generated queries surface the owners of every table they touch
+	payload, err := json.Marshal(models.QueryRequest{Description: "orders placed by user email"})
+	assert.NoError(t, err)
+	req, err = http.NewRequest("POST", "/api/v1/generate-query", bytes.NewBuffer(payload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.QueryResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	owners := make(map[string]string)
+	for _, owner := range response.Owners {
+		owners[owner.Table] = owner.Owner
+	}
+	assert.Equal(t, "identity", owners["users"])
+	assert.Equal(t, "payments", owners["orders"])
+}
+
+func TestTraceID(t *testing.T) {
+	r, err := setupTestRouter()
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{"Generated when absent", "", false},
+		{"Caller-supplied ID is reused", "incident-1234", true},
+		{"Malformed ID is replaced", "bad id\nwith newline", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := json.Marshal(models.QueryRequest{Description: "Get user emails"})
+			assert.NoError(t, err)
+
+			req, err := http.NewRequest("POST", "/api/v1/generate-query", bytes.NewBuffer(payload))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if tc.incoming != "" {
+				req.Header.Set(handlers.TraceIDHeader, tc.incoming)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response models.QueryResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+			header := w.Header().Get(handlers.TraceIDHeader)
+			assert.NotEmpty(t, header)
+			assert.Equal(t, header, response.TraceID)
+			if tc.reused {
+				assert.Equal(t, tc.incoming, header)
+			} else {
+				assert.NotEqual(t, tc.incoming, header)
+			}
+		})
+	}
+}
+
+func TestLongDescriptions(t *testing.T) {
+	r, err := setupTestRouter()
+	assert.NoError(t, err)
+
+	post := func(description string) (*httptest.ResponseRecorder, models.QueryResponse) {
+		payload, err := json.Marshal(models.QueryRequest{Description: description})
+		assert.NoError(t, err)
+		req, err := http.NewRequest("POST", "/api/v1/generate-query", bytes.NewBuffer(payload))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var response models.QueryResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	// A pasted document is summarized down to its most frequent keywords
+	var long strings.Builder
+	long.WriteString("user email user email user email ")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&long, "filler%d ", i)
+	}
+	w, response := post(long.String())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, response.Query, "u.email")
+	if assert.Len(t, response.Warnings, 1) {
+		assert.Contains(t, response.Warnings[0], "summarized")
+	}
+
+	// Anything over the hard cap is rejected without matching
+	w, _ = post(strings.Repeat("x", 20001))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// The cap counts characters, not the bytes that encode them
+	w, _ = post("user email " + strings.Repeat("é", 19989))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifierQuoting(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "legacy.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,measure
+user_id,user,,,User identifier,INTEGER,,,,
+CreatedAt,user,,,When the user signed up,TIMESTAMP,,,,
+order_id,order,,,Order identifier,INTEGER,,,,
+user_id,order,,,User placing the order,INTEGER,user_id,user,user_id,
+group,order,,,Fulfilment group,VARCHAR,,,,
+total,order,,,Order total,DECIMAL,,,,SUM(total)
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	request := models.BuildQueryRequest{
+		Fields:  []models.IntentField{{Table: "user", Column: "CreatedAt"}, {Table: "order", Column: "group"}},
+		Filters: []models.IntentFilter{{Table: "order", Column: "group", Operator: "=", Value: "a"}},
+		OrderBy: []models.IntentOrder{{Table: "user", Column: "CreatedAt"}},
+	}
+
+	testCases := []struct {
+		dialect       string
+		expectedQuery string
+	}{
+		{
+			dialect:       "postgres",
+			expectedQuery: `SELECT u."CreatedAt", o."group" FROM "user" u JOIN "order" o ON o.user_id = u.user_id WHERE o."group" = 'a' ORDER BY u."CreatedAt" ASC`,
+		},
+		{
+			dialect:       "mysql",
+			expectedQuery: "SELECT u.`CreatedAt`, o.`group` FROM `user` u JOIN `order` o ON o.user_id = u.user_id WHERE o.`group` = 'a' ORDER BY u.`CreatedAt` ASC",
+		},
+		{
+			dialect:       "sqlserver",
+			expectedQuery: `SELECT u.[CreatedAt], o.[group] FROM [user] u JOIN [order] o ON o.user_id = u.user_id WHERE o.[group] = 'a' ORDER BY u.[CreatedAt] ASC`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.dialect, func(t *testing.T) {
+			request.Dialect = tc.dialect
+			response, err := queryService.BuildQuery(request)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+
+	t.Run("Measure names", func(t *testing.T) {
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:  []models.IntentField{{Table: "order", Column: "group"}, {Table: "order", Column: "total"}},
+			GroupBy: []models.FieldRef{{Table: "order", Column: "group"}},
+			Dialect: "sqlserver",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT o.[group], SUM(total) AS total FROM [order] o GROUP BY o.[group]`, response.Query)
+	})
+
+	t.Run("Generated queries", func(t *testing.T) {
+		response, err := queryService.GenerateQuery(models.QueryRequest{Description: "user signup CreatedAt", Dialect: "bigquery"})
+		require.NoError(t, err)
+		assert.Contains(t, response.Query, "u.`CreatedAt`")
+		assert.Contains(t, response.Query, "FROM `user`")
+	})
+}
+
+func TestReservedAliasesInMeasures(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "notes.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,measure
+note_id,order_notes,,,Note identifier,INTEGER,,,,
+author,order_notes,,,Who wrote the note,VARCHAR,,,,
+words,order_notes,,,Total words written,INTEGER,,,,SUM(order_notes.word_count)
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	// Abbreviated, order_notes is aliased "on", which every dialect reserves
+	testCases := []struct {
+		dialect       string
+		expectedQuery string
+	}{
+		{
+			dialect:       "postgres",
+			expectedQuery: `SELECT "on".author, SUM("on".word_count) AS words FROM order_notes "on" GROUP BY "on".author`,
+		},
+		{
+			dialect:       "mysql",
+			expectedQuery: "SELECT `on`.author, SUM(`on`.word_count) AS words FROM order_notes `on` GROUP BY `on`.author",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.dialect, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{
+				Fields:     []models.IntentField{{Table: "order_notes", Column: "author"}, {Table: "order_notes", Column: "words"}},
+				GroupBy:    []models.FieldRef{{Table: "order_notes", Column: "author"}},
+				Dialect:    tc.dialect,
+				AliasStyle: "abbreviated",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchIncremental(t *testing.T) {
+	cfg := &config.Config{CSVPath: "../field_mappings.csv"}
+	fieldService, err := services.NewFieldService(cfg)
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+	sessions := services.NewMatchSessions(cfg)
+
+	// The first call opens a session and returns every match as added
+	first, err := queryService.MatchIncremental(sessions, models.IncrementalMatchRequest{Description: "user email "})
+	require.NoError(t, err)
+	require.NotEmpty(t, first.SessionID)
+	assert.True(t, first.Reset)
+	assert.True(t, first.Changed)
+	assert.Equal(t, 1, first.Revision)
+	assert.NotEmpty(t, first.Added)
+	assert.Equal(t, len(first.Added), first.Matches)
+
+	testCases := []struct {
+		name        string
+		description string
+		complete    bool
+		changed     bool
+		added       bool
+	}{
+		{name: "Partial word is ignored", description: "user email ord", changed: false},
+		{name: "Completed word adds matches", description: "user email order total ", changed: true, added: true},
+		{name: "Trailing punctuation completes a word", description: "user email order total,", changed: false},
+		{name: "Complete flag counts the last word", description: "user email order total amount", complete: true, changed: true},
+	}
+
+	revision := first.Revision
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.MatchIncremental(sessions, models.IncrementalMatchRequest{
+				SessionID:   first.SessionID,
+				Description: tc.description,
+				Complete:    tc.complete,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, first.SessionID, response.SessionID)
+			assert.False(t, response.Reset)
+			assert.Equal(t, tc.changed, response.Changed)
+			if tc.added {
+				assert.NotEmpty(t, response.Added)
+			}
+			if !tc.changed {
+				assert.Empty(t, response.Added)
+				assert.Empty(t, response.Updated)
+				assert.Empty(t, response.Removed)
+			}
+			assert.GreaterOrEqual(t, response.Revision, revision)
+			revision = response.Revision
+		})
+	}
+
+	t.Run("Deleting words removes matches", func(t *testing.T) {
+		response, err := queryService.MatchIncremental(sessions, models.IncrementalMatchRequest{
+			SessionID:   first.SessionID,
+			Description: "user email ",
+		})
+		require.NoError(t, err)
+		assert.True(t, response.Changed)
+		assert.NotEmpty(t, response.Removed)
+		assert.Equal(t, first.Matches, response.Matches)
+	})
+
+	t.Run("Unknown session starts over", func(t *testing.T) {
+		response, err := queryService.MatchIncremental(sessions, models.IncrementalMatchRequest{
+			SessionID:   "expired",
+			Description: "user email ",
+		})
+		require.NoError(t, err)
+		assert.True(t, response.Reset)
+		assert.NotEqual(t, "expired", response.SessionID)
+		assert.Equal(t, 1, response.Revision)
+	})
+
+	t.Run("Sessions are capped", func(t *testing.T) {
+		capped := services.NewMatchSessions(&config.Config{MaxMatchSessions: 2})
+		for i := 0; i < 5; i++ {
+			_, err := queryService.MatchIncremental(capped, models.IncrementalMatchRequest{Description: "user "})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, capped.Len())
+	})
+}
+
+func TestIncrementalMatchEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	testCases := []struct {
+		name           string
+		body           string
+		clearance      string
+		expectedStatus int
+	}{
+		{name: "Valid request", body: `{"description": "product name "}`, expectedStatus: http.StatusOK},
+		{name: "Unknown session with a complete word", body: `{"description": "product", "complete": true, "session_id": "stale"}`, expectedStatus: http.StatusOK},
+		{name: "Malformed body", body: `{"description": `, expectedStatus: http.StatusBadRequest},
+		{name: "Unknown clearance", body: `{"description": "product "}`, clearance: "secret", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/match/incremental", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.clearance != "" {
+				req.Header.Set(handlers.ClearanceHeader, tc.clearance)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var response models.IncrementalMatchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.NotEmpty(t, response.SessionID)
+			assert.True(t, response.Reset)
+			assert.NotEmpty(t, response.Added)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildQuery(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name          string
+		request       models.BuildQueryRequest
+		expectedQuery string
+		expectedJoins int
+		expectError   bool
+	}{
+		{
+			name: "Filters, ordering, and limit",
+			request: models.BuildQueryRequest{
+				Fields: []models.IntentField{{Table: "users", Column: "email"}},
+				Filters: []models.IntentFilter{
+					{Table: "users", Column: "email", Operator: "like", Value: "%@example.com"},
+					{Table: "users", Column: "user_id", Operator: "in", Value: []interface{}{1.0, 2.0}},
+				},
+				OrderBy: []models.IntentOrder{{Table: "users", Column: "email", Direction: "desc"}},
+				Limit:   5,
+			},
+			expectedQuery: "SELECT u.email FROM users u WHERE u.email LIKE '%@example.com' AND u.user_id IN (1, 2) ORDER BY u.email DESC LIMIT 5",
+		},
+		{
+			name: "Aggregate with group by joins tables",
+			request: models.BuildQueryRequest{
+				Fields: []models.IntentField{
+					{Table: "users", Column: "email"},
+					{Table: "orders", Column: "total_amount", Aggregate: "sum"},
+				},
+				GroupBy: []models.FieldRef{{Table: "users", Column: "email"}},
+			},
+			expectedQuery: "SELECT u.email, SUM(o.total_amount) FROM users u JOIN orders o ON o.user_id = u.user_id GROUP BY u.email",
+			expectedJoins: 1,
+		},
+		{
+			name: "String literals are escaped",
+			request: models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "products", Column: "product_name"}},
+				Filters: []models.IntentFilter{{Table: "products", Column: "product_name", Operator: "=", Value: "O'Brien"}},
+			},
+			expectedQuery: "SELECT p.product_name FROM products p WHERE p.product_name = 'O''Brien'",
+		},
+		{
+			name: "Unknown field",
+			request: models.BuildQueryRequest{
+				Fields: []models.IntentField{{Table: "users", Column: "password"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "Unsupported operator",
+			request: models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "users", Column: "email"}},
+				Filters: []models.IntentFilter{{Table: "users", Column: "email", Operator: "; DROP", Value: "x"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "Ungrouped column alongside aggregate",
+			request: models.BuildQueryRequest{
+				Fields: []models.IntentField{
+					{Table: "users", Column: "email"},
+					{Table: "orders", Column: "order_id", Aggregate: "count"},
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(tc.request)
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrInvalidIntent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+			assert.Len(t, response.JoinsUsed, tc.expectedJoins)
+			assert.NotEmpty(t, response.TraceID)
+		})
+	}
+}
+
+func TestBuildQueryHandler(t *testing.T) {
+	r, err := setupTestRouter()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+	}{
+		{
+			name:           "Valid intent",
+			payload:        `{"fields":[{"table":"orders","column":"order_id"}],"filters":[{"table":"orders","column":"total_amount","operator":">","value":1000}]}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing fields",
+			payload:        `{"filters":[]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown field",
+			payload:        `{"fields":[{"table":"orders","column":"nope"}]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown dialect",
+			payload:        `{"fields":[{"table":"orders","column":"order_id"}],"dialect":"cobol"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/build-query", bytes.NewBufferString(tc.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, "SELECT o.order_id FROM orders o WHERE o.total_amount > 1000", response["query"])
+			} else {
+				assert.Contains(t, response, "error")
+			}
+		})
+	}
+}
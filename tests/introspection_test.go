@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM information_schema.columns").
+		WithArgs("public").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name", "data_type", "description"}).
+			AddRow("users", "user_id", "integer", "Unique identifier for user").
+			AddRow("users", "email", "character varying", "").
+			AddRow("orders", "order_id", "integer", "Unique order identifier").
+			AddRow("orders", "user_id", "integer", "User who placed order"))
+	mock.ExpectQuery("FROM information_schema.table_constraints").
+		WithArgs("public").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name", "foreign_table", "foreign_column"}).
+			AddRow("orders", "user_id", "users", "user_id"))
+
+	fields, err := services.IntrospectPostgres(context.Background(), db, "")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, fields, 4)
+
+	// Columns without comments are described by their names
+	assert.Equal(t, "email of users", fields[1].Description)
+	assert.Equal(t, "CHARACTER VARYING", fields[1].FieldType)
+
+	// Foreign keys become join metadata
+	assert.Equal(t, "user_id", fields[3].JoinKey)
+	assert.Equal(t, "users", fields[3].ForeignTable)
+	assert.Equal(t, "user_id", fields[3].ForeignKey)
+	assert.Empty(t, fields[0].ForeignTable)
+}
+
+func TestIntrospectMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM information_schema.COLUMNS").
+		WithArgs("").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "COLUMN_COMMENT"}).
+			AddRow("order_items", "item_id", "int", "Unique item identifier").
+			AddRow("order_items", "order_id", "int", "").
+			AddRow("orders", "order_id", "int", "Unique order identifier"))
+	mock.ExpectQuery("FROM information_schema.KEY_COLUMN_USAGE").
+		WithArgs("").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "COLUMN_NAME", "REFERENCED_TABLE_NAME", "REFERENCED_COLUMN_NAME"}).
+			AddRow("order_items", "order_id", "orders", "order_id"))
+
+	fields, err := services.IntrospectMySQL(context.Background(), db, "")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, fields, 3)
+
+	assert.Equal(t, "order id of order items", fields[1].Description)
+	assert.Equal(t, "INT", fields[1].FieldType)
+	assert.Equal(t, "orders", fields[1].ForeignTable)
+	assert.Equal(t, "order_id", fields[1].ForeignKey)
+}
+
+func TestSchemaSourceErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  *config.Config
+	}{
+		{name: "Unknown source", cfg: &config.Config{SchemaSource: "oracle"}},
+		{name: "Postgres without a database URL", cfg: &config.Config{SchemaSource: services.SchemaSourcePostgres}},
+		{name: "MySQL without a database URL", cfg: &config.Config{SchemaSource: services.SchemaSourceMySQL}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := services.NewFieldService(tc.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinHints(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	fields := []models.IntentField{
+		{Table: "orders", Column: "total_amount"},
+		{Table: "users", Column: "email"},
+	}
+
+	testCases := []struct {
+		name          string
+		fields        []models.IntentField
+		joins         []models.JoinHint
+		expectedQuery string
+		expectedErr   error
+	}{
+		{
+			name:          "Left join from the hinted root",
+			fields:        fields,
+			joins:         []models.JoinHint{{From: "users", To: "orders", Type: "left"}},
+			expectedQuery: "SELECT o.total_amount, u.email FROM users u LEFT JOIN orders o ON o.user_id = u.user_id",
+		},
+		{
+			name:          "Inner hints may run against the relationship",
+			fields:        fields,
+			joins:         []models.JoinHint{{From: "users", To: "orders"}, {From: "order_items", To: "orders"}},
+			expectedQuery: "SELECT o.total_amount, u.email FROM users u JOIN orders o ON o.user_id = u.user_id JOIN order_items oi ON oi.order_id = o.order_id",
+		},
+		{
+			name:          "Planner fills in tables the hints don't reach",
+			fields:        []models.IntentField{{Table: "users", Column: "email"}, {Table: "products", Column: "product_name"}},
+			joins:         []models.JoinHint{{From: "orders", To: "users", Type: "LEFT"}},
+			expectedQuery: "SELECT u.email, p.product_name FROM orders o LEFT JOIN users u ON o.user_id = u.user_id JOIN order_items oi ON oi.order_id = o.order_id JOIN products p ON oi.product_id = p.product_id",
+		},
+		{
+			name:        "Tables that aren't directly related",
+			fields:      fields,
+			joins:       []models.JoinHint{{From: "users", To: "products"}},
+			expectedErr: services.ErrInvalidJoinHint,
+		},
+		{
+			name:        "Unsupported join type",
+			fields:      fields,
+			joins:       []models.JoinHint{{From: "users", To: "orders", Type: "full"}},
+			expectedErr: services.ErrInvalidJoinHint,
+		},
+		{
+			name:        "Hint not connected to the root",
+			fields:      fields,
+			joins:       []models.JoinHint{{From: "users", To: "orders"}, {From: "order_items", To: "products"}},
+			expectedErr: services.ErrInvalidJoinHint,
+		},
+		{
+			name:        "Left join from a table not yet joined",
+			fields:      fields,
+			joins:       []models.JoinHint{{From: "users", To: "orders"}, {From: "order_items", To: "orders", Type: "left"}},
+			expectedErr: services.ErrInvalidJoinHint,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{Fields: tc.fields, Joins: tc.joins})
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+
+	t.Run("Generated queries follow hints", func(t *testing.T) {
+		response, err := queryService.GenerateQuery(models.QueryRequest{
+			Description: "user email address and order total value",
+			Joins:       []models.JoinHint{{From: "users", To: "orders", Type: "left"}},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, response.Query, "FROM users u LEFT JOIN orders o")
+		require.NotEmpty(t, response.JoinsUsed)
+		assert.Equal(t, services.JoinTypeLeft, response.JoinsUsed[0].Type)
+	})
+}
+
+func TestJoinHintsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	testCases := []struct {
+		name           string
+		path           string
+		body           interface{}
+		expectedStatus int
+	}{
+		{
+			name: "Generate with an invalid hint",
+			path: "/api/v1/generate-query",
+			body: models.QueryRequest{
+				Description: "user email address",
+				Joins:       []models.JoinHint{{From: "users", To: "products"}},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Build with an invalid hint",
+			path: "/api/v1/build-query",
+			body: models.BuildQueryRequest{
+				Fields: []models.IntentField{{Table: "users", Column: "email"}},
+				Joins:  []models.JoinHint{{From: "users", To: "orders", Type: "cross"}},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Hint missing a table",
+			path:           "/api/v1/build-query",
+			body:           map[string]interface{}{"fields": []map[string]string{{"table": "users", "column": "email"}}, "joins": []map[string]string{{"from": "users"}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			req, _ := http.NewRequest(http.MethodPost, tc.path, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfiguredJoinTypes(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "profiles.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,join_type
+account_id,accounts,,,Account identifier,INTEGER,,,,
+email,accounts,,,Account email address,VARCHAR,,,,
+account_id,profiles,,,Account the profile belongs to,INTEGER,account_id,accounts,account_id,left
+profile_id,profiles,,,Profile identifier,INTEGER,,,,
+bio,profiles,,,Profile biography text,VARCHAR,,,,
+profile_id,avatars,,,Profile the avatar belongs to,INTEGER,profile_id,profiles,profile_id,
+image_url,avatars,,,Avatar image address,VARCHAR,,,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name          string
+		fields        []models.IntentField
+		joins         []models.JoinHint
+		expectedQuery string
+	}{
+		{
+			name:          "Optional relationship is left joined",
+			fields:        []models.IntentField{{Table: "accounts", Column: "email"}, {Table: "profiles", Column: "bio"}},
+			joins:         []models.JoinHint{{From: "accounts", To: "profiles"}},
+			expectedQuery: "SELECT a.email, p.bio FROM accounts a LEFT JOIN profiles p ON p.account_id = a.account_id",
+		},
+		{
+			name:          "Inner hint overrides the configured type",
+			fields:        []models.IntentField{{Table: "accounts", Column: "email"}, {Table: "profiles", Column: "bio"}},
+			joins:         []models.JoinHint{{From: "accounts", To: "profiles", Type: "inner"}},
+			expectedQuery: "SELECT a.email, p.bio FROM accounts a JOIN profiles p ON p.account_id = a.account_id",
+		},
+		{
+			name:          "Joins past a left join stay left joins",
+			fields:        []models.IntentField{{Table: "accounts", Column: "email"}, {Table: "avatars", Column: "image_url"}},
+			joins:         []models.JoinHint{{From: "accounts", To: "profiles"}, {From: "profiles", To: "avatars"}},
+			expectedQuery: "SELECT a.email, a2.image_url FROM accounts a LEFT JOIN profiles p ON p.account_id = a.account_id LEFT JOIN avatars a2 ON a2.profile_id = p.profile_id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{Fields: tc.fields, Joins: tc.joins})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+}
+
+func TestOptionalJoinPhrases(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name         string
+		description  string
+		joins        []models.JoinHint
+		expectedJoin string
+	}{
+		{
+			name:         "Including rows without related rows",
+			description:  "user email and order total including users without orders",
+			expectedJoin: "FROM users u LEFT JOIN orders o",
+		},
+		{
+			name:         "With no related rows",
+			description:  "user email and order total, even users with no orders",
+			expectedJoin: "FROM users u LEFT JOIN orders o",
+		},
+		{
+			name:         "No phrase keeps inner joins",
+			description:  "user email and order total",
+			expectedJoin: "FROM users u JOIN orders o",
+		},
+		{
+			name:         "Request join hints win",
+			description:  "user email and order total including users without orders",
+			joins:        []models.JoinHint{{From: "orders", To: "users"}},
+			expectedJoin: "FROM orders o JOIN users u",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{Description: tc.description, Joins: tc.joins})
+			require.NoError(t, err)
+			assert.Contains(t, response.Query, tc.expectedJoin)
+		})
+	}
+}
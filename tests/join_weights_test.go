@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightedJoinPaths(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "billing.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,join_weight
+customer_id,customers,,,Customer identifier,INTEGER,,,,
+customer_name,customers,,,Customer legal name,VARCHAR,,,,
+account_id,accounts,,,Account identifier,INTEGER,,,,
+customer_id,accounts,,,Customer owning the account,INTEGER,customer_id,customers,customer_id,
+invoice_id,invoices,,,Invoice identifier,INTEGER,,,,
+amount,invoices,,,Invoice amount due,INTEGER,,,,
+account_id,invoices,,,Account billed,INTEGER,account_id,accounts,account_id,0.5
+customer_id,invoices,,,Customer copied at invoicing time,INTEGER,customer_id,customers,customer_id,3
+`), 0o644))
+
+	testCases := []struct {
+		name          string
+		joinWeights   string
+		expectedQuery string
+		expectedCost  float64
+		expectError   bool
+	}{
+		{
+			name:          "Curated path beats a shorter incidental one",
+			expectedQuery: "SELECT i.amount, c.customer_name FROM invoices i JOIN accounts a ON i.account_id = a.account_id JOIN customers c ON a.customer_id = c.customer_id",
+			expectedCost:  1.5,
+		},
+		{
+			name:          "Configured weights override the mappings",
+			joinWeights:   "customers:invoices=0.25",
+			expectedQuery: "SELECT i.amount, c.customer_name FROM invoices i JOIN customers c ON i.customer_id = c.customer_id",
+			expectedCost:  0.25,
+		},
+		{
+			name:        "Invalid configured weight",
+			joinWeights: "customers:invoices=cheap",
+			expectError: true,
+		},
+		{
+			name:        "Weight without a table pair",
+			joinWeights: "invoices=2",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath, JoinWeights: tc.joinWeights})
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			response, err := services.NewQueryService(fieldService).BuildQuery(models.BuildQueryRequest{
+				Fields: []models.IntentField{{Table: "invoices", Column: "amount"}, {Table: "customers", Column: "customer_name"}},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+			assert.Equal(t, tc.expectedCost, response.JoinCost)
+		})
+	}
+}
+
+func TestUnweightedJoinCost(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+
+	response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{Description: "user email and product name"})
+	require.NoError(t, err)
+
+	// users -> orders -> order_items -> products, each weighing the default 1
+	assert.Len(t, response.JoinsUsed, 3)
+	assert.Equal(t, 3.0, response.JoinCost)
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// avroSchemaJSON is a record with primitive, nullable, logical, and nested fields
+const avroSchemaJSON = `{
+  "type": "record",
+  "name": "com.example.lake.Events",
+  "fields": [
+    {"name": "event_id", "type": "long", "doc": "Unique identifier for event"},
+    {"name": "user_id", "type": ["null", "int"]},
+    {"name": "occurred_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "payload", "type": {"type": "record", "name": "Payload", "fields": []}},
+    {"name": "labels", "type": {"type": "array", "items": "string"}}
+  ]
+}`
+
+// compactWriter encodes the Thrift compact protocol for test footers
+type compactWriter struct {
+	buf    []byte
+	lastID []int16
+}
+
+func (w *compactWriter) field(id int16, fieldType byte) {
+	last := &w.lastID[len(w.lastID)-1]
+	w.buf = append(w.buf, byte(id-*last)<<4|fieldType)
+	*last = id
+}
+
+func (w *compactWriter) i32(id int16, value int32) {
+	w.field(id, 5)
+	w.buf = binary.AppendUvarint(w.buf, uint64(uint32(value<<1)^uint32(value>>31)))
+}
+
+func (w *compactWriter) str(id int16, value string) {
+	w.field(id, 8)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(value)))
+	w.buf = append(w.buf, value...)
+}
+
+func (w *compactWriter) begin() { w.lastID = append(w.lastID, 0) }
+
+func (w *compactWriter) end() {
+	w.buf = append(w.buf, 0)
+	w.lastID = w.lastID[:len(w.lastID)-1]
+}
+
+// parquetFile builds a Parquet file whose footer holds id, name, created_at
+// (with a timestamp logical type), and an address group with one child
+func parquetFile() []byte {
+	w := &compactWriter{}
+	w.begin()
+	w.i32(1, 1)
+	w.field(2, 9)
+	w.buf = append(w.buf, 6<<4|12)
+
+	element := func(build func()) {
+		w.begin()
+		build()
+		w.end()
+	}
+	element(func() { w.str(4, "schema"); w.i32(5, 4) })
+	element(func() { w.i32(1, 2); w.str(4, "id") })
+	element(func() { w.i32(1, 6); w.str(4, "name"); w.i32(6, 0) })
+	element(func() {
+		w.i32(1, 2)
+		w.str(4, "created_at")
+		w.field(10, 12)
+		w.begin()
+		w.field(8, 12)
+		w.begin()
+		w.field(1, 1)
+		w.end()
+		w.end()
+	})
+	element(func() { w.str(4, "address"); w.i32(5, 1) })
+	element(func() { w.i32(1, 6); w.str(4, "city"); w.i32(6, 0) })
+	w.end()
+
+	data := append([]byte("PAR1"), []byte("row data")...)
+	data = append(data, w.buf...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(w.buf)))
+	return append(data, "PAR1"...)
+}
+
+func TestLakeSchemaImport(t *testing.T) {
+	dir := t.TempDir()
+	avsc := filepath.Join(dir, "events.avsc")
+	require.NoError(t, os.WriteFile(avsc, []byte(avroSchemaJSON), 0o644))
+
+	container := append([]byte("Obj\x01"), binary.AppendVarint(nil, 1)...)
+	container = append(binary.AppendVarint(container, int64(len("avro.schema"))), "avro.schema"...)
+	container = append(binary.AppendVarint(container, int64(len(avroSchemaJSON))), avroSchemaJSON...)
+	container = append(binary.AppendVarint(container, 0), make([]byte, 16)...)
+	avro := filepath.Join(dir, "click-stream.avro")
+	require.NoError(t, os.WriteFile(avro, container, 0o644))
+
+	parquet := filepath.Join(dir, "orders.parquet")
+	require.NoError(t, os.WriteFile(parquet, parquetFile(), 0o644))
+
+	testCases := []struct {
+		name     string
+		path     string
+		table    string
+		expected map[string]string
+	}{
+		{
+			name:  "Avro schema named after its record",
+			path:  avsc,
+			table: "Events",
+			expected: map[string]string{
+				"event_id": "BIGINT", "user_id": "INTEGER", "occurred_at": "TIMESTAMP",
+				"payload": "STRUCT", "labels": "ARRAY",
+			},
+		},
+		{
+			name:     "Avro data file named after the file",
+			path:     avro,
+			table:    "click_stream",
+			expected: map[string]string{"event_id": "BIGINT", "occurred_at": "TIMESTAMP"},
+		},
+		{
+			name:  "Parquet footer",
+			path:  parquet,
+			table: "orders",
+			expected: map[string]string{
+				"id": "BIGINT", "name": "VARCHAR", "created_at": "TIMESTAMP", "address": "STRUCT",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{CSVPath: tc.path})
+			require.NoError(t, err)
+			for column, expectedType := range tc.expected {
+				field, exists := fieldService.LookupField(tc.table, column)
+				require.True(t, exists, column)
+				assert.Equal(t, expectedType, field.FieldType, column)
+			}
+		})
+	}
+
+	t.Run("Nested Parquet fields are not columns", func(t *testing.T) {
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: parquet})
+		require.NoError(t, err)
+		assert.Len(t, fieldService.GetAllFields("default"), 4)
+		_, exists := fieldService.LookupField("orders", "city")
+		assert.False(t, exists)
+	})
+
+	t.Run("Descriptions are left blank unless documented", func(t *testing.T) {
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: avsc})
+		require.NoError(t, err)
+		documented, _ := fieldService.LookupField("Events", "event_id")
+		assert.Equal(t, "Unique identifier for event", documented.Description)
+		undocumented, _ := fieldService.LookupField("Events", "user_id")
+		assert.Empty(t, undocumented.Description)
+	})
+
+	t.Run("Corrupt footer", func(t *testing.T) {
+		corrupt := filepath.Join(dir, "corrupt.parquet")
+		require.NoError(t, os.WriteFile(corrupt, []byte("PAR1\x05\x00\x00\x00PAR1"), 0o644))
+		_, err := services.NewFieldService(&config.Config{CSVPath: corrupt})
+		assert.Error(t, err)
+	})
+
+	t.Run("Oversized map in footer", func(t *testing.T) {
+		// A map field claiming 2^32-1 entries of booleans, which take a
+		// byte each, so the footer runs out long before they do
+		footer := []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x11, 0x00}
+		data := append([]byte("PAR1"), footer...)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(footer)))
+		oversized := filepath.Join(dir, "oversized.parquet")
+		require.NoError(t, os.WriteFile(oversized, append(data, "PAR1"...), 0o644))
+		_, err := services.NewFieldService(&config.Config{CSVPath: oversized})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLint(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,cardinality
+customer_id,customers,,,Customer identifier,INTEGER,,,,
+name,customers,,,Customer name,VARCHAR,,,,
+region,customers,,,Customer sales region,VARCHAR,,,,
+order_id,orders,,,Order identifier,INTEGER,,,,
+customer_id,orders,,,Customer who ordered,INTEGER,customer_id,customers,customer_id,many-to-one
+region,regions,,,Sales region name,VARCHAR,,,,
+manager,regions,,,Regional sales manager,VARCHAR,region,customers,region,many-to-many
+`), 0o644))
+
+	testCases := []struct {
+		name             string
+		cfg              config.Config
+		request          models.BuildQueryRequest
+		expectedWarnings []string
+	}{
+		{
+			name: "Clean query",
+			request: models.BuildQueryRequest{Fields: []models.IntentField{
+				{Table: "orders", Column: "order_id"}, {Table: "customers", Column: "name"},
+			}},
+		},
+		{
+			name: "Aggregate over a fan-out join",
+			request: models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "customers", Column: "region"}, {Table: "orders", Column: "order_id", Aggregate: "count"}},
+				GroupBy: []models.FieldRef{{Table: "customers", Column: "region"}},
+				Joins:   []models.JoinHint{{From: "customers", To: "orders"}},
+			},
+			expectedWarnings: []string{
+				"joining customers to orders is one-to-many: each customers row may appear several times, so counts and sums over customers can be inflated",
+				"COUNT(o.order_id) is computed over the one-to-many join of customers to orders, which repeats customers rows: it may count or sum them more than once",
+			},
+		},
+		{
+			name: "Join on a key unique on neither side",
+			request: models.BuildQueryRequest{Fields: []models.IntentField{
+				{Table: "regions", Column: "manager"}, {Table: "customers", Column: "name"},
+			}},
+			expectedWarnings: []string{
+				"joining regions to customers is many-to-many: each regions row may appear several times, so counts and sums over regions can be inflated",
+				"the key joining regions and customers is unique on neither side: rows sharing a key pair with each other (a partial cartesian product)",
+			},
+		},
+		{
+			name: "Wide select without a limit",
+			cfg:  config.Config{WideSelectColumns: 3},
+			request: models.BuildQueryRequest{Fields: []models.IntentField{
+				{Table: "customers", Column: "customer_id"}, {Table: "customers", Column: "name"}, {Table: "customers", Column: "region"},
+			}},
+			expectedWarnings: []string{"3 columns are selected without a LIMIT: add one to keep the result manageable"},
+		},
+		{
+			name: "Wide select with a limit",
+			cfg:  config.Config{WideSelectColumns: 3},
+			request: models.BuildQueryRequest{
+				Fields: []models.IntentField{
+					{Table: "customers", Column: "customer_id"}, {Table: "customers", Column: "name"}, {Table: "customers", Column: "region"},
+				},
+				Limit: 100,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.CSVPath = csvPath
+			fieldService, err := services.NewFieldService(&cfg)
+			require.NoError(t, err)
+
+			response, err := services.NewQueryService(fieldService).BuildQuery(tc.request)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedWarnings, response.Warnings)
+		})
+	}
+
+	t.Run("Low-confidence matches", func(t *testing.T) {
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv", LowConfidenceScore: 1000})
+		require.NoError(t, err)
+		response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{Description: "user email"})
+		require.NoError(t, err)
+		require.NotEmpty(t, response.MatchedFields)
+		assert.Contains(t, response.Warnings[len(response.Warnings)-1], "under 1000.0: check it is the field you meant")
+	})
+}
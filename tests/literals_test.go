@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedFilterLiterals(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "events.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key
+event_id,events,,,Event identifier,UUID,,,
+event_date,events,,,Day the event happened,DATE,,,
+created_at,events,,,When the event was recorded,TIMESTAMP,,,
+is_test,events,,,Whether the event is synthetic,BOOLEAN,,,
+amount,events,,,Event amount,"DECIMAL(10,2)",,,
+attempts,events,,,Delivery attempts,INTEGER,,,
+client_ip,events,,,Client address,INET,,,
+label,events,,,Event label,VARCHAR(32),,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name              string
+		column            string
+		operator          string
+		value             interface{}
+		expectedCondition string
+		expectError       bool
+	}{
+		{name: "Date", column: "event_date", operator: ">=", value: "2024-03-01", expectedCondition: "e.event_date >= DATE '2024-03-01'"},
+		{name: "Spelled-out date", column: "event_date", operator: "=", value: "March 1, 2024", expectedCondition: "e.event_date = DATE '2024-03-01'"},
+		{name: "Invalid date", column: "event_date", operator: "=", value: "March 1st", expectError: true},
+		{name: "RFC 3339 timestamp", column: "created_at", operator: "<", value: "2024-03-01T12:30:00Z", expectedCondition: "e.created_at < TIMESTAMP '2024-03-01 12:30:00+00:00'"},
+		{name: "Date as timestamp", column: "created_at", operator: ">=", value: "2024-03-01", expectedCondition: "e.created_at >= TIMESTAMP '2024-03-01 00:00:00'"},
+		{name: "Boolean spelled out", column: "is_test", operator: "=", value: "yes", expectedCondition: "e.is_test = TRUE"},
+		{name: "Boolean value", column: "is_test", operator: "=", value: false, expectedCondition: "e.is_test = FALSE"},
+		{name: "Not a boolean", column: "is_test", operator: "=", value: "maybe", expectError: true},
+		{name: "Decimal from string", column: "amount", operator: ">", value: "19.99", expectedCondition: "e.amount > 19.99"},
+		{name: "Not a number", column: "amount", operator: ">", value: "1; DROP TABLE events", expectError: true},
+		{name: "Integer list", column: "attempts", operator: "in", value: []interface{}{1.0, "2"}, expectedCondition: "e.attempts IN (1, 2)"},
+		{name: "Fractional integer", column: "attempts", operator: "=", value: 1.5, expectError: true},
+		{name: "UUID", column: "event_id", operator: "=", value: "0E984725-C51C-4BF4-9960-E1C80E27ABA0", expectedCondition: "e.event_id = '0e984725-c51c-4bf4-9960-e1c80e27aba0'"},
+		{name: "Not a UUID", column: "event_id", operator: "=", value: "abc", expectError: true},
+		{name: "Network block", column: "client_ip", operator: "=", value: "10.0.0.0/8", expectedCondition: "e.client_ip = '10.0.0.0/8'"},
+		{name: "Sized string", column: "label", operator: "=", value: "it's", expectedCondition: "e.label = 'it''s'"},
+		{name: "Number against text", column: "label", operator: "=", value: 42.0, expectedCondition: "e.label = '42'"},
+		{name: "LIKE on a typed column", column: "attempts", operator: "like", value: "1%", expectedCondition: "e.attempts LIKE '1%'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "events", Column: "label"}},
+				Filters: []models.IntentFilter{{Table: "events", Column: tc.column, Operator: tc.operator, Value: tc.value}},
+			})
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrInvalidIntent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "SELECT e.label FROM events e WHERE "+tc.expectedCondition, response.Query)
+		})
+	}
+}
+
+func TestFilterLiteralWarnings(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "events.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key
+is_test,events,,,Whether the event is synthetic,BOOLEAN,,,
+attempts,events,,,Delivery attempts,INTEGER,,,
+label,events,,,Event label,VARCHAR(32),,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name             string
+		filter           models.IntentFilter
+		expectedWarnings []string
+	}{
+		{
+			name:   "Well-typed comparison",
+			filter: models.IntentFilter{Table: "events", Column: "attempts", Operator: ">", Value: "3"},
+		},
+		{
+			name:             "Number against text",
+			filter:           models.IntentFilter{Table: "events", Column: "label", Operator: "in", Value: []interface{}{"a", 7.0}},
+			expectedWarnings: []string{"e.label is VARCHAR text but is compared with a number, 7; the value is compared as text"},
+		},
+		{
+			name:             "LIKE on a number",
+			filter:           models.IntentFilter{Table: "events", Column: "attempts", Operator: "like", Value: "1%"},
+			expectedWarnings: []string{"LIKE matches the INTEGER column e.attempts as text: the result depends on how the database formats it"},
+		},
+		{
+			name:             "Ordering a boolean",
+			filter:           models.IntentFilter{Table: "events", Column: "is_test", Operator: ">", Value: "no"},
+			expectedWarnings: []string{"e.is_test is a boolean: > orders it with false before true, which rarely reads as intended"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "events", Column: "label"}},
+				Filters: []models.IntentFilter{tc.filter},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedWarnings, response.Warnings)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookMLImport(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../lookml.example.lkml"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		table        string
+		column       string
+		expectedType string
+		measure      string
+		foreignTable string
+	}{
+		{name: "Dimension reads its column", table: "orders", column: "total_amount", expectedType: "NUMERIC"},
+		{name: "Dimension group reads its column", table: "orders", column: "order_date", expectedType: "TIMESTAMP"},
+		{name: "Join becomes a reference", table: "orders", column: "user_id", expectedType: "NUMERIC", foreignTable: "users"},
+		{name: "Count measure", table: "users", column: "user_count", expectedType: "INTEGER", measure: "COUNT(*)"},
+		{name: "Sum measure inlines its dimension", table: "orders", column: "total_revenue", expectedType: "NUMERIC", measure: "SUM(orders.total_amount)"},
+		{name: "Number measure inlines other measures", table: "orders", column: "average_order_value", expectedType: "NUMERIC", measure: "SUM(orders.total_amount) / NULLIF(COUNT(*), 0)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			field, exists := fieldService.LookupField(tc.table, tc.column)
+			require.True(t, exists)
+			assert.Equal(t, tc.expectedType, field.FieldType)
+			assert.Equal(t, tc.measure, field.Measure)
+			assert.Equal(t, tc.foreignTable, field.ForeignTable)
+		})
+	}
+
+	t.Run("Generated aggregates use the measure SQL", func(t *testing.T) {
+		response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{
+			Description: "total revenue across orders",
+			MaxMatches:  1,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, response.Query, "SELECT SUM(o.total_amount) AS total_revenue FROM orders")
+	})
+
+	t.Run("Intents select measures under their name", func(t *testing.T) {
+		queryService := services.NewQueryService(fieldService)
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{
+				{Table: "users", Column: "email"},
+				{Table: "orders", Column: "total_revenue"},
+			},
+			GroupBy: []models.FieldRef{{Table: "users", Column: "email"}},
+			OrderBy: []models.IntentOrder{{Table: "orders", Column: "total_revenue", Direction: "desc"}},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, response.Query, "SELECT u.email, SUM(o.total_amount) AS total_revenue")
+		assert.Contains(t, response.Query, "ORDER BY total_revenue DESC")
+
+		_, err = queryService.BuildQuery(models.BuildQueryRequest{
+			Fields: []models.IntentField{{Table: "orders", Column: "total_revenue", Aggregate: "SUM"}},
+		})
+		assert.ErrorIs(t, err, services.ErrInvalidIntent)
+	})
+}
+
+func TestLookMLIncludes(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "views"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "views", "products.view.lkml"), []byte(`
+view: products {
+  dimension: product_id { primary_key: yes type: number }
+  dimension: name_upper { sql: UPPER(${TABLE}.name) ;; }
+  measure: filtered_count { type: count filters: [product_id: ">0"] }
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "views", "order_items.view.lkml"), []byte(`
+view: order_items {
+  dimension: product_id { type: number sql: ${TABLE}.product_id ;; }
+  measure: units { type: count_distinct sql: ${product_id} ;; }
+}
+`), 0o644))
+	model := filepath.Join(dir, "shop.model.lkml")
+	require.NoError(t, os.WriteFile(model, []byte(`
+include: "/views/*.view.lkml"
+
+explore: products {
+  join: order_items {
+    sql_on: ${products.product_id} = ${order_items.product_id} ;;
+    relationship: one_to_many
+  }
+}
+`), 0o644))
+
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: model})
+	require.NoError(t, err)
+	assert.Len(t, fieldService.GetAllFields("default"), 3)
+
+	field, exists := fieldService.LookupField("order_items", "product_id")
+	require.True(t, exists)
+	assert.Equal(t, "products", field.ForeignTable)
+
+	units, exists := fieldService.LookupField("order_items", "units")
+	require.True(t, exists)
+	assert.Equal(t, "COUNT(DISTINCT order_items.product_id)", units.Measure)
+
+	t.Run("Derived dimensions and filtered measures are skipped", func(t *testing.T) {
+		_, exists := fieldService.LookupField("products", "name_upper")
+		assert.False(t, exists)
+		_, exists = fieldService.LookupField("products", "filtered_count")
+		assert.False(t, exists)
+	})
+
+	t.Run("Syntax errors name the file and line", func(t *testing.T) {
+		broken := filepath.Join(dir, "broken.lkml")
+		require.NoError(t, os.WriteFile(broken, []byte("view: x {\n  dimension: y {\n    sql: ${TABLE}.y\n  }\n}\n"), 0o644))
+		_, err := services.NewFieldService(&config.Config{CSVPath: broken})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broken.lkml: line 3")
+	})
+}
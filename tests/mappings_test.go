@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mappingHeader = "column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key\n"
+
+// writeMappings writes named mapping files into a fresh directory
+func writeMappings(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestMergeMappingFiles(t *testing.T) {
+	dir := writeMappings(t, map[string]string{
+		"a_users.csv": mappingHeader +
+			"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+			"email,users,email_addr,user_email,User email address,VARCHAR,,,\n",
+		"b_orders.csv": mappingHeader +
+			"order_id,orders,order_num,transaction_id,Unique order identifier,INTEGER,,,\n" +
+			"user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,user_id\n" +
+			"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n",
+		"c_users.yaml": "tables:\n  - name: users\n    columns:\n" +
+			"      - name: email\n        description: Primary contact email\n        type: TEXT\n",
+		"notes.txt": "ignored",
+	})
+
+	testCases := []struct {
+		name string
+		spec string
+	}{
+		{name: "Directory", spec: dir},
+		{name: "Comma-separated list", spec: filepath.Join(dir, "a_users.csv") + ", " + filepath.Join(dir, "b_orders.csv") + "," + filepath.Join(dir, "c_users.yaml")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{CSVPath: tc.spec})
+			require.NoError(t, err)
+
+			report := fieldService.MappingReport()
+			assert.Len(t, report.Files, 3)
+			assert.Equal(t, 4, report.Fields)
+			require.Len(t, report.Diagnostics, 2)
+
+			// An identical redefinition is a duplicate, not a conflict
+			assert.Equal(t, "user_id", report.Diagnostics[0].Column)
+			assert.False(t, report.Diagnostics[0].Conflict)
+
+			// A disagreeing redefinition is a conflict; the first definition wins
+			assert.Equal(t, "email", report.Diagnostics[1].Column)
+			assert.True(t, report.Diagnostics[1].Conflict)
+			assert.Equal(t, []string{"field_description", "field_type", "system_a_fieldmap", "system_b_fieldmap"}, report.Diagnostics[1].Attributes)
+			email, found := fieldService.LookupField("users", "email")
+			require.True(t, found)
+			assert.Equal(t, "User email address", email.Description)
+
+			// Joins across files still resolve
+			_, err = fieldService.FindJoinPath("orders", "users")
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("Empty directory", func(t *testing.T) {
+		_, err := services.NewFieldService(&config.Config{CSVPath: t.TempDir()})
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing file in list", func(t *testing.T) {
+		_, err := services.NewFieldService(&config.Config{CSVPath: filepath.Join(dir, "a_users.csv") + ",missing.csv"})
+		assert.Error(t, err)
+	})
+}
+
+func TestMappingReportHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/mappings", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report models.MappingReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, []models.MappingFile{{Path: "../field_mappings.csv", Fields: 11}}, report.Files)
+	assert.Empty(t, report.Diagnostics)
+	assert.NotEmpty(t, report.Version)
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParameterizedQueries(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "events.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key
+created_at,events,,,When the event was recorded,TIMESTAMP,,,
+is_test,events,,,Whether the event is synthetic,BOOLEAN,,,
+attempts,events,,,Delivery attempts,INTEGER,,,
+label,events,,,Event label,VARCHAR(32),,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	filters := []models.IntentFilter{
+		{Table: "events", Column: "created_at", Operator: ">=", Value: "2024-03-01T12:30:00Z"},
+		{Table: "events", Column: "is_test", Operator: "=", Value: "no"},
+		{Table: "events", Column: "attempts", Operator: "in", Value: []interface{}{1.0, "2"}},
+		{Table: "events", Column: "label", Operator: "like", Value: "it's%"},
+	}
+	expectedParameters := []models.QueryParameter{
+		{Position: 1, Value: "2024-03-01 12:30:00+00:00", Type: "TIMESTAMP"},
+		{Position: 2, Value: false, Type: "BOOLEAN"},
+		{Position: 3, Value: int64(1), Type: "INTEGER"},
+		{Position: 4, Value: int64(2), Type: "INTEGER"},
+		{Position: 5, Value: "it's%", Type: "TEXT"},
+	}
+
+	testCases := []struct {
+		name          string
+		style         string
+		dialect       string
+		expectedStyle string
+		expectedWhere string
+		named         bool
+		expectError   bool
+	}{
+		{name: "Dollar", style: "dollar", expectedStyle: "dollar", expectedWhere: "e.created_at >= $1 AND e.is_test = $2 AND e.attempts IN ($3, $4) AND e.label LIKE $5"},
+		{name: "Question", style: "question", expectedStyle: "question", expectedWhere: "e.created_at >= ? AND e.is_test = ? AND e.attempts IN (?, ?) AND e.label LIKE ?"},
+		{name: "Named", style: "named", expectedStyle: "named", named: true, expectedWhere: "e.created_at >= :p1 AND e.is_test = :p2 AND e.attempts IN (:p3, :p4) AND e.label LIKE :p5"},
+		{name: "Dialect's own style", style: "dialect", dialect: "sqlserver", expectedStyle: "at", named: true, expectedWhere: "e.created_at >= @p1 AND e.is_test = @p2 AND e.attempts IN (@p3, @p4) AND e.label LIKE @p5"},
+		{name: "Dialect style defaults to dollar", style: "dialect", expectedStyle: "dollar", expectedWhere: "e.created_at >= $1 AND e.is_test = $2 AND e.attempts IN ($3, $4) AND e.label LIKE $5"},
+		{name: "Unknown style", style: "percent", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{
+				Fields:         []models.IntentField{{Table: "events", Column: "label"}},
+				Filters:        filters,
+				Dialect:        tc.dialect,
+				ParameterStyle: tc.style,
+			})
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrInvalidIntent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "SELECT e.label FROM events e WHERE "+tc.expectedWhere, response.Query)
+			assert.Equal(t, tc.expectedStyle, response.ParameterStyle)
+
+			expected := make([]models.QueryParameter, len(expectedParameters))
+			copy(expected, expectedParameters)
+			if tc.named {
+				for i := range expected {
+					expected[i].Name = fmt.Sprintf("p%d", i+1)
+				}
+			}
+			assert.Equal(t, expected, response.Parameters)
+		})
+	}
+
+	t.Run("Inline by default", func(t *testing.T) {
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:  []models.IntentField{{Table: "events", Column: "label"}},
+			Filters: filters[:1],
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT e.label FROM events e WHERE e.created_at >= TIMESTAMP '2024-03-01 12:30:00+00:00'", response.Query)
+		assert.Empty(t, response.Parameters)
+		assert.Empty(t, response.ParameterStyle)
+	})
+
+	t.Run("Invalid values are refused before binding", func(t *testing.T) {
+		_, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:         []models.IntentField{{Table: "events", Column: "label"}},
+			Filters:        []models.IntentFilter{{Table: "events", Column: "attempts", Operator: "=", Value: "1; DROP TABLE events"}},
+			ParameterStyle: "dollar",
+		})
+		assert.ErrorIs(t, err, services.ErrInvalidIntent)
+	})
+}
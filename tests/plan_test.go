@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPlan(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "shop.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,measure,schema_name
+user_id,users,,,User identifier,INTEGER,,,,,crm
+country,users,,,Country the user lives in,VARCHAR,,,,,crm
+order_id,orders,,,Order identifier,INTEGER,,,,,
+user_id,orders,,,User placing the order,INTEGER,user_id,users,user_id,,
+status,orders,,,Order status,VARCHAR,,,,,
+revenue,orders,,,Order revenue,DECIMAL,,,,SUM(orders.total),
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	t.Run("Structured intent", func(t *testing.T) {
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:  []models.IntentField{{Table: "orders", Column: "status"}, {Table: "orders", Column: "revenue"}, {Table: "users", Column: "country", Aggregate: "count"}},
+			Filters: []models.IntentFilter{{Table: "users", Column: "country", Operator: "is  not null"}},
+			GroupBy: []models.FieldRef{{Table: "orders", Column: "status"}},
+			OrderBy: []models.IntentOrder{{Table: "orders", Column: "revenue", Direction: "desc"}},
+			Limit:   5,
+			Dialect: "postgres",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, response.Plan)
+
+		withoutPlanIDs(t, response.Plan)
+		assert.Equal(t, models.QueryPlan{
+			Select: []models.PlanColumn{
+				{Expression: "o.status", Table: "orders", Column: "status"},
+				{Expression: "SUM(o.total)", Table: "orders", Column: "revenue", Aggregate: "MEASURE", Alias: "revenue"},
+				{Expression: "COUNT(u.country)", Table: "users", Column: "country", Aggregate: "COUNT"},
+			},
+			From: models.PlanTable{Table: "orders", Name: "orders", Alias: "o"},
+			Joins: []models.PlanJoin{
+				{Type: "inner", Table: models.PlanTable{Table: "users", Name: "crm.users", Alias: "u"}, Condition: "o.user_id = u.user_id"},
+			},
+			Filters: []models.PlanFilter{
+				{Expression: "u.country IS NOT NULL", Table: "users", Column: "country", Operator: "IS NOT NULL"},
+			},
+			GroupBy: []string{"o.status"},
+			OrderBy: []models.PlanOrder{{Expression: "revenue", Direction: "DESC"}},
+			Limit:   5,
+		}, *response.Plan)
+		assert.Equal(t, "SELECT o.status, SUM(o.total) AS revenue, COUNT(u.country) FROM orders o JOIN crm.users u ON o.user_id = u.user_id WHERE u.country IS NOT NULL GROUP BY o.status ORDER BY revenue DESC LIMIT 5", response.Query)
+	})
+
+	t.Run("Generated query", func(t *testing.T) {
+		response, err := queryService.GenerateQuery(models.QueryRequest{Description: "count of order status", Offset: 10})
+		require.NoError(t, err)
+		require.NotNil(t, response.Plan)
+
+		plan := response.Plan
+		withoutPlanIDs(t, plan)
+		require.Len(t, plan.Select, 1)
+		assert.Equal(t, models.PlanColumn{Expression: "COUNT(o.status)", Table: "orders", Column: "status", Aggregate: "COUNT"}, plan.Select[0])
+		assert.Equal(t, models.PlanTable{Table: "orders", Name: "orders", Alias: "o"}, plan.From)
+		require.Len(t, plan.Joins, 1)
+		assert.Equal(t, models.PlanTable{Table: "users", Name: "crm.users", Alias: "u"}, plan.Joins[0].Table)
+		assert.Equal(t, 10, plan.Offset)
+		assert.Equal(t, "SELECT COUNT(o.status) FROM orders o JOIN crm.users u ON o.user_id = u.user_id OFFSET 10", response.Query)
+	})
+}
+
+// withoutPlanIDs checks every element of a plan has a unique ID, then
+// clears them so the rest of the plan can be compared
+func withoutPlanIDs(t *testing.T, plan *models.QueryPlan) {
+	t.Helper()
+	seen := make(map[string]bool)
+	check := func(id *string) {
+		assert.NotEmpty(t, *id)
+		assert.False(t, seen[*id], "repeated plan ID %s", *id)
+		seen[*id] = true
+		*id = ""
+	}
+	for i := range plan.Select {
+		check(&plan.Select[i].ID)
+	}
+	for i := range plan.Joins {
+		check(&plan.Joins[i].ID)
+	}
+	for i := range plan.Filters {
+		check(&plan.Filters[i].ID)
+	}
+	for i := range plan.OrderBy {
+		check(&plan.OrderBy[i].ID)
+	}
+}
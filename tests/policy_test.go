@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicySimulation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policyPath := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(policyPath, []byte(`
+roles:
+  analyst:
+    allow_tables: [orders, products]
+  support:
+    deny_fields: [users.email]
+  guest:
+    allow_tables: [products]
+`), 0o644))
+
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{
+		CSVPath:    "../field_mappings.csv",
+		PolicyPath: policyPath,
+		AdminToken: "secret",
+	}))
+
+	simulate := func(token string, request models.PolicySimulationRequest) (*httptest.ResponseRecorder, models.PolicySimulationResponse) {
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPost, "/admin/policies/simulate", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var response models.PolicySimulationResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	testCases := []struct {
+		name           string
+		token          string
+		request        models.PolicySimulationRequest
+		expectedStatus int
+		wouldSucceed   bool
+		blocked        string
+	}{
+		{
+			name:           "Missing admin token",
+			request:        models.PolicySimulationRequest{Role: "support", Description: "user email"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Unknown role",
+			token:          "secret",
+			request:        models.PolicySimulationRequest{Role: "intern", Description: "user email"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Denied field is dropped from the query",
+			token:          "secret",
+			request:        models.PolicySimulationRequest{Role: "support", Description: "user email address"},
+			expectedStatus: http.StatusOK,
+			wouldSucceed:   true,
+			blocked:        "users.email",
+		},
+		{
+			name:           "Every field blocked",
+			token:          "secret",
+			request:        models.PolicySimulationRequest{Role: "guest", Description: "user email address"},
+			expectedStatus: http.StatusOK,
+			blocked:        "users.email",
+		},
+		{
+			name:           "Table outside allow list is blocked",
+			token:          "secret",
+			request:        models.PolicySimulationRequest{Role: "analyst", Description: "order value for user email"},
+			expectedStatus: http.StatusOK,
+			wouldSucceed:   true,
+			blocked:        "users.email",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, response := simulate(tc.token, tc.request)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tc.wouldSucceed, response.WouldSucceed, response.Reason)
+			var blocked []string
+			for _, decision := range response.Decisions {
+				if !decision.Allowed {
+					blocked = append(blocked, decision.TableName+"."+decision.ColumnName)
+					assert.NotEmpty(t, decision.Reason)
+				}
+			}
+			assert.Contains(t, blocked, tc.blocked)
+			if tc.wouldSucceed {
+				assert.NotContains(t, response.Query, tc.blocked)
+			} else {
+				assert.Empty(t, response.Query)
+			}
+		})
+	}
+}
+
+func TestPolicySimulationMatchesGeneration(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "shop.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,classification,measure
+user_id,users,,,User identifier,INTEGER,,,,,
+email,users,,,User email address,VARCHAR,,,,restricted,
+order_id,orders,,,Order identifier,INTEGER,,,,,
+total,orders,,,Order total amount,DECIMAL,,,,,
+refunds,orders,,,Refunds issued,DECIMAL,,,,,SUM(total); DROP TABLE orders
+`), 0o644))
+	policyPath := filepath.Join(dir, "policies.yaml")
+	require.NoError(t, os.WriteFile(policyPath, []byte(`
+roles:
+  analyst:
+    allow_tables: ["*"]
+aggregate_only:
+  tables: [orders]
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	policies, err := services.LoadPolicies(policyPath)
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+	queryService.UsePolicies(policies)
+
+	testCases := []struct {
+		name         string
+		description  string
+		clearance    string
+		wouldSucceed bool
+	}{
+		{name: "Restricted field above the caller's clearance", description: "email address", wouldSucceed: false},
+		{name: "Restricted field within the caller's clearance", description: "email address", clearance: services.ClassificationRestricted, wouldSucceed: true},
+		{name: "Rows of an aggregate-only table", description: "order total amount", wouldSucceed: false},
+		{name: "Count of an aggregate-only table", description: "count orders by total amount", wouldSucceed: true},
+		{name: "Measure that fails the read-only check", description: "refunds issued", wouldSucceed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			simulation, err := queryService.SimulatePolicy(policies, models.PolicySimulationRequest{Role: "analyst", Description: tc.description, Clearance: tc.clearance})
+			require.NoError(t, err)
+			_, generationErr := queryService.GenerateQuery(models.QueryRequest{Description: tc.description, Clearance: tc.clearance})
+
+			assert.Equal(t, tc.wouldSucceed, simulation.WouldSucceed, simulation.Reason)
+			assert.Equal(t, tc.wouldSucceed, generationErr == nil, generationErr)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/mgarce/go_query_api/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// loadSyntheticSchema generates a schema and loads it into a field service
+func loadSyntheticSchema(t *testing.T, opts testutil.SchemaOptions) (*testutil.Schema, *services.FieldService) {
+	t.Helper()
+
+	schema := testutil.GenerateSchema(opts)
+	path := filepath.Join(t.TempDir(), "fields.csv")
+	assert.NoError(t, schema.WriteCSVFile(path))
+
+	service, err := services.NewFieldService(&config.Config{CSVPath: path})
+	assert.NoError(t, err)
+	return schema, service
+}
+
+func TestJoinPathProperties(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		schema, service := loadSyntheticSchema(t, testutil.SchemaOptions{
+			Tables:         12,
+			FieldsPerTable: 4,
+			Connectivity:   1.0,
+			ExtraJoins:     4,
+			Seed:           seed,
+		})
+
+		// In a fully connected schema every pair of tables has a valid chain
+		for _, from := range schema.Tables {
+			for _, to := range schema.Tables {
+				joins, err := service.FindJoinPath(from, to)
+				if !assert.NoError(t, err, "%s -> %s (seed %d)", from, to, seed) {
+					continue
+				}
+				current := from
+				for _, join := range joins {
+					assert.Equal(t, current, join.From)
+					current = join.To
+				}
+				assert.Equal(t, to, current)
+			}
+		}
+	}
+}
+
+func TestMatchRankingProperties(t *testing.T) {
+	schema, service := loadSyntheticSchema(t, testutil.SchemaOptions{
+		Tables:         20,
+		FieldsPerTable: 10,
+		Connectivity:   1.0,
+		Seed:           7,
+	})
+	queryService := services.NewQueryService(service)
+
+	for _, entry := range testutil.GenerateCorpus(schema, 50, 7) {
+		keywords := strings.Fields(entry.Description)
+
+		// Results are bounded, sorted, and above the threshold
+		matches := service.FindFieldMatches(keywords, 30.0, 5)
+		assert.LessOrEqual(t, len(matches), 5)
+		for i, match := range matches {
+			assert.GreaterOrEqual(t, match.MatchScore, 30.0)
+			if i > 0 {
+				assert.GreaterOrEqual(t, matches[i-1].MatchScore, match.MatchScore)
+			}
+		}
+
+		// Generating from a description built from real fields always succeeds
+		response, err := queryService.GenerateQuery(models.QueryRequest{Description: entry.Description})
+		if assert.NoError(t, err, entry.Description) {
+			assert.NotEmpty(t, response.Query)
+		}
+	}
+}
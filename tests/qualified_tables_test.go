@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQualifiedTableNames(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "warehouse.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,schema_name,database_name
+user_id,users,,,User identifier,INTEGER,,,,crm,
+email,users,,,User email address,VARCHAR,,,,crm,
+order_id,orders,,,Order identifier,INTEGER,,,,analytics,mydb
+user_id,orders,,,User placing the order,INTEGER,user_id,users,user_id,analytics,mydb
+total,orders,,,Order total,DECIMAL,,,,,
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		dialect      string
+		expectedFrom string
+	}{
+		{dialect: "", expectedFrom: "FROM crm.users u JOIN mydb.analytics.orders o"},
+		{dialect: "postgres", expectedFrom: "FROM crm.users u JOIN analytics.orders o"},
+		{dialect: "mysql", expectedFrom: "FROM crm.users u JOIN mydb.orders o"},
+		{dialect: "sqlserver", expectedFrom: "FROM crm.users u JOIN mydb.analytics.orders o"},
+		{dialect: "bigquery", expectedFrom: "FROM crm.users u JOIN mydb.analytics.orders o"},
+	}
+
+	for _, tc := range testCases {
+		t.Run("dialect "+tc.dialect, func(t *testing.T) {
+			response, err := queryService.BuildQuery(models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "users", Column: "email"}, {Table: "orders", Column: "total"}},
+				Dialect: tc.dialect,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "SELECT u.email, o.total "+tc.expectedFrom+" ON o.user_id = u.user_id", response.Query)
+		})
+	}
+
+	t.Run("Database without a schema", func(t *testing.T) {
+		legacyPath := filepath.Join(t.TempDir(), "legacy.csv")
+		require.NoError(t, os.WriteFile(legacyPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,database_name
+invoice_id,invoices,,,Invoice identifier,INTEGER,,,,billing
+`), 0o644))
+		legacy, err := services.NewFieldService(&config.Config{CSVPath: legacyPath})
+		require.NoError(t, err)
+
+		response, err := services.NewQueryService(legacy).BuildQuery(models.BuildQueryRequest{
+			Fields:  []models.IntentField{{Table: "invoices", Column: "invoice_id"}},
+			Dialect: "sqlserver",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT i.invoice_id FROM billing..invoices i", response.Query)
+	})
+
+	t.Run("Full-name aliases keep the qualified name", func(t *testing.T) {
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:     []models.IntentField{{Table: "users", Column: "email"}},
+			AliasStyle: "full",
+			Dialect:    "postgres",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT users.email FROM crm.users", response.Query)
+	})
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateQueryPair(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "systems.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,system_a_tablemap,system_b_tablemap
+user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,,tbl_user,
+email,users,email_addr,,User email address,VARCHAR,,,,tbl_user,
+order_id,orders,order_num,transaction_id,Unique order identifier,INTEGER,,,,tbl_order,Orders
+user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,user_id,tbl_order,Orders
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	response, err := queryService.GenerateQueryPair(models.QueryRequest{
+		Description: "order id and user email",
+		Dialect:     "postgres",
+	})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, response.TraceID)
+	assert.Equal(t, response.TraceID, response.SystemA.TraceID)
+	assert.Equal(t, `SELECT u.uid, u.email_addr, o.order_num, o.customer_id FROM tbl_user u JOIN tbl_order o ON o.customer_id = u.uid`, response.SystemA.Query)
+	assert.Equal(t, `SELECT u.user_identifier, u.email, o.transaction_id, o.user_ref FROM users u JOIN "Orders" o ON o.user_ref = u.user_identifier`, response.SystemB.Query)
+	assert.Equal(t, []models.ColumnMapping{
+		{Table: "users", Column: "user_id", Description: "Unique identifier for user", SystemA: "tbl_user.uid", SystemB: "users.user_identifier"},
+		{Table: "users", Column: "email", Description: "User email address", SystemA: "tbl_user.email_addr", SystemB: "users.email"},
+		{Table: "orders", Column: "order_id", Description: "Unique order identifier", SystemA: "tbl_order.order_num", SystemB: "Orders.transaction_id"},
+		{Table: "orders", Column: "user_id", Description: "User who placed order", SystemA: "tbl_order.customer_id", SystemB: "Orders.user_ref"},
+	}, response.ColumnMap)
+	assert.Equal(t, []string{"users.email has no system_b name; the query uses its mapped name"}, response.Warnings)
+}
+
+func TestQueryPairHandler(t *testing.T) {
+	r, err := setupTestRouter()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		request        models.QueryRequest
+		expectedStatus int
+	}{
+		{
+			name:           "Valid description",
+			request:        models.QueryRequest{Description: "get user email"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing description",
+			request:        models.QueryRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown table hint",
+			request:        models.QueryRequest{Description: "get user email", Tables: []string{"customers"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.request)
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query/pair", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -8,6 +10,7 @@ import (
 	"github.com/mgarce/go_query_api/internal/models"
 	"github.com/mgarce/go_query_api/internal/services"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestQueryService(t *testing.T) {
@@ -35,7 +38,7 @@ func TestQueryService(t *testing.T) {
 			description:   "Get user emails",
 			expectSuccess: true,
 			checkFunction: func(t *testing.T, response models.QueryResponse) {
-				assert.Contains(t, response.Query, "users.email")
+				assert.Contains(t, response.Query, "u.email")
 				assert.NotEmpty(t, response.MatchedFields)
 				assert.GreaterOrEqual(t, response.Confidence, 50.0)
 			},
@@ -52,7 +55,7 @@ func TestQueryService(t *testing.T) {
 		},
 		{
 			name:          "Unique products query",
-			description:   "Find unique products ordered",
+			description:   "Find unique product display names",
 			expectSuccess: true,
 			checkFunction: func(t *testing.T, response models.QueryResponse) {
 				assert.Contains(t, response.Query, "DISTINCT")
@@ -66,7 +69,7 @@ func TestQueryService(t *testing.T) {
 			expectSuccess: true,
 			checkFunction: func(t *testing.T, response models.QueryResponse) {
 				assert.Contains(t, response.Query, "JOIN")
-				assert.Contains(t, strings.ToLower(response.Query), "orders")
+				assert.Contains(t, strings.ToLower(response.Query), "order_items")
 				assert.Contains(t, strings.ToLower(response.Query), "products")
 				assert.NotEmpty(t, response.JoinsUsed)
 			},
@@ -92,7 +95,8 @@ func TestQueryService(t *testing.T) {
 			if tc.expectSuccess {
 				assert.NoError(t, err)
 				assert.NotEmpty(t, response.Query)
-				assert.NotZero(t, response.ProcessingTime)
+				// Generation often takes under a millisecond
+				assert.GreaterOrEqual(t, response.ProcessingTime, int64(0))
 				
 				if tc.checkFunction != nil {
 					tc.checkFunction(t, response)
@@ -156,4 +160,482 @@ func TestQueryTypeIdentification(t *testing.T) {
 			assert.Contains(t, response.Query, "DISTINCT")
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestAliasStyles(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+	}
+
+	fieldService, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		aliasStyle string
+		expected   []string
+	}{
+		{"", []string{"FROM users u", "JOIN orders o", "u.email", "ON o.user_id = u.user_id"}},
+		{"first_letter", []string{"FROM users u", "JOIN orders o"}},
+		{"abbreviated", []string{"FROM users usr", "JOIN orders ord", "usr.email", "ON ord.user_id = usr.user_id"}},
+		{"numeric", []string{"FROM users t1", "JOIN orders t2", "t1.email", "ON t2.user_id = t1.user_id"}},
+		{"full", []string{"users.email", "FROM users JOIN orders ON orders.user_id = users.user_id"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run("style "+tc.aliasStyle, func(t *testing.T) {
+			request := models.QueryRequest{
+				Description: "orders placed by user email",
+				AliasStyle:  tc.aliasStyle,
+			}
+
+			// Generate twice to verify aliases are stable across calls
+			first, err := queryService.GenerateQuery(request)
+			assert.NoError(t, err)
+			second, err := queryService.GenerateQuery(request)
+			assert.NoError(t, err)
+			assert.Equal(t, first.Query, second.Query)
+
+			for _, fragment := range tc.expected {
+				assert.Contains(t, first.Query, fragment)
+			}
+		})
+	}
+
+	// Curated aliases from the mappings win over every style but numeric
+	curated := []struct {
+		aliasStyle string
+		expected   string
+	}{
+		{"first_letter", "order_items oi"},
+		{"abbreviated", "order_items oi"},
+		{"numeric", "order_items t1"},
+	}
+	for _, tc := range curated {
+		t.Run("curated "+tc.aliasStyle, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{
+				Description: "order line item identifier",
+				AliasStyle:  tc.aliasStyle,
+			})
+			assert.NoError(t, err)
+			assert.Contains(t, response.Query, tc.expected)
+		})
+	}
+	
+	// Unknown styles are rejected
+	_, err = queryService.GenerateQuery(models.QueryRequest{
+		Description: "orders placed by user email",
+		AliasStyle:  "bogus",
+	})
+	assert.ErrorIs(t, err, services.ErrUnknownAliasStyle)
+}
+
+func TestAliasCollisionsAndOverrides(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "roles.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key
+user_id,users,,,User identifier,INTEGER,,,
+email,users,,,User email address,VARCHAR,,,
+user_id,user_roles,,,User holding the role,INTEGER,user_id,users,user_id
+role_name,user_roles,,,Role name,VARCHAR,,,
+`), 0o644))
+	
+	testCases := []struct {
+		name          string
+		tableAliases  string
+		expectedQuery string
+		expectError   bool
+	}{
+		{
+			name:          "Colliding first letters",
+			expectedQuery: "SELECT u.email, u2.role_name FROM users u JOIN user_roles u2 ON u2.user_id = u.user_id",
+		},
+		{
+			name:          "Configured aliases",
+			tableAliases:  "user_roles=ur, users = usr",
+			expectedQuery: "SELECT usr.email, ur.role_name FROM users usr JOIN user_roles ur ON ur.user_id = usr.user_id",
+		},
+		{name: "Invalid configured alias", tableAliases: "users=u-1", expectError: true},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath, TableAliases: tc.tableAliases})
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			
+			response, err := services.NewQueryService(fieldService).BuildQuery(models.BuildQueryRequest{
+				Fields: []models.IntentField{{Table: "users", Column: "email"}, {Table: "user_roles", Column: "role_name"}},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+}
+
+func TestFreshnessNotes(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+	}
+
+	fieldService, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	queryService := services.NewQueryService(fieldService)
+
+	// products is loaded daily and order_items hourly
+	response, err := queryService.GenerateQuery(models.QueryRequest{
+		Description: "product display name",
+	})
+	assert.NoError(t, err)
+	notes := make(map[string]models.FreshnessNote)
+	for _, note := range response.Freshness {
+		notes[note.Table] = note
+	}
+	assert.Equal(t, "daily", notes["products"].Cadence)
+	assert.Contains(t, notes["products"].Note, "24h")
+	assert.NotContains(t, notes, "users")
+
+	// Real-time tables produce no notes
+	response, err = queryService.GenerateQuery(models.QueryRequest{
+		Description: "user email address",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, response.Freshness)
+}
+
+func TestTagFiltering(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+	}
+
+	fieldService, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name        string
+		request     models.QueryRequest
+		expectTable string
+		rejectField string
+		expectError bool
+	}{
+		{
+			name: "Include tags restrict matching to a domain",
+			request: models.QueryRequest{
+				Description: "user who placed order",
+				IncludeTags: []string{"identity"},
+			},
+			expectTable: "users",
+			rejectField: "orders.user_id",
+		},
+		{
+			name: "Exclude tags drop fields",
+			request: models.QueryRequest{
+				Description: "user email address",
+				ExcludeTags: []string{"PII"},
+			},
+			rejectField: "users.email",
+		},
+		{
+			name: "No fields left after filtering",
+			request: models.QueryRequest{
+				Description: "product display name",
+				IncludeTags: []string{"identity"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(tc.request)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, response.MatchedFields)
+			for _, match := range response.MatchedFields {
+				if tc.expectTable != "" {
+					assert.Equal(t, tc.expectTable, match.TableName)
+				}
+				assert.NotEqual(t, tc.rejectField, match.TableName+"."+match.ColumnName)
+			}
+		})
+	}
+}
+
+func TestDeprecatedFields(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+	}
+
+	fieldService, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	// Deprecated fields stay listed, flagged
+	var listed bool
+	for _, field := range fieldService.GetAllFields("default") {
+		if field.TableName == "users" && field.ColumnName == "username" {
+			listed = true
+			assert.True(t, field.Deprecated)
+		}
+	}
+	assert.True(t, listed, "deprecated field should still be listed")
+
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name              string
+		includeDeprecated bool
+		expectDeprecated  bool
+	}{
+		{name: "Excluded by default"},
+		{name: "Included on request", includeDeprecated: true, expectDeprecated: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{
+				Description:       "user login name",
+				IncludeDeprecated: tc.includeDeprecated,
+			})
+			assert.NoError(t, err)
+
+			var found bool
+			for _, match := range response.MatchedFields {
+				if match.ColumnName == "username" {
+					found = true
+					assert.True(t, match.Deprecated)
+				}
+			}
+			assert.Equal(t, tc.expectDeprecated, found)
+			if tc.expectDeprecated {
+				assert.Contains(t, response.Warnings, "field users.username is deprecated")
+			} else {
+				assert.Empty(t, response.Warnings)
+			}
+		})
+	}
+}
+
+func TestSensitiveFields(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+	}
+
+	fieldService, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name           string
+		allowSensitive bool
+		expectSelected bool
+	}{
+		{name: "Never auto-selected by default"},
+		{name: "Selected and flagged when allowed", allowSensitive: true, expectSelected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{
+				Description:    "user security number",
+				AllowSensitive: tc.allowSensitive,
+			})
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expectSelected, strings.Contains(response.Query, "u.tax_id"))
+			if tc.expectSelected {
+				assert.Equal(t, []string{"users.tax_id"}, response.SensitiveColumns)
+			} else {
+				assert.Empty(t, response.SensitiveColumns)
+			}
+			for _, match := range response.MatchedFields {
+				assert.Equal(t, match.ColumnName == "tax_id", match.Sensitive)
+			}
+		})
+	}
+
+	// Naming a sensitive field in a structured intent is explicit, but flagged
+	built, err := queryService.BuildQuery(models.BuildQueryRequest{
+		Fields: []models.IntentField{{Table: "users", Column: "tax_id"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users.tax_id"}, built.SensitiveColumns)
+}
+
+func TestMatchLimits(t *testing.T) {
+	strict, broad := 90.0, 5.0
+
+	testCases := []struct {
+		name       string
+		cfg        *config.Config
+		request    models.QueryRequest
+		minMatches int
+		maxMatches int
+	}{
+		{
+			name:       "Configured max matches",
+			cfg:        &config.Config{CSVPath: "../field_mappings.csv", MaxMatches: 1},
+			request:    models.QueryRequest{Description: "user order"},
+			minMatches: 1,
+			maxMatches: 1,
+		},
+		{
+			name:       "Request overrides configured max matches",
+			cfg:        &config.Config{CSVPath: "../field_mappings.csv", MaxMatches: 1},
+			request:    models.QueryRequest{Description: "user order", MaxMatches: 3},
+			minMatches: 3,
+			maxMatches: 3,
+		},
+		{
+			name:       "Strict threshold keeps only full matches",
+			cfg:        &config.Config{CSVPath: "../field_mappings.csv"},
+			request:    models.QueryRequest{Description: "user email", MatchThreshold: &strict},
+			minMatches: 1,
+			maxMatches: 1,
+		},
+		{
+			name:       "Configured threshold",
+			cfg:        &config.Config{CSVPath: "../field_mappings.csv", MatchThreshold: 90},
+			request:    models.QueryRequest{Description: "user email"},
+			minMatches: 1,
+			maxMatches: 1,
+		},
+		{
+			name:       "Broad threshold admits partial matches",
+			cfg:        &config.Config{CSVPath: "../field_mappings.csv", MatchThreshold: 90},
+			request:    models.QueryRequest{Description: "user email", MatchThreshold: &broad},
+			minMatches: 3,
+			maxMatches: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(tc.cfg)
+			assert.NoError(t, err)
+
+			response, err := services.NewQueryService(fieldService).GenerateQuery(tc.request)
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, len(response.MatchedFields), tc.minMatches)
+			assert.LessOrEqual(t, len(response.MatchedFields), tc.maxMatches)
+			for _, match := range response.MatchedFields {
+				threshold := tc.cfg.MatchThreshold
+				if tc.request.MatchThreshold != nil {
+					threshold = *tc.request.MatchThreshold
+				}
+				assert.GreaterOrEqual(t, match.MatchScore, threshold)
+			}
+		})
+	}
+}
+
+func TestTableHints(t *testing.T) {
+	cfg := &config.Config{
+		CSVPath: "../field_mappings.csv",
+	}
+
+	fieldService, err := services.NewFieldService(cfg)
+	assert.NoError(t, err)
+
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name          string
+		request       models.QueryRequest
+		allowedTables []string
+		expectError   string
+	}{
+		{
+			name: "Only look at listed tables",
+			request: models.QueryRequest{
+				Description: "order identifier reference",
+				Tables:      []string{"order_items"},
+			},
+			allowedTables: []string{"order_items"},
+		},
+		{
+			name: "Exclude tables",
+			request: models.QueryRequest{
+				Description:   "order identifier reference",
+				ExcludeTables: []string{"Order_Items"},
+			},
+			allowedTables: []string{"orders", "users", "products"},
+		},
+		{
+			name: "Unknown table hint is rejected",
+			request: models.QueryRequest{
+				Description: "user email",
+				Tables:      []string{"users", "customers"},
+			},
+			expectError: `unknown table "customers" in table hints`,
+		},
+		{
+			name: "Unknown excluded table is rejected",
+			request: models.QueryRequest{
+				Description:   "user email",
+				ExcludeTables: []string{"user"},
+			},
+			expectError: `unknown table "user" in table hints`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(tc.request)
+			if tc.expectError != "" {
+				assert.ErrorIs(t, err, services.ErrUnknownTable)
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, response.MatchedFields)
+			for _, match := range response.MatchedFields {
+				assert.Contains(t, tc.allowedTables, match.TableName)
+			}
+		})
+	}
+}
+
+func TestDefaultQueryType(t *testing.T) {
+	testCases := []struct {
+		name             string
+		defaultQueryType string
+		description      string
+		expectedType     string
+		expectedSource   string
+		expectedFragment string
+	}{
+		{"Unconfigured default", "", "user email address", "SELECT", services.QueryTypeDefaulted, "SELECT u.email"},
+		{"Configured GROUP default", "group", "user email address", "GROUP", services.QueryTypeDefaulted, "GROUP BY u.email"},
+		{"Keywords override the default", "GROUP", "how many user email address", "COUNT", services.QueryTypeInferred, "COUNT(u.email)"},
+		{"Distinct is inferred", "COUNT", "unique user email address", "SELECT", services.QueryTypeInferred, "SELECT DISTINCT"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{
+				CSVPath:          "../field_mappings.csv",
+				DefaultQueryType: tc.defaultQueryType,
+			})
+			require.NoError(t, err)
+
+			response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{Description: tc.description})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedType, response.QueryType)
+			assert.Equal(t, tc.expectedSource, response.QueryTypeSource)
+			assert.Contains(t, response.Query, tc.expectedFragment)
+		})
+	}
+
+	assert.Error(t, services.ValidateQueryType("UPDATE"))
+	assert.NoError(t, services.ValidateQueryType("count"))
+}
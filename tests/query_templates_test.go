@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTemplates(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{
+		CSVPath:            "../field_mappings.csv",
+		QueryTemplatesPath: "../query_templates.example.yaml",
+	})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name          string
+		request       models.QueryRequest
+		expectedQuery string
+		expectedSlots map[string]string
+		expectError   bool
+	}{
+		{
+			name:          "Slot captured from the description",
+			request:       models.QueryRequest{Description: "Monthly active users in March 1, 2024"},
+			expectedQuery: "SELECT COUNT(DISTINCT o.user_id) AS active_users FROM orders o WHERE o.created_at >= DATE '2024-03-01' AND o.created_at < DATE '2024-03-01' + INTERVAL '1 month'",
+			expectedSlots: map[string]string{"month": "march 1, 2024"},
+		},
+		{
+			name:          "Dialect override",
+			request:       models.QueryRequest{Description: "mau in 2024-03-01", Dialect: "sqlserver"},
+			expectedQuery: "SELECT COUNT(DISTINCT o.user_id) AS active_users FROM orders o WHERE o.created_at >= CAST('2024-03-01' AS DATE) AND o.created_at < DATEADD(month, 1, CAST('2024-03-01' AS DATE))",
+			expectedSlots: map[string]string{"month": "2024-03-01"},
+		},
+		{
+			name:          "Slot default",
+			request:       models.QueryRequest{Description: "show the best selling products"},
+			expectedQuery: "SELECT p.product_name, COUNT(*) AS units FROM order_items oi JOIN products p ON oi.product_id = p.product_id GROUP BY p.product_name ORDER BY units DESC LIMIT 10",
+			expectedSlots: map[string]string{"count": "10"},
+		},
+		{
+			name:          "Captured slot",
+			request:       models.QueryRequest{Description: "top 5 products"},
+			expectedQuery: "SELECT p.product_name, COUNT(*) AS units FROM order_items oi JOIN products p ON oi.product_id = p.product_id GROUP BY p.product_name ORDER BY units DESC LIMIT 5",
+			expectedSlots: map[string]string{"count": "5"},
+		},
+		{
+			name:        "Slot of the wrong type",
+			request:     models.QueryRequest{Description: "top five products"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(tc.request)
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrTemplateSlot)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+			require.NotNil(t, response.QueryTemplate)
+			assert.Equal(t, tc.expectedSlots, response.QueryTemplate.Slots)
+			assert.Equal(t, services.QueryTypeTemplate, response.QueryType)
+		})
+	}
+
+	t.Run("Other descriptions are generated", func(t *testing.T) {
+		response, err := queryService.GenerateQuery(models.QueryRequest{Description: "user email"})
+		require.NoError(t, err)
+		assert.Nil(t, response.QueryTemplate)
+	})
+
+	t.Run("Slot errors are bad requests", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		require.NoError(t, handlers.SetupRoutes(r, &config.Config{
+			CSVPath:            "../field_mappings.csv",
+			QueryTemplatesPath: "../query_templates.example.yaml",
+		}))
+		body, _ := json.Marshal(models.QueryRequest{Description: "top five products"})
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid query template slot")
+	})
+
+	t.Run("Templates skipped", func(t *testing.T) {
+		response, err := queryService.GenerateQuery(models.QueryRequest{Description: "product name of the best selling products", SkipTemplates: true})
+		require.NoError(t, err)
+		assert.Nil(t, response.QueryTemplate)
+	})
+}
+
+func TestLoadQueryTemplatesValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+	}{
+		{name: "Undeclared slot", contents: "templates:\n  - name: t\n    phrases: [orders on {day}]\n    fields: [orders.order_id]\n    sql: SELECT 1\n"},
+		{name: "Writes", contents: "templates:\n  - name: t\n    phrases: [purge]\n    fields: [orders.order_id]\n    sql: DELETE FROM orders\n"},
+		{name: "Second statement", contents: "templates:\n  - name: t\n    phrases: [orders]\n    fields: [orders.order_id]\n    sql: SELECT 1; DROP TABLE orders\n"},
+		{name: "Unknown dialect", contents: "templates:\n  - name: t\n    phrases: [orders]\n    fields: [orders.order_id]\n    sql: SELECT 1\n    dialects:\n      oracle: SELECT 1 FROM dual\n"},
+		{name: "No SQL", contents: "templates:\n  - name: t\n    phrases: [orders]\n    fields: [orders.order_id]\n"},
+		{name: "No fields", contents: "templates:\n  - name: t\n    phrases: [orders]\n    sql: SELECT 1\n"},
+		{name: "Field without a table", contents: "templates:\n  - name: t\n    phrases: [orders]\n    fields: [order_id]\n    sql: SELECT 1\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "query_templates.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0o644))
+			_, err := services.LoadQueryTemplates(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestQueryTemplateAccess(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "shop.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,classification
+user_id,users,,,User identifier,INTEGER,,,,
+email,users,,,User email address,VARCHAR,,,,restricted
+order_id,orders,,,Order identifier,INTEGER,,,,
+total,orders,,,Order total amount,DECIMAL,,,,
+`), 0o644))
+	templatesPath := filepath.Join(dir, "query_templates.yaml")
+	require.NoError(t, os.WriteFile(templatesPath, []byte(`templates:
+  - name: order totals
+    phrases: [largest order totals]
+    sql: SELECT o.order_id, o.total FROM orders o ORDER BY o.total DESC
+    fields: [orders.order_id, orders.total]
+  - name: top orders
+    phrases: ["top {count} orders"]
+    sql: SELECT o.order_id FROM orders o ORDER BY o.order_id LIMIT {count}
+    fields: [orders.order_id]
+    slots:
+      - name: count
+        type: INTEGER
+  - name: user emails
+    phrases: [mailing list]
+    sql: SELECT u.email FROM users u
+    fields: [users.email]
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath, QueryTemplatesPath: templatesPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	t.Run("Templates are judged by the fields they read", func(t *testing.T) {
+		testCases := []struct {
+			name      string
+			request   models.QueryRequest
+			templated bool
+		}{
+			{name: "Admitted", request: models.QueryRequest{Description: "largest order totals"}, templated: true},
+			{name: "Excluded table", request: models.QueryRequest{Description: "largest order totals", ExcludeTables: []string{"orders"}}},
+			{name: "Other tables only", request: models.QueryRequest{Description: "largest order totals", Tables: []string{"users"}}},
+			{name: "Restricted field above the caller's clearance", request: models.QueryRequest{Description: "mailing list"}},
+			{name: "Restricted field within the caller's clearance", request: models.QueryRequest{Description: "mailing list", Clearance: services.ClassificationRestricted}, templated: true},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				response, err := queryService.GenerateQuery(tc.request)
+				assert.Equal(t, tc.templated, err == nil && response.QueryTemplate != nil, response.Query)
+			})
+		}
+	})
+
+	t.Run("Aggregate-only tables are generated", func(t *testing.T) {
+		policies, err := services.LoadPolicies(writePolicies(t, "aggregate_only:\n  tables: [orders]\n"))
+		require.NoError(t, err)
+		restricted := services.NewQueryService(fieldService)
+		restricted.UsePolicies(policies)
+		// Generation holds the rows of orders back, which the template wouldn't
+		_, err = restricted.GenerateQuery(models.QueryRequest{Description: "largest order totals"})
+		assert.ErrorIs(t, err, services.ErrAggregateOnly)
+	})
+
+	t.Run("Row limits", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			request       models.QueryRequest
+			expectedQuery string
+		}{
+			{
+				name:          "Appended after the ORDER BY",
+				request:       models.QueryRequest{Description: "largest order totals", Limit: 5},
+				expectedQuery: "SELECT o.order_id, o.total FROM orders o ORDER BY o.total DESC LIMIT 5",
+			},
+			{
+				name:          "SQL Server",
+				request:       models.QueryRequest{Description: "largest order totals", Limit: 5, Dialect: "sqlserver"},
+				expectedQuery: "SELECT o.order_id, o.total FROM orders o ORDER BY o.total DESC OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY",
+			},
+			{
+				name:          "The template's own limit is lowered",
+				request:       models.QueryRequest{Description: "top 50 orders", Limit: 20},
+				expectedQuery: "SELECT o.order_id FROM orders o ORDER BY o.order_id LIMIT 20",
+			},
+			{
+				name:          "The template's own lower limit stands",
+				request:       models.QueryRequest{Description: "top 5 orders", Limit: 20},
+				expectedQuery: "SELECT o.order_id FROM orders o ORDER BY o.order_id LIMIT 5",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				response, err := queryService.GenerateQuery(tc.request)
+				require.NoError(t, err)
+				require.NotNil(t, response.QueryTemplate)
+				assert.Equal(t, tc.expectedQuery, response.Query)
+			})
+		}
+	})
+
+	t.Run("Policy simulation", func(t *testing.T) {
+		policies, err := services.LoadPolicies(writePolicies(t, "roles:\n  analyst:\n    allow_tables: [orders]\n  auditor:\n    allow_tables: [orders]\n    deny_fields: [orders.total]\n"))
+		require.NoError(t, err)
+
+		simulation, err := queryService.SimulatePolicy(policies, models.PolicySimulationRequest{Role: "analyst", Description: "largest order totals"})
+		require.NoError(t, err)
+		assert.True(t, simulation.WouldSucceed, simulation.Reason)
+		assert.Equal(t, "SELECT o.order_id, o.total FROM orders o ORDER BY o.total DESC", simulation.Query)
+		assert.Len(t, simulation.Decisions, 2)
+
+		// A role denied a field the template reads falls through to generation
+		simulation, err = queryService.SimulatePolicy(policies, models.PolicySimulationRequest{Role: "auditor", Description: "largest order totals"})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT o.order_id FROM orders o", simulation.Query)
+	})
+}
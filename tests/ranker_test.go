@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findMatch(matches []models.FieldMatch, table, column string) (models.FieldMatch, bool) {
+	for _, match := range matches {
+		if match.TableName == table && match.ColumnName == column {
+			return match, true
+		}
+	}
+	return models.FieldMatch{}, false
+}
+
+func TestRankers(t *testing.T) {
+	scoreFor := func(t *testing.T, ranker string, keywords []string, table, column string) models.FieldMatch {
+		service, err := services.NewFieldService(&config.Config{
+			CSVPath: "../field_mappings.csv",
+			Ranker:  ranker,
+		})
+		require.NoError(t, err)
+
+		match, found := findMatch(service.FindFieldMatches(keywords, 1.0, 20), table, column)
+		require.True(t, found, "%s.%s should match", table, column)
+		return match
+	}
+
+	t.Run("TF-IDF discounts common keywords", func(t *testing.T) {
+		// "user" appears in three descriptions, "email" in one
+		keyword := scoreFor(t, services.RankerKeyword, []string{"user", "email"}, "users", "user_id")
+		tfidf := scoreFor(t, services.RankerTFIDF, []string{"user", "email"}, "users", "user_id")
+		assert.InDelta(t, 50.0, keyword.MatchScore, 0.0001)
+		assert.Less(t, tfidf.MatchScore, keyword.MatchScore)
+	})
+
+	t.Run("Fuzzy ranker credits misspellings", func(t *testing.T) {
+		match := scoreFor(t, services.RankerFuzzy, []string{"emial"}, "users", "email")
+		assert.Greater(t, match.MatchScore, 80.0)
+	})
+
+	t.Run("Weighted ensemble", func(t *testing.T) {
+		match := scoreFor(t, "keyword:3, tfidf:1", []string{"user", "email"}, "users", "user_id")
+		require.NotNil(t, match.ScoreBreakdown)
+		components := match.ScoreBreakdown.Components
+		require.Len(t, components, 2)
+		assert.Equal(t, services.RankerKeyword, components[0].Ranker)
+		assert.InDelta(t, 0.75, components[0].Weight, 0.0001)
+		assert.Equal(t, services.RankerTFIDF, components[1].Ranker)
+		assert.InDelta(t, 0.25, components[1].Weight, 0.0001)
+		assert.InDelta(t, 0.75*components[0].Score+0.25*components[1].Score, match.MatchScore, 0.0001)
+	})
+
+	t.Run("Single ranker has no breakdown", func(t *testing.T) {
+		match := scoreFor(t, services.RankerKeyword, []string{"email"}, "users", "email")
+		assert.Nil(t, match.ScoreBreakdown)
+	})
+}
+
+func TestRankerWithoutEmbeddingWeight(t *testing.T) {
+	// The provider embeds the fields at startup, then goes down
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var req struct {
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		data := make([]map[string]interface{}, len(req.Input))
+		for i := range req.Input {
+			data[i] = map[string]interface{}{"index": i, "embedding": []float64{1, 0}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	service, err := services.NewFieldService(&config.Config{
+		CSVPath:           "../field_mappings.csv",
+		Ranker:            "keyword:0, tfidf:0, embedding:1",
+		EmbeddingProvider: services.EmbeddingProviderHTTP,
+		EmbeddingURL:      server.URL,
+	})
+	require.NoError(t, err)
+	down.Store(true)
+
+	// Keyword and TF-IDF carry no weight of their own, so they share it
+	match, found := findMatch(service.FindFieldMatches([]string{"user", "email"}, 1.0, 20), "users", "user_id")
+	require.True(t, found, "users.user_id should match")
+	require.NotNil(t, match.ScoreBreakdown)
+	components := match.ScoreBreakdown.Components
+	require.Len(t, components, 2)
+	assert.InDelta(t, 0.5, components[0].Weight, 0.0001)
+	assert.InDelta(t, 0.5, components[1].Weight, 0.0001)
+	assert.InDelta(t, 0.5*components[0].Score+0.5*components[1].Score, match.MatchScore, 0.0001)
+}
+
+func TestRankerConfigErrors(t *testing.T) {
+	for _, ranker := range []string{"bm25", "keyword:heavy", "keyword:-1", "feedback", "keyword:0"} {
+		t.Run(ranker, func(t *testing.T) {
+			_, err := services.NewFieldService(&config.Config{
+				CSVPath: "../field_mappings.csv",
+				Ranker:  ranker,
+			})
+			assert.Error(t, err)
+		})
+	}
+}
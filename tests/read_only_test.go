@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyGuarantee(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,measure
+order_id,orders,,,Order identifier,INTEGER,,,,
+note,orders,,,Order note,VARCHAR,,,,
+lock,orders,,,Whether the order is locked,BOOLEAN,,,,
+revenue,orders,,,Order revenue,DECIMAL,,,,SUM(total)
+separated,orders,,,Separated measure,DECIMAL,,,,SUM(total); DROP TABLE orders
+commented,orders,,,Commented measure,DECIMAL,,,,SUM(total) -- the rest is hidden
+blocked,orders,,,Block comment measure,DECIMAL,,,,SUM(total) /* hidden */
+selected,orders,,,Measure selecting into a table,DECIMAL,,,,(SELECT SUM(total) INTO copied FROM orders)
+mysql_escaped,orders,,,Escaped quote in a MySQL string,DECIMAL,,,,"LENGTH(""a\""; b"")"
+mysql_smuggled,orders,,,Statement after a MySQL string,DECIMAL,,,,"LENGTH(""\""""; DELETE FROM orders; """")"
+postgres_escaped,orders,,,Escaped quote in a Postgres escape string,DECIMAL,,,,LENGTH(E'a\'; b')
+postgres_smuggled,orders,,,Statement after a Postgres escape string,DECIMAL,,,,LENGTH(E'\''; DELETE FROM orders; '')
+`+"returned,orders,,,Carriage return before INTO,DECIMAL,,,,\"(SELECT *\rINTO backup FROM users)\"\n"+
+		"fed,orders,,,Form feeds around FOR UPDATE,DECIMAL,,,,\"(SELECT id FROM users\fFOR\fUPDATE)\"\n"), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	t.Run("Strings and names may hold anything", func(t *testing.T) {
+		response, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:  []models.IntentField{{Table: "orders", Column: "order_id"}, {Table: "orders", Column: "lock"}},
+			Filters: []models.IntentFilter{{Table: "orders", Column: "note", Operator: "=", Value: "x'; DROP TABLE orders; --"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT o.order_id, o."lock" FROM orders o WHERE o.note = 'x''; DROP TABLE orders; --'`, response.Query)
+	})
+
+	testCases := []struct {
+		measure     string
+		dialect     string
+		expectError bool
+	}{
+		{measure: "revenue"},
+		{measure: "separated", expectError: true},
+		{measure: "commented", expectError: true},
+		{measure: "blocked", expectError: true},
+		{measure: "selected", expectError: true},
+		// Any whitespace an engine accepts separates words
+		{measure: "returned", expectError: true},
+		{measure: "fed", expectError: true},
+		// Strings end where the dialect's engine ends them
+		{measure: "mysql_escaped", dialect: "mysql"},
+		{measure: "mysql_smuggled", dialect: "mysql", expectError: true},
+		{measure: "postgres_escaped", dialect: "postgres"},
+		{measure: "postgres_smuggled", dialect: "postgres", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.measure, func(t *testing.T) {
+			_, err := queryService.BuildQuery(models.BuildQueryRequest{
+				Fields:  []models.IntentField{{Table: "orders", Column: tc.measure}},
+				Dialect: tc.dialect,
+			})
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrUnsafeSQL)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelationshipGraphEndpoint(t *testing.T) {
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, append(csvData, []byte("page,audit_log,,,Page viewed,VARCHAR,,,\n")...), 0o644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: csvPath}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/relationships", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	var graph models.RelationshipGraph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	assert.NotEmpty(t, graph.Version)
+
+	tables := make([]string, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		tables = append(tables, node.Table)
+	}
+	assert.Equal(t, []string{"audit_log", "order_items", "orders", "products", "users"}, tables)
+	assert.Equal(t, models.RelationshipNode{Table: "orders", Fields: 3, Relationships: 2, Component: 0}, graph.Nodes[2])
+
+	// The table no relationship reaches is a group of its own
+	assert.Equal(t, [][]string{{"order_items", "orders", "products", "users"}, {"audit_log"}}, graph.Components)
+	assert.Equal(t, models.RelationshipNode{Table: "audit_log", Fields: 1, Relationships: 0, Component: 1}, graph.Nodes[0])
+
+	// Each relationship appears once, in foreign key direction
+	require.Len(t, graph.Edges, 3)
+	assert.Equal(t, "orders", graph.Edges[0].From)
+	assert.Equal(t, "users", graph.Edges[0].To)
+	assert.Equal(t, "orders.user_id = users.user_id", graph.Edges[0].Condition)
+	assert.Equal(t, 1.0, graph.Edges[0].Weight)
+}
+
+func TestRelationshipGraphExport(t *testing.T) {
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, append(csvData, []byte("page,audit_log,,,Page viewed,VARCHAR,,,\n")...), 0o644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: csvPath}))
+
+	testCases := []struct {
+		name         string
+		format       string
+		expectedCode int
+		contentType  string
+		contains     []string
+	}{
+		{
+			name:         "Graphviz",
+			format:       "dot",
+			expectedCode: http.StatusOK,
+			contentType:  "text/vnd.graphviz; charset=utf-8",
+			contains: []string{
+				"digraph relationships {",
+				`"orders" -> "users" [label="user_id = user_id"];`,
+				`"order_items" -> "products" [label="product_id = product_id"];`,
+				`"audit_log" [color=red`,
+			},
+		},
+		{
+			name:         "Mermaid",
+			format:       "mermaid",
+			expectedCode: http.StatusOK,
+			contentType:  "text/plain; charset=utf-8",
+			contains: []string{
+				"erDiagram\n",
+				`orders }o--|| users : "user_id = user_id"`,
+				"\n    audit_log\n",
+			},
+		},
+		{name: "Unknown format", format: "svg", expectedCode: http.StatusBadRequest, contains: []string{"use json, dot, or mermaid"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/relationships?format="+tc.format, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.contentType != "" {
+				assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+			}
+			for _, expected := range tc.contains {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAdminToken opens the admin routes of test routers
+const testAdminToken = "admin-secret"
+
+func TestAdminReload(t *testing.T) {
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, csvData, 0o644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: csvPath, AdminToken: testAdminToken}))
+
+	reload := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/admin/reload", nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Reloading an unchanged source is a no-op swap
+	w := reload()
+	require.Equal(t, http.StatusOK, w.Code)
+	var unchanged models.ReloadSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &unchanged))
+	assert.False(t, unchanged.Changed)
+	assert.Equal(t, 11, unchanged.Fields)
+	assert.Equal(t, 4, unchanged.Tables)
+	assert.Equal(t, 3, unchanged.Relationships)
+
+	// New rows are picked up and bad rows counted
+	updated := append(append([]byte{}, csvData...), []byte("status,orders,state,order_state,Order fulfillment status,VARCHAR,,,\nbroken,row\n")...)
+	require.NoError(t, os.WriteFile(csvPath, updated, 0o644))
+	w = reload()
+	require.Equal(t, http.StatusOK, w.Code)
+	var changed models.ReloadSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &changed))
+	assert.True(t, changed.Changed)
+	assert.Equal(t, unchanged.Version, changed.PreviousVersion)
+	assert.Equal(t, 12, changed.Fields)
+	assert.Equal(t, 1, changed.SkippedRows)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/fields", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "Order fulfillment status")
+
+	// A broken source leaves the last good schema live
+	require.NoError(t, os.WriteFile(csvPath, []byte("column_name,table_name\n"), 0o644))
+	w = reload()
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var failed map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &failed))
+	assert.Equal(t, true, failed["rolled_back"])
+	assert.Equal(t, changed.Version, failed["version"])
+
+	require.NoError(t, os.Remove(csvPath))
+	w = reload()
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/fields", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "Order fulfillment status")
+
+	// Both loaded versions remain available to schema_version requests
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/schema-versions", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var versions struct {
+		Current  string                 `json:"current"`
+		Versions []models.SchemaVersion `json:"versions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &versions))
+	assert.Equal(t, changed.Version, versions.Current)
+	assert.Len(t, versions.Versions, 2)
+}
+
+func TestAdminRoutesClosedWithoutToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	for _, authorization := range []string{"", "Bearer ", "Bearer anything"} {
+		req, _ := http.NewRequest(http.MethodPost, "/admin/reload", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, authorization)
+	}
+
+	w := uploadMappings(t, r, mappingHeader+"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+// uploadMappings posts a mapping file to /admin/mappings as a multipart form
+func uploadMappings(t *testing.T, r *gin.Engine, content string) *httptest.ResponseRecorder {
+	return postMultipart(t, r, "/admin/mappings", content)
+}
+
+// postMultipart posts content as the multipart "file" field
+func postMultipart(t *testing.T, r *gin.Engine, path, content string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "field_mappings.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req, _ := http.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadMappings(t *testing.T) {
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, csvData, 0o644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: csvPath, AdminToken: testAdminToken}))
+
+	testCases := []struct {
+		name           string
+		content        string
+		expectedStatus int
+		expectedFields int
+	}{
+		{
+			name:           "Malformed row is rejected",
+			content:        mappingHeader + "status,orders,state,order_state,Order status,VARCHAR,,,\nbroken,row\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Header only is rejected",
+			content:        mappingHeader,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Valid file is activated",
+			content: mappingHeader +
+				"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+				"status,orders,state,order_state,Order fulfillment status,VARCHAR,,,\n",
+			expectedStatus: http.StatusOK,
+			expectedFields: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			before, err := os.ReadFile(csvPath)
+			require.NoError(t, err)
+
+			w := uploadMappings(t, r, tc.content)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+
+			after, err := os.ReadFile(csvPath)
+			require.NoError(t, err)
+			if tc.expectedStatus != http.StatusOK {
+				// The file on disk is untouched
+				assert.Equal(t, before, after)
+				return
+			}
+
+			var summary models.ReloadSummary
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+			assert.True(t, summary.Changed)
+			assert.Equal(t, tc.expectedFields, summary.Fields)
+			assert.Equal(t, tc.content, string(after))
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/fields", nil)
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Contains(t, w.Body.String(), "Order fulfillment status")
+		})
+	}
+
+	t.Run("Missing file field", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/admin/mappings", bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Directory sources can't be replaced", func(t *testing.T) {
+		dirRouter := gin.New()
+		require.NoError(t, handlers.SetupRoutes(dirRouter, &config.Config{CSVPath: filepath.Dir(csvPath), AdminToken: testAdminToken}))
+		w := uploadMappings(t, dirRouter, string(csvData))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestUploadRestoresMappings(t *testing.T) {
+	valid := mappingHeader +
+		"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+		"status,orders,state,order_state,Order fulfillment status,VARCHAR,,,\n"
+
+	t.Run("Failed upload removes a file that didn't exist", func(t *testing.T) {
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+		require.NoError(t, err)
+		csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+		schema := services.NewLiveSchema(&config.Config{CSVPath: csvPath}, fieldService, nil, nil)
+
+		_, err = schema.Upload([]byte(mappingHeader))
+		assert.ErrorIs(t, err, services.ErrInvalidMapping)
+		_, statErr := os.Stat(csvPath)
+		assert.True(t, os.IsNotExist(statErr))
+	})
+
+	t.Run("Failed activation restores the previous file", func(t *testing.T) {
+		csvData, err := os.ReadFile("../field_mappings.csv")
+		require.NoError(t, err)
+		dir := t.TempDir()
+		csvPath := filepath.Join(dir, "field_mappings.csv")
+		require.NoError(t, os.WriteFile(csvPath, csvData, 0o644))
+		historyDir := filepath.Join(dir, "history")
+		cfg := &config.Config{CSVPath: csvPath, SchemaHistoryDir: historyDir}
+
+		fieldService, err := services.NewFieldService(cfg)
+		require.NoError(t, err)
+		versions, err := services.NewSchemaVersions(cfg, fieldService)
+		require.NoError(t, err)
+		schema := services.NewLiveSchema(cfg, fieldService, versions, nil)
+
+		// Saving the new version fails once the history directory is gone
+		require.NoError(t, os.RemoveAll(historyDir))
+		require.NoError(t, os.Symlink(filepath.Join(dir, "missing"), historyDir))
+
+		_, err = schema.Upload([]byte(valid))
+		require.Error(t, err)
+		after, err := os.ReadFile(csvPath)
+		require.NoError(t, err)
+		assert.Equal(t, csvData, after)
+		assert.Equal(t, fieldService.Version(), schema.Fields().Version())
+	})
+
+	t.Run("Failed activation removes a file that didn't exist", func(t *testing.T) {
+		dir := t.TempDir()
+		csvPath := filepath.Join(dir, "field_mappings.csv")
+		historyDir := filepath.Join(dir, "history")
+		cfg := &config.Config{CSVPath: csvPath, SchemaHistoryDir: historyDir}
+
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+		require.NoError(t, err)
+		versions, err := services.NewSchemaVersions(cfg, fieldService)
+		require.NoError(t, err)
+		schema := services.NewLiveSchema(cfg, fieldService, versions, nil)
+
+		require.NoError(t, os.RemoveAll(historyDir))
+		require.NoError(t, os.Symlink(filepath.Join(dir, "missing"), historyDir))
+
+		_, err = schema.Upload([]byte(valid))
+		require.Error(t, err)
+		_, statErr := os.Stat(csvPath)
+		assert.True(t, os.IsNotExist(statErr))
+	})
+}
+
+func TestAdminSchemaRollback(t *testing.T) {
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, csvData, 0o644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: csvPath, AdminToken: testAdminToken}))
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Load a second version that adds a field
+	updated := append(append([]byte{}, csvData...), []byte("status,orders,state,order_state,Order fulfillment status,VARCHAR,,,\n")...)
+	require.NoError(t, os.WriteFile(csvPath, updated, 0o644))
+	w := post("/admin/reload")
+	require.Equal(t, http.StatusOK, w.Code)
+	var reloaded models.ReloadSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reloaded))
+	require.True(t, reloaded.Changed)
+
+	testCases := []struct {
+		name           string
+		version        string
+		expectedStatus int
+		expectedFields int
+	}{
+		{name: "Prior version", version: reloaded.PreviousVersion, expectedStatus: http.StatusOK, expectedFields: 11},
+		{name: "Forward again", version: reloaded.Version, expectedStatus: http.StatusOK, expectedFields: 12},
+		{name: "Unknown version", version: "0123456789ab", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := post("/admin/schema-versions/" + tc.version + "/rollback")
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var summary models.ReloadSummary
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+			assert.Equal(t, tc.version, summary.Version)
+			assert.Equal(t, tc.expectedFields, summary.Fields)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/schema-versions", nil)
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Contains(t, w.Body.String(), `"current":"`+tc.version+`"`)
+		})
+	}
+
+	// Queries are tagged with the rolled-back version
+	w = post("/admin/schema-versions/" + reloaded.PreviousVersion + "/rollback")
+	require.Equal(t, http.StatusOK, w.Code)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewBufferString(`{"description":"user email address"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response models.QueryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, reloaded.PreviousVersion, response.SchemaVersion)
+}
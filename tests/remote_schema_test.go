@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// remoteMappings serves a mapping file with an ETag, answering conditional
+// requests with 304 while the content is unchanged
+type remoteMappings struct {
+	mu          sync.Mutex
+	content     string
+	etag        string
+	requests    []*http.Request
+	notModified int
+}
+
+func (m *remoteMappings) set(content, etag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.content, m.etag = content, etag
+}
+
+func (m *remoteMappings) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, r)
+	if r.Header.Get("If-None-Match") == m.etag {
+		m.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", m.etag)
+	_, _ = w.Write([]byte(m.content))
+}
+
+func TestRemoteSchema(t *testing.T) {
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+	remote := &remoteMappings{}
+	remote.set(string(csvData), `"v1"`)
+	server := httptest.NewServer(remote)
+	defer server.Close()
+
+	cfg := &config.Config{CSVPath: server.URL + "/field_mappings.csv?token=secret"}
+	fieldService, err := services.NewFieldService(cfg)
+	require.NoError(t, err)
+	assert.Len(t, fieldService.GetAllFields("default"), 11)
+	schema := services.NewLiveSchema(cfg, fieldService, nil, nil)
+
+	// An unchanged ETag skips the reload entirely
+	_, reloaded, err := schema.Refresh()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, 1, remote.notModified)
+
+	// A new ETag reloads the schema
+	remote.set(string(csvData)+"status,orders,state,order_state,Order fulfillment status,VARCHAR,,,\n", `"v2"`)
+	summary, reloaded, err := schema.Refresh()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.True(t, summary.Changed)
+	assert.Equal(t, 12, summary.Fields)
+	assert.Equal(t, 12, len(schema.Fields().GetAllFields("default")))
+
+	t.Run("Failed fetch keeps the current schema", func(t *testing.T) {
+		server.Close()
+		_, _, err := schema.Refresh()
+		assert.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret")
+		assert.Equal(t, 12, len(schema.Fields().GetAllFields("default")))
+	})
+}
+
+func TestRemoteSchemaS3(t *testing.T) {
+	remote := &remoteMappings{}
+	remote.set(mappingHeader+"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n", `"s3"`)
+	server := httptest.NewServer(remote)
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		accessKey  string
+		authPrefix string
+	}{
+		{name: "Signed with credentials", accessKey: "AKIDEXAMPLE", authPrefix: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"},
+		{name: "Anonymous without credentials"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{
+				CSVPath:            "s3://mappings/" + strings.ReplaceAll(tc.name, " ", "_") + "/users.csv",
+				S3Endpoint:         server.URL,
+				S3Region:           "eu-west-1",
+				AWSAccessKeyID:     tc.accessKey,
+				AWSSecretAccessKey: "secret",
+			})
+			require.NoError(t, err)
+			assert.Len(t, fieldService.GetAllFields("default"), 1)
+
+			request := remote.requests[len(remote.requests)-1]
+			assert.Equal(t, "/mappings/"+strings.ReplaceAll(tc.name, " ", "_")+"/users.csv", request.URL.Path)
+			if tc.authPrefix == "" {
+				assert.Empty(t, request.Header.Get("Authorization"))
+				return
+			}
+			assert.True(t, strings.HasPrefix(request.Header.Get("Authorization"), tc.authPrefix))
+			assert.Contains(t, request.Header.Get("Authorization"), "/eu-west-1/s3/aws4_request")
+			assert.NotEmpty(t, request.Header.Get("X-Amz-Date"))
+		})
+	}
+
+	t.Run("Uploads are refused for remote sources", func(t *testing.T) {
+		cfg := &config.Config{CSVPath: server.URL + "/users.csv"}
+		fieldService, err := services.NewFieldService(cfg)
+		require.NoError(t, err)
+		_, err = services.NewLiveSchema(cfg, fieldService, nil, nil).Upload([]byte(mappingHeader))
+		assert.ErrorIs(t, err, services.ErrUploadUnsupported)
+	})
+}
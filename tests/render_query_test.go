@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderQuery(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "shop.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,measure
+user_id,users,,,User identifier,INTEGER,,,,
+country,users,,,Country the user lives in,VARCHAR,,,,
+order_id,orders,,,Order identifier,INTEGER,,,,
+user_id,orders,,,User placing the order,INTEGER,user_id,users,user_id,
+status,orders,,,Order status,VARCHAR,,,,
+revenue,orders,,,Order revenue,DECIMAL,,,,SUM(orders.total)
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	built, err := queryService.BuildQuery(models.BuildQueryRequest{
+		Fields:  []models.IntentField{{Table: "orders", Column: "status"}, {Table: "orders", Column: "revenue"}, {Table: "users", Column: "country", Aggregate: "count"}},
+		Filters: []models.IntentFilter{{Table: "orders", Column: "status", Operator: "=", Value: "shipped"}},
+		GroupBy: []models.FieldRef{{Table: "orders", Column: "status"}},
+		OrderBy: []models.IntentOrder{{Table: "orders", Column: "revenue", Direction: "desc"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT o.status, SUM(o.total) AS revenue, COUNT(u.country) FROM orders o JOIN users u ON o.user_id = u.user_id WHERE o.status = 'shipped' GROUP BY o.status ORDER BY revenue DESC", built.Query)
+
+	// edit copies the built plan and applies a change to it
+	edit := func(change func(plan *models.QueryPlan)) models.QueryPlan {
+		var plan models.QueryPlan
+		data, _ := json.Marshal(built.Plan)
+		require.NoError(t, json.Unmarshal(data, &plan))
+		change(&plan)
+		return plan
+	}
+
+	testCases := []struct {
+		name          string
+		plan          models.QueryPlan
+		expectedQuery string
+		expectedError error
+	}{
+		{
+			name:          "Unchanged plan renders the same query",
+			plan:          edit(func(plan *models.QueryPlan) {}),
+			expectedQuery: built.Query,
+		},
+		{
+			name: "Removing a column drops its join",
+			plan: edit(func(plan *models.QueryPlan) {
+				plan.Select = plan.Select[:2]
+			}),
+			expectedQuery: "SELECT o.status, SUM(o.total) AS revenue FROM orders o WHERE o.status = 'shipped' GROUP BY o.status ORDER BY revenue DESC",
+		},
+		{
+			name: "Changed filter is rebuilt from its value, not its expression",
+			plan: edit(func(plan *models.QueryPlan) {
+				plan.Filters[0].Value = "it's returned"
+				plan.Filters[0].Expression = "1 = 1; DROP TABLE orders"
+			}),
+			expectedQuery: "SELECT o.status, SUM(o.total) AS revenue, COUNT(u.country) FROM orders o JOIN users u ON o.user_id = u.user_id WHERE o.status = 'it''s returned' GROUP BY o.status ORDER BY revenue DESC",
+		},
+		{
+			name: "Limit added",
+			plan: edit(func(plan *models.QueryPlan) {
+				plan.Limit = 10
+			}),
+			expectedQuery: built.Query + " LIMIT 10",
+		},
+		{
+			name: "Group by an expression that isn't a column",
+			plan: edit(func(plan *models.QueryPlan) {
+				plan.GroupBy = []string{"o.status) UNION SELECT (1"}
+			}),
+			expectedError: services.ErrInvalidPlan,
+		},
+		{
+			name: "Order by an alias no longer selected",
+			plan: edit(func(plan *models.QueryPlan) {
+				plan.Select = []models.PlanColumn{plan.Select[0], plan.Select[2]}
+			}),
+			expectedError: services.ErrInvalidPlan,
+		},
+		{
+			name: "Plain column left out of the grouping",
+			plan: edit(func(plan *models.QueryPlan) {
+				plan.GroupBy = nil
+			}),
+			expectedError: services.ErrInvalidPlan,
+		},
+		{
+			name: "Unknown field",
+			plan: edit(func(plan *models.QueryPlan) {
+				plan.Select[0].Column = "password"
+			}),
+			expectedError: services.ErrInvalidPlan,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.RenderQuery(models.RenderQueryRequest{Plan: tc.plan})
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+
+	t.Run("IDs survive edits", func(t *testing.T) {
+		response, err := queryService.RenderQuery(models.RenderQueryRequest{Plan: edit(func(plan *models.QueryPlan) {
+			plan.Select = plan.Select[1:]
+			plan.Select = append(plan.Select, models.PlanColumn{Table: "users", Column: "user_id", Aggregate: "max"})
+		})})
+		require.NoError(t, err)
+		require.Len(t, response.Plan.Select, 3)
+		assert.Equal(t, built.Plan.Select[1].ID, response.Plan.Select[0].ID)
+		assert.Equal(t, built.Plan.Select[2].ID, response.Plan.Select[1].ID)
+		assert.NotEmpty(t, response.Plan.Select[2].ID)
+		assert.Equal(t, built.Plan.Joins[0].ID, response.Plan.Joins[0].ID)
+		assert.Equal(t, built.Plan.Filters[0].ID, response.Plan.Filters[0].ID)
+		assert.Equal(t, built.Plan.OrderBy[0].ID, response.Plan.OrderBy[0].ID)
+	})
+
+	t.Run("Same query, same IDs", func(t *testing.T) {
+		again, err := queryService.BuildQuery(models.BuildQueryRequest{
+			Fields:  []models.IntentField{{Table: "orders", Column: "status"}, {Table: "orders", Column: "revenue"}, {Table: "users", Column: "country", Aggregate: "count"}},
+			Filters: []models.IntentFilter{{Table: "orders", Column: "status", Operator: "=", Value: "shipped"}},
+			GroupBy: []models.FieldRef{{Table: "orders", Column: "status"}},
+			OrderBy: []models.IntentOrder{{Table: "orders", Column: "revenue", Direction: "desc"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, built.Plan, again.Plan)
+	})
+}
+
+func TestRenderQueryHandler(t *testing.T) {
+	r, err := setupTestRouter()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		plan           models.QueryPlan
+		expectedStatus int
+	}{
+		{
+			name:           "Valid plan",
+			plan:           models.QueryPlan{Select: []models.PlanColumn{{Table: "users", Column: "email"}}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid plan",
+			plan:           models.QueryPlan{Select: []models.PlanColumn{{Table: "users", Column: "nope"}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.RenderQueryRequest{Plan: tc.plan})
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/render-query", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
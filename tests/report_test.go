@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderReport(t *testing.T) {
+	response := models.QueryResponse{
+		TraceID:    "trace-1",
+		Query:      "SELECT users.email, orders.order_total FROM users JOIN orders ON orders.user_id = users.user_id",
+		Confidence: 72.5,
+		MatchedFields: []models.FieldMatch{
+			{TableName: "users", ColumnName: "email", MatchScore: 80, FieldDescription: "User <email> | address"},
+			{TableName: "orders", ColumnName: "order_total", MatchScore: 65, FieldDescription: "Order total"},
+		},
+		JoinsUsed: []models.Join{
+			{From: "users", To: "orders", Condition: "orders.user_id = users.user_id"},
+		},
+		Warnings: []string{"table \"invoices\" is not in the schema"},
+	}
+
+	testCases := []struct {
+		name        string
+		format      string
+		contentType string
+		contains    []string
+		excludes    []string
+	}{
+		{
+			name:        "Markdown",
+			format:      services.FormatMarkdown,
+			contentType: "text/markdown; charset=utf-8",
+			contains: []string{
+				"```sql\n" + response.Query + "\n```",
+				"**Confidence:** 72.5%",
+				`| users | email | 80.0 | User <email> \| address |`,
+				"users\n└─ orders ON orders.user_id = users.user_id",
+				"- table \"invoices\" is not in the schema",
+			},
+		},
+		{
+			name:        "HTML",
+			format:      services.FormatHTML,
+			contentType: "text/html; charset=utf-8",
+			contains: []string{
+				`<code class="language-sql">`,
+				"<td>User &lt;email&gt; | address</td>",
+				"└─ orders ON orders.user_id = users.user_id",
+				"72.5%",
+			},
+			excludes: []string{"<email>"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, contentType, err := services.RenderReport(tc.format, response)
+			require.NoError(t, err)
+			assert.Equal(t, tc.contentType, contentType)
+			for _, want := range tc.contains {
+				assert.Contains(t, report, want)
+			}
+			for _, unwanted := range tc.excludes {
+				assert.NotContains(t, report, unwanted)
+			}
+		})
+	}
+
+	_, _, err := services.RenderReport("pdf", response)
+	assert.ErrorIs(t, err, services.ErrUnknownFormat)
+}
+
+func TestReportFormatHandler(t *testing.T) {
+	r, err := setupTestRouter()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		format         string
+		expectedStatus int
+		contentType    string
+	}{
+		{"Default JSON", "", http.StatusOK, "application/json; charset=utf-8"},
+		{"Markdown", "markdown", http.StatusOK, "text/markdown; charset=utf-8"},
+		{"HTML", "html", http.StatusOK, "text/html; charset=utf-8"},
+		{"Zip bundle", "zip", http.StatusOK, "application/zip"},
+		{"Tar bundle", "tar", http.StatusOK, "application/gzip"},
+		{"dbt model", "dbt", http.StatusOK, "text/plain; charset=utf-8"},
+		{"Go snippet", "go", http.StatusOK, "text/plain; charset=utf-8"},
+		{"Unknown format", "pdf", http.StatusBadRequest, "application/json; charset=utf-8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.QueryRequest{Description: "Get user emails", Format: tc.format})
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestRenderBundle(t *testing.T) {
+	response := models.QueryResponse{
+		TraceID:       "trace-1",
+		SchemaVersion: "abc123",
+		Query:         "SELECT users.email, orders.total_amount FROM users u JOIN orders o ON orders.user_id = users.user_id",
+		Confidence:    72.5,
+		MatchedFields: []models.FieldMatch{{TableName: "users", ColumnName: "email", MatchScore: 80}},
+	}
+
+	// readBundle extracts every file in a bundle by name
+	readBundle := func(t *testing.T, format string, data []byte) map[string]string {
+		files := make(map[string]string)
+		switch format {
+		case services.FormatZip:
+			archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+			for _, file := range archive.File {
+				r, err := file.Open()
+				require.NoError(t, err)
+				content, err := io.ReadAll(r)
+				require.NoError(t, err)
+				files[file.Name] = string(content)
+			}
+		case services.FormatTar:
+			compressed, err := gzip.NewReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			archive := tar.NewReader(compressed)
+			for {
+				header, err := archive.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				content, err := io.ReadAll(archive)
+				require.NoError(t, err)
+				files[header.Name] = string(content)
+			}
+		}
+		return files
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		dbtModel bool
+		filename string
+	}{
+		{name: "Zip with dbt model", format: services.FormatZip, dbtModel: true, filename: "query-trace-1.zip"},
+		{name: "Tar without dbt model", format: services.FormatTar, filename: "query-trace-1.tar.gz"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, _, filename, err := services.RenderBundle(tc.format, response, tc.dbtModel, services.DbtTarget{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.filename, filename)
+
+			files := readBundle(t, tc.format, data)
+			assert.Equal(t, response.Query+";\n", files["query-trace-1/query.sql"])
+
+			var metadata map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(files["query-trace-1/metadata.json"]), &metadata))
+			assert.Equal(t, "abc123", metadata["schema_version"])
+			assert.Equal(t, 72.5, metadata["confidence"])
+			assert.Contains(t, metadata, "matched_fields")
+			assert.Contains(t, metadata, "generated_at")
+
+			model, bundled := files["query-trace-1/models/generated_query.sql"]
+			assert.Equal(t, tc.dbtModel, bundled)
+			if tc.dbtModel {
+				assert.Contains(t, model, "{{ config(materialized='view') }}")
+				assert.Contains(t, model, "FROM {{ source('warehouse', 'users') }} u JOIN {{ source('warehouse', 'orders') }} o")
+			}
+		})
+	}
+}
+
+func TestRenderDbtModel(t *testing.T) {
+	response := models.QueryResponse{
+		TraceID:       "trace-1",
+		SchemaVersion: "abc123",
+		Query:         "SELECT u.email, o.total_amount FROM users u JOIN analytics.orders o ON o.user_id = u.user_id JOIN products p ON p.id = o.product_id",
+	}
+	refs := map[string]string{"orders": "ref('fct_orders')", "products": "source('shop', 'products')"}
+
+	testCases := []struct {
+		name     string
+		target   services.DbtTarget
+		expected string
+	}{
+		{
+			name:     "Unknown tables read from the default source",
+			target:   services.DbtTarget{},
+			expected: "FROM {{ source('warehouse', 'users') }} u JOIN {{ source('warehouse', 'orders') }} o ON o.user_id = u.user_id JOIN {{ source('warehouse', 'products') }} p",
+		},
+		{
+			name:     "Catalog refs replace qualified and bare tables",
+			target:   services.DbtTarget{Source: "raw", Refs: refs},
+			expected: "FROM {{ source('raw', 'users') }} u JOIN {{ ref('fct_orders') }} o ON o.user_id = u.user_id JOIN {{ source('shop', 'products') }} p",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, contentType, filename := services.RenderDbtModel(response, tc.target)
+			assert.Equal(t, "text/plain; charset=utf-8", contentType)
+			assert.Equal(t, "generated_query.sql", filename)
+			assert.True(t, strings.HasPrefix(model, "-- Generated by go_query_api from schema version abc123 (trace trace-1)\n{{ config(materialized='view') }}\n\n"))
+			assert.Contains(t, model, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultFormat(t *testing.T) {
+	testCases := []struct {
+		name           string
+		requested      string
+		accept         string
+		expectedFormat string
+		expectError    bool
+	}{
+		{name: "Default", expectedFormat: services.ResultFormatJSON},
+		{name: "Requested", requested: "CSV", accept: "application/x-ndjson", expectedFormat: services.ResultFormatCSV},
+		{name: "Accept header", accept: "text/html, text/csv;q=0.9", expectedFormat: services.ResultFormatCSV},
+		{name: "NDJSON media type", accept: "application/x-ndjson", expectedFormat: services.ResultFormatNDJSON},
+		{name: "Unknown media types", accept: "text/html, */*", expectedFormat: services.ResultFormatJSON},
+		{name: "Unknown format", requested: "xlsx", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := services.ResultFormat(tc.requested, tc.accept)
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrUnknownFormat)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFormat, format)
+		})
+	}
+}
+
+func TestExecuteStream(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		format         string
+		expectedOutput string
+	}{
+		{
+			name:           "CSV",
+			format:         services.ResultFormatCSV,
+			expectedOutput: "product_name,created_at\n\"Desk, oak\",2024-03-01T12:00:00Z\nLamp,\n",
+		},
+		{
+			name:           "NDJSON",
+			format:         services.ResultFormatNDJSON,
+			expectedOutput: "{\"product_name\":\"Desk, oak\",\"created_at\":\"2024-03-01T12:00:00Z\"}\n{\"product_name\":\"Lamp\",\"created_at\":null}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"product_name", "created_at"}).
+				AddRow("Desk, oak", created).AddRow("Lamp", nil))
+			mock.ExpectRollback()
+
+			var out bytes.Buffer
+			response, err := queryService.ExecuteStream(
+				services.NewDatabaseExecutor(db, services.SchemaSourcePostgres, services.ResultLimits{}),
+				models.QueryRequest{Description: "product display name", MaxMatches: 1},
+				services.NewResultWriter(tc.format, &out),
+			)
+			require.NoError(t, err)
+			require.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.expectedOutput, out.String())
+			assert.Equal(t, 2, response.RowCount)
+			assert.False(t, response.Truncated)
+		})
+	}
+
+	t.Run("JSON is not streamed", func(t *testing.T) {
+		assert.Nil(t, services.NewResultWriter(services.ResultFormatJSON, &bytes.Buffer{}))
+	})
+}
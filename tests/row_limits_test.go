@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowLimits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cfg           config.Config
+		request       models.BuildQueryRequest
+		expectedQuery string
+		expectedNote  string
+	}{
+		{
+			name:          "No limits configured",
+			request:       models.BuildQueryRequest{Fields: []models.IntentField{{Table: "users", Column: "email"}}},
+			expectedQuery: "SELECT u.email FROM users u",
+		},
+		{
+			name:          "Default injected",
+			cfg:           config.Config{DefaultLimit: 1000, MaxLimit: 10000},
+			request:       models.BuildQueryRequest{Fields: []models.IntentField{{Table: "users", Column: "email"}}},
+			expectedQuery: "SELECT u.email FROM users u LIMIT 1000",
+			expectedNote:  "no limit was requested, so the default LIMIT 1000 was applied",
+		},
+		{
+			name:          "Maximum injected without a default",
+			cfg:           config.Config{MaxLimit: 500},
+			request:       models.BuildQueryRequest{Fields: []models.IntentField{{Table: "users", Column: "email"}}},
+			expectedQuery: "SELECT u.email FROM users u LIMIT 500",
+			expectedNote:  "no limit was requested, so the maximum LIMIT 500 was applied",
+		},
+		{
+			name:          "Requested limit within the maximum",
+			cfg:           config.Config{DefaultLimit: 1000, MaxLimit: 10000},
+			request:       models.BuildQueryRequest{Fields: []models.IntentField{{Table: "users", Column: "email"}}, Limit: 20},
+			expectedQuery: "SELECT u.email FROM users u LIMIT 20",
+		},
+		{
+			name:          "Requested limit clamped",
+			cfg:           config.Config{DefaultLimit: 1000, MaxLimit: 10000},
+			request:       models.BuildQueryRequest{Fields: []models.IntentField{{Table: "users", Column: "email"}}, Limit: 50000},
+			expectedQuery: "SELECT u.email FROM users u LIMIT 10000",
+			expectedNote:  "the requested limit of 50000 was lowered to the maximum of 10000",
+		},
+		{
+			name:          "Single-row aggregates are left alone",
+			cfg:           config.Config{DefaultLimit: 1000, MaxLimit: 10000},
+			request:       models.BuildQueryRequest{Fields: []models.IntentField{{Table: "users", Column: "email", Aggregate: "count"}}},
+			expectedQuery: "SELECT COUNT(u.email) FROM users u",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.CSVPath = "../field_mappings.csv"
+			fieldService, err := services.NewFieldService(&cfg)
+			require.NoError(t, err)
+
+			response, err := services.NewQueryService(fieldService).BuildQuery(tc.request)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+			if tc.expectedNote == "" {
+				assert.Empty(t, response.Warnings)
+			} else {
+				assert.Contains(t, response.Warnings, tc.expectedNote)
+			}
+		})
+	}
+
+	t.Run("Generated queries", func(t *testing.T) {
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv", DefaultLimit: 100})
+		require.NoError(t, err)
+		response, err := services.NewQueryService(fieldService).GenerateQuery(models.QueryRequest{Description: "user email"})
+		require.NoError(t, err)
+		assert.Equal(t, 100, response.Plan.Limit)
+		assert.Contains(t, response.Warnings, "no limit was requested, so the default LIMIT 100 was applied")
+	})
+
+	t.Run("Negative limits are rejected", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+		for path, body := range map[string]string{
+			"/api/v1/generate-query": `{"description": "user email", "limit": -1}`,
+			"/api/v1/build-query":    `{"fields": [{"table": "users", "column": "email"}], "limit": -1}`,
+		} {
+			req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleValues(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name           string
+		driver         string
+		request        models.SampleValuesRequest
+		expectedQuery  string
+		expectedValues []interface{}
+		expectedError  error
+	}{
+		{
+			name:           "Default sample size",
+			driver:         services.SchemaSourcePostgres,
+			request:        models.SampleValuesRequest{Table: "users", Column: "email"},
+			expectedQuery:  "SELECT DISTINCT email FROM users WHERE email IS NOT NULL ORDER BY email LIMIT 10",
+			expectedValues: []interface{}{"a@example.com", "b@example.com"},
+		},
+		{
+			name:           "System names and requested size",
+			driver:         services.SchemaSourceMySQL,
+			request:        models.SampleValuesRequest{Table: "users", Column: "email", System: "system_a", Limit: 3},
+			expectedQuery:  "SELECT DISTINCT email_addr FROM users WHERE email_addr IS NOT NULL ORDER BY email_addr LIMIT 3",
+			expectedValues: []interface{}{"a@example.com", "b@example.com"},
+		},
+		{
+			name:           "Size capped",
+			driver:         services.SchemaSourcePostgres,
+			request:        models.SampleValuesRequest{Table: "users", Column: "email", Limit: 5000},
+			expectedQuery:  "SELECT DISTINCT email FROM users WHERE email IS NOT NULL ORDER BY email LIMIT 100",
+			expectedValues: []interface{}{"a@example.com", "b@example.com"},
+		},
+		{
+			name:          "Unknown field",
+			driver:        services.SchemaSourcePostgres,
+			request:       models.SampleValuesRequest{Table: "users", Column: "password"},
+			expectedError: services.ErrUnknownField,
+		},
+		{
+			name:          "Sensitive field",
+			driver:        services.SchemaSourcePostgres,
+			request:       models.SampleValuesRequest{Table: "users", Column: "tax_id"},
+			expectedError: services.ErrNotSampleable,
+		},
+		{
+			name:          "Classified above the caller's clearance",
+			driver:        services.SchemaSourcePostgres,
+			request:       models.SampleValuesRequest{Table: "users", Column: "email", Clearance: services.ClassificationPublic},
+			expectedError: services.ErrInsufficientClearance,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tc.expectedError == nil {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(tc.expectedQuery)).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a@example.com").AddRow([]byte("b@example.com")))
+				mock.ExpectRollback()
+			}
+
+			response, err := queryService.SampleValues(services.NewDatabaseExecutor(db, tc.driver, services.ResultLimits{}), tc.request)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.expectedQuery, response.Query)
+			assert.Equal(t, tc.expectedValues, response.Values)
+			assert.Equal(t, services.DefaultConnection, response.Connection)
+		})
+	}
+}
+
+func TestSampleValuesAggregateOnly(t *testing.T) {
+	policies, err := services.LoadPolicies(writePolicies(t, "aggregate_only:\n  tables: [orders]\n"))
+	require.NoError(t, err)
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+	queryService.UsePolicies(policies)
+
+	// Distinct values of an aggregate-only table are never read
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	executor := services.NewDatabaseExecutor(db, services.SchemaSourcePostgres, services.ResultLimits{})
+	_, err = queryService.SampleValues(executor, models.SampleValuesRequest{Table: "orders", Column: "total_amount"})
+	assert.ErrorIs(t, err, services.ErrAggregateOnly)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// Other tables are sampled as usual
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT email FROM users")).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a@example.com"))
+	mock.ExpectRollback()
+	_, err = queryService.SampleValues(executor, models.SampleValuesRequest{Table: "users", Column: "email"})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSampleValuesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name           string
+		cfg            *config.Config
+		path           string
+		expectedStatus int
+	}{
+		{name: "Execution disabled", cfg: &config.Config{CSVPath: "../field_mappings.csv"}, path: "/api/v1/fields/users/email/samples", expectedStatus: http.StatusForbidden},
+		{name: "No database configured", cfg: &config.Config{CSVPath: "../field_mappings.csv", ExecuteQueries: true}, path: "/api/v1/fields/users/email/samples", expectedStatus: http.StatusServiceUnavailable},
+		{name: "Invalid limit", cfg: &config.Config{CSVPath: "../field_mappings.csv", ExecuteQueries: true}, path: "/api/v1/fields/users/email/samples?limit=many", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown field", cfg: &config.Config{CSVPath: "../field_mappings.csv", ExecuteQueries: true, DatabaseURL: "postgres://localhost/app"}, path: "/api/v1/fields/users/password/samples", expectedStatus: http.StatusNotFound},
+		{name: "Aggregate-only table", cfg: &config.Config{CSVPath: "../field_mappings.csv", ExecuteQueries: true, DatabaseURL: "postgres://localhost/app", PolicyPath: "../policies.example.yaml"}, path: "/api/v1/fields/order_items/order_id/samples", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			require.NoError(t, handlers.SetupRoutes(r, tc.cfg))
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+}
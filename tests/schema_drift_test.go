@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "shop.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,measure
+user_id,users,uid,,User identifier,INTEGER,,,,
+email,users,email_addr,,User email address,VARCHAR,,,,
+order_id,orders,,,Order identifier,INTEGER,,,,
+status,orders,,,Order status,VARCHAR,,,,
+revenue,orders,,,Order revenue,DECIMAL,,,,SUM(orders.total)
+sku,products,,,Product SKU,VARCHAR,,,,
+`), 0o644))
+	cfg := &config.Config{CSVPath: csvPath}
+	fieldService, err := services.NewFieldService(cfg)
+	require.NoError(t, err)
+	schema := services.NewLiveSchema(cfg, fieldService, nil, nil)
+
+	columns := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("users", "user_id").AddRow("users", "uid").AddRow("users", "email").
+			AddRow("orders", "order_id").AddRow("orders", "total").AddRow("orders", "created_at").
+			AddRow("audit_log", "entry")
+	}
+
+	testCases := []struct {
+		name                    string
+		system                  string
+		expectedMissingTables   []string
+		expectedMissingColumns  []models.MissingColumn
+		expectedUnmappedColumns []models.DatabaseColumn
+	}{
+		{
+			name:                   "Logical names",
+			system:                 "default",
+			expectedMissingTables:  []string{"products"},
+			expectedMissingColumns: []models.MissingColumn{{Field: "orders.status", Table: "orders", Column: "status"}},
+			expectedUnmappedColumns: []models.DatabaseColumn{
+				{Table: "orders", Column: "created_at"}, {Table: "orders", Column: "total"}, {Table: "users", Column: "uid"},
+			},
+		},
+		{
+			name:                  "System names",
+			system:                "system_a",
+			expectedMissingTables: []string{"products"},
+			expectedMissingColumns: []models.MissingColumn{
+				{Field: "orders.status", Table: "orders", Column: "status"}, {Field: "users.email", Table: "users", Column: "email_addr"},
+			},
+			expectedUnmappedColumns: []models.DatabaseColumn{
+				{Table: "orders", Column: "created_at"}, {Table: "orders", Column: "total"},
+				{Table: "users", Column: "email"}, {Table: "users", Column: "user_id"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectQuery("FROM information_schema.columns").WithArgs("").WillReturnRows(columns())
+
+			report, err := services.NewSchemaDriftMonitor(schema, services.NewDatabaseEstimator(db, services.SchemaSourcePostgres), nil).Check(tc.system)
+			require.NoError(t, err)
+			require.NoError(t, mock.ExpectationsWereMet())
+			assert.True(t, report.Drifted)
+			assert.Equal(t, fieldService.Version(), report.Version)
+			assert.Equal(t, tc.expectedMissingTables, report.MissingTables)
+			assert.Equal(t, tc.expectedMissingColumns, report.MissingColumns)
+			assert.Equal(t, tc.expectedUnmappedColumns, report.UnmappedColumns)
+		})
+	}
+
+	t.Run("No drift", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery("FROM information_schema.columns").WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("Users", "USER_ID").AddRow("users", "email").
+			AddRow("orders", "order_id").AddRow("orders", "status").AddRow("products", "sku"))
+
+		report, err := services.NewSchemaDriftMonitor(schema, services.NewDatabaseEstimator(db, services.SchemaSourcePostgres), nil).Check("default")
+		require.NoError(t, err)
+		assert.False(t, report.Drifted)
+	})
+
+	t.Run("Failed lookup", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery("FROM information_schema.columns").WillReturnError(errors.New("permission denied"))
+
+		_, err = services.NewSchemaDriftMonitor(schema, services.NewDatabaseEstimator(db, services.SchemaSourcePostgres), nil).Check("default")
+		assert.ErrorContains(t, err, "permission denied")
+	})
+
+	t.Run("Systems with their own connection are checked against it", func(t *testing.T) {
+		defaultDB, defaultMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer defaultDB.Close()
+		systemDB, systemMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer systemDB.Close()
+		executor := services.NewDatabaseExecutor(nil, "", services.ResultLimits{})
+		executor.UseConnection("system_a", systemDB, services.SchemaSourceMySQL)
+		monitor := services.NewSchemaDriftMonitor(schema, services.NewDatabaseEstimator(defaultDB, services.SchemaSourcePostgres), executor)
+
+		systemMock.ExpectQuery("FROM information_schema.COLUMNS").WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "COLUMN_NAME"}).
+			AddRow("users", "uid").AddRow("users", "email_addr").
+			AddRow("orders", "order_id").AddRow("orders", "status").AddRow("products", "sku"))
+		report, err := monitor.Check("system_a")
+		require.NoError(t, err)
+		assert.False(t, report.Drifted)
+		require.NoError(t, systemMock.ExpectationsWereMet())
+
+		// system_b has no connection of its own and uses DATABASE_URL
+		defaultMock.ExpectQuery("FROM information_schema.columns").WillReturnRows(columns())
+		report, err = monitor.Check("system_b")
+		require.NoError(t, err)
+		assert.True(t, report.Drifted)
+		require.NoError(t, defaultMock.ExpectationsWereMet())
+	})
+
+	t.Run("Watch checks every connection", func(t *testing.T) {
+		defaultDB, defaultMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer defaultDB.Close()
+		systemDB, systemMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer systemDB.Close()
+		executor := services.NewDatabaseExecutor(nil, "", services.ResultLimits{})
+		executor.UseConnection("system_a", systemDB, services.SchemaSourceMySQL)
+		monitor := services.NewSchemaDriftMonitor(schema, services.NewDatabaseEstimator(defaultDB, services.SchemaSourcePostgres), executor)
+
+		defaultMock.ExpectQuery("FROM information_schema.columns").WillReturnRows(columns())
+		systemMock.ExpectQuery("FROM information_schema.COLUMNS").WillReturnRows(columns())
+		stop := make(chan struct{})
+		go monitor.Watch(10*time.Millisecond, stop)
+		defer close(stop)
+		assert.Eventually(t, func() bool {
+			return defaultMock.ExpectationsWereMet() == nil && systemMock.ExpectationsWereMet() == nil
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("Unknown system", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		_, err = services.NewSchemaDriftMonitor(schema, services.NewDatabaseEstimator(db, services.SchemaSourcePostgres), nil).Check("system_c")
+		assert.ErrorIs(t, err, services.ErrUnknownSystem)
+	})
+
+	t.Run("No database configured", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		require.NoError(t, handlers.SetupRoutes(r, cfg))
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/schema/drift", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		req, _ = http.NewRequest(http.MethodGet, "/api/v1/schema/drift?system=warehouse", nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
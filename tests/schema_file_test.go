@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDefinitionFile(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"tables": [
+		{"name": "users", "tags": ["identity"], "columns": [
+			{"name": "user_id", "description": "Unique identifier for user", "type": "INTEGER"}]},
+		{"name": "orders", "columns": [
+			{"name": "user_id", "description": "User who placed order", "references": "users.user_id"}]}
+	]}`), 0o644))
+
+	testCases := []struct {
+		name   string
+		path   string
+		fields int
+	}{
+		{name: "YAML", path: "../schema.example.yaml", fields: 5},
+		{name: "JSON", path: jsonPath, fields: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldService, err := services.NewFieldService(&config.Config{CSVPath: tc.path})
+			require.NoError(t, err)
+			assert.Len(t, fieldService.GetAllFields("default"), tc.fields)
+			assert.NotEmpty(t, fieldService.Version())
+
+			// Table tags are inherited by their columns
+			userID, found := fieldService.LookupField("users", "user_id")
+			require.True(t, found)
+			assert.Contains(t, userID.Tags, "identity")
+
+			// References become joins
+			joins, err := fieldService.FindJoinPath("orders", "users")
+			require.NoError(t, err)
+			require.Len(t, joins, 1)
+			assert.Equal(t, "orders.user_id = users.user_id", joins[0].Condition)
+		})
+	}
+
+	t.Run("Synonyms become system names", func(t *testing.T) {
+		fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../schema.example.yaml"})
+		require.NoError(t, err)
+		email, found := fieldService.LookupField("users", "email")
+		require.True(t, found)
+		assert.Equal(t, "email_addr", email.SystemAFieldMap)
+		assert.Equal(t, "user_email", email.SystemBFieldMap)
+		assert.Equal(t, []string{"identity", "pii"}, email.Tags)
+	})
+}
+
+func TestSchemaDefinitionErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "Invalid YAML", content: "tables: [unclosed"},
+		{name: "Table without a name", content: "tables:\n  - columns:\n      - name: id\n"},
+		{name: "Column without a name", content: "tables:\n  - name: users\n    columns:\n      - description: id\n"},
+		{name: "Malformed reference", content: "tables:\n  - name: orders\n    columns:\n      - name: user_id\n        references: users\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o644))
+			_, err := services.NewFieldService(&config.Config{CSVPath: path})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersionTimeTravel(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "fields.csv")
+	header := "column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key\n"
+	cfg := &config.Config{
+		CSVPath:          csvPath,
+		SchemaHistoryDir: filepath.Join(dir, "history"),
+	}
+
+	// Version 1 calls the column email; version 2 renames it
+	require.NoError(t, os.WriteFile(csvPath, []byte(header+"email,users,,,User email address,VARCHAR,,,\n"), 0o644))
+	v1, err := services.NewFieldService(cfg)
+	require.NoError(t, err)
+	versions, err := services.NewSchemaVersions(cfg, v1)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(csvPath, []byte(header+"email_address,users,,,User email address,VARCHAR,,,\n"), 0o644))
+	v2, err := services.NewFieldService(cfg)
+	require.NoError(t, err)
+	require.NoError(t, versions.Record(v2))
+	require.NotEqual(t, v1.Version(), v2.Version())
+	assert.Equal(t, v2.Version(), versions.Current())
+
+	queryService := services.NewQueryService(v2)
+	queryService.UseSchemaVersions(versions)
+	sink := &recordingSink{}
+	events := services.NewEventStreamWithSink(cfg, sink)
+	queryService.UseEventStream(events)
+
+	testCases := []struct {
+		name          string
+		schemaVersion string
+		expectedQuery string
+		expectError   bool
+	}{
+		{name: "Current by default", expectedQuery: "SELECT u.email_address FROM users u"},
+		{name: "Prior version", schemaVersion: v1.Version(), expectedQuery: "SELECT u.email FROM users u"},
+		{name: "Unknown version", schemaVersion: "0123456789ab", expectError: true},
+		{name: "Not a version", schemaVersion: "../fields", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{
+				Description:   "user email address",
+				SchemaVersion: tc.schemaVersion,
+			})
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrUnknownSchemaVersion)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+			if tc.schemaVersion != "" {
+				assert.Equal(t, tc.schemaVersion, response.SchemaVersion)
+			} else {
+				assert.Equal(t, v2.Version(), response.SchemaVersion)
+			}
+		})
+	}
+
+	// Generating against a prior version keeps the service's configuration,
+	// such as its event stream
+	events.Close()
+	var published []string
+	for _, batch := range sink.batches {
+		for _, event := range batch {
+			published = append(published, event.SchemaVersion)
+		}
+	}
+	assert.Equal(t, []string{v2.Version(), v1.Version()}, published)
+
+	// After a restart the prior version is reloaded from the history directory
+	restarted, err := services.NewSchemaVersions(cfg, v2)
+	require.NoError(t, err)
+	list := restarted.List()
+	require.Len(t, list, 2)
+	assert.True(t, list[0].Current)
+	assert.False(t, list[1].InMemory)
+
+	historical, err := restarted.Get(v1.Version())
+	require.NoError(t, err)
+	assert.Equal(t, v1.Version(), historical.Version())
+}
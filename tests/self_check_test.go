@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaSelfCheck(t *testing.T) {
+	dir := writeMappings(t, map[string]string{
+		"disconnected.csv": mappingHeader +
+			"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+			"user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,user_id\n" +
+			"sku,inventory,sku,stock_keeping_unit,Stock keeping unit,VARCHAR,,,\n",
+		"skipped.csv": mappingHeader +
+			"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+			"broken,row\n",
+		"dangling.csv": mappingHeader +
+			"user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,user_id\n",
+	})
+
+	testCases := []struct {
+		name       string
+		path       string
+		valid      bool
+		connected  bool
+		components [][]string
+		codes      []string
+	}{
+		{
+			name:       "Bundled mappings",
+			path:       "../field_mappings.csv",
+			valid:      true,
+			connected:  true,
+			components: [][]string{{"order_items", "orders", "products", "users"}},
+		},
+		{
+			name:       "Disconnected tables are a warning",
+			path:       filepath.Join(dir, "disconnected.csv"),
+			valid:      true,
+			components: [][]string{{"orders", "users"}, {"inventory"}},
+			codes:      []string{services.IssueDisconnectedTables},
+		},
+		{
+			name:       "Skipped rows are an error",
+			path:       filepath.Join(dir, "skipped.csv"),
+			connected:  true,
+			components: [][]string{{"users"}},
+			codes:      []string{services.IssueSkippedRows},
+		},
+		{
+			name:       "Joins to undefined tables are an error",
+			path:       filepath.Join(dir, "dangling.csv"),
+			connected:  true,
+			components: [][]string{{"orders", "users"}},
+			codes:      []string{services.IssueDanglingTable},
+		},
+		{
+			name:       "Load failure is reported",
+			path:       filepath.Join(dir, "missing.csv"),
+			connected:  false,
+			components: [][]string{},
+			codes:      []string{services.IssueLoadFailed},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := services.CheckSchema(&config.Config{CSVPath: tc.path})
+			assert.Equal(t, tc.valid, check.Valid)
+			assert.Equal(t, tc.connected, check.Connected)
+			assert.Equal(t, tc.components, check.Components)
+			var codes []string
+			for _, issue := range check.Issues {
+				codes = append(codes, issue.Code)
+			}
+			assert.Equal(t, tc.codes, codes)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyFormat(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	request := models.BuildQueryRequest{
+		Fields: []models.IntentField{{Table: "users", Column: "email"}, {Table: "orders", Column: "order_id"}},
+		Filters: []models.IntentFilter{
+			{Table: "users", Column: "email", Operator: "in", Value: []interface{}{"a, b", "it's (x) AND y"}},
+			{Table: "orders", Column: "order_id", Operator: ">", Value: 3.0},
+		},
+		OrderBy:  []models.IntentOrder{{Table: "users", Column: "email"}, {Table: "orders", Column: "order_id", Direction: "desc"}},
+		Distinct: true,
+		Limit:    10,
+	}
+
+	testCases := []struct {
+		name          string
+		format        string
+		dialect       string
+		expectedQuery string
+		expectError   bool
+	}{
+		{
+			name:          "Compact by default",
+			expectedQuery: "SELECT DISTINCT u.email, o.order_id FROM users u JOIN orders o ON o.user_id = u.user_id WHERE u.email IN ('a, b', 'it''s (x) AND y') AND o.order_id > 3 ORDER BY u.email ASC, o.order_id DESC LIMIT 10",
+		},
+		{
+			name:          "Compact",
+			format:        "compact",
+			expectedQuery: "SELECT DISTINCT u.email, o.order_id FROM users u JOIN orders o ON o.user_id = u.user_id WHERE u.email IN ('a, b', 'it''s (x) AND y') AND o.order_id > 3 ORDER BY u.email ASC, o.order_id DESC LIMIT 10",
+		},
+		{
+			name:   "Pretty",
+			format: "pretty",
+			expectedQuery: strings.Join([]string{
+				"SELECT DISTINCT",
+				"  u.email,",
+				"  o.order_id",
+				"FROM users u",
+				"JOIN orders o ON o.user_id = u.user_id",
+				"WHERE u.email IN ('a, b', 'it''s (x) AND y')",
+				"  AND o.order_id > 3",
+				"ORDER BY",
+				"  u.email ASC,",
+				"  o.order_id DESC",
+				"LIMIT 10",
+			}, "\n"),
+		},
+		{
+			name:    "Pretty SQL Server",
+			format:  "pretty",
+			dialect: "sqlserver",
+			expectedQuery: strings.Join([]string{
+				"SELECT DISTINCT TOP 10",
+				"  u.email,",
+				"  o.order_id",
+				"FROM users u",
+				"JOIN orders o ON o.user_id = u.user_id",
+				"WHERE u.email IN ('a, b', 'it''s (x) AND y')",
+				"  AND o.order_id > 3",
+				"ORDER BY",
+				"  u.email ASC,",
+				"  o.order_id DESC",
+			}, "\n"),
+		},
+		{
+			name:        "Unknown format",
+			format:      "tidy",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request.Format = tc.format
+			request.Dialect = tc.dialect
+			response, err := queryService.BuildQuery(request)
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrInvalidIntent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+
+	t.Run("Generated queries", func(t *testing.T) {
+		require.NoError(t, services.ValidateFormat("pretty"))
+		response, err := queryService.GenerateQuery(models.QueryRequest{Description: "user email", Format: "pretty", Limit: 5, Offset: 3})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(response.Query, "SELECT\n  u.email"), response.Query)
+		assert.True(t, strings.HasSuffix(response.Query, "\nLIMIT 5\nOFFSET 3"), response.Query)
+	})
+}
+
+func TestSQLStyle(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "shop.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,measure
+user_id,users,,,User identifier,INTEGER,,,,
+signup_date,users,,,Day the user signed up,DATE,,,,
+order_id,orders,,,Order identifier,INTEGER,,,,
+user_id,orders,,,User placing the order,INTEGER,user_id,users,user_id,
+revenue,orders,,,Order revenue,DECIMAL,,,,SUM(orders.total)
+`), 0o644))
+
+	request := models.BuildQueryRequest{
+		Fields: []models.IntentField{{Table: "users", Column: "signup_date"}, {Table: "orders", Column: "revenue"}},
+		Filters: []models.IntentFilter{
+			{Table: "users", Column: "signup_date", Operator: "is not null"},
+			{Table: "users", Column: "signup_date", Operator: ">=", Value: "2024-01-01"},
+		},
+		GroupBy: []models.FieldRef{{Table: "users", Column: "signup_date"}},
+		Limit:   10,
+	}
+
+	testCases := []struct {
+		name          string
+		cfg           config.Config
+		format        string
+		expectedQuery string
+	}{
+		{
+			name:          "As rendered by default",
+			expectedQuery: "SELECT u.signup_date, SUM(o.total) AS revenue FROM users u JOIN orders o ON o.user_id = u.user_id WHERE u.signup_date IS NOT NULL AND u.signup_date >= DATE '2024-01-01' GROUP BY u.signup_date LIMIT 10",
+		},
+		{
+			name:          "Lowercase keywords with AS for tables",
+			cfg:           config.Config{KeywordCase: "lower", AliasKeyword: "always"},
+			expectedQuery: "select u.signup_date, SUM(o.total) as revenue from users as u join orders as o on o.user_id = u.user_id where u.signup_date is not null and u.signup_date >= date '2024-01-01' group by u.signup_date limit 10",
+		},
+		{
+			name:          "No AS",
+			cfg:           config.Config{AliasKeyword: "never"},
+			expectedQuery: "SELECT u.signup_date, SUM(o.total) revenue FROM users u JOIN orders o ON o.user_id = u.user_id WHERE u.signup_date IS NOT NULL AND u.signup_date >= DATE '2024-01-01' GROUP BY u.signup_date LIMIT 10",
+		},
+		{
+			name:   "Leading commas",
+			cfg:    config.Config{LeadingCommas: true},
+			format: "pretty",
+			expectedQuery: strings.Join([]string{
+				"SELECT",
+				"  u.signup_date",
+				"  , SUM(o.total) AS revenue",
+				"FROM users u",
+				"JOIN orders o ON o.user_id = u.user_id",
+				"WHERE u.signup_date IS NOT NULL",
+				"  AND u.signup_date >= DATE '2024-01-01'",
+				"GROUP BY",
+				"  u.signup_date",
+				"LIMIT 10",
+			}, "\n"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.CSVPath = csvPath
+			fieldService, err := services.NewFieldService(&cfg)
+			require.NoError(t, err)
+
+			request.Format = tc.format
+			response, err := services.NewQueryService(fieldService).BuildQuery(request)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+
+	t.Run("Invalid styles", func(t *testing.T) {
+		_, err := services.ParseSQLStyle("title", false, "")
+		assert.Error(t, err)
+		_, err = services.ParseSQLStyle("", false, "sometimes")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStarterMappings(t *testing.T) {
+	data, err := services.StarterMappings([]string{"users", " orders", "categories", "users"})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# field_description: ")
+	assert.Contains(t, string(data), "# join_type: ")
+
+	report := services.ValidateMappings(data, "", "")
+	assert.True(t, report.Valid)
+	assert.Empty(t, report.Issues)
+	assert.Equal(t, 11, report.Fields)
+
+	// The starter file loads as is, with the example relationships
+	csvPath := filepath.Join(t.TempDir(), "field_mappings.csv")
+	require.NoError(t, os.WriteFile(csvPath, data, 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	assert.Len(t, fieldService.GetAllFields(""), 11)
+	field, found := fieldService.LookupField("categories", "category_id")
+	require.True(t, found)
+	assert.Equal(t, "Unique identifier for category", field.Description)
+
+	response, err := services.NewQueryService(fieldService).BuildQuery(models.BuildQueryRequest{
+		Fields: []models.IntentField{{Table: "orders", Column: "created_at"}, {Table: "users", Column: "name"}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, response.Query, "o.user_id = u.user_id")
+
+	for _, tables := range [][]string{{}, {" ", ""}, {"users; drop"}} {
+		_, err := services.StarterMappings(tables)
+		assert.Error(t, err, "tables %q", tables)
+	}
+}
+
+func TestValidateMappingsCountsCommentLines(t *testing.T) {
+	data := []byte("# Field mappings\n# for the shop\ncolumn_name;table_name;system_a_fieldmap;system_b_fieldmap;field_description;field_type;join_key;foreign_table;foreign_key\n# shops\ncafe_name;shops;;;;VARCHAR;;;\n")
+
+	report := services.ValidateMappings(data, "", "")
+
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, services.IssueMissingDescription, report.Issues[0].Code)
+	assert.Equal(t, 5, report.Issues[0].Row)
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemSpecificNames(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "systems.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(`column_name,table_name,system_a_fieldmap,system_b_fieldmap,field_description,field_type,join_key,foreign_table,foreign_key,system_a_tablemap,system_b_tablemap
+user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,,tbl_user,
+email,users,email_addr,,User email address,VARCHAR,,,,tbl_user,
+order_id,orders,order_num,transaction_id,Unique order identifier,INTEGER,,,,tbl_order,Orders
+user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,user_id,tbl_order,Orders
+`), 0o644))
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: csvPath})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name          string
+		system        string
+		aliasStyle    string
+		expectedQuery string
+	}{
+		{
+			name:          "Default names",
+			system:        "default",
+			expectedQuery: `SELECT u.user_id, u.email, o.order_id, o.user_id FROM users u JOIN orders o ON o.user_id = u.user_id`,
+		},
+		{
+			name:          "System A",
+			system:        "system_a",
+			expectedQuery: `SELECT u.uid, u.email_addr, o.order_num, o.customer_id FROM tbl_user u JOIN tbl_order o ON o.customer_id = u.uid`,
+		},
+		{
+			name:          "System B falls back where unmapped",
+			system:        "system_b",
+			expectedQuery: `SELECT u.user_identifier, u.email, o.transaction_id, o.user_ref FROM users u JOIN "Orders" o ON o.user_ref = u.user_identifier`,
+		},
+		{
+			name:          "Full aliases keep the mapped table names",
+			system:        "system_a",
+			aliasStyle:    "full",
+			expectedQuery: `SELECT users.uid, users.email_addr, orders.order_num, orders.customer_id FROM tbl_user users JOIN tbl_order orders ON orders.customer_id = users.uid`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(models.QueryRequest{
+				Description: "order id and user email",
+				System:      tc.system,
+				AliasStyle:  tc.aliasStyle,
+				Dialect:     "postgres",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, response.Query)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptionTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+templates:
+  user_contact: "user {channel} address"
+`), 0o644))
+
+	templates, err := services.LoadTemplates(path)
+	require.NoError(t, err)
+	require.Len(t, templates.List(), 1)
+	assert.Equal(t, []string{"channel"}, templates.List()[0].Variables)
+
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+	queryService.UseTemplates(templates)
+
+	testCases := []struct {
+		name             string
+		request          models.QueryRequest
+		expectedExpanded string
+		expectError      bool
+	}{
+		{
+			name: "Saved template",
+			request: models.QueryRequest{
+				Template:  "user_contact",
+				Variables: map[string]string{"channel": "email"},
+			},
+			expectedExpanded: "user email address",
+		},
+		{
+			name: "Inline description with variables",
+			request: models.QueryRequest{
+				Description: "{entity} email address",
+				Variables:   map[string]string{"entity": "user"},
+			},
+			expectedExpanded: "user email address",
+		},
+		{
+			name: "Missing variable",
+			request: models.QueryRequest{
+				Template: "user_contact",
+			},
+			expectError: true,
+		},
+		{
+			name: "Unknown template",
+			request: models.QueryRequest{
+				Template:  "nope",
+				Variables: map[string]string{"channel": "email"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := queryService.GenerateQuery(tc.request)
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrInvalidTemplate)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedExpanded, response.ExpandedDescription)
+			assert.Contains(t, response.Query, "u.email")
+		})
+	}
+}
+
+func TestTemplateHandlers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"templates": {"user_contact": "user {channel} address"}}`), 0o644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{
+		CSVPath:       "../field_mappings.csv",
+		TemplatesPath: path,
+	}))
+
+	// Templates are listed with their variables
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/templates", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"variables":["channel"]`)
+
+	testCases := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+	}{
+		{"Template without description", `{"template":"user_contact","variables":{"channel":"email"}}`, http.StatusOK},
+		{"Missing variable", `{"template":"user_contact"}`, http.StatusBadRequest},
+		{"Neither description nor template", `{"variables":{"channel":"email"}}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewBufferString(tc.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMappings(t *testing.T) {
+	testCases := []struct {
+		name          string
+		content       string
+		valid         bool
+		expectedCodes []string
+	}{
+		{
+			name: "Clean file",
+			content: mappingHeader +
+				"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+				"user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,user_id\n",
+			valid: true,
+		},
+		{
+			name:          "Wrong column count",
+			content:       mappingHeader + "user_id,users,uid\nemail,users,email_addr,user_email,User email address,VARCHAR,,,,extra\n",
+			valid:         false,
+			expectedCodes: []string{services.IssueColumnCount, services.IssueColumnCount},
+		},
+		{
+			name: "Duplicate field",
+			content: mappingHeader +
+				"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+				"user_id,users,uid,user_identifier,Another user id,INTEGER,,,\n",
+			valid:         false,
+			expectedCodes: []string{services.IssueDuplicateField},
+		},
+		{
+			name: "Dangling references",
+			content: mappingHeader +
+				"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n" +
+				"account_id,orders,acct,account_ref,Account that placed order,INTEGER,account_id,accounts,account_id\n" +
+				"user_id,orders,customer_id,user_ref,User who placed order,INTEGER,user_id,users,id\n",
+			valid:         false,
+			expectedCodes: []string{services.IssueDanglingTable, services.IssueDanglingKey},
+		},
+		{
+			name:          "Missing description",
+			content:       mappingHeader + "user_id,users,uid,user_identifier,,INTEGER,,,\n",
+			valid:         true,
+			expectedCodes: []string{services.IssueMissingDescription},
+		},
+		{
+			name:          "Missing names",
+			content:       mappingHeader + ",users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n",
+			valid:         false,
+			expectedCodes: []string{services.IssueMissingName, services.IssueNoFields},
+		},
+		{
+			name:          "Unterminated quote",
+			content:       mappingHeader + "user_id,users,\"uid,user_identifier\n",
+			valid:         false,
+			expectedCodes: []string{services.IssueMalformedCSV},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := services.ValidateMappings([]byte(tc.content), "", "")
+			assert.Equal(t, tc.valid, report.Valid)
+
+			codes := make([]string, 0, len(report.Issues))
+			for _, issue := range report.Issues {
+				codes = append(codes, issue.Code)
+			}
+			assert.ElementsMatch(t, tc.expectedCodes, codes)
+		})
+	}
+
+	t.Run("Issues carry row numbers", func(t *testing.T) {
+		report := services.ValidateMappings([]byte(mappingHeader+
+			"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n"+
+			"user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n"), "", "")
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, 3, report.Issues[0].Row)
+		assert.Contains(t, report.Issues[0].Message, "row 2")
+	})
+}
+
+func TestValidateMappingsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv", AdminToken: testAdminToken}))
+
+	csvData, err := os.ReadFile("../field_mappings.csv")
+	require.NoError(t, err)
+
+	// Validation never activates the file, whatever the outcome
+	w := postMultipart(t, r, "/admin/mappings/validate", mappingHeader+"user_id,users,uid\n")
+	require.Equal(t, http.StatusOK, w.Code)
+	var report models.ValidationReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.False(t, report.Valid)
+	assert.Equal(t, 2, report.Errors)
+
+	w = postMultipart(t, r, "/admin/mappings/validate", string(csvData))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.Valid)
+	assert.Equal(t, 11, report.Fields)
+}
+
+func TestValidateMappingsHandlerUsesConfiguredFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	row := "user_id,users,uid,user_identifier,Unique identifier for user,INTEGER,,,\n"
+	semicolons := strings.ReplaceAll(mappingHeader+row, ",", ";")
+
+	testCases := []struct {
+		name      string
+		delimiter string
+		content   string
+		valid     bool
+	}{
+		{
+			name:    "Detected when unset",
+			content: mappingHeader + row,
+			valid:   true,
+		},
+		{
+			name:      "Configured delimiter",
+			delimiter: "semicolon",
+			content:   semicolons,
+			valid:     true,
+		},
+		{
+			name:      "Other delimiter than configured",
+			delimiter: "semicolon",
+			content:   mappingHeader + row,
+			valid:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The live file is in the configured format too
+			csvPath := filepath.Join(t.TempDir(), "mappings.csv")
+			live := mappingHeader + row
+			if tc.delimiter != "" {
+				live = semicolons
+			}
+			require.NoError(t, os.WriteFile(csvPath, []byte(live), 0o644))
+
+			r := gin.New()
+			require.NoError(t, handlers.SetupRoutes(r, &config.Config{
+				CSVPath:      csvPath,
+				CSVDelimiter: tc.delimiter,
+				AdminToken:   testAdminToken,
+			}))
+
+			w := postMultipart(t, r, "/admin/mappings/validate", tc.content)
+			require.Equal(t, http.StatusOK, w.Code)
+			var report models.ValidationReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tc.valid, report.Valid)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/handlers"
+	"github.com/mgarce/go_query_api/internal/models"
+	"github.com/mgarce/go_query_api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteIntentsRefused(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		name        string
+		description string
+		expectError bool
+	}{
+		{name: "Delete", description: "delete old orders", expectError: true},
+		{name: "Update with a new value", description: "Please update user emails to lowercase.", expectError: true},
+		{name: "Drop", description: "drop the users table", expectError: true},
+		{name: "Insert", description: "insert a new order for user 5", expectError: true},
+		{name: "Create a table", description: "create a new table of products", expectError: true},
+		{name: "Adding up is a question", description: "add up order total amounts"},
+		{name: "Verb later in the description", description: "show orders users deleted"},
+		{name: "Hyphenated compound noun", description: "drop-off rate by month"},
+		{name: "Verb as a noun modifier", description: "edit history for user 5"},
+		{name: "Count of updates", description: "update count by day"},
+		{name: "Verb alone", description: "delete", expectError: true},
+		{name: "Ordinary question", description: "user email addresses"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := queryService.GenerateQuery(models.QueryRequest{Description: tc.description})
+			if tc.expectError {
+				assert.ErrorIs(t, err, services.ErrWriteIntent)
+			} else {
+				assert.NotErrorIs(t, err, services.ErrWriteIntent)
+			}
+		})
+	}
+}
+
+func TestWriteIntentSuggestions(t *testing.T) {
+	fieldService, err := services.NewFieldService(&config.Config{CSVPath: "../field_mappings.csv"})
+	require.NoError(t, err)
+	queryService := services.NewQueryService(fieldService)
+
+	testCases := []struct {
+		description string
+		operation   string
+		suggestion  string
+	}{
+		{"delete old orders", "delete", "show old orders"},
+		{"insert a new order for user 5", "insert", "show orders for user 5"},
+		{"insert into orders values (1, 2)", "insert", "show orders"},
+		{"create a new table of products", "create", "show products"},
+		{"add an entry to order items", "add", "show order items"},
+		{"update user emails to lowercase", "update", "show user emails"},
+		{"drop", "drop", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			request := models.QueryRequest{Description: tc.description}
+			_, err := queryService.GenerateQuery(request)
+			require.ErrorIs(t, err, services.ErrWriteIntent)
+
+			refusal := queryService.ReadOnlyRefusal(request, err)
+			assert.Equal(t, tc.operation, refusal.Operation)
+			assert.Equal(t, tc.suggestion, refusal.SuggestedDescription)
+		})
+	}
+}
+
+func TestWriteIntentRefusalSuggestsInspection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.NoError(t, handlers.SetupRoutes(r, &config.Config{CSVPath: "../field_mappings.csv"}))
+
+	body, _ := json.Marshal(models.QueryRequest{Description: "update user emails to lowercase"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/generate-query", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var refusal models.ReadOnlyRefusal
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refusal))
+	assert.True(t, refusal.ReadOnly)
+	assert.Equal(t, "update", refusal.Operation)
+	assert.Equal(t, "show user emails", refusal.SuggestedDescription)
+	assert.Contains(t, refusal.SuggestedQuery, "u.email")
+	assert.Contains(t, refusal.Error, "read-only")
+	assert.NotEmpty(t, refusal.TraceID)
+}
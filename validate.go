@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mgarce/go_query_api/internal/config"
+	"github.com/mgarce/go_query_api/internal/services"
+)
+
+// runValidate implements the validate subcommand: it checks mapping CSVs
+// without starting the server, in the configured CSV_DELIMITER and
+// CSV_ENCODING, prints a JSON report for each, and returns the process exit
+// code (1 if any file has errors)
+func runValidate(paths []string) int {
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s validate <mappings.csv> [more.csv ...]\n", os.Args[0])
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	exitCode := 0
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			exitCode = 1
+			continue
+		}
+
+		delimiter, encodingName := services.CSVFormat(cfg, path)
+		report := services.ValidateMappings(data, delimiter, encodingName)
+		if err := encoder.Encode(map[string]interface{}{"path": path, "report": report}); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 1
+		}
+		if !report.Valid {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// runSelfCheck implements --validate-only: it loads the configured schema,
+// prints its self-check and join graph report as JSON, and returns the
+// process exit code (1 if the schema has errors)
+func runSelfCheck(cfg *config.Config) int {
+	check := services.CheckSchema(cfg)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(check); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !check.Valid {
+		return 1
+	}
+	return 0
+}